@@ -0,0 +1,129 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"phenix/app"
+	"phenix/types"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func init() {
+	app.RegisterUserApp("callback", func() app.App { return new(Callback) })
+}
+
+// Callback is the scenario app used to give guests access to the phenix guest
+// callback API. Each time the experiment is started, a new token is minted for
+// every VM and injected into it along with the callback URL, so the tokens of
+// previous deployments are revoked. Injection requires VMs to use snapshot
+// disks.
+type Callback struct {
+	options app.Options
+}
+
+func (this *Callback) Init(opts ...app.Option) error {
+	this.options = app.NewOptions(opts...)
+	return nil
+}
+
+func (Callback) Name() string {
+	return "callback"
+}
+
+func (this Callback) Configure(ctx context.Context, exp *types.Experiment) error {
+	_, err := decodeMetadata(exp)
+	return err
+}
+
+func (this Callback) PreStart(ctx context.Context, exp *types.Experiment) error {
+	md, err := decodeMetadata(exp)
+	if err != nil {
+		return err
+	}
+
+	var (
+		dir    = exp.Spec.BaseDir() + "/callback"
+		status = Status{VMs: make(map[string]VMStatus)}
+	)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating experiment callback directory path: %w", err)
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		var (
+			name = node.General().Hostname()
+			file = dir + "/" + name + ".json"
+		)
+
+		token, err := mint()
+		if err != nil {
+			return fmt.Errorf("minting callback token for VM %s: %w", name, err)
+		}
+
+		body, _ := json.Marshal(config{URL: md.URL, Token: token, Experiment: exp.Metadata.Name, VM: name})
+
+		if err := os.WriteFile(file, body, 0600); err != nil {
+			return fmt.Errorf("writing callback config for VM %s: %w", name, err)
+		}
+
+		if strings.EqualFold(node.Hardware().OSType(), "windows") {
+			node.AddInject(file, "/phenix/callback.json", "0600", "")
+		} else {
+			node.AddInject(file, "/etc/phenix/callback.json", "0600", "")
+		}
+
+		status.VMs[name] = VMStatus{TokenHash: hash(token)}
+	}
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	return nil
+}
+
+func (Callback) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Callback) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this Callback) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	// Revoke the tokens minted for the experiment.
+	exp.Status.SetAppStatus(this.Name(), nil)
+
+	if err := os.RemoveAll(exp.Spec.BaseDir() + "/callback"); err != nil {
+		return fmt.Errorf("removing experiment callback directory: %w", err)
+	}
+
+	return nil
+}
+
+func decodeMetadata(exp *types.Experiment) (Metadata, error) {
+	var md Metadata
+
+	a := exp.App("callback")
+	if a == nil {
+		return md, fmt.Errorf("callback app not defined in experiment scenario")
+	}
+
+	if err := mapstructure.Decode(a.Metadata(), &md); err != nil {
+		return md, fmt.Errorf("decoding callback app metadata: %w", err)
+	}
+
+	if md.URL == "" {
+		return md, fmt.Errorf("callback URL must be provided in callback app metadata")
+	}
+
+	return md, nil
+}
@@ -0,0 +1,112 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestCallbackApp(t *testing.T) {
+	baseDir := t.TempDir()
+
+	nodes := []*v1.Node{
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "linux"},
+			HardwareF: &v1.Hardware{OSTypeF: "linux"},
+		},
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "windows"},
+			HardwareF: &v1.Hardware{OSTypeF: "windows"},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF:     "callback",
+				MetadataF: map[string]any{"url": "http://172.16.0.254:3001"},
+				HostsF: []*v2.ScenarioAppHost{
+					{HostnameF: "linux", MetadataF: map[string]any{"secrets": map[string]any{"db": "hunter2"}}},
+				},
+			},
+		},
+	}
+
+	exp := &types.Experiment{
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec: &v1.ExperimentSpec{
+			BaseDirF:  baseDir,
+			TopologyF: &v1.TopologySpec{NodesF: nodes},
+			ScenarioF: scenario,
+		},
+		Status: &v1.ExperimentStatus{},
+	}
+
+	if err := new(Callback).PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var status Status
+
+	if err := exp.Status.ParseAppStatus("callback", &status); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for name, dst := range map[string]string{"linux": "/etc/phenix/callback.json", "windows": "/phenix/callback.json"} {
+		body, err := os.ReadFile(baseDir + "/callback/" + name + ".json")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		var c config
+
+		if err := json.Unmarshal(body, &c); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if c.URL != "http://172.16.0.254:3001" || c.Experiment != "test" || c.VM != name {
+			t.Logf("unexpected callback config for %s: %+v", name, c)
+			t.FailNow()
+		}
+
+		if status.VMs[name].TokenHash != hash(c.Token) {
+			t.Logf("expected status to contain hash of token minted for %s", name)
+			t.FailNow()
+		}
+
+		node := exp.Spec.Topology().FindNodeByName(name)
+
+		if injects := node.Injections(); len(injects) != 1 || injects[0].Dst() != dst {
+			t.Logf("expected callback config to be injected into %s at %s", name, dst)
+			t.FailNow()
+		}
+	}
+
+	secrets, err := hostSecrets(exp, "linux")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if secrets["db"] != "hunter2" {
+		t.Logf("expected db secret for linux host, got %v", secrets)
+		t.FailNow()
+	}
+
+	if _, err := hostSecrets(exp, "windows"); err != ErrSecretNotFound {
+		t.Logf("expected ErrSecretNotFound for windows host, got %v", err)
+		t.FailNow()
+	}
+}
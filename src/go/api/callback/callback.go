@@ -0,0 +1,189 @@
+package callback
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/app"
+	"phenix/store"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid callback token")
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+// Lookup returns the names of the running experiment and VM the given callback
+// token was minted for.
+func Lookup(token string) (string, string, error) {
+	if token == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	exps, err := experiment.List()
+	if err != nil {
+		return "", "", fmt.Errorf("getting list of experiments: %w", err)
+	}
+
+	h := []byte(hash(token))
+
+	for _, exp := range exps {
+		if !exp.Running() {
+			continue
+		}
+
+		var status Status
+
+		if err := exp.Status.ParseAppStatus("callback", &status); err != nil {
+			continue
+		}
+
+		for vm, s := range status.VMs {
+			if subtle.ConstantTimeCompare(h, []byte(s.TokenHash)) == 1 {
+				return exp.Metadata.Name, vm, nil
+			}
+		}
+	}
+
+	return "", "", ErrInvalidToken
+}
+
+// readyMu serializes recording VMs as ready so VMs reporting ready at the same
+// time (ie. at boot) don't overwrite each other's updates.
+var readyMu sync.Mutex
+
+// Ready records the given VM in the given experiment as ready, along with an
+// optional message from the guest. Only the VM's entry in the callback status
+// is updated in the store, so status written by running apps isn't clobbered.
+func Ready(expName, vm, message string) error {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+
+	c, _ := store.NewConfig("experiment/" + expName)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	var (
+		apps, _   = c.Status["apps"].(map[string]any)
+		status, _ = apps["callback"].(map[string]any)
+		vms, _    = status["vms"].(map[string]any)
+	)
+
+	s, ok := vms[vm].(map[string]any)
+	if !ok {
+		return ErrInvalidToken
+	}
+
+	s["ready"] = time.Now().UTC().Format(time.RFC3339)
+	s["message"] = message
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("writing callback status to store: %w", err)
+	}
+
+	return nil
+}
+
+// Node returns the topology node for the given VM in the given experiment.
+func Node(expName, vm string) (ifaces.NodeSpec, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vm)
+	if node == nil {
+		return nil, fmt.Errorf("VM %s not found in experiment %s", vm, expName)
+	}
+
+	return node, nil
+}
+
+// GuestInfo returns the phenix identity of the given VM in the given
+// experiment, as injected into the VM by the guestinfo app.
+func GuestInfo(expName, vm string) (app.GuestInfo, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return app.GuestInfo{}, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vm)
+	if node == nil {
+		return app.GuestInfo{}, fmt.Errorf("VM %s not found in experiment %s", vm, expName)
+	}
+
+	return app.NodeGuestInfo(exp, node)
+}
+
+// Secret returns the value of the named secret configured for the given VM in
+// the callback app metadata of the given experiment.
+func Secret(expName, vm, name string) (string, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return "", fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	secrets, err := hostSecrets(exp, vm)
+	if err != nil {
+		return "", err
+	}
+
+	secret, ok := secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return secret, nil
+}
+
+func hostSecrets(exp *types.Experiment, vm string) (map[string]string, error) {
+	a := exp.App("callback")
+	if a == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	for _, host := range a.Hosts() {
+		if host.Hostname() != vm {
+			continue
+		}
+
+		var md HostMetadata
+
+		if err := mapstructure.Decode(host.Metadata(), &md); err != nil {
+			return nil, fmt.Errorf("decoding callback app metadata for host %s: %w", vm, err)
+		}
+
+		return md.Secrets, nil
+	}
+
+	return nil, ErrSecretNotFound
+}
+
+// mint returns a new random callback token.
+func mint() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
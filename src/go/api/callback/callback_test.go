@@ -0,0 +1,103 @@
+package callback
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"phenix/api/experiment"
+	"phenix/store"
+)
+
+func TestReady(t *testing.T) {
+	prev := store.DefaultStore
+	defer func() { store.DefaultStore = prev }()
+
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	vms := make(map[string]any)
+
+	for i := 0; i < 20; i++ {
+		vms[fmt.Sprintf("vm-%d", i)] = map[string]any{"tokenHash": hash(fmt.Sprintf("token-%d", i))}
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec: map[string]any{
+			"experimentName": "test",
+			"topology":       map[string]any{"nodes": []any{}},
+		},
+		Status: map[string]any{
+			"apps": map[string]any{
+				"callback": map[string]any{"vms": vms},
+				"soh":      map[string]any{"state": "healthy"},
+			},
+		},
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// VMs reporting ready at the same time don't overwrite each other's marks.
+	var (
+		wg   sync.WaitGroup
+		errs = make(chan error, len(vms))
+	)
+
+	for vm := range vms {
+		wg.Add(1)
+
+		go func(vm string) {
+			defer wg.Done()
+			errs <- Ready("test", vm, "booted")
+		}(vm)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	exp, err := experiment.Get("test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var status Status
+
+	if err := exp.Status.ParseAppStatus("callback", &status); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for vm, s := range status.VMs {
+		if s.Ready == "" || s.Message != "booted" || s.TokenHash == "" {
+			t.Logf("expected %s to be marked ready, got %+v", vm, s)
+			t.FailNow()
+		}
+	}
+
+	if _, ok := exp.Status.AppStatus()["soh"]; !ok {
+		t.Log("expected status of other apps to be kept")
+		t.FailNow()
+	}
+
+	if err := Ready("test", "missing", ""); err != ErrInvalidToken {
+		t.Logf("expected ErrInvalidToken for unknown VM, got %v", err)
+		t.FailNow()
+	}
+}
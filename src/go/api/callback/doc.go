@@ -0,0 +1,4 @@
+// Implementation of the phenix guest callback API, used by in-guest scripts to
+// report readiness, request their node metadata or guest info, or fetch injected
+// secrets.
+package callback
@@ -0,0 +1,35 @@
+package callback
+
+// Metadata is the callback scenario app metadata. URL is the base URL of the
+// phenix guest callback endpoint as reachable from guests (typically via the
+// management network), ie. `http://172.16.0.254:3001`.
+type Metadata struct {
+	URL string `mapstructure:"url"`
+}
+
+// HostMetadata is the callback scenario app metadata for a single host.
+// Secrets are only served to the VM they're configured for.
+type HostMetadata struct {
+	Secrets map[string]string `mapstructure:"secrets"`
+}
+
+// Status is the callback app status, keyed by VM name.
+type Status struct {
+	VMs map[string]VMStatus `structs:"vms" mapstructure:"vms"`
+}
+
+// VMStatus is the callback status of a single VM. Only a hash of the VM's
+// token is stored so tokens aren't exposed via the experiment status.
+type VMStatus struct {
+	TokenHash string `structs:"tokenHash" mapstructure:"tokenHash"`
+	Ready     string `structs:"ready" mapstructure:"ready"`
+	Message   string `structs:"message" mapstructure:"message"`
+}
+
+// config is the callback config injected into each VM.
+type config struct {
+	URL        string `json:"url"`
+	Token      string `json:"token"`
+	Experiment string `json:"experiment"`
+	VM         string `json:"vm"`
+}
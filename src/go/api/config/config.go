@@ -21,7 +21,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var AllKinds = []string{"Topology", "Scenario", "Experiment", "Image", "User", "Role"}
+var AllKinds = []string{"Topology", "Scenario", "Experiment", "Image", "User", "Role", "Template", "App", "Service", "Appliance", "Overlay", "Site", "View"}
 
 var NameRegex = regexp.MustCompile(`^[a-zA-Z0-9_@.-]*$`)
 
@@ -201,6 +201,20 @@ func List(which string) (store.Configs, error) {
 		configs, err = store.List("User")
 	case "role":
 		configs, err = store.List("Role")
+	case "template":
+		configs, err = store.List("Template")
+	case "app":
+		configs, err = store.List("App")
+	case "service":
+		configs, err = store.List("Service")
+	case "appliance":
+		configs, err = store.List("Appliance")
+	case "overlay":
+		configs, err = store.List("Overlay")
+	case "site":
+		configs, err = store.List("Site")
+	case "view":
+		configs, err = store.List("View")
 	default:
 		return nil, util.HumanizeError(fmt.Errorf("unknown config kind provided: %s", which), "")
 	}
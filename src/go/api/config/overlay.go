@@ -0,0 +1,235 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// EnvironmentAnnotation is the annotation used to record the environment a
+// rendered config (or an experiment created from rendered configs) had
+// overlays applied for.
+const EnvironmentAnnotation = "phenix/environment"
+
+// Overlays returns the overlays for the given base config (ie.
+// `topology/range`) and environment, sorted by name, which is the order they're
+// applied in. If the environment is empty, overlays for all environments are
+// returned.
+func Overlays(base, env string) ([]types.Overlay, error) {
+	configs, err := store.List("Overlay")
+	if err != nil {
+		return nil, fmt.Errorf("getting list of overlay configs from store: %w", err)
+	}
+
+	var overlays []types.Overlay
+
+	for _, c := range configs {
+		spec := new(v1.OverlaySpec)
+
+		if err := mapstructure.Decode(c.Spec, spec); err != nil {
+			return nil, fmt.Errorf("decoding overlay spec for %s: %w", c.Metadata.Name, err)
+		}
+
+		if !strings.EqualFold(spec.Base, base) {
+			continue
+		}
+
+		if env != "" && spec.Environment != env {
+			continue
+		}
+
+		overlays = append(overlays, types.Overlay{Metadata: c.Metadata, Spec: spec})
+	}
+
+	sort.Slice(overlays, func(i, j int) bool {
+		return overlays[i].Metadata.Name < overlays[j].Metadata.Name
+	})
+
+	return overlays, nil
+}
+
+// Render returns the config with the given name (ie. `topology/range`) with the
+// overlays for the given environment applied (see `ApplyOverlays`). If the
+// environment is empty, the base config is returned as-is.
+func Render(name, env string) (*store.Config, error) {
+	c, err := store.NewConfig(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting config from store: %w", err)
+	}
+
+	if env == "" {
+		return c, nil
+	}
+
+	return ApplyOverlays(c, env)
+}
+
+// ApplyOverlays returns a copy of the given config with the patches of its
+// overlays for the given environment merged into its spec, in order. The merged
+// spec is validated, and the environment is recorded in the copy's
+// `phenix/environment` annotation. The given config is not modified.
+//
+// Patches are merged as follows: maps are merged recursively, with null values
+// removing keys from the base map; lists of maps are merged element by element,
+// matching elements by their `name`, `hostname`, or `general.hostname` key, with
+// unmatched elements appended and elements with `$delete: true` removed; and
+// any other value replaces the base value.
+func ApplyOverlays(c *store.Config, env string) (*store.Config, error) {
+	name := strings.ToLower(c.Kind) + "/" + c.Metadata.Name
+
+	overlays, err := Overlays(name, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec any
+
+	if err := normalize(c.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("normalizing %s spec: %w", name, err)
+	}
+
+	for _, o := range overlays {
+		var patch any
+
+		if err := normalize(o.Spec.Patch, &patch); err != nil {
+			return nil, fmt.Errorf("normalizing overlay %s patch: %w", o.Metadata.Name, err)
+		}
+
+		spec = merge(spec, patch)
+	}
+
+	rendered := *c
+
+	rendered.Spec, _ = spec.(map[string]any)
+	rendered.Metadata.Annotations = make(map[string]string)
+
+	for k, v := range c.Metadata.Annotations {
+		rendered.Metadata.Annotations[k] = v
+	}
+
+	rendered.Metadata.Annotations[EnvironmentAnnotation] = env
+
+	if err := types.ValidateConfigSpec(rendered); err != nil {
+		return nil, fmt.Errorf("validating %s with %s overlays applied: %w", name, env, err)
+	}
+
+	return &rendered, nil
+}
+
+// normalize converts the given value to generic JSON types so specs decoded
+// from YAML, JSON, or structs can be merged the same way.
+func normalize(v any, out *any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+func merge(base, patch any) any {
+	switch p := patch.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		if !ok {
+			b = make(map[string]any)
+		}
+
+		merged := make(map[string]any, len(b))
+
+		for k, v := range b {
+			merged[k] = v
+		}
+
+		for k, v := range p {
+			if v == nil {
+				delete(merged, k)
+				continue
+			}
+
+			merged[k] = merge(merged[k], v)
+		}
+
+		return merged
+	case []any:
+		b, ok := base.([]any)
+		if !ok || !keyed(b) || !keyed(p) {
+			return p
+		}
+
+		merged := append([]any(nil), b...)
+
+		for _, v := range p {
+			key := mergeKey(v)
+
+			idx := -1
+
+			for i, e := range merged {
+				if mergeKey(e) == key {
+					idx = i
+					break
+				}
+			}
+
+			del, _ := v.(map[string]any)["$delete"].(bool)
+
+			switch {
+			case del && idx >= 0:
+				merged = append(merged[:idx], merged[idx+1:]...)
+			case del:
+			case idx >= 0:
+				merged[idx] = merge(merged[idx], v)
+			default:
+				merged = append(merged, v)
+			}
+		}
+
+		return merged
+	}
+
+	return patch
+}
+
+// keyed returns true if every element of the given list is a map with a merge
+// key.
+func keyed(list []any) bool {
+	for _, v := range list {
+		if mergeKey(v) == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mergeKey(v any) string {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return ""
+	}
+
+	for _, k := range []string{"name", "hostname"} {
+		if s, ok := m[k].(string); ok && s != "" {
+			return k + "=" + s
+		}
+	}
+
+	if g, ok := m["general"].(map[string]any); ok {
+		if s, ok := g["hostname"].(string); ok && s != "" {
+			return "general.hostname=" + s
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	var (
+		base, patch, expected any
+	)
+
+	json.Unmarshal([]byte(`{
+		"nodes": [
+			{"general": {"hostname": "web", "vm_type": "kvm"}, "hardware": {"memory": 1024}},
+			{"general": {"hostname": "db"}, "hardware": {"memory": 2048}},
+			{"general": {"hostname": "scratch"}}
+		],
+		"vlans": {"aliases": {"EXP": 100}},
+		"tags": ["a", "b"],
+		"debug": true
+	}`), &base)
+
+	json.Unmarshal([]byte(`{
+		"nodes": [
+			{"general": {"hostname": "web"}, "hardware": {"memory": 4096}},
+			{"general": {"hostname": "scratch"}, "$delete": true},
+			{"general": {"hostname": "cache"}}
+		],
+		"vlans": {"aliases": {"EXP": 200, "MGMT": 300}},
+		"tags": ["c"],
+		"debug": null
+	}`), &patch)
+
+	json.Unmarshal([]byte(`{
+		"nodes": [
+			{"general": {"hostname": "web", "vm_type": "kvm"}, "hardware": {"memory": 4096}},
+			{"general": {"hostname": "db"}, "hardware": {"memory": 2048}},
+			{"general": {"hostname": "cache"}}
+		],
+		"vlans": {"aliases": {"EXP": 200, "MGMT": 300}},
+		"tags": ["c"]
+	}`), &expected)
+
+	merged := merge(base, patch)
+
+	if !reflect.DeepEqual(merged, expected) {
+		m, _ := json.Marshal(merged)
+		t.Logf("unexpected merged spec: %s", m)
+		t.FailNow()
+	}
+
+	// The base spec must not be modified by the merge.
+	if nodes := base.(map[string]any)["nodes"].([]any); len(nodes) != 3 {
+		t.Log("base spec was modified by merge")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"phenix/store"
+
+	"inet.af/netaddr"
+)
+
+// SearchQuery is a query used to search stored configs. All of the provided
+// fields must match for a config to be included in search results.
+type SearchQuery struct {
+	// Text is matched (case-insensitive) against the name, annotations, and spec
+	// of configs. Each whitespace separated term must be present.
+	Text string `json:"text,omitempty"`
+
+	// Kind limits results to configs of the given kind (ie. `topology`).
+	Kind string `json:"kind,omitempty"`
+
+	// Annotations limits results to configs with the given annotations. An empty
+	// value matches any config with the annotation key present.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// IP limits results to topologies and experiments with a node interface
+	// containing the given address or overlapping the given subnet.
+	IP string `json:"ip,omitempty"`
+
+	// App limits results to scenarios and experiments that include the given
+	// app.
+	App string `json:"app,omitempty"`
+
+	// AppKey limits results to scenarios and experiments with an app using the
+	// given metadata key, either app-wide or for a host.
+	AppKey string `json:"appKey,omitempty"`
+}
+
+// ParseSearchQuery parses a search query string consisting of free text terms
+// and `field:value` terms, ie. `kind:topology ip:10.1.0.0/24 server`. Valid
+// fields are `kind`, `annotation` (as `key=value` or `key`), `ip`, `app`, and
+// `app-key`. Terms with unknown fields are treated as free text.
+func ParseSearchQuery(q string) SearchQuery {
+	var (
+		query SearchQuery
+		text  []string
+	)
+
+	for _, term := range strings.Fields(q) {
+		field, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			text = append(text, term)
+			continue
+		}
+
+		switch strings.ToLower(field) {
+		case "kind":
+			query.Kind = value
+		case "annotation":
+			if query.Annotations == nil {
+				query.Annotations = make(map[string]string)
+			}
+
+			key, val, _ := strings.Cut(value, "=")
+			query.Annotations[key] = val
+		case "ip":
+			query.IP = value
+		case "app":
+			query.App = value
+		case "app-key":
+			query.AppKey = value
+		default:
+			text = append(text, term)
+		}
+	}
+
+	query.Text = strings.Join(text, " ")
+
+	return query
+}
+
+// Search returns the stored configs matching the given query.
+func Search(query SearchQuery) (store.Configs, error) {
+	kind := query.Kind
+	if kind == "" {
+		kind = "all"
+	}
+
+	var prefix netaddr.IPPrefix
+
+	if query.IP != "" {
+		var err error
+
+		if prefix, err = parseSearchPrefix(query.IP); err != nil {
+			return nil, fmt.Errorf("parsing IP %s: %w", query.IP, err)
+		}
+	}
+
+	configs, err := List(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches store.Configs
+
+	for _, c := range configs {
+		if !matchAnnotations(c, query.Annotations) {
+			continue
+		}
+
+		if !prefix.IsZero() && !matchNodeIP(c, prefix) {
+			continue
+		}
+
+		if query.App != "" || query.AppKey != "" {
+			if !matchApps(c, query.App, query.AppKey) {
+				continue
+			}
+		}
+
+		if query.Text != "" && !matchText(c, query.Text) {
+			continue
+		}
+
+		matches = append(matches, c)
+	}
+
+	return matches, nil
+}
+
+func parseSearchPrefix(ip string) (netaddr.IPPrefix, error) {
+	if strings.Contains(ip, "/") {
+		prefix, err := netaddr.ParseIPPrefix(ip)
+		if err != nil {
+			return netaddr.IPPrefix{}, err
+		}
+
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return netaddr.IPPrefix{}, err
+	}
+
+	return netaddr.IPPrefixFrom(addr, addr.BitLen()), nil
+}
+
+func matchAnnotations(c store.Config, annotations map[string]string) bool {
+	for k, v := range annotations {
+		actual, ok := c.Metadata.Annotations[k]
+		if !ok {
+			return false
+		}
+
+		if v != "" && v != actual {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchText(c store.Config, text string) bool {
+	body, err := json.Marshal(struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+		Spec        map[string]any    `json:"spec"`
+	}{c.Metadata.Name, c.Metadata.Annotations, c.Spec})
+
+	if err != nil {
+		return false
+	}
+
+	haystack := strings.ToLower(string(body))
+
+	for _, term := range strings.Fields(strings.ToLower(text)) {
+		if !strings.Contains(haystack, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchNodeIP checks if any interface of the topology nodes in the given config
+// (either a topology or an experiment) overlaps with the given prefix.
+func matchNodeIP(c store.Config, prefix netaddr.IPPrefix) bool {
+	spec := c.Spec
+
+	if c.Kind == "Experiment" {
+		spec, _ = spec["topology"].(map[string]any)
+	} else if c.Kind != "Topology" {
+		return false
+	}
+
+	nodes, _ := spec["nodes"].([]any)
+
+	for _, n := range nodes {
+		node, _ := n.(map[string]any)
+		network, _ := node["network"].(map[string]any)
+		ifaces, _ := network["interfaces"].([]any)
+
+		for _, i := range ifaces {
+			iface, _ := i.(map[string]any)
+
+			addr, err := netaddr.ParseIP(fmt.Sprint(iface["address"]))
+			if err != nil {
+				continue
+			}
+
+			bits := addr.BitLen()
+
+			if mask, ok := searchInt(iface["mask"]); ok && mask > 0 {
+				bits = uint8(mask)
+			}
+
+			if netaddr.IPPrefixFrom(addr, bits).Masked().Overlaps(prefix) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchApps checks if the scenario apps in the given config (either a scenario
+// or an experiment) include an app with the given name (if not empty) using
+// the given metadata key (if not empty).
+func matchApps(c store.Config, name, key string) bool {
+	spec := c.Spec
+
+	if c.Kind == "Experiment" {
+		spec, _ = spec["scenario"].(map[string]any)
+	} else if c.Kind != "Scenario" {
+		return false
+	}
+
+	apps, _ := spec["apps"].([]any)
+
+	for _, a := range apps {
+		app, _ := a.(map[string]any)
+
+		if name != "" && !strings.EqualFold(fmt.Sprint(app["name"]), name) {
+			continue
+		}
+
+		if key == "" {
+			return true
+		}
+
+		if md, ok := app["metadata"].(map[string]any); ok {
+			if _, ok := md[key]; ok {
+				return true
+			}
+		}
+
+		hosts, _ := app["hosts"].([]any)
+
+		for _, h := range hosts {
+			host, _ := h.(map[string]any)
+
+			if md, ok := host["metadata"].(map[string]any); ok {
+				if _, ok := md[key]; ok {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func searchInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"testing"
+
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	query := ParseSearchQuery("kind:topology annotation:owner=ops ip:10.1.0.0/24 app-key:dhcp substation foo:bar")
+
+	if query.Kind != "topology" || query.IP != "10.1.0.0/24" || query.AppKey != "dhcp" {
+		t.Logf("unexpected field terms in parsed query: %+v", query)
+		t.FailNow()
+	}
+
+	if query.Annotations["owner"] != "ops" {
+		t.Logf("expected owner annotation, got %v", query.Annotations)
+		t.FailNow()
+	}
+
+	if query.Text != "substation foo:bar" {
+		t.Logf("unexpected free text in parsed query: %s", query.Text)
+		t.FailNow()
+	}
+}
+
+func TestSearch(t *testing.T) {
+	configs := store.Configs{
+		{
+			Kind:     "Topology",
+			Metadata: store.ConfigMetadata{Name: "substation", Annotations: map[string]string{"owner": "ops"}},
+			Spec: map[string]any{
+				"nodes": []any{
+					map[string]any{
+						"general": map[string]any{"hostname": "rtu"},
+						"network": map[string]any{
+							"interfaces": []any{
+								map[string]any{"name": "IF0", "address": "10.1.0.10", "mask": float64(24)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Kind:     "Topology",
+			Metadata: store.ConfigMetadata{Name: "enterprise"},
+			Spec: map[string]any{
+				"nodes": []any{
+					map[string]any{
+						"general": map[string]any{"hostname": "dc"},
+						"network": map[string]any{
+							"interfaces": []any{
+								map[string]any{"name": "IF0", "address": "192.168.1.10", "mask": float64(24)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Kind:     "Scenario",
+			Metadata: store.ConfigMetadata{Name: "routing"},
+			Spec: map[string]any{
+				"apps": []any{
+					map[string]any{
+						"name": "vrouter",
+						"hosts": []any{
+							map[string]any{"hostname": "rtr", "metadata": map[string]any{"dhcp": []any{}}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := store.NewMockStore(ctrl)
+	m.EXPECT().List(gomock.Any()).Return(configs, nil).AnyTimes()
+
+	store.DefaultStore = m
+
+	tests := map[string]string{
+		"ip:10.1.0.1":               "substation",
+		"ip:192.168.0.0/16":         "enterprise",
+		"annotation:owner":          "substation",
+		"app:vrouter app-key:dhcp":  "routing",
+		"DC":                        "enterprise",
+		"kind:scenario dhcp":        "routing",
+		"ip:10.1.0.1 annotation:x":  "",
+		"app:vrouter app-key:relay": "",
+	}
+
+	for q, expected := range tests {
+		matches, err := Search(ParseSearchQuery(q))
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if expected == "" {
+			if len(matches) != 0 {
+				t.Logf("expected no matches for %q, got %d", q, len(matches))
+				t.FailNow()
+			}
+
+			continue
+		}
+
+		if len(matches) != 1 || matches[0].Metadata.Name != expected {
+			t.Logf("expected %q to only match %s, got %v", q, expected, matches)
+			t.FailNow()
+		}
+	}
+
+	if _, err := Search(SearchQuery{IP: "not-an-ip"}); err == nil {
+		t.Log("expected error for invalid IP")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,133 @@
+package experiment
+
+import (
+	"fmt"
+	"sort"
+
+	"phenix/types"
+	"phenix/util/file"
+)
+
+// ARTIFACT_CATEGORY is the file category given to experiment files registered
+// as app artifacts, in addition to the name of the app that registered them.
+const ARTIFACT_CATEGORY = "App Artifact"
+
+// Artifact is a file registered by an app as an artifact of an experiment,
+// along with the details of the file in the experiment files service.
+type Artifact struct {
+	App         string `json:"app"`
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	MIMEType    string `json:"mimeType"`
+	Description string `json:"description,omitempty"`
+	Created     string `json:"created"`
+	Exists      bool   `json:"exists"`
+	Size        int64  `json:"size"`
+}
+
+// Artifacts returns the artifacts registered by apps for the given experiment,
+// sorted by app and then name. Artifacts whose files no longer exist (or don't
+// exist yet) are included, but marked as such.
+func Artifacts(name string) ([]Artifact, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	files, err := file.GetExperimentFiles(name, "")
+	if err != nil {
+		return nil, fmt.Errorf("getting list of experiment files: %w", err)
+	}
+
+	return artifacts(exp, files), nil
+}
+
+// ArtifactMIMEType returns the MIME type of the artifact registered for the
+// given experiment at the given path, or an empty string if the path isn't a
+// registered artifact.
+func ArtifactMIMEType(name, path string) string {
+	exp, err := Get(name)
+	if err != nil {
+		return ""
+	}
+
+	for _, arts := range exp.Status.AppArtifacts() {
+		for _, a := range arts {
+			if a.Path() == path {
+				return a.MIMEType()
+			}
+		}
+	}
+
+	return ""
+}
+
+func artifacts(exp *types.Experiment, files file.Files) []Artifact {
+	var (
+		byPath    = make(map[string]file.File)
+		artifacts []Artifact
+	)
+
+	for _, f := range files {
+		byPath[f.Path] = f
+	}
+
+	for app, arts := range exp.Status.AppArtifacts() {
+		for _, a := range arts {
+			artifact := Artifact{
+				App:         app,
+				Name:        a.Name(),
+				Path:        a.Path(),
+				MIMEType:    a.MIMEType(),
+				Description: a.Description(),
+				Created:     a.Created(),
+			}
+
+			if f, ok := byPath[a.Path()]; ok {
+				artifact.Exists = true
+				artifact.Size = f.Size
+			}
+
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].App == artifacts[j].App {
+			return artifacts[i].Name < artifacts[j].Name
+		}
+
+		return artifacts[i].App < artifacts[j].App
+	})
+
+	return artifacts
+}
+
+// categorizeArtifacts adds the artifact category, and the name of the app that
+// registered it, to each of the given files registered as an artifact.
+func categorizeArtifacts(exp *types.Experiment, files file.Files) {
+	apps := make(map[string]string)
+
+	for app, arts := range exp.Status.AppArtifacts() {
+		for _, a := range arts {
+			apps[a.Path()] = app
+		}
+	}
+
+	for i, f := range files {
+		app, ok := apps[f.Path]
+		if !ok {
+			continue
+		}
+
+		var categories []string
+
+		for _, c := range f.Categories {
+			if c != "Unknown" {
+				categories = append(categories, c)
+			}
+		}
+
+		files[i].Categories = append(categories, ARTIFACT_CATEGORY, app)
+	}
+}
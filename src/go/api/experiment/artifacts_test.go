@@ -0,0 +1,47 @@
+package experiment
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/file"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestArtifacts(t *testing.T) {
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+
+	exp.Status.SetAppArtifact("soh", &v1.Artifact{NameF: "report", PathF: "soh/report.html", MIMETypeF: "text/html"})
+	exp.Status.SetAppArtifact("dhcp", &v1.Artifact{NameF: "leases", PathF: "dhcp/leases.json", MIMETypeF: "application/json"})
+
+	files := file.Files{
+		{Name: "report.html", Path: "soh/report.html", Size: 42, Categories: []string{"Unknown"}},
+		{Name: "capture.pcap", Path: "capture.pcap", Categories: []string{"Packet Capture"}},
+	}
+
+	arts := artifacts(exp, files)
+
+	if len(arts) != 2 || arts[0].App != "dhcp" || arts[1].App != "soh" {
+		t.Logf("expected artifacts sorted by app, got %+v", arts)
+		t.FailNow()
+	}
+
+	if arts[0].Exists || !arts[1].Exists || arts[1].Size != 42 {
+		t.Logf("unexpected artifact file details: %+v", arts)
+		t.FailNow()
+	}
+
+	categorizeArtifacts(exp, files)
+
+	if c := files[0].Categories; len(c) != 2 || c[0] != ARTIFACT_CATEGORY || c[1] != "soh" {
+		t.Logf("unexpected artifact categories: %v", c)
+		t.FailNow()
+	}
+
+	if c := files[1].Categories; len(c) != 1 || c[0] != "Packet Capture" {
+		t.Logf("unexpected categories for non-artifact: %v", c)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,206 @@
+package experiment
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// ParseExternalBridge parses an external bridge provided as
+// `<name>[:<alias>=<id>,...][:no-destroy]`, ie.
+// `labfabric:SITE_MGMT=310,SITE_DATA=311:no-destroy`, returning the bridge
+// name, its externally-managed VLANs, and whether it should never be deleted.
+func ParseExternalBridge(s string) (string, map[string]int, bool, error) {
+	var (
+		parts     = strings.Split(strings.TrimSpace(s), ":")
+		name      = parts[0]
+		vlans     = make(map[string]int)
+		noDestroy bool
+	)
+
+	if name == "" {
+		return "", nil, false, fmt.Errorf("invalid external bridge %s (expected <name>[:<alias>=<id>,...][:no-destroy])", s)
+	}
+
+	for _, part := range parts[1:] {
+		if part == "no-destroy" {
+			noDestroy = true
+			continue
+		}
+
+		for _, vlan := range strings.Split(part, ",") {
+			alias, val, ok := strings.Cut(vlan, "=")
+			if !ok || alias == "" {
+				return "", nil, false, fmt.Errorf("invalid VLAN %s for external bridge %s (expected <alias>=<id>)", vlan, name)
+			}
+
+			id, err := strconv.Atoi(val)
+			if err != nil {
+				return "", nil, false, fmt.Errorf("invalid VLAN ID %s for external bridge %s", val, name)
+			}
+
+			vlans[alias] = id
+		}
+	}
+
+	return name, vlans, noDestroy, nil
+}
+
+// validateExternalBridges ensures the external bridges for the given
+// experiment spec have valid names, and that their externally-managed VLANs
+// don't conflict with each other or with VLANs managed by phenix.
+func validateExternalBridges(spec ifaces.ExperimentSpec) error {
+	var (
+		names    = make(map[string]struct{})
+		external = make(map[string]int)
+		vlans    = spec.VLANs()
+	)
+
+	for _, b := range spec.ExternalBridges() {
+		if b.Name() == "" {
+			return fmt.Errorf("external bridge name is required")
+		}
+
+		if len(b.Name()) > 15 {
+			return fmt.Errorf("external bridge name %s must be 15 characters or less", b.Name())
+		}
+
+		if _, ok := names[b.Name()]; ok {
+			return fmt.Errorf("external bridge %s declared more than once", b.Name())
+		}
+
+		names[b.Name()] = struct{}{}
+
+		for alias, id := range b.VLANs() {
+			if id < 1 || id > 4094 {
+				return fmt.Errorf("VLAN %s on external bridge %s has invalid VLAN ID %d", alias, b.Name(), id)
+			}
+
+			if other, ok := external[alias]; ok && other != id {
+				return fmt.Errorf("VLAN %s declared with different VLAN IDs (%d and %d) on external bridges", alias, other, id)
+			}
+
+			// VLAN IDs in the experiment's VLAN range can be allocated to VLANs
+			// managed by phenix.
+			if vlans.Min() != 0 && vlans.Max() != 0 && id >= vlans.Min() && id <= vlans.Max() {
+				return fmt.Errorf("VLAN %s on external bridge %s (VLAN ID %d) is in the experiment VLAN range %d-%d", alias, b.Name(), id, vlans.Min(), vlans.Max())
+			}
+
+			external[alias] = id
+		}
+	}
+
+	for alias, id := range vlans.Aliases() {
+		if _, ok := external[alias]; ok || id == 0 {
+			continue
+		}
+
+		for ext, extID := range external {
+			if id == extID {
+				return fmt.Errorf("VLAN %s (VLAN ID %d) uses the same VLAN ID as externally-managed VLAN %s", alias, id, ext)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkExternalBridges checks each external bridge for the given experiment
+// exists on each cluster host the experiment is scheduled on (or all
+// schedulable hosts if it isn't scheduled), since phenix never creates them.
+func checkExternalBridges(exp *types.Experiment) error {
+	bridges := exp.Spec.ExternalBridges()
+
+	if len(bridges) == 0 {
+		return nil
+	}
+
+	hosts, err := scheduledHosts(exp.Spec.Schedules())
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bridges {
+		for host := range hosts {
+			out, err := mm.MeshShellResponse(host, fmt.Sprintf(`bash -c "ovs-vsctl br-exists %s && echo exists"`, b.Name()))
+			if err != nil || out != "exists" {
+				return fmt.Errorf("external bridge %s doesn't exist on host %s", b.Name(), host)
+			}
+		}
+	}
+
+	return nil
+}
+
+// destroyExternalBridges deletes the external bridges for the given experiment
+// that aren't marked no-destroy from each cluster host the experiment was
+// running on. Bridges still used by other running experiments are left alone.
+func destroyExternalBridges(exp *types.Experiment) error {
+	var destroy []string
+
+	for _, b := range exp.Spec.ExternalBridges() {
+		if b.NoDestroy() {
+			plog.Info("leaving no-destroy external bridge in place", "exp", exp.Metadata.Name, "bridge", b.Name())
+			continue
+		}
+
+		if other := bridgeInUse(b.Name(), exp.Metadata.Name); other != "" {
+			plog.Info("leaving external bridge used by another experiment in place", "exp", exp.Metadata.Name, "bridge", b.Name(), "other", other)
+			continue
+		}
+
+		destroy = append(destroy, b.Name())
+	}
+
+	if len(destroy) == 0 {
+		return nil
+	}
+
+	hosts, err := scheduledHosts(exp.Status.Schedules())
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+
+	for _, name := range destroy {
+		for host := range hosts {
+			if err := mm.MeshShell(host, "ovs-vsctl --if-exists del-br "+name); err != nil {
+				errs = append(errs, fmt.Sprintf("deleting external bridge %s on host %s: %v", name, host, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// bridgeInUse returns the name of a running experiment, other than the given
+// experiment, using the given bridge, if any.
+func bridgeInUse(bridge, exp string) string {
+	exps, err := List()
+	if err != nil {
+		// Err on the side of not deleting a bridge that might be in use.
+		return "unknown"
+	}
+
+	for _, other := range exps {
+		if other.Metadata.Name == exp || !other.Running() {
+			continue
+		}
+
+		if other.Spec.DefaultBridge() == bridge || other.Spec.ExternalBridge(bridge) != nil {
+			return other.Metadata.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,72 @@
+package experiment
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestParseExternalBridge(t *testing.T) {
+	name, vlans, noDestroy, err := ParseExternalBridge("labfabric:SITE_MGMT=310,SITE_DATA=311:no-destroy")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if name != "labfabric" || !noDestroy {
+		t.Logf("unexpected external bridge %s (no-destroy %v)", name, noDestroy)
+		t.FailNow()
+	}
+
+	if len(vlans) != 2 || vlans["SITE_MGMT"] != 310 || vlans["SITE_DATA"] != 311 {
+		t.Logf("unexpected external bridge VLANs %v", vlans)
+		t.FailNow()
+	}
+
+	for _, bad := range []string{"", ":SITE=310", "labfabric:SITE", "labfabric:SITE=foo"} {
+		if _, _, _, err := ParseExternalBridge(bad); err == nil {
+			t.Logf("expected error parsing external bridge %s", bad)
+			t.FailNow()
+		}
+	}
+}
+
+func TestValidateExternalBridges(t *testing.T) {
+	newSpec := func(bridges ...*v1.ExternalBridge) *v1.ExperimentSpec {
+		spec := &v1.ExperimentSpec{
+			VLANsF:           &v1.VLANSpec{AliasesF: map[string]int{"EXP": 0}, MinF: 100, MaxF: 200},
+			ExternalBridgesF: bridges,
+		}
+
+		spec.Init()
+
+		return spec
+	}
+
+	fabric := &v1.ExternalBridge{NameF: "labfabric", VLANsF: map[string]int{"SITE_MGMT": 310}}
+
+	if err := validateExternalBridges(newSpec(fabric)); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if aliases := newSpec(fabric).VLANs().Aliases(); aliases["SITE_MGMT"] != 310 {
+		t.Logf("expected externally-managed VLAN alias to be set, got %v", aliases)
+		t.FailNow()
+	}
+
+	bad := map[string]*v1.ExperimentSpec{
+		"long name":     newSpec(&v1.ExternalBridge{NameF: "labfabric-uplink0"}),
+		"duplicate":     newSpec(fabric, &v1.ExternalBridge{NameF: "labfabric"}),
+		"in range":      newSpec(&v1.ExternalBridge{NameF: "labfabric", VLANsF: map[string]int{"SITE": 150}}),
+		"invalid ID":    newSpec(&v1.ExternalBridge{NameF: "labfabric", VLANsF: map[string]int{"SITE": 5000}}),
+		"different IDs": newSpec(fabric, &v1.ExternalBridge{NameF: "uplink", VLANsF: map[string]int{"SITE_MGMT": 311}}),
+	}
+
+	for name, spec := range bad {
+		if err := validateExternalBridges(spec); err == nil {
+			t.Logf("expected error validating external bridges (%s)", name)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package experiment
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"phenix/store"
+	"phenix/util/anonymize"
+	"phenix/util/common"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnonymizeKeyPath returns the path of the key file used by default to
+// anonymize experiment artifacts.
+func AnonymizeKeyPath() string {
+	return filepath.Join(common.PhenixBase, "anonymize.key")
+}
+
+// Anonymizer returns an anonymizer for artifacts of the given experiment using
+// the given key, remapping the hostnames of the experiment's nodes in addition
+// to IP and MAC addresses.
+func Anonymizer(name string, key []byte) (*anonymize.Anonymizer, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var hostnames []string
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		hostnames = append(hostnames, node.General().Hostname())
+	}
+
+	return anonymize.New(key, hostnames...), nil
+}
+
+// Bundle writes a gzipped tarball to the given writer containing the given
+// experiment's config, its state of health data (if any), and its files (ie.
+// packet captures). If an anonymizer is provided, all bundle contents are
+// anonymized and files that can't be anonymized are left out of the bundle.
+// It returns the paths of any files left out.
+func Bundle(name string, w io.Writer, opts ...BundleOption) ([]string, error) {
+	o := newBundleOptions(opts...)
+
+	c, err := store.NewConfig("experiment/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment: %w", err)
+	}
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	add := func(path string, data []byte) error {
+		if o.anonymizer != nil {
+			var err error
+
+			if data, err = o.anonymizer.File(path, data); err != nil {
+				return err
+			}
+		}
+
+		header := &tar.Header{
+			Name:    filepath.Join(name, path),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("writing bundle header for %s: %w", path, err)
+		}
+
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("writing %s to bundle: %w", path, err)
+		}
+
+		return nil
+	}
+
+	body, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling experiment config: %w", err)
+	}
+
+	if err := add("experiment.yml", body); err != nil {
+		return nil, err
+	}
+
+	apps, _ := c.Status["apps"].(map[string]any)
+
+	if soh, ok := apps["soh"]; ok {
+		body, _ := json.MarshalIndent(soh, "", "  ")
+
+		if err := add("soh.json", body); err != nil {
+			return nil, err
+		}
+	}
+
+	var skipped []string
+
+	if o.files {
+		files, err := Files(name, "")
+		if err != nil {
+			return nil, fmt.Errorf("getting list of experiment files: %w", err)
+		}
+
+		for _, f := range files {
+			if f.IsDir {
+				continue
+			}
+
+			data, err := File(name, f.Path)
+			if err != nil {
+				// Captures still in progress can't be included.
+				skipped = append(skipped, f.Path)
+				continue
+			}
+
+			if err := add(filepath.Join("files", f.Path), data); err != nil {
+				if errors.Is(err, anonymize.ErrUnsupportedFile) || errors.Is(err, anonymize.ErrUnsupportedPCAP) {
+					skipped = append(skipped, f.Path)
+					continue
+				}
+
+				return nil, err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("closing bundle archive: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("closing bundle archive: %w", err)
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,259 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/notes"
+	"phenix/util/plog"
+)
+
+// ConcurrencyPollInterval is how often a queued start checks whether the
+// concurrency groups it's waiting on are free.
+var ConcurrencyPollInterval = 10 * time.Second
+
+// ConcurrencyClaimTimeout is how long an experiment being started holds its
+// concurrency groups for before it has to be running. It keeps groups from
+// being held forever by a start that never finished (ie. phenix was killed).
+var ConcurrencyClaimTimeout = 1 * time.Hour
+
+var ErrConcurrencyConflict = errors.New("concurrency group conflict")
+
+// Experiments being started record a claim on their concurrency groups in their
+// status in the store (see `Starting`) so starts by other phenix processes see
+// it too. Claims made by this process are serialized by claimMu.
+var claimMu sync.Mutex
+
+// groupConflict is another experiment holding a concurrency group.
+type groupConflict struct {
+	group string
+	exp   string
+}
+
+func (this groupConflict) String() string {
+	return fmt.Sprintf("experiment %s holds concurrency group %s", this.exp, this.group)
+}
+
+// claimConcurrencyGroups claims the concurrency groups the given experiment is
+// a member of for the duration of its start, returning a function to release
+// them once it's running (or failed to start) and whether they were claimed.
+// The claim is recorded in the experiment's status in the store, so the
+// experiment should be read from the store again once they're claimed. If
+// another member of any of the groups is running or being started, an error
+// wrapping ErrConcurrencyConflict is returned, unless queue is true, in which
+// case it waits until the groups are free (or the context is canceled).
+func claimConcurrencyGroups(ctx context.Context, exp *types.Experiment, queue bool) (func(), bool, error) {
+	var (
+		name   = exp.Metadata.Name
+		groups = exp.Spec.ConcurrencyGroups()
+	)
+
+	if len(groups) == 0 {
+		return func() {}, false, nil
+	}
+
+	var waiting bool
+
+	for {
+		claim, conflicts, err := tryClaimConcurrencyGroups(name, groups)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(conflicts) == 0 {
+			release := func() {
+				if err := releaseClaim(name, claim); err != nil {
+					plog.Error("releasing concurrency groups", "exp", name, "err", err)
+				}
+			}
+
+			return release, true, nil
+		}
+
+		msgs := make([]string, len(conflicts))
+
+		for i, c := range conflicts {
+			msgs[i] = c.String()
+		}
+
+		msg := strings.Join(msgs, ", ")
+
+		if !queue {
+			return nil, false, fmt.Errorf("%w: %s (only one experiment per group can run at a time)", ErrConcurrencyConflict, msg)
+		}
+
+		if !waiting {
+			notes.AddInfo(ctx, false, "waiting for concurrency groups to be free: "+msg)
+			waiting = true
+		}
+
+		plog.Info("waiting for concurrency groups to be free", "exp", name, "conflicts", msg)
+
+		select {
+		case <-ctx.Done():
+			return nil, false, fmt.Errorf("waiting for concurrency groups: %w", ctx.Err())
+		case <-time.After(ConcurrencyPollInterval):
+		}
+	}
+}
+
+// tryClaimConcurrencyGroups claims the given concurrency groups for the
+// experiment with the given name if no other members are running or being
+// started, returning the claim recorded in the experiment's status, or the
+// conflicts if the groups couldn't be claimed.
+//
+// Claims by other phenix processes can be recorded at the same time, so the
+// claim is checked again once it's recorded. Of two experiments claiming the
+// same group at once, the one with the earlier claim keeps it.
+func tryClaimConcurrencyGroups(name string, groups []string) (string, []groupConflict, error) {
+	claimMu.Lock()
+	defer claimMu.Unlock()
+
+	now := time.Now()
+
+	if conflicts := concurrencyConflicts(name, groups, listForClaims(), now, ""); len(conflicts) > 0 {
+		return "", conflicts, nil
+	}
+
+	claim := now.UTC().Format(time.RFC3339Nano)
+
+	if err := recordClaim(name, claim); err != nil {
+		return "", nil, fmt.Errorf("claiming concurrency groups: %w", err)
+	}
+
+	if conflicts := concurrencyConflicts(name, groups, listForClaims(), now, claim); len(conflicts) > 0 {
+		if err := releaseClaim(name, claim); err != nil {
+			plog.Error("withdrawing concurrency groups claim", "exp", name, "err", err)
+		}
+
+		return "", conflicts, nil
+	}
+
+	return claim, nil, nil
+}
+
+// listForClaims lists the experiments to check concurrency group claims
+// against. Experiments that can't be decoded are skipped, but the rest are
+// still returned.
+func listForClaims() []types.Experiment {
+	exps, err := List()
+	if err != nil {
+		plog.Warn("unable to get some experiments to check concurrency groups against", "err", err)
+	}
+
+	return exps
+}
+
+// concurrencyConflicts returns the other members (of the given experiments) of
+// the given concurrency groups that are running or being started as of now. If
+// claim is set, only members being started with an earlier claim conflict.
+func concurrencyConflicts(name string, groups []string, exps []types.Experiment, now time.Time, claim string) []groupConflict {
+	var conflicts []groupConflict
+
+	for _, g := range groups {
+		for _, other := range exps {
+			if other.Metadata.Name == name || !hasGroup(other.Spec.ConcurrencyGroups(), g) {
+				continue
+			}
+
+			if other.Running() || claimHeld(other, name, now, claim) {
+				conflicts = append(conflicts, groupConflict{group: g, exp: other.Metadata.Name})
+				break
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].group < conflicts[j].group })
+
+	return conflicts
+}
+
+// claimHeld returns true if the given experiment is being started per the
+// claim recorded in its status, and the claim hasn't timed out. If claim is
+// set, the experiment's claim has to precede it (claims made at the same time
+// are ordered by experiment name).
+func claimHeld(exp types.Experiment, name string, now time.Time, claim string) bool {
+	starting := exp.Status.Starting()
+
+	if starting == "" {
+		return false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, starting)
+	if err != nil || now.Sub(ts) > ConcurrencyClaimTimeout {
+		return false
+	}
+
+	if claim == "" {
+		return true
+	}
+
+	mine, _ := time.Parse(time.RFC3339Nano, claim)
+
+	if ts.Equal(mine) {
+		return exp.Metadata.Name < name
+	}
+
+	return ts.Before(mine)
+}
+
+// recordClaim records the given claim in the status of the experiment with the
+// given name in the store, replacing any claim (ie. one that timed out) already
+// recorded.
+func recordClaim(name, claim string) error {
+	return updateClaim(name, func(string) string { return claim })
+}
+
+// releaseClaim clears the given claim from the status of the experiment with
+// the given name in the store, unless it's since been replaced or cleared (ie.
+// once the experiment is running).
+func releaseClaim(name, claim string) error {
+	return updateClaim(name, func(current string) string {
+		if current == claim {
+			return ""
+		}
+
+		return current
+	})
+}
+
+func updateClaim(name string, update func(string) string) error {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	if c.Status == nil {
+		c.Status = make(map[string]any)
+	}
+
+	current, _ := c.Status["starting"].(string)
+
+	if claim := update(current); claim != current {
+		c.Status["starting"] = claim
+
+		if err := store.Update(c); err != nil {
+			return fmt.Errorf("updating experiment %s in store: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func hasGroup(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+)
+
+func TestConcurrencyConflicts(t *testing.T) {
+	now := time.Now()
+
+	newExp := func(name string, running bool, starting time.Time, groups ...string) types.Experiment {
+		exp := types.NewExperiment(store.ConfigMetadata{Name: name})
+		exp.Spec.SetConcurrencyGroups(groups)
+
+		if running {
+			exp.Status.SetStartTime(now.Format(time.RFC3339))
+		}
+
+		if !starting.IsZero() {
+			exp.Status.SetStarting(starting.UTC().Format(time.RFC3339Nano))
+		}
+
+		return *exp
+	}
+
+	exps := []types.Experiment{
+		newExp("rig-a", true, time.Time{}, "hil-rig"),
+		newExp("rig-b", false, time.Time{}, "hil-rig", "lab"),
+		newExp("other", true, time.Time{}, "lab"),
+	}
+
+	conflicts := concurrencyConflicts("rig-b", []string{"hil-rig", "lab"}, exps, now, "")
+	if len(conflicts) != 2 || conflicts[0].exp != "rig-a" || conflicts[1].exp != "other" {
+		t.Logf("expected conflicts with rig-a and other, got %v", conflicts)
+		t.FailNow()
+	}
+
+	// Stopped members don't conflict.
+	exps[0] = newExp("rig-a", false, time.Time{}, "hil-rig")
+
+	if conflicts := concurrencyConflicts("rig-b", []string{"hil-rig"}, exps, now, ""); len(conflicts) != 0 {
+		t.Logf("expected no conflicts, got %v", conflicts)
+		t.FailNow()
+	}
+
+	// Members being started (but not running yet) conflict, unless their claim
+	// timed out.
+	exps[1] = newExp("rig-b", false, now.Add(-1*time.Minute), "hil-rig", "lab")
+
+	conflicts = concurrencyConflicts("rig-a", []string{"hil-rig"}, exps, now, "")
+	if len(conflicts) != 1 || conflicts[0].exp != "rig-b" {
+		t.Logf("expected conflict with rig-b being started, got %v", conflicts)
+		t.FailNow()
+	}
+
+	exps[1] = newExp("rig-b", false, now.Add(-2*ConcurrencyClaimTimeout), "hil-rig", "lab")
+
+	if conflicts := concurrencyConflicts("rig-a", []string{"hil-rig"}, exps, now, ""); len(conflicts) != 0 {
+		t.Logf("expected timed out claim not to conflict, got %v", conflicts)
+		t.FailNow()
+	}
+
+	// Of two claims recorded at once, the earlier one is kept.
+	var (
+		earlier = now.Add(-1 * time.Second)
+		claim   = now.UTC().Format(time.RFC3339Nano)
+	)
+
+	exps[1] = newExp("rig-b", false, earlier, "hil-rig", "lab")
+
+	if conflicts := concurrencyConflicts("rig-a", []string{"hil-rig"}, exps, now, claim); len(conflicts) != 1 {
+		t.Logf("expected conflict with earlier claim, got %v", conflicts)
+		t.FailNow()
+	}
+
+	exps[1] = newExp("rig-b", false, now.Add(1*time.Second), "hil-rig", "lab")
+
+	if conflicts := concurrencyConflicts("rig-a", []string{"hil-rig"}, exps, now, claim); len(conflicts) != 0 {
+		t.Logf("expected later claim not to conflict, got %v", conflicts)
+		t.FailNow()
+	}
+}
+
+func TestClaimConcurrencyGroups(t *testing.T) {
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer func(interval time.Duration) { ConcurrencyPollInterval = interval }(ConcurrencyPollInterval)
+
+	ConcurrencyPollInterval = 10 * time.Millisecond
+
+	for _, name := range []string{"rig-a", "rig-b"} {
+		c := &store.Config{
+			Version:  "phenix.sandia.gov/v1",
+			Kind:     "Experiment",
+			Metadata: store.ConfigMetadata{Name: name},
+			Spec: map[string]any{
+				"experimentName":    name,
+				"topology":          map[string]any{"nodes": []any{}},
+				"concurrencyGroups": []any{"hil-rig"},
+			},
+		}
+
+		if err := store.Create(c); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	get := func(name string) *types.Experiment {
+		exp, err := Get(name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		return exp
+	}
+
+	release, claimed, err := claimConcurrencyGroups(context.Background(), get("rig-a"), false)
+	if err != nil || !claimed {
+		t.Logf("expected rig-a to claim its concurrency groups, got %v", err)
+		t.FailNow()
+	}
+
+	// The claim is recorded in the store so it's seen by other phenix processes.
+	if get("rig-a").Status.Starting() == "" {
+		t.Log("expected claim to be recorded in rig-a status")
+		t.FailNow()
+	}
+
+	if _, _, err := claimConcurrencyGroups(context.Background(), get("rig-b"), false); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Logf("expected concurrency conflict with rig-a being started, got %v", err)
+		t.FailNow()
+	}
+
+	done := make(chan error)
+
+	go func() {
+		release, _, err := claimConcurrencyGroups(context.Background(), get("rig-b"), true)
+		if err == nil {
+			release()
+		}
+
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	release()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	case <-time.After(5 * time.Second):
+		t.Log("expected queued claim to succeed once rig-a released its groups")
+		t.FailNow()
+	}
+
+	if get("rig-a").Status.Starting() != "" || get("rig-b").Status.Starting() != "" {
+		t.Log("expected claims to be cleared once released")
+		t.FailNow()
+	}
+}
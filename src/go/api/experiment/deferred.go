@@ -0,0 +1,254 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/scheduler"
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/journal"
+	"phenix/util/mm"
+	"phenix/util/perror"
+	"phenix/util/plog"
+)
+
+// DeferredPollInterval is how often cluster capacity is checked for VMs
+// deferred by a best-effort start.
+var DeferredPollInterval = 30 * time.Second
+
+// LaunchDeferred launches the VMs deferred by a best-effort start of the given
+// experiment (if any) as cluster capacity becomes available, in priority
+// order, in a Goroutine that will exit when the given context is canceled or
+// when there are no more deferred VMs.
+func LaunchDeferred(ctx context.Context, wg *sync.WaitGroup, exp *types.Experiment) error {
+	// The experiment provided may have been loaded before it was started, so get
+	// the latest status (including deferred VMs) from the store.
+	exp, err := Get(exp.Metadata.Name)
+	if err != nil {
+		return fmt.Errorf("getting experiment: %w", err)
+	}
+
+	if len(exp.Status.Deferred()) == 0 {
+		return nil
+	}
+
+	plog.Info("[✓] waiting for capacity to launch deferred VMs", "exp", exp.Metadata.Name, "vms", exp.Status.Deferred())
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(DeferredPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				remaining, err := launchDeferred(exp.Metadata.Name)
+				if err != nil {
+					plog.Error("launching deferred VMs", "exp", exp.Metadata.Name, "err", err)
+				}
+
+				if remaining == 0 {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// launchDeferred launches the deferred VMs in the given experiment that now fit
+// on the cluster, returning the number of VMs still deferred.
+func launchDeferred(name string) (int, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return 0, fmt.Errorf("getting experiment: %w", err)
+	}
+
+	if !exp.Running() {
+		return 0, nil
+	}
+
+	deferred := make(map[string]bool)
+
+	for _, vm := range exp.Status.Deferred() {
+		deferred[vm] = true
+	}
+
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if deferred[node.General().Hostname()] {
+			nodes = append(nodes, node)
+		}
+	}
+
+	cluster, err := mm.GetClusterHosts(true)
+	if err != nil {
+		return len(deferred), fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	var (
+		schedules, _ = persistentSchedules(exp)
+		still        = scheduler.BestEffort(nodes, schedules, cluster)
+		launch       = make(map[string]bool)
+	)
+
+	for vm := range deferred {
+		launch[vm] = true
+	}
+
+	for _, vm := range still {
+		delete(launch, vm)
+	}
+
+	if len(launch) == 0 {
+		return len(still), nil
+	}
+
+	mmScript := fmt.Sprintf("%s/mm_files/%s-deferred.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
+
+	// Only the VMs being launched are included in the script.
+	var others = make(map[string]bool)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if !launch[node.General().Hostname()] {
+			others[node.General().Hostname()] = true
+		}
+	}
+
+	restore := deferNodes(exp.Spec, others)
+	restoreSchedules := setSchedules(exp.Spec, schedules)
+
+	err = tmpl.CreateFileFromTemplate("minimega_script.tmpl", newLaunchScript(exp.Spec, cluster, exp.Status.PersistentDisks()), mmScript)
+
+	restoreSchedules()
+	restore()
+
+	if err != nil {
+		return len(deferred), fmt.Errorf("generating minimega script for deferred VMs: %w", err)
+	}
+
+	if err := mm.ReadScriptFromFile(mmScript); err != nil {
+		return len(deferred), perror.Errorf(perror.CodeMinimega, "experiment/"+name, "reading minimega script for deferred VMs: %w", err)
+	}
+
+	var names []string
+
+	for vm := range launch {
+		names = append(names, vm)
+	}
+
+	if err := mm.LaunchVMs(exp.Spec.ExperimentName(), names...); err != nil {
+		return len(deferred), perror.Errorf(perror.CodeMinimega, "experiment/"+name, "launching deferred VMs: %w", err)
+	}
+
+	for _, vm := range names {
+		plog.Info("deferred VM launched", "exp", name, "vm", vm, "host", schedules[vm])
+		journal.Record(name, journal.CategoryVM, "", "deferred VM %s launched on host %s", vm, schedules[vm])
+	}
+
+	var (
+		status   = exp.Status.Schedules()
+		launched = make(map[string]string)
+	)
+
+	for _, vm := range mm.GetVMInfo(mm.NS(exp.Spec.ExperimentName())) {
+		status[vm.Name] = vm.Host
+
+		if launch[vm.Name] {
+			launched[vm.Name] = vm.Host
+		}
+	}
+
+	exp.Status.SetSchedule(status)
+	recordPersistentDisks(exp, launched)
+	exp.Status.SetDeferred(still)
+
+	if err := exp.WriteToStore(true); err != nil {
+		return len(still), fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return len(still), nil
+}
+
+// bestEffort determines which of the given experiment's bootable VMs fit on
+// the cluster, returning the names of those that don't (and should be
+// deferred). VMs that fit on a host other than the one they're scheduled on
+// are moved in the experiment's schedules until the returned function is
+// called, so the experiment spec itself isn't changed.
+func bestEffort(exp *types.Experiment) ([]string, func(), error) {
+	cluster, err := mm.GetClusterHosts(true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting cluster hosts for best-effort start: %w", err)
+	}
+
+	var (
+		bootable  = exp.Spec.Topology().BootableNodes()
+		schedules = copySchedules(exp.Spec.Schedules())
+		deferred  = scheduler.BestEffort(bootable, schedules, cluster)
+	)
+
+	var internal int
+
+	for _, node := range bootable {
+		if !node.External() {
+			internal++
+		}
+	}
+
+	if internal > 0 && len(deferred) == internal {
+		return nil, nil, perror.Errorf(perror.CodeScheduling, "experiment/"+exp.Metadata.Name, "no VMs fit on the cluster for a best-effort start")
+	}
+
+	return deferred, setSchedules(exp.Spec, schedules), nil
+}
+
+// deferNodes marks the given nodes as do not boot, so they're left out of
+// launch scripts, until the returned function is called.
+func deferNodes(spec ifaces.ExperimentSpec, names map[string]bool) func() {
+	orig := make(map[ifaces.NodeSpec]bool)
+
+	for _, node := range spec.Topology().Nodes() {
+		if node.External() || !names[node.General().Hostname()] {
+			continue
+		}
+
+		orig[node] = *node.General().DoNotBoot()
+		node.General().SetDoNotBoot(true)
+	}
+
+	return func() {
+		for node, dnb := range orig {
+			node.General().SetDoNotBoot(dnb)
+		}
+	}
+}
+
+// setSchedules replaces the given spec's schedules with the given schedules
+// until the returned function is called.
+func setSchedules(spec ifaces.ExperimentSpec, schedules map[string]string) func() {
+	orig := spec.Schedules()
+	spec.SetSchedule(schedules)
+
+	return func() { spec.SetSchedule(orig) }
+}
+
+func copySchedules(schedules map[string]string) map[string]string {
+	cp := make(map[string]string, len(schedules))
+
+	for vm, host := range schedules {
+		cp[vm] = host
+	}
+
+	return cp
+}
@@ -0,0 +1,194 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// DependencyPollInterval is how often unavailable experiment dependencies are
+// checked again while waiting on them.
+var DependencyPollInterval = 5 * time.Second
+
+var ErrDependencyUnavailable = errors.New("dependency unavailable")
+
+// ParseDependency parses a dependency provided as `<type>:<target>`, ie.
+// `url:http://repo.example.com`, `mount:/mnt/nfs` or `experiment:infra`.
+func ParseDependency(s string) (string, string, error) {
+	typ, target, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || target == "" {
+		return "", "", fmt.Errorf("invalid dependency %s (expected <type>:<target>)", s)
+	}
+
+	switch typ {
+	case "url", "mount", "experiment":
+		return typ, target, nil
+	default:
+		return "", "", fmt.Errorf("unknown dependency type %s (expected url, mount or experiment)", typ)
+	}
+}
+
+type dependencyChecker func(context.Context, ifaces.DependencySpec) error
+
+// waitForDependencies checks each of the given dependencies, waiting up to the
+// dependency's timeout for it to become available. Dependencies without a
+// timeout are only checked once.
+func waitForDependencies(ctx context.Context, deps []ifaces.DependencySpec, check dependencyChecker) error {
+	for _, dep := range deps {
+		var timeout time.Duration
+
+		if dep.Timeout() != "" {
+			var err error
+
+			if timeout, err = time.ParseDuration(dep.Timeout()); err != nil {
+				return fmt.Errorf("parsing timeout for %s dependency %s: %w", dep.Type(), dep.Target(), err)
+			}
+		}
+
+		deadline := time.Now().Add(timeout)
+
+		for {
+			err := check(ctx, dep)
+			if err == nil {
+				break
+			}
+
+			if !time.Now().Before(deadline) {
+				return fmt.Errorf("%w: %s %s: %v", ErrDependencyUnavailable, dep.Type(), dep.Target(), err)
+			}
+
+			plog.Info("waiting on experiment dependency", "type", dep.Type(), "target", dep.Target(), "err", err)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(DependencyPollInterval):
+			}
+		}
+	}
+
+	return nil
+}
+
+// dependencyCheckerFor returns the checker used for the dependencies of the
+// given experiment.
+func dependencyCheckerFor(exp *types.Experiment) dependencyChecker {
+	return func(ctx context.Context, dep ifaces.DependencySpec) error {
+		switch dep.Type() {
+		case "url":
+			return checkURL(ctx, dep.Target())
+		case "mount":
+			return checkMount(exp, dep.Target())
+		case "experiment":
+			return checkExperiment(dep.Target())
+		default:
+			return fmt.Errorf("unknown dependency type %s", dep.Type())
+		}
+	}
+}
+
+// checkURL checks the given URL is reachable. HTTP(S) URLs are reachable if
+// the server responds without a server error, and other URLs (ie.
+// `tcp://host:port`) are reachable if a TCP connection to the host succeeds.
+func checkURL(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		var d net.Dialer
+
+		conn, err := d.DialContext(ctx, "tcp", u.Host)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// checkMount checks the given path is a mount point on each cluster host the
+// experiment is scheduled on (or all schedulable hosts if it isn't scheduled).
+func checkMount(exp *types.Experiment, path string) error {
+	hosts, err := scheduledHosts(exp.Spec.Schedules())
+	if err != nil {
+		return err
+	}
+
+	for host := range hosts {
+		out, err := mm.MeshShellResponse(host, fmt.Sprintf(`bash -c "mountpoint -q %s && echo mounted"`, path))
+		if err != nil || out != "mounted" {
+			return fmt.Errorf("%s not mounted on host %s", path, host)
+		}
+	}
+
+	return nil
+}
+
+func checkExperiment(name string) error {
+	exp, err := Get(name)
+	if err != nil {
+		return fmt.Errorf("experiment %s not found", name)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("experiment %s not running", name)
+	}
+
+	return nil
+}
+
+// scheduledHosts returns the cluster hosts in the given schedule, or all
+// schedulable hosts if the schedule is empty.
+func scheduledHosts(schedules map[string]string) (map[string]struct{}, error) {
+	hosts := make(map[string]struct{})
+
+	for _, host := range schedules {
+		hosts[host] = struct{}{}
+	}
+
+	if len(hosts) == 0 {
+		cluster, err := mm.GetClusterHosts(true)
+		if err != nil {
+			return nil, fmt.Errorf("getting list of cluster hosts: %w", err)
+		}
+
+		for _, host := range cluster {
+			hosts[host.Name] = struct{}{}
+		}
+	}
+
+	return hosts, nil
+}
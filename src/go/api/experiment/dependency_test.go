@@ -0,0 +1,100 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+func TestParseDependency(t *testing.T) {
+	typ, target, err := ParseDependency("url:http://repo.example.com:8080")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if typ != "url" || target != "http://repo.example.com:8080" {
+		t.Logf("unexpected dependency %s %s", typ, target)
+		t.FailNow()
+	}
+
+	for _, bad := range []string{"mount", "mount:", "file:/etc/hosts"} {
+		if _, _, err := ParseDependency(bad); err == nil {
+			t.Logf("expected error parsing dependency %s", bad)
+			t.FailNow()
+		}
+	}
+}
+
+func TestWaitForDependencies(t *testing.T) {
+	DependencyPollInterval = 10 * time.Millisecond
+
+	var (
+		checks int
+		deps   = []ifaces.DependencySpec{&v1.Dependency{TypeF: "experiment", TargetF: "infra", TimeoutF: "1s"}}
+	)
+
+	check := func(ctx context.Context, dep ifaces.DependencySpec) error {
+		if checks++; checks < 3 {
+			return errors.New("not yet")
+		}
+
+		return nil
+	}
+
+	if err := waitForDependencies(context.Background(), deps, check); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if checks != 3 {
+		t.Logf("expected dependency to be checked 3 times, got %d", checks)
+		t.FailNow()
+	}
+
+	deps = []ifaces.DependencySpec{&v1.Dependency{TypeF: "mount", TargetF: "/mnt/nfs"}}
+
+	err := waitForDependencies(context.Background(), deps, func(context.Context, ifaces.DependencySpec) error {
+		return errors.New("not mounted")
+	})
+
+	if !errors.Is(err, ErrDependencyUnavailable) {
+		t.Logf("expected ErrDependencyUnavailable for dependency without timeout, got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	defer ok.Close()
+
+	if err := checkURL(context.Background(), ok.URL); err != nil {
+		t.Logf("expected client error response to be reachable, got %v", err)
+		t.FailNow()
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	defer bad.Close()
+
+	if err := checkURL(context.Background(), bad.URL); err == nil {
+		t.Log("expected server error response to be unreachable")
+		t.FailNow()
+	}
+
+	if err := checkURL(context.Background(), "tcp://"+ok.Listener.Addr().String()); err != nil {
+		t.Logf("expected TCP connection to succeed, got %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,62 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"phenix/app"
+	"phenix/store"
+	"phenix/types"
+)
+
+// DiffApps previews the given stages (the configure and pre-start stages if
+// none are given) for the given stopped experiment by applying its apps in
+// dry-run mode to a copy of the experiment, returning a diff of the changes
+// each app would make to it. The experiment in the store is left untouched.
+func DiffApps(ctx context.Context, name string, stages ...app.Action) ([]app.AppDiff, error) {
+	if len(stages) == 0 {
+		stages = []app.Action{app.ACTIONCONFIG, app.ACTIONPRESTART}
+	}
+
+	for _, stage := range stages {
+		if stage != app.ACTIONCONFIG && stage != app.ACTIONPRESTART {
+			return nil, fmt.Errorf("diffing apps for the %s stage is not supported", stage)
+		}
+	}
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if exp.Running() {
+		return nil, fmt.Errorf("experiment is running")
+	}
+
+	// Mark the copy of the experiment as a dry run so apps that check for one
+	// don't do any work outside of the experiment spec (ie. attaching to shared
+	// services).
+	exp.Status.SetStartTime(time.Now().Format(time.RFC3339) + "-DRYRUN")
+
+	var diffs []app.AppDiff
+
+	opts := []app.Option{
+		app.DryRun(true),
+		app.OnDiff(func(d app.AppDiff) { diffs = append(diffs, d) }),
+	}
+
+	for _, stage := range stages {
+		if err := app.ApplyApps(ctx, exp, append(opts, app.Stage(stage))...); err != nil {
+			return diffs, fmt.Errorf("applying apps to experiment for %s stage: %w", stage, err)
+		}
+	}
+
+	return diffs, nil
+}
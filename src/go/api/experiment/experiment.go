@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"phenix/api/config"
+	"phenix/api/signing"
 	"phenix/app"
 	"phenix/scheduler"
 	"phenix/store"
@@ -20,10 +21,13 @@ import (
 	"phenix/types/version"
 	v1 "phenix/types/version/v1"
 	"phenix/util/common"
+	"phenix/util/daemon"
 	"phenix/util/file"
+	"phenix/util/journal"
 	"phenix/util/mm"
 	"phenix/util/mm/mmcli"
 	"phenix/util/notes"
+	"phenix/util/perror"
 	"phenix/util/plog"
 	"phenix/util/pubsub"
 
@@ -32,6 +36,10 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+// OwnerAnnotation is the experiment annotation used to record the user that
+// created an experiment.
+const OwnerAnnotation = "phenix/owner"
+
 var (
 	ErrExperimentNotFound   = errors.New("experiment not found")
 	ErrExperimentNotRunning = errors.New("experiment not running")
@@ -97,10 +105,18 @@ func init() {
 				return fmt.Errorf("verifying experiment scenario: %w", err)
 			}
 
+			if err := app.ValidateApps(exp); err != nil {
+				return fmt.Errorf("validating experiment apps: %w", err)
+			}
+
 			if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCONFIG)); err != nil {
 				return fmt.Errorf("applying apps to experiment: %w", err)
 			}
 
+			if err := validateVMOverrides(exp.Spec.Topology()); err != nil {
+				return perror.Errorf(perror.CodeValidation, "experiment/"+c.Metadata.Name, "validating VM overrides: %w", err)
+			}
+
 			c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 		case "update":
 			if exp.Running() {
@@ -163,6 +179,16 @@ func init() {
 				errors = multierror.Append(errors, fmt.Errorf("deleting experiment snapshots and CC responses: %w", err))
 			}
 
+			var disks []string
+
+			for node := range exp.Status.PersistentDisks() {
+				disks = append(disks, node)
+			}
+
+			if err := deletePersistentDisks(exp, disks...); err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("deleting experiment persistent disks: %w", err))
+			}
+
 			if err := os.RemoveAll(exp.Spec.BaseDir()); err != nil {
 				errors = multierror.Append(errors, fmt.Errorf("deleting experiment base directory: %w", err))
 			}
@@ -176,7 +202,8 @@ func init() {
 
 // Hook is a function to be called during the different lifecycle stages of an
 // experiment. The first argument is the experiment stage (create, start, stop,
-// delete), and the second argument is the experiment, name.
+// delete, or launch-failed if launching the experiment's VMs failed), and the
+// second argument is the experiment, name.
 type Hook func(string, string)
 
 var hooks = make(map[string][]Hook)
@@ -275,6 +302,22 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		return fmt.Errorf("topology doesn't exist")
 	}
 
+	// Record the digests of the configs as they are right now so signatures are
+	// verified against what the experiment was actually created from.
+	signed := make(store.Annotations)
+
+	if err := signing.RecordConfig(signed, "topology", *topoC); err != nil {
+		return fmt.Errorf("recording topology digest: %w", err)
+	}
+
+	if o.environment != "" {
+		var err error
+
+		if topoC, err = config.ApplyOverlays(topoC, o.environment); err != nil {
+			return fmt.Errorf("applying %s overlays to topology: %w", o.environment, err)
+		}
+	}
+
 	// This will upgrade the toplogy to the latest known version if needed.
 	topo, err := types.DecodeTopologyFromConfig(*topoC)
 	if err != nil {
@@ -307,6 +350,18 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 			return fmt.Errorf("scenario doesn't exist")
 		}
 
+		if err := signing.RecordConfig(signed, "scenario", *scenarioC); err != nil {
+			return fmt.Errorf("recording scenario digest: %w", err)
+		}
+
+		if o.environment != "" {
+			var err error
+
+			if scenarioC, err = config.ApplyOverlays(scenarioC, o.environment); err != nil {
+				return fmt.Errorf("applying %s overlays to scenario: %w", o.environment, err)
+			}
+		}
+
 		topo, ok := scenarioC.Metadata.Annotations["topology"]
 		if !ok {
 			return fmt.Errorf("topology annotation missing from scenario")
@@ -337,6 +392,28 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		}
 	}
 
+	// Recorded digests always win over user provided annotations so they can't
+	// be spoofed.
+	for k, v := range signed {
+		meta.Annotations[k] = v
+	}
+
+	for k := range meta.Annotations {
+		if strings.HasPrefix(k, signing.DigestAnnotationPrefix) || strings.HasPrefix(k, signing.SignatureAnnotationPrefix) {
+			if _, ok := signed[k]; !ok {
+				delete(meta.Annotations, k)
+			}
+		}
+	}
+
+	if o.owner != "" {
+		meta.Annotations[OwnerAnnotation] = o.owner
+	}
+
+	if o.environment != "" {
+		meta.Annotations[config.EnvironmentAnnotation] = o.environment
+	}
+
 	c := &store.Config{
 		Version:  store.API_GROUP + "/" + apiVersion,
 		Kind:     kind,
@@ -352,7 +429,39 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 	exp.Spec.SetVLANRange(o.vlanMin, o.vlanMax, false)
 	exp.Spec.VLANs().SetAliases(o.vlanAliases)
 	exp.Spec.SetSchedule(o.schedules)
+	exp.Spec.SetSkipStages(o.skipStages)
 	exp.Spec.SetUseGREMesh(o.useGREMesh)
+	exp.Spec.SetLocale(o.locale, o.timezone, o.keyboard)
+
+	if o.depTimeout != "" {
+		if _, err := time.ParseDuration(o.depTimeout); err != nil {
+			return fmt.Errorf("parsing dependency timeout: %w", err)
+		}
+	}
+
+	for _, d := range o.dependencies {
+		typ, target, err := ParseDependency(d)
+		if err != nil {
+			return err
+		}
+
+		exp.Spec.AddDependency(typ, target, o.depTimeout)
+	}
+
+	exp.Spec.SetConcurrencyGroups(o.concurrency)
+
+	for _, b := range o.externalBridges {
+		name, vlans, noDestroy, err := ParseExternalBridge(b)
+		if err != nil {
+			return err
+		}
+
+		exp.Spec.AddExternalBridge(name, vlans, noDestroy)
+	}
+
+	if err := validateExternalBridges(exp.Spec); err != nil {
+		return fmt.Errorf("validating external bridges: %w", err)
+	}
 
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 
@@ -360,6 +469,8 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		return fmt.Errorf("creating experiment config: %w", err)
 	}
 
+	journal.Record(o.name, journal.CategoryLifecycle, "", "experiment created")
+
 	for _, hook := range hooks["create"] {
 		hook("create", o.name)
 	}
@@ -403,9 +514,30 @@ func Schedule(opts ...ScheduleOption) error {
 
 // Start starts the experiment with the given name. It returns any errors
 // encountered while starting the experiment.
-func Start(ctx context.Context, opts ...StartOption) error {
+func Start(ctx context.Context, opts ...StartOption) (err error) {
 	o := newStartOptions(opts...)
 
+	if o.user != "" {
+		ctx = app.SetContextUser(ctx, o.user)
+	}
+
+	started := time.Now()
+
+	defer func() {
+		if err != nil {
+			journal.Record(o.name, journal.CategoryLifecycle, "", "experiment failed to start: %v", err)
+		}
+
+		if !o.dryrun {
+			observeStart(started, err)
+		}
+	}()
+
+	profile, err := GetStartProfile(o.profile)
+	if err != nil {
+		return err
+	}
+
 	c, _ := store.NewConfig("experiment/" + o.name)
 
 	if err := store.Get(c); err != nil {
@@ -417,12 +549,64 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		return fmt.Errorf("decoding experiment from config: %w", err)
 	}
 
+	appOpts := []app.Option{
+		app.DryRun(o.dryrun),
+		app.SkipApp(profile.SkipApps...),
+		app.SkipOptional(profile.SkipOptionalApps),
+		app.StageTimeout(o.stageTimeout),
+		app.AppTimeout(o.appTimeout),
+		app.AppRetry(o.appRetries, o.appRetryBackoff),
+		app.OnProgress(o.progress),
+	}
+
+	if profile.Name != "full" {
+		notes.AddInfo(ctx, false, fmt.Sprintf("starting experiment with %s profile", profile.Name))
+	}
+
 	if exp.Running() {
 		if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
 			return fmt.Errorf("experiment already running (started at: %s)", exp.Status.StartTime())
 		}
 	}
 
+	// Dry runs don't launch anything, so they don't conflict with other members
+	// of the experiment's concurrency groups.
+	if !o.dryrun {
+		release, claimed, err := claimConcurrencyGroups(ctx, exp, o.queue)
+		if err != nil {
+			return perror.Wrap(perror.CodeConflict, "experiment/"+o.name, err)
+		}
+
+		defer release()
+
+		// The experiment may have been updated (or started) while waiting for its
+		// concurrency groups, and the claim on them is recorded in its status, so
+		// it's read from the store again.
+		if claimed {
+			if err := store.Get(c); err != nil {
+				return fmt.Errorf("getting experiment %s from store: %w", o.name, err)
+			}
+
+			if exp, err = types.DecodeExperimentFromConfig(*c); err != nil {
+				return fmt.Errorf("decoding experiment from config: %w", err)
+			}
+
+			if exp.Running() && !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+				return fmt.Errorf("experiment already running (started at: %s)", exp.Status.StartTime())
+			}
+		}
+	}
+
+	// Dry runs don't launch anything, so there's nothing that depends on external
+	// resources being available.
+	if deps := exp.Spec.Dependencies(); len(deps) > 0 && !o.dryrun {
+		notes.AddInfo(ctx, false, fmt.Sprintf("checking %d experiment dependencies", len(deps)))
+
+		if err := waitForDependencies(ctx, deps, dependencyCheckerFor(exp)); err != nil {
+			return perror.Wrap(perror.CodeDependency, "experiment/"+o.name, err)
+		}
+	}
+
 	if o.vlanMin != 0 {
 		exp.Spec.VLANs().SetMin(o.vlanMin)
 	}
@@ -431,16 +615,107 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		exp.Spec.VLANs().SetMax(o.vlanMax)
 	}
 
-	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPRESTART), app.DryRun(o.dryrun)); err != nil {
+	if err := app.ApplyApps(ctx, exp, append(appOpts, app.Stage(app.ACTIONPRESTART))...); err != nil {
 		return fmt.Errorf("applying apps to experiment: %w", err)
 	}
 
+	// Allowlists may have changed since the experiment was created, so validate
+	// again before launching any VMs.
+	if err := validateVMOverrides(exp.Spec.Topology()); err != nil {
+		return perror.Errorf(perror.CodeValidation, "experiment/"+o.name, "validating VM overrides: %w", err)
+	}
+
+	if err := validateExternalBridges(exp.Spec); err != nil {
+		return perror.Errorf(perror.CodeValidation, "experiment/"+o.name, "validating external bridges: %w", err)
+	}
+
+	// Dry runs don't launch anything, so external bridges don't need to exist.
+	if !o.dryrun {
+		if err := checkExternalBridges(exp); err != nil {
+			return perror.Wrap(perror.CodeDependency, "experiment/"+o.name, err)
+		}
+	}
+
+	// Dry runs don't launch anything, so there's nothing to prove was approved.
+	if !o.dryrun {
+		warns, err := signing.VerifyExperiment(exp)
+		if err != nil {
+			return fmt.Errorf("verifying signatures: %w", err)
+		}
+
+		for _, warn := range warns {
+			plog.Warn("signature verification failed", "exp", o.name, "err", warn)
+		}
+
+		notes.AddWarnings(ctx, false, warns...)
+	}
+
 	var (
 		mmScript = fmt.Sprintf("%s/mm_files/%s.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
 		ccScript = fmt.Sprintf("%s/mm_files/%s-cc.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
 	)
 
-	if err := tmpl.CreateFileFromTemplate("minimega_script.tmpl", exp.Spec, mmScript); err != nil {
+	var hosts mm.Hosts
+
+	// Dry runs don't launch anything, so there's no need to adapt VM launch
+	// configs to cluster host capabilities.
+	if !o.dryrun {
+		if hosts, err = mm.GetClusterHosts(false); err != nil {
+			plog.Warn("unable to get cluster host capabilities", "exp", o.name, "err", err)
+		}
+	}
+
+	restorePinned := func() {}
+
+	// Nodes with persistent disks are kept on the host their disk lives on,
+	// unless the start profile forces snapshot disks.
+	if !profile.Snapshot {
+		pinned, warns := persistentSchedules(exp)
+		notes.AddWarnings(ctx, false, warns...)
+
+		restorePinned = setSchedules(exp.Spec, pinned)
+	}
+
+	var (
+		deferred         map[string]bool
+		restoreSchedules = func() {}
+	)
+
+	// Dry runs don't launch anything, so everything fits.
+	if o.bestEffort && !o.dryrun {
+		var names []string
+
+		names, restoreSchedules, err = bestEffort(exp)
+		if err != nil {
+			restorePinned()
+			return err
+		}
+
+		deferred = make(map[string]bool)
+
+		for _, name := range names {
+			deferred[name] = true
+
+			notes.AddWarnings(ctx, false, fmt.Errorf("VM %s deferred - will be launched when cluster capacity is available", name))
+			journal.Record(o.name, journal.CategoryVM, "", "VM %s deferred until cluster capacity is available", name)
+		}
+
+		exp.Status.SetDeferred(names)
+	}
+
+	restore := profile.snapshot(exp.Spec.Topology().Nodes())
+	restoreDeferred := deferNodes(exp.Spec, deferred)
+	script := newLaunchScript(exp.Spec, hosts, exp.Status.PersistentDisks())
+
+	notes.AddWarnings(ctx, false, script.Fallbacks()...)
+
+	err = tmpl.CreateFileFromTemplate("minimega_script.tmpl", script, mmScript)
+	restoreDeferred()
+	restoreSchedules()
+	restorePinned()
+	restore()
+
+	if err != nil {
 		return fmt.Errorf("generating minimega script: %w", err)
 	}
 
@@ -471,7 +746,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		if err := mm.ReadScriptFromFile(mmScript); err != nil {
 			if !o.mmErrAsWarn {
 				mm.ClearNamespace(exp.Spec.ExperimentName())
-				return fmt.Errorf("reading minimega script: %w", err)
+				return perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "reading minimega script: %w", err)
 			}
 
 			if merr, ok := err.(*multierror.Error); ok {
@@ -493,6 +768,10 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 			hostname := node.General().Hostname()
 
+			if deferred[hostname] {
+				continue
+			}
+
 			if node.Delay().User() {
 				notes.AddInfo(ctx, true, fmt.Sprintf("VM %s delayed - to be started by user", hostname))
 				continue
@@ -522,6 +801,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			}
 
 			if d := node.Delay().Timer(); d != 0 {
+				d = profile.delay(d)
 				delays[hostname] = d
 
 				notes.AddInfo(ctx, true, fmt.Sprintf("VM %s delayed - will be started after %v", hostname, d))
@@ -541,8 +821,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 		if err := mm.LaunchVMs(exp.Spec.ExperimentName(), start...); err != nil {
 			if !o.mmErrAsWarn {
+				// Give hooks a chance to inspect the VMs that failed to launch before
+				// they're killed.
+				for _, hook := range hooks["launch-failed"] {
+					hook("launch-failed", o.name)
+				}
+
 				mm.ClearNamespace(exp.Spec.ExperimentName())
-				return fmt.Errorf("launching experiment VMs: %w", err)
+				return perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "launching experiment VMs: %w", err)
 			}
 
 			if merr, ok := err.(*multierror.Error); ok {
@@ -556,12 +842,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		// already exists in minimega (and OVS) before creating GRE tunnels between
 		// them. This cannot be done as part of the minimega script template since
 		// the VM taps (and thus bridges) do not get created until the overall
-		// minimega namespace is launched.
-		if exp.Spec.UseGREMesh() {
+		// minimega namespace is launched. External bridges are already part of a
+		// fabric spanning the cluster hosts, so they're never added to the
+		// namespace (which would also have minimega delete them at cleanup).
+		if exp.Spec.UseGREMesh() && exp.Spec.ExternalBridge(exp.Spec.DefaultBridge()) == nil {
 			if err := mm.CreateBridge(mm.NS(exp.Metadata.Name), mm.Bridge(exp.Spec.DefaultBridge())); err != nil {
 				if !o.mmErrAsWarn {
 					mm.ClearNamespace(exp.Spec.ExperimentName())
-					return fmt.Errorf("creating experiment bridge: %w", err)
+					return perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "creating experiment bridge: %w", err)
 				}
 
 				if merr, ok := err.(*multierror.Error); ok {
@@ -580,10 +868,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 		exp.Status.SetSchedule(schedule)
 
+		if !profile.Snapshot {
+			recordPersistentDisks(exp, schedule)
+		}
+
 		vlans, err := mm.GetVLANs(mm.NS(exp.Spec.ExperimentName()))
 		if err != nil {
 			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("processing experiment VLANs: %w", err)
+			return perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "processing experiment VLANs: %w", err)
 		}
 
 		exp.Status.SetVLANs(vlans)
@@ -599,7 +891,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		if !o.dryrun {
 			if exp.Spec.Topology().HasCommands() {
 				if err := mm.ReadScriptFromFile(ccScript); err != nil {
-					errors := multierror.Append(nil, fmt.Errorf("reading minimega cc script: %w", err))
+					errors := multierror.Append(nil, perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "reading minimega cc script: %w", err))
 
 					if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
 						errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
@@ -620,13 +912,17 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			}
 		}
 
-		if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
+		if err := app.ApplyApps(ctx, exp, append(appOpts, app.Stage(app.ACTIONPOSTSTART))...); err != nil {
 			errors := multierror.Append(nil, fmt.Errorf("applying apps to experiment: %w", err))
 
-			if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(o.dryrun)); err != nil {
+			if err := app.ApplyApps(app.SetContextUser(context.TODO(), o.user), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(o.dryrun), app.OnProgress(o.progress)); err != nil {
 				errors = multierror.Append(errors, fmt.Errorf("cleaning up app experiments: %w", err))
 			}
 
+			if err := daemon.StopAll(exp.Spec.ExperimentName()); err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("stopping app daemons: %w", err))
+			}
+
 			if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
 				errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
 			}
@@ -640,9 +936,9 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			if !o.dryrun {
 				if exp.Spec.Topology().HasCommands() {
 					if err := mm.ReadScriptFromFile(ccScript); err != nil {
-						o.errChan <- fmt.Errorf("reading minimega cc script: %w", err)
+						o.errChan <- perror.Errorf(perror.CodeMinimega, "experiment/"+o.name, "reading minimega cc script: %w", err)
 
-						if err := Stop(exp.Spec.ExperimentName()); err != nil {
+						if err := Stop(exp.Spec.ExperimentName(), StopWithUser(o.user)); err != nil {
 							o.errChan <- fmt.Errorf("stopping experiment: %w", err)
 						}
 
@@ -653,7 +949,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 				if err := handleDelayedVMs(ctx, exp.Spec.ExperimentName(), delays, c2s); err != nil {
 					o.errChan <- fmt.Errorf("handling delayed VMs: %w", err)
 
-					if err := Stop(exp.Spec.ExperimentName()); err != nil {
+					if err := Stop(exp.Spec.ExperimentName(), StopWithUser(o.user)); err != nil {
 						o.errChan <- fmt.Errorf("stopping experiment: %w", err)
 					}
 
@@ -661,10 +957,10 @@ func Start(ctx context.Context, opts ...StartOption) error {
 				}
 			}
 
-			if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
+			if err := app.ApplyApps(ctx, exp, append(appOpts, app.Stage(app.ACTIONPOSTSTART))...); err != nil {
 				o.errChan <- fmt.Errorf("applying apps to experiment: %w", err)
 
-				if err := Stop(exp.Spec.ExperimentName()); err != nil {
+				if err := Stop(exp.Spec.ExperimentName(), StopWithUser(o.user)); err != nil {
 					o.errChan <- fmt.Errorf("stopping experiment: %w", err)
 				}
 			}
@@ -673,6 +969,10 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 	exp.Status.SetStartTime(start)
 
+	// Running experiments hold their concurrency groups, so the claim on them
+	// made for the start is no longer needed.
+	exp.Status.SetStarting("")
+
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
 
@@ -681,6 +981,8 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		return fmt.Errorf("updating experiment config: %w", err)
 	}
 
+	journal.Record(o.name, journal.CategoryLifecycle, "", "experiment started")
+
 	for _, hook := range hooks["start"] {
 		hook("start", o.name)
 	}
@@ -690,7 +992,9 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 // Stop stops the experiment with the given name. It returns any errors
 // encountered while stopping the experiment.
-func Stop(name string) error {
+func Stop(name string, opts ...StopOption) error {
+	o := newStopOptions(opts...)
+
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
@@ -710,17 +1014,28 @@ func Stop(name string) error {
 
 	var errors error
 
-	if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun)); err != nil {
+	ctx := app.SetContextUser(context.TODO(), o.user)
+
+	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun), app.OnProgress(o.progress)); err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("cleaning up app experiments: %w", err))
 	}
 
+	if err := daemon.StopAll(name); err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("stopping app daemons: %w", err))
+	}
+
 	if !dryrun {
 		if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
 			errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
 		}
+
+		if err := destroyExternalBridges(exp); err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("deleting external bridges: %w", err))
+		}
 	}
 
 	exp.Status.SetStartTime("")
+	exp.Status.SetDeferred(nil)
 
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
@@ -729,6 +1044,8 @@ func Stop(name string) error {
 		errors = multierror.Append(errors, fmt.Errorf("updating experiment config: %w", err))
 	}
 
+	journal.Record(name, journal.CategoryLifecycle, "", "experiment stopped")
+
 	for _, hook := range hooks["stop"] {
 		hook("stop", name)
 	}
@@ -835,7 +1152,7 @@ func Reconfigure(name string) error {
 	// Try deleting the minimega bridge associated with this experiment if we're
 	// not using the GRE mesh, just in case we were using it prior. Ignore any
 	// errors since they will occur if GRE wasn't being used.
-	if !exp.Spec.UseGREMesh() {
+	if !exp.Spec.UseGREMesh() && exp.Spec.ExternalBridge(exp.Spec.DefaultBridge()) == nil {
 		mm.MeshSend(name, "", fmt.Sprintf("ns del-bridge %s", exp.Spec.DefaultBridge()))
 	}
 
@@ -889,6 +1206,12 @@ func Delete(name string) error {
 
 	var errors error
 
+	// Daemons can be started for an experiment that isn't running, so make sure
+	// none are left behind.
+	if err := daemon.StopAll(name); err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("stopping app daemons: %w", err))
+	}
+
 	// Delete any snapshot files created by this headnode for this experiment
 	// after deleting the experiment.
 	if err := deleteC2AndSnapshots(exp); err != nil {
@@ -899,6 +1222,8 @@ func Delete(name string) error {
 		errors = multierror.Append(errors, fmt.Errorf("deleting experiment base directory: %w", err))
 	}
 
+	journal.Record(name, journal.CategoryLifecycle, "", "experiment deleted")
+
 	for _, hook := range hooks["delete"] {
 		hook("delete", name)
 	}
@@ -907,7 +1232,18 @@ func Delete(name string) error {
 }
 
 func Files(name, filter string) (file.Files, error) {
-	return file.GetExperimentFiles(name, filter)
+	files, err := file.GetExperimentFiles(name, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are still listed if the experiment can't be retrieved, just without
+	// their artifact categories.
+	if exp, err := Get(name); err == nil {
+		categorizeArtifacts(exp, files)
+	}
+
+	return files, nil
 }
 
 func File(name, filePath string) ([]byte, error) {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"phenix/store"
+	v1 "phenix/types/version/v1"
+	"phenix/util/common"
 
 	"github.com/golang/mock/gomock"
 )
@@ -40,3 +42,79 @@ func TestList(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestValidateVMOverrides(t *testing.T) {
+	defer func() {
+		common.QEMUArgAllowlist = nil
+		common.VMConfigAllowlist = nil
+	}()
+
+	var (
+		node = &v1.Node{TypeF: "VirtualMachine", GeneralF: &v1.General{HostnameF: "foo"}}
+		topo = &v1.TopologySpec{NodesF: []*v1.Node{node}}
+	)
+
+	node.QEMUAppendF = []string{"-usb", "-device usb-host,vendorid=0x1234"}
+	node.AddAdvanced("serial-ports", "2")
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected QEMU arguments to be rejected with an empty allowlist")
+		t.FailNow()
+	}
+
+	common.QEMUArgAllowlist = []string{"-usb", "-device"}
+
+	if err := validateVMOverrides(topo); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// positional arguments would be treated as disk images by QEMU
+	node.QEMUAppendF = []string{"/etc/shadow"}
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected positional QEMU argument to be rejected")
+		t.FailNow()
+	}
+
+	node.QEMUAppendF = nil
+	common.VMConfigAllowlist = []string{"cpu"}
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected vm config setting not in allowlist to be rejected")
+		t.FailNow()
+	}
+
+	// newlines would inject additional commands into the minimega script
+	common.VMConfigAllowlist = nil
+	node.AdvancedF = nil
+	node.QEMUAppendF = []string{"-usb\nshell rm -rf /"}
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected QEMU argument with newline to be rejected")
+		t.FailNow()
+	}
+
+	node.QEMUAppendF = nil
+	node.AddAdvanced("serial-ports", "2\nshell reboot")
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected vm config value with newline to be rejected")
+		t.FailNow()
+	}
+
+	node.AdvancedF = nil
+	node.OverridesF = map[string]string{"-enable-kvm": "-accel tcg\"\nshell reboot\n\""}
+
+	if err := validateVMOverrides(topo); err == nil {
+		t.Log("expected QEMU override with newline to be rejected")
+		t.FailNow()
+	}
+
+	node.OverridesF = map[string]string{"-enable-kvm": "-accel tcg"}
+
+	if err := validateVMOverrides(topo); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
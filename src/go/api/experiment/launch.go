@@ -0,0 +1,123 @@
+package experiment
+
+import (
+	"fmt"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+)
+
+// launchScript is the data used to generate an experiment's minimega script.
+// It adapts each VM's launch config to the capabilities of the cluster host the
+// VM is scheduled on instead of assuming a homogeneous cluster.
+type launchScript struct {
+	ifaces.ExperimentSpec
+
+	hosts mm.Hosts
+
+	// Persistent disks already created, keyed by node hostname.
+	disks map[string]string
+}
+
+func newLaunchScript(spec ifaces.ExperimentSpec, hosts mm.Hosts, disks map[string]string) launchScript {
+	return launchScript{ExperimentSpec: spec, hosts: hosts, disks: disks}
+}
+
+// Persistent returns true if the given node keeps its disk across experiment
+// restarts.
+func (this launchScript) Persistent(node ifaces.NodeSpec) bool {
+	return persistentNode(node)
+}
+
+// PersistentDisk returns the name of the given node's persistent disk.
+func (this launchScript) PersistentDisk(node ifaces.NodeSpec) string {
+	return persistentDiskName(this.ExperimentName(), node.General().Hostname())
+}
+
+// CreatePersistentDisk returns true if the given node's persistent disk needs
+// to be created (and have its injections applied) before the node is launched.
+// Existing persistent disks are used as-is so the changes made to them aren't
+// lost.
+func (this launchScript) CreatePersistentDisk(node ifaces.NodeSpec) bool {
+	_, ok := this.disks[node.General().Hostname()]
+	return !ok
+}
+
+// LaunchSnapshot returns true if the given node should be launched with a
+// snapshot disk that throws away changes when the VM is killed.
+func (this launchScript) LaunchSnapshot(node ifaces.NodeSpec) bool {
+	if this.Persistent(node) {
+		return false
+	}
+
+	snapshot := node.General().Snapshot()
+	return snapshot != nil && *snapshot
+}
+
+// capabilities returns the capabilities of the host the given node is scheduled
+// on, or nil if unknown.
+func (this launchScript) capabilities(node ifaces.NodeSpec) *mm.HostCapabilities {
+	// Containers don't need hardware acceleration.
+	if node.General().VMType() == "container" {
+		return nil
+	}
+
+	host := this.hosts.FindHostByName(this.Schedules()[node.General().Hostname()])
+	if host == nil {
+		return nil
+	}
+
+	return host.Capabilities
+}
+
+// LaunchCPU returns the CPU model to launch the given node with. QEMU can't
+// pass through the host CPU without KVM, so the `max` model is used instead
+// when the node is scheduled on a host without KVM.
+func (this launchScript) LaunchCPU(node ifaces.NodeSpec) string {
+	cpu := node.Hardware().CPU()
+
+	if caps := this.capabilities(node); caps != nil && !caps.KVM {
+		if cpu == "" || cpu == "host" {
+			return "max"
+		}
+	}
+
+	return cpu
+}
+
+// LaunchOverrides returns the QEMU overrides to launch the given node with,
+// falling back to TCG when the node is scheduled on a host without KVM.
+func (this launchScript) LaunchOverrides(node ifaces.NodeSpec) map[string]string {
+	caps := this.capabilities(node)
+
+	if caps == nil || caps.KVM {
+		return node.Overrides()
+	}
+
+	overrides := map[string]string{"-enable-kvm": "-accel tcg"}
+
+	for match, replacement := range node.Overrides() {
+		overrides[match] = replacement
+	}
+
+	return overrides
+}
+
+// Fallbacks returns a warning for each node that will be launched without KVM
+// acceleration due to the host it's scheduled on.
+func (this launchScript) Fallbacks() []error {
+	var warns []error
+
+	for _, node := range this.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if caps := this.capabilities(node); caps != nil && !caps.KVM {
+			host := this.Schedules()[node.General().Hostname()]
+			warns = append(warns, fmt.Errorf("KVM not available on host %s; VM %s will use TCG (software emulation)", host, node.General().Hostname()))
+		}
+	}
+
+	return warns
+}
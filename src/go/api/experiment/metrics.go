@@ -0,0 +1,33 @@
+package experiment
+
+import (
+	"time"
+
+	"phenix/util/metrics"
+)
+
+var (
+	experimentsStarted = metrics.NewCounterVec(
+		"phenix_experiments_started_total",
+		"Number of experiments started, by whether the start succeeded.",
+		"status",
+	)
+
+	startDuration = metrics.NewHistogramVec(
+		"phenix_experiment_start_duration_seconds",
+		"Time taken to start experiments, including applying apps for all start stages.",
+		metrics.DurationBuckets, "status",
+	)
+)
+
+// observeStart records an experiment start that began at the given time.
+func observeStart(started time.Time, err error) {
+	status := "success"
+
+	if err != nil {
+		status = "error"
+	}
+
+	experimentsStarted.Inc(status)
+	startDuration.Observe(time.Since(started).Seconds(), status)
+}
@@ -1,26 +1,40 @@
 package experiment
 
 import (
+	"time"
+
+	"phenix/app"
 	ifaces "phenix/types/interfaces"
+	"phenix/util/anonymize"
 	"phenix/util/common"
 )
 
 type CreateOption func(*createOptions)
 
 type createOptions struct {
-	name          string
-	annotations   map[string]string
-	topology      string
-	scenario      string
-	disabledApps  []string
-	vlanMin       int
-	vlanMax       int
-	vlanAliases   map[string]int
-	schedules     map[string]string
-	baseDir       string
-	deployMode    common.DeploymentMode
-	useGREMesh    bool
-	defaultBridge string
+	name            string
+	annotations     map[string]string
+	owner           string
+	topology        string
+	scenario        string
+	disabledApps    []string
+	vlanMin         int
+	vlanMax         int
+	vlanAliases     map[string]int
+	schedules       map[string]string
+	skipStages      map[string][]string
+	dependencies    []string
+	depTimeout      string
+	concurrency     []string
+	externalBridges []string
+	locale          string
+	timezone        string
+	keyboard        string
+	baseDir         string
+	deployMode      common.DeploymentMode
+	useGREMesh      bool
+	defaultBridge   string
+	environment     string
 }
 
 func newCreateOptions(opts ...CreateOption) createOptions {
@@ -56,6 +70,23 @@ func CreateWithAnnotations(a map[string]string) CreateOption {
 	}
 }
 
+// CreateWithOwner records the given user as the owner of the experiment (via
+// the `phenix/owner` annotation) so resource usage can be attributed to them.
+func CreateWithOwner(u string) CreateOption {
+	return func(o *createOptions) {
+		o.owner = u
+	}
+}
+
+// CreateWithEnvironment applies the overlays for the given environment (ie.
+// `site-a`) to the topology and scenario before creating the experiment. See
+// `config.ApplyOverlays`.
+func CreateWithEnvironment(e string) CreateOption {
+	return func(o *createOptions) {
+		o.environment = e
+	}
+}
+
 func CreateWithTopology(t string) CreateOption {
 	return func(o *createOptions) {
 		o.topology = t
@@ -98,6 +129,52 @@ func CreateWithSchedules(s map[string]string) CreateOption {
 	}
 }
 
+// CreateWithDependencies sets the external resources (as `<type>:<target>`)
+// the experiment depends on, each waited on for up to the given timeout (ie.
+// `5m`) when the experiment is started.
+func CreateWithDependencies(d []string, timeout string) CreateOption {
+	return func(o *createOptions) {
+		o.dependencies = d
+		o.depTimeout = timeout
+	}
+}
+
+// CreateWithConcurrencyGroups sets the concurrency groups the experiment is a
+// member of. Only one member of a group can run at a time.
+func CreateWithConcurrencyGroups(g []string) CreateOption {
+	return func(o *createOptions) {
+		o.concurrency = g
+	}
+}
+
+// CreateWithExternalBridges sets the pre-existing OVS bridges (as
+// `<name>[:<alias>=<id>,...][:no-destroy]`) the experiment uses, along with
+// the externally-managed VLANs on them.
+func CreateWithExternalBridges(b []string) CreateOption {
+	return func(o *createOptions) {
+		o.externalBridges = b
+	}
+}
+
+// CreateWithSkipStages sets the app stages (keyed by app name) to skip when
+// applying apps to the experiment.
+func CreateWithSkipStages(s map[string][]string) CreateOption {
+	return func(o *createOptions) {
+		o.skipStages = s
+	}
+}
+
+// CreateWithLocale sets the locale (ie. `de_DE.UTF-8`), timezone (ie.
+// `Europe/Berlin`), and keyboard layout (ie. `de`) the startup app configures
+// on the experiment's VMs. Empty values are left as configured in VM images.
+func CreateWithLocale(locale, timezone, keyboard string) CreateOption {
+	return func(o *createOptions) {
+		o.locale = locale
+		o.timezone = timezone
+		o.keyboard = keyboard
+	}
+}
+
 func CreateWithBaseDirectory(b string) CreateOption {
 	return func(o *createOptions) {
 		o.baseDir = b
@@ -212,10 +289,36 @@ type startOptions struct {
 	vlanMin int
 	vlanMax int
 	errChan chan error
+	profile string
 
 	// Option to treat all errors generated by minimega as warnings when launching
 	// an experiment.
 	mmErrAsWarn bool
+
+	// Option to launch as many VMs as fit on the cluster (by priority), deferring
+	// the rest until there's capacity, instead of launching every VM.
+	bestEffort bool
+
+	// How long apps are allowed to run for each stage, and how long each app is
+	// allowed to run for each stage. Zero means there's no limit.
+	stageTimeout time.Duration
+	appTimeout   time.Duration
+
+	// How many times each app that fails is retried for each stage, and how long
+	// to wait before the first retry.
+	appRetries      int
+	appRetryBackoff time.Duration
+
+	// User that started the experiment, recorded in the audit log for each app
+	// applied.
+	user string
+
+	// Called with each progress event emitted while applying apps.
+	progress app.ProgressHandler
+
+	// Option to wait for other members of the experiment's concurrency groups to
+	// stop instead of failing to start.
+	queue bool
 }
 
 func newStartOptions(opts ...StartOption) startOptions {
@@ -263,3 +366,128 @@ func StartWithMMErrorsAsWarnings(w bool) StartOption {
 		o.mmErrAsWarn = w
 	}
 }
+
+func StartWithProfile(p string) StartOption {
+	return func(o *startOptions) {
+		o.profile = p
+	}
+}
+
+func StartWithBestEffort(b bool) StartOption {
+	return func(o *startOptions) {
+		o.bestEffort = b
+	}
+}
+
+func StartWithStageTimeout(t time.Duration) StartOption {
+	return func(o *startOptions) {
+		o.stageTimeout = t
+	}
+}
+
+func StartWithAppTimeout(t time.Duration) StartOption {
+	return func(o *startOptions) {
+		o.appTimeout = t
+	}
+}
+
+// StartWithAppRetries retries each app that fails up to the given number of
+// times for each stage, unless overridden by the app's retry metadata, waiting
+// the given backoff before the first retry.
+func StartWithAppRetries(retries int, backoff time.Duration) StartOption {
+	return func(o *startOptions) {
+		o.appRetries = retries
+		o.appRetryBackoff = backoff
+	}
+}
+
+// StartWithUser records the given user as the user that started the
+// experiment in the audit log entries for the apps applied.
+func StartWithUser(u string) StartOption {
+	return func(o *startOptions) {
+		o.user = u
+	}
+}
+
+// StartWithProgress calls the given handler with each progress event emitted
+// while applying apps, instead of logging them.
+func StartWithProgress(h app.ProgressHandler) StartOption {
+	return func(o *startOptions) {
+		o.progress = h
+	}
+}
+
+// StartWithQueue waits for other running members of the experiment's
+// concurrency groups to stop before starting the experiment, instead of
+// failing to start it.
+func StartWithQueue(q bool) StartOption {
+	return func(o *startOptions) {
+		o.queue = q
+	}
+}
+
+type StopOption func(*stopOptions)
+
+type stopOptions struct {
+	// User that stopped the experiment, recorded in the audit log for each app
+	// applied.
+	user string
+
+	// Called with each progress event emitted while applying apps.
+	progress app.ProgressHandler
+}
+
+func newStopOptions(opts ...StopOption) stopOptions {
+	var o stopOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// StopWithUser records the given user as the user that stopped the experiment
+// in the audit log entries for the apps applied.
+func StopWithUser(u string) StopOption {
+	return func(o *stopOptions) {
+		o.user = u
+	}
+}
+
+type BundleOption func(*bundleOptions)
+
+type bundleOptions struct {
+	anonymizer *anonymize.Anonymizer
+	files      bool
+}
+
+func newBundleOptions(opts ...BundleOption) bundleOptions {
+	o := bundleOptions{files: true}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func BundleWithAnonymizer(a *anonymize.Anonymizer) BundleOption {
+	return func(o *bundleOptions) {
+		o.anonymizer = a
+	}
+}
+
+func BundleWithFiles(f bool) BundleOption {
+	return func(o *bundleOptions) {
+		o.files = f
+	}
+}
+
+// StopWithProgress calls the given handler with each progress event emitted
+// while applying apps, instead of logging them.
+func StopWithProgress(h app.ProgressHandler) StopOption {
+	return func(o *stopOptions) {
+		o.progress = h
+	}
+}
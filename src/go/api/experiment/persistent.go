@@ -0,0 +1,188 @@
+package experiment
+
+import (
+	"fmt"
+	"sort"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/file"
+	"phenix/util/mm"
+)
+
+// PersistentDisk is the disk of a node configured to keep its disk across
+// experiment restarts.
+type PersistentDisk struct {
+	Node string `json:"node"`
+	Disk string `json:"disk"`
+
+	// Host is the cluster host the disk lives on, which the node is kept on
+	// when the experiment is restarted. Empty if the disk hasn't been created
+	// yet (ie. the experiment hasn't been started since the node was configured
+	// to persist its disk).
+	Host string `json:"host,omitempty"`
+}
+
+// PersistentDisks returns the persistent disks for the nodes in the given
+// experiment configured to persist their disk, sorted by node.
+func PersistentDisks(name string) ([]PersistentDisk, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	var (
+		created = exp.Status.PersistentDisks()
+		disks   []PersistentDisk
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if !persistentNode(node) {
+			continue
+		}
+
+		hostname := node.General().Hostname()
+
+		disks = append(disks, PersistentDisk{
+			Node: hostname,
+			Disk: persistentDiskName(exp.Spec.ExperimentName(), hostname),
+			Host: created[hostname],
+		})
+	}
+
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Node < disks[j].Node })
+
+	return disks, nil
+}
+
+// ResetPersistentDisks deletes the persistent disks of the given nodes (all
+// nodes if none are given) in the given stopped experiment, so they're created
+// again from their base images the next time the experiment is started.
+func ResetPersistentDisks(name string, nodes ...string) error {
+	exp, err := Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if exp.Running() {
+		return fmt.Errorf("experiment is running")
+	}
+
+	if len(nodes) == 0 {
+		for node := range exp.Status.PersistentDisks() {
+			nodes = append(nodes, node)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, ok := exp.Status.PersistentDisks()[node]; !ok {
+			return fmt.Errorf("node %s has no persistent disk", node)
+		}
+	}
+
+	if err := deletePersistentDisks(exp, nodes...); err != nil {
+		return err
+	}
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return nil
+}
+
+// persistentNode returns true if the given node keeps its disk across
+// experiment restarts. Only KVM VMs that boot from a disk can.
+func persistentNode(node ifaces.NodeSpec) bool {
+	if node.External() || node.General() == nil || node.Hardware() == nil {
+		return false
+	}
+
+	if p := node.General().Persistent(); p == nil || !*p {
+		return false
+	}
+
+	if t := node.General().VMType(); t != "" && t != "kvm" {
+		return false
+	}
+
+	return node.Hardware().PXE() == nil && len(node.Hardware().Drives()) > 0
+}
+
+// persistentDiskName returns the name of the persistent disk for the given
+// node, which lives in the minimega files directory alongside snapshots. It
+// follows the snapshot naming convention, but with a different suffix so it
+// isn't deleted when the experiment is started.
+func persistentDiskName(exp, node string) string {
+	return fmt.Sprintf("%s_%s_%s_persistent", mm.Headnode(), exp, node)
+}
+
+// persistentSchedules returns a copy of the given experiment's schedules with
+// each node whose persistent disk has already been created scheduled on the
+// host the disk lives on, since changes to the disk only live on that host.
+// Nodes manually scheduled on a different host are left alone, but a warning
+// is returned for each.
+func persistentSchedules(exp *types.Experiment) (map[string]string, []error) {
+	var (
+		schedules = copySchedules(exp.Spec.Schedules())
+		created   = exp.Status.PersistentDisks()
+		warns     []error
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if !persistentNode(node) {
+			continue
+		}
+
+		hostname := node.General().Hostname()
+
+		host := created[hostname]
+		if host == "" {
+			continue
+		}
+
+		if sched := schedules[hostname]; sched != "" && sched != host {
+			warns = append(warns, fmt.Errorf("VM %s scheduled on %s but its persistent disk lives on %s - changes made to it since it was created won't be used", hostname, sched, host))
+			continue
+		}
+
+		schedules[hostname] = host
+	}
+
+	return schedules, warns
+}
+
+// recordPersistentDisks records the persistent disk of each node in the given
+// experiment that was just launched (given as a map of VM names to the hosts
+// they were launched on), so the disk isn't created again the next time the
+// experiment is started.
+func recordPersistentDisks(exp *types.Experiment, launched map[string]string) {
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if !persistentNode(node) {
+			continue
+		}
+
+		hostname := node.General().Hostname()
+
+		if host, ok := launched[hostname]; ok {
+			exp.Status.SetPersistentDisk(hostname, host)
+		}
+	}
+}
+
+// deletePersistentDisks deletes the persistent disks of the given nodes in the
+// given experiment across the cluster, removing them from the experiment's
+// status.
+func deletePersistentDisks(exp *types.Experiment, nodes ...string) error {
+	for _, node := range nodes {
+		disk := persistentDiskName(exp.Spec.ExperimentName(), node)
+
+		if err := file.DeleteFile(disk); err != nil {
+			return fmt.Errorf("deleting persistent disk for VM %s in experiment %s: %w", node, exp.Metadata.Name, err)
+		}
+
+		exp.Status.ClearPersistentDisk(node)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package experiment
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+)
+
+func TestPersistentSchedules(t *testing.T) {
+	newNode := func(hostname string, persistent bool) *v1.Node {
+		node := &v1.Node{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: hostname},
+			HardwareF: &v1.Hardware{DrivesF: []*v1.Drive{{ImageF: "base.qc2"}}},
+		}
+
+		node.GeneralF.SetSnapshot(true)
+		node.GeneralF.SetPersistent(persistent)
+
+		return node
+	}
+
+	var (
+		db    = newNode("db", true)
+		web   = newNode("web", true)
+		other = newNode("other", false)
+	)
+
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+	exp.Spec.(*v1.ExperimentSpec).TopologyF = &v1.TopologySpec{NodesF: []*v1.Node{db, web, other}}
+	exp.Spec.SetSchedule(map[string]string{"web": "compute2"})
+
+	// Nothing is pinned until the persistent disks have been created.
+	schedules, warns := persistentSchedules(exp)
+
+	if len(schedules) != 1 || len(warns) != 0 {
+		t.Logf("expected no pinned nodes, got %v (warnings: %v)", schedules, warns)
+		t.FailNow()
+	}
+
+	recordPersistentDisks(exp, map[string]string{"db": "compute1", "web": "compute1", "other": "compute3"})
+
+	if disks := exp.Status.PersistentDisks(); len(disks) != 2 || disks["db"] != "compute1" {
+		t.Logf("expected persistent disks for db and web, got %v", disks)
+		t.FailNow()
+	}
+
+	schedules, warns = persistentSchedules(exp)
+
+	if schedules["db"] != "compute1" {
+		t.Logf("expected db to be pinned to compute1, got %s", schedules["db"])
+		t.FailNow()
+	}
+
+	if schedules["web"] != "compute2" || len(warns) != 1 {
+		t.Logf("expected manual schedule for web to be kept with a warning, got %s (warnings: %v)", schedules["web"], warns)
+		t.FailNow()
+	}
+
+	if len(exp.Spec.Schedules()) != 1 {
+		t.Log("expected experiment schedules to be left alone")
+		t.FailNow()
+	}
+
+	script := newLaunchScript(exp.Spec, nil, map[string]string{"db": "compute1"})
+
+	if script.CreatePersistentDisk(db) || !script.CreatePersistentDisk(web) {
+		t.Log("expected only web persistent disk to need creating")
+		t.FailNow()
+	}
+
+	if script.LaunchSnapshot(db) || !script.LaunchSnapshot(other) {
+		t.Log("expected only non-persistent nodes to be launched with snapshot disks")
+		t.FailNow()
+	}
+
+	lite, _ := GetStartProfile("lite")
+	restore := lite.snapshot(exp.Spec.Topology().Nodes())
+
+	if persistentNode(db) {
+		t.Log("expected lite profile to disable persistent disks")
+		t.FailNow()
+	}
+
+	restore()
+
+	if !persistentNode(db) {
+		t.Log("expected persistent setting to be restored")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,225 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/file"
+	"phenix/util/journal"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// Preload is the result of distributing an experiment's images to the cluster
+// hosts its VMs will be launched on ahead of starting it.
+type Preload struct {
+	Experiment string    `json:"experiment"`
+	State      string    `json:"state"` // running, done, or failed
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished,omitempty"`
+
+	// For is the scheduled start the images were preloaded for, if any.
+	For time.Time `json:"for,omitempty"`
+
+	Transfers []PreloadTransfer `json:"transfers"`
+}
+
+// PreloadTransfer is an image copied (or skipped) to a cluster host while
+// preloading an experiment's images.
+type PreloadTransfer struct {
+	Image string `json:"image"`
+	Host  string `json:"host"`
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	preloads   = make(map[string]*Preload)
+	preloadsMu sync.Mutex
+)
+
+// PreloadImages copies the images used by the VMs in the given stopped
+// experiment to the cluster hosts they're scheduled on, so starting the
+// experiment later isn't slowed down by image transfers. VMs that aren't
+// scheduled on a specific host have their images copied to every cluster host
+// (other than the headnode, which already has all images). Only images in the
+// minimega files directory can be preloaded.
+func PreloadImages(ctx context.Context, name string) (Preload, error) {
+	return preloadImages(ctx, name, time.Time{})
+}
+
+// PreloadStatus returns the latest preload of the given experiment's images
+// (which may still be running), if any.
+func PreloadStatus(name string) (Preload, bool) {
+	preloadsMu.Lock()
+	defer preloadsMu.Unlock()
+
+	p, ok := preloads[name]
+	if !ok {
+		return Preload{}, false
+	}
+
+	return *p, true
+}
+
+func preloadImages(ctx context.Context, name string, at time.Time) (Preload, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return Preload{}, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if exp.Running() {
+		return Preload{}, fmt.Errorf("experiment is running")
+	}
+
+	preloadsMu.Lock()
+
+	if p, ok := preloads[name]; ok && p.State == "running" {
+		preloadsMu.Unlock()
+		return *p, fmt.Errorf("images already being preloaded for experiment %s", name)
+	}
+
+	preload := &Preload{Experiment: name, State: "running", Started: time.Now(), For: at}
+	preloads[name] = preload
+
+	preloadsMu.Unlock()
+
+	transfers, err := preloadTransfers(exp)
+	if err != nil {
+		return finishPreload(preload, nil, err)
+	}
+
+	journal.Record(name, journal.CategoryLifecycle, "", "preloading %d images onto cluster hosts", len(transfers))
+
+	for i, t := range transfers {
+		select {
+		case <-ctx.Done():
+			return finishPreload(preload, transfers, ctx.Err())
+		default:
+		}
+
+		if t.Error != "" {
+			continue
+		}
+
+		plog.Debug("preloading experiment image", "exp", name, "image", t.Image, "host", t.Host)
+
+		if err := file.CopyFile(t.Image, t.Host, nil); err != nil {
+			transfers[i].Error = err.Error()
+		}
+	}
+
+	return finishPreload(preload, transfers, nil)
+}
+
+func finishPreload(preload *Preload, transfers []PreloadTransfer, err error) (Preload, error) {
+	preloadsMu.Lock()
+	defer preloadsMu.Unlock()
+
+	preload.Finished = time.Now()
+	preload.Transfers = transfers
+	preload.State = "done"
+
+	var failed int
+
+	for _, t := range transfers {
+		if t.Error != "" {
+			failed++
+		}
+	}
+
+	if err != nil || failed > 0 {
+		preload.State = "failed"
+	}
+
+	if err != nil {
+		journal.Record(preload.Experiment, journal.CategoryLifecycle, "", "preloading images failed: %v", err)
+		return *preload, fmt.Errorf("preloading images for experiment %s: %w", preload.Experiment, err)
+	}
+
+	journal.Record(preload.Experiment, journal.CategoryLifecycle, "", "preloaded images onto cluster hosts (%d of %d transfers failed)", failed, len(transfers))
+
+	return *preload, nil
+}
+
+// preloadTransfers returns the image transfers needed to preload the given
+// experiment's images, sorted by host and image. Images that can't be preloaded
+// are included with an error.
+func preloadTransfers(exp *types.Experiment) ([]PreloadTransfer, error) {
+	// Everything runs on the headnode, which already has every image.
+	if mode := exp.Spec.DeployMode(); mode == "only-headnode" {
+		return nil, nil
+	}
+
+	cluster, err := mm.GetClusterHosts(true)
+	if err != nil {
+		return nil, fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	var (
+		filesDir     = util.GetMMFilesDirectory()
+		schedules, _ = persistentSchedules(exp)
+		needed       = make(map[PreloadTransfer]struct{})
+	)
+
+	for _, node := range exp.Spec.Topology().BootableNodes() {
+		if node.External() || node.Hardware() == nil {
+			continue
+		}
+
+		var hosts []string
+
+		if host := schedules[node.General().Hostname()]; host != "" {
+			hosts = []string{host}
+		} else {
+			for _, host := range cluster {
+				hosts = append(hosts, host.Name)
+			}
+		}
+
+		for _, drive := range node.Hardware().Drives() {
+			image := drive.Image()
+
+			var errMsg string
+
+			if filepath.IsAbs(image) {
+				rel, err := filepath.Rel(filesDir, image)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					errMsg = "image not in minimega files directory"
+				} else {
+					image = rel
+				}
+			}
+
+			for _, host := range hosts {
+				if mm.IsHeadnode(host) {
+					continue
+				}
+
+				needed[PreloadTransfer{Image: image, Host: host, Error: errMsg}] = struct{}{}
+			}
+		}
+	}
+
+	transfers := make([]PreloadTransfer, 0, len(needed))
+
+	for t := range needed {
+		transfers = append(transfers, t)
+	}
+
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].Host == transfers[j].Host {
+			return transfers[i].Image < transfers[j].Image
+		}
+
+		return transfers[i].Host < transfers[j].Host
+	})
+
+	return transfers, nil
+}
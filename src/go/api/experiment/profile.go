@@ -0,0 +1,120 @@
+package experiment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ifaces "phenix/types/interfaces"
+)
+
+// StartProfile controls the expensive behaviors of starting an experiment in
+// one switch, so iterative development loops can start experiments quickly
+// while exercise starts stay thorough.
+type StartProfile struct {
+	Name string `json:"name"`
+
+	// SkipApps are the names of default or user apps to skip when starting.
+	SkipApps []string `json:"skipApps,omitempty"`
+
+	// SkipOptionalApps skips scenario apps marked as optional.
+	SkipOptionalApps bool `json:"skipOptionalApps,omitempty"`
+
+	// Snapshot forces every VM to use snapshot disks, so disk writes are thrown
+	// away instead of persisted to the base images (or to persistent disks).
+	Snapshot bool `json:"snapshot,omitempty"`
+
+	// DelayScale scales the timer based start delays of VMs. A value of 1 (or
+	// 0) keeps the delays configured in the topology.
+	DelayScale float64 `json:"delayScale,omitempty"`
+}
+
+// Built-in start profiles. The full profile starts experiments exactly as
+// configured and is used when no profile is given.
+var profiles = map[string]StartProfile{
+	"full": {Name: "full"},
+	"lite": {
+		Name:             "lite",
+		SkipApps:         []string{"soh"},
+		SkipOptionalApps: true,
+		Snapshot:         true,
+		DelayScale:       0.1,
+	},
+}
+
+// StartProfiles returns the names of the available start profiles, sorted.
+func StartProfiles() []string {
+	names := make([]string, 0, len(profiles))
+
+	for name := range profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// GetStartProfile returns the start profile with the given name. An empty name
+// returns the full profile.
+func GetStartProfile(name string) (StartProfile, error) {
+	if name == "" {
+		name = "full"
+	}
+
+	p, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return StartProfile{}, fmt.Errorf("unknown start profile %s (must be one of %s)", name, strings.Join(StartProfiles(), ", "))
+	}
+
+	return p, nil
+}
+
+// delay returns the given VM start delay scaled by the profile.
+func (this StartProfile) delay(d time.Duration) time.Duration {
+	if this.DelayScale <= 0 {
+		return d
+	}
+
+	return time.Duration(float64(d) * this.DelayScale)
+}
+
+// snapshot forces the given nodes to use snapshot disks (instead of persistent
+// disks) if the profile calls for it, returning a function that restores the
+// disk settings configured in the topology so the profile isn't persisted with
+// the experiment.
+func (this StartProfile) snapshot(nodes []ifaces.NodeSpec) func() {
+	if !this.Snapshot {
+		return func() {}
+	}
+
+	var (
+		orig       = make(map[ifaces.NodeSpec]bool)
+		persistent = make(map[ifaces.NodeSpec]bool)
+	)
+
+	for _, node := range nodes {
+		if node.External() || node.General() == nil {
+			continue
+		}
+
+		orig[node] = *node.General().Snapshot()
+		node.General().SetSnapshot(true)
+
+		if persistentNode(node) {
+			persistent[node] = true
+			node.General().SetPersistent(false)
+		}
+	}
+
+	return func() {
+		for node, snapshot := range orig {
+			node.General().SetSnapshot(snapshot)
+		}
+
+		for node := range persistent {
+			node.General().SetPersistent(true)
+		}
+	}
+}
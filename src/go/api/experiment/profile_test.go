@@ -0,0 +1,58 @@
+package experiment
+
+import (
+	"testing"
+	"time"
+
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+func TestStartProfile(t *testing.T) {
+	full, err := GetStartProfile("")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if full.Name != "full" || full.delay(time.Minute) != time.Minute {
+		t.Logf("expected full profile to keep delays, got %+v", full)
+		t.FailNow()
+	}
+
+	lite, err := GetStartProfile("LITE")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if d := lite.delay(time.Minute); d != 6*time.Second {
+		t.Logf("expected lite profile to reduce delay to 6s, got %v", d)
+		t.FailNow()
+	}
+
+	if _, err := GetStartProfile("foo"); err == nil {
+		t.Log("expected error for unknown profile")
+		t.FailNow()
+	}
+
+	persistent := new(v1.Node)
+	persistent.GeneralF = &v1.General{HostnameF: "persistent"}
+	persistent.GeneralF.SetSnapshot(false)
+
+	nodes := []ifaces.NodeSpec{persistent}
+
+	restore := lite.snapshot(nodes)
+
+	if !*persistent.General().Snapshot() {
+		t.Log("expected lite profile to force snapshot disks")
+		t.FailNow()
+	}
+
+	restore()
+
+	if *persistent.General().Snapshot() {
+		t.Log("expected snapshot setting to be restored")
+		t.FailNow()
+	}
+}
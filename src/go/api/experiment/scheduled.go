@@ -0,0 +1,180 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/journal"
+	"phenix/util/plog"
+)
+
+// StartAtAnnotation is the experiment annotation the time (RFC3339) a stopped
+// experiment is scheduled to be started at is stored in.
+const StartAtAnnotation = "phenix/start-at"
+
+// ScheduleStart schedules the given stopped experiment to be started at the
+// given time. Scheduled starts are only carried out while the UI server is
+// running (see StartScheduled). A zero time cancels the scheduled start.
+func ScheduleStart(name string, at time.Time, user string) error {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if at.IsZero() {
+		if _, ok := c.Metadata.Annotations[StartAtAnnotation]; !ok {
+			return nil
+		}
+
+		delete(c.Metadata.Annotations, StartAtAnnotation)
+	} else {
+		if exp.Running() {
+			return fmt.Errorf("experiment is running")
+		}
+
+		if !at.After(time.Now()) {
+			return fmt.Errorf("scheduled start must be in the future")
+		}
+
+		if c.Metadata.Annotations == nil {
+			c.Metadata.Annotations = make(map[string]string)
+		}
+
+		c.Metadata.Annotations[StartAtAnnotation] = at.UTC().Format(time.RFC3339)
+	}
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	if at.IsZero() {
+		journal.Record(name, journal.CategoryLifecycle, user, "scheduled start canceled")
+	} else {
+		journal.Record(name, journal.CategoryLifecycle, user, "experiment scheduled to start at %s", at.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// ScheduledStart returns the time the given experiment is scheduled to be
+// started at, if any.
+func ScheduledStart(exp types.Experiment) (time.Time, bool) {
+	v, ok := exp.Metadata.Annotations[StartAtAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	at, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
+
+// StartScheduled checks for experiments with a scheduled start at the given
+// interval until the given context is canceled, calling the given function to
+// start each experiment once its scheduled start is due. If preload is greater
+// than zero, the images of each experiment are preloaded onto the cluster
+// hosts its VMs will be launched on (see PreloadImages) in the background that
+// long before its scheduled start, so starting it is limited by how long its
+// VMs take to boot rather than image transfers.
+func StartScheduled(ctx context.Context, interval, preload time.Duration, start func(string) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			runScheduled(ctx, time.Now(), preload, start)
+		}
+	}()
+}
+
+func runScheduled(ctx context.Context, now time.Time, preload time.Duration, start func(string) error) {
+	// Experiments that can't be decoded are skipped, but the rest are still
+	// returned.
+	exps, err := List()
+	if err != nil {
+		plog.Error("getting experiments with scheduled starts", "err", err)
+	}
+
+	for _, exp := range exps {
+		at, ok := ScheduledStart(exp)
+		if !ok {
+			continue
+		}
+
+		name := exp.Metadata.Name
+
+		switch scheduledAction(exp, at, now, preload) {
+		case "cancel":
+			// Started some other way before its scheduled start was due.
+			if err := ScheduleStart(name, time.Time{}, ""); err != nil {
+				plog.Error("canceling scheduled start of running experiment", "exp", name, "err", err)
+			}
+		case "start":
+			// Cleared first so a failed start isn't retried every interval.
+			if err := ScheduleStart(name, time.Time{}, ""); err != nil {
+				plog.Error("clearing scheduled start of experiment", "exp", name, "err", err)
+				continue
+			}
+
+			plog.Info("starting experiment per its scheduled start", "exp", name, "at", at)
+			journal.Record(name, journal.CategoryLifecycle, "", "starting experiment per its scheduled start")
+
+			go func(name string) {
+				if err := start(name); err != nil {
+					plog.Error("starting experiment per its scheduled start", "exp", name, "err", err)
+					journal.Record(name, journal.CategoryLifecycle, "", "scheduled start failed: %v", err)
+				}
+			}(name)
+		case "preload":
+			plog.Info("preloading experiment images for scheduled start", "exp", name, "at", at)
+
+			go func(name string, at time.Time) {
+				if _, err := preloadImages(ctx, name, at); err != nil {
+					plog.Error("preloading experiment images for scheduled start", "exp", name, "err", err)
+				}
+			}(name, at)
+		}
+	}
+}
+
+// scheduledAction returns what to do for the given experiment scheduled to
+// start at the given time: `cancel` its scheduled start, `start` it, `preload`
+// its images, or nothing.
+func scheduledAction(exp types.Experiment, at, now time.Time, preload time.Duration) string {
+	if exp.Running() {
+		return "cancel"
+	}
+
+	if !now.Before(at) {
+		return "start"
+	}
+
+	if preload <= 0 || now.Before(at.Add(-preload)) {
+		return ""
+	}
+
+	// Only preload once per scheduled start (unless it's rescheduled).
+	if p, ok := PreloadStatus(exp.Metadata.Name); ok && (p.State == "running" || p.For.Equal(at)) {
+		return ""
+	}
+
+	return "preload"
+}
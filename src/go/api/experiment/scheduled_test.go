@@ -0,0 +1,69 @@
+package experiment
+
+import (
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+)
+
+func TestScheduledAction(t *testing.T) {
+	var (
+		now = time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC)
+		at  = now.Add(2 * time.Hour)
+	)
+
+	exp := types.NewExperiment(store.ConfigMetadata{
+		Name:        "scheduled-test",
+		Annotations: map[string]string{StartAtAnnotation: at.Format(time.RFC3339)},
+	})
+
+	scheduled, ok := ScheduledStart(*exp)
+	if !ok || !scheduled.Equal(at) {
+		t.Logf("expected scheduled start at %v, got %v", at, scheduled)
+		t.FailNow()
+	}
+
+	if action := scheduledAction(*exp, at, now, time.Hour); action != "" {
+		t.Logf("expected no action before preload window, got %s", action)
+		t.FailNow()
+	}
+
+	if action := scheduledAction(*exp, at, now, 3*time.Hour); action != "preload" {
+		t.Logf("expected preload within preload window, got %s", action)
+		t.FailNow()
+	}
+
+	if action := scheduledAction(*exp, at, now, 0); action != "" {
+		t.Logf("expected no preload when disabled, got %s", action)
+		t.FailNow()
+	}
+
+	preloadsMu.Lock()
+	preloads[exp.Metadata.Name] = &Preload{Experiment: exp.Metadata.Name, State: "done", For: at}
+	preloadsMu.Unlock()
+
+	defer func() {
+		preloadsMu.Lock()
+		delete(preloads, exp.Metadata.Name)
+		preloadsMu.Unlock()
+	}()
+
+	if action := scheduledAction(*exp, at, now, 3*time.Hour); action != "" {
+		t.Logf("expected images to only be preloaded once, got %s", action)
+		t.FailNow()
+	}
+
+	if action := scheduledAction(*exp, at, at, 3*time.Hour); action != "start" {
+		t.Logf("expected start once due, got %s", action)
+		t.FailNow()
+	}
+
+	exp.Status.SetStartTime(now.Format(time.RFC3339))
+
+	if action := scheduledAction(*exp, at, at, 3*time.Hour); action != "cancel" {
+		t.Logf("expected running experiment to have scheduled start canceled, got %s", action)
+		t.FailNow()
+	}
+}
@@ -1,5 +1,17 @@
 package experiment
 
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/common"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/exp/slices"
+)
+
 func ClusterNodes(exp string) ([]string, error) {
 	nodeMap := make(map[string]struct{})
 
@@ -24,3 +36,69 @@ func ClusterNodes(exp string) ([]string, error) {
 
 	return nodes, nil
 }
+
+// validateVMOverrides validates the extra QEMU arguments and minimega vm config
+// overrides for each node in the given topology against the admin-configured
+// allowlists. Only QEMU argument names are validated, not their values, and
+// each value must directly follow an argument name since QEMU treats
+// positional arguments as disk images. Since overrides end up in the minimega
+// script one per line, control characters (ie. newlines) are rejected in all of
+// them so they can't be used to inject additional minimega commands.
+func validateVMOverrides(topo ifaces.TopologySpec) error {
+	var errs error
+
+	for _, node := range topo.Nodes() {
+		var (
+			host = node.General().Hostname()
+			flag string
+		)
+
+		for _, arg := range node.QEMUAppend() {
+			if hasControlChars(arg) {
+				errs = multierror.Append(errs, fmt.Errorf("QEMU argument %q for node %s contains control characters", arg, host))
+				continue
+			}
+
+			for _, field := range strings.Fields(arg) {
+				if strings.HasPrefix(field, "-") {
+					if !slices.Contains(common.QEMUArgAllowlist, field) {
+						errs = multierror.Append(errs, fmt.Errorf("QEMU argument %s for node %s is not allowed", field, host))
+					}
+
+					flag = field
+					continue
+				}
+
+				if flag == "" {
+					errs = multierror.Append(errs, fmt.Errorf("QEMU argument value %s for node %s must follow an argument", field, host))
+				}
+
+				flag = ""
+			}
+		}
+
+		for match, replacement := range node.Overrides() {
+			// QEMU overrides are quoted in the minimega script.
+			if hasControlChars(match+replacement) || strings.Contains(match+replacement, `"`) {
+				errs = multierror.Append(errs, fmt.Errorf("QEMU override %q for node %s contains control characters or quotes", match, host))
+			}
+		}
+
+		for config, value := range node.Advanced() {
+			if hasControlChars(config + value) {
+				errs = multierror.Append(errs, fmt.Errorf("vm config setting %q for node %s contains control characters", config, host))
+				continue
+			}
+
+			if len(common.VMConfigAllowlist) > 0 && !slices.Contains(common.VMConfigAllowlist, config) {
+				errs = multierror.Append(errs, fmt.Errorf("vm config setting %s for node %s is not allowed", config, host))
+			}
+		}
+	}
+
+	return errs
+}
+
+func hasControlChars(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
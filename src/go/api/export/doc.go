@@ -0,0 +1,6 @@
+// Implementation of the phenix topology export API. Topologies (including the
+// topologies of existing experiments) can be translated into vSphere
+// constructs, either as a plan of port groups and VM specs or as a Terraform
+// configuration using the vSphere provider, so the same scenario can be
+// reproduced in environments without minimega.
+package export
@@ -0,0 +1,393 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+)
+
+const (
+	FORMAT_VSPHERE   = "vsphere"
+	FORMAT_TERRAFORM = "terraform"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported export format")
+	ErrUnsupportedKind   = errors.New("unsupported export kind")
+
+	identRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// Plan is a topology translated into vSphere constructs.
+type Plan struct {
+	Datacenter string      `json:"datacenter,omitempty"`
+	Cluster    string      `json:"cluster,omitempty"`
+	Datastore  string      `json:"datastore,omitempty"`
+	Switch     string      `json:"switch,omitempty"`
+	DiskFolder string      `json:"diskFolder,omitempty"`
+	PortGroups []PortGroup `json:"portGroups"`
+	VMs        []VM        `json:"vms"`
+
+	// Nodes that couldn't be exported (ie. containers and external nodes),
+	// along with the reason they were skipped.
+	Skipped map[string]string `json:"skipped,omitempty"`
+}
+
+// PortGroup is a distributed port group for a single topology VLAN.
+type PortGroup struct {
+	ID     string `json:"-"`
+	Name   string `json:"name"`
+	VLANID int    `json:"vlanId"`
+}
+
+type VM struct {
+	ID         string `json:"-"`
+	Name       string `json:"name"`
+	Annotation string `json:"annotation,omitempty"`
+	GuestID    string `json:"guestId"`
+	NumCPUs    int    `json:"numCPUs"`
+	MemoryMB   int    `json:"memoryMB"`
+	Disks      []Disk `json:"disks"`
+	NICs       []NIC  `json:"nics"`
+}
+
+type Disk struct {
+	Label string `json:"label"`
+	Unit  int    `json:"unitNumber"`
+	Path  string `json:"path"`
+}
+
+type NIC struct {
+	Name        string   `json:"name"`
+	PortGroup   string   `json:"portGroup"`
+	PortGroupID string   `json:"-"`
+	AdapterType string   `json:"adapterType"`
+	MAC         string   `json:"macAddress,omitempty"`
+	Address     string   `json:"address,omitempty"`
+	Mask        int      `json:"mask,omitempty"`
+	Gateway     string   `json:"gateway,omitempty"`
+	DNS         []string `json:"dns,omitempty"`
+}
+
+type options struct {
+	datacenter string
+	cluster    string
+	datastore  string
+	vswitch    string
+	diskFolder string
+	vlanMin    int
+	aliases    map[string]int
+}
+
+type Option func(*options)
+
+func newOptions(opts ...Option) options {
+	o := options{vlanMin: 100, diskFolder: "phenix"}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func ExportWithDatacenter(d string) Option {
+	return func(o *options) {
+		o.datacenter = d
+	}
+}
+
+func ExportWithCluster(c string) Option {
+	return func(o *options) {
+		o.cluster = c
+	}
+}
+
+func ExportWithDatastore(d string) Option {
+	return func(o *options) {
+		o.datastore = d
+	}
+}
+
+// ExportWithSwitch sets the name of the distributed virtual switch port groups
+// are created on.
+func ExportWithSwitch(s string) Option {
+	return func(o *options) {
+		o.vswitch = s
+	}
+}
+
+// ExportWithDiskFolder sets the datastore folder VM disk images are expected
+// to have been uploaded to (as VMDKs). It defaults to `phenix`.
+func ExportWithDiskFolder(f string) Option {
+	return func(o *options) {
+		if f != "" {
+			o.diskFolder = f
+		}
+	}
+}
+
+// ExportWithVLANMin sets the first VLAN ID assigned to topology VLANs that
+// don't already have one. It defaults to 100.
+func ExportWithVLANMin(m int) Option {
+	return func(o *options) {
+		if m > 0 {
+			o.vlanMin = m
+		}
+	}
+}
+
+// ExportWithVLANAliases sets the VLAN IDs to use for the given topology VLANs.
+func ExportWithVLANAliases(a map[string]int) Option {
+	return func(o *options) {
+		o.aliases = a
+	}
+}
+
+// Config exports the topology in the given config, which can be either a
+// topology or an experiment (ie. `experiment/foo`), in the given format.
+// When exporting an experiment, any VLAN IDs already assigned to the
+// experiment are used for the corresponding port groups.
+func Config(name, format string, w io.Writer, opts ...Option) error {
+	c, err := store.NewConfig(name)
+	if err != nil {
+		return fmt.Errorf("parsing config name %s: %w", name, err)
+	}
+
+	var topo ifaces.TopologySpec
+
+	switch c.Kind {
+	case "Topology":
+		if err := store.Get(c); err != nil {
+			return fmt.Errorf("getting topology %s: %w", c.Metadata.Name, err)
+		}
+
+		topo, err = types.DecodeTopologyFromConfig(*c)
+		if err != nil {
+			return fmt.Errorf("decoding topology %s: %w", c.Metadata.Name, err)
+		}
+	case "Experiment":
+		exp, err := experiment.Get(c.Metadata.Name)
+		if err != nil {
+			return fmt.Errorf("getting experiment %s: %w", c.Metadata.Name, err)
+		}
+
+		aliases := make(map[string]int)
+
+		for k, v := range exp.Spec.VLANs().Aliases() {
+			aliases[k] = v
+		}
+
+		for k, v := range exp.Status.VLANs() {
+			aliases[k] = v
+		}
+
+		// Put VLAN alias option first so user-provided aliases take precedence.
+		opts = append([]Option{ExportWithVLANAliases(aliases)}, opts...)
+		topo = exp.Spec.Topology()
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedKind, c.Kind)
+	}
+
+	return Topology(topo, format, w, opts...)
+}
+
+// Topology exports the given topology in the given format, either as a JSON
+// vSphere plan or as a Terraform configuration.
+func Topology(topo ifaces.TopologySpec, format string, w io.Writer, opts ...Option) error {
+	plan := NewPlan(topo, opts...)
+
+	switch format {
+	case FORMAT_VSPHERE:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(plan); err != nil {
+			return fmt.Errorf("encoding vSphere plan: %w", err)
+		}
+	case FORMAT_TERRAFORM:
+		if err := tmpl.GenerateFromTemplate("terraform_vsphere.tmpl", plan, w); err != nil {
+			return fmt.Errorf("generating Terraform configuration: %w", err)
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+
+	return nil
+}
+
+// NewPlan translates the given topology into a vSphere plan. Each topology
+// VLAN becomes a port group, and each VM becomes a VM spec attached to the
+// port groups for its interfaces. Container and external nodes are skipped,
+// as are serial interfaces.
+func NewPlan(topo ifaces.TopologySpec, opts ...Option) Plan {
+	o := newOptions(opts...)
+
+	plan := Plan{
+		Datacenter: o.datacenter,
+		Cluster:    o.cluster,
+		Datastore:  o.datastore,
+		Switch:     o.vswitch,
+		DiskFolder: o.diskFolder,
+		Skipped:    make(map[string]string),
+	}
+
+	var (
+		groups = make(map[string]*PortGroup)
+		used   = make(map[int]struct{})
+		ids    = make(map[string]struct{})
+	)
+
+	for _, id := range o.aliases {
+		used[id] = struct{}{}
+	}
+
+	portGroup := func(vlan string) *PortGroup {
+		if pg, ok := groups[vlan]; ok {
+			return pg
+		}
+
+		id, ok := o.aliases[vlan]
+		if !ok {
+			id = o.vlanMin
+
+			for {
+				if _, taken := used[id]; !taken {
+					break
+				}
+
+				id++
+			}
+
+			used[id] = struct{}{}
+		}
+
+		pg := &PortGroup{ID: identifier("pg_"+vlan, ids), Name: vlan, VLANID: id}
+		groups[vlan] = pg
+
+		return pg
+	}
+
+	for _, node := range topo.Nodes() {
+		host := node.General().Hostname()
+
+		if node.External() {
+			plan.Skipped[host] = "external node"
+			continue
+		}
+
+		if strings.EqualFold(node.General().VMType(), "container") {
+			plan.Skipped[host] = "container nodes are not supported by vSphere"
+			continue
+		}
+
+		vm := VM{
+			ID:         identifier("vm_"+host, ids),
+			Name:       host,
+			Annotation: node.General().Description(),
+			GuestID:    guestID(node.Hardware().OSType()),
+			NumCPUs:    node.Hardware().VCPU(),
+			MemoryMB:   node.Hardware().Memory(),
+		}
+
+		for i, drive := range node.Hardware().Drives() {
+			image := filepath.Base(drive.Image())
+			image = strings.TrimSuffix(image, filepath.Ext(image)) + ".vmdk"
+
+			vm.Disks = append(vm.Disks, Disk{
+				Label: fmt.Sprintf("disk%d", i),
+				Unit:  i,
+				Path:  filepath.Join(o.diskFolder, image),
+			})
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Type() == "serial" {
+				continue
+			}
+
+			pg := portGroup(iface.VLAN())
+
+			nic := NIC{
+				Name:        iface.Name(),
+				PortGroup:   pg.Name,
+				PortGroupID: pg.ID,
+				AdapterType: adapterType(iface.Driver()),
+				MAC:         iface.MAC(),
+			}
+
+			if iface.Proto() == "static" || iface.Proto() == "ospf" {
+				nic.Address = iface.Address()
+				nic.Mask = iface.Mask()
+				nic.Gateway = iface.Gateway()
+				nic.DNS = iface.DNS()
+			}
+
+			vm.NICs = append(vm.NICs, nic)
+		}
+
+		plan.VMs = append(plan.VMs, vm)
+	}
+
+	for _, pg := range groups {
+		plan.PortGroups = append(plan.PortGroups, *pg)
+	}
+
+	sort.Slice(plan.PortGroups, func(i, j int) bool {
+		return plan.PortGroups[i].VLANID < plan.PortGroups[j].VLANID
+	})
+
+	return plan
+}
+
+// identifier converts the given name into a unique Terraform identifier.
+func identifier(name string, ids map[string]struct{}) string {
+	id := identRegex.ReplaceAllString(name, "_")
+
+	for i := 1; ; i++ {
+		if _, ok := ids[id]; !ok {
+			break
+		}
+
+		id = fmt.Sprintf("%s_%d", identRegex.ReplaceAllString(name, "_"), i)
+	}
+
+	ids[id] = struct{}{}
+
+	return id
+}
+
+func guestID(os string) string {
+	switch strings.ToLower(os) {
+	case "windows":
+		return "windows9Server64Guest"
+	case "linux", "":
+		return "otherLinux64Guest"
+	}
+
+	return "otherGuest64"
+}
+
+// adapterType maps the given QEMU NIC driver to the equivalent vSphere network
+// adapter type, defaulting to vmxnet3.
+func adapterType(driver string) string {
+	switch strings.ToLower(driver) {
+	case "e1000":
+		return "e1000"
+	case "e1000e":
+		return "e1000e"
+	}
+
+	return "vmxnet3"
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func testTopology() *v1.TopologySpec {
+	external := true
+
+	return &v1.TopologySpec{
+		NodesF: []*v1.Node{
+			{
+				TypeF:    "VirtualMachine",
+				GeneralF: &v1.General{HostnameF: "ad-server", DescriptionF: "Active Directory"},
+				HardwareF: &v1.Hardware{
+					VCPUF:   2,
+					MemoryF: 4096,
+					OSTypeF: "windows",
+					DrivesF: []*v1.Drive{{ImageF: "win-2019.qc2"}},
+				},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", TypeF: "ethernet", ProtoF: "static", VLANF: "EXP-1", AddressF: "10.0.0.1", MaskF: 24, DriverF: "e1000"},
+						{NameF: "S0", TypeF: "serial", ProtoF: "static", VLANF: "EXP-S"},
+					},
+				},
+			},
+			{
+				TypeF:     "VirtualMachine",
+				GeneralF:  &v1.General{HostnameF: "client"},
+				HardwareF: &v1.Hardware{VCPUF: 1, MemoryF: 512, OSTypeF: "linux", DrivesF: []*v1.Drive{{ImageF: "/phenix/images/bionic.qc2"}}},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "eth0", TypeF: "ethernet", ProtoF: "dhcp", VLANF: "EXP-1"},
+						{NameF: "eth1", TypeF: "ethernet", ProtoF: "dhcp", VLANF: "MGMT"},
+					},
+				},
+			},
+			{
+				TypeF:     "VirtualMachine",
+				GeneralF:  &v1.General{HostnameF: "router", VMTypeF: "container"},
+				HardwareF: &v1.Hardware{},
+			},
+			{
+				TypeF:     "Router",
+				ExternalF: &external,
+				GeneralF:  &v1.General{HostnameF: "upstream"},
+			},
+		},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	plan := NewPlan(testTopology(), ExportWithVLANAliases(map[string]int{"MGMT": 100}))
+
+	if len(plan.VMs) != 2 {
+		t.Logf("expected 2 VMs, got %d", len(plan.VMs))
+		t.FailNow()
+	}
+
+	if len(plan.Skipped) != 2 {
+		t.Logf("expected 2 skipped nodes, got %v", plan.Skipped)
+		t.FailNow()
+	}
+
+	if len(plan.PortGroups) != 2 {
+		t.Logf("expected 2 port groups, got %d", len(plan.PortGroups))
+		t.FailNow()
+	}
+
+	// MGMT has an alias of 100, so EXP-1 should get the next VLAN ID.
+	if pg := plan.PortGroups[0]; pg.Name != "MGMT" || pg.VLANID != 100 {
+		t.Logf("expected MGMT port group with VLAN 100, got %s with VLAN %d", pg.Name, pg.VLANID)
+		t.FailNow()
+	}
+
+	if pg := plan.PortGroups[1]; pg.Name != "EXP-1" || pg.VLANID != 101 || pg.ID != "pg_EXP_1" {
+		t.Logf("expected EXP-1 port group (pg_EXP_1) with VLAN 101, got %s (%s) with VLAN %d", pg.Name, pg.ID, pg.VLANID)
+		t.FailNow()
+	}
+
+	ad := plan.VMs[0]
+
+	if ad.GuestID != "windows9Server64Guest" || ad.NumCPUs != 2 || ad.MemoryMB != 4096 {
+		t.Logf("unexpected hardware for ad-server VM: %+v", ad)
+		t.FailNow()
+	}
+
+	if len(ad.NICs) != 1 || ad.NICs[0].AdapterType != "e1000" || ad.NICs[0].Address != "10.0.0.1" {
+		t.Logf("expected single static e1000 NIC for ad-server VM, got %+v", ad.NICs)
+		t.FailNow()
+	}
+
+	if disk := plan.VMs[1].Disks[0]; disk.Path != "phenix/bionic.vmdk" {
+		t.Logf("expected client disk path phenix/bionic.vmdk, got %s", disk.Path)
+		t.FailNow()
+	}
+}
+
+func TestTopologyTerraform(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := Topology(testTopology(), FORMAT_TERRAFORM, &buf, ExportWithDatastore("datastore1")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	out := buf.String()
+
+	for _, expected := range []string{
+		`resource "vsphere_distributed_port_group" "pg_EXP_1"`,
+		`resource "vsphere_virtual_machine" "vm_ad_server"`,
+		`network_id   = vsphere_distributed_port_group.pg_MGMT.id`,
+		`default = "datastore1"`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Logf("expected Terraform configuration to include '%s'\n%s", expected, out)
+			t.FailNow()
+		}
+	}
+}
+
+func TestTopologyUnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := Topology(testTopology(), "bogus", &buf); err == nil {
+		t.Log("expected error for unsupported format")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,4 @@
+// Implementation of the phenix VM health API, which aggregates state of health
+// checks, app-registered expectations, crash detection, and CPU load
+// thresholds into a single health status per VM.
+package health
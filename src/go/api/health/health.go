@@ -0,0 +1,201 @@
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+)
+
+var (
+	// CPU load thresholds at which a VM's health is reported as warning or
+	// critical. For Linux VMs, load is the 1 minute load average per vCPU. For
+	// Windows VMs, load is the average processor load percentage divided by 100.
+	CPU_LOAD_WARNING  = 0.8
+	CPU_LOAD_CRITICAL = 1.0
+)
+
+// Get returns the health of every VM in the given experiment.
+func Get(expName string) (*Experiment, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	vms, err := vm.List(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s VMs: %w", expName, err)
+	}
+
+	return evaluate(exp, vms)
+}
+
+// GetVM returns the health of the given VM in the given experiment.
+func GetVM(expName, vmName string) (*VM, error) {
+	health, err := Get(expName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vm := range health.VMs {
+		if vm.Name == vmName {
+			return &vm, nil
+		}
+	}
+
+	return nil, fmt.Errorf("VM %s not found in experiment %s", vmName, expName)
+}
+
+func evaluate(exp *types.Experiment, vms []mm.VM) (*Experiment, error) {
+	health := &Experiment{
+		Experiment: exp.Metadata.Name,
+		Running:    exp.Running(),
+		Severity:   SEVERITY_OK,
+		Updated:    time.Now(),
+	}
+
+	// Health is only tracked while an experiment is running.
+	if !health.Running {
+		health.Severity = SEVERITY_UNKNOWN
+
+		for _, vm := range vms {
+			health.VMs = append(health.VMs, VM{Name: vm.Name, Severity: SEVERITY_UNKNOWN})
+		}
+
+		return health, nil
+	}
+
+	sohChecks, err := stateOfHealthChecks(exp, vms)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		expectations = make(map[string][]Check)
+		deferred     = make(map[string]bool)
+	)
+
+	for app, expects := range exp.Status.AppExpectations() {
+		for _, e := range expects {
+			expectations[e.VM()] = append(expectations[e.VM()], expectationCheck(app, e))
+		}
+	}
+
+	for _, name := range exp.Status.Deferred() {
+		deferred[name] = true
+	}
+
+	for _, vm := range vms {
+		h := VM{Name: vm.Name, Severity: SEVERITY_OK}
+
+		h.add(stateCheck(vm, deferred[vm.Name]))
+
+		for _, check := range sohChecks[vm.Name] {
+			h.add(check)
+		}
+
+		for _, check := range expectations[vm.Name] {
+			h.add(check)
+		}
+
+		if h.Severity.Worse(health.Severity) {
+			health.Severity = h.Severity
+		}
+
+		health.VMs = append(health.VMs, h)
+	}
+
+	return health, nil
+}
+
+// stateCheck detects VMs that have crashed or otherwise aren't running in
+// minimega as expected.
+func stateCheck(vm mm.VM, deferred bool) Check {
+	check := Check{Source: SOURCE_VM, Name: "state", Severity: SEVERITY_OK, Timestamp: time.Now()}
+
+	switch {
+	case vm.State == "" && vm.DoNotBoot:
+		check.Message = "VM not booted (do not boot set)"
+	case vm.State == "" && deferred:
+		check.Severity = SEVERITY_WARNING
+		check.Message = "VM deferred until there's cluster capacity to launch it"
+	case vm.State == "":
+		check.Severity = SEVERITY_CRITICAL
+		check.Message = "VM not found in minimega"
+	case vm.State == "EXTERNAL":
+		check.Message = "VM is external to minimega"
+	case vm.State == "ERROR":
+		check.Severity = SEVERITY_CRITICAL
+		check.Message = "VM is in an error state"
+	case vm.State == "QUIT":
+		check.Severity = SEVERITY_CRITICAL
+		check.Message = "VM has exited (crashed or shut down)"
+	case !vm.Running:
+		check.Severity = SEVERITY_WARNING
+		check.Message = fmt.Sprintf("VM is not running (%s)", strings.ToLower(vm.State))
+	default:
+		check.Message = "VM is running"
+	}
+
+	return check
+}
+
+// cpuLoadCheck checks the CPU load last recorded for the VM by the SoH app
+// against the CPU load thresholds. It returns false if no CPU load was
+// recorded.
+func cpuLoadCheck(vm mm.VM, load string) (Check, bool) {
+	if load == "" {
+		return Check{}, false
+	}
+
+	check := Check{Source: SOURCE_METRICS, Name: "cpuLoad", Severity: SEVERITY_OK, Timestamp: time.Now()}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(load), 64)
+	if err != nil {
+		// The SoH app records errors getting the CPU load in place of the load.
+		check.Severity = SEVERITY_UNKNOWN
+		check.Message = load
+
+		return check, true
+	}
+
+	if strings.EqualFold(vm.OSType, "windows") {
+		value = value / 100
+	} else if vm.CPUs > 0 {
+		value = value / float64(vm.CPUs)
+	}
+
+	switch {
+	case value >= CPU_LOAD_CRITICAL:
+		check.Severity = SEVERITY_CRITICAL
+	case value >= CPU_LOAD_WARNING:
+		check.Severity = SEVERITY_WARNING
+	}
+
+	check.Message = fmt.Sprintf("CPU load at %.0f%%", value*100)
+
+	return check, true
+}
+
+func expectationCheck(app string, e ifaces.Expectation) Check {
+	check := Check{Source: SOURCE_EXPECTATION, App: app, Name: e.Name(), Severity: SEVERITY_OK, Message: e.Message()}
+
+	if !e.Met() {
+		switch severity := Severity(e.Severity()); severity {
+		case SEVERITY_WARNING, SEVERITY_UNKNOWN:
+			check.Severity = severity
+		default:
+			check.Severity = SEVERITY_CRITICAL
+		}
+	}
+
+	check.Timestamp, _ = time.Parse(time.RFC3339, e.Updated())
+
+	return check
+}
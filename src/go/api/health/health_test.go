@@ -0,0 +1,99 @@
+package health
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	"phenix/util/mm"
+)
+
+func TestEvaluate(t *testing.T) {
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+
+	exp.Status.SetStartTime("2024-03-01T00:00:00Z")
+	exp.Status.SetDeferred([]string{"deferred"})
+
+	exp.Status.SetAppStatus("soh", map[string]interface{}{
+		"hosts": []map[string]interface{}{
+			{
+				"hostname":   "host-00",
+				"cpuLoad":    "1.5",
+				"processes":  []map[string]interface{}{{"success": "process foo running"}},
+				"listeners":  []map[string]interface{}{{"error": "not listening on :80"}},
+				"networking": []map[string]interface{}{{"success": "interface up"}},
+			},
+			{
+				"hostname": "host-01",
+				"cpuLoad":  "executing command: C2 client not active",
+			},
+		},
+	})
+
+	exp.Status.SetAppExpectation("traffic", &v1.Expectation{VMF: "host-01", NameF: "flows", SeverityF: "warning", MessageF: "no flows seen"})
+	exp.Status.SetAppExpectation("traffic", &v1.Expectation{VMF: "host-02", NameF: "flows", MetF: true})
+
+	vms := []mm.VM{
+		{Name: "host-00", State: "RUNNING", Running: true, CPUs: 2},
+		{Name: "host-01", State: "RUNNING", Running: true, CPUs: 1},
+		{Name: "host-02", State: "QUIT"},
+		{Name: "deferred"},
+		{Name: "dnb", DoNotBoot: true},
+	}
+
+	health, err := evaluate(exp, vms)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string]Severity{
+		"host-00":  SEVERITY_CRITICAL, // failed listener check
+		"host-01":  SEVERITY_WARNING,  // unmet expectation
+		"host-02":  SEVERITY_CRITICAL, // crashed
+		"deferred": SEVERITY_WARNING,
+		"dnb":      SEVERITY_OK,
+	}
+
+	for _, vm := range health.VMs {
+		if vm.Severity != expected[vm.Name] {
+			t.Logf("expected %s severity for VM %s, got %s", expected[vm.Name], vm.Name, vm.Severity)
+			t.FailNow()
+		}
+	}
+
+	if health.Severity != SEVERITY_CRITICAL {
+		t.Logf("expected critical experiment severity, got %s", health.Severity)
+		t.FailNow()
+	}
+
+	var load *Check
+
+	for _, check := range health.VMs[0].Checks {
+		if check.Source == SOURCE_METRICS {
+			load = &check
+		}
+	}
+
+	// A load average of 1.5 across 2 vCPUs is 75% utilization.
+	if load == nil || load.Severity != SEVERITY_OK {
+		t.Logf("expected ok CPU load check for host-00, got %+v", load)
+		t.FailNow()
+	}
+}
+
+func TestEvaluateStopped(t *testing.T) {
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+
+	health, err := evaluate(exp, []mm.VM{{Name: "host-00"}})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if health.Severity != SEVERITY_UNKNOWN || health.VMs[0].Severity != SEVERITY_UNKNOWN {
+		t.Logf("expected unknown severity for stopped experiment, got %s", health.Severity)
+		t.FailNow()
+	}
+}
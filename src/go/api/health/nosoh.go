@@ -0,0 +1,14 @@
+//go:build nosoh
+
+package health
+
+import (
+	"phenix/types"
+	"phenix/util/mm"
+)
+
+// The state of health app isn't compiled into phenix, so there's never any
+// state of health (or CPU load) recorded for VMs.
+func stateOfHealthChecks(exp *types.Experiment, vms []mm.VM) (map[string][]Check, error) {
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+//go:build !nosoh
+
+package health
+
+import (
+	"fmt"
+	"time"
+
+	"phenix/api/soh"
+	"phenix/types"
+	"phenix/util/mm"
+)
+
+type sohCategory struct {
+	name   string
+	states []soh.State
+}
+
+// sohCategories returns the states recorded for each state of health check
+// category.
+func sohCategories(state *soh.HostState) []sohCategory {
+	return []sohCategory{
+		{"networking", state.Networking},
+		{"reachability", state.Reachability},
+		{"processes", state.Processes},
+		{"listeners", state.Listeners},
+		{"customTests", state.CustomTests},
+		{"windows", state.Windows},
+		{"liveness", state.Liveness},
+	}
+}
+
+// stateOfHealthChecks returns the checks for the state of health (including
+// CPU load) recorded by the SoH app for each of the given VMs, keyed by VM
+// name.
+func stateOfHealthChecks(exp *types.Experiment, vms []mm.VM) (map[string][]Check, error) {
+	states, err := soh.HostStates(exp)
+	if err != nil {
+		return nil, fmt.Errorf("getting state of health for experiment %s: %w", exp.Metadata.Name, err)
+	}
+
+	checks := make(map[string][]Check)
+
+	for _, vm := range vms {
+		state, ok := states[vm.Name]
+		if !ok {
+			continue
+		}
+
+		for _, category := range sohCategories(state) {
+			for _, s := range category.states {
+				checks[vm.Name] = append(checks[vm.Name], sohCheck(category.name, s))
+			}
+		}
+
+		if check, ok := cpuLoadCheck(vm, state.CPULoad); ok {
+			checks[vm.Name] = append(checks[vm.Name], check)
+		}
+	}
+
+	return checks, nil
+}
+
+func sohCheck(category string, state soh.State) Check {
+	check := Check{Source: SOURCE_SOH, Name: category, Severity: SEVERITY_OK, Message: state.Success}
+
+	if state.Error != "" {
+		check.Severity = SEVERITY_CRITICAL
+		check.Message = state.Error
+	}
+
+	check.Timestamp, _ = time.Parse(time.RFC3339, state.Timestamp)
+
+	return check
+}
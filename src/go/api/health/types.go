@@ -0,0 +1,69 @@
+package health
+
+import "time"
+
+type Severity string
+
+const (
+	SEVERITY_OK       Severity = "ok"
+	SEVERITY_UNKNOWN  Severity = "unknown"
+	SEVERITY_WARNING  Severity = "warning"
+	SEVERITY_CRITICAL Severity = "critical"
+)
+
+var severityRanks = map[Severity]int{
+	SEVERITY_OK:       0,
+	SEVERITY_UNKNOWN:  1,
+	SEVERITY_WARNING:  2,
+	SEVERITY_CRITICAL: 3,
+}
+
+// Worse returns true if this severity is worse than the other one.
+func (this Severity) Worse(other Severity) bool {
+	return severityRanks[this] > severityRanks[other]
+}
+
+type Source string
+
+const (
+	SOURCE_SOH         Source = "soh"
+	SOURCE_EXPECTATION Source = "expectation"
+	SOURCE_VM          Source = "vm"
+	SOURCE_METRICS     Source = "metrics"
+)
+
+// Check is a single health check contributing to a VM's health. For checks
+// sourced from app-registered expectations, App is the app that registered it.
+type Check struct {
+	Source    Source    `json:"source"`
+	App       string    `json:"app,omitempty"`
+	Name      string    `json:"name"`
+	Severity  Severity  `json:"severity"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// VM is the health of a single VM: the worst severity of all of its checks.
+type VM struct {
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	Checks   []Check  `json:"checks"`
+}
+
+func (this *VM) add(check Check) {
+	this.Checks = append(this.Checks, check)
+
+	if check.Severity.Worse(this.Severity) {
+		this.Severity = check.Severity
+	}
+}
+
+// Experiment is the health of every VM in an experiment. Its severity is the
+// worst severity of all of its VMs.
+type Experiment struct {
+	Experiment string    `json:"experiment"`
+	Running    bool      `json:"running"`
+	Severity   Severity  `json:"severity"`
+	Updated    time.Time `json:"updated"`
+	VMs        []VM      `json:"vms"`
+}
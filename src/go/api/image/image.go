@@ -274,8 +274,18 @@ func Build(ctx context.Context, name string, verbosity int, cache bool, dryrun b
 	if dryrun {
 		fmt.Printf("DRY RUN: vmdb2 %s\n", strings.Join(args, " "))
 	} else {
+		site, err := types.SiteSettings()
+		if err != nil {
+			return fmt.Errorf("getting site settings: %w", err)
+		}
+
 		cmd := exec.Command("vmdb2", args...)
 
+		// Use the site's HTTP proxy, if any, when fetching packages for the image.
+		if site.Proxy != nil {
+			cmd.Env = append(os.Environ(), site.Proxy.Env()...)
+		}
+
 		stdout, _ := cmd.StdoutPipe()
 		stderr, _ := cmd.StderrPipe()
 
@@ -0,0 +1,547 @@
+package image
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	"phenix/util"
+	"phenix/util/shell"
+
+	"github.com/activeshadow/structs"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported import format")
+	ErrApplianceExists   = errors.New("appliance already exists")
+	ErrNoOVFDescriptor   = errors.New("no OVF descriptor found")
+
+	allocUnitsRegex = regexp.MustCompile(`^byte\s*\*\s*2\^\s*([0-9]+)$`)
+)
+
+// OVF virtual hardware resource types (CIM_ResourceAllocationSettingData).
+const (
+	ovfResourceCPU      = 3
+	ovfResourceMemory   = 4
+	ovfResourceEthernet = 10
+	ovfResourceDisk     = 17
+)
+
+type importOptions struct {
+	name   string
+	format string
+	dir    string
+	force  bool
+}
+
+type ImportOption func(*importOptions)
+
+func newImportOptions(opts ...ImportOption) importOptions {
+	var o importOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.dir == "" {
+		o.dir = util.GetMMFilesDirectory()
+	}
+
+	return o
+}
+
+// ImportWithName sets the name of the imported appliance, which is also used
+// to name the converted disk images. It defaults to the base name of the
+// imported file.
+func ImportWithName(n string) ImportOption {
+	return func(o *importOptions) {
+		o.name = n
+	}
+}
+
+// ImportWithFormat sets the format of the imported file (`ova` or `ovf`). It
+// defaults to the extension of the imported file.
+func ImportWithFormat(f string) ImportOption {
+	return func(o *importOptions) {
+		o.format = strings.ToLower(f)
+	}
+}
+
+// ImportWithDirectory sets the directory converted disk images are written to.
+// It defaults to the minimega files directory.
+func ImportWithDirectory(d string) ImportOption {
+	return func(o *importOptions) {
+		o.dir = d
+	}
+}
+
+// ImportWithForce allows an existing appliance (and its disk images) to be
+// replaced.
+func ImportWithForce(f bool) ImportOption {
+	return func(o *importOptions) {
+		o.force = f
+	}
+}
+
+// Import unpacks the OVA or OVF package at the given path, converts its disks
+// to qcow2 images in the minimega files directory, and registers the resulting
+// appliance in the store, including a node template built from the hardware
+// described in the package's OVF descriptor.
+func Import(ctx context.Context, path string, opts ...ImportOption) (*types.Appliance, error) {
+	o := newImportOptions(opts...)
+
+	if o.format == "" {
+		o.format = strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	}
+
+	if o.name == "" {
+		o.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	var (
+		dir  string
+		desc string
+	)
+
+	switch o.format {
+	case "ova":
+		tmp, err := os.MkdirTemp("", "phenix-ova-")
+		if err != nil {
+			return nil, fmt.Errorf("creating temp directory: %w", err)
+		}
+
+		defer os.RemoveAll(tmp)
+
+		desc, err = unpackOVA(path, tmp)
+		if err != nil {
+			return nil, fmt.Errorf("unpacking OVA %s: %w", path, err)
+		}
+
+		dir = tmp
+	case "ovf":
+		dir = filepath.Dir(path)
+		desc = path
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, o.format)
+	}
+
+	c, _ := store.NewConfig("appliance/" + o.name)
+
+	exists := store.Get(c) == nil
+
+	if exists && !o.force {
+		return nil, fmt.Errorf("%w: %s", ErrApplianceExists, o.name)
+	}
+
+	body, err := os.ReadFile(desc)
+	if err != nil {
+		return nil, fmt.Errorf("reading OVF descriptor: %w", err)
+	}
+
+	env, err := parseOVF(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OVF descriptor: %w", err)
+	}
+
+	if !shell.CommandExists("qemu-img") {
+		return nil, fmt.Errorf("qemu-img must be installed to convert appliance disks")
+	}
+
+	var disks []string
+
+	for i, file := range env.diskFiles() {
+		src, cleanup, err := sourceFile(dir, file)
+		if err != nil {
+			return nil, err
+		}
+
+		defer cleanup()
+
+		name := o.name + ".qc2"
+
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.qc2", o.name, i)
+		}
+
+		dst := filepath.Join(o.dir, name)
+
+		if _, err := os.Stat(dst); err == nil && !o.force {
+			return nil, fmt.Errorf("disk image %s already exists", dst)
+		}
+
+		if err := convertDisk(ctx, src, dst); err != nil {
+			return nil, fmt.Errorf("converting disk %s: %w", file.Href, err)
+		}
+
+		disks = append(disks, name)
+	}
+
+	if len(disks) == 0 {
+		return nil, fmt.Errorf("no disks found in OVF descriptor")
+	}
+
+	spec := &v1.ApplianceSpec{
+		Description: env.description(),
+		Source:      path,
+		Format:      o.format,
+		Disks:       disks,
+		Node:        env.node(o.name, disks),
+	}
+
+	c.Spec = structs.MapDefaultCase(spec, structs.CASESNAKE)
+
+	if exists {
+		if err := store.Update(c); err != nil {
+			return nil, fmt.Errorf("updating appliance %s in store: %w", o.name, err)
+		}
+	} else {
+		if err := store.Create(c); err != nil {
+			return nil, fmt.Errorf("registering appliance %s in store: %w", o.name, err)
+		}
+	}
+
+	return &types.Appliance{Metadata: c.Metadata, Spec: spec}, nil
+}
+
+type ovfEnvelope struct {
+	References []ovfFile        `xml:"References>File"`
+	Disks      []ovfDisk        `xml:"DiskSection>Disk"`
+	System     ovfVirtualSystem `xml:"VirtualSystem"`
+}
+
+type ovfFile struct {
+	ID          string `xml:"id,attr"`
+	Href        string `xml:"href,attr"`
+	Compression string `xml:"compression,attr"`
+}
+
+type ovfDisk struct {
+	ID      string `xml:"diskId,attr"`
+	FileRef string `xml:"fileRef,attr"`
+}
+
+type ovfVirtualSystem struct {
+	ID      string `xml:"id,attr"`
+	Product string `xml:"ProductSection>Product"`
+	Vendor  string `xml:"ProductSection>Vendor"`
+	Version string `xml:"ProductSection>Version"`
+	OS      struct {
+		Type        string `xml:"osType,attr"`
+		Description string `xml:"Description"`
+	} `xml:"OperatingSystemSection"`
+	Hardware []ovfHardwareItem `xml:"VirtualHardwareSection>Item"`
+}
+
+type ovfHardwareItem struct {
+	ResourceType    int    `xml:"ResourceType"`
+	ResourceSubType string `xml:"ResourceSubType"`
+	VirtualQuantity int    `xml:"VirtualQuantity"`
+	AllocationUnits string `xml:"AllocationUnits"`
+	HostResource    string `xml:"HostResource"`
+	Connection      string `xml:"Connection"`
+}
+
+func parseOVF(body []byte) (*ovfEnvelope, error) {
+	var env ovfEnvelope
+
+	if err := xml.Unmarshal(body, &env); err != nil {
+		return nil, err
+	}
+
+	return &env, nil
+}
+
+func (this ovfEnvelope) description() string {
+	desc := strings.TrimSpace(this.System.Vendor + " " + this.System.Product + " " + this.System.Version)
+
+	if desc == "" {
+		return this.System.ID
+	}
+
+	return desc
+}
+
+// diskFiles returns the files referenced by the disks attached to the virtual
+// hardware, in the order they're attached. If the virtual hardware doesn't
+// reference any disks, all the disks in the disk section are returned.
+func (this ovfEnvelope) diskFiles() []ovfFile {
+	var (
+		files = make(map[string]ovfFile)
+		disks = make(map[string]string)
+		ids   []string
+	)
+
+	for _, f := range this.References {
+		files[f.ID] = f
+	}
+
+	for _, d := range this.Disks {
+		disks[d.ID] = d.FileRef
+	}
+
+	for _, item := range this.System.Hardware {
+		if item.ResourceType != ovfResourceDisk {
+			continue
+		}
+
+		// Host resources for disks look like `ovf:/disk/vmdisk1`.
+		id := item.HostResource[strings.LastIndex(item.HostResource, "/")+1:]
+
+		if _, ok := disks[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		for _, d := range this.Disks {
+			ids = append(ids, d.ID)
+		}
+	}
+
+	var refs []ovfFile
+
+	for _, id := range ids {
+		if f, ok := files[disks[id]]; ok {
+			refs = append(refs, f)
+		}
+	}
+
+	return refs
+}
+
+// node builds a node template from the virtual hardware described in the OVF
+// descriptor. Interfaces are configured for DHCP, with their VLAN set to the
+// name of the OVF network they're connected to.
+func (this ovfEnvelope) node(name string, disks []string) *v1.Node {
+	var (
+		snapshot = true
+		hardware = &v1.Hardware{VCPUF: 1, MemoryF: 512, OSTypeF: "linux"}
+		network  = new(v1.Network)
+	)
+
+	if strings.Contains(strings.ToLower(this.System.OS.Type+this.System.OS.Description), "windows") {
+		hardware.OSTypeF = "windows"
+	}
+
+	for _, disk := range disks {
+		hardware.DrivesF = append(hardware.DrivesF, &v1.Drive{ImageF: disk})
+	}
+
+	for _, item := range this.System.Hardware {
+		switch item.ResourceType {
+		case ovfResourceCPU:
+			if item.VirtualQuantity > 0 {
+				hardware.VCPUF = item.VirtualQuantity
+			}
+		case ovfResourceMemory:
+			if mem := memoryMB(item.VirtualQuantity, item.AllocationUnits); mem > 0 {
+				hardware.MemoryF = mem
+			}
+		case ovfResourceEthernet:
+			vlan := item.Connection
+			if vlan == "" {
+				vlan = fmt.Sprintf("EXP-%d", len(network.InterfacesF)+1)
+			}
+
+			network.InterfacesF = append(network.InterfacesF, &v1.Interface{
+				NameF:      fmt.Sprintf("eth%d", len(network.InterfacesF)),
+				TypeF:      "ethernet",
+				ProtoF:     "dhcp",
+				VLANF:      vlan,
+				DriverF:    nicDriver(item.ResourceSubType),
+				AutostartF: true,
+			})
+		}
+	}
+
+	return &v1.Node{
+		TypeF: "VirtualMachine",
+		GeneralF: &v1.General{
+			HostnameF:    name,
+			DescriptionF: this.description(),
+			VMTypeF:      "kvm",
+			SnapshotF:    &snapshot,
+		},
+		HardwareF: hardware,
+		NetworkF:  network,
+	}
+}
+
+// memoryMB converts the given OVF memory quantity to megabytes. OVF allocation
+// units are typically of the form `byte * 2^20`, though some exporters use
+// unit names instead.
+func memoryMB(qty int, units string) int {
+	units = strings.ToLower(strings.TrimSpace(units))
+
+	if m := allocUnitsRegex.FindStringSubmatch(units); m != nil {
+		exp, _ := strconv.Atoi(m[1])
+
+		if exp >= 20 {
+			return qty << (exp - 20)
+		}
+
+		return qty >> (20 - exp)
+	}
+
+	switch units {
+	case "", "mb", "megabytes", "mib":
+		return qty
+	case "gb", "gigabytes", "gib":
+		return qty * 1024
+	case "kb", "kilobytes", "kib":
+		return qty / 1024
+	}
+
+	return 0
+}
+
+// nicDriver maps the given OVF ethernet resource subtype to the equivalent
+// QEMU NIC driver, returning an empty string (to use the phenix default) for
+// unknown subtypes.
+func nicDriver(subtype string) string {
+	switch strings.ToLower(subtype) {
+	case "e1000":
+		return "e1000"
+	case "e1000e":
+		return "e1000e"
+	case "vmxnet3":
+		return "vmxnet3"
+	case "pcnet32":
+		return "pcnet"
+	case "virtio", "virtio-net":
+		return "virtio-net-pci"
+	}
+
+	return ""
+}
+
+// unpackOVA extracts the given OVA (a tar archive) into the given directory,
+// returning the path to the OVF descriptor.
+func unpackOVA(path, dir string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	var (
+		tr   = tar.NewReader(f)
+		desc string
+	)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// OVAs are flat archives, so only the base name is used to avoid writing
+		// outside of the given directory.
+		name := filepath.Join(dir, filepath.Base(hdr.Name))
+
+		out, err := os.Create(name)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return "", err
+		}
+
+		out.Close()
+
+		if desc == "" && strings.EqualFold(filepath.Ext(name), ".ovf") {
+			desc = name
+		}
+	}
+
+	if desc == "" {
+		return "", ErrNoOVFDescriptor
+	}
+
+	return desc, nil
+}
+
+// sourceFile returns the path to the given OVF file in the given directory,
+// decompressing it to a temp file first if necessary. The returned cleanup
+// function removes the temp file, if one was created.
+func sourceFile(dir string, file ovfFile) (string, func(), error) {
+	path := filepath.Join(dir, filepath.Base(file.Href))
+
+	if _, err := os.Stat(path); err != nil {
+		return "", nil, fmt.Errorf("disk %s not found in package", file.Href)
+	}
+
+	if file.Compression == "" {
+		return path, func() {}, nil
+	}
+
+	if file.Compression != "gzip" {
+		return "", nil, fmt.Errorf("unsupported compression %s for disk %s", file.Compression, file.Href)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return "", nil, fmt.Errorf("decompressing disk %s: %w", file.Href, err)
+	}
+
+	defer gz.Close()
+
+	out, err := os.CreateTemp("", filepath.Base(file.Href)+"-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer out.Close()
+
+	cleanup := func() { os.Remove(out.Name()) }
+
+	if _, err := io.Copy(out, gz); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("decompressing disk %s: %w", file.Href, err)
+	}
+
+	return out.Name(), cleanup, nil
+}
+
+func convertDisk(ctx context.Context, src, dst string) error {
+	opts := []shell.Option{
+		shell.Command("qemu-img"),
+		shell.Args("convert", "-O", "qcow2", src, dst),
+	}
+
+	if _, stderr, err := shell.ExecCommand(ctx, opts...); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(stderr)))
+	}
+
+	return nil
+}
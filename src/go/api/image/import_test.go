@@ -0,0 +1,141 @@
+package image
+
+import (
+	"testing"
+)
+
+var testOVF = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1" xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData">
+  <References>
+    <File ovf:id="file1" ovf:href="fw-disk1.vmdk"/>
+    <File ovf:id="file2" ovf:href="fw-disk2.vmdk"/>
+  </References>
+  <DiskSection>
+    <Disk ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:capacity="8589934592"/>
+    <Disk ovf:diskId="vmdisk2" ovf:fileRef="file2" ovf:capacity="1073741824"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="fw">
+    <ProductSection>
+      <Product>Firewall</Product>
+      <Vendor>Acme</Vendor>
+      <Version>9.1</Version>
+    </ProductSection>
+    <OperatingSystemSection ovf:id="101" ovf:osType="otherLinux64Guest">
+      <Description>Other Linux (64-bit)</Description>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>4</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^30</rasd:AllocationUnits>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>8</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:HostResource>ovf:/disk/vmdisk2</rasd:HostResource>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Connection>mgmt</rasd:Connection>
+        <rasd:ResourceSubType>VmxNet3</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:ResourceSubType>E1000</rasd:ResourceSubType>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`)
+
+func TestParseOVF(t *testing.T) {
+	env, err := parseOVF(testOVF)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	files := env.diskFiles()
+
+	if len(files) != 2 {
+		t.Logf("expected 2 disk files, got %d", len(files))
+		t.FailNow()
+	}
+
+	if files[0].Href != "fw-disk2.vmdk" || files[1].Href != "fw-disk1.vmdk" {
+		t.Logf("expected disk files in attached order, got %s, %s", files[0].Href, files[1].Href)
+		t.FailNow()
+	}
+
+	node := env.node("fw", []string{"fw.qc2", "fw-1.qc2"})
+
+	if desc := node.General().Description(); desc != "Acme Firewall 9.1" {
+		t.Logf("expected description 'Acme Firewall 9.1', got '%s'", desc)
+		t.FailNow()
+	}
+
+	if node.Hardware().VCPU() != 4 {
+		t.Logf("expected 4 VCPUs, got %d", node.Hardware().VCPU())
+		t.FailNow()
+	}
+
+	if node.Hardware().Memory() != 8192 {
+		t.Logf("expected 8192 MB of memory, got %d", node.Hardware().Memory())
+		t.FailNow()
+	}
+
+	if node.Hardware().OSType() != "linux" {
+		t.Logf("expected linux OS type, got %s", node.Hardware().OSType())
+		t.FailNow()
+	}
+
+	if drives := node.Hardware().Drives(); len(drives) != 2 || drives[0].Image() != "fw.qc2" {
+		t.Logf("expected 2 drives starting with fw.qc2, got %v", drives)
+		t.FailNow()
+	}
+
+	ifaces := node.Network().Interfaces()
+
+	if len(ifaces) != 2 {
+		t.Logf("expected 2 interfaces, got %d", len(ifaces))
+		t.FailNow()
+	}
+
+	if ifaces[0].VLAN() != "mgmt" || ifaces[0].Driver() != "vmxnet3" {
+		t.Logf("expected eth0 on mgmt VLAN with vmxnet3 driver, got %s, %s", ifaces[0].VLAN(), ifaces[0].Driver())
+		t.FailNow()
+	}
+
+	if ifaces[1].VLAN() != "EXP-2" || ifaces[1].Driver() != "e1000" {
+		t.Logf("expected eth1 on EXP-2 VLAN with e1000 driver, got %s, %s", ifaces[1].VLAN(), ifaces[1].Driver())
+		t.FailNow()
+	}
+}
+
+func TestMemoryMB(t *testing.T) {
+	cases := []struct {
+		qty      int
+		units    string
+		expected int
+	}{
+		{2048, "byte * 2^20", 2048},
+		{2, "byte * 2^30", 2048},
+		{2097152, "byte * 2^10", 2048},
+		{2048, "", 2048},
+		{2, "GigaBytes", 2048},
+		{2048, "bogus", 0},
+	}
+
+	for _, c := range cases {
+		if mem := memoryMB(c.qty, c.units); mem != c.expected {
+			t.Logf("expected %d MB for %d '%s', got %d", c.expected, c.qty, c.units, mem)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"inet.af/netaddr"
+)
+
+var clabMemoryRegex = regexp.MustCompile(`(?i)^([0-9.]+)\s*([kmgt]?)i?b?$`)
+
+type clabTopology struct {
+	Name string `yaml:"name"`
+	Mgmt struct {
+		IPv4Subnet string `yaml:"ipv4-subnet"`
+	} `yaml:"mgmt"`
+	Topology struct {
+		Defaults clabNode            `yaml:"defaults"`
+		Kinds    map[string]clabNode `yaml:"kinds"`
+		Nodes    map[string]clabNode `yaml:"nodes"`
+		Links    []clabLink          `yaml:"links"`
+	} `yaml:"topology"`
+}
+
+type clabNode struct {
+	Kind          string            `yaml:"kind"`
+	Image         string            `yaml:"image"`
+	CPU           float64           `yaml:"cpu"`
+	Memory        string            `yaml:"memory"`
+	MgmtIPv4      string            `yaml:"mgmt-ipv4"`
+	StartupConfig string            `yaml:"startup-config"`
+	NetworkMode   string            `yaml:"network-mode"`
+	Binds         []string          `yaml:"binds"`
+	Ports         []string          `yaml:"ports"`
+	Exec          []string          `yaml:"exec"`
+	Env           map[string]string `yaml:"env"`
+}
+
+// merge fills in any unset fields of the node from the given defaults.
+func (this clabNode) merge(defaults clabNode) clabNode {
+	if this.Kind == "" {
+		this.Kind = defaults.Kind
+	}
+
+	if this.Image == "" {
+		this.Image = defaults.Image
+	}
+
+	if this.CPU == 0 {
+		this.CPU = defaults.CPU
+	}
+
+	if this.Memory == "" {
+		this.Memory = defaults.Memory
+	}
+
+	if this.StartupConfig == "" {
+		this.StartupConfig = defaults.StartupConfig
+	}
+
+	if this.NetworkMode == "" {
+		this.NetworkMode = defaults.NetworkMode
+	}
+
+	if this.Binds == nil {
+		this.Binds = defaults.Binds
+	}
+
+	if this.Ports == nil {
+		this.Ports = defaults.Ports
+	}
+
+	if this.Exec == nil {
+		this.Exec = defaults.Exec
+	}
+
+	if this.Env == nil {
+		this.Env = defaults.Env
+	}
+
+	return this
+}
+
+// clabLink supports both the brief link format (`endpoints: ["a:e1", "b:e1"]`)
+// and the extended link format with typed links and endpoint maps.
+type clabLink struct {
+	Type      string        `yaml:"type"`
+	Endpoints []interface{} `yaml:"endpoints"`
+	Endpoint  interface{}   `yaml:"endpoint"`
+}
+
+type clabEndpoint struct {
+	node  string
+	iface string
+}
+
+func (this clabLink) endpoints() ([]clabEndpoint, error) {
+	raw := this.Endpoints
+
+	if this.Endpoint != nil {
+		raw = append(raw, this.Endpoint)
+	}
+
+	var endpoints []clabEndpoint
+
+	for _, e := range raw {
+		switch e := e.(type) {
+		case string:
+			node, iface, ok := strings.Cut(e, ":")
+			if !ok {
+				return nil, fmt.Errorf("invalid endpoint %s", e)
+			}
+
+			endpoints = append(endpoints, clabEndpoint{node: node, iface: iface})
+		case map[string]interface{}:
+			node, _ := e["node"].(string)
+			iface, _ := e["interface"].(string)
+
+			if node == "" || iface == "" {
+				return nil, fmt.Errorf("invalid endpoint %v", e)
+			}
+
+			endpoints = append(endpoints, clabEndpoint{node: node, iface: iface})
+		default:
+			return nil, fmt.Errorf("invalid endpoint %v", e)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// containerlab converts the given containerlab topology. Nodes of kind `linux`
+// become phenix container VMs and all other node kinds (ie. network operating
+// systems) become KVM VMs. Links to nodes of kind `bridge` or `ovs-bridge` are
+// merged into a single VLAN per bridge.
+func containerlab(body []byte, name string, o options) (*Result, error) {
+	var lab clabTopology
+
+	if err := yaml.Unmarshal(body, &lab); err != nil {
+		return nil, fmt.Errorf("parsing containerlab topology: %w", err)
+	}
+
+	if lab.Name != "" {
+		name = lab.Name
+	}
+
+	var (
+		b       = newBuilder(o)
+		bridges = make(map[string]struct{})
+		names   []string
+		mask    = 24
+	)
+
+	if lab.Mgmt.IPv4Subnet != "" {
+		if subnet, err := netaddr.ParseIPPrefix(lab.Mgmt.IPv4Subnet); err == nil {
+			mask = int(subnet.Bits())
+		}
+	}
+
+	for n := range lab.Topology.Nodes {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	for _, n := range names {
+		node := lab.Topology.Nodes[n]
+
+		// Node settings take precedence over kind settings, which take precedence
+		// over default settings.
+		kind := node.Kind
+		if kind == "" {
+			kind = lab.Topology.Defaults.Kind
+		}
+
+		node = node.merge(lab.Topology.Kinds[kind]).merge(lab.Topology.Defaults)
+
+		switch node.Kind {
+		case "bridge", "ovs-bridge":
+			bridges[n] = struct{}{}
+			b.segments[n] = n
+
+			continue
+		case "host", "ext-container":
+			b.warn("%s: node kind %s is not supported; node skipped", n, node.Kind)
+			continue
+		}
+
+		container := node.Kind == "linux"
+
+		if !container && node.Kind != "" {
+			b.warn("%s: node kind %s converted to a KVM VM", n, node.Kind)
+		}
+
+		memory, err := clabMemory(node.Memory)
+		if err != nil {
+			b.warn("%s: %v; using default memory", n, err)
+		}
+
+		vm := b.addNode(n, node.Image, container, int(math.Ceil(node.CPU)), memory)
+
+		if node.MgmtIPv4 != "" {
+			iface := "mgmt0"
+
+			if container {
+				iface = "eth0"
+			}
+
+			vm.NetworkF.InterfacesF = append(vm.NetworkF.InterfacesF, newStaticInterface(iface, "MGMT", node.MgmtIPv4, mask))
+		}
+
+		if node.StartupConfig != "" {
+			b.warn("%s: startup config %s not imported; add it as an injection", n, node.StartupConfig)
+		}
+
+		if node.NetworkMode != "" {
+			b.warn("%s: network mode %s is not supported", n, node.NetworkMode)
+		}
+
+		if len(node.Binds) > 0 {
+			b.warn("%s: bind mounts are not supported; add files as injections", n)
+		}
+
+		if len(node.Ports) > 0 {
+			b.warn("%s: port publishing is not supported", n)
+		}
+
+		if len(node.Exec) > 0 {
+			b.warn("%s: exec commands are not supported; add them to a startup script", n)
+		}
+
+		if len(node.Env) > 0 {
+			b.warn("%s: environment variables are not supported", n)
+		}
+	}
+
+	for i, link := range lab.Topology.Links {
+		if link.Type != "" && link.Type != "veth" {
+			b.warn("link %d: link type %s is not supported; link skipped", i, link.Type)
+			continue
+		}
+
+		endpoints, err := link.endpoints()
+		if err != nil {
+			return nil, fmt.Errorf("parsing link %d: %w", i, err)
+		}
+
+		if len(endpoints) != 2 {
+			b.warn("link %d: expected 2 endpoints, got %d; link skipped", i, len(endpoints))
+			continue
+		}
+
+		var (
+			a, z       = endpoints[0], endpoints[1]
+			_, aBridge = bridges[a.node]
+			_, zBridge = bridges[z.node]
+			segment    string
+		)
+
+		switch {
+		case aBridge && zBridge:
+			b.merge(a.node, z.node)
+			continue
+		case aBridge:
+			segment = a.node
+		case zBridge:
+			segment = z.node
+		default:
+			segment = b.link(a.node, z.node)
+		}
+
+		for _, e := range endpoints {
+			if _, ok := bridges[e.node]; ok {
+				continue
+			}
+
+			if _, ok := b.nodes[e.node]; !ok {
+				b.warn("link %d: endpoint node %s is not in the topology; endpoint skipped", i, e.node)
+				continue
+			}
+
+			b.addInterface(e.node, e.iface, segment)
+		}
+	}
+
+	return b.result(name)
+}
+
+// clabMemory converts the given containerlab memory limit (ie. `1Gb`) to
+// megabytes. Limits without a unit are in bytes.
+func clabMemory(mem string) (int, error) {
+	if mem == "" {
+		return 0, nil
+	}
+
+	m := clabMemoryRegex.FindStringSubmatch(strings.TrimSpace(mem))
+	if m == nil {
+		return 0, fmt.Errorf("invalid memory %s", mem)
+	}
+
+	qty, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory %s", mem)
+	}
+
+	switch strings.ToLower(m[2]) {
+	case "":
+		qty /= 1024 * 1024
+	case "k":
+		qty /= 1024
+	case "g":
+		qty *= 1024
+	case "t":
+		qty *= 1024 * 1024
+	}
+
+	return int(qty), nil
+}
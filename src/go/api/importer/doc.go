@@ -0,0 +1,5 @@
+// Implementation of the phenix topology import API. Existing network labs
+// defined as containerlab topology files or GNS3 projects can be converted
+// into phenix topology configs, with a report of any constructs that couldn't
+// be mapped to phenix.
+package importer
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type gns3Project struct {
+	Name     string `json:"name"`
+	Topology struct {
+		Nodes []gns3Node `json:"nodes"`
+		Links []gns3Link `json:"links"`
+	} `json:"topology"`
+}
+
+type gns3Node struct {
+	ID         string                 `json:"node_id"`
+	Name       string                 `json:"name"`
+	NodeType   string                 `json:"node_type"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type gns3Link struct {
+	Nodes []struct {
+		NodeID  string `json:"node_id"`
+		Adapter int    `json:"adapter_number"`
+		Port    int    `json:"port_number"`
+	} `json:"nodes"`
+}
+
+// gns3 converts the given GNS3 project. QEMU nodes become KVM VMs and Docker
+// nodes become phenix container VMs. Links to Ethernet switches and hubs are
+// merged into a single VLAN per switch or hub; switch port VLAN configurations
+// are not imported.
+func gns3(body []byte, name string, o options) (*Result, error) {
+	var project gns3Project
+
+	if err := json.Unmarshal(body, &project); err != nil {
+		return nil, fmt.Errorf("parsing GNS3 project: %w", err)
+	}
+
+	if project.Name != "" {
+		name = project.Name
+	}
+
+	var (
+		b        = newBuilder(o)
+		nodes    = make(map[string]string)
+		switches = make(map[string]struct{})
+	)
+
+	for _, node := range project.Topology.Nodes {
+		switch node.NodeType {
+		case "ethernet_switch", "ethernet_hub":
+			nodes[node.ID] = node.Name
+			switches[node.Name] = struct{}{}
+			b.segments[node.Name] = node.Name
+		case "qemu":
+			image, _ := node.Properties["hda_disk_image"].(string)
+			cpus, _ := node.Properties["cpus"].(float64)
+			ram, _ := node.Properties["ram"].(float64)
+
+			nodes[node.ID] = node.Name
+			b.addNode(node.Name, image, false, int(cpus), int(ram))
+
+			for _, disk := range []string{"hdb_disk_image", "hdc_disk_image", "hdd_disk_image"} {
+				if img, _ := node.Properties[disk].(string); img != "" {
+					b.warn("%s: additional disk image %s not imported", node.Name, img)
+				}
+			}
+		case "docker":
+			image, _ := node.Properties["image"].(string)
+
+			nodes[node.ID] = node.Name
+			b.addNode(node.Name, image, true, 0, 0)
+		default:
+			b.warn("%s: node type %s is not supported; node skipped", node.Name, node.NodeType)
+		}
+	}
+
+	for i, link := range project.Topology.Links {
+		if len(link.Nodes) != 2 {
+			b.warn("link %d: expected 2 endpoints, got %d; link skipped", i, len(link.Nodes))
+			continue
+		}
+
+		var (
+			a, aOK     = nodes[link.Nodes[0].NodeID]
+			z, zOK     = nodes[link.Nodes[1].NodeID]
+			_, aSwitch = switches[a]
+			_, zSwitch = switches[z]
+			segment    string
+		)
+
+		if !aOK || !zOK {
+			b.warn("link %d: connected to unsupported node; link skipped", i)
+			continue
+		}
+
+		switch {
+		case aSwitch && zSwitch:
+			b.merge(a, z)
+			continue
+		case aSwitch:
+			segment = a
+		case zSwitch:
+			segment = z
+		default:
+			segment = b.link(a, z)
+		}
+
+		for _, e := range link.Nodes {
+			node := nodes[e.NodeID]
+
+			if _, ok := switches[node]; ok {
+				continue
+			}
+
+			iface := fmt.Sprintf("eth%d", e.Adapter)
+
+			if e.Port != 0 {
+				iface = fmt.Sprintf("eth%d-%d", e.Adapter, e.Port)
+			}
+
+			b.addInterface(node, iface, segment)
+		}
+	}
+
+	return b.result(name)
+}
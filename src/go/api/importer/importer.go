@@ -0,0 +1,356 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+)
+
+const (
+	FORMAT_CONTAINERLAB = "containerlab"
+	FORMAT_GNS3         = "gns3"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported import format")
+
+	imageNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// Result is a topology config converted from an existing network lab, along
+// with any warnings about lab constructs that couldn't be mapped to phenix.
+type Result struct {
+	Config   *store.Config
+	Warnings []string
+}
+
+type options struct {
+	name   string
+	format string
+	images map[string]string
+}
+
+type Option func(*options)
+
+func newOptions(opts ...Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.images == nil {
+		o.images = make(map[string]string)
+	}
+
+	return o
+}
+
+// ImportWithName sets the name of the resulting topology config. It defaults
+// to the name of the lab, or the base name of the imported file if the lab
+// isn't named.
+func ImportWithName(n string) Option {
+	return func(o *options) {
+		o.name = n
+	}
+}
+
+// ImportWithFormat sets the format of the imported file (`containerlab` or
+// `gns3`). It defaults to `gns3` for files with a `.gns3` extension and
+// `containerlab` for all other files.
+func ImportWithFormat(f string) Option {
+	return func(o *options) {
+		o.format = strings.ToLower(f)
+	}
+}
+
+// ImportWithImages sets the phenix disk image (or container filesystem) to use
+// for each lab image. Lab images not in the map are converted to a phenix
+// image name derived from the lab image name.
+func ImportWithImages(i map[string]string) Option {
+	return func(o *options) {
+		o.images = i
+	}
+}
+
+// File converts the lab topology at the given path into a phenix topology
+// config. The config is not created in the store.
+func File(path string, opts ...Option) (*Result, error) {
+	o := newOptions(opts...)
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	if o.format == "" {
+		if strings.EqualFold(filepath.Ext(path), ".gns3") {
+			o.format = FORMAT_GNS3
+		} else {
+			o.format = FORMAT_CONTAINERLAB
+		}
+	}
+
+	name := filepath.Base(path)
+
+	// Containerlab topology files are typically named `<lab>.clab.yml`.
+	for filepath.Ext(name) != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	switch o.format {
+	case FORMAT_CONTAINERLAB:
+		return containerlab(body, name, o)
+	case FORMAT_GNS3:
+		return gns3(body, name, o)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, o.format)
+	}
+}
+
+// builder accumulates topology nodes and lab network segments while a lab is
+// being converted. Segments connected via lab switches or bridges are merged so
+// all the interfaces connected to them share a single VLAN.
+type builder struct {
+	opts options
+
+	nodes    map[string]*v1.Node
+	order    []string
+	segments map[string]string
+	names    map[string]struct{}
+	warnings []string
+}
+
+func newBuilder(o options) *builder {
+	return &builder{
+		opts:     o,
+		nodes:    make(map[string]*v1.Node),
+		segments: make(map[string]string),
+		names:    make(map[string]struct{}),
+	}
+}
+
+func (this *builder) warn(format string, args ...interface{}) {
+	this.warnings = append(this.warnings, fmt.Sprintf(format, args...))
+}
+
+// addNode adds a VM to the topology. Container VMs use a container filesystem
+// image, while all others use a disk image.
+func (this *builder) addNode(name, image string, container bool, cpus, memory int) *v1.Node {
+	var (
+		vmType = "kvm"
+		disk   = this.image(name, image, container)
+	)
+
+	if container {
+		vmType = "container"
+	}
+
+	if cpus < 1 {
+		cpus = 1
+	}
+
+	if memory < 1 {
+		memory = 512
+
+		if !container {
+			memory = 2048
+		}
+	}
+
+	node := &v1.Node{
+		TypeF:    "VirtualMachine",
+		GeneralF: &v1.General{HostnameF: name, VMTypeF: vmType},
+		HardwareF: &v1.Hardware{
+			VCPUF:   cpus,
+			MemoryF: memory,
+			OSTypeF: "linux",
+			DrivesF: []*v1.Drive{{ImageF: disk}},
+		},
+		NetworkF: new(v1.Network),
+	}
+
+	this.nodes[name] = node
+	this.order = append(this.order, name)
+
+	return node
+}
+
+// image returns the phenix image to use for the given node's lab image,
+// warning about lab images that aren't explicitly mapped to a phenix image.
+func (this *builder) image(node, image string, container bool) string {
+	if mapped, ok := this.opts.images[image]; ok {
+		return mapped
+	}
+
+	name := image
+
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+
+	if idx := strings.Index(name, ":"); idx >= 0 {
+		name = name[:idx]
+	}
+
+	name = imageNameRegex.ReplaceAllString(name, "_")
+
+	if name == "" {
+		name = "unknown"
+	}
+
+	if container {
+		name += "_rootfs.tgz"
+	} else {
+		name += ".qc2"
+	}
+
+	if image == "" {
+		this.warn("%s: no image specified; defaulting to %s", node, name)
+	} else {
+		this.warn("%s: image %s converted to %s; ensure it exists or map it to an existing image", node, image, name)
+	}
+
+	return name
+}
+
+// segment returns the VLAN for the lab segment with the given key, following
+// any segments merged into other segments.
+func (this *builder) segment(key string) string {
+	for {
+		parent, ok := this.segments[key]
+		if !ok || parent == key {
+			return key
+		}
+
+		key = parent
+	}
+}
+
+// merge merges the two given lab segments into a single segment.
+func (this *builder) merge(a, b string) {
+	a, b = this.segment(a), this.segment(b)
+
+	if a != b {
+		this.segments[b] = a
+	}
+}
+
+// link returns a unique segment key for a point-to-point link between the
+// given nodes.
+func (this *builder) link(a, b string) string {
+	key := a + "-" + b
+
+	for i := 2; ; i++ {
+		if _, ok := this.names[key]; !ok {
+			break
+		}
+
+		key = fmt.Sprintf("%s-%s-%d", a, b, i)
+	}
+
+	this.names[key] = struct{}{}
+	this.segments[key] = key
+
+	return key
+}
+
+func (this *builder) addInterface(node, name, segment string) {
+	n, ok := this.nodes[node]
+	if !ok {
+		return
+	}
+
+	n.NetworkF.InterfacesF = append(n.NetworkF.InterfacesF, &v1.Interface{
+		NameF:      name,
+		TypeF:      "ethernet",
+		ProtoF:     "manual",
+		VLANF:      segment,
+		AutostartF: true,
+	})
+}
+
+func newStaticInterface(name, vlan, addr string, mask int) *v1.Interface {
+	return &v1.Interface{
+		NameF:      name,
+		TypeF:      "ethernet",
+		ProtoF:     "static",
+		VLANF:      vlan,
+		AddressF:   addr,
+		MaskF:      mask,
+		AutostartF: true,
+	}
+}
+
+// config builds the topology config. Interface VLANs are resolved last since
+// segments can be merged after interfaces are added to them.
+func (this *builder) config(name string) (*store.Config, error) {
+	if this.opts.name != "" {
+		name = this.opts.name
+	}
+
+	if len(this.nodes) == 0 {
+		return nil, fmt.Errorf("no supported nodes found in lab")
+	}
+
+	topo := new(v1.TopologySpec)
+
+	for _, n := range this.order {
+		node := this.nodes[n]
+
+		for _, iface := range node.NetworkF.InterfacesF {
+			if iface.VLANF != "MGMT" {
+				iface.VLANF = this.segment(iface.VLANF)
+			}
+		}
+
+		topo.NodesF = append(topo.NodesF, node)
+	}
+
+	c, err := store.NewConfig("topology/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("creating topology config: %w", err)
+	}
+
+	c.Spec = structs.MapDefaultCase(topo, structs.CASESNAKE)
+
+	return c, nil
+}
+
+func (this *builder) result(name string) (*Result, error) {
+	c, err := this.config(name)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(this.warnings)
+
+	return &Result{Config: c, Warnings: dedup(this.warnings)}, nil
+}
+
+func dedup(s []string) []string {
+	var (
+		seen = make(map[string]struct{})
+		out  []string
+	)
+
+	for _, v := range s {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+
+		seen[v] = struct{}{}
+		out = append(out, v)
+	}
+
+	return out
+}
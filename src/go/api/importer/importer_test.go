@@ -0,0 +1,213 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/types"
+)
+
+var testContainerlab = `name: srl-linux
+mgmt:
+  ipv4-subnet: 172.100.100.0/24
+topology:
+  defaults:
+    kind: linux
+  kinds:
+    nokia_srlinux:
+      image: ghcr.io/nokia/srlinux:23.3.1
+  nodes:
+    srl:
+      kind: nokia_srlinux
+      mgmt-ipv4: 172.100.100.10
+      startup-config: srl.cfg
+    client1:
+      image: ghcr.io/hellt/network-multitool
+      memory: 1Gb
+      exec:
+      - ip addr add 10.0.0.2/24 dev eth1
+    client2:
+      image: ghcr.io/hellt/network-multitool
+    br-lan:
+      kind: bridge
+  links:
+  - endpoints: ["srl:e1-1", "client1:eth1"]
+  - endpoints: ["srl:e1-2", "br-lan:eth1"]
+  - type: veth
+    endpoints:
+    - node: client2
+      interface: eth1
+    - node: br-lan
+      interface: eth2
+  - type: host
+    endpoint:
+      node: client2
+      interface: eth9`
+
+var testGNS3 = `{
+  "name": "ospf-lab",
+  "topology": {
+    "nodes": [
+      {"node_id": "n1", "name": "R1", "node_type": "qemu", "properties": {"hda_disk_image": "vyos-1.3.qcow2", "ram": 1024, "cpus": 2}},
+      {"node_id": "n2", "name": "R2", "node_type": "qemu", "properties": {"hda_disk_image": "vyos-1.3.qcow2", "ram": 1024, "cpus": 2}},
+      {"node_id": "n3", "name": "PC1", "node_type": "docker", "properties": {"image": "alpine:latest"}},
+      {"node_id": "n4", "name": "SW1", "node_type": "ethernet_switch", "properties": {}},
+      {"node_id": "n5", "name": "SW2", "node_type": "ethernet_switch", "properties": {}},
+      {"node_id": "n6", "name": "Internet", "node_type": "nat", "properties": {}}
+    ],
+    "links": [
+      {"nodes": [{"node_id": "n1", "adapter_number": 0, "port_number": 0}, {"node_id": "n2", "adapter_number": 0, "port_number": 0}]},
+      {"nodes": [{"node_id": "n2", "adapter_number": 1, "port_number": 0}, {"node_id": "n4", "adapter_number": 0, "port_number": 1}]},
+      {"nodes": [{"node_id": "n4", "adapter_number": 0, "port_number": 2}, {"node_id": "n5", "adapter_number": 0, "port_number": 1}]},
+      {"nodes": [{"node_id": "n3", "adapter_number": 0, "port_number": 0}, {"node_id": "n5", "adapter_number": 0, "port_number": 2}]},
+      {"nodes": [{"node_id": "n1", "adapter_number": 1, "port_number": 0}, {"node_id": "n6", "adapter_number": 0, "port_number": 0}]}
+    ]
+  }
+}`
+
+func importTestFile(t *testing.T, name, body string, opts ...Option) *Result {
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	result, err := File(path, opts...)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := types.ValidateConfigSpec(*result.Config); err != nil {
+		t.Logf("expected imported topology to be valid: %v", err)
+		t.FailNow()
+	}
+
+	return result
+}
+
+func interfaceVLANs(t *testing.T, result *Result) map[string]string {
+	topo, err := types.DecodeTopologyFromConfig(*result.Config)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	vlans := make(map[string]string)
+
+	for _, node := range topo.Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			vlans[node.General().Hostname()+"/"+iface.Name()] = iface.VLAN()
+		}
+	}
+
+	return vlans
+}
+
+func hasWarning(warnings []string, substr string) bool {
+	for _, w := range warnings {
+		if strings.Contains(w, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestContainerlab(t *testing.T) {
+	result := importTestFile(t, "lab.clab.yml", testContainerlab, ImportWithImages(map[string]string{"ghcr.io/nokia/srlinux:23.3.1": "srlinux.qc2"}))
+
+	if result.Config.Metadata.Name != "srl-linux" {
+		t.Logf("expected topology name srl-linux, got %s", result.Config.Metadata.Name)
+		t.FailNow()
+	}
+
+	vlans := interfaceVLANs(t, result)
+
+	expected := map[string]string{
+		"srl/mgmt0":    "MGMT",
+		"srl/e1-1":     "srl-client1",
+		"client1/eth1": "srl-client1",
+		"srl/e1-2":     "br-lan",
+		"client2/eth1": "br-lan",
+	}
+
+	if len(vlans) != len(expected) {
+		t.Logf("expected %d interfaces, got %v", len(expected), vlans)
+		t.FailNow()
+	}
+
+	for iface, vlan := range expected {
+		if vlans[iface] != vlan {
+			t.Logf("expected %s to be on VLAN %s, got %s", iface, vlan, vlans[iface])
+			t.FailNow()
+		}
+	}
+
+	for _, w := range []string{"srl: startup config", "client1: exec commands", "link type host", "client1: image ghcr.io/hellt/network-multitool converted to network-multitool_rootfs.tgz"} {
+		if !hasWarning(result.Warnings, w) {
+			t.Logf("expected warning containing '%s', got %v", w, result.Warnings)
+			t.FailNow()
+		}
+	}
+
+	if hasWarning(result.Warnings, "srl: image") {
+		t.Logf("expected no image warning for mapped srl image, got %v", result.Warnings)
+		t.FailNow()
+	}
+}
+
+func TestClabMemory(t *testing.T) {
+	for mem, expected := range map[string]int{"1Gb": 1024, "512MB": 512, "2g": 2048, "1048576": 1} {
+		got, err := clabMemory(mem)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if got != expected {
+			t.Logf("expected %d MB for %s, got %d", expected, mem, got)
+			t.FailNow()
+		}
+	}
+
+	if _, err := clabMemory("lots"); err == nil {
+		t.Log("expected error for invalid memory")
+		t.FailNow()
+	}
+}
+
+func TestGNS3(t *testing.T) {
+	result := importTestFile(t, "lab.gns3", testGNS3)
+
+	if result.Config.Metadata.Name != "ospf-lab" {
+		t.Logf("expected topology name ospf-lab, got %s", result.Config.Metadata.Name)
+		t.FailNow()
+	}
+
+	vlans := interfaceVLANs(t, result)
+
+	if vlans["R1/eth0"] != "R1-R2" || vlans["R2/eth0"] != "R1-R2" {
+		t.Logf("expected R1 and R2 eth0 to be on VLAN R1-R2, got %v", vlans)
+		t.FailNow()
+	}
+
+	// Switches connected to each other should be merged into one VLAN.
+	if vlans["R2/eth1"] != "SW1" || vlans["PC1/eth0"] != "SW1" {
+		t.Logf("expected R2 eth1 and PC1 eth0 to be on VLAN SW1, got %v", vlans)
+		t.FailNow()
+	}
+
+	if _, ok := vlans["R1/eth1"]; ok {
+		t.Log("expected R1 eth1 to be skipped since it's connected to an unsupported node")
+		t.FailNow()
+	}
+
+	if !hasWarning(result.Warnings, "Internet: node type nat is not supported") {
+		t.Logf("expected warning for unsupported nat node, got %v", result.Warnings)
+		t.FailNow()
+	}
+}
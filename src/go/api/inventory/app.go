@@ -0,0 +1,99 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"phenix/app"
+	"phenix/types"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func init() {
+	app.RegisterUserApp("inventory", func() app.App { return new(Inventory) })
+}
+
+// Inventory is the scenario app used to periodically collect guest software
+// inventories. Inventory is collected each time the running stage is executed,
+// so it's typically combined with the app's `runPeriodically` setting.
+// Inventory can also be collected on demand via `Collect` without including
+// this app in the scenario.
+type Inventory struct {
+	options app.Options
+}
+
+func (this *Inventory) Init(opts ...app.Option) error {
+	this.options = app.NewOptions(opts...)
+	return nil
+}
+
+func (Inventory) Name() string {
+	return "inventory"
+}
+
+func (Inventory) Configure(ctx context.Context, exp *types.Experiment) error {
+	_, err := decodeCollectOptions(exp)
+	return err
+}
+
+func (Inventory) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Inventory) PostStart(ctx context.Context, exp *types.Experiment) error {
+	// Don't carry inventory over from previous experiment runs.
+	exp.Status.SetAppStatus("inventory", nil)
+	return nil
+}
+
+func (this Inventory) Running(ctx context.Context, exp *types.Experiment) error {
+	if this.options.DryRun {
+		return nil
+	}
+
+	opts, err := decodeCollectOptions(exp)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := collect(ctx, exp, newCollectOptions(opts...))
+	if err != nil {
+		return fmt.Errorf("collecting inventory: %w", err)
+	}
+
+	return persist(exp, hosts)
+}
+
+func (Inventory) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func decodeCollectOptions(exp *types.Experiment) ([]CollectOption, error) {
+	var (
+		md   inventoryMetadata
+		opts []CollectOption
+	)
+
+	if app := exp.App("inventory"); app != nil && app.Metadata() != nil {
+		if err := mapstructure.Decode(app.Metadata(), &md); err != nil {
+			return nil, fmt.Errorf("decoding app metadata: %w", err)
+		}
+	}
+
+	if md.C2Timeout != "" {
+		timeout, err := time.ParseDuration(md.C2Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("parsing C2 timeout setting '%s': %w", md.C2Timeout, err)
+		}
+
+		opts = append(opts, CollectWithC2Timeout(timeout))
+	}
+
+	if len(md.Hosts) > 0 {
+		opts = append(opts, CollectFromHosts(md.Hosts...))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,2 @@
+// Implementation of the phenix guest software inventory API.
+package inventory
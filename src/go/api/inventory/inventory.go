@@ -0,0 +1,368 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"golang.org/x/exp/slices"
+)
+
+var ErrNoInventory = errors.New("no inventory collected for experiment")
+
+// Commands used to collect inventory from Linux guests. Package managers that
+// aren't installed will simply produce no output, so both dpkg and rpm are
+// queried.
+const (
+	linuxPackages  = `sh -c "dpkg-query -W -f='${Package}\t${Version}\n' 2>/dev/null; rpm -qa --qf '%{NAME}\t%|EPOCH?{%{EPOCH}:}:{}|%{VERSION}-%{RELEASE}\n' 2>/dev/null"`
+	linuxProcesses = `ps -eo comm=`
+	linuxServices  = `sh -c "systemctl list-units --type=service --state=running --no-legend --plain 2>/dev/null | cut -d' ' -f1"`
+)
+
+// Commands used to collect inventory from Windows guests.
+const (
+	windowsPackages  = `powershell -command "Get-ItemProperty HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*, HKLM:\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Uninstall\* -ErrorAction SilentlyContinue | Where-Object { $_.DisplayName } | ForEach-Object { $_.DisplayName + [char]9 + $_.DisplayVersion }"`
+	windowsProcesses = `powershell -command "Get-Process | Select-Object -ExpandProperty ProcessName"`
+	windowsServices  = `powershell -command "Get-Service | Where-Object { $_.Status -eq 'Running' } | Select-Object -ExpandProperty Name"`
+)
+
+type collectOptions struct {
+	hosts   []string
+	timeout time.Duration
+}
+
+type CollectOption func(*collectOptions)
+
+func newCollectOptions(opts ...CollectOption) collectOptions {
+	o := collectOptions{
+		timeout: 1 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// CollectFromHosts limits inventory collection to the given VMs. By default,
+// inventory is collected from all VMs in the experiment.
+func CollectFromHosts(h ...string) CollectOption {
+	return func(o *collectOptions) {
+		o.hosts = h
+	}
+}
+
+func CollectWithC2Timeout(t time.Duration) CollectOption {
+	return func(o *collectOptions) {
+		if t > 0 {
+			o.timeout = t
+		}
+	}
+}
+
+// Collect gathers installed package, running process, and running service
+// inventories from VMs in the given running experiment via minimega's C2 and
+// persists them to the experiment status. Inventory for any VMs not collected
+// from remains as it was.
+func Collect(ctx context.Context, expName string, opts ...CollectOption) (map[string]Host, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return nil, experiment.ErrExperimentNotRunning
+	}
+
+	hosts, err := collect(ctx, exp, newCollectOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	// Collection can take a while, so make sure we don't overwrite any status
+	// updates that happened in the meantime.
+	if err := exp.Reload(); err != nil {
+		return nil, fmt.Errorf("reloading experiment %s: %w", expName, err)
+	}
+
+	if err := persist(exp, hosts); err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
+// Get returns the inventory last collected for the given VMs in the given
+// experiment. If no VMs are provided, inventory for all VMs is returned.
+func Get(expName string, hosts ...string) (map[string]Host, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get experiment %s: %w", expName, err)
+	}
+
+	var status inventoryStatus
+
+	if err := exp.Status.ParseAppStatus("inventory", &status); err != nil || len(status.Hosts) == 0 {
+		return nil, ErrNoInventory
+	}
+
+	if len(hosts) == 0 {
+		return status.Hosts, nil
+	}
+
+	filtered := make(map[string]Host)
+
+	for _, h := range hosts {
+		if inv, ok := status.Hosts[h]; ok {
+			filtered[h] = inv
+		}
+	}
+
+	return filtered, nil
+}
+
+// Query returns all the VMs in the given experiment that have the given
+// package installed at a version matching the given constraint (ie.
+// `<1.1.1`). Package names are case-insensitive, and an empty constraint
+// matches any installed version.
+func Query(expName, pkg, constraint string) ([]Match, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing version constraint: %w", err)
+	}
+
+	hosts, err := Get(expName)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		name    = strings.ToLower(pkg)
+		matches []Match
+	)
+
+	for host, inv := range hosts {
+		p, ok := inv.Package(name)
+		if !ok {
+			continue
+		}
+
+		if c.Matches(p.Version) {
+			matches = append(matches, Match{Hostname: host, Package: p.Name, Version: p.Version})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Hostname < matches[j].Hostname
+	})
+
+	return matches, nil
+}
+
+func collect(ctx context.Context, exp *types.Experiment, o collectOptions) (map[string]Host, error) {
+	var (
+		ns    = exp.Spec.ExperimentName()
+		hosts = make(map[string]Host)
+		nodes []ifaces.NodeSpec
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || *node.General().DoNotBoot() {
+			continue
+		}
+
+		if len(o.hosts) > 0 && !slices.Contains(o.hosts, node.General().Hostname()) {
+			continue
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	if len(o.hosts) > 0 && len(nodes) == 0 {
+		return nil, fmt.Errorf("none of the VMs provided exist in experiment %s", ns)
+	}
+
+	for _, node := range nodes {
+		wg.Add(1)
+
+		go func(node ifaces.NodeSpec) {
+			defer wg.Done()
+
+			inv := collectHost(ctx, ns, node, o.timeout)
+
+			mu.Lock()
+			hosts[inv.Hostname] = inv
+			mu.Unlock()
+		}(node)
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return hosts, nil
+}
+
+func collectHost(ctx context.Context, ns string, node ifaces.NodeSpec, timeout time.Duration) Host {
+	var (
+		host    = node.General().Hostname()
+		os      = strings.ToLower(node.Hardware().OSType())
+		windows = os == "windows"
+
+		pkgCmd  = linuxPackages
+		procCmd = linuxProcesses
+		svcCmd  = linuxServices
+	)
+
+	if windows {
+		pkgCmd = windowsPackages
+		procCmd = windowsProcesses
+		svcCmd = windowsServices
+	}
+
+	inv := Host{Hostname: host, OSType: os, Collected: time.Now().Format(time.RFC3339)}
+
+	if out, err := run(ctx, ns, host, pkgCmd, timeout); err != nil {
+		inv.Errors = append(inv.Errors, fmt.Sprintf("collecting packages: %v", err))
+	} else {
+		inv.Packages = parsePackages(out)
+	}
+
+	if out, err := run(ctx, ns, host, procCmd, timeout); err != nil {
+		inv.Errors = append(inv.Errors, fmt.Sprintf("collecting processes: %v", err))
+	} else {
+		inv.Processes = parseNames(out, windows)
+	}
+
+	if out, err := run(ctx, ns, host, svcCmd, timeout); err != nil {
+		inv.Errors = append(inv.Errors, fmt.Sprintf("collecting services: %v", err))
+	} else {
+		inv.Services = parseNames(out, windows)
+	}
+
+	if len(inv.Errors) > 0 {
+		plog.Warn("errors collecting inventory", "exp", ns, "vm", host, "errors", inv.Errors)
+	}
+
+	return inv
+}
+
+func run(ctx context.Context, ns, host, cmd string, timeout time.Duration) (string, error) {
+	opts := []mm.C2Option{mm.C2NS(ns), mm.C2VM(host), mm.C2Context(ctx), mm.C2Timeout(timeout)}
+
+	id, err := mm.ExecC2Command(append(opts, mm.C2Command(cmd), mm.C2Wait())...)
+	if err != nil {
+		return "", err
+	}
+
+	return mm.GetC2Response(append(opts, mm.C2CommandID(id), mm.C2ResponseTypeStdout())...)
+}
+
+// parsePackages parses tab-separated name/version pairs, one per line.
+func parsePackages(out string) []Package {
+	var (
+		pkgs []Package
+		seen = make(map[string]struct{})
+	)
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+
+		if fields[0] == "" {
+			continue
+		}
+
+		pkg := Package{Name: strings.ToLower(strings.TrimSpace(fields[0]))}
+
+		if len(fields) == 2 {
+			pkg.Version = strings.TrimSpace(fields[1])
+		}
+
+		key := pkg.Name + "\t" + pkg.Version
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+
+		seen[key] = struct{}{}
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+
+	return pkgs
+}
+
+// parseNames parses process or service names, one per line, removing
+// duplicates. Windows process names don't include the `.exe` extension, and
+// systemd service names have their `.service` suffix removed, so names are
+// comparable across operating systems.
+func parseNames(out string, windows bool) []string {
+	var (
+		names []string
+		seen  = make(map[string]struct{})
+	)
+
+	for _, line := range strings.Split(out, "\n") {
+		name := strings.ToLower(strings.TrimSpace(line))
+
+		if windows {
+			name = strings.TrimSuffix(name, ".exe")
+		} else {
+			name = strings.TrimSuffix(name, ".service")
+		}
+
+		if name == "" {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func persist(exp *types.Experiment, hosts map[string]Host) error {
+	var status inventoryStatus
+	exp.Status.ParseAppStatus("inventory", &status)
+
+	if status.Hosts == nil {
+		status.Hosts = make(map[string]Host)
+	}
+
+	for name, inv := range hosts {
+		status.Hosts[name] = inv
+	}
+
+	exp.Status.SetAppStatus("inventory", status)
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("writing inventory to store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package inventory
+
+type Package struct {
+	Name    string `json:"name" mapstructure:"name" structs:"name"`
+	Version string `json:"version" mapstructure:"version" structs:"version"`
+}
+
+// Host is the normalized software inventory collected from a single VM.
+// Package, process, and service names are always lowercase so they can be
+// queried the same way regardless of the guest OS.
+type Host struct {
+	Hostname  string    `json:"hostname" mapstructure:"hostname" structs:"hostname"`
+	OSType    string    `json:"os_type" mapstructure:"os_type" structs:"os_type"`
+	Collected string    `json:"collected" mapstructure:"collected" structs:"collected"`
+	Packages  []Package `json:"packages" mapstructure:"packages" structs:"packages"`
+	Processes []string  `json:"processes" mapstructure:"processes" structs:"processes"`
+	Services  []string  `json:"services" mapstructure:"services" structs:"services"`
+	Errors    []string  `json:"errors,omitempty" mapstructure:"errors" structs:"errors"`
+}
+
+func (this Host) Package(name string) (Package, bool) {
+	for _, pkg := range this.Packages {
+		if pkg.Name == name {
+			return pkg, true
+		}
+	}
+
+	return Package{}, false
+}
+
+// Match is a single result from an inventory query.
+type Match struct {
+	Hostname string `json:"hostname"`
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+}
+
+// inventoryStatus is what gets persisted to the experiment status under the
+// `inventory` app key.
+type inventoryStatus struct {
+	Hosts map[string]Host `mapstructure:"hosts" structs:"hosts"`
+}
+
+type inventoryMetadata struct {
+	C2Timeout string   `mapstructure:"c2Timeout"`
+	Hosts     []string `mapstructure:"hosts"`
+}
@@ -0,0 +1,179 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// CompareVersions compares two package version strings, returning -1, 0, or 1
+// if a is less than, equal to, or greater than b. It's modeled after the
+// segment-based comparison used by package managers (e.g. rpmvercmp) so it
+// handles the common Debian, RPM, and Windows version formats well enough for
+// inventory queries. Any epoch prefix (ie. `1:`) is compared first.
+func CompareVersions(a, b string) int {
+	ea, va := splitEpoch(a)
+	eb, vb := splitEpoch(b)
+
+	if c := compareSegments(ea, eb); c != 0 {
+		return c
+	}
+
+	return compareSegments(va, vb)
+}
+
+func splitEpoch(v string) (string, string) {
+	if idx := strings.Index(v, ":"); idx > 0 {
+		return v[:idx], v[idx+1:]
+	}
+
+	return "0", v
+}
+
+func compareSegments(a, b string) int {
+	sa, sb := segments(a), segments(b)
+
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		var (
+			x = sa[i]
+			y = sb[i]
+
+			xNum = unicode.IsDigit(rune(x[0]))
+			yNum = unicode.IsDigit(rune(y[0]))
+		)
+
+		// numeric segments are always newer than alpha segments
+		if xNum != yNum {
+			if xNum {
+				return 1
+			}
+
+			return -1
+		}
+
+		if xNum {
+			x = strings.TrimLeft(x, "0")
+			y = strings.TrimLeft(y, "0")
+
+			if len(x) != len(y) {
+				if len(x) > len(y) {
+					return 1
+				}
+
+				return -1
+			}
+		}
+
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(sa) > len(sb):
+		return 1
+	case len(sa) < len(sb):
+		return -1
+	}
+
+	return 0
+}
+
+// segments splits a version string into alternating runs of digits and
+// letters, dropping all separators.
+func segments(v string) []string {
+	var (
+		segs []string
+		cur  []rune
+		num  bool
+	)
+
+	flush := func() {
+		if len(cur) > 0 {
+			segs = append(segs, string(cur))
+			cur = nil
+		}
+	}
+
+	for _, r := range v {
+		switch {
+		case unicode.IsDigit(r):
+			if !num {
+				flush()
+			}
+
+			num = true
+			cur = append(cur, r)
+		case unicode.IsLetter(r):
+			if num {
+				flush()
+			}
+
+			num = false
+			cur = append(cur, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+
+	flush()
+
+	return segs
+}
+
+// Constraint is a version constraint such as `<1.1.1` or `>=3.0`. An empty
+// constraint matches all versions.
+type Constraint struct {
+	op      string
+	version string
+}
+
+func ParseConstraint(c string) (Constraint, error) {
+	c = strings.TrimSpace(c)
+
+	if c == "" {
+		return Constraint{}, nil
+	}
+
+	for _, op := range []string{"<=", ">=", "!=", "==", "<", ">", "="} {
+		if strings.HasPrefix(c, op) {
+			v := strings.TrimSpace(strings.TrimPrefix(c, op))
+
+			if v == "" {
+				return Constraint{}, fmt.Errorf("missing version in constraint '%s'", c)
+			}
+
+			if op == "==" {
+				op = "="
+			}
+
+			return Constraint{op: op, version: v}, nil
+		}
+	}
+
+	// no operator means an exact match
+	return Constraint{op: "=", version: c}, nil
+}
+
+func (this Constraint) Matches(version string) bool {
+	if this.op == "" {
+		return true
+	}
+
+	c := CompareVersions(version, this.version)
+
+	switch this.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "!=":
+		return c != 0
+	}
+
+	return c == 0
+}
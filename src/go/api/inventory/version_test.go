@@ -0,0 +1,50 @@
+package inventory
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.1.1", "1.1.1", 0},
+		{"1.1.1k", "1.1.1", 1},
+		{"1.1.0l", "1.1.1", -1},
+		{"3.0.2-0ubuntu1.10", "3.0.2-0ubuntu1.9", 1},
+		{"1:1.0", "2.0", 1},
+		{"1.02", "1.2", 0},
+		{"1.0a", "1.0.1", -1},
+		{"10.0.19041.1", "9.0", 1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConstraintMatches(t *testing.T) {
+	tests := []struct {
+		constraint, version string
+		want                bool
+	}{
+		{"", "1.0", true},
+		{"<1.1.1", "1.1.0l", true},
+		{"<1.1.1", "1.1.1k", false},
+		{">=3.0", "3.0.2", true},
+		{"!=3.0.2", "3.0.2", false},
+		{"1.2.3", "1.2.3", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("parsing constraint %q: %v", tt.constraint, err)
+		}
+
+		if got := c.Matches(tt.version); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package matrix implements a harness for testing scenarios without deploying
+// them, suitable for running in CI for scenario repositories. Each scenario is
+// instantiated as a dry-run experiment against each of a set of topologies (or
+// a small synthetic topology generated for the scenario), its apps are
+// validated and applied for every experiment lifecycle stage, and the
+// resulting experiment spec is checked for invariants phenix relies on to
+// launch it, such as no IP conflicts and resolvable file injections.
+package matrix
@@ -0,0 +1,328 @@
+package matrix
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"phenix/app"
+	"phenix/store"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/types/version"
+	v1 "phenix/types/version/v1"
+)
+
+// Run tests each of the given scenario files against each of the configured
+// topology files (or a synthetic topology if none are configured), returning a
+// result for each combination. Failures of the tests themselves are reported in
+// the results. An error is only returned if the options are invalid or a
+// scenario or topology file can't be loaded.
+func Run(ctx context.Context, scenarios []string, opts ...Option) ([]Result, error) {
+	o := newOptions(opts...)
+
+	for _, stage := range o.stages {
+		if !slices.Contains(Stages, stage) {
+			return nil, fmt.Errorf("applying apps for the %s stage is not supported", stage)
+		}
+	}
+
+	var (
+		scenarioCs = make([]*store.Config, len(scenarios))
+		topologyCs []*store.Config
+	)
+
+	for i, path := range scenarios {
+		c, err := load(path, "Scenario")
+		if err != nil {
+			return nil, err
+		}
+
+		scenarioCs[i] = c
+	}
+
+	for _, path := range o.topologies {
+		c, err := load(path, "Topology")
+		if err != nil {
+			return nil, err
+		}
+
+		topologyCs = append(topologyCs, c)
+	}
+
+	// A nil topology config is used for the synthetic topology.
+	if len(topologyCs) == 0 {
+		topologyCs = append(topologyCs, nil)
+	}
+
+	var results []Result
+
+	for _, scenario := range scenarioCs {
+		for _, topology := range topologyCs {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+
+			res := Result{Scenario: scenario.Metadata.Name, Topology: SyntheticTopology}
+
+			if topology != nil {
+				res.Topology = topology.Metadata.Name
+			}
+
+			if err := run(ctx, o, scenario, topology, &res); err != nil {
+				res.Failures = append(res.Failures, err.Error())
+			}
+
+			res.Passed = len(res.Failures) == 0
+
+			results = append(results, res)
+		}
+	}
+
+	return results, nil
+}
+
+// load reads the config of the given kind from the given file, defaulting its
+// name to the name of the file.
+func load(path, kind string) (*store.Config, error) {
+	c, err := store.NewConfigFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading %s file %s: %w", strings.ToLower(kind), path, err)
+	}
+
+	if c.Kind != kind {
+		return nil, fmt.Errorf("%s is a %s config, not a %s config", path, c.Kind, kind)
+	}
+
+	if c.Metadata.Name == "" {
+		c.Metadata.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return c, nil
+}
+
+func run(ctx context.Context, o options, scenarioC, topologyC *store.Config, res *Result) error {
+	// This will upgrade the scenario to the latest known version if needed.
+	scenario, err := types.MakeCustomScenarioFromConfig(*scenarioC, o.skip)
+	if err != nil {
+		return fmt.Errorf("decoding scenario: %w", err)
+	}
+
+	var topo ifaces.TopologySpec
+
+	if topologyC == nil {
+		topo = synthetic(scenario, o.nodes)
+	} else if topo, err = types.DecodeTopologyFromConfig(*topologyC); err != nil {
+		return fmt.Errorf("decoding topology: %w", err)
+	}
+
+	baseDir, err := os.MkdirTemp("", "phenix-matrix-")
+	if err != nil {
+		return fmt.Errorf("creating experiment base directory: %w", err)
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	name := "matrix-" + res.Scenario
+
+	c := &store.Config{
+		Version: store.API_GROUP + "/" + version.StoredVersion["Experiment"],
+		Kind:    "Experiment",
+		Metadata: store.ConfigMetadata{
+			Name:        name,
+			Annotations: map[string]string{"topology": res.Topology, "scenario": res.Scenario},
+		},
+		Spec: map[string]any{
+			"experimentName": name,
+			"baseDir":        baseDir,
+			"topology":       topo,
+			"scenario":       scenario,
+		},
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment: %w", err)
+	}
+
+	if err := exp.Spec.Init(); err != nil {
+		return fmt.Errorf("initializing experiment: %w", err)
+	}
+
+	exp.Status.Init()
+
+	if err := app.ValidateApps(exp); err != nil {
+		return err
+	}
+
+	// Mark the experiment as a dry run so apps that check for one don't do any
+	// work outside of the experiment spec (ie. attaching to shared services).
+	exp.Status.SetStartTime(time.Now().Format(time.RFC3339) + "-DRYRUN")
+
+	var (
+		checked bool
+		diffs   []app.AppDiff
+	)
+
+	opts := []app.Option{
+		app.DryRun(true),
+		app.SkipApp(o.skip...),
+		app.OnDiff(func(d app.AppDiff) { diffs = append(diffs, d) }),
+	}
+
+	for _, stage := range Stages {
+		if !slices.Contains(o.stages, stage) {
+			continue
+		}
+
+		diffs = nil
+
+		err := app.ApplyApps(ctx, exp, append(opts, app.Stage(stage))...)
+
+		res.Apps = append(res.Apps, diffs...)
+
+		var failed bool
+
+		for _, d := range diffs {
+			if d.Error != "" {
+				res.Failures = append(res.Failures, fmt.Sprintf("%s: app %s: %s", stage, d.App, d.Error))
+				failed = true
+			}
+		}
+
+		if err != nil {
+			if !failed {
+				res.Failures = append(res.Failures, fmt.Sprintf("%s: %v", stage, err))
+			}
+
+			return nil
+		}
+
+		// VMs are launched after the pre-start stage, so that's when the spec
+		// has to be valid.
+		if stage == app.ACTIONPRESTART {
+			res.Failures = append(res.Failures, check(exp)...)
+			checked = true
+		}
+	}
+
+	if !checked {
+		res.Failures = append(res.Failures, check(exp)...)
+	}
+
+	return nil
+}
+
+// synthetic returns a small topology for the given scenario: a Linux VM, a
+// Windows VM, the given nodes, and a Linux VM for each host referenced by the
+// scenario's apps, all on a single VLAN with unique static addresses.
+func synthetic(scenario ifaces.ScenarioSpec, extra []Node) *v1.TopologySpec {
+	nodes := []Node{{Hostname: "matrix-linux", OS: "linux"}, {Hostname: "matrix-windows", OS: "windows"}}
+	nodes = append(nodes, extra...)
+
+	for _, a := range scenario.Apps() {
+		for _, host := range a.Hosts() {
+			// Skip hostnames that are patterns rather than names.
+			if strings.ContainsAny(host.Hostname(), "*?[") {
+				continue
+			}
+
+			nodes = append(nodes, Node{Hostname: host.Hostname(), OS: "linux"})
+		}
+	}
+
+	var (
+		topo = new(v1.TopologySpec)
+		seen = make(map[string]struct{})
+	)
+
+	for _, n := range nodes {
+		if _, ok := seen[n.Hostname]; ok {
+			continue
+		}
+
+		seen[n.Hostname] = struct{}{}
+
+		iface := &v1.Interface{
+			NameF:    "eth0",
+			TypeF:    "ethernet",
+			VLANF:    "MATRIX",
+			ProtoF:   "static",
+			AddressF: fmt.Sprintf("10.213.%d.%d", len(topo.NodesF)/250, len(topo.NodesF)%250+1),
+			MaskF:    16,
+		}
+
+		topo.NodesF = append(topo.NodesF, &v1.Node{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: n.Hostname},
+			HardwareF: &v1.Hardware{OSTypeF: n.OS, DrivesF: []*v1.Drive{{ImageF: n.OS + ".qc2"}}},
+			NetworkF:  &v1.Network{InterfacesF: []*v1.Interface{iface}},
+		})
+	}
+
+	return topo
+}
+
+// check returns a description of each violation of the invariants phenix relies
+// on to launch the given experiment: hostnames are unique, static addresses
+// aren't used more than once on a VLAN, and the source of every file injection
+// exists.
+func check(exp *types.Experiment) []string {
+	var (
+		failures []string
+		hosts    = make(map[string]struct{})
+		addrs    = make(map[string]string)
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		host := node.General().Hostname()
+
+		if _, ok := hosts[host]; ok {
+			failures = append(failures, fmt.Sprintf("duplicate hostname %s", host))
+		}
+
+		hosts[host] = struct{}{}
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Address() == "" || strings.EqualFold(iface.Proto(), "dhcp") {
+				continue
+			}
+
+			var (
+				key  = iface.VLAN() + "|" + iface.Address()
+				name = host + "/" + iface.Name()
+			)
+
+			if other, ok := addrs[key]; ok {
+				failures = append(failures, fmt.Sprintf("IP conflict: %s and %s both use %s on VLAN %s", other, name, iface.Address(), iface.VLAN()))
+				continue
+			}
+
+			addrs[key] = name
+		}
+
+		if node.External() {
+			continue
+		}
+
+		for _, inject := range node.Injections() {
+			src := inject.Src()
+
+			if !filepath.IsAbs(src) {
+				src = filepath.Join(exp.Spec.BaseDir(), src)
+			}
+
+			if info, err := os.Stat(src); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: injected file %s (to %s) doesn't exist", host, src, inject.Dst()))
+			} else if info.IsDir() {
+				failures = append(failures, fmt.Sprintf("%s: injected file %s (to %s) is a directory", host, src, inject.Dst()))
+			}
+		}
+	}
+
+	return failures
+}
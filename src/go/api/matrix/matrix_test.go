@@ -0,0 +1,180 @@
+package matrix
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/app"
+	"phenix/store"
+)
+
+var scenario = `
+apiVersion: phenix.sandia.gov/v2
+kind: Scenario
+metadata:
+  name: web
+spec:
+  apps:
+  - name: firewall
+    metadata:
+      defaultPolicy: deny
+      rules:
+      - action: allow
+        dst: web
+        port: 80
+  - name: not-installed
+    hosts:
+    - hostname: web
+`
+
+var topology = `
+apiVersion: phenix.sandia.gov/v1
+kind: Topology
+metadata:
+  name: conflicts
+spec:
+  nodes:
+  - type: VirtualMachine
+    general:
+      hostname: web
+    hardware:
+      os_type: linux
+      drives:
+      - image: linux.qc2
+    network:
+      interfaces:
+      - name: eth0
+        vlan: EXP
+        address: 10.0.0.1
+        mask: 24
+        proto: static
+        type: ethernet
+    injections:
+    - src: /does/not/exist.sh
+      dst: /etc/phenix/startup/9_missing-start.sh
+  - type: VirtualMachine
+    general:
+      hostname: db
+    hardware:
+      os_type: linux
+      drives:
+      - image: linux.qc2
+    network:
+      interfaces:
+      - name: eth0
+        vlan: EXP
+        address: 10.0.0.1
+        mask: 24
+        proto: static
+        type: ethernet
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	// Default apps read site settings from the store.
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(dir, "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var (
+		scenarioPath = filepath.Join(dir, "scenario.yml")
+		topologyPath = filepath.Join(dir, "topology.yml")
+	)
+
+	os.WriteFile(scenarioPath, []byte(scenario), 0644)
+	os.WriteFile(topologyPath, []byte(topology), 0644)
+
+	results, err := Run(context.Background(), []string{scenarioPath}, WithSkipApps("not-installed"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(results) != 1 || results[0].Topology != SyntheticTopology {
+		t.Logf("expected a single result for the synthetic topology, got %+v", results)
+		t.FailNow()
+	}
+
+	res := results[0]
+
+	if !res.Passed {
+		t.Logf("expected scenario to pass against synthetic topology, got failures: %v", res.Failures)
+		t.FailNow()
+	}
+
+	// The firewall app should have injected rules into every synthetic node,
+	// including the web node referenced by the skipped app.
+	injected := make(map[string]bool)
+
+	for _, d := range res.Apps {
+		if d.App != "firewall" {
+			continue
+		}
+
+		for _, i := range d.InjectionsAdded {
+			injected[i.Node] = true
+		}
+	}
+
+	for _, host := range []string{"matrix-linux", "matrix-windows", "web"} {
+		if !injected[host] {
+			t.Logf("expected firewall app to inject rules into %s, got %+v", host, res.Apps)
+			t.FailNow()
+		}
+	}
+
+	// Only apply the configure stage, since the startup app also fails the
+	// pre-start stage for IP conflicts.
+	results, err = Run(context.Background(), []string{scenarioPath}, WithTopologies(topologyPath), WithSkipApps("not-installed"), WithStages(app.ACTIONCONFIG))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(results) != 1 || results[0].Topology != "conflicts" || results[0].Passed {
+		t.Logf("expected a single failed result for the conflicts topology, got %+v", results)
+		t.FailNow()
+	}
+
+	failures := strings.Join(results[0].Failures, "\n")
+
+	for _, expected := range []string{"IP conflict: web/eth0 and db/eth0 both use 10.0.0.1 on VLAN EXP", "/does/not/exist.sh (to /etc/phenix/startup/9_missing-start.sh) doesn't exist"} {
+		if !strings.Contains(failures, expected) {
+			t.Logf("expected failure '%s', got: %s", expected, failures)
+			t.FailNow()
+		}
+	}
+
+	if _, err := Run(context.Background(), []string{scenarioPath}, WithStages(app.ACTIONRUNNING)); err == nil {
+		t.Log("expected error for unsupported running stage")
+		t.FailNow()
+	}
+
+	if _, err := Run(context.Background(), []string{topologyPath}); err == nil {
+		t.Log("expected error for topology passed as scenario")
+		t.FailNow()
+	}
+}
+
+func TestParseNode(t *testing.T) {
+	n, err := ParseNode("hmi:Windows")
+	if err != nil || n.Hostname != "hmi" || n.OS != "windows" {
+		t.Logf("unexpected node %+v (%v)", n, err)
+		t.FailNow()
+	}
+
+	if n, _ := ParseNode("web"); n.OS != "linux" {
+		t.Logf("expected OS to default to linux, got %s", n.OS)
+		t.FailNow()
+	}
+
+	if _, err := ParseNode(":linux"); err == nil {
+		t.Log("expected error for node without hostname")
+		t.FailNow()
+	}
+}
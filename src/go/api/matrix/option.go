@@ -0,0 +1,58 @@
+package matrix
+
+import "phenix/app"
+
+type Option func(*options)
+
+type options struct {
+	topologies []string
+	nodes      []Node
+	stages     []app.Action
+	skip       []string
+}
+
+func newOptions(opts ...Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.stages) == 0 {
+		o.stages = Stages
+	}
+
+	return o
+}
+
+// WithTopologies sets the topology files each scenario is tested against. If
+// none are given, each scenario is tested against a synthetic topology.
+func WithTopologies(t ...string) Option {
+	return func(o *options) {
+		o.topologies = append(o.topologies, t...)
+	}
+}
+
+// WithNodes adds the given nodes to the synthetic topology, for hosts apps
+// reference in their metadata rather than in their hosts list.
+func WithNodes(n ...Node) Option {
+	return func(o *options) {
+		o.nodes = append(o.nodes, n...)
+	}
+}
+
+// WithStages limits the app stages applied to the given stages, which are
+// always applied in lifecycle order.
+func WithStages(s ...app.Action) Option {
+	return func(o *options) {
+		o.stages = append(o.stages, s...)
+	}
+}
+
+// WithSkipApps disables the given scenario apps, ie. user apps that aren't
+// installed where the tests are run.
+func WithSkipApps(a ...string) Option {
+	return func(o *options) {
+		o.skip = append(o.skip, a...)
+	}
+}
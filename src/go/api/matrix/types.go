@@ -0,0 +1,53 @@
+package matrix
+
+import (
+	"fmt"
+	"strings"
+
+	"phenix/app"
+)
+
+// SyntheticTopology is the name results report for the synthetic topology.
+const SyntheticTopology = "synthetic"
+
+// Stages are the app stages applied to each experiment, in lifecycle order.
+// The running stage is not applied since it's only triggered for deployed
+// experiments.
+var Stages = []app.Action{app.ACTIONCONFIG, app.ACTIONPRESTART, app.ACTIONPOSTSTART, app.ACTIONCLEANUP}
+
+// Node is a VM added to the synthetic topology.
+type Node struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+}
+
+// ParseNode parses a synthetic topology node in the form `hostname[:os]`. The
+// OS type defaults to linux.
+func ParseNode(n string) (Node, error) {
+	host, os, _ := strings.Cut(n, ":")
+
+	if host == "" {
+		return Node{}, fmt.Errorf("no hostname provided for node %s", n)
+	}
+
+	if os == "" {
+		os = "linux"
+	}
+
+	return Node{Hostname: host, OS: strings.ToLower(os)}, nil
+}
+
+// Result is the outcome of testing a single scenario against a single
+// topology.
+type Result struct {
+	Scenario string `json:"scenario"`
+	Topology string `json:"topology"`
+	Passed   bool   `json:"passed"`
+
+	// The changes each app made to the experiment for each stage applied.
+	Apps []app.AppDiff `json:"apps,omitempty"`
+
+	// Descriptions of each app validation error, app failure, and spec
+	// invariant violation.
+	Failures []string `json:"failures,omitempty"`
+}
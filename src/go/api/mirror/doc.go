@@ -0,0 +1,8 @@
+// Package mirror implements the in-cluster package mirror guests are pointed
+// at (via the `mirror` site setting) to install packages in air-gapped ranges.
+// The mirror is a read-only HTTP server for a directory containing apt, yum,
+// pip, and chocolatey repositories, each in the subdirectory named after its
+// repository type. Repositories are populated out of band (ie. with apt-mirror,
+// reposync, or `pip download`), and the startup app configures guests to use
+// the repositories found in the mirror automatically.
+package mirror
@@ -0,0 +1,158 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"phenix/util/plog"
+)
+
+// REPOS are the repository types the mirror can serve, each from the mirror
+// directory's subdirectory of the same name.
+var REPOS = []string{"apt", "yum", "pip", "choco"}
+
+// Repo summarizes a repository in the mirror directory.
+type Repo struct {
+	Type  string `json:"type"`
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+// Repos returns the repositories present in the given mirror directory.
+func Repos(dir string) ([]Repo, error) {
+	var repos []Repo
+
+	for _, typ := range REPOS {
+		path := filepath.Join(dir, typ)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("checking mirror %s repository: %w", typ, err)
+		}
+
+		if !info.IsDir() {
+			continue
+		}
+
+		repo := Repo{Type: typ, Path: path}
+
+		err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			repo.Files++
+			repo.Size += info.Size()
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("walking mirror %s repository: %w", typ, err)
+		}
+
+		repos = append(repos, repo)
+	}
+
+	return repos, nil
+}
+
+// Serve serves the repositories in the given mirror directory over HTTP at the
+// given address until the given context is canceled. Only GET and HEAD
+// requests for the known repository types are served, and hidden files (ie.
+// partial downloads left by mirroring tools) are never served.
+func Serve(ctx context.Context, dir, addr string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("checking mirror directory: %w", err)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           Handler(dir),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		server.Shutdown(shutdown)
+	}()
+
+	plog.Info("serving package mirror", "dir", dir, "addr", addr)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving package mirror: %w", err)
+	}
+
+	return nil
+}
+
+// Handler returns the HTTP handler used to serve the repositories in the given
+// mirror directory.
+func Handler(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !servable(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+
+		plog.Debug("serving package mirror file", "path", r.URL.Path, "client", r.RemoteAddr)
+
+		files.ServeHTTP(w, r)
+	})
+}
+
+// servable returns true if the given request path is within one of the known
+// repository types and doesn't include any hidden files or directories.
+func servable(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if !known(parts[0]) {
+		return false
+	}
+
+	for _, p := range parts {
+		if strings.HasPrefix(p, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func known(typ string) bool {
+	for _, r := range REPOS {
+		if r == typ {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package mirror
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "pip", "simple", "requests"), 0755)
+	os.WriteFile(filepath.Join(dir, "pip", "simple", "requests", "requests-2.31.0.tar.gz"), []byte("pkg"), 0644)
+	os.WriteFile(filepath.Join(dir, "pip", "simple", "requests", ".partial"), []byte("tmp"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("nope"), 0644)
+
+	handler := Handler(dir)
+
+	cases := map[string]int{
+		"/pip/simple/requests/requests-2.31.0.tar.gz": http.StatusOK,
+		"/pip/simple/requests/.partial":               http.StatusNotFound,
+		"/secret.txt":                                 http.StatusNotFound,
+		"/apt/":                                       http.StatusNotFound,
+	}
+
+	for path, expected := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != expected {
+			t.Logf("expected status %d for %s, got %d", expected, path, rec.Code)
+			t.FailNow()
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/pip/simple/requests/requests-2.31.0.tar.gz", nil))
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Logf("expected PUT to not be allowed, got %d", rec.Code)
+		t.FailNow()
+	}
+
+	repos, err := Repos(dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(repos) != 1 || repos[0].Type != "pip" || repos[0].Files != 2 {
+		t.Logf("expected only pip repo with 2 files, got %+v", repos)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,6 @@
+// Implementation of the phenix experiment README API. Each experiment can have
+// a markdown document of operator notes stored with it, which can reference
+// live experiment values (assigned IPs, VLANs, credentials, and console links)
+// that are substituted when the document is rendered, making it suitable for
+// generating exercise handouts.
+package readme
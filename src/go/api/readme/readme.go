@@ -0,0 +1,222 @@
+package readme
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/mm"
+)
+
+// ReadmeAnnotation is the experiment annotation the README markdown is stored
+// in.
+const ReadmeAnnotation = "phenix/readme"
+
+// CredentialAnnotationPrefix is the prefix of experiment annotations that can
+// be referenced as credentials in the README (ie. an annotation of
+// `phenix.credentials/domain-admin` is referenced as `{{ credential
+// "domain-admin" }}`). This allows credentials to be managed separately from
+// the README itself.
+const CredentialAnnotationPrefix = "phenix.credentials/"
+
+var ErrNoReadme = errors.New("no README for experiment")
+
+// Data is the data available to README templates, in addition to the README
+// template functions.
+type Data struct {
+	Experiment string
+	Running    bool
+	StartTime  string
+	VMs        []mm.VM
+}
+
+type renderOptions struct {
+	baseURL string
+}
+
+type RenderOption func(*renderOptions)
+
+// RenderWithBaseURL sets the base URL of the phenix UI used when generating
+// console links. If not set, console links are relative to the phenix UI.
+func RenderWithBaseURL(u string) RenderOption {
+	return func(o *renderOptions) {
+		o.baseURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+// Get returns the raw README markdown for the given experiment.
+func Get(name string) (string, error) {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	md, ok := exp.Metadata.Annotations[ReadmeAnnotation]
+	if !ok {
+		return "", ErrNoReadme
+	}
+
+	return md, nil
+}
+
+// Set stores the given README markdown with the given experiment, replacing
+// any existing README. The markdown is checked for valid substitutions before
+// being stored. An empty README removes the experiment's README.
+func Set(name, md string) error {
+	// Template functions aren't called when parsing, so the experiment and VMs
+	// aren't needed here.
+	if _, err := parse(md, nil, nil, renderOptions{}); err != nil {
+		return fmt.Errorf("parsing README: %w", err)
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if exp.Metadata.Annotations == nil {
+		exp.Metadata.Annotations = make(map[string]string)
+	}
+
+	if md == "" {
+		delete(exp.Metadata.Annotations, ReadmeAnnotation)
+	} else {
+		exp.Metadata.Annotations[ReadmeAnnotation] = md
+	}
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("saving README for experiment %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Render returns the README markdown for the given experiment with all live
+// values substituted. The following functions are available in the README, in
+// addition to the fields of the `Data` struct:
+//
+//	-- `{{ ip "<vm>" "<interface name or VLAN>" }}`: the IP assigned to a VM
+//	   interface, as reported by minimega for running experiments.
+//	-- `{{ vlan "<VLAN alias>" }}`: the VLAN ID assigned to a VLAN alias.
+//	-- `{{ console "<vm>" }}`: a link to the VNC console for a VM.
+//	-- `{{ credential "<name>" }}`: the value of the experiment's
+//	   `phenix.credentials/<name>` annotation.
+func Render(name string, opts ...RenderOption) (string, error) {
+	var o renderOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	md, ok := exp.Metadata.Annotations[ReadmeAnnotation]
+	if !ok {
+		return "", ErrNoReadme
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		return "", fmt.Errorf("getting VMs for experiment %s: %w", name, err)
+	}
+
+	tmpl, err := parse(md, exp, vms, o)
+	if err != nil {
+		return "", fmt.Errorf("parsing README: %w", err)
+	}
+
+	data := Data{
+		Experiment: name,
+		Running:    exp.Running(),
+		StartTime:  exp.Status.StartTime(),
+		VMs:        vms,
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering README: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func parse(md string, exp *types.Experiment, vms []mm.VM, o renderOptions) (*template.Template, error) {
+	return template.New("README").Funcs(funcs(exp, vms, o)).Parse(md)
+}
+
+func funcs(exp *types.Experiment, vms []mm.VM, o renderOptions) template.FuncMap {
+	find := func(name string) (mm.VM, bool) {
+		for _, v := range vms {
+			if v.Name == name {
+				return v, true
+			}
+		}
+
+		return mm.VM{}, false
+	}
+
+	return template.FuncMap{
+		"ip": func(host, iface string) (string, error) {
+			v, ok := find(host)
+			if !ok {
+				return "", fmt.Errorf("VM %s not in experiment", host)
+			}
+
+			node := exp.Spec.Topology().FindNodeByName(host)
+			if node == nil {
+				return "", fmt.Errorf("VM %s not in experiment", host)
+			}
+
+			for idx, i := range node.Network().Interfaces() {
+				if i.Name() != iface && i.VLAN() != iface {
+					continue
+				}
+
+				if idx < len(v.IPv4) && v.IPv4[idx] != "" && v.IPv4[idx] != "n/a" {
+					return v.IPv4[idx], nil
+				}
+
+				return "n/a", nil
+			}
+
+			return "", fmt.Errorf("interface %s not found for VM %s", iface, host)
+		},
+		"vlan": func(alias string) string {
+			if id, ok := exp.Status.VLANs()[alias]; ok {
+				return fmt.Sprint(id)
+			}
+
+			if id, ok := exp.Spec.VLANs().Aliases()[alias]; ok {
+				return fmt.Sprint(id)
+			}
+
+			return "n/a"
+		},
+		"console": func(host string) (string, error) {
+			if _, ok := find(host); !ok {
+				return "", fmt.Errorf("VM %s not in experiment", host)
+			}
+
+			path := fmt.Sprintf("/api/v1/experiments/%s/vms/%s/vnc", url.PathEscape(exp.Metadata.Name), url.PathEscape(host))
+
+			return o.baseURL + path, nil
+		},
+		"credential": func(name string) string {
+			if cred, ok := exp.Metadata.Annotations[CredentialAnnotationPrefix+name]; ok {
+				return cred
+			}
+
+			return fmt.Sprintf("(credential %s not set)", name)
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package readme
+
+import (
+	"bytes"
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	"phenix/util/mm"
+)
+
+func TestRender(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF: &v1.TopologySpec{
+			NodesF: []*v1.Node{
+				{
+					GeneralF: &v1.General{HostnameF: "dc"},
+					NetworkF: &v1.Network{
+						InterfacesF: []*v1.Interface{
+							{NameF: "mgmt", VLANF: "MGMT"},
+							{NameF: "eth0", VLANF: "EXP"},
+						},
+					},
+				},
+			},
+		},
+		VLANsF: &v1.VLANSpec{AliasesF: map[string]int{"EXP": 101}},
+	}
+
+	exp := &types.Experiment{
+		Metadata: store.ConfigMetadata{
+			Name:        "test",
+			Annotations: map[string]string{CredentialAnnotationPrefix + "admin": "admin:P@ssw0rd"},
+		},
+		Spec:   spec,
+		Status: new(v1.ExperimentStatus),
+	}
+
+	vms := []mm.VM{{Name: "dc", IPv4: []string{"n/a", "10.0.0.1"}}}
+
+	md := `{{ ip "dc" "eth0" }} {{ ip "dc" "MGMT" }} {{ vlan "EXP" }} {{ vlan "FOO" }} {{ console "dc" }} {{ credential "admin" }} {{ credential "user" }}`
+
+	tmpl, err := parse(md, exp, vms, renderOptions{baseURL: "https://phenix.local"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, Data{Experiment: "test"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := `10.0.0.1 n/a 101 n/a https://phenix.local/api/v1/experiments/test/vms/dc/vnc admin:P@ssw0rd (credential user not set)`
+
+	if buf.String() != expected {
+		t.Logf("expected %q, got %q", expected, buf.String())
+		t.FailNow()
+	}
+
+	tmpl, err = parse(`{{ ip "foo" "eth0" }}`, exp, vms, renderOptions{})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := tmpl.Execute(&buf, Data{}); err == nil {
+		t.Log("expected error for VM not in experiment")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,289 @@
+// Package renumber renumbers the IPv4 addresses used by topology, scenario,
+// and experiment configs from one address plan to another, so a topology can
+// be moved to a different supernet without a manual find and replace.
+package renumber
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"phenix/api/config"
+	"phenix/store"
+
+	"github.com/hashicorp/go-multierror"
+	"inet.af/netaddr"
+)
+
+// Addresses can include a prefix length (ie. `10.1.0.0/24`).
+var addrRegex = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b`)
+
+// Mapping renumbers addresses in the From network to the same host offset in
+// the To network.
+type Mapping struct {
+	From netaddr.IPPrefix
+	To   netaddr.IPPrefix
+}
+
+func (this Mapping) String() string {
+	return this.From.String() + "=" + this.To.String()
+}
+
+// Plan is a set of networks to renumber.
+type Plan []Mapping
+
+// ParsePlan parses the given mappings, each of the form `<from>=<to>` (ie.
+// `10.0.0.0/16=172.16.0.0/16`). Each network being renumbered must be at least
+// as large as the network it's being renumbered from, and neither the networks
+// being renumbered nor the networks being renumbered to can overlap.
+func ParsePlan(mappings ...string) (Plan, error) {
+	var plan Plan
+
+	for _, mapping := range mappings {
+		from, to, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %s (expected <from>=<to>)", mapping)
+		}
+
+		var (
+			m   Mapping
+			err error
+		)
+
+		if m.From, err = parseNetwork(from); err != nil {
+			return nil, fmt.Errorf("invalid mapping %s: %w", mapping, err)
+		}
+
+		if m.To, err = parseNetwork(to); err != nil {
+			return nil, fmt.Errorf("invalid mapping %s: %w", mapping, err)
+		}
+
+		if m.To.Bits() > m.From.Bits() {
+			return nil, fmt.Errorf("invalid mapping %s: network %s is smaller than network %s", mapping, m.To, m.From)
+		}
+
+		for _, other := range plan {
+			if m.From.Overlaps(other.From) {
+				return nil, fmt.Errorf("invalid mapping %s: network %s overlaps network %s from mapping %s", mapping, m.From, other.From, other)
+			}
+
+			if m.To.Overlaps(other.To) {
+				return nil, fmt.Errorf("invalid mapping %s: network %s overlaps network %s from mapping %s", mapping, m.To, other.To, other)
+			}
+		}
+
+		plan = append(plan, m)
+	}
+
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("no mappings provided")
+	}
+
+	return plan, nil
+}
+
+func parseNetwork(s string) (netaddr.IPPrefix, error) {
+	prefix, err := netaddr.ParseIPPrefix(strings.TrimSpace(s))
+	if err != nil {
+		return netaddr.IPPrefix{}, fmt.Errorf("parsing network %s: %w", s, err)
+	}
+
+	if !prefix.IP().Is4() {
+		return netaddr.IPPrefix{}, fmt.Errorf("network %s is not an IPv4 network", s)
+	}
+
+	return prefix.Masked(), nil
+}
+
+// renumberIP returns the given address renumbered per the plan, or false if
+// the address isn't in any of the networks being renumbered.
+func (this Plan) renumberIP(ip netaddr.IP) (netaddr.IP, bool) {
+	for _, m := range this {
+		if !m.From.Contains(ip) {
+			continue
+		}
+
+		var (
+			from = m.From.IP().As4()
+			to   = m.To.IP().As4()
+			addr = ip.As4()
+			host = binary.BigEndian.Uint32(addr[:]) - binary.BigEndian.Uint32(from[:])
+		)
+
+		binary.BigEndian.PutUint32(addr[:], binary.BigEndian.Uint32(to[:])+host)
+
+		return netaddr.IPv4(addr[0], addr[1], addr[2], addr[3]), true
+	}
+
+	return ip, false
+}
+
+// Change is a single value renumbered in a config. Paths are dot-separated,
+// with list items identified by name (or hostname) when they have one and by
+// index otherwise, ie. `nodes[0].network.interfaces[IF0].address`.
+type Change struct {
+	Config string `json:"config"`
+	Path   string `json:"path"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// Spec renumbers every address in the given config spec (interfaces,
+// gateways, routes, OSPF networks, ACL rules, app metadata, etc.) per the given
+// plan, updating the spec in place and returning the changes made. The given
+// name is only used to label changes. An error is returned if the spec uses a
+// network that spans a network being renumbered (it can't be renumbered
+// consistently), or if it already uses addresses in a network being renumbered
+// to that aren't being renumbered themselves (they'd collide).
+func Spec(name string, spec map[string]any, plan Plan) ([]Change, error) {
+	w := walker{config: name, plan: plan}
+
+	for _, key := range sortedKeys(spec) {
+		spec[key] = w.walk(key, spec[key])
+	}
+
+	return w.changes, w.errs
+}
+
+type walker struct {
+	config string
+	plan   Plan
+
+	changes []Change
+	errs    error
+}
+
+func (this *walker) walk(path string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			v[key] = this.walk(path+"."+key, v[key])
+		}
+	case []any:
+		for i, value := range v {
+			key := fmt.Sprintf("%s[%d]", path, i)
+
+			if m, ok := value.(map[string]any); ok {
+				for _, field := range []string{"name", "hostname"} {
+					if name, ok := m[field].(string); ok && name != "" {
+						key = fmt.Sprintf("%s[%s]", path, name)
+						break
+					}
+				}
+			}
+
+			v[i] = this.walk(key, value)
+		}
+	case string:
+		renumbered := addrRegex.ReplaceAllStringFunc(v, func(match string) string {
+			return this.renumber(path, match)
+		})
+
+		if renumbered != v {
+			this.changes = append(this.changes, Change{Config: this.config, Path: path, Old: v, New: renumbered})
+		}
+
+		return renumbered
+	}
+
+	return v
+}
+
+// renumber returns the given address (optionally with a prefix length) found
+// at the given path renumbered per the plan. Matches that aren't valid
+// addresses (ie. version strings) are returned as is.
+func (this *walker) renumber(path, match string) string {
+	addr, bits, hasBits := strings.Cut(match, "/")
+
+	ip, err := netaddr.ParseIP(addr)
+	if err != nil {
+		return match
+	}
+
+	if hasBits {
+		prefix, err := netaddr.ParseIPPrefix(match)
+		if err != nil {
+			return match
+		}
+
+		for _, m := range this.plan {
+			if prefix.Bits() < m.From.Bits() && prefix.Overlaps(m.From) {
+				this.errs = multierror.Append(this.errs, fmt.Errorf("%s: network %s at %s spans network %s being renumbered", this.config, match, path, m.From))
+				return match
+			}
+		}
+	}
+
+	renumbered, ok := this.plan.renumberIP(ip)
+	if !ok {
+		for _, m := range this.plan {
+			if m.To.Contains(ip) {
+				this.errs = multierror.Append(this.errs, fmt.Errorf("%s: address %s at %s is already in network %s being renumbered to", this.config, match, path, m.To))
+			}
+		}
+
+		return match
+	}
+
+	if hasBits {
+		return renumbered.String() + "/" + bits
+	}
+
+	return renumbered.String()
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Configs renumbers the given configs (ie. `topology/foo`) in the store per
+// the given plan, returning the changes made to each. Configs are only updated
+// in the store if apply is true and none of the configs have errors, so the
+// changes can be previewed first.
+func Configs(plan Plan, apply bool, names ...string) ([]Change, error) {
+	var (
+		configs []*store.Config
+		changes []Change
+		errs    error
+	)
+
+	for _, name := range names {
+		c, err := config.Get(name, false)
+		if err != nil {
+			return nil, fmt.Errorf("getting config %s: %w", name, err)
+		}
+
+		changed, err := Spec(c.FullName(), c.Spec, plan)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+
+		changes = append(changes, changed...)
+
+		if len(changed) > 0 {
+			configs = append(configs, c)
+		}
+	}
+
+	if errs != nil || !apply {
+		return changes, errs
+	}
+
+	for _, c := range configs {
+		if err := config.Update(c.FullName(), c); err != nil {
+			return changes, fmt.Errorf("updating config %s: %w", c.FullName(), err)
+		}
+	}
+
+	return changes, nil
+}
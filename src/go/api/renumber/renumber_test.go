@@ -0,0 +1,103 @@
+package renumber
+
+import (
+	"testing"
+)
+
+func TestSpec(t *testing.T) {
+	plan, err := ParsePlan("10.0.0.0/16=172.16.0.0/12", "192.168.1.0/24=192.168.100.0/24")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	spec := map[string]any{
+		"nodes": []any{
+			map[string]any{
+				"general": map[string]any{"hostname": "rtr"},
+				"network": map[string]any{
+					"interfaces": []any{
+						map[string]any{"name": "IF0", "address": "10.0.3.7", "mask": 24, "gateway": "10.0.3.254"},
+						map[string]any{"name": "IF1", "address": "192.168.1.1", "mask": 24},
+					},
+					"routes": []any{
+						map[string]any{"destination": "10.0.5.0/24", "next": "10.0.3.1", "cost": 1},
+					},
+				},
+			},
+		},
+		"apps": []any{
+			map[string]any{"name": "foo", "metadata": map[string]any{"servers": "10.0.3.7:80,8.8.8.8", "version": "1.2.3"}},
+		},
+	}
+
+	changes, err := Spec("topology/foo", spec, plan)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string]string{
+		"nodes[0].network.interfaces[IF0].address": "172.16.3.7",
+		"nodes[0].network.interfaces[IF0].gateway": "172.16.3.254",
+		"nodes[0].network.interfaces[IF1].address": "192.168.100.1",
+		"nodes[0].network.routes[0].destination":   "172.16.5.0/24",
+		"nodes[0].network.routes[0].next":          "172.16.3.1",
+		"apps[foo].metadata.servers":               "172.16.3.7:80,8.8.8.8",
+	}
+
+	if len(changes) != len(expected) {
+		t.Logf("expected %d changes, got %d: %+v", len(expected), len(changes), changes)
+		t.FailNow()
+	}
+
+	for _, c := range changes {
+		if expected[c.Path] != c.New {
+			t.Logf("expected %s to be renumbered to %s, got %s", c.Path, expected[c.Path], c.New)
+			t.FailNow()
+		}
+	}
+
+	iface := spec["nodes"].([]any)[0].(map[string]any)["network"].(map[string]any)["interfaces"].([]any)[0].(map[string]any)
+
+	if iface["address"] != "172.16.3.7" {
+		t.Logf("expected spec to be updated in place, got address %v", iface["address"])
+		t.FailNow()
+	}
+}
+
+func TestSpecConflicts(t *testing.T) {
+	plan, err := ParsePlan("10.1.0.0/16=10.2.0.0/16")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	specs := []map[string]any{
+		{"routes": []any{map[string]any{"destination": "10.0.0.0/8"}}}, // spans network being renumbered
+		{"address": "10.2.0.1"}, // collides with renumbered addresses
+	}
+
+	for _, spec := range specs {
+		if _, err := Spec("topology/foo", spec, plan); err == nil {
+			t.Logf("expected error renumbering %v", spec)
+			t.FailNow()
+		}
+	}
+}
+
+func TestParsePlan(t *testing.T) {
+	invalid := [][]string{
+		{"10.0.0.0/16"},
+		{"10.0.0.0/16=172.16.0.0/24"},
+		{"10.0.0.0/16=172.16.0.0/16", "10.0.1.0/24=192.168.0.0/24"},
+		{"10.0.0.0/16=172.16.0.0/16", "10.1.0.0/16=172.16.0.0/12"},
+	}
+
+	for _, mappings := range invalid {
+		if _, err := ParsePlan(mappings...); err == nil {
+			t.Logf("expected error parsing plan %v", mappings)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,9 @@
+// Package retention implements experiment data retention policies. Policies
+// are defined in a YAML retention policy file, with a default policy and
+// optional per-namespace (experiment) overrides, and limit how old packet
+// captures and state of health results can get, how many scorch runs are
+// kept, and how large an experiment's files directory can grow. Policies can
+// be applied on demand, or periodically by a background janitor while the UI
+// server is running, and either can be a dry run that only reports what would
+// be deleted.
+package retention
@@ -0,0 +1,335 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/api/scorch/scorchmd"
+	"phenix/types"
+	"phenix/util/file"
+	"phenix/util/journal"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	scorchRunRegex     = regexp.MustCompile(`^scorch/run-(\d+)/`)
+	scorchArchiveRegex = regexp.MustCompile(`^(?:info-)?scorch-run-(\d+)_`)
+)
+
+// Start applies the given retention policies at their configured interval
+// until the given context is canceled.
+func Start(ctx context.Context, policies Policies) {
+	interval, err := policies.interval()
+	if err != nil {
+		plog.Error("not starting retention janitor", "err", err)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			report, err := Run(ctx, policies, policies.DryRun)
+			if err != nil {
+				plog.Error("applying retention policies", "err", err)
+				continue
+			}
+
+			for _, a := range report.Actions {
+				plog.Debug("retention policy action", "exp", a.Experiment, "rule", a.Rule, "path", a.Path, "size", a.Size, "deleted", a.Deleted)
+			}
+
+			for _, e := range report.Errors {
+				plog.Error("applying retention policies", "err", e)
+			}
+
+			if len(report.Actions) > 0 {
+				plog.Info("applied retention policies", "dryRun", report.DryRun, "files", len(report.Actions), "freed", FormatSize(report.Freed()))
+			}
+		}
+	}()
+}
+
+// Run applies the given retention policies to the files of each experiment (or
+// only the given experiments, if any), returning a report of the files deleted.
+// If dryRun is true, nothing is deleted and the report lists the files that
+// would have been. Errors deleting individual files are included in the report
+// rather than returned.
+func Run(ctx context.Context, policies Policies, dryRun bool, exps ...string) (Report, error) {
+	report := Report{DryRun: dryRun, Time: time.Now().UTC()}
+
+	all, err := types.Experiments(false)
+	if err != nil {
+		return report, fmt.Errorf("getting experiments: %w", err)
+	}
+
+	for _, exp := range all {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		name := exp.Metadata.Name
+
+		if len(exps) > 0 && !slices.Contains(exps, name) {
+			continue
+		}
+
+		policy := policies.For(name)
+
+		if policy.empty() {
+			continue
+		}
+
+		files, err := file.GetExperimentFiles(name, "")
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("listing files for experiment %s: %v", name, err))
+			continue
+		}
+
+		var (
+			actions = plan(files, policy, report.Time, protected(exp))
+			deleted int
+			freed   int64
+		)
+
+		for i := range actions {
+			actions[i].Experiment = name
+
+			if dryRun {
+				continue
+			}
+
+			if err := file.DeleteFile(fmt.Sprintf("%s/files/%s", name, actions[i].Path)); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("deleting %s for experiment %s: %v", actions[i].Path, name, err))
+				continue
+			}
+
+			actions[i].Deleted = true
+
+			deleted++
+			freed += actions[i].Size
+		}
+
+		if deleted > 0 {
+			journal.Record(name, journal.CategoryLifecycle, "", "retention policy deleted %d file(s) (%s)", deleted, FormatSize(freed))
+		}
+
+		report.Actions = append(report.Actions, actions...)
+	}
+
+	return report, nil
+}
+
+// FormatSize formats the given number of bytes in megabytes.
+func FormatSize(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
+
+// protected returns a function reporting whether the file at the given path
+// (relative to the experiment's files directory) must not be deleted, either
+// because it's a packet capture still being written or it's part of the scorch
+// run currently executing.
+func protected(exp *types.Experiment) func(string) bool {
+	var (
+		captures = make(map[string]struct{})
+		running  string
+	)
+
+	if exp.Running() {
+		for _, c := range mm.GetExperimentCaptures(mm.NS(exp.Metadata.Name)) {
+			captures[filepath.Base(c.Filepath)] = struct{}{}
+		}
+	}
+
+	if exp.Status.AppRunning()["scorch"] {
+		var status scorchmd.ScorchStatus
+
+		if err := exp.Status.ParseAppStatus("scorch", &status); err == nil {
+			running = fmt.Sprintf("scorch/run-%d", status.RunID)
+		}
+	}
+
+	return func(path string) bool {
+		if _, ok := captures[filepath.Base(path)]; ok {
+			return true
+		}
+
+		return running != "" && (path == running || strings.HasPrefix(path, running+"/"))
+	}
+}
+
+// plan returns the files that should be deleted from an experiment's files
+// directory to satisfy the given policy. Scorch runs are pruned first, then
+// old captures and state of health results, then the oldest remaining files
+// until the directory is under its size limit.
+func plan(files file.Files, policy Policy, now time.Time, protect func(string) bool) []Action {
+	var (
+		actions []Action
+		deleted = make(map[string]bool)
+	)
+
+	add := func(rule Rule, path string, size int64, date string) {
+		actions = append(actions, Action{Rule: rule, Path: path, Size: size, Date: date})
+		deleted[path] = true
+	}
+
+	if policy.ScorchRuns > 0 {
+		runs := make(map[int][]file.File)
+
+		for _, f := range files {
+			if id, ok := scorchRun(f.Path); ok {
+				runs[id] = append(runs[id], f)
+			}
+		}
+
+		var ids []int
+
+		for id := range runs {
+			ids = append(ids, id)
+		}
+
+		// Most recent (highest ID) runs first.
+		sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+
+		if len(ids) > policy.ScorchRuns {
+			for _, id := range ids[policy.ScorchRuns:] {
+				var (
+					dir  = fmt.Sprintf("scorch/run-%d", id)
+					size int64
+					date string
+					dirs bool
+				)
+
+				if protect(dir) {
+					continue
+				}
+
+				for _, f := range runs[id] {
+					if strings.HasPrefix(f.Path, dir+"/") {
+						size += f.Size
+						deleted[f.Path] = true
+						dirs = true
+
+						if f.Date > date {
+							date = f.Date
+						}
+
+						continue
+					}
+
+					add(RULE_SCORCH_RUNS, f.Path, f.Size, f.Date)
+				}
+
+				if dirs {
+					add(RULE_SCORCH_RUNS, dir, size, date)
+				}
+			}
+		}
+	}
+
+	olderThan := func(f file.File, days int) bool {
+		if days <= 0 {
+			return false
+		}
+
+		t, err := time.Parse(time.RFC3339, f.Date)
+		if err != nil {
+			return false
+		}
+
+		return now.Sub(t) > time.Duration(days)*24*time.Hour
+	}
+
+	for _, f := range files {
+		if f.IsDir || deleted[f.Path] || protect(f.Path) {
+			continue
+		}
+
+		switch {
+		case slices.Contains(f.Categories, "Packet Capture") && olderThan(f, policy.CaptureMaxAgeDays):
+			add(RULE_CAPTURE_AGE, f.Path, f.Size, f.Date)
+		case f.Name == "soh.json" && olderThan(f, policy.SoHMaxAgeDays):
+			add(RULE_SOH_AGE, f.Path, f.Size, f.Date)
+		}
+	}
+
+	if policy.FilesMaxSizeMB > 0 {
+		var (
+			limit      = policy.FilesMaxSizeMB * 1024 * 1024
+			total      int64
+			candidates file.Files
+		)
+
+		for _, f := range files {
+			if f.IsDir || deleted[f.Path] {
+				continue
+			}
+
+			total += f.Size
+
+			// VM disk images (ie. snapshots and their backing images) are needed to
+			// restore VMs, so they're never deleted to free up space.
+			if ext := filepath.Ext(f.Name); ext == ".qc2" || ext == ".qcow2" || protect(f.Path) {
+				continue
+			}
+
+			candidates = append(candidates, f)
+		}
+
+		// Oldest files first.
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, _ := time.Parse(time.RFC3339, candidates[i].Date)
+			b, _ := time.Parse(time.RFC3339, candidates[j].Date)
+
+			return a.Before(b)
+		})
+
+		for _, f := range candidates {
+			if total <= limit {
+				break
+			}
+
+			add(RULE_FILES_SIZE, f.Path, f.Size, f.Date)
+			total -= f.Size
+		}
+	}
+
+	return actions
+}
+
+// scorchRun returns the ID of the scorch run the file at the given path is
+// part of, if any.
+func scorchRun(path string) (int, bool) {
+	match := scorchRunRegex.FindStringSubmatch(path)
+
+	if match == nil {
+		match = scorchArchiveRegex.FindStringSubmatch(path)
+	}
+
+	if match == nil {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,128 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"phenix/util/file"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := `
+interval: 6h
+dryRun: true
+default:
+  captureMaxAgeDays: 30
+  scorchRuns: 10
+namespaces:
+  long-range:
+    captureMaxAgeDays: 90
+    scorchRuns: -1
+    filesMaxSizeMB: 1024
+`
+
+	path := filepath.Join(dir, "valid.yml")
+	os.WriteFile(path, []byte(valid), 0644)
+
+	policies, err := Load(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !policies.DryRun {
+		t.Log("expected dry run to be enabled")
+		t.FailNow()
+	}
+
+	if p := policies.For("other"); p.CaptureMaxAgeDays != 30 || p.ScorchRuns != 10 || p.FilesMaxSizeMB != 0 {
+		t.Logf("unexpected default policy: %+v", p)
+		t.FailNow()
+	}
+
+	if p := policies.For("long-range"); p.CaptureMaxAgeDays != 90 || p.ScorchRuns != -1 || p.FilesMaxSizeMB != 1024 {
+		t.Logf("unexpected namespace policy: %+v", p)
+		t.FailNow()
+	}
+
+	path = filepath.Join(dir, "invalid.yml")
+	os.WriteFile(path, []byte("interval: sometimes"), 0644)
+
+	if _, err := Load(path); err == nil {
+		t.Log("expected error loading invalid retention policy file")
+		t.FailNow()
+	}
+}
+
+func TestPlan(t *testing.T) {
+	var (
+		now = time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+		old = now.Add(-45 * 24 * time.Hour).Format(time.RFC3339)
+		new = now.Add(-1 * 24 * time.Hour).Format(time.RFC3339)
+	)
+
+	files := file.Files{
+		{Name: "old.pcap", Path: "old.pcap", Date: old, Size: 100, Categories: []string{"Packet Capture"}},
+		{Name: "active.pcap", Path: "active.pcap", Date: old, Size: 100, Categories: []string{"Packet Capture"}},
+		{Name: "new.pcap", Path: "new.pcap", Date: new, Size: 100, Categories: []string{"Packet Capture"}},
+		{Name: "soh.json", Path: "scorch/run-2/soh/loop-0-count-0/soh.json", Date: old, Size: 10},
+		{Name: "out.txt", Path: "scorch/run-1/foo/out.txt", Date: old, Size: 50},
+		{Name: "scorch-run-1_2024-05-01T00-00-00Z.tgz", Path: "scorch-run-1_2024-05-01T00-00-00Z.tgz", Date: old, Size: 20},
+		{Name: "disk.qc2", Path: "disk.qc2", Date: old, Size: 1 << 30},
+	}
+
+	protect := func(path string) bool { return path == "active.pcap" }
+
+	policy := Policy{CaptureMaxAgeDays: 30, ScorchRuns: 1, SoHMaxAgeDays: 30}
+
+	actions := plan(files, policy, now, protect)
+
+	expected := map[string]Rule{
+		"old.pcap": RULE_CAPTURE_AGE,
+		"scorch/run-2/soh/loop-0-count-0/soh.json": RULE_SOH_AGE,
+		"scorch/run-1":                          RULE_SCORCH_RUNS,
+		"scorch-run-1_2024-05-01T00-00-00Z.tgz": RULE_SCORCH_RUNS,
+	}
+
+	if len(actions) != len(expected) {
+		t.Logf("expected %d actions, got %+v", len(expected), actions)
+		t.FailNow()
+	}
+
+	for _, a := range actions {
+		if rule, ok := expected[a.Path]; !ok || rule != a.Rule {
+			t.Logf("unexpected action: %+v", a)
+			t.FailNow()
+		}
+
+		if a.Path == "scorch/run-1" && a.Size != 50 {
+			t.Logf("expected scorch run size 50, got %d", a.Size)
+			t.FailNow()
+		}
+	}
+
+	// Nothing is deleted while the files directory is under its size limit.
+	actions = plan(files[1:3], Policy{FilesMaxSizeMB: 1}, now, protect)
+
+	if len(actions) != 0 {
+		t.Logf("expected no actions under size limit, got %+v", actions)
+		t.FailNow()
+	}
+
+	big := file.Files{
+		{Name: "a.log", Path: "a.log", Date: old, Size: 1 << 20},
+		{Name: "b.log", Path: "b.log", Date: new, Size: 1 << 20},
+		{Name: "disk.qc2", Path: "disk.qc2", Date: old, Size: 1 << 20},
+	}
+
+	actions = plan(big, Policy{FilesMaxSizeMB: 2}, now, protect)
+
+	if len(actions) != 1 || actions[0].Path != "a.log" || actions[0].Rule != RULE_FILES_SIZE {
+		t.Logf("expected oldest file to be deleted, got %+v", actions)
+		t.FailNow()
+	}
+}
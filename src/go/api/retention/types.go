@@ -0,0 +1,161 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultInterval is how often the janitor applies retention policies if the
+// retention policy file doesn't specify an interval.
+const DefaultInterval = time.Hour
+
+// Policies are the retention policies defined in a retention policy file.
+type Policies struct {
+	// How often the janitor applies the policies (defaults to 1h).
+	Interval string `yaml:"interval" json:"interval"`
+
+	// If true, the janitor only reports what it would delete.
+	DryRun bool `yaml:"dryRun" json:"dryRun"`
+
+	// Default policy applied to every experiment namespace.
+	Default Policy `yaml:"default" json:"default"`
+
+	// Namespace specific policies, keyed by experiment name. Fields that are
+	// zero fall back to the default policy, and fields that are negative disable
+	// the rule for the namespace.
+	Namespaces map[string]Policy `yaml:"namespaces" json:"namespaces"`
+}
+
+// Policy limits how much data is kept in an experiment's files directory. A
+// zero (or negative) value disables the rule.
+type Policy struct {
+	// Delete packet captures older than this many days.
+	CaptureMaxAgeDays int `yaml:"captureMaxAgeDays" json:"captureMaxAgeDays"`
+
+	// Delete the oldest files until the files directory is smaller than this
+	// many megabytes. VM disk images are never deleted by this rule.
+	FilesMaxSizeMB int64 `yaml:"filesMaxSizeMB" json:"filesMaxSizeMB"`
+
+	// Only keep this many of the most recent scorch runs.
+	ScorchRuns int `yaml:"scorchRuns" json:"scorchRuns"`
+
+	// Delete state of health results older than this many days.
+	SoHMaxAgeDays int `yaml:"sohMaxAgeDays" json:"sohMaxAgeDays"`
+}
+
+// Rule identifies which part of a retention policy caused a file to be
+// deleted.
+type Rule string
+
+const (
+	RULE_CAPTURE_AGE Rule = "capture-age"
+	RULE_FILES_SIZE  Rule = "files-size"
+	RULE_SCORCH_RUNS Rule = "scorch-runs"
+	RULE_SOH_AGE     Rule = "soh-age"
+)
+
+// Report is the outcome of applying retention policies.
+type Report struct {
+	DryRun  bool      `json:"dryRun"`
+	Time    time.Time `json:"time"`
+	Actions []Action  `json:"actions"`
+	Errors  []string  `json:"errors,omitempty"`
+}
+
+// Action is a single file (or directory) deleted, or that would be deleted
+// for a dry run, by a retention policy.
+type Action struct {
+	Experiment string `json:"experiment"`
+	Rule       Rule   `json:"rule"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	Date       string `json:"date"`
+	Deleted    bool   `json:"deleted"`
+}
+
+// Freed returns the total size of the files deleted (or that would be deleted
+// for a dry run).
+func (this Report) Freed() int64 {
+	var freed int64
+
+	for _, a := range this.Actions {
+		if a.Deleted || this.DryRun {
+			freed += a.Size
+		}
+	}
+
+	return freed
+}
+
+// Load reads the retention policies defined in the given YAML file.
+func Load(path string) (Policies, error) {
+	var p Policies
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("reading retention policy file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(body, &p); err != nil {
+		return p, fmt.Errorf("parsing retention policy file %s: %w", path, err)
+	}
+
+	if _, err := p.interval(); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// For returns the retention policy for the given namespace, which is the
+// default policy with any namespace specific settings applied.
+func (this Policies) For(namespace string) Policy {
+	policy := this.Default
+
+	ns, ok := this.Namespaces[namespace]
+	if !ok {
+		return policy
+	}
+
+	if ns.CaptureMaxAgeDays != 0 {
+		policy.CaptureMaxAgeDays = ns.CaptureMaxAgeDays
+	}
+
+	if ns.FilesMaxSizeMB != 0 {
+		policy.FilesMaxSizeMB = ns.FilesMaxSizeMB
+	}
+
+	if ns.ScorchRuns != 0 {
+		policy.ScorchRuns = ns.ScorchRuns
+	}
+
+	if ns.SoHMaxAgeDays != 0 {
+		policy.SoHMaxAgeDays = ns.SoHMaxAgeDays
+	}
+
+	return policy
+}
+
+func (this Policies) interval() (time.Duration, error) {
+	if this.Interval == "" {
+		return DefaultInterval, nil
+	}
+
+	interval, err := time.ParseDuration(this.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid retention interval %s: %w", this.Interval, err)
+	}
+
+	if interval <= 0 {
+		return 0, fmt.Errorf("retention interval must be positive")
+	}
+
+	return interval, nil
+}
+
+func (this Policy) empty() bool {
+	return this.CaptureMaxAgeDays <= 0 && this.FilesMaxSizeMB <= 0 && this.ScorchRuns <= 0 && this.SoHMaxAgeDays <= 0
+}
@@ -23,6 +23,7 @@ import (
 	"phenix/util/mm/mmcli"
 	"phenix/util/plog"
 	"phenix/util/shell"
+	"phenix/util/subsystem"
 	"phenix/version"
 	"phenix/web/scorch"
 
@@ -33,6 +34,7 @@ import (
 
 func init() {
 	app.RegisterUserApp("scorch", func() app.App { return newScorch() })
+	subsystem.Register(subsystem.SCORCH)
 }
 
 type Scorch struct {
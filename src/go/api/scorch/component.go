@@ -43,16 +43,13 @@ type Component interface {
 	Cleanup(context.Context) error
 }
 
-var components map[string]Component
-
-func init() {
-	components = map[string]Component{
-		"break":      new(Break),
-		"pause":      new(Pause),
-		"soh":        new(SOH),
-		"tap":        new(Tap),
-		"user-shell": new(UserComponent),
-	}
+// Components belonging to optional subsystems (ie. soh) add themselves from
+// code excluded by the subsystem's build tag.
+var components = map[string]Component{
+	"break":      new(Break),
+	"pause":      new(Pause),
+	"tap":        new(Tap),
+	"user-shell": new(UserComponent),
 }
 
 func GetComponent(name string) Component {
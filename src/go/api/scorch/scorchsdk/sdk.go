@@ -0,0 +1,195 @@
+// Package scorchsdk provides helpers for writing external SCORCH user
+// components in Go. User components are executables named
+// `phenix-scorch-component-<type>` that phenix calls with the stage, component
+// name, run, loop and count as arguments, the experiment as JSON on STDIN, and
+// the phenix directories in the environment.
+//
+//	func main() {
+//		payload, err := scorchsdk.ParsePayload(os.Args[1:], os.Stdin)
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//
+//		var md struct {
+//			Hosts []string `mapstructure:"hosts"`
+//		}
+//
+//		if err := payload.DecodeMetadata(&md); err != nil {
+//			log.Fatal(err)
+//		}
+//
+//		payload.WriteArtifact("hosts.txt", []byte(strings.Join(md.Hosts, "\n")))
+//	}
+package scorchsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"phenix/api/scorch/scorchmd"
+	"phenix/store"
+	"phenix/types"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Environment variables set by phenix when running user components.
+const (
+	EnvPhenixDir = "PHENIX_DIR"
+	EnvFilesDir  = "PHENIX_FILES_DIR"
+	EnvDryRun    = "PHENIX_DRYRUN"
+	EnvStartTime = "PHENIX_SCORCH_STARTTIME"
+)
+
+// Payload is everything phenix passes to a user component when running it.
+type Payload struct {
+	Stage string
+	Name  string
+	Run   int
+	Loop  int
+	Count int
+
+	Exp *types.Experiment
+
+	PhenixDir string
+	FilesDir  string
+	StartTime string
+	DryRun    bool
+}
+
+// ParsePayload parses the payload passed to a user component from its
+// arguments (excluding the executable name), STDIN and environment.
+func ParsePayload(args []string, stdin io.Reader) (*Payload, error) {
+	if len(args) != 5 {
+		return nil, fmt.Errorf("expected 5 arguments (stage, name, run, loop, count), got %d", len(args))
+	}
+
+	p := &Payload{
+		Stage:     args[0],
+		Name:      args[1],
+		PhenixDir: os.Getenv(EnvPhenixDir),
+		FilesDir:  os.Getenv(EnvFilesDir),
+		StartTime: os.Getenv(EnvStartTime),
+	}
+
+	for i, v := range []*int{&p.Run, &p.Loop, &p.Count} {
+		var err error
+
+		if *v, err = strconv.Atoi(args[i+2]); err != nil {
+			return nil, fmt.Errorf("parsing argument %d: %w", i+3, err)
+		}
+	}
+
+	p.DryRun, _ = strconv.ParseBool(os.Getenv(EnvDryRun))
+
+	body, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("reading experiment from STDIN: %w", err)
+	}
+
+	p.Exp = types.NewExperiment(store.ConfigMetadata{})
+
+	if err := json.Unmarshal(body, p.Exp); err != nil {
+		return nil, fmt.Errorf("unmarshaling experiment from JSON: %w", err)
+	}
+
+	if p.FilesDir == "" {
+		p.FilesDir = p.Exp.FilesDir()
+	}
+
+	return p, nil
+}
+
+// Metadata returns the metadata configured for the component in the
+// experiment's scorch app.
+func (this Payload) Metadata() (scorchmd.ComponentMetadata, error) {
+	md, err := scorchmd.DecodeMetadata(this.Exp)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := md.ComponentSpecs()[this.Name]
+	if !ok {
+		return nil, fmt.Errorf("component %s not found in scorch metadata", this.Name)
+	}
+
+	return spec.Metadata, nil
+}
+
+// DecodeMetadata decodes the metadata configured for the component into the
+// given value using mapstructure.
+func (this Payload) DecodeMetadata(v any) error {
+	md, err := this.Metadata()
+	if err != nil {
+		return err
+	}
+
+	if err := mapstructure.Decode(md, v); err != nil {
+		return fmt.Errorf("decoding component metadata: %w", err)
+	}
+
+	return nil
+}
+
+// ArtifactDir returns the directory artifacts for the current run, loop and
+// count of the component should be written to. It's included in the scorch run
+// archive when the run completes.
+func (this Payload) ArtifactDir() string {
+	return ArtifactDir(this.FilesDir, this.Name, this.Run, this.Loop, this.Count)
+}
+
+// WriteArtifact writes the given data to the named artifact, returning the
+// path to the artifact.
+func (this Payload) WriteArtifact(name string, data []byte) (string, error) {
+	path := filepath.Join(this.ArtifactDir(), name)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("creating artifact directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("writing artifact %s: %w", name, err)
+	}
+
+	return path, nil
+}
+
+// RegisterArtifact copies the file at the given path into the artifact
+// directory, returning the path to the artifact.
+func (this Payload) RegisterArtifact(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening artifact %s: %w", path, err)
+	}
+
+	defer src.Close()
+
+	dst := filepath.Join(this.ArtifactDir(), filepath.Base(path))
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("creating artifact directory: %w", err)
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return "", fmt.Errorf("creating artifact %s: %w", dst, err)
+	}
+
+	defer f.Close()
+
+	if _, err := io.Copy(f, src); err != nil {
+		return "", fmt.Errorf("copying artifact %s: %w", path, err)
+	}
+
+	return dst, nil
+}
+
+// ArtifactDir returns the artifact directory for the given component run, loop
+// and count in the given experiment files directory.
+func ArtifactDir(filesDir, name string, run, loop, count int) string {
+	return filepath.Join(filesDir, "scorch", fmt.Sprintf("run-%d", run), name, fmt.Sprintf("loop-%d-count-%d", loop, count))
+}
@@ -0,0 +1,88 @@
+package scorchsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestParsePayload(t *testing.T) {
+	filesDir := t.TempDir()
+
+	t.Setenv(EnvFilesDir, filesDir)
+	t.Setenv(EnvDryRun, "true")
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "scorch",
+				MetadataF: map[string]any{
+					"components": []any{
+						map[string]any{"name": "hosts", "type": "hosts", "metadata": map[string]any{"hosts": []any{"a", "b"}}},
+					},
+				},
+			},
+		},
+	}
+
+	exp := &types.Experiment{
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec:     &v1.ExperimentSpec{ExperimentNameF: "test", ScenarioF: scenario},
+		Status:   &v1.ExperimentStatus{},
+	}
+
+	body, _ := json.Marshal(exp)
+
+	payload, err := ParsePayload([]string{"start", "hosts", "1", "2", "3"}, bytes.NewReader(body))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if payload.Stage != "start" || payload.Run != 1 || payload.Loop != 2 || payload.Count != 3 || !payload.DryRun {
+		t.Logf("unexpected payload: %+v", payload)
+		t.FailNow()
+	}
+
+	var md struct {
+		Hosts []string `mapstructure:"hosts"`
+	}
+
+	if err := payload.DecodeMetadata(&md); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(md.Hosts) != 2 || md.Hosts[0] != "a" {
+		t.Logf("unexpected component metadata: %+v", md)
+		t.FailNow()
+	}
+
+	path, err := payload.WriteArtifact("hosts.txt", []byte("a\nb"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if expected := filepath.Join(filesDir, "scorch", "run-1", "hosts", "loop-2-count-3", "hosts.txt"); path != expected {
+		t.Logf("expected artifact at %s, got %s", expected, path)
+		t.FailNow()
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := ParsePayload([]string{"start"}, bytes.NewReader(body)); err == nil {
+		t.Log("expected error for missing arguments")
+		t.FailNow()
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nosoh
+
 package scorch
 
 import (
@@ -17,6 +19,10 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+func init() {
+	components["soh"] = new(SOH)
+}
+
 type SOHMetadata struct {
 	Checks      []string `mapstructure:"checks"`
 	C2Timeout   string   `mapstructure:"c2Timeout"`
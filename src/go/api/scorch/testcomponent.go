@@ -0,0 +1,200 @@
+package scorch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"phenix/api/scorch/scorchsdk"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/common"
+	"phenix/util/shell"
+)
+
+// RunContext is the context a user component was run with. It's recorded in
+// the component's artifact directory (as `context-<stage>.json`) each time the
+// component is run so it can be run again with TestComponent without a live
+// experiment.
+type RunContext struct {
+	Stage     Action            `json:"stage"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Run       int               `json:"run"`
+	Loop      int               `json:"loop"`
+	Count     int               `json:"count"`
+	StartTime string            `json:"startTime"`
+	Exp       *types.Experiment `json:"experiment"`
+}
+
+// LoadRunContext reads a recorded run context from the given file.
+func LoadRunContext(path string) (RunContext, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return RunContext{}, fmt.Errorf("reading run context: %w", err)
+	}
+
+	rc := RunContext{Exp: types.NewExperiment(store.ConfigMetadata{})}
+
+	if err := json.Unmarshal(body, &rc); err != nil {
+		return RunContext{}, fmt.Errorf("parsing run context: %w", err)
+	}
+
+	if rc.Name == "" || rc.Type == "" {
+		return RunContext{}, fmt.Errorf("run context is missing component name or type")
+	}
+
+	return rc, nil
+}
+
+type testOptions struct {
+	stage    Action
+	exe      string
+	filesDir string
+	dryRun   bool
+}
+
+type TestOption func(*testOptions)
+
+func newTestOptions(opts ...TestOption) testOptions {
+	o := testOptions{dryRun: true}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// TestWithStage overrides the stage recorded in the run context.
+func TestWithStage(s Action) TestOption {
+	return func(o *testOptions) {
+		o.stage = s
+	}
+}
+
+// TestWithExecutable sets the component executable to run instead of looking
+// up `phenix-scorch-component-<type>` in the PATH.
+func TestWithExecutable(e string) TestOption {
+	return func(o *testOptions) {
+		o.exe = e
+	}
+}
+
+// TestWithFilesDir sets the experiment files directory passed to the
+// component. It defaults to a new temporary directory.
+func TestWithFilesDir(d string) TestOption {
+	return func(o *testOptions) {
+		o.filesDir = d
+	}
+}
+
+// TestWithDryRun sets whether the component is told the experiment is a dry
+// run. It defaults to true, since there's no live experiment to act on.
+func TestWithDryRun(d bool) TestOption {
+	return func(o *testOptions) {
+		o.dryRun = d
+	}
+}
+
+// TestResult is the result of running a user component with TestComponent.
+type TestResult struct {
+	Stdout    []byte
+	Stderr    []byte
+	FilesDir  string
+	Artifacts []string
+}
+
+// TestComponent runs the user component for the recorded run context at the
+// given path, passing it the same arguments, STDIN and environment it would
+// get in a live experiment. Component status updates and history events are
+// not published.
+func TestComponent(ctx context.Context, path string, opts ...TestOption) (*TestResult, error) {
+	o := newTestOptions(opts...)
+
+	rc, err := LoadRunContext(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.stage != "" {
+		rc.Stage = o.stage
+	}
+
+	cmd := o.exe
+
+	if cmd == "" {
+		cmd = "phenix-scorch-component-" + rc.Type
+
+		if !shell.CommandExists(cmd) {
+			return nil, fmt.Errorf("external user component %s does not exist in your path: %w", cmd, ErrUserComponentNotFound)
+		}
+	}
+
+	if o.filesDir == "" {
+		if o.filesDir, err = os.MkdirTemp("", "phenix-scorch-test-"); err != nil {
+			return nil, fmt.Errorf("creating temp files directory: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(rc.Exp)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling experiment metadata to JSON: %w", err)
+	}
+
+	result := &TestResult{FilesDir: o.filesDir}
+
+	result.Stdout, result.Stderr, err = shell.ExecCommand(ctx, componentCommand(cmd, rc, data, o.filesDir, o.dryRun)...)
+	if err != nil {
+		return result, fmt.Errorf("external user component %s (command %s) failed: %w", rc.Type, cmd, err)
+	}
+
+	filepath.WalkDir(o.filesDir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(o.filesDir, p)
+			result.Artifacts = append(result.Artifacts, rel)
+		}
+
+		return nil
+	})
+
+	return result, nil
+}
+
+// componentCommand returns the shell options for running the given user
+// component executable for the given run context.
+func componentCommand(cmd string, rc RunContext, data []byte, filesDir string, dryRun bool) []shell.Option {
+	return []shell.Option{
+		shell.Command(cmd),
+		shell.Args(string(rc.Stage), rc.Name, strconv.Itoa(rc.Run), strconv.Itoa(rc.Loop), strconv.Itoa(rc.Count)),
+		shell.Stdin(data),
+		shell.Env(
+			scorchsdk.EnvPhenixDir+"="+common.PhenixBase,
+			scorchsdk.EnvFilesDir+"="+filesDir,
+			"PHENIX_LOG_LEVEL="+util.GetEnv("PHENIX_LOG_LEVEL", "DEBUG"),
+			"PHENIX_LOG_FILE="+util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
+			scorchsdk.EnvDryRun+"="+strconv.FormatBool(dryRun),
+			scorchsdk.EnvStartTime+"="+rc.StartTime,
+		),
+	}
+}
+
+func recordRunContext(rc RunContext) error {
+	dir := scorchsdk.ArtifactDir(rc.Exp.FilesDir(), rc.Name, rc.Run, rc.Loop, rc.Count)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating artifact directory: %w", err)
+	}
+
+	body, err := json.MarshalIndent(rc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling run context: %w", err)
+	}
+
+	return os.WriteFile(filepath.Join(dir, "context-"+string(rc.Stage)+".json"), body, 0644)
+}
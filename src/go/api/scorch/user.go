@@ -7,8 +7,7 @@ import (
 	"strconv"
 
 	"phenix/store"
-	"phenix/util"
-	"phenix/util/common"
+	"phenix/util/plog"
 	"phenix/util/shell"
 	"phenix/web/scorch"
 )
@@ -93,22 +92,27 @@ func (this UserComponent) run(ctx context.Context, stage Action, cmd string, dat
 		Status:  "running",
 	}
 
+	rc := RunContext{
+		Stage:     stage,
+		Name:      this.options.Name,
+		Type:      this.options.Type,
+		Run:       this.options.Run,
+		Loop:      this.options.Loop,
+		Count:     this.options.Count,
+		StartTime: this.options.StartTime,
+		Exp:       &this.options.Exp,
+	}
+
+	if err := recordRunContext(rc); err != nil {
+		plog.Warn("unable to record scorch component run context", "component", this.options.Name, "err", err)
+	}
+
 	stdout := make(chan []byte)
 
-	opts := []shell.Option{
-		shell.Command(cmd),
-		shell.Args(string(stage), this.options.Name, strconv.Itoa(this.options.Run), strconv.Itoa(this.options.Loop), strconv.Itoa(this.options.Count)),
-		shell.Stdin(data),
+	opts := append(
+		componentCommand(cmd, rc, data, this.options.Exp.FilesDir(), this.options.Exp.DryRun()),
 		shell.StreamStdout(stdout),
-		shell.Env(
-			"PHENIX_DIR="+common.PhenixBase,
-			"PHENIX_FILES_DIR="+this.options.Exp.FilesDir(),
-			"PHENIX_LOG_LEVEL="+util.GetEnv("PHENIX_LOG_LEVEL", "DEBUG"),
-			"PHENIX_LOG_FILE="+util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
-			"PHENIX_DRYRUN="+strconv.FormatBool(this.options.Exp.DryRun()),
-			"PHENIX_SCORCH_STARTTIME="+this.options.StartTime,
-		),
-	}
+	)
 
 	go func() {
 		for output := range stdout {
@@ -0,0 +1,316 @@
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"phenix/app"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func init() {
+	app.RegisterUserApp("scoring", func() app.App { return new(Scoring) })
+}
+
+// Scoring is a capture-the-flag scoring engine. Each time the running stage is
+// executed (typically via the app's `runPeriodically` setting) a new scoring
+// round is started, all the checks configured in the scenario app metadata are
+// evaluated against the experiment VMs via minimega's C2, and points are
+// awarded to the team each passing check belongs to. Flags submitted via the
+// API are scored separately (see `SubmitFlag`).
+type Scoring struct {
+	md scoringMetadata
+
+	options app.Options
+}
+
+func (this *Scoring) Init(opts ...app.Option) error {
+	this.options = app.NewOptions(opts...)
+	return nil
+}
+
+func (Scoring) Name() string {
+	return "scoring"
+}
+
+func (this *Scoring) Configure(ctx context.Context, exp *types.Experiment) error {
+	// Validate the scoring metadata early so configuration errors are caught
+	// before the experiment is started.
+	return this.decodeMetadata(exp)
+}
+
+func (this *Scoring) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this *Scoring) PostStart(ctx context.Context, exp *types.Experiment) error {
+	if err := this.decodeMetadata(exp); err != nil {
+		return err
+	}
+
+	// Start each experiment run with a clean scoreboard.
+	exp.Status.SetAppStatus("scoring", scoringStatus{Updated: time.Now().Format(time.RFC3339)})
+
+	return nil
+}
+
+func (this *Scoring) Running(ctx context.Context, exp *types.Experiment) error {
+	if err := this.decodeMetadata(exp); err != nil {
+		return err
+	}
+
+	if this.options.DryRun {
+		plog.Info("skipping scoring checks since this is a dry run")
+		return nil
+	}
+
+	return this.runRound(ctx, exp)
+}
+
+func (Scoring) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this *Scoring) decodeMetadata(exp *types.Experiment) error {
+	app := exp.App("scoring")
+	if app == nil || app.Metadata() == nil {
+		return fmt.Errorf("scoring app must have metadata defined")
+	}
+
+	this.md = scoringMetadata{}
+
+	if err := mapstructure.Decode(app.Metadata(), &this.md); err != nil {
+		return fmt.Errorf("decoding app metadata: %w", err)
+	}
+
+	if err := this.md.init(); err != nil {
+		return fmt.Errorf("initializing app metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (this *Scoring) runRound(ctx context.Context, exp *types.Experiment) error {
+	logger := plog.LoggerFromContext(ctx)
+
+	var (
+		ns = exp.Spec.ExperimentName()
+		wg = new(mm.StateGroup)
+	)
+
+	for _, c := range this.md.Checks {
+		meta := map[string]interface{}{"check": c.Name}
+
+		node := exp.Spec.Topology().FindNodeByName(c.Host)
+		if node == nil {
+			wg.AddError(fmt.Errorf("host %s not found in topology", c.Host), meta)
+			continue
+		}
+
+		cmd, err := this.newCheckCommand(ns, node, c, wg, meta)
+		if err != nil {
+			wg.AddError(err, meta)
+			continue
+		}
+
+		mm.ScheduleC2ParallelCommand(ctx, cmd)
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	results := make(map[string]mm.GroupState)
+
+	for _, state := range wg.States {
+		results[state.Meta["check"].(string)] = state
+	}
+
+	// The experiment passed to the running stage may have been loaded before
+	// flags were submitted via the API, so the results are applied to the latest
+	// status in the store to avoid losing any recent captures.
+	update := func(_ *types.Experiment, _ scoringMetadata, status *scoringStatus) error {
+		var (
+			now   = time.Now().Format(time.RFC3339)
+			prior = make(map[string]CheckResult)
+		)
+
+		for _, r := range status.Checks {
+			prior[r.Name] = r
+		}
+
+		status.Round++
+		status.Updated = now
+		status.Checks = nil
+
+		for _, c := range this.md.Checks {
+			result := prior[c.Name]
+
+			result.Name = c.Name
+			result.Type = string(c.Type)
+			result.Team = c.Team
+			result.Host = c.Host
+			result.Points = c.Points
+			result.Timestamp = now
+
+			if state, ok := results[c.Name]; ok && state.Err == nil {
+				logger.Debug("[✓] scoring check passed", "check", c.Name, "team", c.Team)
+
+				result.Passed++
+				result.Success = true
+				result.Error = ""
+			} else {
+				result.Failed++
+				result.Success = false
+
+				if ok {
+					result.Error = state.Err.Error()
+				} else {
+					result.Error = "no result returned for check"
+				}
+
+				logger.Debug("[✗] scoring check failed", "check", c.Name, "team", c.Team, "err", result.Error)
+			}
+
+			status.Checks = append(status.Checks, result)
+		}
+
+		return nil
+	}
+
+	status, err := updateStatus(exp.Metadata.Name, update)
+	if err != nil {
+		return fmt.Errorf("recording scoring round results: %w", err)
+	}
+
+	// Keep the in-memory experiment in sync with the store since the caller
+	// writes its status back once the running stage completes.
+	exp.Status.SetAppStatus("scoring", status)
+
+	logger.Info("scoring round complete", "round", status.Round, "failed", wg.ErrCount)
+
+	return nil
+}
+
+func (this Scoring) newCheckCommand(ns string, node ifaces.NodeSpec, c check, wg *mm.StateGroup, meta map[string]interface{}) (*mm.C2ParallelCommand, error) {
+	var (
+		windows = strings.EqualFold(node.Hardware().OSType(), "windows")
+		exec    string
+	)
+
+	expected := func(resp string) error {
+		if strings.TrimSpace(resp) == "" {
+			return fmt.Errorf("no output returned")
+		}
+
+		if c.Token != "" && !strings.Contains(resp, c.Token) {
+			return fmt.Errorf("expected token not found")
+		}
+
+		wg.AddSuccess("check passed", meta)
+		return nil
+	}
+
+	switch c.Type {
+	case CHECK_SERVICE:
+		if c.Process != "" {
+			process, err := quoteArg(c.Process)
+			if err != nil {
+				return nil, fmt.Errorf("invalid process for check %s: %w", c.Name, err)
+			}
+
+			exec = fmt.Sprintf("pgrep -f %s", process)
+
+			if windows {
+				exec = fmt.Sprintf(`powershell -command "Get-Process %s -ErrorAction SilentlyContinue"`, process)
+			}
+		} else {
+			port := strings.TrimPrefix(c.Port, ":")
+
+			if _, err := strconv.Atoi(port); err != nil {
+				return nil, fmt.Errorf("invalid port for check %s: %w", c.Name, err)
+			}
+
+			exec = fmt.Sprintf("ss -lntuH state all 'sport = %s'", port)
+
+			if windows {
+				exec = fmt.Sprintf(`powershell -command "netstat -an | select-string -pattern 'listening' | select-string -pattern ':%s '"`, port)
+			}
+		}
+	case CHECK_FILE:
+		path, err := quoteArg(c.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path for check %s: %w", c.Name, err)
+		}
+
+		if c.Token == "" {
+			exec = fmt.Sprintf("ls %s", path)
+
+			if windows {
+				exec = fmt.Sprintf(`powershell -command "Get-Item -Path %s -ErrorAction SilentlyContinue"`, path)
+			}
+		} else {
+			exec = fmt.Sprintf("cat %s", path)
+
+			if windows {
+				exec = fmt.Sprintf(`powershell -command "Get-Content -Path %s -ErrorAction SilentlyContinue"`, path)
+			}
+		}
+	case CHECK_HTTP:
+		url, err := quoteArg(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL for check %s: %w", c.Name, err)
+		}
+
+		timeout := int(this.md.c2Timeout.Seconds())
+
+		exec = fmt.Sprintf("curl -sfL -m %d %s", timeout, url)
+
+		if windows {
+			exec = fmt.Sprintf(`powershell -command "(Invoke-WebRequest -UseBasicParsing -TimeoutSec %d -Uri %s).Content"`, timeout, url)
+		}
+	default:
+		return nil, fmt.Errorf("unknown check type %s", c.Type)
+	}
+
+	opts := []mm.C2Option{mm.C2NS(ns), mm.C2VM(node.General().Hostname()), mm.C2Command(exec), mm.C2Timeout(this.md.c2Timeout)}
+
+	cmd := &mm.C2ParallelCommand{
+		Options:        opts,
+		Wait:           wg,
+		Meta:           meta,
+		ExpectedStdout: expected,
+	}
+
+	return cmd, nil
+}
+
+// quoteArg wraps the given check argument in single quotes so it's passed to
+// the command executed in the VM as a single literal argument. Arguments
+// containing quotes, backticks, or control characters are rejected outright
+// since they could be used to break out of the quoting on either Linux shells
+// or PowerShell.
+func quoteArg(arg string) (string, error) {
+	if arg == "" {
+		return "", fmt.Errorf("empty argument")
+	}
+
+	for _, r := range arg {
+		if r == '\'' || r == '"' || r == '`' || unicode.IsControl(r) {
+			return "", fmt.Errorf("argument contains disallowed character %q", r)
+		}
+	}
+
+	return "'" + arg + "'", nil
+}
@@ -0,0 +1,2 @@
+// Implementation of the phenix capture-the-flag (CTF) scoring API.
+package scoring
@@ -0,0 +1,193 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/types"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrScoringNotConfigured = errors.New("scoring app not configured for experiment")
+	ErrUnknownTeam          = errors.New("unknown team")
+	ErrInvalidFlag          = errors.New("invalid flag")
+	ErrFlagAlreadyCaptured  = errors.New("flag already captured by team")
+	ErrNotTeamMember        = errors.New("user is not a member of team")
+)
+
+func Configured(exp *types.Experiment) bool {
+	return exp.App("scoring") != nil
+}
+
+// Get returns the current scoreboard for the given experiment. Per-team scores
+// are calculated from the results of each scoring round and any captured flags.
+func Get(expName string) (*Scoreboard, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get experiment %s: %w", expName, err)
+	}
+
+	if !Configured(exp) {
+		return nil, ErrScoringNotConfigured
+	}
+
+	var s Scoring
+
+	if err := s.decodeMetadata(exp); err != nil {
+		return nil, err
+	}
+
+	board := &Scoreboard{ExpStarted: exp.Running()}
+
+	if !board.ExpStarted {
+		board.Teams = tally(s.md, scoringStatus{})
+		return board, nil
+	}
+
+	var status scoringStatus
+	exp.Status.ParseAppStatus("scoring", &status)
+
+	board.Round = status.Round
+	board.Updated = status.Updated
+	board.Teams = tally(s.md, status)
+
+	return board, nil
+}
+
+// SubmitFlag records a flag capture on behalf of the given user if the flag is
+// valid, the user's team is allowed to capture it, and the team hasn't already
+// captured it. The team is derived from the user's team membership in the
+// scoring app metadata; if a team name is also provided it must match.
+func SubmitFlag(expName, user, teamName, value string) (*Capture, error) {
+	var capture Capture
+
+	update := func(exp *types.Experiment, md scoringMetadata, status *scoringStatus) error {
+		if !exp.Running() {
+			return experiment.ErrExperimentNotRunning
+		}
+
+		if teamName != "" && !slices.ContainsFunc(md.Teams, func(t team) bool { return t.Name == teamName }) {
+			return ErrUnknownTeam
+		}
+
+		member := md.teamForUser(user)
+
+		if member == "" || (teamName != "" && member != teamName) {
+			return ErrNotTeamMember
+		}
+
+		f, ok := md.flag(value)
+		if !ok {
+			return ErrInvalidFlag
+		}
+
+		if len(f.Teams) > 0 && !slices.Contains(f.Teams, member) {
+			return ErrInvalidFlag
+		}
+
+		if status.captured(member, f.Name) {
+			return ErrFlagAlreadyCaptured
+		}
+
+		capture = Capture{
+			Team:      member,
+			Flag:      f.Name,
+			Points:    f.Points,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+
+		status.Captures = append(status.Captures, capture)
+
+		return nil
+	}
+
+	if _, err := updateStatus(expName, update); err != nil {
+		return nil, err
+	}
+
+	return &capture, nil
+}
+
+// statusMu serializes every read-modify-write of the scoring status so flag
+// submissions and scoring rounds don't overwrite each other's updates.
+var statusMu sync.Mutex
+
+// updateStatus loads the latest version of the given experiment from the store,
+// applies the given update to its scoring status, and writes the result back
+// to the store, all while holding statusMu. The updated status is returned.
+func updateStatus(expName string, update func(*types.Experiment, scoringMetadata, *scoringStatus) error) (scoringStatus, error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return scoringStatus{}, fmt.Errorf("unable to get experiment %s: %w", expName, err)
+	}
+
+	if !Configured(exp) {
+		return scoringStatus{}, ErrScoringNotConfigured
+	}
+
+	var s Scoring
+
+	if err := s.decodeMetadata(exp); err != nil {
+		return scoringStatus{}, err
+	}
+
+	var status scoringStatus
+	exp.Status.ParseAppStatus("scoring", &status)
+
+	if err := update(exp, s.md, &status); err != nil {
+		return scoringStatus{}, err
+	}
+
+	exp.Status.SetAppStatus("scoring", status)
+
+	if err := exp.WriteToStore(true); err != nil {
+		return scoringStatus{}, fmt.Errorf("writing scoring status to store: %w", err)
+	}
+
+	return status, nil
+}
+
+func tally(md scoringMetadata, status scoringStatus) []TeamScore {
+	var (
+		teams  = make(map[string]*TeamScore)
+		scores []TeamScore
+	)
+
+	for _, t := range md.Teams {
+		teams[t.Name] = &TeamScore{Name: t.Name}
+	}
+
+	for _, c := range status.Checks {
+		if t, ok := teams[c.Team]; ok {
+			t.Score += c.Passed * c.Points
+			t.Checks = append(t.Checks, c)
+		}
+	}
+
+	for _, c := range status.Captures {
+		if t, ok := teams[c.Team]; ok {
+			t.Score += c.Points
+			t.Flags = append(t.Flags, c)
+		}
+	}
+
+	for _, t := range md.Teams {
+		scores = append(scores, *teams[t.Name])
+	}
+
+	// highest score first, keeping configured team order for ties
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+
+	return scores
+}
@@ -0,0 +1,246 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+)
+
+func initExperiment(t *testing.T, running bool) {
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var flags []any
+
+	for i := 0; i < 10; i++ {
+		flags = append(flags, map[string]any{"name": fmt.Sprintf("flag-%d", i), "value": fmt.Sprintf("secret-%d", i), "points": 5})
+	}
+
+	flags = append(flags, map[string]any{"name": "blue-only", "value": "blue-secret", "teams": []any{"blue"}})
+
+	metadata := map[string]any{
+		"teams": []any{
+			map[string]any{"name": "red", "hosts": []any{"red-web"}, "users": []any{"alice"}},
+			map[string]any{"name": "blue", "hosts": []any{"blue-web"}, "users": []any{"bob"}},
+		},
+		"checks": []any{
+			map[string]any{"name": "red-http", "type": "http", "host": "red-web", "url": "http://localhost"},
+		},
+		"flags": flags,
+	}
+
+	status := map[string]any{}
+
+	if running {
+		status["startTime"] = "2026-01-01T00:00:00Z"
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "ctf"},
+		Spec: map[string]any{
+			"experimentName": "ctf",
+			"topology":       map[string]any{"nodes": []any{}},
+			"scenario": map[string]any{
+				"apps": []any{map[string]any{"name": "scoring", "metadata": metadata}},
+			},
+		},
+		Status: status,
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestSubmitFlag(t *testing.T) {
+	initExperiment(t, true)
+
+	capture, err := SubmitFlag("ctf", "alice", "", "secret-0")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// team is derived from the user's team membership
+	if capture.Team != "red" || capture.Flag != "flag-0" || capture.Points != 5 {
+		t.Logf("unexpected capture: %+v", capture)
+		t.FailNow()
+	}
+
+	if _, err := SubmitFlag("ctf", "alice", "", "secret-0"); !errors.Is(err, ErrFlagAlreadyCaptured) {
+		t.Logf("expected already captured error, got %v", err)
+		t.FailNow()
+	}
+
+	// users can't submit flags on behalf of other teams
+	if _, err := SubmitFlag("ctf", "alice", "blue", "secret-1"); !errors.Is(err, ErrNotTeamMember) {
+		t.Logf("expected not team member error, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := SubmitFlag("ctf", "mallory", "", "secret-1"); !errors.Is(err, ErrNotTeamMember) {
+		t.Logf("expected not team member error, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := SubmitFlag("ctf", "alice", "green", "secret-1"); !errors.Is(err, ErrUnknownTeam) {
+		t.Logf("expected unknown team error, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := SubmitFlag("ctf", "alice", "", "blue-secret"); !errors.Is(err, ErrInvalidFlag) {
+		t.Logf("expected invalid flag error for flag restricted to another team, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := SubmitFlag("ctf", "bob", "blue", "blue-secret"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	board, err := Get("ctf")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	scores := make(map[string]int)
+
+	for _, team := range board.Teams {
+		scores[team.Name] = team.Score
+	}
+
+	if scores["red"] != 5 || scores["blue"] != 1 {
+		t.Logf("unexpected scores: %v", scores)
+		t.FailNow()
+	}
+}
+
+func TestSubmitFlagNotRunning(t *testing.T) {
+	initExperiment(t, false)
+
+	if _, err := SubmitFlag("ctf", "alice", "", "secret-0"); !errors.Is(err, experiment.ErrExperimentNotRunning) {
+		t.Logf("expected experiment not running error, got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestSubmitFlagConcurrentRounds(t *testing.T) {
+	initExperiment(t, true)
+
+	var wg sync.WaitGroup
+
+	// Interleave flag submissions with scoring round updates -- none of the
+	// captures should be lost to a round writing a stale status.
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if _, err := SubmitFlag("ctf", "alice", "red", fmt.Sprintf("secret-%d", i)); err != nil {
+				t.Errorf("submitting flag %d: %v", i, err)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			round := func(_ *types.Experiment, _ scoringMetadata, status *scoringStatus) error {
+				status.Round++
+				return nil
+			}
+
+			if _, err := updateStatus("ctf", round); err != nil {
+				t.Errorf("updating round: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	exp, err := experiment.Get("ctf")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var status scoringStatus
+	exp.Status.ParseAppStatus("scoring", &status)
+
+	if status.Round != 10 || len(status.Captures) != 10 {
+		t.Logf("expected 10 rounds and 10 captures, got %d rounds and %d captures", status.Round, len(status.Captures))
+		t.FailNow()
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	quoted, err := quoteArg("/var/www/html/index.html")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if quoted != "'/var/www/html/index.html'" {
+		t.Logf("unexpected quoted argument: %s", quoted)
+		t.FailNow()
+	}
+
+	for _, arg := range []string{"", "foo'; rm -rf /; '", `foo" & calc "`, "foo`id`", "foo\nid"} {
+		if _, err := quoteArg(arg); err == nil {
+			t.Logf("expected argument %q to be rejected", arg)
+			t.FailNow()
+		}
+	}
+}
+
+func TestMetadataInit(t *testing.T) {
+	md := scoringMetadata{
+		Teams:  []team{{Name: "red", Hosts: []string{"web"}}},
+		Checks: []check{{Name: "file", Type: CHECK_FILE, Host: "web", Path: "/tmp/$(reboot)'"}},
+	}
+
+	if err := md.init(); err == nil || !strings.Contains(err.Error(), "disallowed character") {
+		t.Logf("expected check with quote in path to be rejected, got %v", err)
+		t.FailNow()
+	}
+
+	md.Checks = []check{{Name: "svc", Type: CHECK_SERVICE, Host: "web", Port: "80'"}}
+
+	if err := md.init(); err == nil {
+		t.Log("expected check with invalid port to be rejected")
+		t.FailNow()
+	}
+
+	md.Checks = []check{{Name: "svc", Type: CHECK_SERVICE, Host: "web", Port: ":443"}}
+
+	if err := md.init(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if md.Checks[0].Port != "443" || md.Checks[0].Team != "red" || md.Checks[0].Points != 1 {
+		t.Logf("unexpected check defaults: %+v", md.Checks[0])
+		t.FailNow()
+	}
+
+	md.Teams = append(md.Teams, team{Name: "blue", Users: []string{"bob"}})
+	md.Teams[0].Users = []string{"bob"}
+
+	if err := md.init(); err == nil {
+		t.Log("expected user assigned to multiple teams to be rejected")
+		t.FailNow()
+	}
+}
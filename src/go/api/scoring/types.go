@@ -0,0 +1,263 @@
+package scoring
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CheckType string
+
+const (
+	CHECK_SERVICE CheckType = "service"
+	CHECK_FILE    CheckType = "file"
+	CHECK_HTTP    CheckType = "http"
+)
+
+type team struct {
+	Name  string   `mapstructure:"name"`
+	Hosts []string `mapstructure:"hosts"`
+
+	// phenix users allowed to submit flags on behalf of the team
+	Users []string `mapstructure:"users"`
+}
+
+type check struct {
+	Name   string    `mapstructure:"name"`
+	Type   CheckType `mapstructure:"type"`
+	Team   string    `mapstructure:"team"`
+	Host   string    `mapstructure:"host"`
+	Points int       `mapstructure:"points"`
+
+	// used by service checks -- at least one of `process` or `port` must be set
+	Process string `mapstructure:"process"`
+	Port    string `mapstructure:"port"`
+
+	// used by file checks
+	Path string `mapstructure:"path"`
+
+	// used by HTTP checks -- `host` is the VM the request is made from
+	URL string `mapstructure:"url"`
+
+	// used by file and HTTP checks -- if set, the file contents or HTTP response
+	// body must contain the token for the check to pass
+	Token string `mapstructure:"token"`
+}
+
+type flag struct {
+	Name   string `mapstructure:"name"`
+	Value  string `mapstructure:"value"`
+	Points int    `mapstructure:"points"`
+
+	// if set, only the listed teams can capture this flag
+	Teams []string `mapstructure:"teams"`
+}
+
+type scoringMetadata struct {
+	C2Timeout string  `mapstructure:"c2Timeout"`
+	Teams     []team  `mapstructure:"teams"`
+	Checks    []check `mapstructure:"checks"`
+	Flags     []flag  `mapstructure:"flags"`
+
+	// set after parsing
+	c2Timeout time.Duration
+}
+
+func (this *scoringMetadata) init() error {
+	if this.C2Timeout == "" {
+		// Default C2 timeout to 30s if not specified in the scenario app config so
+		// a single unresponsive VM doesn't hold up an entire scoring round.
+		this.c2Timeout = 30 * time.Second
+	} else {
+		var err error
+
+		if this.c2Timeout, err = time.ParseDuration(this.C2Timeout); err != nil {
+			return fmt.Errorf("parsing C2 timeout setting '%s': %w", this.C2Timeout, err)
+		}
+	}
+
+	var (
+		teams = make(map[string]struct{})
+		users = make(map[string]string)
+	)
+
+	for _, t := range this.Teams {
+		if t.Name == "" {
+			return fmt.Errorf("all teams must have a name")
+		}
+
+		teams[t.Name] = struct{}{}
+
+		for _, u := range t.Users {
+			if other, ok := users[u]; ok && other != t.Name {
+				return fmt.Errorf("user %s is assigned to both team %s and team %s", u, other, t.Name)
+			}
+
+			users[u] = t.Name
+		}
+	}
+
+	for i, c := range this.Checks {
+		if c.Name == "" {
+			return fmt.Errorf("check %d is missing a name", i)
+		}
+
+		if c.Host == "" {
+			return fmt.Errorf("check %s is missing a host", c.Name)
+		}
+
+		if c.Team == "" {
+			c.Team = this.teamForHost(c.Host)
+		}
+
+		if _, ok := teams[c.Team]; !ok {
+			return fmt.Errorf("check %s is not assigned to a known team", c.Name)
+		}
+
+		for _, arg := range []string{c.Process, c.Path, c.URL} {
+			if arg == "" {
+				continue
+			}
+
+			if _, err := quoteArg(arg); err != nil {
+				return fmt.Errorf("check %s: %w", c.Name, err)
+			}
+		}
+
+		switch c.Type {
+		case CHECK_SERVICE:
+			if c.Process == "" && c.Port == "" {
+				return fmt.Errorf("service check %s must specify a process or a port", c.Name)
+			}
+
+			if c.Port != "" {
+				c.Port = strings.TrimPrefix(c.Port, ":")
+
+				if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+					return fmt.Errorf("service check %s has invalid port '%s'", c.Name, c.Port)
+				}
+			}
+		case CHECK_FILE:
+			if c.Path == "" {
+				return fmt.Errorf("file check %s must specify a path", c.Name)
+			}
+		case CHECK_HTTP:
+			if c.URL == "" {
+				return fmt.Errorf("HTTP check %s must specify a URL", c.Name)
+			}
+		default:
+			return fmt.Errorf("unknown type '%s' for check %s", c.Type, c.Name)
+		}
+
+		if c.Points == 0 {
+			c.Points = 1
+		}
+
+		this.Checks[i] = c
+	}
+
+	for i, f := range this.Flags {
+		if f.Name == "" || f.Value == "" {
+			return fmt.Errorf("flag %d must have a name and value", i)
+		}
+
+		if f.Points == 0 {
+			f.Points = 1
+		}
+
+		this.Flags[i] = f
+	}
+
+	return nil
+}
+
+func (this scoringMetadata) teamForHost(host string) string {
+	for _, t := range this.Teams {
+		for _, h := range t.Hosts {
+			if h == host {
+				return t.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+func (this scoringMetadata) teamForUser(user string) string {
+	for _, t := range this.Teams {
+		for _, u := range t.Users {
+			if u == user {
+				return t.Name
+			}
+		}
+	}
+
+	return ""
+}
+
+func (this scoringMetadata) flag(value string) (flag, bool) {
+	for _, f := range this.Flags {
+		if f.Value == value {
+			return f, true
+		}
+	}
+
+	return flag{}, false
+}
+
+// CheckResult is the most recent result of a scoring check, along with the
+// running tally of how many scoring rounds the check has passed and failed.
+type CheckResult struct {
+	Name      string `json:"name" mapstructure:"name" structs:"name"`
+	Type      string `json:"type" mapstructure:"type" structs:"type"`
+	Team      string `json:"team" mapstructure:"team" structs:"team"`
+	Host      string `json:"host" mapstructure:"host" structs:"host"`
+	Points    int    `json:"points" mapstructure:"points" structs:"points"`
+	Passed    int    `json:"passed" mapstructure:"passed" structs:"passed"`
+	Failed    int    `json:"failed" mapstructure:"failed" structs:"failed"`
+	Success   bool   `json:"success" mapstructure:"success" structs:"success"`
+	Error     string `json:"error,omitempty" mapstructure:"error" structs:"error"`
+	Timestamp string `json:"timestamp" mapstructure:"timestamp" structs:"timestamp"`
+}
+
+// Capture is a flag successfully submitted by a team.
+type Capture struct {
+	Team      string `json:"team" mapstructure:"team" structs:"team"`
+	Flag      string `json:"flag" mapstructure:"flag" structs:"flag"`
+	Points    int    `json:"points" mapstructure:"points" structs:"points"`
+	Timestamp string `json:"timestamp" mapstructure:"timestamp" structs:"timestamp"`
+}
+
+// scoringStatus is what gets persisted to the experiment status under the
+// `scoring` app key. Team scores are always calculated from it on demand.
+type scoringStatus struct {
+	Round    int           `mapstructure:"round" structs:"round"`
+	Updated  string        `mapstructure:"updated" structs:"updated"`
+	Checks   []CheckResult `mapstructure:"checks" structs:"checks"`
+	Captures []Capture     `mapstructure:"captures" structs:"captures"`
+}
+
+func (this scoringStatus) captured(team, flag string) bool {
+	for _, c := range this.Captures {
+		if c.Team == team && c.Flag == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+type TeamScore struct {
+	Name   string        `json:"name"`
+	Score  int           `json:"score"`
+	Checks []CheckResult `json:"checks"`
+	Flags  []Capture     `json:"flags"`
+}
+
+type Scoreboard struct {
+	ExpStarted bool        `json:"started"`
+	Round      int         `json:"round"`
+	Updated    string      `json:"updated"`
+	Teams      []TeamScore `json:"teams"`
+}
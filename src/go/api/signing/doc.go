@@ -0,0 +1,5 @@
+// Implementation of the phenix signing API. Configs and disk images can be
+// signed with Ed25519 keys (minisign style), and signatures are verified when
+// experiments are started based on the verification policy configured for the
+// experiment's namespace.
+package signing
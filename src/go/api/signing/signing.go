@@ -0,0 +1,415 @@
+package signing
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/common"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// SignatureAnnotation is the config annotation signatures of configs are
+// stored in.
+const SignatureAnnotation = "phenix/signature"
+
+// DigestAnnotationPrefix and SignatureAnnotationPrefix prefix the experiment
+// annotations the digests and signatures of the topology and scenario configs
+// an experiment was created from are recorded in (ie.
+// `phenix/signed-digest/topology`).
+const (
+	DigestAnnotationPrefix    = "phenix/signed-digest/"
+	SignatureAnnotationPrefix = "phenix/signed-signature/"
+)
+
+// SignatureExt is the file extension of detached disk image signatures, which
+// are stored alongside the disk image they sign.
+const SignatureExt = ".sig"
+
+const commentPrefix = "untrusted comment:"
+
+type Mode string
+
+const (
+	MODE_OFF     Mode = "off"
+	MODE_WARN    Mode = "warn"
+	MODE_ENFORCE Mode = "enforce"
+)
+
+var (
+	ErrNoSignature      = errors.New("no signature")
+	ErrUntrustedKey     = errors.New("signed with untrusted key")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Policy returns the signature verification mode for the given namespace.
+// Namespace specific policies take precedence over the default policy.
+func Policy(namespace string) Mode {
+	if mode, ok := common.SigningNamespacePolicies[namespace]; ok {
+		return Mode(mode)
+	}
+
+	if common.SigningPolicy == "" {
+		return MODE_OFF
+	}
+
+	return Mode(common.SigningPolicy)
+}
+
+// GenerateKeys generates a new Ed25519 key pair, writing the private key to
+// the given path with a `.key` extension and the public key to the given path
+// with a `.pub` extension.
+func GenerateKeys(path string) (string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("generating key pair: %w", err)
+	}
+
+	id := keyID(pub)
+
+	if err := writeKey(path+".key", "phenix private key "+id, priv.Seed(), 0600); err != nil {
+		return "", err
+	}
+
+	if err := writeKey(path+".pub", "phenix public key "+id, pub, 0644); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// LoadPrivateKey loads the Ed25519 private key at the given path.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	seed, err := readKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid private key in %s", path)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// LoadPublicKeys loads the Ed25519 public keys at the given paths, keyed by
+// key ID.
+func LoadPublicKeys(paths ...string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid public key in %s", path)
+		}
+
+		keys[keyID(key)] = ed25519.PublicKey(key)
+	}
+
+	return keys, nil
+}
+
+// Sign returns a signature of the given digest in the form `<key ID>:<base64
+// signature>`.
+func Sign(key ed25519.PrivateKey, digest []byte) string {
+	sig := ed25519.Sign(key, digest)
+	return keyID(key.Public().(ed25519.PublicKey)) + ":" + base64.StdEncoding.EncodeToString(sig)
+}
+
+// Verify verifies the given signature of the given digest was created by one
+// of the given trusted keys.
+func Verify(keys map[string]ed25519.PublicKey, digest []byte, sig string) error {
+	if sig == "" {
+		return ErrNoSignature
+	}
+
+	id, encoded, ok := strings.Cut(strings.TrimSpace(sig), ":")
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	key, ok := keys[id]
+	if !ok {
+		return fmt.Errorf("%w (key ID %s)", ErrUntrustedKey, id)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(key, digest, raw) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ConfigDigest returns the digest of the given config that gets signed. Only
+// the config's version, kind, name, and spec are included so that signatures
+// remain valid when metadata (ie. timestamps) changes.
+func ConfigDigest(c store.Config) ([]byte, error) {
+	body, err := json.Marshal(map[string]any{
+		"apiVersion": c.Version,
+		"kind":       c.Kind,
+		"name":       c.Metadata.Name,
+		"spec":       c.Spec,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("marshaling config: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	return sum[:], nil
+}
+
+// SignConfig signs the given config, storing the signature as a config
+// annotation. The config is not updated in the store.
+func SignConfig(c *store.Config, key ed25519.PrivateKey) error {
+	digest, err := ConfigDigest(*c)
+	if err != nil {
+		return err
+	}
+
+	if c.Metadata.Annotations == nil {
+		c.Metadata.Annotations = make(store.Annotations)
+	}
+
+	c.Metadata.Annotations[SignatureAnnotation] = Sign(key, digest)
+
+	return nil
+}
+
+// VerifyConfig verifies the signature stored in the given config's
+// annotations.
+func VerifyConfig(c store.Config, keys map[string]ed25519.PublicKey) error {
+	digest, err := ConfigDigest(c)
+	if err != nil {
+		return err
+	}
+
+	return Verify(keys, digest, c.Metadata.Annotations[SignatureAnnotation])
+}
+
+// RecordConfig records the digest of the given config, along with its
+// signature (if any), in the given experiment annotations under the given kind
+// so the signature can be verified later against the config exactly as it was
+// when the experiment was created.
+func RecordConfig(annotations store.Annotations, kind string, c store.Config) error {
+	digest, err := ConfigDigest(c)
+	if err != nil {
+		return err
+	}
+
+	annotations[DigestAnnotationPrefix+kind] = hex.EncodeToString(digest)
+
+	if sig, ok := c.Metadata.Annotations[SignatureAnnotation]; ok {
+		annotations[SignatureAnnotationPrefix+kind] = sig
+	} else {
+		delete(annotations, SignatureAnnotationPrefix+kind)
+	}
+
+	return nil
+}
+
+// VerifyRecordedConfig verifies the signature of the config of the given kind
+// recorded in the given experiment annotations by RecordConfig.
+func VerifyRecordedConfig(annotations store.Annotations, kind string, keys map[string]ed25519.PublicKey) error {
+	encoded, ok := annotations[DigestAnnotationPrefix+kind]
+	if !ok {
+		return fmt.Errorf("%w recorded at experiment creation", ErrNoSignature)
+	}
+
+	digest, err := hex.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	return Verify(keys, digest, annotations[SignatureAnnotationPrefix+kind])
+}
+
+// FileDigest returns the SHA256 digest of the file at the given path.
+func FileDigest(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("hashing %s: %w", path, err)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// SignFile signs the file at the given path, writing the signature to a
+// detached signature file alongside it.
+func SignFile(path string, key ed25519.PrivateKey) error {
+	digest, err := FileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+SignatureExt, []byte(Sign(key, digest)+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing signature for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// VerifyFile verifies the detached signature of the file at the given path.
+func VerifyFile(path string, keys map[string]ed25519.PublicKey) error {
+	sig, err := os.ReadFile(path + SignatureExt)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoSignature
+		}
+
+		return fmt.Errorf("reading signature for %s: %w", path, err)
+	}
+
+	digest, err := FileDigest(path)
+	if err != nil {
+		return err
+	}
+
+	return Verify(keys, digest, string(sig))
+}
+
+// VerifyExperiment verifies the signatures of the topology and scenario
+// configs the given experiment was created from, along with the signatures of
+// the disk images used by the experiment's VMs, according to the verification
+// policy for the experiment's namespace. Config signatures are verified against
+// the config digests recorded on the experiment when it was created rather than
+// the configs currently in the store, which may have changed since. If the
+// policy is set to warn, verification failures are returned as warnings
+// instead of an error.
+func VerifyExperiment(exp *types.Experiment) (warnings []error, err error) {
+	mode := Policy(exp.Spec.ExperimentName())
+
+	switch mode {
+	case MODE_OFF:
+		return nil, nil
+	case MODE_WARN, MODE_ENFORCE:
+	default:
+		return nil, fmt.Errorf("unknown signature verification policy '%s'", mode)
+	}
+
+	keys, err := LoadPublicKeys(common.SigningPublicKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("loading trusted public keys: %w", err)
+	}
+
+	var errs error
+
+	for _, kind := range []string{"topology", "scenario"} {
+		name, ok := exp.Metadata.Annotations[kind]
+		if !ok {
+			continue
+		}
+
+		if err := VerifyRecordedConfig(exp.Metadata.Annotations, kind, keys); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("verifying %s %s: %w", kind, name, err))
+		}
+	}
+
+	verified := make(map[string]struct{})
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || *node.General().DoNotBoot() {
+			continue
+		}
+
+		for _, drive := range node.Hardware().Drives() {
+			if drive.Image() == "" {
+				continue
+			}
+
+			path := util.GetMMFullPath(drive.Image())
+
+			if _, ok := verified[path]; ok {
+				continue
+			}
+
+			verified[path] = struct{}{}
+
+			if err := VerifyFile(path, keys); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("verifying disk image %s: %w", drive.Image(), err))
+			}
+		}
+	}
+
+	if errs == nil {
+		return nil, nil
+	}
+
+	if mode == MODE_WARN {
+		return errs.(*multierror.Error).Errors, nil
+	}
+
+	return nil, errs
+}
+
+func keyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+func writeKey(path, comment string, key []byte, perm os.FileMode) error {
+	body := fmt.Sprintf("%s %s\n%s\n", commentPrefix, comment, base64.StdEncoding.EncodeToString(key))
+
+	if err := os.WriteFile(path, []byte(body), perm); err != nil {
+		return fmt.Errorf("writing key to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func readKey(path string) ([]byte, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key %s: %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, commentPrefix) {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("decoding key %s: %w", path, err)
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("no key found in %s", path)
+}
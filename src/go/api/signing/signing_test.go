@@ -0,0 +1,159 @@
+package signing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"phenix/store"
+)
+
+func TestSignConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := GenerateKeys(filepath.Join(dir, "trusted")); err != nil {
+		t.Fatalf("generating trusted keys: %v", err)
+	}
+
+	if _, err := GenerateKeys(filepath.Join(dir, "other")); err != nil {
+		t.Fatalf("generating other keys: %v", err)
+	}
+
+	priv, err := LoadPrivateKey(filepath.Join(dir, "trusted.key"))
+	if err != nil {
+		t.Fatalf("loading private key: %v", err)
+	}
+
+	keys, err := LoadPublicKeys(filepath.Join(dir, "trusted.pub"))
+	if err != nil {
+		t.Fatalf("loading public keys: %v", err)
+	}
+
+	c, _ := store.NewConfig("topology/foo")
+	c.Spec = map[string]any{"nodes": []any{map[string]any{"hostname": "foo"}}}
+
+	if err := VerifyConfig(*c, keys); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected no signature error, got %v", err)
+	}
+
+	if err := SignConfig(c, priv); err != nil {
+		t.Fatalf("signing config: %v", err)
+	}
+
+	if err := VerifyConfig(*c, keys); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	// Metadata changes shouldn't invalidate the signature.
+	c.Metadata.Updated = "2024-03-01T00:00:00Z"
+
+	if err := VerifyConfig(*c, keys); err != nil {
+		t.Errorf("expected valid signature after metadata change, got %v", err)
+	}
+
+	c.Spec["nodes"] = []any{map[string]any{"hostname": "bar"}}
+
+	if err := VerifyConfig(*c, keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+
+	other, err := LoadPublicKeys(filepath.Join(dir, "other.pub"))
+	if err != nil {
+		t.Fatalf("loading public keys: %v", err)
+	}
+
+	if err := VerifyConfig(*c, other); !errors.Is(err, ErrUntrustedKey) {
+		t.Errorf("expected untrusted key error, got %v", err)
+	}
+}
+
+func TestSignFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := GenerateKeys(filepath.Join(dir, "trusted")); err != nil {
+		t.Fatalf("generating keys: %v", err)
+	}
+
+	priv, _ := LoadPrivateKey(filepath.Join(dir, "trusted.key"))
+	keys, _ := LoadPublicKeys(filepath.Join(dir, "trusted.pub"))
+
+	image := filepath.Join(dir, "foo.qc2")
+
+	if err := os.WriteFile(image, []byte("disk image"), 0644); err != nil {
+		t.Fatalf("writing image: %v", err)
+	}
+
+	if err := VerifyFile(image, keys); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected no signature error, got %v", err)
+	}
+
+	if err := SignFile(image, priv); err != nil {
+		t.Fatalf("signing image: %v", err)
+	}
+
+	if err := VerifyFile(image, keys); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	if err := os.WriteFile(image, []byte("tampered disk image"), 0644); err != nil {
+		t.Fatalf("writing image: %v", err)
+	}
+
+	if err := VerifyFile(image, keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+}
+
+func TestRecordConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := GenerateKeys(filepath.Join(dir, "trusted")); err != nil {
+		t.Fatalf("generating keys: %v", err)
+	}
+
+	priv, _ := LoadPrivateKey(filepath.Join(dir, "trusted.key"))
+	keys, _ := LoadPublicKeys(filepath.Join(dir, "trusted.pub"))
+
+	c, _ := store.NewConfig("topology/foo")
+	c.Spec = map[string]any{"nodes": []any{map[string]any{"hostname": "foo"}}}
+
+	annotations := make(store.Annotations)
+
+	if err := VerifyRecordedConfig(annotations, "topology", keys); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected no signature error for missing record, got %v", err)
+	}
+
+	if err := RecordConfig(annotations, "topology", *c); err != nil {
+		t.Fatalf("recording unsigned config: %v", err)
+	}
+
+	if err := VerifyRecordedConfig(annotations, "topology", keys); !errors.Is(err, ErrNoSignature) {
+		t.Errorf("expected no signature error for unsigned config, got %v", err)
+	}
+
+	if err := SignConfig(c, priv); err != nil {
+		t.Fatalf("signing config: %v", err)
+	}
+
+	if err := RecordConfig(annotations, "topology", *c); err != nil {
+		t.Fatalf("recording signed config: %v", err)
+	}
+
+	// Changes to the config after it was recorded (ie. re-signing a modified
+	// config in the store) don't affect the recorded signature.
+	c.Spec["nodes"] = []any{map[string]any{"hostname": "bar"}}
+	SignConfig(c, priv)
+
+	if err := VerifyRecordedConfig(annotations, "topology", keys); err != nil {
+		t.Errorf("expected valid recorded signature, got %v", err)
+	}
+
+	// Pairing the recorded digest with the signature of a different config
+	// fails verification.
+	annotations[SignatureAnnotationPrefix+"topology"] = c.Metadata.Annotations[SignatureAnnotation]
+
+	if err := VerifyRecordedConfig(annotations, "topology", keys); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected invalid signature error, got %v", err)
+	}
+}
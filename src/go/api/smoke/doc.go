@@ -0,0 +1,7 @@
+// Package smoke implements automated experiment smoke tests. A smoke test
+// instantiates an experiment from a designated topology and scenario on a
+// reserved subset of cluster hosts, checks its state of health and runs
+// user-defined checks in its VMs, then tears it down and records whether it
+// passed in the smoke test history. Smoke tests can be run on demand or
+// nightly at a given time of day.
+package smoke
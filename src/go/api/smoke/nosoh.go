@@ -0,0 +1,11 @@
+//go:build nosoh
+
+package smoke
+
+import "context"
+
+// The state of health app isn't compiled into phenix, so there are never any
+// state of health failures to report.
+func health(ctx context.Context, name string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,33 @@
+package smoke
+
+import (
+	"path/filepath"
+
+	"phenix/util/common"
+)
+
+type Option func(*options)
+
+type options struct {
+	history string
+}
+
+func newOptions(opts ...Option) options {
+	o := options{history: filepath.Join(common.PhenixBase, "smoke", "history.jsonl")}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithHistory sets the path to the JSONL file smoke test results are recorded
+// in. It defaults to `smoke/history.jsonl` in the phenix base directory.
+func WithHistory(h string) Option {
+	return func(o *options) {
+		if h != "" {
+			o.history = h
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package smoke
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// Annotation is set on experiments created for smoke tests so existing
+// experiments that weren't created by a smoke test are never torn down.
+const Annotation = "phenix/smoke-test"
+
+var ErrRunning = errors.New("smoke test already running")
+
+var (
+	// Guards the smoke test history file.
+	historyMu sync.Mutex
+
+	// Tracks smoke tests currently running.
+	running   = make(map[string]struct{})
+	runningMu sync.Mutex
+)
+
+// Start runs each of the given smoke tests nightly at their configured time of
+// day until the given context is canceled. Tests without a time of day are
+// ignored.
+func Start(ctx context.Context, tests []Test, opts ...Option) {
+	for _, t := range tests {
+		if t.At == "" {
+			continue
+		}
+
+		go func(t Test) {
+			for {
+				next := t.next(time.Now())
+
+				plog.Info("smoke test scheduled", "test", t.Name, "next", next)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Until(next)):
+				}
+
+				res, err := Run(ctx, t, opts...)
+				if err != nil {
+					plog.Error("running smoke test", "test", t.Name, "err", err)
+					continue
+				}
+
+				plog.Info("smoke test complete", "test", t.Name, "passed", res.Passed)
+			}
+		}(t)
+	}
+}
+
+// Run runs the given smoke test and records its result in the smoke test
+// history. Failures of the test itself are reported in the result. An error is
+// only returned if the test is already running.
+func Run(ctx context.Context, t Test, opts ...Option) (Result, error) {
+	o := newOptions(opts...)
+
+	runningMu.Lock()
+
+	if _, ok := running[t.Name]; ok {
+		runningMu.Unlock()
+		return Result{}, fmt.Errorf("%w: %s", ErrRunning, t.Name)
+	}
+
+	running[t.Name] = struct{}{}
+	runningMu.Unlock()
+
+	defer func() {
+		runningMu.Lock()
+		delete(running, t.Name)
+		runningMu.Unlock()
+	}()
+
+	res := Result{Test: t.Name, Experiment: t.experiment(), Start: time.Now().UTC()}
+
+	if err := run(ctx, t, &res); err != nil {
+		res.Error = err.Error()
+	}
+
+	res.End = time.Now().UTC()
+	res.Passed = res.Error == "" && len(res.SoH) == 0
+
+	for _, c := range res.Checks {
+		if !c.Passed {
+			res.Passed = false
+		}
+	}
+
+	if err := record(o.history, res); err != nil {
+		plog.Error("recording smoke test result", "test", t.Name, "err", err)
+	}
+
+	return res, nil
+}
+
+// History returns the recorded results for the given smoke test (or all smoke
+// tests if empty), oldest first.
+func History(test string, opts ...Option) ([]Result, error) {
+	o := newOptions(opts...)
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	f, err := os.Open(o.history)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("opening smoke test history: %w", err)
+	}
+
+	defer f.Close()
+
+	var (
+		results []Result
+		scanner = bufio.NewScanner(f)
+	)
+
+	scanner.Buffer(nil, 1024*1024)
+
+	for scanner.Scan() {
+		var res Result
+
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			continue
+		}
+
+		if test == "" || res.Test == test {
+			results = append(results, res)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading smoke test history: %w", err)
+	}
+
+	return results, nil
+}
+
+func run(ctx context.Context, t Test, res *Result) error {
+	timeout, err := t.timeout()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	name := t.experiment()
+
+	// Clean up after a previous run that didn't finish tearing down.
+	if err := teardown(name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := teardown(name); err != nil {
+			plog.Error("tearing down smoke test experiment", "exp", name, "err", err)
+		}
+	}()
+
+	schedules, err := schedule(t)
+	if err != nil {
+		return err
+	}
+
+	opts := []experiment.CreateOption{
+		experiment.CreateWithName(name),
+		experiment.CreateWithTopology(t.Topology),
+		experiment.CreateWithScenario(t.Scenario),
+		experiment.CreateWithSchedules(schedules),
+		experiment.CreateWithAnnotations(map[string]string{Annotation: t.Name}),
+	}
+
+	if err := experiment.Create(ctx, opts...); err != nil {
+		return fmt.Errorf("creating experiment %s: %w", name, err)
+	}
+
+	if err := experiment.Start(ctx, experiment.StartWithName(name)); err != nil {
+		return fmt.Errorf("starting experiment %s: %w", name, err)
+	}
+
+	if res.SoH, err = health(ctx, name); err != nil {
+		return err
+	}
+
+	for _, c := range t.Checks {
+		res.Checks = append(res.Checks, check(ctx, name, c))
+	}
+
+	return nil
+}
+
+// teardown stops and deletes the experiment with the given name if it exists
+// and was created by a smoke test.
+func teardown(name string) error {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		// Experiment doesn't exist.
+		return nil
+	}
+
+	if _, ok := exp.Metadata.Annotations[Annotation]; !ok {
+		return fmt.Errorf("experiment %s already exists and was not created by a smoke test", name)
+	}
+
+	if exp.Running() {
+		if err := experiment.Stop(name); err != nil {
+			return fmt.Errorf("stopping experiment %s: %w", name, err)
+		}
+	}
+
+	if err := experiment.Delete(name); err != nil {
+		return fmt.Errorf("deleting experiment %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// schedule spreads the VMs in the test topology round robin across the hosts
+// reserved for the test.
+func schedule(t Test) (map[string]string, error) {
+	if len(t.Hosts) == 0 {
+		return nil, nil
+	}
+
+	c, _ := store.NewConfig("topology/" + t.Topology)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting topology %s: %w", t.Topology, err)
+	}
+
+	topo, err := types.DecodeTopologyFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding topology %s: %w", t.Topology, err)
+	}
+
+	var (
+		schedules = make(map[string]string)
+		idx       int
+	)
+
+	for _, node := range topo.Nodes() {
+		if node.External() {
+			continue
+		}
+
+		schedules[node.General().Hostname()] = t.Hosts[idx%len(t.Hosts)]
+		idx++
+	}
+
+	return schedules, nil
+}
+
+func check(ctx context.Context, ns string, c Check) CheckResult {
+	res := CheckResult{Name: c.Name, VM: c.VM}
+
+	opts := []mm.C2Option{mm.C2NS(ns), mm.C2VM(c.VM), mm.C2Context(ctx)}
+
+	id, err := mm.ExecC2Command(append(opts, mm.C2Command(c.Command), mm.C2Wait())...)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Output, err = mm.GetC2Response(append(opts, mm.C2CommandID(id), mm.C2ResponseTypeStdout())...)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if c.Expect != "" {
+		if matched, _ := regexp.MatchString(c.Expect, res.Output); !matched {
+			res.Error = fmt.Sprintf("output did not match %s", c.Expect)
+			return res
+		}
+	}
+
+	res.Passed = true
+
+	return res
+}
+
+func record(path string, res Result) error {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("marshaling smoke test result: %w", err)
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating smoke test history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening smoke test history: %w", err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("writing smoke test history: %w", err)
+	}
+
+	return nil
+}
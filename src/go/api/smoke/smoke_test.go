@@ -0,0 +1,112 @@
+package smoke
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := `
+tests:
+- name: nightly
+  topology: foo
+  scenario: bar
+  hosts: [compute1, compute2]
+  at: "02:30"
+  timeout: 1h
+  checks:
+  - name: web
+    vm: web
+    command: curl -s localhost
+    expect: Welcome
+`
+
+	path := filepath.Join(dir, "valid.yml")
+	os.WriteFile(path, []byte(valid), 0644)
+
+	tests, err := Load(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(tests) != 1 || tests[0].experiment() != "smoke-nightly" || len(tests[0].Hosts) != 2 || len(tests[0].Checks) != 1 {
+		t.Logf("unexpected smoke tests: %+v", tests)
+		t.FailNow()
+	}
+
+	invalid := map[string]string{
+		"name":     "tests: [{name: 'bad name', topology: foo}]",
+		"topology": "tests: [{name: foo}]",
+		"at":       "tests: [{name: foo, topology: foo, at: '2am'}]",
+		"timeout":  "tests: [{name: foo, topology: foo, timeout: forever}]",
+		"check":    "tests: [{name: foo, topology: foo, checks: [{name: bar, vm: baz}]}]",
+		"dup":      "tests: [{name: foo, topology: foo}, {name: foo, topology: bar}]",
+	}
+
+	for name, body := range invalid {
+		path := filepath.Join(dir, name+".yml")
+		os.WriteFile(path, []byte(body), 0644)
+
+		if _, err := Load(path); err == nil {
+			t.Logf("expected error loading invalid smoke test (%s)", name)
+			t.FailNow()
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	test := Test{At: "02:30"}
+
+	now := time.Date(2024, 3, 1, 1, 0, 0, 0, time.Local)
+
+	if next := test.next(now); !next.Equal(time.Date(2024, 3, 1, 2, 30, 0, 0, time.Local)) {
+		t.Logf("expected next run later today, got %s", next)
+		t.FailNow()
+	}
+
+	now = time.Date(2024, 3, 1, 2, 30, 0, 0, time.Local)
+
+	if next := test.next(now); !next.Equal(time.Date(2024, 3, 2, 2, 30, 0, 0, time.Local)) {
+		t.Logf("expected next run tomorrow, got %s", next)
+		t.FailNow()
+	}
+
+	if next := (Test{}).next(now); !next.IsZero() {
+		t.Logf("expected unscheduled test to never run, got %s", next)
+		t.FailNow()
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	if results, err := History("", WithHistory(path)); err != nil || results != nil {
+		t.Logf("expected empty history, got %v (%v)", results, err)
+		t.FailNow()
+	}
+
+	record(path, Result{Test: "foo", Passed: true})
+	record(path, Result{Test: "bar", Passed: false, SoH: []string{"web: VM notrunning"}})
+	record(path, Result{Test: "foo", Passed: false})
+
+	results, err := History("foo", WithHistory(path))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(results) != 2 || !results[0].Passed || results[1].Passed {
+		t.Logf("unexpected history for foo: %+v", results)
+		t.FailNow()
+	}
+
+	if results, _ := History("", WithHistory(path)); len(results) != 3 {
+		t.Logf("expected 3 results, got %d", len(results))
+		t.FailNow()
+	}
+}
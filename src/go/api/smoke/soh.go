@@ -0,0 +1,62 @@
+//go:build !nosoh
+
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/soh"
+)
+
+// health waits for the state of health app (if configured for the experiment)
+// to finish its initial run, then returns a description of each VM that didn't
+// boot or reported state of health errors.
+func health(ctx context.Context, name string) ([]string, error) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		exp, err := experiment.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+		}
+
+		if !soh.Configured(exp) || (soh.Initialized(exp) && !soh.Running(exp)) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for state of health: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	network, err := soh.Get(name, "")
+	if err != nil {
+		return nil, fmt.Errorf("getting state of health for experiment %s: %w", name, err)
+	}
+
+	var failures []string
+
+	for _, node := range network.Nodes {
+		if node.Status == "notdeploy" || node.Status == "notrunning" {
+			failures = append(failures, fmt.Sprintf("%s: VM %s", node.Label, node.Status))
+		}
+
+		if node.SOH == nil {
+			continue
+		}
+
+		for _, state := range node.SOH.AllStates() {
+			if state.Error != "" {
+				failures = append(failures, fmt.Sprintf("%s: %s", node.Label, state.Error))
+			}
+		}
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,170 @@
+package smoke
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Tests are the smoke tests defined in a smoke test file.
+type Tests struct {
+	Tests []Test `yaml:"tests" json:"tests"`
+}
+
+// Test is a single smoke test definition.
+type Test struct {
+	// Name of the smoke test. The experiment created for the test is named
+	// `smoke-<name>`.
+	Name string `yaml:"name" json:"name"`
+
+	// Topology and (optional) scenario the experiment is created from.
+	Topology string `yaml:"topology" json:"topology"`
+	Scenario string `yaml:"scenario" json:"scenario"`
+
+	// Cluster hosts reserved for the test. VMs are spread across them round
+	// robin. If empty, the experiment is scheduled like any other.
+	Hosts []string `yaml:"hosts" json:"hosts"`
+
+	// Time of day (`HH:MM`, local time) to run the test at nightly. If empty,
+	// the test is only run on demand.
+	At string `yaml:"at" json:"at"`
+
+	// How long the test can run before failing (defaults to 30m).
+	Timeout string `yaml:"timeout" json:"timeout"`
+
+	Checks []Check `yaml:"checks" json:"checks"`
+}
+
+// Check is a user-defined check run in a VM via cc once the experiment has
+// started. It passes if the command succeeds and its output matches Expect (a
+// regular expression), if provided.
+type Check struct {
+	Name    string `yaml:"name" json:"name"`
+	VM      string `yaml:"vm" json:"vm"`
+	Command string `yaml:"command" json:"command"`
+	Expect  string `yaml:"expect" json:"expect"`
+}
+
+// Result is the outcome of a single smoke test run, as recorded in the smoke
+// test history.
+type Result struct {
+	Test       string        `json:"test"`
+	Experiment string        `json:"experiment"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Passed     bool          `json:"passed"`
+	Error      string        `json:"error,omitempty"`
+	SoH        []string      `json:"soh,omitempty"`
+	Checks     []CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single user-defined check.
+type CheckResult struct {
+	Name   string `json:"name"`
+	VM     string `json:"vm"`
+	Passed bool   `json:"passed"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Load reads the smoke tests defined in the given YAML file.
+func Load(path string) ([]Test, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading smoke test file %s: %w", path, err)
+	}
+
+	var tests Tests
+
+	if err := yaml.Unmarshal(body, &tests); err != nil {
+		return nil, fmt.Errorf("parsing smoke test file %s: %w", path, err)
+	}
+
+	seen := make(map[string]struct{})
+
+	for _, t := range tests.Tests {
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("invalid smoke test %s: %w", t.Name, err)
+		}
+
+		if _, ok := seen[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate smoke test %s", t.Name)
+		}
+
+		seen[t.Name] = struct{}{}
+	}
+
+	return tests.Tests, nil
+}
+
+func (this Test) validate() error {
+	if !nameRegex.MatchString(this.Name) {
+		return fmt.Errorf("name must only contain letters, numbers, underscores, and dashes")
+	}
+
+	if this.Topology == "" {
+		return fmt.Errorf("no topology provided")
+	}
+
+	if this.At != "" {
+		if _, err := time.Parse("15:04", this.At); err != nil {
+			return fmt.Errorf("invalid time of day %s (expected HH:MM)", this.At)
+		}
+	}
+
+	if _, err := this.timeout(); err != nil {
+		return err
+	}
+
+	for _, c := range this.Checks {
+		if c.VM == "" || c.Command == "" {
+			return fmt.Errorf("check %s must provide a VM and command", c.Name)
+		}
+
+		if _, err := regexp.Compile(c.Expect); err != nil {
+			return fmt.Errorf("invalid expect regex for check %s: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (this Test) timeout() (time.Duration, error) {
+	if this.Timeout == "" {
+		return 30 * time.Minute, nil
+	}
+
+	d, err := time.ParseDuration(this.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %s: %w", this.Timeout, err)
+	}
+
+	return d, nil
+}
+
+// experiment returns the name of the experiment created for the test.
+func (this Test) experiment() string {
+	return "smoke-" + this.Name
+}
+
+// next returns the next time after now the test is scheduled to run, or the
+// zero time if it isn't scheduled.
+func (this Test) next(now time.Time) time.Time {
+	at, err := time.ParseInLocation("15:04", this.At, now.Location())
+	if this.At == "" || err != nil {
+		return time.Time{}
+	}
+
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next
+}
@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"sort"
 	"strings"
 	"time"
 
 	"phenix/app"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	"phenix/util/journal"
 	"phenix/util/mm"
 	"phenix/util/plog"
 
@@ -20,10 +22,6 @@ import (
 	"github.com/olivere/elastic/v7"
 )
 
-func init() {
-	app.RegisterUserApp("soh", func() app.App { return newSOH() })
-}
-
 type SOH struct {
 	// App configuration metadata (from scenario config)
 	md sohMetadata
@@ -200,17 +198,43 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment) error {
 
 	if checks == nil { // default to all checks
 		checks = map[string]bool{
+			"liveness":            true,
 			"network-config":      true,
 			"reachability":        true,
 			"custom-reachability": true,
 			"processes":           true,
 			"ports":               true,
 			"custom":              true,
+			"windows":             true,
 			"cpu-load":            true,
 			"flows":               true,
 		}
 	}
 
+	// In liveness only mode, none of the checks requiring the C2 agent are run,
+	// making it a fast way to see which guests are up (even ones that can't run
+	// the agent).
+	if this.md.Liveness == "only" {
+		var errs bool
+
+		if checks["liveness"] {
+			errs = this.waitForLivenessTest(ctx, exp)
+			this.writeResults(exp)
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+
+		this.writeInitialized(exp)
+
+		if errs {
+			return fmt.Errorf("errors encountered in state of health app")
+		}
+
+		return nil
+	}
+
 	for _, node := range exp.Spec.Topology().Nodes() {
 		if node.External() {
 			// track IP addresses so custom reachability tests still work
@@ -378,6 +402,17 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment) error {
 		errs = errs || err
 	}
 
+	if this.md.Liveness == "on" && checks["liveness"] {
+		err := this.waitForLivenessTest(ctx, exp)
+		this.writeResults(exp)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		errs = errs || err
+	}
+
 	if checks["processes"] {
 		err := this.waitForProcTest(ctx, ns)
 		this.writeResults(exp)
@@ -411,6 +446,17 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment) error {
 		errs = errs || err
 	}
 
+	if checks["windows"] {
+		err := this.waitForWindowsTest(ctx, ns)
+		this.writeResults(exp)
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		errs = errs || err
+	}
+
 	if checks["cpu-load"] {
 		err := this.waitForCPULoad(ctx, ns)
 		this.writeResults(exp)
@@ -432,6 +478,7 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment) error {
 	}
 
 	this.writeInitialized(exp)
+	this.recordAlerts(exp)
 
 	if errs || wg.ErrCount > 0 {
 		return fmt.Errorf("errors encountered in state of health app")
@@ -601,6 +648,33 @@ func (this SOH) writeResults(exp *types.Experiment) {
 	exp.WriteToStore(true)
 }
 
+// recordAlerts adds an entry to the experiment timeline listing the hosts with
+// failed checks, if any.
+func (this SOH) recordAlerts(exp *types.Experiment) {
+	var hosts []string
+
+	for host, state := range this.status {
+		for _, s := range state.AllStates() {
+			if s.Error != "" {
+				hosts = append(hosts, host)
+				break
+			}
+		}
+	}
+
+	if len(hosts) == 0 {
+		return
+	}
+
+	sort.Strings(hosts)
+
+	journal.RecordWithDetails(
+		exp.Metadata.Name, journal.CategorySoH, "",
+		map[string]string{"hosts": strings.Join(hosts, ",")},
+		"SoH checks failed for %d host(s)", len(hosts),
+	)
+}
+
 func (this SOH) writeInitialized(exp *types.Experiment) {
 	// we do this to make sure we don't overwrite the existing app status
 	status := make(map[string]any)
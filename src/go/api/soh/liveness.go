@@ -0,0 +1,328 @@
+package soh
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/util/tap"
+
+	"inet.af/netaddr"
+)
+
+// Maximum number of guests probed at the same time on a single VLAN.
+const livenessConcurrency = 16
+
+// Guards host liveness states being added by concurrent probes.
+var livenessMu sync.Mutex
+
+var livenessResponsePatterns = map[string]*regexp.Regexp{
+	"ARP":  regexp.MustCompile(`(?i)received [1-9][0-9]* response|[1-9][0-9]* packets received`),
+	"ND":   regexp.MustCompile(`(?i)target link-layer address`),
+	"ICMP": regexp.MustCompile(`[1-9][0-9]* (packets )?received`),
+}
+
+type livenessTarget struct {
+	host string
+	addr netaddr.IP
+}
+
+// livenessSegment is a single subnet within an experiment VLAN to be swept
+// from a host-side tap.
+type livenessSegment struct {
+	vlan    string
+	bridge  string
+	subnet  netaddr.IPPrefix
+	targets []livenessTarget
+}
+
+type livenessProbe struct {
+	method string
+	cmd    string
+}
+
+// waitForLivenessTest sweeps each experiment VLAN from a temporary host-side
+// tap, probing each guest with ARP (IPv4) or neighbor discovery (IPv6) and
+// falling back to ICMP. Unlike the other state of health checks, this doesn't
+// require the C2 agent to be running in guests, so it works for black-box
+// appliance VMs too.
+func (this *SOH) waitForLivenessTest(ctx context.Context, exp *types.Experiment) bool {
+	var (
+		logger   = plog.LoggerFromContext(ctx)
+		ns       = exp.Spec.ExperimentName()
+		segments = this.livenessSegments(exp)
+		errs     bool
+	)
+
+	logger.Info("starting liveness sweep", "segments", len(segments))
+
+	cancel := periodicallyNotify(ctx, "waiting for liveness sweep to complete...", 5*time.Second)
+	defer cancel()
+
+	for _, seg := range segments {
+		if ctx.Err() != nil {
+			return true
+		}
+
+		if err := this.sweepSegment(ctx, ns, seg); err != nil {
+			logger.Error("unable to sweep VLAN for liveness", "vlan", seg.vlan, "subnet", seg.subnet, "err", err)
+
+			for _, t := range seg.targets {
+				this.addLivenessState(t.host, State{
+					Metadata:  map[string]interface{}{"host": t.host, "target": t.addr.String(), "vlan": seg.vlan},
+					Timestamp: time.Now().Format(time.RFC3339),
+					Error:     fmt.Sprintf("unable to sweep VLAN %s: %v", seg.vlan, err),
+				})
+			}
+
+			errs = true
+		}
+	}
+
+	for _, state := range this.status {
+		for _, s := range state.Liveness {
+			if s.Error != "" {
+				errs = true
+			}
+		}
+	}
+
+	return errs
+}
+
+// livenessSegments groups the statically addressed interfaces of each booted
+// VM in the experiment by VLAN and subnet.
+func (this *SOH) livenessSegments(exp *types.Experiment) []livenessSegment {
+	segments := make(map[string]*livenessSegment)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || *node.General().DoNotBoot() {
+			continue
+		}
+
+		host := node.General().Hostname()
+
+		if skip(node, this.md.SkipHosts) {
+			continue
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			if strings.EqualFold(iface.VLAN(), "MGMT") || iface.Type() == "serial" {
+				continue
+			}
+
+			addr := iface.Address()
+
+			// Use the address assigned via DHCP (as reported by minimega) if it's
+			// already been discovered by the network config test.
+			if ips, ok := this.hostIPs[host]; ok && ips[iface.Name()] != "" {
+				addr = ips[iface.Name()]
+			}
+
+			ip, err := netaddr.ParseIP(addr)
+			if err != nil {
+				continue
+			}
+
+			subnet, err := ip.Prefix(uint8(iface.Mask()))
+			if err != nil || iface.Mask() == 0 {
+				continue
+			}
+
+			key := iface.VLAN() + "|" + subnet.String()
+
+			seg, ok := segments[key]
+			if !ok {
+				bridge := iface.Bridge()
+				if bridge == "" {
+					bridge = exp.Spec.DefaultBridge()
+				}
+
+				seg = &livenessSegment{vlan: iface.VLAN(), bridge: bridge, subnet: subnet}
+				segments[key] = seg
+			}
+
+			seg.targets = append(seg.targets, livenessTarget{host: host, addr: ip})
+		}
+	}
+
+	var sorted []livenessSegment
+
+	for _, seg := range segments {
+		sorted = append(sorted, *seg)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].vlan == sorted[j].vlan {
+			return sorted[i].subnet.String() < sorted[j].subnet.String()
+		}
+
+		return sorted[i].vlan < sorted[j].vlan
+	})
+
+	return sorted
+}
+
+func (this *SOH) sweepSegment(ctx context.Context, ns string, seg livenessSegment) error {
+	var used []netaddr.IP
+
+	for _, t := range seg.targets {
+		used = append(used, t.addr)
+	}
+
+	addr, err := unusedAddress(seg.subnet, used)
+	if err != nil {
+		return err
+	}
+
+	// Create the tap on the same cluster host as one of the guests being probed
+	// so the VLAN is guaranteed to be present on the host's bridge.
+	host, err := mm.GetVMHost(mm.NS(ns), mm.VMName(seg.targets[0].host))
+	if err != nil {
+		return fmt.Errorf("getting cluster host for VM %s: %w", seg.targets[0].host, err)
+	}
+
+	t := &tap.Tap{
+		Name:   fmt.Sprintf("%s-soh", util.RandomString(11)),
+		Bridge: seg.bridge,
+		VLAN:   seg.vlan,
+		IP:     fmt.Sprintf("%s/%d", addr, seg.subnet.Bits()),
+	}
+
+	t.Init(seg.bridge, tap.Experiment(ns))
+
+	if _, err := t.Create(host); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := t.Delete(host); err != nil {
+			plog.Error("deleting liveness sweep tap", "tap", t.Name, "host", host, "err", err)
+		}
+	}()
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, livenessConcurrency)
+	)
+
+	for _, target := range seg.targets {
+		wg.Add(1)
+
+		go func(target livenessTarget) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			state := State{
+				Metadata:  map[string]interface{}{"host": target.host, "target": target.addr.String(), "vlan": seg.vlan},
+				Timestamp: time.Now().Format(time.RFC3339),
+			}
+
+			if method, ok := probeLiveness(ctx, host, t.Name, target.addr); ok {
+				state.Success = fmt.Sprintf("%s responded to %s on VLAN %s", target.addr, method, seg.vlan)
+			} else {
+				state.Error = fmt.Sprintf("%s did not respond on VLAN %s", target.addr, seg.vlan)
+			}
+
+			this.addLivenessState(target.host, state)
+		}(target)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (this *SOH) addLivenessState(host string, s State) {
+	livenessMu.Lock()
+	defer livenessMu.Unlock()
+
+	state, ok := this.status[host]
+	if !ok {
+		state = HostState{Hostname: host}
+	}
+
+	state.Liveness = append(state.Liveness, s)
+	this.status[host] = state
+}
+
+// probeLiveness runs each liveness probe for the given address from within the
+// network namespace of the given tap until one gets a response, returning the
+// probe method that got a response.
+func probeLiveness(ctx context.Context, host, netns string, addr netaddr.IP) (string, bool) {
+	for _, probe := range livenessProbes(netns, netns, addr) {
+		if ctx.Err() != nil {
+			return "", false
+		}
+
+		// Probe commands exit non-zero when there's no response, which minimega
+		// reports as an error.
+		resp, err := mm.MeshShellResponse(host, probe.cmd)
+		if err != nil {
+			continue
+		}
+
+		if livenessResponsePatterns[probe.method].MatchString(resp) {
+			return probe.method, true
+		}
+	}
+
+	return "", false
+}
+
+// livenessProbes returns the commands used to probe the given address from the
+// given network namespace and interface, in the order they should be tried.
+func livenessProbes(netns, iface string, addr netaddr.IP) []livenessProbe {
+	exec := "ip netns exec " + netns
+
+	if addr.Is6() {
+		return []livenessProbe{
+			{method: "ND", cmd: fmt.Sprintf("%s ndisc6 -1 -r 1 -w 1000 %s %s", exec, addr, iface)},
+			{method: "ICMP", cmd: fmt.Sprintf("%s ping -6 -c 1 -W 1 %s", exec, addr)},
+		}
+	}
+
+	return []livenessProbe{
+		{method: "ARP", cmd: fmt.Sprintf("%s arping -c 1 -w 1 -I %s %s", exec, iface, addr)},
+		{method: "ICMP", cmd: fmt.Sprintf("%s ping -c 1 -W 1 %s", exec, addr)},
+	}
+}
+
+// unusedAddress returns the highest host address in the given subnet not in
+// the given list of used addresses. The highest address is used since guests
+// are typically addressed from the bottom of the subnet up.
+func unusedAddress(subnet netaddr.IPPrefix, used []netaddr.IP) (netaddr.IP, error) {
+	var (
+		taken = make(map[netaddr.IP]struct{})
+		rng   = subnet.Masked().Range()
+	)
+
+	for _, ip := range used {
+		taken[ip] = struct{}{}
+	}
+
+	// Skip the broadcast address for IPv4 subnets.
+	ip := rng.To()
+
+	if ip.Is4() && subnet.Bits() < 31 {
+		ip = ip.Prior()
+	}
+
+	for ; rng.Contains(ip) && ip != rng.From(); ip = ip.Prior() {
+		if _, ok := taken[ip]; !ok {
+			return ip, nil
+		}
+	}
+
+	return netaddr.IP{}, fmt.Errorf("no unused addresses in subnet %s", subnet)
+}
@@ -0,0 +1,75 @@
+package soh
+
+import (
+	"strings"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+func TestUnusedAddress(t *testing.T) {
+	subnet := netaddr.MustParseIPPrefix("192.168.10.0/24")
+
+	used := []netaddr.IP{
+		netaddr.MustParseIP("192.168.10.1"),
+		netaddr.MustParseIP("192.168.10.254"),
+	}
+
+	addr, err := unusedAddress(subnet, used)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if addr.String() != "192.168.10.253" {
+		t.Errorf("expected 192.168.10.253, got %s", addr)
+	}
+
+	subnet = netaddr.MustParseIPPrefix("10.0.0.0/30")
+
+	used = []netaddr.IP{
+		netaddr.MustParseIP("10.0.0.1"),
+		netaddr.MustParseIP("10.0.0.2"),
+	}
+
+	if _, err := unusedAddress(subnet, used); err == nil {
+		t.Errorf("expected error for subnet with no unused addresses")
+	}
+}
+
+func TestLivenessProbes(t *testing.T) {
+	probes := livenessProbes("abc-soh", "abc-soh", netaddr.MustParseIP("10.0.0.5"))
+
+	if len(probes) != 2 || probes[0].method != "ARP" || probes[1].method != "ICMP" {
+		t.Fatalf("unexpected IPv4 probes: %+v", probes)
+	}
+
+	if !strings.HasPrefix(probes[0].cmd, "ip netns exec abc-soh arping") || !strings.Contains(probes[0].cmd, "-I abc-soh 10.0.0.5") {
+		t.Errorf("unexpected ARP probe command: %s", probes[0].cmd)
+	}
+
+	probes = livenessProbes("abc-soh", "abc-soh", netaddr.MustParseIP("fd00::5"))
+
+	if len(probes) != 2 || probes[0].method != "ND" || probes[1].method != "ICMP" {
+		t.Fatalf("unexpected IPv6 probes: %+v", probes)
+	}
+
+	responses := map[string]string{
+		"ARP":  "ARPING 10.0.0.5 from 10.0.0.254 abc-soh\nSent 1 probes (1 broadcast(s))\nReceived 1 response(s)",
+		"ICMP": "1 packets transmitted, 1 received, 0% packet loss, time 0ms",
+		"ND":   "Soliciting fd00::5 (fd00::5) on abc-soh...\nTarget link-layer address: 52:54:00:12:34:56",
+	}
+
+	for method, resp := range responses {
+		if !livenessResponsePatterns[method].MatchString(resp) {
+			t.Errorf("expected %s response to match: %s", method, resp)
+		}
+	}
+
+	if livenessResponsePatterns["ICMP"].MatchString("1 packets transmitted, 0 received, 100% packet loss") {
+		t.Errorf("expected failed ICMP response not to match")
+	}
+
+	if livenessResponsePatterns["ARP"].MatchString("Sent 1 probes (1 broadcast(s))\nReceived 0 response(s)") {
+		t.Errorf("expected failed ARP response not to match")
+	}
+}
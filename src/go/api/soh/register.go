@@ -0,0 +1,13 @@
+//go:build !nosoh
+
+package soh
+
+import (
+	"phenix/app"
+	"phenix/util/subsystem"
+)
+
+func init() {
+	app.RegisterUserApp("soh", func() app.App { return newSOH() })
+	subsystem.Register(subsystem.SOH)
+}
@@ -7,6 +7,7 @@ import (
 
 	"phenix/api/experiment"
 	"phenix/api/vm"
+	"phenix/types"
 
 	"github.com/mitchellh/mapstructure"
 )
@@ -41,28 +42,9 @@ func Get(expName, statusFilter string) (*Network, error) {
 		network.SOHInitialized = Initialized(exp)
 		network.SOHRunning = Running(exp)
 
-		if app, ok := exp.Status.AppStatus()["soh"]; ok {
-			data, ok := app.(map[string]interface{})
-			if !ok {
-				return nil, fmt.Errorf("unable to decode state of health details: %w", err)
-			}
-
-			var states []*HostState
-
-			if err := mapstructure.Decode(data["hosts"], &states); err != nil {
-				return nil, fmt.Errorf("unable to decode state of health host details: %w", err)
-			}
-
-			for _, state := range states {
-				for _, s := range state.AllStates() {
-					if s.Error != "" {
-						state.Errors = true
-						break
-					}
-				}
-
-				status[state.Hostname] = state
-			}
+		status, err = HostStates(exp)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -203,3 +185,39 @@ func GetFlows(name string) ([]string, [][]int, error) {
 
 	return packets.Hosts, packets.Flows, nil
 }
+
+// HostStates returns the state of health of each host in the given experiment
+// as last recorded by the SoH app, keyed by hostname. The returned map is
+// empty if the SoH app hasn't recorded any state.
+func HostStates(exp *types.Experiment) (map[string]*HostState, error) {
+	status := make(map[string]*HostState)
+
+	app, ok := exp.Status.AppStatus()["soh"]
+	if !ok {
+		return status, nil
+	}
+
+	data, ok := app.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to decode state of health details")
+	}
+
+	var states []*HostState
+
+	if err := mapstructure.Decode(data["hosts"], &states); err != nil {
+		return nil, fmt.Errorf("unable to decode state of health host details: %w", err)
+	}
+
+	for _, state := range states {
+		for _, s := range state.AllStates() {
+			if s.Error != "" {
+				state.Errors = true
+				break
+			}
+		}
+
+		status[state.Hostname] = state
+	}
+
+	return status, nil
+}
@@ -2,7 +2,9 @@ package soh
 
 import (
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 )
 
 type Font struct {
@@ -51,6 +53,8 @@ type HostState struct {
 	Processes    []State `json:"processes,omitempty" mapstructure:"processes,omitempty" structs:"processes,omitempty"`
 	Listeners    []State `json:"listeners,omitempty" mapstructure:"listeners,omitempty" structs:"listeners,omitempty"`
 	CustomTests  []State `json:"customTests,omitempty" mapstructure:"customTests,omitempty" structs:"customTests,omitempty"`
+	Windows      []State `json:"windows,omitempty" mapstructure:"windows,omitempty" structs:"windows,omitempty"`
+	Liveness     []State `json:"liveness,omitempty" mapstructure:"liveness,omitempty" structs:"liveness,omitempty"`
 
 	// populated before sending to UI client
 	Errors bool `json:"errors" mapstructure:"-" structs:"-"`
@@ -64,6 +68,8 @@ func (this HostState) AllStates() []State {
 	all = append(all, this.Processes...)
 	all = append(all, this.Listeners...)
 	all = append(all, this.CustomTests...)
+	all = append(all, this.Windows...)
+	all = append(all, this.Liveness...)
 
 	return all
 }
@@ -113,6 +119,60 @@ type customHostTest struct {
 	ValidateStderr string `mapstructure:"validateStderr"`
 }
 
+type WindowsCheckType string
+
+const (
+	WINDOWS_CHECK_SERVICE WindowsCheckType = "service"
+	WINDOWS_CHECK_DOMAIN  WindowsCheckType = "domain"
+	WINDOWS_CHECK_TIME    WindowsCheckType = "time"
+)
+
+// windowsCheck is a built-in check for Windows hosts. The `service` setting is
+// required for service checks. The `domain` setting is optional for domain
+// checks and, if set, the host must be a member of the given domain. The
+// `source` setting is optional for time checks and, if set, the W32Time
+// source must contain the given string.
+type windowsCheck struct {
+	Type    WindowsCheckType `mapstructure:"type"`
+	Service string           `mapstructure:"service"`
+	Domain  string           `mapstructure:"domain"`
+	Source  string           `mapstructure:"source"`
+}
+
+func (this windowsCheck) name() string {
+	switch this.Type {
+	case WINDOWS_CHECK_SERVICE:
+		return fmt.Sprintf("%s %s", this.Type, this.Service)
+	case WINDOWS_CHECK_DOMAIN:
+		if this.Domain != "" {
+			return fmt.Sprintf("%s %s", this.Type, this.Domain)
+		}
+	}
+
+	return string(this.Type)
+}
+
+// validate checks the settings of the Windows check. Service names are
+// interpolated into a PowerShell command, so they can't contain quotes
+// (PowerShell also treats typographic quotes as quotes) or escape characters.
+func (this windowsCheck) validate() error {
+	if this.Type != WINDOWS_CHECK_SERVICE {
+		return nil
+	}
+
+	if this.Service == "" {
+		return fmt.Errorf("no service provided for service check")
+	}
+
+	for _, r := range this.Service {
+		if strings.ContainsRune("'\"`‘’‚‛“”„", r) || unicode.IsControl(r) {
+			return fmt.Errorf("service %q for service check contains disallowed character %q", this.Service, r)
+		}
+	}
+
+	return nil
+}
+
 type sohMetadata struct {
 	AppProfileKey      string                      `mapstructure:"appMetadataProfileKey"`
 	C2Timeout          string                      `mapstructure:"c2Timeout"`
@@ -120,10 +180,12 @@ type sohMetadata struct {
 	HostListeners      map[string][]string         `mapstructure:"hostListeners"`
 	HostProcesses      map[string][]string         `mapstructure:"hostProcesses"`
 	CustomHostTests    map[string][]customHostTest `mapstructure:"hostCustomTests"`
+	WindowsHostChecks  map[string][]windowsCheck   `mapstructure:"hostWindowsChecks"`
 	InjectICMPAllow    bool                        `mapstructure:"injectICMPAllow"`
 	PacketCapture      packetCapture               `mapstructure:"packetCapture"`
 	Reachability       string                      `mapstructure:"testReachability"`
 	CustomReachability []customReachability        `mapstructure:"testCustomReachability"`
+	Liveness           string                      `mapstructure:"testLiveness"`
 	SkipNetworkConfig  bool                        `mapstructure:"skipInitialNetworkConfigTests"`
 	SkipHosts          []string                    `mapstructure:"skipHosts"`
 
@@ -159,6 +221,16 @@ func (this *sohMetadata) init() error {
 		this.InjectICMPAllow = false
 	}
 
+	switch this.Liveness {
+	case "":
+		// Default to liveness sweep being disabled if not specified in the
+		// scenario app config.
+		this.Liveness = "off"
+	case "off", "on", "only":
+	default:
+		return fmt.Errorf("invalid 'testLiveness' setting '%s' (must be off, on, or only)", this.Liveness)
+	}
+
 	if this.C2Timeout == "" {
 		// Default C2 timeout to 5m if not specified in the scenario app config.
 		this.c2Timeout = 5 * time.Minute
@@ -174,6 +246,14 @@ func (this *sohMetadata) init() error {
 		this.AppProfileKey = "sohProfile"
 	}
 
+	for host, checks := range this.WindowsHostChecks {
+		for _, check := range checks {
+			if err := check.validate(); err != nil {
+				return fmt.Errorf("invalid Windows check for host %s: %w", host, err)
+			}
+		}
+	}
+
 	this.uuidHosts = make(map[string]struct{})
 
 	if useUUID, ok := this.Other["hostsToUseUUIDForC2Active"]; ok {
@@ -213,11 +293,12 @@ func (this sohMetadata) useUUIDForC2Active(host string) bool {
 }
 
 type sohProfile struct {
-	C2Timeout   string           `mapstructure:"c2Timeout"`
-	Processes   []string         `mapstructure:"processes"`
-	Listeners   []string         `mapstructure:"listeners"`
-	CustomTests []customHostTest `mapstructure:"customTests"`
-	Captures    []string         `mapstructure:"captureInterfaces"`
+	C2Timeout     string           `mapstructure:"c2Timeout"`
+	Processes     []string         `mapstructure:"processes"`
+	Listeners     []string         `mapstructure:"listeners"`
+	CustomTests   []customHostTest `mapstructure:"customTests"`
+	WindowsChecks []windowsCheck   `mapstructure:"windowsChecks"`
+	Captures      []string         `mapstructure:"captureInterfaces"`
 
 	// set after parsing
 	c2Timeout time.Duration
@@ -1401,7 +1401,7 @@ func skip(node ifaces.NodeSpec, toSkip []string) bool {
 		// Check to see if this is a reference to an image. If so, skip this host if
 		// it's using the referenced image.
 		if ext := filepath.Ext(skipHost); ext == ".qc2" || ext == ".qcow2" {
-			if filepath.Base(node.Hardware().Drives()[0].Image()) == skipHost {
+			if drives := node.Hardware().Drives(); len(drives) > 0 && filepath.Base(drives[0].Image()) == skipHost {
 				return true
 			}
 		}
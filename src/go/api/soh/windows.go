@@ -0,0 +1,237 @@
+package soh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Commands used for built-in Windows checks.
+const (
+	windowsServiceStatus = `powershell -command "(Get-Service -Name '%s' -ErrorAction SilentlyContinue).Status"`
+	windowsDomainStatus  = `powershell -command "$cs = Get-WmiObject Win32_ComputerSystem; if ($cs.PartOfDomain) { $cs.Domain; Test-ComputerSecureChannel } else { 'WORKGROUP' }"`
+	windowsTimeStatus    = `w32tm /query /status`
+)
+
+func (this *SOH) waitForWindowsTest(ctx context.Context, ns string) bool {
+	var (
+		logger = plog.LoggerFromContext(ctx)
+		wg     = new(mm.StateGroup)
+	)
+
+	for host, checks := range this.md.WindowsHostChecks {
+		// If the host isn't in the C2 hosts map, then don't operate on it since it
+		// was likely skipped for a reason.
+		if _, ok := this.c2Hosts[host]; !ok {
+			logger.Debug("skipping host per config", "host", host)
+			continue
+		}
+
+		for _, check := range checks {
+			logger.Debug("running Windows check on host", "host", host, "check", check.name())
+			this.windowsTest(ctx, wg, ns, this.nodes[host], check)
+		}
+	}
+
+	// Check to see if any of the apps have hosts with metadata that include an SoH profile.
+	for _, app := range this.apps {
+		for _, host := range app.Hosts() {
+			if ms, ok := host.Metadata()[this.md.AppProfileKey]; ok {
+				if _, ok := this.c2Hosts[host.Hostname()]; !ok {
+					logger.Debug("skipping host per config", "host", host.Hostname())
+					continue
+				}
+
+				var profile sohProfile
+
+				if err := mapstructure.Decode(ms, &profile); err != nil {
+					logger.Warn("incorrect SoH profile for host in app", "host", host.Hostname(), "app", app.Name())
+					continue
+				}
+
+				for _, check := range profile.WindowsChecks {
+					logger.Debug("running Windows check on host", "host", host.Hostname(), "check", check.name())
+					this.windowsTest(ctx, wg, ns, this.nodes[host.Hostname()], check)
+				}
+			}
+		}
+	}
+
+	cancel := periodicallyNotify(ctx, "waiting for Windows checks to complete...", 5*time.Second)
+
+	wg.Wait()
+	cancel()
+
+	for _, state := range wg.States {
+		var (
+			host  = state.Meta["host"].(string)
+			check = state.Meta["check"].(string)
+		)
+
+		s := State{
+			Metadata:  state.Meta,
+			Timestamp: time.Now().Format(time.RFC3339),
+		}
+
+		if err := state.Err; err != nil {
+			if errors.Is(err, mm.ErrC2ClientNotActive) {
+				delete(this.c2Hosts, host)
+			}
+
+			s.Error = err.Error()
+
+			logger.Error("[✗] Windows check failed on host", "host", host, "check", check)
+		} else {
+			s.Success = state.Msg
+		}
+
+		state, ok := this.status[host]
+		if !ok {
+			state = HostState{Hostname: host}
+		}
+
+		state.Windows = append(state.Windows, s)
+		this.status[host] = state
+	}
+
+	return wg.ErrCount > 0
+}
+
+func (this SOH) windowsTest(ctx context.Context, wg *mm.StateGroup, ns string, node ifaces.NodeSpec, check windowsCheck) {
+	var (
+		host = node.General().Hostname()
+		meta = map[string]interface{}{"host": host, "check": check.name()}
+	)
+
+	if !strings.EqualFold(node.Hardware().OSType(), "windows") {
+		wg.AddError(fmt.Errorf("Windows checks not supported on %s hosts", node.Hardware().OSType()), meta)
+		return
+	}
+
+	var (
+		exec    string
+		retries = 5
+		verify  func(string) (string, error)
+	)
+
+	switch check.Type {
+	case WINDOWS_CHECK_SERVICE:
+		if err := check.validate(); err != nil {
+			wg.AddError(err, meta)
+			return
+		}
+
+		exec = fmt.Sprintf(windowsServiceStatus, check.Service)
+		verify = check.verifyService
+	case WINDOWS_CHECK_DOMAIN:
+		exec = windowsDomainStatus
+		verify = check.verifyDomain
+	case WINDOWS_CHECK_TIME:
+		exec = windowsTimeStatus
+		verify = check.verifyTime
+	default:
+		wg.AddError(fmt.Errorf("unknown Windows check type '%s'", check.Type), meta)
+		return
+	}
+
+	expected := func(resp string) error {
+		msg, err := verify(resp)
+		if err != nil {
+			// Services may still be starting, domain secure channels may still be
+			// getting established, and time may not have synced yet, so retry
+			// failures a few times before giving up.
+			if retries > 0 {
+				retries--
+				return mm.C2RetryError{Delay: 5 * time.Second}
+			}
+
+			return err
+		}
+
+		wg.AddSuccess(msg, meta)
+		return nil
+	}
+
+	cmd := this.newParallelCommand(ns, host, exec)
+	cmd.Wait = wg
+	cmd.Meta = meta
+	cmd.Expected = expected
+
+	mm.ScheduleC2ParallelCommand(ctx, cmd)
+}
+
+func (this windowsCheck) verifyService(resp string) (string, error) {
+	lines := trim(resp)
+
+	if len(lines) == 0 {
+		return "", fmt.Errorf("service %s not found", this.Service)
+	}
+
+	if status := lines[0]; !strings.EqualFold(status, "running") {
+		return "", fmt.Errorf("service %s not running (status: %s)", this.Service, status)
+	}
+
+	return fmt.Sprintf("service %s running", this.Service), nil
+}
+
+func (this windowsCheck) verifyDomain(resp string) (string, error) {
+	lines := trim(resp)
+
+	if len(lines) == 0 || strings.EqualFold(lines[0], "workgroup") {
+		return "", fmt.Errorf("host not joined to a domain")
+	}
+
+	domain := lines[0]
+
+	if this.Domain != "" && !strings.EqualFold(domain, this.Domain) {
+		return "", fmt.Errorf("host joined to domain %s (expected %s)", domain, this.Domain)
+	}
+
+	if len(lines) < 2 || !strings.EqualFold(lines[1], "true") {
+		return "", fmt.Errorf("secure channel to domain %s is not healthy", domain)
+	}
+
+	return fmt.Sprintf("joined to domain %s with healthy secure channel", domain), nil
+}
+
+func (this windowsCheck) verifyTime(resp string) (string, error) {
+	status := make(map[string]string)
+
+	for _, line := range trim(resp) {
+		if strings.Contains(line, "service has not been started") {
+			return "", fmt.Errorf("W32Time service not running")
+		}
+
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			status[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+
+	source, ok := status["source"]
+	if !ok {
+		return "", fmt.Errorf("unable to determine W32Time status")
+	}
+
+	if strings.Contains(status["leap indicator"], "not synchronized") {
+		return "", fmt.Errorf("time not synchronized")
+	}
+
+	switch strings.ToLower(source) {
+	case "local cmos clock", "free-running system clock":
+		return "", fmt.Errorf("time not synchronized to an external source (source: %s)", source)
+	}
+
+	if this.Source != "" && !strings.Contains(strings.ToLower(source), strings.ToLower(this.Source)) {
+		return "", fmt.Errorf("time synchronized to %s (expected %s)", source, this.Source)
+	}
+
+	return fmt.Sprintf("time synchronized to %s", source), nil
+}
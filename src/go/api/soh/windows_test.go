@@ -0,0 +1,86 @@
+package soh
+
+import "testing"
+
+func TestVerifyWindowsDomain(t *testing.T) {
+	tests := []struct {
+		check windowsCheck
+		resp  string
+		ok    bool
+	}{
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN}, "example.local\r\nTrue\r\n", true},
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN}, "example.local\r\nFalse\r\n", false},
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN}, "WORKGROUP\r\n", false},
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN, Domain: "EXAMPLE.LOCAL"}, "example.local\r\nTrue\r\n", true},
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN, Domain: "other.local"}, "example.local\r\nTrue\r\n", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.check.verifyDomain(tt.resp); (err == nil) != tt.ok {
+			t.Errorf("verifyDomain(%q) with domain %q: unexpected error %v", tt.resp, tt.check.Domain, err)
+		}
+	}
+}
+
+func TestVerifyWindowsTime(t *testing.T) {
+	synced := `Leap Indicator: 0(no warning)
+Stratum: 3 (secondary reference - syncd by (S)NTP)
+Precision: -23 (119.209ns per tick)
+Last Successful Sync Time: 3/1/2024 10:00:00 AM
+Source: dc01.example.local
+Poll Interval: 10 (1024s)`
+
+	unsynced := `Leap Indicator: 3(not synchronized)
+Stratum: 0 (unspecified)
+Last Successful Sync Time: unspecified
+Source: Local CMOS Clock`
+
+	tests := []struct {
+		check windowsCheck
+		resp  string
+		ok    bool
+	}{
+		{windowsCheck{Type: WINDOWS_CHECK_TIME}, synced, true},
+		{windowsCheck{Type: WINDOWS_CHECK_TIME, Source: "dc01"}, synced, true},
+		{windowsCheck{Type: WINDOWS_CHECK_TIME, Source: "dc02"}, synced, false},
+		{windowsCheck{Type: WINDOWS_CHECK_TIME}, unsynced, false},
+		{windowsCheck{Type: WINDOWS_CHECK_TIME}, "The following error occurred: The service has not been started. (0x80070426)", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.check.verifyTime(tt.resp); (err == nil) != tt.ok {
+			t.Errorf("verifyTime with source %q: unexpected error %v", tt.check.Source, err)
+		}
+	}
+}
+
+func TestValidateWindowsCheck(t *testing.T) {
+	tests := []struct {
+		check windowsCheck
+		ok    bool
+	}{
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE, Service: "W32Time"}, true},
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE, Service: "Windows Update"}, true},
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE}, false},
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE, Service: "foo'; Stop-Computer; '"}, false},
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE, Service: `foo" & shutdown /s & "`}, false},
+		{windowsCheck{Type: WINDOWS_CHECK_SERVICE, Service: "foo’; Stop-Computer; ’"}, false},
+		{windowsCheck{Type: WINDOWS_CHECK_DOMAIN, Domain: "example.local"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.check.validate(); (err == nil) != tt.ok {
+			t.Errorf("validate(%q): unexpected error %v", tt.check.Service, err)
+		}
+	}
+
+	md := sohMetadata{
+		WindowsHostChecks: map[string][]windowsCheck{
+			"dc01": {{Type: WINDOWS_CHECK_SERVICE, Service: "foo'bar"}},
+		},
+	}
+
+	if err := md.init(); err == nil {
+		t.Error("expected invalid Windows check to be rejected when metadata is initialized")
+	}
+}
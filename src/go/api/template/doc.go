@@ -0,0 +1,5 @@
+// Implementation of the phenix experiment template API. Templates allow users
+// without permission to create arbitrary experiments to instantiate
+// experiments from admin-approved topologies and scenarios with bounded
+// resources.
+package template
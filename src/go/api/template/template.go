@@ -0,0 +1,215 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/exp/slices"
+)
+
+// TemplateAnnotation is the experiment annotation used to record the template
+// an experiment was instantiated from.
+const TemplateAnnotation = "phenix/template"
+
+var (
+	ErrTemplateNotFound    = errors.New("template not found")
+	ErrParameterNotAllowed = errors.New("parameter not allowed by template")
+	ErrLimitExceeded       = errors.New("template limits exceeded")
+)
+
+// List returns all the templates in the store.
+func List() ([]types.Template, error) {
+	configs, err := store.List("Template")
+	if err != nil {
+		return nil, fmt.Errorf("getting list of template configs from store: %w", err)
+	}
+
+	var templates []types.Template
+
+	for _, c := range configs {
+		spec := new(v1.TemplateSpec)
+
+		if err := mapstructure.Decode(c.Spec, spec); err != nil {
+			return nil, fmt.Errorf("decoding template spec: %w", err)
+		}
+
+		templates = append(templates, types.Template{Metadata: c.Metadata, Spec: spec})
+	}
+
+	return templates, nil
+}
+
+// Get returns the template with the given name.
+func Get(name string) (*types.Template, error) {
+	c, _ := store.NewConfig("template/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	spec := new(v1.TemplateSpec)
+
+	if err := mapstructure.Decode(c.Spec, spec); err != nil {
+		return nil, fmt.Errorf("decoding template spec: %w", err)
+	}
+
+	return &types.Template{Metadata: c.Metadata, Spec: spec}, nil
+}
+
+type instantiateOptions struct {
+	name     string
+	topology string
+	scenario string
+	owner    string
+}
+
+type InstantiateOption func(*instantiateOptions)
+
+func newInstantiateOptions(opts ...InstantiateOption) instantiateOptions {
+	var o instantiateOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func InstantiateWithName(n string) InstantiateOption {
+	return func(o *instantiateOptions) {
+		o.name = n
+	}
+}
+
+// InstantiateWithTopology selects one of the template's topologies. It's only
+// required if the template includes more than one topology.
+func InstantiateWithTopology(t string) InstantiateOption {
+	return func(o *instantiateOptions) {
+		o.topology = t
+	}
+}
+
+// InstantiateWithScenario selects one of the template's scenarios. If not
+// provided, the experiment is created without a scenario.
+func InstantiateWithScenario(s string) InstantiateOption {
+	return func(o *instantiateOptions) {
+		o.scenario = s
+	}
+}
+
+func InstantiateWithOwner(u string) InstantiateOption {
+	return func(o *instantiateOptions) {
+		o.owner = u
+	}
+}
+
+// Instantiate creates a new experiment from the given template. The
+// parameters provided must be allowed by the template, and the resulting
+// experiment (after any scenario apps have been configured) must fall within
+// the template's limits. If it doesn't, the experiment is deleted and an error
+// describing each limit exceeded is returned.
+func Instantiate(ctx context.Context, name string, opts ...InstantiateOption) error {
+	tmpl, err := Get(name)
+	if err != nil {
+		return err
+	}
+
+	o := newInstantiateOptions(opts...)
+
+	if o.topology == "" && len(tmpl.Spec.Topologies) == 1 {
+		o.topology = tmpl.Spec.Topologies[0]
+	}
+
+	if !slices.Contains(tmpl.Spec.Topologies, o.topology) {
+		return fmt.Errorf("%w: topology '%s'", ErrParameterNotAllowed, o.topology)
+	}
+
+	if o.scenario != "" && !slices.Contains(tmpl.Spec.Scenarios, o.scenario) {
+		return fmt.Errorf("%w: scenario '%s'", ErrParameterNotAllowed, o.scenario)
+	}
+
+	create := []experiment.CreateOption{
+		experiment.CreateWithName(o.name),
+		experiment.CreateWithTopology(o.topology),
+		experiment.CreateWithScenario(o.scenario),
+		experiment.CreateWithVLANMin(tmpl.Spec.VLANMin),
+		experiment.CreateWithVLANMax(tmpl.Spec.VLANMax),
+		experiment.CreateWithAnnotations(map[string]string{TemplateAnnotation: name}),
+		experiment.CreateWithOwner(o.owner),
+	}
+
+	if err := experiment.Create(ctx, create...); err != nil {
+		return fmt.Errorf("creating experiment from template %s: %w", name, err)
+	}
+
+	exp, err := experiment.Get(o.name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", o.name, err)
+	}
+
+	if err := CheckLimits(tmpl.Spec.Limits, exp); err != nil {
+		if err := experiment.Delete(o.name); err != nil {
+			return fmt.Errorf("deleting experiment %s exceeding template limits: %w", o.name, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// CheckLimits checks the given experiment against the given template limits,
+// returning an error describing each limit exceeded.
+func CheckLimits(limits v1.TemplateLimits, exp *types.Experiment) error {
+	var (
+		errs error
+		vms  int
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || *node.General().DoNotBoot() {
+			continue
+		}
+
+		vms++
+
+		for _, drive := range node.Hardware().Drives() {
+			if drive.Image() == "" || imageAllowed(limits.AllowedImages, drive.Image()) {
+				continue
+			}
+
+			errs = multierror.Append(errs, fmt.Errorf("%w: image %s for VM %s is not allowed", ErrLimitExceeded, drive.Image(), node.General().Hostname()))
+		}
+	}
+
+	if limits.MaxVMs > 0 && vms > limits.MaxVMs {
+		errs = multierror.Append(errs, fmt.Errorf("%w: experiment has %d VMs (max %d)", ErrLimitExceeded, vms, limits.MaxVMs))
+	}
+
+	return errs
+}
+
+func imageAllowed(allowed []string, image string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	base := filepath.Base(image)
+
+	for _, pattern := range allowed {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+)
+
+func initStore(t *testing.T) {
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	initStore(t)
+
+	node := func(hostname, image string, dnb bool) map[string]any {
+		return map[string]any{
+			"type":     "VirtualMachine",
+			"general":  map[string]any{"hostname": hostname, "do_not_boot": dnb},
+			"hardware": map[string]any{"os_type": "linux", "drives": []any{map[string]any{"image": image}}},
+		}
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "sandbox"},
+		Spec: map[string]any{
+			"experimentName": "sandbox",
+			"topology": map[string]any{
+				"nodes": []any{
+					node("web", "ubuntu-22.04.qc2", false),
+					node("db", "/phenix/images/ubuntu-20.04.qc2", false),
+					node("attacker", "kali.qc2", false),
+					node("spare", "kali.qc2", true),
+				},
+			},
+		},
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	exp, err := experiment.Get("sandbox")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := CheckLimits(v1.TemplateLimits{MaxVMs: 3, AllowedImages: []string{"ubuntu-*.qc2", "kali.qc2"}}, exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// VMs that aren't booted don't count against the limits. Errors are wrapped
+	// in a multierror, which doesn't support errors.Is.
+	err = CheckLimits(v1.TemplateLimits{MaxVMs: 2, AllowedImages: []string{"ubuntu-*.qc2"}}, exp)
+	if err == nil {
+		t.Log("expected limit exceeded error")
+		t.FailNow()
+	}
+
+	if msg := err.Error(); !strings.Contains(msg, "3 VMs (max 2)") || !strings.Contains(msg, "kali.qc2 for VM attacker") || strings.Contains(msg, "spare") {
+		t.Logf("unexpected limit errors: %v", err)
+		t.FailNow()
+	}
+}
+
+func TestInstantiateParameters(t *testing.T) {
+	initStore(t)
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Template",
+		Metadata: store.ConfigMetadata{Name: "training"},
+		Spec: map[string]any{
+			"topologies": []any{"small", "large"},
+			"scenarios":  []any{"baseline"},
+			"limits":     map[string]any{"maxVMs": 10},
+		},
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	tmpl, err := Get("training")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if tmpl.Spec.Limits.MaxVMs != 10 || len(tmpl.Spec.Topologies) != 2 {
+		t.Logf("unexpected template spec: %+v", tmpl.Spec)
+		t.FailNow()
+	}
+
+	if _, err := Get("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Logf("expected template not found error, got %v", err)
+		t.FailNow()
+	}
+
+	ctx := context.Background()
+
+	// A topology has to be selected when the template includes more than one.
+	if err := Instantiate(ctx, "training", InstantiateWithName("foo")); !errors.Is(err, ErrParameterNotAllowed) {
+		t.Logf("expected parameter not allowed error without topology, got %v", err)
+		t.FailNow()
+	}
+
+	if err := Instantiate(ctx, "training", InstantiateWithName("foo"), InstantiateWithTopology("huge")); !errors.Is(err, ErrParameterNotAllowed) {
+		t.Logf("expected parameter not allowed error for topology, got %v", err)
+		t.FailNow()
+	}
+
+	err = Instantiate(ctx, "training", InstantiateWithName("foo"), InstantiateWithTopology("small"), InstantiateWithScenario("advanced"))
+	if !errors.Is(err, ErrParameterNotAllowed) {
+		t.Logf("expected parameter not allowed error for scenario, got %v", err)
+		t.FailNow()
+	}
+
+	if exps, _ := experiment.List(); len(exps) != 0 {
+		t.Logf("expected no experiments to be created, got %d", len(exps))
+		t.FailNow()
+	}
+}
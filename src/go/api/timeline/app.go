@@ -0,0 +1,109 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+
+	"phenix/app"
+	"phenix/types"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+func init() {
+	app.RegisterUserApp("timeline", func() app.App { return new(Timeline) })
+}
+
+// Timeline is the scenario app used to configure an experiment's exercise
+// timeline. The timeline itself is executed by the phenix daemon once the
+// experiment has started (see `Start`), so the app only validates the timeline
+// metadata and resets the timeline status each time the experiment starts.
+type Timeline struct {
+	options app.Options
+}
+
+func (this *Timeline) Init(opts ...app.Option) error {
+	this.options = app.NewOptions(opts...)
+	return nil
+}
+
+func (Timeline) Name() string {
+	return "timeline"
+}
+
+func (Timeline) Configure(ctx context.Context, exp *types.Experiment) error {
+	_, err := decodeMetadata(exp)
+	return err
+}
+
+func (Timeline) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Timeline) PostStart(ctx context.Context, exp *types.Experiment) error {
+	md, err := decodeMetadata(exp)
+	if err != nil {
+		return err
+	}
+
+	exp.Status.SetAppStatus("timeline", newStatus(md, Status{}))
+
+	return nil
+}
+
+func (Timeline) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Timeline) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func decodeMetadata(exp *types.Experiment) (timelineMetadata, error) {
+	var md timelineMetadata
+
+	app := exp.App("timeline")
+	if app == nil || app.Metadata() == nil {
+		return md, fmt.Errorf("timeline app must have metadata defined")
+	}
+
+	if err := mapstructure.Decode(app.Metadata(), &md); err != nil {
+		return md, fmt.Errorf("decoding app metadata: %w", err)
+	}
+
+	if err := md.init(); err != nil {
+		return md, fmt.Errorf("initializing app metadata: %w", err)
+	}
+
+	return md, nil
+}
+
+// newStatus creates a timeline status for the given metadata, carrying over
+// the state of any events from the previous status that have already been
+// executed or skipped.
+func newStatus(md timelineMetadata, prev Status) Status {
+	status := Status{Paused: prev.Paused, PausedFor: prev.PausedFor}
+
+	for _, e := range md.Events {
+		evt := Event{
+			Name:   e.Name,
+			At:     e.At,
+			Action: string(e.Action),
+			Host:   e.Host,
+			State:  EVENT_PENDING,
+		}
+
+		if p, ok := prev.event(e.Name); ok {
+			switch p.State {
+			case EVENT_DONE, EVENT_FAILED, EVENT_SKIPPED:
+				evt.State = p.State
+				evt.Executed = p.Executed
+				evt.Error = p.Error
+			}
+		}
+
+		status.Events = append(status.Events, evt)
+	}
+
+	return status
+}
@@ -0,0 +1,2 @@
+// Implementation of the phenix exercise timeline (MSEL) API.
+package timeline
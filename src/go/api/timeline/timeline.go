@@ -0,0 +1,379 @@
+package timeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/util/pubsub"
+)
+
+var (
+	ErrTimelineNotConfigured = errors.New("timeline not configured for experiment")
+	ErrTimelineNotActive     = errors.New("timeline not active for experiment")
+	ErrEventNotFound         = errors.New("timeline event not found")
+	ErrEventNotPending       = errors.New("timeline event not pending")
+)
+
+var (
+	runners   = make(map[string]*runner)
+	runnersMu sync.Mutex
+)
+
+func Configured(exp *types.Experiment) bool {
+	return exp.App("timeline") != nil
+}
+
+// Start begins executing the timeline configured for the given experiment (if
+// any) in a Goroutine that will exit when the given context is canceled. Event
+// offsets are relative to the experiment start time. If the experiment already
+// has timeline status (e.g. the UI server was restarted and is resuming the
+// timeline of a running experiment), events that were already executed or
+// skipped will not be executed again.
+func Start(ctx context.Context, wg *sync.WaitGroup, exp *types.Experiment) error {
+	if !Configured(exp) {
+		return nil
+	}
+
+	md, err := decodeMetadata(exp)
+	if err != nil {
+		return err
+	}
+
+	// The experiment provided may have been loaded before it was started, so get
+	// the latest status (including the start time) from the store.
+	exp, err = experiment.Get(exp.Metadata.Name)
+	if err != nil {
+		return fmt.Errorf("getting experiment: %w", err)
+	}
+
+	if exp.DryRun() {
+		plog.Info("skipping timeline since this is a dry run", "exp", exp.Metadata.Name)
+		return nil
+	}
+
+	start, err := time.Parse(time.RFC3339, exp.Status.StartTime())
+	if err != nil {
+		return fmt.Errorf("parsing experiment start time: %w", err)
+	}
+
+	var prev Status
+	exp.Status.ParseAppStatus("timeline", &prev)
+
+	r := &runner{
+		exp:    exp.Metadata.Name,
+		start:  start,
+		md:     md,
+		status: newStatus(md, prev),
+	}
+
+	if r.status.Paused {
+		r.pausedAt = time.Now()
+	}
+
+	runnersMu.Lock()
+	runners[r.exp] = r
+	runnersMu.Unlock()
+
+	plog.Info("[✓] starting timeline", "exp", r.exp, "events", len(md.Events))
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		r.run(ctx)
+
+		runnersMu.Lock()
+		delete(runners, r.exp)
+		runnersMu.Unlock()
+	}()
+
+	return nil
+}
+
+// Get returns the current timeline status for the given experiment. If the
+// timeline is currently active in this process, the live status is returned.
+// Otherwise, the status last persisted to the store is returned.
+func Get(expName string) (*Status, error) {
+	if r := getRunner(expName); r != nil {
+		r.Lock()
+		defer r.Unlock()
+
+		status := r.snapshot()
+		return &status, nil
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get experiment %s: %w", expName, err)
+	}
+
+	if !Configured(exp) {
+		return nil, ErrTimelineNotConfigured
+	}
+
+	md, err := decodeMetadata(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	var prev Status
+	exp.Status.ParseAppStatus("timeline", &prev)
+
+	status := newStatus(md, prev)
+	status.Elapsed = prev.Elapsed
+
+	return &status, nil
+}
+
+// Pause stops the timeline clock for the given experiment. Events will not be
+// executed while the timeline is paused, and the time spent paused does not
+// count towards event offsets.
+func Pause(expName string) error {
+	r := getRunner(expName)
+	if r == nil {
+		return ErrTimelineNotActive
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if r.status.Paused {
+		return nil
+	}
+
+	r.status.Paused = true
+	r.pausedAt = time.Now()
+
+	return r.persist()
+}
+
+// Resume restarts the timeline clock for the given experiment.
+func Resume(expName string) error {
+	r := getRunner(expName)
+	if r == nil {
+		return ErrTimelineNotActive
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.status.Paused {
+		return nil
+	}
+
+	r.status.Paused = false
+	r.status.PausedFor += time.Since(r.pausedAt).Seconds()
+
+	return r.persist()
+}
+
+// Skip marks the given pending event as skipped so it's never executed.
+func Skip(expName, event string) error {
+	r := getRunner(expName)
+	if r == nil {
+		return ErrTimelineNotActive
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	for i, e := range r.status.Events {
+		if e.Name != event {
+			continue
+		}
+
+		if e.State != EVENT_PENDING {
+			return ErrEventNotPending
+		}
+
+		r.status.Events[i].State = EVENT_SKIPPED
+
+		return r.persist()
+	}
+
+	return ErrEventNotFound
+}
+
+func getRunner(expName string) *runner {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+
+	return runners[expName]
+}
+
+type runner struct {
+	sync.Mutex
+
+	exp   string
+	start time.Time
+	md    timelineMetadata
+
+	status   Status
+	pausedAt time.Time
+}
+
+func (this *runner) run(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			this.fire(ctx)
+		}
+	}
+}
+
+// fire executes all pending events that are due.
+func (this *runner) fire(ctx context.Context) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.status.Paused {
+		return
+	}
+
+	var (
+		elapsed = this.elapsed()
+		fired   bool
+	)
+
+	for i, e := range this.md.Events {
+		if e.at > elapsed {
+			// events are sorted by offset, so nothing else is due yet
+			break
+		}
+
+		if this.status.Events[i].State != EVENT_PENDING {
+			continue
+		}
+
+		this.status.Events[i].State = EVENT_RUNNING
+		fired = true
+
+		go this.execute(ctx, i, e)
+	}
+
+	if fired {
+		if err := this.persist(); err != nil {
+			plog.Error("persisting timeline status", "exp", this.exp, "err", err)
+		}
+	}
+}
+
+func (this *runner) execute(ctx context.Context, idx int, e event) {
+	plog.Info("executing timeline event", "exp", this.exp, "event", e.Name, "action", e.Action, "host", e.Host)
+
+	err := execute(ctx, this.exp, e)
+
+	this.Lock()
+	defer this.Unlock()
+
+	evt := &this.status.Events[idx]
+	evt.Executed = time.Now().Format(time.RFC3339)
+
+	if err != nil {
+		plog.Error("[✗] timeline event failed", "exp", this.exp, "event", e.Name, "err", err)
+
+		evt.State = EVENT_FAILED
+		evt.Error = err.Error()
+	} else {
+		plog.Info("[✓] timeline event complete", "exp", this.exp, "event", e.Name)
+
+		evt.State = EVENT_DONE
+	}
+
+	if err := this.persist(); err != nil {
+		plog.Error("persisting timeline status", "exp", this.exp, "err", err)
+	}
+}
+
+// elapsed returns the amount of timeline time that has passed since the
+// experiment started. The caller must hold the runner lock.
+func (this *runner) elapsed() time.Duration {
+	paused := time.Duration(this.status.PausedFor * float64(time.Second))
+
+	if this.status.Paused {
+		paused += time.Since(this.pausedAt)
+	}
+
+	return time.Since(this.start) - paused
+}
+
+// snapshot returns a copy of the current timeline status. The caller must hold
+// the runner lock.
+func (this *runner) snapshot() Status {
+	status := this.status
+
+	status.Elapsed = this.elapsed().Round(time.Second).String()
+	status.Events = make([]Event, len(this.status.Events))
+
+	copy(status.Events, this.status.Events)
+
+	return status
+}
+
+// persist writes the current timeline status to the store. The caller must
+// hold the runner lock.
+func (this *runner) persist() error {
+	exp, err := experiment.Get(this.exp)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", this.exp, err)
+	}
+
+	exp.Status.SetAppStatus("timeline", this.snapshot())
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("writing timeline status to store: %w", err)
+	}
+
+	return nil
+}
+
+func execute(ctx context.Context, ns string, e event) error {
+	switch e.Action {
+	case ACTION_CC:
+		opts := []mm.C2Option{mm.C2NS(ns), mm.C2VM(e.Host), mm.C2Command(e.Command), mm.C2Context(ctx)}
+
+		if _, err := mm.ExecC2Command(opts...); err != nil {
+			return fmt.Errorf("executing command on %s: %w", e.Host, err)
+		}
+	case ACTION_TRAFFIC:
+		// Traffic generators are expected to run until the experiment is stopped,
+		// so they're started in the background.
+		opts := []mm.C2Option{mm.C2NS(ns), mm.C2VM(e.Host), mm.C2Command(e.Command), mm.C2Context(ctx), mm.C2Background()}
+
+		if _, err := mm.ExecC2Command(opts...); err != nil {
+			return fmt.Errorf("starting traffic on %s: %w", e.Host, err)
+		}
+	case ACTION_LINK:
+		if e.VLAN == "" {
+			return vm.Disonnect(ns, e.Host, e.Interface)
+		}
+
+		return vm.Connect(ns, e.Host, e.Interface, e.VLAN)
+	case ACTION_REVEAL:
+		// Revealed nodes are expected to be configured with a `user` delay so
+		// they're not started when the experiment starts.
+		if err := mm.StartVM(mm.NS(ns), mm.VMName(e.Host)); err != nil {
+			return fmt.Errorf("starting VM %s: %w", e.Host, err)
+		}
+
+		pubsub.Publish("delayed-start", fmt.Sprintf("%s/%s", ns, e.Host))
+	default:
+		return fmt.Errorf("unknown action %s", e.Action)
+	}
+
+	return nil
+}
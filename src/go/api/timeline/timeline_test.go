@@ -0,0 +1,190 @@
+package timeline
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/store"
+)
+
+func initExperiment(t *testing.T) {
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	metadata := map[string]any{
+		"events": []any{
+			map[string]any{"name": "later", "at": "1h", "action": "reveal", "host": "web"},
+			map[string]any{"name": "kickoff", "at": "0s", "action": "cc", "host": "web", "command": "echo hello"},
+		},
+	}
+
+	// Status of a running experiment whose timeline already executed its first
+	// event before the server was restarted.
+	status := map[string]any{
+		"startTime": time.Now().Add(-1 * time.Minute).Format(time.RFC3339),
+		"apps": map[string]any{
+			"timeline": map[string]any{
+				"paused": false,
+				"events": []any{
+					map[string]any{"name": "kickoff", "state": "done", "executed": "2026-01-01T00:00:00Z"},
+					map[string]any{"name": "later", "state": "pending"},
+				},
+			},
+		},
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "exercise"},
+		Spec: map[string]any{
+			"experimentName": "exercise",
+			"topology":       map[string]any{"nodes": []any{}},
+			"scenario": map[string]any{
+				"apps": []any{map[string]any{"name": "timeline", "metadata": metadata}},
+			},
+		},
+		Status: status,
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestStartResumesTimeline(t *testing.T) {
+	initExperiment(t)
+
+	exp, err := experiment.Get("exercise")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := Pause("exercise"); !errors.Is(err, ErrTimelineNotActive) {
+		t.Logf("expected timeline not active error before start, got %v", err)
+		t.FailNow()
+	}
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		wg          sync.WaitGroup
+	)
+
+	defer cancel()
+
+	if err := Start(ctx, &wg, exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	status, err := Get("exercise")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// events are sorted by offset, and already executed events aren't executed
+	// again once the timeline is resumed
+	if len(status.Events) != 2 || status.Events[0].Name != "kickoff" || status.Events[0].State != EVENT_DONE {
+		t.Logf("expected executed event to be carried over, got %+v", status.Events)
+		t.FailNow()
+	}
+
+	if status.Events[1].State != EVENT_PENDING {
+		t.Logf("expected later event to still be pending, got %+v", status.Events[1])
+		t.FailNow()
+	}
+
+	if err := Skip("exercise", "kickoff"); !errors.Is(err, ErrEventNotPending) {
+		t.Logf("expected event not pending error, got %v", err)
+		t.FailNow()
+	}
+
+	if err := Skip("exercise", "missing"); !errors.Is(err, ErrEventNotFound) {
+		t.Logf("expected event not found error, got %v", err)
+		t.FailNow()
+	}
+
+	if err := Pause("exercise"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if status, _ := Get("exercise"); !status.Paused {
+		t.Log("expected timeline to be paused")
+		t.FailNow()
+	}
+
+	if err := Skip("exercise", "later"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := Resume("exercise"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	cancel()
+	wg.Wait()
+
+	if err := Resume("exercise"); !errors.Is(err, ErrTimelineNotActive) {
+		t.Logf("expected timeline not active error after stop, got %v", err)
+		t.FailNow()
+	}
+
+	// once the timeline is no longer active, the status persisted to the store
+	// is returned
+	status, err = Get("exercise")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if status.Paused || status.Events[0].State != EVENT_DONE || status.Events[1].State != EVENT_SKIPPED {
+		t.Logf("unexpected persisted timeline status: %+v", status)
+		t.FailNow()
+	}
+}
+
+func TestMetadataInit(t *testing.T) {
+	md := timelineMetadata{
+		Events: []event{
+			{Name: "b", At: "10m", Action: ACTION_LINK, Host: "web"},
+			{Name: "a", At: "5m", Action: ACTION_CC, Host: "web", Command: "whoami"},
+		},
+	}
+
+	if err := md.init(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if md.Events[0].Name != "a" || md.Events[0].at != 5*time.Minute {
+		t.Logf("expected events to be sorted by offset, got %+v", md.Events)
+		t.FailNow()
+	}
+
+	md.Events = append(md.Events, event{Name: "a", At: "1m", Action: ACTION_REVEAL, Host: "web"})
+
+	if err := md.init(); err == nil {
+		t.Log("expected duplicate event name to be rejected")
+		t.FailNow()
+	}
+
+	md.Events = []event{{Name: "c", At: "1m", Action: ACTION_TRAFFIC, Host: "web"}}
+
+	if err := md.init(); err == nil {
+		t.Log("expected traffic event without command to be rejected")
+		t.FailNow()
+	}
+}
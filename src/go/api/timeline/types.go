@@ -0,0 +1,126 @@
+package timeline
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Action string
+
+const (
+	ACTION_CC      Action = "cc"
+	ACTION_TRAFFIC Action = "traffic"
+	ACTION_LINK    Action = "link"
+	ACTION_REVEAL  Action = "reveal"
+)
+
+type EventState string
+
+const (
+	EVENT_PENDING EventState = "pending"
+	EVENT_RUNNING EventState = "running"
+	EVENT_DONE    EventState = "done"
+	EVENT_FAILED  EventState = "failed"
+	EVENT_SKIPPED EventState = "skipped"
+)
+
+type event struct {
+	Name   string `mapstructure:"name"`
+	At     string `mapstructure:"at"`
+	Action Action `mapstructure:"action"`
+	Host   string `mapstructure:"host"`
+
+	// used by cc and traffic events
+	Command string `mapstructure:"command"`
+
+	// used by link events -- an empty VLAN disconnects the interface
+	Interface int    `mapstructure:"interface"`
+	VLAN      string `mapstructure:"vlan"`
+
+	// set after parsing
+	at time.Duration
+}
+
+type timelineMetadata struct {
+	Events []event `mapstructure:"events"`
+}
+
+func (this *timelineMetadata) init() error {
+	names := make(map[string]struct{})
+
+	for i, e := range this.Events {
+		if e.Name == "" {
+			return fmt.Errorf("event %d is missing a name", i)
+		}
+
+		if _, ok := names[e.Name]; ok {
+			return fmt.Errorf("duplicate event name %s", e.Name)
+		}
+
+		names[e.Name] = struct{}{}
+
+		var err error
+
+		if e.at, err = time.ParseDuration(e.At); err != nil {
+			return fmt.Errorf("parsing time offset '%s' for event %s: %w", e.At, e.Name, err)
+		}
+
+		if e.Host == "" {
+			return fmt.Errorf("event %s is missing a host", e.Name)
+		}
+
+		switch e.Action {
+		case ACTION_CC, ACTION_TRAFFIC:
+			if e.Command == "" {
+				return fmt.Errorf("%s event %s must specify a command", e.Action, e.Name)
+			}
+		case ACTION_LINK, ACTION_REVEAL:
+		default:
+			return fmt.Errorf("unknown action '%s' for event %s", e.Action, e.Name)
+		}
+
+		this.Events[i] = e
+	}
+
+	// keep events in the order they're due
+	sort.SliceStable(this.Events, func(i, j int) bool {
+		return this.Events[i].at < this.Events[j].at
+	})
+
+	return nil
+}
+
+type Event struct {
+	Name     string     `json:"name" mapstructure:"name" structs:"name"`
+	At       string     `json:"at" mapstructure:"at" structs:"at"`
+	Action   string     `json:"action" mapstructure:"action" structs:"action"`
+	Host     string     `json:"host" mapstructure:"host" structs:"host"`
+	State    EventState `json:"state" mapstructure:"state" structs:"state"`
+	Executed string     `json:"executed,omitempty" mapstructure:"executed" structs:"executed"`
+	Error    string     `json:"error,omitempty" mapstructure:"error" structs:"error"`
+}
+
+// Status is the current state of an experiment timeline. It's persisted to the
+// experiment status under the `timeline` app key.
+type Status struct {
+	Paused bool `json:"paused" mapstructure:"paused" structs:"paused"`
+
+	// Elapsed is the amount of timeline time (which doesn't advance while the
+	// timeline is paused) that has passed since the experiment was started.
+	Elapsed string  `json:"elapsed" mapstructure:"elapsed" structs:"elapsed"`
+	Events  []Event `json:"events" mapstructure:"events" structs:"events"`
+
+	// Total amount of time the timeline has been paused for, in seconds.
+	PausedFor float64 `json:"-" mapstructure:"paused_for" structs:"paused_for"`
+}
+
+func (this Status) event(name string) (Event, bool) {
+	for _, e := range this.Events {
+		if e.Name == name {
+			return e, true
+		}
+	}
+
+	return Event{}, false
+}
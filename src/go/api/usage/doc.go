@@ -0,0 +1,2 @@
+// Implementation of the phenix resource usage (chargeback) reporting API.
+package usage
@@ -0,0 +1,49 @@
+package usage
+
+import "time"
+
+type GroupBy string
+
+const (
+	GROUP_BY_USER      GroupBy = "user"
+	GROUP_BY_NAMESPACE GroupBy = "namespace"
+)
+
+// Resources are the resources allocated to an experiment while it's running.
+// Memory is in MB and disk is in bytes.
+type Resources struct {
+	VMs    int   `json:"vms"`
+	VCPUs  int   `json:"vcpus"`
+	Memory int   `json:"memory"`
+	Disk   int64 `json:"disk"`
+}
+
+// Session is a single (possibly ongoing) run of an experiment, from the time
+// it was started to the time it was stopped.
+type Session struct {
+	Experiment string    `json:"experiment"`
+	Namespace  string    `json:"namespace"`
+	Owner      string    `json:"owner"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Running    bool      `json:"running"`
+	Resources  Resources `json:"resources"`
+}
+
+// Entry is the resource usage for a single user or namespace over the
+// reporting period.
+type Entry struct {
+	Name          string   `json:"name"`
+	Experiments   []string `json:"experiments"`
+	Hours         float64  `json:"hours"`
+	VCPUHours     float64  `json:"vcpu_hours"`
+	MemoryGBHours float64  `json:"memory_gb_hours"`
+	DiskGBHours   float64  `json:"disk_gb_hours"`
+}
+
+type Report struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupBy GroupBy   `json:"group_by"`
+	Entries []Entry   `json:"entries"`
+}
@@ -0,0 +1,341 @@
+package usage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"phenix/api/cluster"
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/plog"
+
+	"golang.org/x/exp/slices"
+)
+
+const unknownOwner = "unknown"
+
+func init() {
+	experiment.RegisterHook("start", func(stage, name string) {
+		if err := record(name, "start"); err != nil {
+			plog.Error("recording experiment usage", "exp", name, "action", "start", "err", err)
+		}
+	})
+
+	experiment.RegisterHook("stop", func(stage, name string) {
+		if err := record(name, "stop"); err != nil {
+			plog.Error("recording experiment usage", "exp", name, "action", "stop", "err", err)
+		}
+	})
+}
+
+type reportOptions struct {
+	from      time.Time
+	to        time.Time
+	groupBy   GroupBy
+	user      string
+	namespace string
+}
+
+type ReportOption func(*reportOptions)
+
+func newReportOptions(opts ...ReportOption) reportOptions {
+	now := time.Now()
+
+	o := reportOptions{
+		from:    time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()),
+		to:      now,
+		groupBy: GROUP_BY_USER,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ReportForMonth limits the report to the calendar month containing the given
+// time. This is the default, using the current month.
+func ReportForMonth(t time.Time) ReportOption {
+	return func(o *reportOptions) {
+		o.from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		o.to = o.from.AddDate(0, 1, 0)
+
+		if now := time.Now(); o.to.After(now) {
+			o.to = now
+		}
+	}
+}
+
+// ReportForWindow limits the report to the rolling window of the given
+// duration ending now.
+func ReportForWindow(d time.Duration) ReportOption {
+	return func(o *reportOptions) {
+		o.to = time.Now()
+		o.from = o.to.Add(-d)
+	}
+}
+
+func ReportGroupBy(g GroupBy) ReportOption {
+	return func(o *reportOptions) {
+		if g != "" {
+			o.groupBy = g
+		}
+	}
+}
+
+func ReportForUser(u string) ReportOption {
+	return func(o *reportOptions) {
+		o.user = u
+	}
+}
+
+func ReportForNamespace(ns string) ReportOption {
+	return func(o *reportOptions) {
+		o.namespace = ns
+	}
+}
+
+// Generate builds a resource usage report from the usage events recorded
+// each time an experiment is started and stopped. Experiments still running
+// are accounted for up to the end of the reporting period.
+func Generate(opts ...ReportOption) (*Report, error) {
+	o := newReportOptions(opts...)
+
+	if o.groupBy != GROUP_BY_USER && o.groupBy != GROUP_BY_NAMESPACE {
+		return nil, fmt.Errorf("unknown report grouping '%s'", o.groupBy)
+	}
+
+	if !o.from.Before(o.to) {
+		return nil, fmt.Errorf("invalid reporting period (%s - %s)", o.from.Format(time.RFC3339), o.to.Format(time.RFC3339))
+	}
+
+	events, err := store.GetEventsBy(store.Event{Type: store.EventTypeUsage})
+	if err != nil {
+		return nil, fmt.Errorf("getting usage events: %w", err)
+	}
+
+	return report(Sessions(events, time.Now()), o), nil
+}
+
+// Sessions pairs up the given experiment start and stop usage events into
+// sessions. Experiments that are still running have their session end set to
+// the given time. A start event without a matching stop event (ie. phenix was
+// killed while the experiment was running) is closed out by the next start
+// event for the same experiment.
+func Sessions(events store.Events, now time.Time) []Session {
+	var (
+		open     = make(map[string]*Session)
+		sessions []Session
+	)
+
+	events.SortByTimestamp(true)
+
+	for _, event := range events {
+		if event.Type != store.EventTypeUsage {
+			continue
+		}
+
+		name := event.Metadata["experiment"]
+
+		if name == "" {
+			continue
+		}
+
+		if s, ok := open[name]; ok {
+			s.End = event.Timestamp
+			sessions = append(sessions, *s)
+
+			delete(open, name)
+		}
+
+		if event.Metadata["action"] != "start" {
+			continue
+		}
+
+		s := &Session{
+			Experiment: name,
+			Namespace:  event.Metadata["namespace"],
+			Owner:      event.Metadata["owner"],
+			Start:      event.Timestamp,
+		}
+
+		s.Resources.VMs, _ = strconv.Atoi(event.Metadata["vms"])
+		s.Resources.VCPUs, _ = strconv.Atoi(event.Metadata["vcpus"])
+		s.Resources.Memory, _ = strconv.Atoi(event.Metadata["memory"])
+		s.Resources.Disk, _ = strconv.ParseInt(event.Metadata["disk"], 10, 64)
+
+		if s.Namespace == "" {
+			s.Namespace = name
+		}
+
+		if s.Owner == "" {
+			s.Owner = unknownOwner
+		}
+
+		open[name] = s
+	}
+
+	for _, s := range open {
+		s.End = now
+		s.Running = true
+
+		sessions = append(sessions, *s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+
+	return sessions
+}
+
+func report(sessions []Session, o reportOptions) *Report {
+	var (
+		entries = make(map[string]*Entry)
+		rpt     = &Report{From: o.from, To: o.to, GroupBy: o.groupBy}
+	)
+
+	for _, s := range sessions {
+		if o.user != "" && s.Owner != o.user {
+			continue
+		}
+
+		if o.namespace != "" && s.Namespace != o.namespace {
+			continue
+		}
+
+		var (
+			start = s.Start
+			end   = s.End
+		)
+
+		// Only account for the portion of the session within the reporting period.
+		if start.Before(o.from) {
+			start = o.from
+		}
+
+		if end.After(o.to) {
+			end = o.to
+		}
+
+		if !start.Before(end) {
+			continue
+		}
+
+		key := s.Owner
+
+		if o.groupBy == GROUP_BY_NAMESPACE {
+			key = s.Namespace
+		}
+
+		entry, ok := entries[key]
+		if !ok {
+			entry = &Entry{Name: key}
+			entries[key] = entry
+		}
+
+		if !slices.Contains(entry.Experiments, s.Experiment) {
+			entry.Experiments = append(entry.Experiments, s.Experiment)
+		}
+
+		hours := end.Sub(start).Hours()
+
+		entry.Hours += hours
+		entry.VCPUHours += hours * float64(s.Resources.VCPUs)
+		entry.MemoryGBHours += hours * float64(s.Resources.Memory) / 1024
+		entry.DiskGBHours += hours * float64(s.Resources.Disk) / (1024 * 1024 * 1024)
+	}
+
+	for _, entry := range entries {
+		sort.Strings(entry.Experiments)
+		rpt.Entries = append(rpt.Entries, *entry)
+	}
+
+	sort.Slice(rpt.Entries, func(i, j int) bool {
+		return rpt.Entries[i].Name < rpt.Entries[j].Name
+	})
+
+	return rpt
+}
+
+func record(name, action string) error {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	// Dry runs don't consume any cluster resources.
+	if action == "start" && exp.DryRun() {
+		return nil
+	}
+
+	owner := exp.Metadata.Annotations[experiment.OwnerAnnotation]
+
+	if owner == "" {
+		owner = unknownOwner
+	}
+
+	event := store.NewEvent("experiment %s %s", name, action).
+		WithMetadata("experiment", name).
+		WithMetadata("namespace", exp.Spec.ExperimentName()).
+		WithMetadata("owner", owner).
+		WithMetadata("action", action)
+
+	event.Type = store.EventTypeUsage
+
+	if action == "start" {
+		res := resources(exp)
+
+		event.
+			WithMetadata("vms", strconv.Itoa(res.VMs)).
+			WithMetadata("vcpus", strconv.Itoa(res.VCPUs)).
+			WithMetadata("memory", strconv.Itoa(res.Memory)).
+			WithMetadata("disk", strconv.FormatInt(res.Disk, 10))
+	}
+
+	return store.AddEvent(*event)
+}
+
+// resources totals the resources allocated to the VMs that get booted in the
+// given experiment. Disk usage is the size of each VM's disk images as
+// reported by minimega, so it's a best-effort value.
+func resources(exp *types.Experiment) Resources {
+	var (
+		res   Resources
+		sizes = make(map[string]int64)
+	)
+
+	images, err := cluster.GetImages(exp.Spec.ExperimentName(), cluster.VM_IMAGE)
+	if err != nil {
+		plog.Warn("unable to get image sizes for usage reporting", "exp", exp.Spec.ExperimentName(), "err", err)
+	}
+
+	for _, img := range images {
+		sizes[img.Name] = int64(img.Size)
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || *node.General().DoNotBoot() {
+			continue
+		}
+
+		res.VMs++
+		res.VCPUs += node.Hardware().VCPU()
+		res.Memory += node.Hardware().Memory()
+
+		for _, drive := range node.Hardware().Drives() {
+			if drive.Image() == "" {
+				continue
+			}
+
+			res.Disk += sizes[filepath.Base(drive.Image())]
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,116 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"phenix/store"
+)
+
+func usageEvent(ts time.Time, exp, owner, action string) store.Event {
+	event := store.Event{
+		Timestamp: ts,
+		Type:      store.EventTypeUsage,
+		Metadata: map[string]string{
+			"experiment": exp,
+			"namespace":  exp,
+			"owner":      owner,
+			"action":     action,
+		},
+	}
+
+	if action == "start" {
+		event.Metadata["vcpus"] = "4"
+		event.Metadata["memory"] = "2048"
+		event.Metadata["disk"] = "1073741824"
+	}
+
+	return event
+}
+
+func TestSessions(t *testing.T) {
+	var (
+		base = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		now  = base.Add(10 * time.Hour)
+	)
+
+	events := store.Events{
+		usageEvent(base.Add(3*time.Hour), "foo", "alice", "stop"),
+		usageEvent(base, "foo", "alice", "start"),
+		usageEvent(base.Add(1*time.Hour), "bar", "bob", "start"),
+		usageEvent(base.Add(4*time.Hour), "foo", "alice", "start"),
+		// missed stop event for second run of foo
+		usageEvent(base.Add(6*time.Hour), "foo", "alice", "start"),
+		// stop without a start (ie. dry run) is ignored
+		usageEvent(base.Add(7*time.Hour), "baz", "bob", "stop"),
+	}
+
+	sessions := Sessions(events, now)
+
+	if len(sessions) != 4 {
+		t.Fatalf("expected 4 sessions, got %d", len(sessions))
+	}
+
+	expected := []struct {
+		exp     string
+		hours   float64
+		running bool
+	}{
+		{"foo", 3, false},
+		{"bar", 9, true},
+		{"foo", 2, false},
+		{"foo", 4, true},
+	}
+
+	for i, e := range expected {
+		s := sessions[i]
+
+		if s.Experiment != e.exp || s.End.Sub(s.Start).Hours() != e.hours || s.Running != e.running {
+			t.Errorf("unexpected session %d: %+v", i, s)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	var (
+		base = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		now  = base.Add(10 * time.Hour)
+	)
+
+	events := store.Events{
+		// started before the reporting period
+		usageEvent(base.Add(-2*time.Hour), "foo", "alice", "start"),
+		usageEvent(base.Add(2*time.Hour), "foo", "alice", "stop"),
+		usageEvent(base.Add(1*time.Hour), "bar", "alice", "start"),
+		usageEvent(base.Add(5*time.Hour), "bar", "alice", "stop"),
+		usageEvent(base.Add(1*time.Hour), "baz", "bob", "start"),
+	}
+
+	sessions := Sessions(events, now)
+
+	rpt := report(sessions, reportOptions{from: base, to: now, groupBy: GROUP_BY_USER})
+
+	if len(rpt.Entries) != 2 {
+		t.Fatalf("expected 2 report entries, got %d", len(rpt.Entries))
+	}
+
+	alice := rpt.Entries[0]
+
+	if alice.Name != "alice" || len(alice.Experiments) != 2 {
+		t.Fatalf("unexpected entry: %+v", alice)
+	}
+
+	if alice.Hours != 6 || alice.VCPUHours != 24 || alice.MemoryGBHours != 12 || alice.DiskGBHours != 6 {
+		t.Errorf("unexpected usage for alice: %+v", alice)
+	}
+
+	if bob := rpt.Entries[1]; bob.Name != "bob" || bob.Hours != 9 {
+		t.Errorf("unexpected entry: %+v", bob)
+	}
+
+	rpt = report(sessions, reportOptions{from: base, to: now, groupBy: GROUP_BY_NAMESPACE, user: "alice"})
+
+	if len(rpt.Entries) != 2 || rpt.Entries[0].Name != "bar" || rpt.Entries[0].Hours != 4 {
+		t.Errorf("unexpected namespace report: %+v", rpt.Entries)
+	}
+}
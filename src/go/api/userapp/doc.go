@@ -0,0 +1,5 @@
+// Implementation of the phenix user app API. External user apps can be
+// installed from a git repository or OCI artifact, with each install verified
+// against the manifest included with the app and registered in the store so
+// the app's metadata is available to the web UI.
+package userapp
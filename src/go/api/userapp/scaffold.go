@@ -0,0 +1,187 @@
+package userapp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"phenix/tmpl"
+)
+
+// ScaffoldLanguages are the languages user apps can be scaffolded in.
+var ScaffoldLanguages = []string{"go", "python"}
+
+var ErrScaffoldExists = errors.New("scaffold directory already exists")
+
+type scaffoldFile struct {
+	tmpl string
+	path string
+	mode os.FileMode
+}
+
+// scaffoldFiles are the files generated for each language, in the order
+// they're generated. The paths are relative to the scaffold directory, with
+// `%s` replaced by the app executable name. The manifest and schema are
+// generated for all languages after these.
+var scaffoldFiles = map[string][]scaffoldFile{
+	"go": {
+		{tmpl: "app_scaffold/go/main.go.tmpl", path: "main.go", mode: 0644},
+		{tmpl: "app_scaffold/go/main_test.go.tmpl", path: "main_test.go", mode: 0644},
+		{tmpl: "app_scaffold/go/go.mod.tmpl", path: "go.mod", mode: 0644},
+		{tmpl: "app_scaffold/go/Makefile.tmpl", path: "Makefile", mode: 0644},
+	},
+	"python": {
+		{tmpl: "app_scaffold/python/app.py.tmpl", path: "%s", mode: 0755},
+		{tmpl: "app_scaffold/python/test_app.py.tmpl", path: "test_app.py", mode: 0644},
+		{tmpl: "app_scaffold/python/Makefile.tmpl", path: "Makefile", mode: 0644},
+	},
+}
+
+type scaffoldOptions struct {
+	lang  string
+	dir   string
+	force bool
+}
+
+type ScaffoldOption func(*scaffoldOptions)
+
+func newScaffoldOptions(opts ...ScaffoldOption) scaffoldOptions {
+	o := scaffoldOptions{lang: "go"}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ScaffoldWithLang sets the language the app is scaffolded in (see
+// `ScaffoldLanguages`). It defaults to `go`.
+func ScaffoldWithLang(l string) ScaffoldOption {
+	return func(o *scaffoldOptions) {
+		if l != "" {
+			o.lang = l
+		}
+	}
+}
+
+// ScaffoldWithDir sets the directory the app is scaffolded in. It defaults to
+// `phenix-app-<name>` in the current directory.
+func ScaffoldWithDir(d string) ScaffoldOption {
+	return func(o *scaffoldOptions) {
+		o.dir = d
+	}
+}
+
+// ScaffoldWithForce allows files in an existing scaffold directory to be
+// overwritten.
+func ScaffoldWithForce(f bool) ScaffoldOption {
+	return func(o *scaffoldOptions) {
+		o.force = f
+	}
+}
+
+// Scaffold generates a working user app skeleton with the given name, including
+// stage handling wired to the user app JSON contract, a metadata schema stub,
+// tests, a Makefile, and a manifest that can be installed with `Install`. It
+// returns the paths of the files generated.
+func Scaffold(name string, opts ...ScaffoldOption) ([]string, error) {
+	o := newScaffoldOptions(opts...)
+
+	if !nameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid app name '%s'", name)
+	}
+
+	files, ok := scaffoldFiles[o.lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported scaffold language '%s' (expected one of %v)", o.lang, ScaffoldLanguages)
+	}
+
+	exe := "phenix-app-" + name
+
+	if o.dir == "" {
+		o.dir = exe
+	}
+
+	if _, err := os.Stat(o.dir); err == nil && !o.force {
+		return nil, fmt.Errorf("%w: %s", ErrScaffoldExists, o.dir)
+	}
+
+	if err := os.MkdirAll(o.dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating scaffold directory %s: %w", o.dir, err)
+	}
+
+	data := struct {
+		Name       string
+		Executable string
+		Lang       string
+		SHA256     string
+	}{
+		Name:       name,
+		Executable: exe,
+		Lang:       o.lang,
+	}
+
+	files = append(files,
+		scaffoldFile{tmpl: "app_scaffold/schema.yml.tmpl", path: "schema.yml", mode: 0644},
+		scaffoldFile{tmpl: "app_scaffold/README.md.tmpl", path: "README.md", mode: 0644},
+	)
+
+	var generated []string
+
+	for _, f := range files {
+		path, err := generateScaffoldFile(o.dir, f, exe, data)
+		if err != nil {
+			return generated, err
+		}
+
+		generated = append(generated, path)
+	}
+
+	// Scripted apps can be installed as-is, so include the checksum of the
+	// executable in the manifest. Compiled apps have to be built first, which
+	// updates the checksum in the manifest (`make manifest`).
+	if path := filepath.Join(o.dir, exe); fileExists(path) {
+		var err error
+
+		if data.SHA256, err = checksum(path); err != nil {
+			return generated, fmt.Errorf("computing checksum of app executable: %w", err)
+		}
+	}
+
+	path, err := generateScaffoldFile(o.dir, scaffoldFile{tmpl: "app_scaffold/phenix-app.yml.tmpl", path: ManifestFile, mode: 0644}, exe, data)
+	if err != nil {
+		return generated, err
+	}
+
+	return append(generated, path), nil
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func generateScaffoldFile(dir string, f scaffoldFile, exe string, data any) (string, error) {
+	path := filepath.Join(dir, strings.ReplaceAll(f.path, "%s", exe))
+
+	var buf bytes.Buffer
+
+	if err := tmpl.GenerateFromTemplate(f.tmpl, data, &buf); err != nil {
+		return "", fmt.Errorf("generating %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), f.mode); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	// WriteFile doesn't change the mode of existing files.
+	if err := os.Chmod(path, f.mode); err != nil {
+		return "", fmt.Errorf("setting mode of %s: %w", path, err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,464 @@
+package userapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"phenix/app"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/common"
+	"phenix/util/shell"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest file expected at the root of user
+// app git repositories and OCI artifacts.
+const ManifestFile = "phenix-app.yml"
+
+// DefaultInstallDir is the directory user app executables are installed to if
+// one isn't specified. It should be in the PATH of the phenix server.
+const DefaultInstallDir = "/usr/local/bin"
+
+var (
+	ErrAppNotFound      = errors.New("app not found")
+	ErrInvalidManifest  = errors.New("invalid app manifest")
+	ErrChecksumMismatch = errors.New("app checksum mismatch")
+	ErrAppExists        = errors.New("app already installed")
+
+	nameRegex    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	versionRegex = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+([-+][0-9A-Za-z.-]+)?$`)
+)
+
+// Manifest describes a user app. The executable and schema paths are relative
+// to the root of the app source.
+type Manifest struct {
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+	Executable  string `yaml:"executable"`
+	SHA256      string `yaml:"sha256"`
+	Schema      string `yaml:"schema"`
+
+	// Protocol is the protocol the app uses to exchange the experiment with
+	// phenix (`shell` or `grpc`). Defaults to `shell`.
+	Protocol string `yaml:"protocol"`
+}
+
+type installOptions struct {
+	ref         string
+	dir         string
+	versionsDir string
+	force       bool
+}
+
+type InstallOption func(*installOptions)
+
+func newInstallOptions(opts ...InstallOption) installOptions {
+	o := installOptions{
+		dir:         DefaultInstallDir,
+		versionsDir: filepath.Join(common.PhenixBase, "apps"),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// InstallWithRef sets the git branch or tag to install from. It's ignored for
+// OCI artifacts, which should include the tag in the artifact reference.
+func InstallWithRef(r string) InstallOption {
+	return func(o *installOptions) {
+		o.ref = r
+	}
+}
+
+func InstallWithDir(d string) InstallOption {
+	return func(o *installOptions) {
+		if d != "" {
+			o.dir = d
+		}
+	}
+}
+
+// InstallWithVersionsDir sets the directory every installed version of an app
+// is kept in (as `<name>/<version>/phenix-app-<name>`). It defaults to the
+// `apps` directory in the phenix base directory, and should not be in the PATH
+// of the phenix server.
+func InstallWithVersionsDir(d string) InstallOption {
+	return func(o *installOptions) {
+		if d != "" {
+			o.versionsDir = d
+		}
+	}
+}
+
+// InstallWithForce allows an already installed version of an app to be
+// replaced.
+func InstallWithForce(f bool) InstallOption {
+	return func(o *installOptions) {
+		o.force = f
+	}
+}
+
+// Install fetches the user app at the given source, verifies it against its
+// manifest, installs its executable into the app path as `phenix-app-<name>`,
+// and registers its metadata in the store. The executable is also kept in the
+// versions directory so scenarios can continue to pin previously installed
+// versions of the app after it's upgraded. Sources prefixed with `oci://` are
+// pulled using `oras`, existing local directories are used as-is, and all
+// other sources are cloned using `git`.
+func Install(ctx context.Context, source string, opts ...InstallOption) (*types.App, error) {
+	o := newInstallOptions(opts...)
+
+	dir, cleanup, err := fetch(ctx, source, o.ref)
+	if err != nil {
+		return nil, fmt.Errorf("fetching app from %s: %w", source, err)
+	}
+
+	defer cleanup()
+
+	manifest, schema, err := Verify(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c, _ := store.NewConfig("app/" + manifest.Name)
+
+	var (
+		exists   = store.Get(c) == nil
+		versions = make(map[string]v1.AppVersion)
+	)
+
+	if exists {
+		var existing v1.AppSpec
+
+		if err := mapstructure.Decode(c.Spec, &existing); err != nil {
+			return nil, fmt.Errorf("decoding existing app spec: %w", err)
+		}
+
+		if _, ok := existing.Versions[manifest.Version]; ok && !o.force {
+			return nil, fmt.Errorf("%w: %s (version %s)", ErrAppExists, manifest.Name, manifest.Version)
+		}
+
+		for v, av := range existing.Versions {
+			versions[v] = av
+		}
+	}
+
+	var (
+		src    = filepath.Join(dir, manifest.Executable)
+		exe    = filepath.Join(o.dir, app.USER_APP_PREFIX+manifest.Name)
+		pinned = filepath.Join(o.versionsDir, manifest.Name, manifest.Version, app.USER_APP_PREFIX+manifest.Name)
+		sha    = "sha256:" + manifest.SHA256
+	)
+
+	if err := os.MkdirAll(filepath.Dir(pinned), 0755); err != nil {
+		return nil, fmt.Errorf("creating app version directory: %w", err)
+	}
+
+	if err := copyExecutable(src, pinned); err != nil {
+		return nil, fmt.Errorf("installing app version executable: %w", err)
+	}
+
+	if err := copyExecutable(src, exe); err != nil {
+		return nil, fmt.Errorf("installing app executable: %w", err)
+	}
+
+	versions[manifest.Version] = v1.AppVersion{Source: source, Executable: pinned, Checksum: sha, Protocol: manifest.Protocol}
+
+	spec := &v1.AppSpec{
+		Description: manifest.Description,
+		Version:     manifest.Version,
+		Source:      source,
+		Executable:  exe,
+		Checksum:    sha,
+		Protocol:    manifest.Protocol,
+		Schema:      schema,
+		Versions:    versions,
+	}
+
+	c.Spec = structs.MapDefaultCase(spec, structs.CASESNAKE)
+
+	if exists {
+		if err := store.Update(c); err != nil {
+			return nil, fmt.Errorf("updating app %s in store: %w", manifest.Name, err)
+		}
+	} else {
+		if err := store.Create(c); err != nil {
+			return nil, fmt.Errorf("registering app %s in store: %w", manifest.Name, err)
+		}
+	}
+
+	return &types.App{Metadata: c.Metadata, Spec: spec}, nil
+}
+
+// List returns all the user apps registered in the store.
+func List() ([]types.App, error) {
+	configs, err := store.List("App")
+	if err != nil {
+		return nil, fmt.Errorf("getting list of app configs from store: %w", err)
+	}
+
+	var apps []types.App
+
+	for _, c := range configs {
+		spec := new(v1.AppSpec)
+
+		if err := mapstructure.Decode(c.Spec, spec); err != nil {
+			return nil, fmt.Errorf("decoding app spec: %w", err)
+		}
+
+		apps = append(apps, types.App{Metadata: c.Metadata, Spec: spec})
+	}
+
+	return apps, nil
+}
+
+// Get returns the registered user app with the given name.
+func Get(name string) (*types.App, error) {
+	c, _ := store.NewConfig("app/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAppNotFound, name)
+	}
+
+	spec := new(v1.AppSpec)
+
+	if err := mapstructure.Decode(c.Spec, spec); err != nil {
+		return nil, fmt.Errorf("decoding app spec: %w", err)
+	}
+
+	return &types.App{Metadata: c.Metadata, Spec: spec}, nil
+}
+
+// Verify reads the manifest at the root of the given directory, verifying the
+// manifest's name and version, the checksum of its executable, and its
+// metadata schema (if included). The parsed schema is returned along with the
+// manifest.
+func Verify(dir string) (*Manifest, map[string]interface{}, error) {
+	body, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidManifest, ManifestFile, err)
+	}
+
+	var manifest Manifest
+
+	if err := yaml.Unmarshal(body, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: parsing %s: %v", ErrInvalidManifest, ManifestFile, err)
+	}
+
+	var errs error
+
+	if !nameRegex.MatchString(manifest.Name) {
+		errs = multierror.Append(errs, fmt.Errorf("invalid name '%s'", manifest.Name))
+	}
+
+	if !versionRegex.MatchString(manifest.Version) {
+		errs = multierror.Append(errs, fmt.Errorf("invalid version '%s' (expected semantic version)", manifest.Version))
+	}
+
+	if manifest.Executable == "" {
+		errs = multierror.Append(errs, fmt.Errorf("no executable specified"))
+	}
+
+	if manifest.SHA256 == "" {
+		errs = multierror.Append(errs, fmt.Errorf("no sha256 checksum specified"))
+	}
+
+	switch manifest.Protocol {
+	case "", app.USER_APP_PROTOCOL_SHELL, app.USER_APP_PROTOCOL_GRPC:
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid protocol '%s' (expected %s or %s)", manifest.Protocol, app.USER_APP_PROTOCOL_SHELL, app.USER_APP_PROTOCOL_GRPC))
+	}
+
+	if errs != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidManifest, errs)
+	}
+
+	manifest.SHA256 = strings.ToLower(strings.TrimPrefix(manifest.SHA256, "sha256:"))
+
+	exe, err := sourcePath(dir, manifest.Executable)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: executable %v", ErrInvalidManifest, err)
+	}
+
+	sum, err := checksum(exe)
+	if err != nil {
+		return nil, nil, fmt.Errorf("computing checksum of app executable: %w", err)
+	}
+
+	if sum != manifest.SHA256 {
+		return nil, nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, manifest.SHA256, sum)
+	}
+
+	if manifest.Schema == "" {
+		return &manifest, nil, nil
+	}
+
+	path, err := sourcePath(dir, manifest.Schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: schema %v", ErrInvalidManifest, err)
+	}
+
+	schema, err := loadSchema(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: schema %v", ErrInvalidManifest, err)
+	}
+
+	return &manifest, schema, nil
+}
+
+func fetch(ctx context.Context, src, ref string) (string, func(), error) {
+	if info, err := os.Stat(src); err == nil && info.IsDir() {
+		return src, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "phenix-app-")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating temp directory: %w", err)
+	}
+
+	cleanup := func() { os.RemoveAll(dir) }
+
+	var opts []shell.Option
+
+	if strings.HasPrefix(src, "oci://") {
+		if !shell.CommandExists("oras") {
+			cleanup()
+			return "", nil, fmt.Errorf("oras must be installed to install apps from OCI artifacts")
+		}
+
+		opts = append(opts, shell.Command("oras"), shell.Args("pull", "--output", dir, strings.TrimPrefix(src, "oci://")))
+	} else {
+		args := []string{"clone", "--quiet", "--depth", "1"}
+
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+
+		opts = append(opts, shell.Command("git"), shell.Args(append(args, src, dir)...))
+	}
+
+	if _, stderr, err := shell.ExecCommand(ctx, opts...); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(stderr)))
+	}
+
+	return dir, cleanup, nil
+}
+
+// sourcePath resolves the given path relative to the given app source
+// directory, ensuring it doesn't escape the directory.
+func sourcePath(dir, path string) (string, error) {
+	full := filepath.Join(dir, path)
+
+	if rel, err := filepath.Rel(dir, full); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is outside of app source", path)
+	}
+
+	if _, err := os.Stat(full); err != nil {
+		return "", fmt.Errorf("%s not found in app source", path)
+	}
+
+	return full, nil
+}
+
+func loadSchema(path string) (map[string]interface{}, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var schema map[string]interface{}
+
+	// YAML is a superset of JSON, so this handles both.
+	if err := yaml.Unmarshal(body, &schema); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	body, err = json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s to JSON: %w", path, err)
+	}
+
+	var s openapi3.Schema
+
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, fmt.Errorf("parsing %s as OpenAPI schema: %w", path, err)
+	}
+
+	if err := s.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("validating %s: %w", path, err)
+	}
+
+	return schema, nil
+}
+
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	// Write to a temp file in the same directory and rename it so an app that's
+	// currently running isn't clobbered mid-execution.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
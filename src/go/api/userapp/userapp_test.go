@@ -0,0 +1,199 @@
+package userapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"phenix/store"
+)
+
+func writeApp(t *testing.T, manifest string) string {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"bin/phenix-app-foo": "#!/bin/sh\ncat\n",
+		"schema.yml":         "type: object\nproperties:\n  count:\n    type: integer\n",
+		ManifestFile:         manifest,
+	}
+
+	for name, body := range files {
+		path := filepath.Join(dir, name)
+
+		os.MkdirAll(filepath.Dir(path), 0755)
+
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestVerify(t *testing.T) {
+	sum := sha256.Sum256([]byte("#!/bin/sh\ncat\n"))
+	checksum := hex.EncodeToString(sum[:])
+
+	manifest := `
+name: foo
+version: 1.2.0
+executable: bin/phenix-app-foo
+sha256: ` + checksum + `
+schema: schema.yml
+`
+
+	m, schema, err := Verify(writeApp(t, manifest))
+	if err != nil {
+		t.Fatalf("verifying app: %v", err)
+	}
+
+	if m.Name != "foo" || m.Version != "1.2.0" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	if schema["type"] != "object" {
+		t.Errorf("unexpected schema: %v", schema)
+	}
+
+	bad := `
+name: foo
+version: 1.2.0
+executable: bin/phenix-app-foo
+sha256: ` + checksum[1:] + "0"
+
+	if _, _, err := Verify(writeApp(t, bad)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected checksum mismatch error, got %v", err)
+	}
+
+	invalid := `
+name: Foo Bar
+version: latest
+executable: ../phenix-app-foo
+sha256: ` + checksum
+
+	if _, _, err := Verify(writeApp(t, invalid)); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("expected invalid manifest error, got %v", err)
+	}
+
+	escape := `
+name: foo
+version: 1.2.0
+executable: ../phenix-app-foo
+sha256: ` + checksum
+
+	if _, _, err := Verify(writeApp(t, escape)); !errors.Is(err, ErrInvalidManifest) {
+		t.Errorf("expected invalid manifest error, got %v", err)
+	}
+}
+
+func TestInstallVersions(t *testing.T) {
+	var (
+		tmp      = t.TempDir()
+		sum      = sha256.Sum256([]byte("#!/bin/sh\ncat\n"))
+		checksum = hex.EncodeToString(sum[:])
+	)
+
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(tmp, "phenix.bdb"))); err != nil {
+		t.Fatalf("initializing store: %v", err)
+	}
+
+	opts := []InstallOption{
+		InstallWithDir(filepath.Join(tmp, "bin")),
+		InstallWithVersionsDir(filepath.Join(tmp, "apps")),
+	}
+
+	os.MkdirAll(filepath.Join(tmp, "bin"), 0755)
+
+	for _, version := range []string{"1.2.0", "1.3.0"} {
+		manifest := "name: foo\nversion: " + version + "\nexecutable: bin/phenix-app-foo\nsha256: " + checksum + "\n"
+
+		if _, err := Install(context.Background(), writeApp(t, manifest), opts...); err != nil {
+			t.Fatalf("installing version %s: %v", version, err)
+		}
+	}
+
+	app, err := Get("foo")
+	if err != nil {
+		t.Fatalf("getting app: %v", err)
+	}
+
+	if app.Spec.Version != "1.3.0" {
+		t.Errorf("expected current version 1.3.0, got %s", app.Spec.Version)
+	}
+
+	for _, version := range []string{"1.2.0", "1.3.0"} {
+		v, ok := app.Spec.Versions[version]
+		if !ok {
+			t.Fatalf("expected version %s to be registered", version)
+		}
+
+		if _, err := os.Stat(v.Executable); err != nil {
+			t.Errorf("expected executable for version %s: %v", version, err)
+		}
+	}
+
+	manifest := "name: foo\nversion: 1.3.0\nexecutable: bin/phenix-app-foo\nsha256: " + checksum + "\n"
+
+	if _, err := Install(context.Background(), writeApp(t, manifest), opts...); !errors.Is(err, ErrAppExists) {
+		t.Errorf("expected app exists error, got %v", err)
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "foo")
+
+	files, err := Scaffold("foo", ScaffoldWithLang("python"), ScaffoldWithDir(dir))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(files) != 6 {
+		t.Logf("expected 6 files generated, got %d", len(files))
+		t.FailNow()
+	}
+
+	// Scripted apps should be installable as-is.
+	manifest, schema, err := Verify(dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if manifest.Name != "foo" || manifest.Executable != "phenix-app-foo" || schema == nil {
+		t.Logf("unexpected manifest %+v", manifest)
+		t.FailNow()
+	}
+
+	if _, err := Scaffold("foo", ScaffoldWithDir(dir)); !errors.Is(err, ErrScaffoldExists) {
+		t.Logf("expected ErrScaffoldExists, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := Scaffold("foo", ScaffoldWithDir(dir), ScaffoldWithForce(true)); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	dir = filepath.Join(t.TempDir(), "bar")
+
+	if _, err := Scaffold("bar", ScaffoldWithDir(dir)); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Compiled apps have to be built before the manifest is valid.
+	if _, _, err := Verify(dir); !errors.Is(err, ErrInvalidManifest) {
+		t.Logf("expected invalid manifest for unbuilt Go app, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := Scaffold("foo", ScaffoldWithLang("rust"), ScaffoldWithDir(t.TempDir()), ScaffoldWithForce(true)); err == nil {
+		t.Log("expected error for unsupported language")
+		t.FailNow()
+	}
+}
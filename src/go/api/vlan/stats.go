@@ -0,0 +1,291 @@
+package vlan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+)
+
+// Lists the name and statistics columns of every OVS interface on a host as
+// `<name>,<key>=<value> <key>=<value> ...`.
+const ovsStatsCmd = "ovs-vsctl --format=csv --data=bare --no-headings --columns=name,statistics list interface"
+
+var networkRegex = regexp.MustCompile(`^(.*) \((\d+)\)$`)
+
+// Counters are the cumulative counters OVS tracks for an interface. For taps,
+// `rx` is traffic received by the bridge from the VM and `tx` is traffic sent
+// by the bridge to the VM.
+type Counters struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+func (this *Counters) add(c Counters) {
+	this.RxBytes += c.RxBytes
+	this.TxBytes += c.TxBytes
+	this.RxPackets += c.RxPackets
+	this.TxPackets += c.TxPackets
+	this.RxDropped += c.RxDropped
+	this.TxDropped += c.TxDropped
+}
+
+// Rates are per second rates computed from the difference between two samples
+// of the same counters.
+type Rates struct {
+	RxBytes   float64 `json:"rxBytes"`
+	TxBytes   float64 `json:"txBytes"`
+	RxPackets float64 `json:"rxPackets"`
+	TxPackets float64 `json:"txPackets"`
+	RxDropped float64 `json:"rxDropped"`
+	TxDropped float64 `json:"txDropped"`
+}
+
+func (this *Rates) add(r Rates) {
+	this.RxBytes += r.RxBytes
+	this.TxBytes += r.TxBytes
+	this.RxPackets += r.RxPackets
+	this.TxPackets += r.TxPackets
+	this.RxDropped += r.RxDropped
+	this.TxDropped += r.TxDropped
+}
+
+// TapStats are the statistics for a single VM interface tap. Rates is nil the
+// first time a tap is sampled, or if its counters were reset since the last
+// sample (ie. the VM was redeployed).
+type TapStats struct {
+	Tap       string   `json:"tap"`
+	Host      string   `json:"host"`
+	VM        string   `json:"vm"`
+	Interface int      `json:"interface"`
+	Counters  Counters `json:"counters"`
+	Rates     *Rates   `json:"rates,omitempty"`
+}
+
+// VLANStats are the statistics for all the taps on an experiment VLAN. The
+// VLAN rates are the sum of the rates of the taps that have them.
+type VLANStats struct {
+	Alias    string     `json:"alias"`
+	ID       int        `json:"id"`
+	Counters Counters   `json:"counters"`
+	Rates    Rates      `json:"rates"`
+	Taps     []TapStats `json:"taps"`
+}
+
+type sample struct {
+	counters Counters
+	ts       time.Time
+}
+
+// Previous samples of each tap, keyed by `<host>/<tap>`, used to compute rates.
+var samples = struct {
+	sync.Mutex
+
+	taps map[string]sample
+}{
+	taps: make(map[string]sample),
+}
+
+// Stats collects the OVS counters for the taps of every running VM in the given
+// experiment from the cluster hosts they're running on, grouped by VLAN. Rates
+// are computed from the previous time the stats were collected. It returns the
+// stats sorted by VLAN alias and any errors encountered while collecting them.
+func Stats(opts ...Option) ([]VLANStats, error) {
+	o := newOptions(opts...)
+
+	if o.exp == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	exp, err := experiment.Get(o.exp)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", o.exp, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("experiment %s is not running", o.exp)
+	}
+
+	var (
+		vms   = mm.GetVMInfo(mm.NS(o.exp))
+		hosts = make(map[string]map[string]Counters)
+		vlans = make(map[string]*VLANStats)
+		now   = time.Now()
+	)
+
+	for _, vm := range vms {
+		if _, ok := hosts[vm.Host]; ok || len(vm.Taps) == 0 {
+			continue
+		}
+
+		resp, err := mm.MeshShellResponse(vm.Host, ovsStatsCmd)
+		if err != nil {
+			return nil, fmt.Errorf("getting OVS stats from host %s: %w", vm.Host, err)
+		}
+
+		hosts[vm.Host] = parseOVSStats(resp)
+	}
+
+	samples.Lock()
+	defer samples.Unlock()
+
+	for _, vm := range vms {
+		for idx, tap := range vm.Taps {
+			if idx >= len(vm.Networks) {
+				break
+			}
+
+			counters, ok := hosts[vm.Host][tap]
+			if !ok {
+				continue
+			}
+
+			alias, id := parseNetwork(vm.Networks[idx])
+
+			stats, ok := vlans[alias]
+			if !ok {
+				stats = &VLANStats{Alias: alias, ID: id}
+				vlans[alias] = stats
+			}
+
+			ts := TapStats{Tap: tap, Host: vm.Host, VM: vm.Name, Interface: idx, Counters: counters}
+
+			key := vm.Host + "/" + tap
+
+			if prev, ok := samples.taps[key]; ok {
+				ts.Rates = rates(prev, sample{counters: counters, ts: now})
+			}
+
+			samples.taps[key] = sample{counters: counters, ts: now}
+
+			stats.Counters.add(counters)
+
+			if ts.Rates != nil {
+				stats.Rates.add(*ts.Rates)
+			}
+
+			stats.Taps = append(stats.Taps, ts)
+		}
+	}
+
+	list := make([]VLANStats, 0, len(vlans))
+
+	for _, stats := range vlans {
+		sort.Slice(stats.Taps, func(i, j int) bool {
+			if stats.Taps[i].VM == stats.Taps[j].VM {
+				return stats.Taps[i].Interface < stats.Taps[j].Interface
+			}
+
+			return stats.Taps[i].VM < stats.Taps[j].VM
+		})
+
+		list = append(list, *stats)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Alias < list[j].Alias })
+
+	return list, nil
+}
+
+// parseOVSStats parses the output of `ovsStatsCmd` into counters keyed by
+// interface name.
+func parseOVSStats(out string) map[string]Counters {
+	stats := make(map[string]Counters)
+
+	for _, line := range strings.Split(out, "\n") {
+		name, values, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok || name == "" {
+			continue
+		}
+
+		// Depending on the OVS version, map entries may be quoted and separated
+		// by commas instead of spaces.
+		values = strings.Trim(values, `"{}`)
+		values = strings.ReplaceAll(values, ",", " ")
+
+		var c Counters
+
+		for _, field := range strings.Fields(values) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			switch key {
+			case "rx_bytes":
+				c.RxBytes = v
+			case "tx_bytes":
+				c.TxBytes = v
+			case "rx_packets":
+				c.RxPackets = v
+			case "tx_packets":
+				c.TxPackets = v
+			case "rx_dropped":
+				c.RxDropped = v
+			case "tx_dropped":
+				c.TxDropped = v
+			}
+		}
+
+		stats[strings.Trim(name, `"`)] = c
+	}
+
+	return stats
+}
+
+// parseNetwork splits a minimega VM network (ie. `EXP_1 (101)`) into its VLAN
+// alias and ID.
+func parseNetwork(nw string) (string, int) {
+	match := networkRegex.FindStringSubmatch(nw)
+	if match == nil {
+		id, _ := strconv.Atoi(nw)
+		return nw, id
+	}
+
+	id, _ := strconv.Atoi(match[2])
+
+	return match[1], id
+}
+
+// rates computes the per second rates between two samples. It returns nil if
+// no time has passed or any counter went backwards.
+func rates(prev, cur sample) *Rates {
+	secs := cur.ts.Sub(prev.ts).Seconds()
+	if secs <= 0 {
+		return nil
+	}
+
+	var (
+		p = prev.counters
+		c = cur.counters
+	)
+
+	if c.RxBytes < p.RxBytes || c.TxBytes < p.TxBytes || c.RxPackets < p.RxPackets ||
+		c.TxPackets < p.TxPackets || c.RxDropped < p.RxDropped || c.TxDropped < p.TxDropped {
+		return nil
+	}
+
+	return &Rates{
+		RxBytes:   float64(c.RxBytes-p.RxBytes) / secs,
+		TxBytes:   float64(c.TxBytes-p.TxBytes) / secs,
+		RxPackets: float64(c.RxPackets-p.RxPackets) / secs,
+		TxPackets: float64(c.TxPackets-p.TxPackets) / secs,
+		RxDropped: float64(c.RxDropped-p.RxDropped) / secs,
+		TxDropped: float64(c.TxDropped-p.TxDropped) / secs,
+	}
+}
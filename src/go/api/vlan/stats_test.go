@@ -0,0 +1,61 @@
+package vlan
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOVSStats(t *testing.T) {
+	out := `mega_tap0,collisions=0 rx_bytes=1000 rx_crc_err=0 rx_dropped=2 rx_packets=10 tx_bytes=2000 tx_dropped=0 tx_packets=20
+"mega_tap1","{rx_bytes=5, tx_bytes=6, rx_packets=1, tx_packets=1}"
+mega_bridge,`
+
+	stats := parseOVSStats(out)
+
+	if c := stats["mega_tap0"]; c.RxBytes != 1000 || c.TxBytes != 2000 || c.RxDropped != 2 || c.RxPackets != 10 || c.TxPackets != 20 {
+		t.Logf("unexpected counters for mega_tap0: %+v", c)
+		t.FailNow()
+	}
+
+	if c := stats["mega_tap1"]; c.RxBytes != 5 || c.TxBytes != 6 {
+		t.Logf("unexpected counters for mega_tap1: %+v", c)
+		t.FailNow()
+	}
+
+	if _, ok := stats["mega_bridge"]; !ok {
+		t.Log("expected interface without statistics to be included")
+		t.FailNow()
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+	if alias, id := parseNetwork("EXP_1 (101)"); alias != "EXP_1" || id != 101 {
+		t.Logf("expected EXP_1 (101), got %s (%d)", alias, id)
+		t.FailNow()
+	}
+
+	if alias, id := parseNetwork("101"); alias != "101" || id != 101 {
+		t.Logf("expected 101 (101), got %s (%d)", alias, id)
+		t.FailNow()
+	}
+}
+
+func TestRates(t *testing.T) {
+	var (
+		now  = time.Now()
+		prev = sample{counters: Counters{RxBytes: 1000, TxPackets: 10}, ts: now}
+		cur  = sample{counters: Counters{RxBytes: 3000, TxPackets: 30}, ts: now.Add(2 * time.Second)}
+	)
+
+	r := rates(prev, cur)
+	if r == nil || r.RxBytes != 1000 || r.TxPackets != 10 {
+		t.Logf("unexpected rates: %+v", r)
+		t.FailNow()
+	}
+
+	// Counters are reset when a tap is recreated.
+	if r := rates(cur, sample{counters: Counters{RxBytes: 10}, ts: now.Add(4 * time.Second)}); r != nil {
+		t.Logf("expected no rates for reset counters, got %+v", r)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,235 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"unicode"
+
+	"phenix/api/experiment"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+)
+
+// X11 keysym names for the non-alphanumeric characters that can be typed into
+// VM consoles via minimega's `vnc inject` command.
+var keysyms = map[rune]string{
+	'\n': "Return", '\t': "Tab", ' ': "space", '!': "exclam", '"': "quotedbl",
+	'#': "numbersign", '$': "dollar", '%': "percent", '&': "ampersand",
+	'\'': "apostrophe", '(': "parenleft", ')': "parenright", '*': "asterisk",
+	'+': "plus", ',': "comma", '-': "minus", '.': "period", '/': "slash",
+	':': "colon", ';': "semicolon", '<': "less", '=': "equal", '>': "greater",
+	'?': "question", '@': "at", '[': "bracketleft", '\\': "backslash",
+	']': "bracketright", '^': "asciicircum", '_': "underscore", '`': "grave",
+	'{': "braceleft", '|': "bar", '}': "braceright", '~': "asciitilde",
+}
+
+// BroadcastResult is the result of broadcasting to a single VM in a group. The
+// results of commands (or files) broadcast are tracked by the batch they're
+// dispatched in instead.
+type BroadcastResult struct {
+	VM    string `json:"vm"`
+	Error string `json:"error,omitempty"`
+}
+
+// BroadcastGroup returns the names of the VMs in the given experiment selected
+// by the given broadcast options, sorted by name.
+func BroadcastGroup(expName string, opts ...BroadcastOption) ([]string, error) {
+	o := newBroadcastOptions(opts...)
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	return broadcastGroup(exp.Spec.Topology().Nodes(), o)
+}
+
+// Broadcast types keystrokes into the consoles of, or executes a cc command
+// (or sends a file via cc) in, every VM of a group in the given running
+// experiment at once. The group is made up of the VMs named and the VMs
+// matching all the labels provided. Key events are sent to every VM in the
+// group before the next key is typed so the consoles stay in lockstep, and
+// typing stops if the given context is canceled. Commands are dispatched via
+// `mm.DispatchC2`, and Broadcast returns as soon as they're queued along with
+// the batch tracking their completion (nil if keys were broadcast). Each VM's
+// cc command ID is available from the batch's results once its command
+// completes. Commands still queued when the given context is canceled are
+// canceled, so callers that don't wait on the batch should pass a context that
+// outlives the call. Failures for individual VMs are included in the results
+// rather than stopping the broadcast.
+func Broadcast(ctx context.Context, expName string, opts ...BroadcastOption) ([]BroadcastResult, *mm.C2Batch, error) {
+	o := newBroadcastOptions(opts...)
+
+	if expName == "" {
+		return nil, nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if (o.keys == "") == (o.command == "" && o.file == "") {
+		return nil, nil, fmt.Errorf("exactly one of keys or a command (and/or file) must be provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return nil, nil, fmt.Errorf("experiment %s is not running", expName)
+	}
+
+	group, err := broadcastGroup(exp.Spec.Topology().Nodes(), o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]BroadcastResult, len(group))
+
+	for i, name := range group {
+		results[i].VM = name
+	}
+
+	if o.command != "" || o.file != "" {
+		var c2 []mm.C2Option
+
+		if o.file != "" {
+			c2 = append(c2, mm.C2SendFile(o.file))
+		}
+
+		if o.command != "" {
+			c2 = append(c2, mm.C2Command(o.command))
+		}
+
+		tasks := make([]mm.C2Task, len(group))
+
+		for i, name := range group {
+			tasks[i] = mm.C2Task{VM: name, Options: c2}
+		}
+
+		// Commands are dispatched through the C2 queue so large groups don't
+		// overwhelm the cc channels of the VMs on each cluster host.
+		batch := mm.DispatchC2(ctx, expName, tasks)
+
+		return results, batch, nil
+	}
+
+	keys, err := keyEvents(o.keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return results, nil, fmt.Errorf("typing keys: %w", err)
+		}
+
+		for i, name := range group {
+			// Stop typing into consoles that have already failed.
+			if results[i].Error != "" {
+				continue
+			}
+
+			for _, down := range []bool{true, false} {
+				cmd := mmcli.NewNamespacedCommand(expName)
+				cmd.Command = fmt.Sprintf("vnc inject %s KeyEvent,%t,%s", name, down, key)
+
+				if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+					results[i].Error = fmt.Sprintf("injecting key %s: %v", key, err)
+					break
+				}
+			}
+		}
+	}
+
+	return results, nil, nil
+}
+
+func broadcastGroup(nodes []ifaces.NodeSpec, o broadcastOptions) ([]string, error) {
+	if len(o.vms) == 0 && len(o.labels) == 0 {
+		return nil, fmt.Errorf("no VMs or labels provided to select VM group")
+	}
+
+	var (
+		named = make(map[string]bool)
+		group = make(map[string]struct{})
+	)
+
+	for _, name := range o.vms {
+		named[name] = false
+	}
+
+	for _, node := range nodes {
+		if node.External() {
+			continue
+		}
+
+		name := node.General().Hostname()
+
+		if _, ok := named[name]; ok {
+			named[name] = true
+			group[name] = struct{}{}
+
+			continue
+		}
+
+		if len(o.labels) == 0 {
+			continue
+		}
+
+		matched := true
+
+		for k, v := range o.labels {
+			if node.Labels()[k] != v {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			group[name] = struct{}{}
+		}
+	}
+
+	for name, found := range named {
+		if !found {
+			return nil, fmt.Errorf("VM %s not found in experiment topology", name)
+		}
+	}
+
+	if len(group) == 0 {
+		return nil, fmt.Errorf("no VMs matched labels")
+	}
+
+	names := make([]string, 0, len(group))
+
+	for name := range group {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// keyEvents converts the given text to the X11 keysym names to send as key
+// events for each character.
+func keyEvents(text string) ([]string, error) {
+	var keys []string
+
+	for _, r := range text {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			keys = append(keys, string(r))
+			continue
+		}
+
+		key, ok := keysyms[r]
+		if !ok {
+			return nil, fmt.Errorf("unsupported character %q in keys", r)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+func TestBroadcastGroup(t *testing.T) {
+	external := true
+
+	nodes := []ifaces.NodeSpec{
+		&v1.Node{GeneralF: &v1.General{HostnameF: "ws-2"}, LabelsF: map[string]string{"role": "student", "class": "a"}},
+		&v1.Node{GeneralF: &v1.General{HostnameF: "ws-1"}, LabelsF: map[string]string{"role": "student", "class": "b"}},
+		&v1.Node{GeneralF: &v1.General{HostnameF: "instructor"}, LabelsF: map[string]string{"role": "instructor"}},
+		&v1.Node{GeneralF: &v1.General{HostnameF: "router"}, LabelsF: map[string]string{"role": "student"}, ExternalF: &external},
+	}
+
+	group, err := broadcastGroup(nodes, newBroadcastOptions(BroadcastToLabel("role", "student")))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if expected := []string{"ws-1", "ws-2"}; !reflect.DeepEqual(group, expected) {
+		t.Logf("expected group %v, got %v", expected, group)
+		t.FailNow()
+	}
+
+	group, err = broadcastGroup(nodes, newBroadcastOptions(BroadcastToLabel("role", "student"), BroadcastToLabel("class", "a"), BroadcastToVMs("instructor")))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if expected := []string{"instructor", "ws-2"}; !reflect.DeepEqual(group, expected) {
+		t.Logf("expected group %v, got %v", expected, group)
+		t.FailNow()
+	}
+
+	if _, err := broadcastGroup(nodes, newBroadcastOptions(BroadcastToVMs("foo"))); err == nil {
+		t.Log("expected error for unknown VM")
+		t.FailNow()
+	}
+
+	if _, err := broadcastGroup(nodes, newBroadcastOptions()); err == nil {
+		t.Log("expected error for empty group selection")
+		t.FailNow()
+	}
+}
+
+func TestKeyEvents(t *testing.T) {
+	keys, err := keyEvents("ls -l /\n")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{"l", "s", "space", "minus", "l", "space", "slash", "Return"}
+
+	if !reflect.DeepEqual(keys, expected) {
+		t.Logf("expected keys %v, got %v", expected, keys)
+		t.FailNow()
+	}
+
+	if _, err := keyEvents("é"); err == nil {
+		t.Log("expected error for unsupported character")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,477 @@
+package vm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/common"
+	"phenix/util/journal"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// DIAGNOSTICS_DIR is the directory, relative to an experiment's files
+// directory, VM diagnostic bundles are written to.
+const DIAGNOSTICS_DIR = "diagnostics"
+
+var (
+	// BOOT_DIAGNOSTICS_TIMEOUT is how long VMs in a newly started experiment
+	// have to check in (ie. have an active miniccc agent) before diagnostics are
+	// collected for them. VMs that fail to boot (error or exit) before then have
+	// diagnostics collected right away. Zero disables automatic collection.
+	BOOT_DIAGNOSTICS_TIMEOUT = 10 * time.Minute
+
+	// BOOT_DIAGNOSTICS_INTERVAL is how often VMs in a newly started experiment
+	// are checked for boot failures.
+	BOOT_DIAGNOSTICS_INTERVAL = 15 * time.Second
+
+	// DIAGNOSTICS_QEMU_LOGS are the files in a VM's minimega instance directory
+	// QEMU's stderr may be captured in, depending on the minimega version.
+	DIAGNOSTICS_QEMU_LOGS = []string{"qemu.stderr", "qemu.log"}
+
+	// DIAGNOSTICS_SERIAL_CAPTURE is how long output is captured from a VM's
+	// serial console for its diagnostics.
+	DIAGNOSTICS_SERIAL_CAPTURE = 5 * time.Second
+
+	// Output collected from cluster hosts for each diagnostic is truncated to
+	// its last DIAGNOSTICS_MAX_OUTPUT bytes.
+	DIAGNOSTICS_MAX_OUTPUT = 64 * 1024
+)
+
+// Diagnostics summarizes a diagnostic bundle collected for a VM. The bundle
+// itself is a gzipped tarball in the experiment files directory containing the
+// summary, the VM's minimega info, QEMU's stderr, recent serial console output,
+// the state of the VM's taps and their bridges, and the VM's file injections.
+type Diagnostics struct {
+	VM        string    `json:"vm"`
+	Host      string    `json:"host"`
+	State     string    `json:"state"`
+	Reason    string    `json:"reason"`
+	Collected time.Time `json:"collected"`
+	Path      string    `json:"path"`
+	Errors    []string  `json:"errors,omitempty"`
+}
+
+var (
+	bootWatchersMu sync.Mutex
+	bootWatchers   = make(map[string]context.CancelFunc)
+)
+
+func init() {
+	experiment.RegisterHook("start", func(stage, name string) {
+		if BOOT_DIAGNOSTICS_TIMEOUT == 0 {
+			return
+		}
+
+		exp, err := experiment.Get(name)
+		if err != nil || exp.DryRun() {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		bootWatchersMu.Lock()
+
+		if prev, ok := bootWatchers[name]; ok {
+			prev()
+		}
+
+		bootWatchers[name] = cancel
+
+		bootWatchersMu.Unlock()
+
+		go func() {
+			watchBoot(ctx, name)
+
+			bootWatchersMu.Lock()
+			defer bootWatchersMu.Unlock()
+
+			// The watcher was canceled if the experiment was stopped or restarted
+			// (and given a new watcher) while it was running.
+			if ctx.Err() == nil {
+				delete(bootWatchers, name)
+			}
+
+			cancel()
+		}()
+	})
+
+	experiment.RegisterHook("stop", func(stage, name string) {
+		bootWatchersMu.Lock()
+		defer bootWatchersMu.Unlock()
+
+		if cancel, ok := bootWatchers[name]; ok {
+			cancel()
+			delete(bootWatchers, name)
+		}
+	})
+
+	// VMs are killed when launching an experiment fails, so diagnostics have to
+	// be collected for any VMs that didn't start before then.
+	experiment.RegisterHook("launch-failed", func(stage, name string) {
+		var (
+			wg     sync.WaitGroup
+			reason = "experiment failed to launch"
+		)
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			if vm.Running {
+				continue
+			}
+
+			wg.Add(1)
+
+			go func(vm mm.VM) {
+				defer wg.Done()
+
+				collectDiagnostics(name, vm, reason)
+			}(vm)
+		}
+
+		wg.Wait()
+	})
+}
+
+// watchBoot collects diagnostics for VMs in the given experiment that fail to
+// boot, or haven't checked in by the time the boot diagnostics timeout passes.
+func watchBoot(ctx context.Context, expName string) {
+	var (
+		deadline  = time.Now().Add(BOOT_DIAGNOSTICS_TIMEOUT)
+		collected = make(map[string]bool)
+		ticker    = time.NewTicker(BOOT_DIAGNOSTICS_INTERVAL)
+	)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired := time.Now().After(deadline)
+
+		for _, vm := range mm.GetVMInfo(mm.NS(expName)) {
+			if collected[vm.Name] {
+				continue
+			}
+
+			var reason string
+
+			switch {
+			case vm.State == "ERROR" || vm.State == "QUIT":
+				reason = fmt.Sprintf("VM failed to boot (%s)", strings.ToLower(vm.State))
+			case expired && vm.Running && !vm.CCActive:
+				reason = fmt.Sprintf("VM never checked in (no active miniccc agent after %v)", BOOT_DIAGNOSTICS_TIMEOUT)
+			default:
+				continue
+			}
+
+			collected[vm.Name] = true
+
+			collectDiagnostics(expName, vm, reason)
+		}
+
+		if expired {
+			return
+		}
+	}
+}
+
+func collectDiagnostics(expName string, vm mm.VM, reason string) {
+	diag, err := CollectDiagnostics(expName, vm.Name, reason)
+	if err != nil {
+		plog.Error("collecting VM diagnostics", "exp", expName, "vm", vm.Name, "err", err)
+		return
+	}
+
+	plog.Warn("collected VM diagnostics", "exp", expName, "vm", vm.Name, "reason", reason, "path", diag.Path)
+	journal.RecordWithDetails(expName, journal.CategoryVM, vm.Name, map[string]string{"reason": reason, "path": diag.Path}, "collected VM diagnostics")
+}
+
+// CollectDiagnostics collects a diagnostic bundle for the given VM, replacing
+// any bundle previously collected for it. Diagnostics that can't be collected
+// (ie. because the VM's cluster host isn't reachable) are noted in the bundle's
+// summary rather than failing the collection.
+func CollectDiagnostics(expName, vmName, reason string) (*Diagnostics, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	diag := &Diagnostics{
+		VM:        vmName,
+		Reason:    reason,
+		Collected: time.Now().UTC(),
+		Path:      filepath.Join(DIAGNOSTICS_DIR, vmName+".tar.gz"),
+	}
+
+	files := make(map[string][]byte)
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node != nil {
+		files["injections.txt"] = []byte(injectionReport(exp, node.Injections()))
+	}
+
+	vms := mm.GetVMInfo(mm.NS(expName), mm.VMName(vmName))
+
+	if len(vms) == 0 {
+		if node == nil {
+			return nil, fmt.Errorf("VM %s not found in experiment %s", vmName, expName)
+		}
+
+		diag.State = "NOT FOUND"
+		diag.Errors = append(diag.Errors, "VM not found in minimega")
+	} else {
+		vm := vms[0]
+
+		diag.Host = vm.Host
+		diag.State = vm.State
+
+		if body, err := json.MarshalIndent(vm, "", "  "); err == nil {
+			files["vm.json"] = body
+		}
+
+		var (
+			vmDir   = fmt.Sprintf("%s/%d", common.MinimegaBase, vm.ID)
+			qemuLog []string
+		)
+
+		for _, name := range DIAGNOSTICS_QEMU_LOGS {
+			qemuLog = append(qemuLog, vmDir+"/"+name)
+		}
+
+		commands := []struct {
+			file string
+			cmd  string
+		}{
+			{"qemu.log", fmt.Sprintf(`cat %s 2> /dev/null | tail -c %d`, strings.Join(qemuLog, " "), DIAGNOSTICS_MAX_OUTPUT)},
+			{"serial.log", fmt.Sprintf(`timeout %d socat -u UNIX-CONNECT:%s/serial0 - 2>&1 | tail -c %d`, int(DIAGNOSTICS_SERIAL_CAPTURE.Seconds()), vmDir, DIAGNOSTICS_MAX_OUTPUT)},
+			{"network.txt", networkCommand(vm.Taps)},
+		}
+
+		for _, c := range commands {
+			out, err := mm.MeshShellResponse(vm.Host, fmt.Sprintf(`bash -c "%s"`, c.cmd))
+			if err != nil {
+				diag.Errors = append(diag.Errors, fmt.Sprintf("collecting %s from host %s: %v", c.file, vm.Host, err))
+				continue
+			}
+
+			files[c.file] = []byte(out + "\n")
+		}
+	}
+
+	if err := writeDiagnostics(exp.FilesDir(), diag, files); err != nil {
+		return nil, err
+	}
+
+	return diag, nil
+}
+
+// networkCommand returns the shell command used to collect the state of the
+// given taps and the bridges they're attached to.
+func networkCommand(taps []string) string {
+	if len(taps) == 0 {
+		return "echo no taps"
+	}
+
+	var cmds []string
+
+	for _, tap := range taps {
+		cmds = append(cmds,
+			fmt.Sprintf("echo '### %s'", tap),
+			fmt.Sprintf("ip -details -statistics link show %s 2>&1", tap),
+			"printf 'bridge: '",
+			fmt.Sprintf("ovs-vsctl port-to-br %s 2>&1", tap),
+			fmt.Sprintf("ovs-vsctl --columns=name,admin_state,link_state,error,ofport list interface %s 2>&1", tap),
+		)
+	}
+
+	return strings.Join(cmds, "; ")
+}
+
+// injectionReport lists the given file injections, noting any whose source
+// files are missing.
+func injectionReport(exp *types.Experiment, injects []ifaces.NodeInjection) string {
+	if len(injects) == 0 {
+		return "no file injections\n"
+	}
+
+	var report strings.Builder
+
+	for _, inject := range injects {
+		src := inject.Src()
+
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(exp.Spec.BaseDir(), src)
+		}
+
+		status := "ok"
+
+		if info, err := os.Stat(src); err != nil {
+			status = "missing source file"
+		} else if info.IsDir() {
+			status = "source is a directory"
+		}
+
+		fmt.Fprintf(&report, "%s -> %s (%s): %s\n", src, inject.Dst(), inject.Permissions(), status)
+	}
+
+	return report.String()
+}
+
+// writeDiagnostics writes a diagnostic bundle containing the given files, and
+// its summary, to the given experiment files directory. The summary is also
+// written next to the bundle so bundles can be listed without reading them.
+func writeDiagnostics(filesDir string, diag *Diagnostics, files map[string][]byte) error {
+	dir := filepath.Join(filesDir, DIAGNOSTICS_DIR)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating diagnostics directory: %w", err)
+	}
+
+	summary, err := json.MarshalIndent(diag, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling diagnostics summary: %w", err)
+	}
+
+	files["summary.json"] = summary
+
+	names := make([]string, 0, len(files))
+
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	tmp, err := os.CreateTemp(dir, "."+diag.VM+"-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("creating diagnostic bundle: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	var (
+		gz = gzip.NewWriter(tmp)
+		tw = tar.NewWriter(gz)
+	)
+
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:    diag.VM + "/" + name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: diag.Collected,
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			tmp.Close()
+			return fmt.Errorf("writing diagnostic bundle: %w", err)
+		}
+
+		if _, err := tw.Write(files[name]); err != nil {
+			tmp.Close()
+			return fmt.Errorf("writing diagnostic bundle: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing diagnostic bundle: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing diagnostic bundle: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing diagnostic bundle: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(filesDir, diag.Path)); err != nil {
+		return fmt.Errorf("saving diagnostic bundle: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, diag.VM+".json"), summary, 0644); err != nil {
+		return fmt.Errorf("saving diagnostics summary: %w", err)
+	}
+
+	return nil
+}
+
+// ListDiagnostics returns the summaries of the diagnostic bundles collected for
+// the VMs in the given experiment, sorted by VM name.
+func ListDiagnostics(expName string) ([]Diagnostics, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	return listDiagnostics(exp.FilesDir())
+}
+
+func listDiagnostics(filesDir string) ([]Diagnostics, error) {
+	summaries, err := filepath.Glob(filepath.Join(filesDir, DIAGNOSTICS_DIR, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing diagnostic bundles: %w", err)
+	}
+
+	var diags []Diagnostics
+
+	for _, path := range summaries {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading diagnostics summary %s: %w", path, err)
+		}
+
+		var diag Diagnostics
+
+		if err := json.Unmarshal(body, &diag); err != nil {
+			return nil, fmt.Errorf("parsing diagnostics summary %s: %w", path, err)
+		}
+
+		diags = append(diags, diag)
+	}
+
+	sort.Slice(diags, func(i, j int) bool { return diags[i].VM < diags[j].VM })
+
+	return diags, nil
+}
+
+// DiagnosticsBundle returns the summary of the diagnostic bundle collected for
+// the given VM, along with the path to the bundle. An error wrapping
+// os.ErrNotExist is returned if no diagnostics have been collected for it.
+func DiagnosticsBundle(expName, vmName string) (*Diagnostics, string, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, "", fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	body, err := os.ReadFile(filepath.Join(exp.FilesDir(), DIAGNOSTICS_DIR, vmName+".json"))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading diagnostics summary for VM %s: %w", vmName, err)
+	}
+
+	var diag Diagnostics
+
+	if err := json.Unmarshal(body, &diag); err != nil {
+		return nil, "", fmt.Errorf("parsing diagnostics summary for VM %s: %w", vmName, err)
+	}
+
+	return &diag, filepath.Join(exp.FilesDir(), diag.Path), nil
+}
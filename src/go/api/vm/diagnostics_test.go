@@ -0,0 +1,126 @@
+package vm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	filesDir := t.TempDir()
+
+	diag := &Diagnostics{
+		VM:        "host-00",
+		Host:      "compute1",
+		State:     "ERROR",
+		Reason:    "VM failed to boot (error)",
+		Collected: time.Now().UTC(),
+		Path:      filepath.Join(DIAGNOSTICS_DIR, "host-00.tar.gz"),
+	}
+
+	files := map[string][]byte{
+		"qemu.log":   []byte("qemu-system-x86_64: could not open disk image\n"),
+		"serial.log": []byte("Booting from Hard Disk...\n"),
+	}
+
+	if err := writeDiagnostics(filesDir, diag, files); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	f, err := os.Open(filepath.Join(filesDir, diag.Path))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var (
+		tr       = tar.NewReader(gz)
+		contents = make(map[string]string)
+	)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		body, _ := io.ReadAll(tr)
+		contents[hdr.Name] = string(body)
+	}
+
+	for _, name := range []string{"host-00/qemu.log", "host-00/serial.log", "host-00/summary.json"} {
+		if _, ok := contents[name]; !ok {
+			t.Logf("expected %s in diagnostic bundle, got %v", name, contents)
+			t.FailNow()
+		}
+	}
+
+	if !strings.Contains(contents["host-00/summary.json"], `"reason": "VM failed to boot (error)"`) {
+		t.Logf("unexpected diagnostics summary: %s", contents["host-00/summary.json"])
+		t.FailNow()
+	}
+
+	diags, err := listDiagnostics(filesDir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(diags) != 1 || diags[0].VM != "host-00" || diags[0].State != "ERROR" {
+		t.Logf("expected diagnostics listed for host-00, got %v", diags)
+		t.FailNow()
+	}
+}
+
+func TestInjectionReport(t *testing.T) {
+	baseDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(baseDir, "startup.sh"), []byte("#!/bin/bash\n"), 0755); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	exp := &types.Experiment{Spec: &v1.ExperimentSpec{BaseDirF: baseDir}}
+
+	injects := []ifaces.NodeInjection{
+		&v1.Injection{SrcF: "startup.sh", DstF: "/etc/phenix/startup/1_startup.sh", PermissionsF: "0755"},
+		&v1.Injection{SrcF: "/does/not/exist", DstF: "/etc/motd"},
+	}
+
+	report := injectionReport(exp, injects)
+
+	expected := []string{
+		baseDir + "/startup.sh -> /etc/phenix/startup/1_startup.sh (0755): ok",
+		"/does/not/exist -> /etc/motd (): missing source file",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(report, e) {
+			t.Logf("expected injection report to contain %q, got: %s", e, report)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/util/common"
+	"phenix/util/mm"
+)
+
+var ErrNoGoldenState = errors.New("no golden state recorded")
+
+var (
+	// DefaultLinuxDriftPaths are the guest paths hashed for Linux VMs when no
+	// paths are provided. They cover accounts, privileges, and common
+	// persistence locations.
+	DefaultLinuxDriftPaths = []string{
+		"/etc/passwd", "/etc/shadow", "/etc/group", "/etc/sudoers", "/etc/sudoers.d",
+		"/etc/crontab", "/etc/cron.d", "/var/spool/cron", "/etc/rc.local",
+		"/etc/systemd/system", "/etc/profile.d", "/etc/ld.so.preload",
+		"/etc/ssh/sshd_config", "/root/.ssh",
+	}
+
+	// DefaultWindowsDriftPaths are the guest paths hashed for Windows VMs when
+	// no paths are provided.
+	DefaultWindowsDriftPaths = []string{
+		`C:\Windows\System32\drivers\etc\hosts`,
+		`C:\Windows\System32\Tasks`,
+		`C:\ProgramData\Microsoft\Windows\Start Menu\Programs\StartUp`,
+	}
+)
+
+// GuestState is the state of a VM guest used to detect drift: the SHA256
+// hashes of files under a set of paths and the installed packages.
+type GuestState struct {
+	Image    string            `json:"image"`
+	VM       string            `json:"vm"`
+	Captured time.Time         `json:"captured"`
+	Paths    []string          `json:"paths"`
+	Files    map[string]string `json:"files"`
+	Packages map[string]string `json:"packages"`
+}
+
+// Drift is the difference between a VM guest's current state and the golden
+// state recorded for its source image.
+type Drift struct {
+	VM       string    `json:"vm"`
+	Image    string    `json:"image"`
+	Golden   time.Time `json:"golden"`
+	Captured time.Time `json:"captured"`
+
+	FilesAdded    []string `json:"filesAdded,omitempty"`
+	FilesRemoved  []string `json:"filesRemoved,omitempty"`
+	FilesModified []string `json:"filesModified,omitempty"`
+
+	PackagesAdded   []string `json:"packagesAdded,omitempty"`
+	PackagesRemoved []string `json:"packagesRemoved,omitempty"`
+	PackagesChanged []string `json:"packagesChanged,omitempty"`
+}
+
+// Drifted returns true if any difference from the golden state was found.
+func (this Drift) Drifted() bool {
+	return len(this.FilesAdded)+len(this.FilesRemoved)+len(this.FilesModified)+
+		len(this.PackagesAdded)+len(this.PackagesRemoved)+len(this.PackagesChanged) > 0
+}
+
+// RecordGoldenState captures the current state of the given running VM via
+// cc and records it as the golden state for the VM's source image. It should
+// be called on a VM freshly booted from the image. If no paths are provided,
+// the default paths for the VM's OS type are used.
+func RecordGoldenState(ctx context.Context, expName, vmName string, paths []string) (*GuestState, error) {
+	state, err := CaptureGuestState(ctx, expName, vmName, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	path := goldenStatePath(state.Image)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating golden state directory: %w", err)
+	}
+
+	body, _ := json.Marshal(state)
+
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return nil, fmt.Errorf("writing golden state for image %s: %w", state.Image, err)
+	}
+
+	return state, nil
+}
+
+// GoldenState returns the golden state recorded for the given image.
+func GoldenState(image string) (*GuestState, error) {
+	body, err := os.ReadFile(goldenStatePath(filepath.Base(image)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for image %s", ErrNoGoldenState, image)
+		}
+
+		return nil, fmt.Errorf("reading golden state for image %s: %w", image, err)
+	}
+
+	var state GuestState
+
+	if err := json.Unmarshal(body, &state); err != nil {
+		return nil, fmt.Errorf("parsing golden state for image %s: %w", image, err)
+	}
+
+	return &state, nil
+}
+
+// CheckDrift captures the current state of the given running VM via cc and
+// compares it against the golden state recorded for the VM's source image,
+// using the same paths the golden state was captured with.
+func CheckDrift(ctx context.Context, expName, vmName string) (*Drift, error) {
+	vm, err := Get(expName, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("getting VM details: %w", err)
+	}
+
+	golden, err := GoldenState(vm.Disk)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := CaptureGuestState(ctx, expName, vmName, golden.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := compareGuestState(*golden, *current)
+
+	return &drift, nil
+}
+
+// CaptureGuestState captures the current state of the given running VM via
+// cc. If no paths are provided, the default paths for the VM's OS type are
+// used.
+func CaptureGuestState(ctx context.Context, expName, vmName string, paths []string) (*GuestState, error) {
+	vm, err := Get(expName, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("getting VM details: %w", err)
+	}
+
+	if !vm.Running {
+		return nil, fmt.Errorf("VM is not running")
+	}
+
+	if vm.Disk == "" {
+		return nil, fmt.Errorf("VM %s has no disk image", vmName)
+	}
+
+	windows := strings.EqualFold(vm.OSType, "windows")
+
+	if len(paths) == 0 {
+		paths = DefaultLinuxDriftPaths
+
+		if windows {
+			paths = DefaultWindowsDriftPaths
+		}
+	}
+
+	hashCmd, pkgCmd := linuxDriftCommands(paths)
+
+	if windows {
+		hashCmd, pkgCmd = windowsDriftCommands(paths)
+	}
+
+	hashes, err := execGuestCommand(ctx, expName, vmName, hashCmd)
+	if err != nil {
+		return nil, fmt.Errorf("hashing guest files: %w", err)
+	}
+
+	pkgs, err := execGuestCommand(ctx, expName, vmName, pkgCmd)
+	if err != nil {
+		return nil, fmt.Errorf("listing guest packages: %w", err)
+	}
+
+	state := &GuestState{
+		Image:    filepath.Base(vm.Disk),
+		VM:       vmName,
+		Captured: time.Now().UTC(),
+		Paths:    paths,
+		Files:    parseFileHashes(hashes),
+		Packages: parsePackages(pkgs),
+	}
+
+	return state, nil
+}
+
+func linuxDriftCommands(paths []string) (string, string) {
+	quoted := make([]string, len(paths))
+
+	for i, p := range paths {
+		quoted[i] = "'" + p + "'"
+	}
+
+	var (
+		hash = fmt.Sprintf(`sh -c "find %s -xdev -type f -exec sha256sum {} + 2>/dev/null"`, strings.Join(quoted, " "))
+		pkgs = `sh -c "dpkg-query -W -f '${Package}\t${Version}\n' 2>/dev/null || rpm -qa --qf '%{NAME}\t%{VERSION}-%{RELEASE}\n' 2>/dev/null"`
+	)
+
+	return hash, pkgs
+}
+
+func windowsDriftCommands(paths []string) (string, string) {
+	quoted := make([]string, len(paths))
+
+	for i, p := range paths {
+		quoted[i] = "'" + p + "'"
+	}
+
+	var (
+		hash = fmt.Sprintf(`powershell -command "Get-ChildItem -Path %s -Recurse -File -Force -ErrorAction SilentlyContinue | Get-FileHash -Algorithm SHA256 | ForEach-Object { $_.Hash.ToLower() + '  ' + $_.Path }"`, strings.Join(quoted, ","))
+		pkgs = `powershell -command "Get-ItemProperty HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*, HKLM:\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Uninstall\* -ErrorAction SilentlyContinue | Where-Object DisplayName | ForEach-Object { $_.DisplayName + [char]9 + $_.DisplayVersion }"`
+	)
+
+	return hash, pkgs
+}
+
+func execGuestCommand(ctx context.Context, expName, vmName, command string) (string, error) {
+	opts := []mm.C2Option{mm.C2NS(expName), mm.C2VM(vmName), mm.C2Context(ctx), mm.C2Timeout(5 * time.Minute)}
+
+	id, err := mm.ExecC2Command(append(opts, mm.C2Command(command), mm.C2Wait())...)
+	if err != nil {
+		return "", err
+	}
+
+	return mm.GetC2Response(append(opts, mm.C2CommandID(id), mm.C2ResponseTypeStdout())...)
+}
+
+// parseFileHashes parses `sha256sum` style output (hash, two spaces, path).
+func parseFileHashes(out string) map[string]string {
+	files := make(map[string]string)
+
+	for _, line := range strings.Split(out, "\n") {
+		hash, path, ok := strings.Cut(strings.TrimRight(line, "\r"), "  ")
+		if !ok || path == "" {
+			continue
+		}
+
+		files[path] = hash
+	}
+
+	return files
+}
+
+// parsePackages parses tab separated package names and versions.
+func parsePackages(out string) map[string]string {
+	pkgs := make(map[string]string)
+
+	for _, line := range strings.Split(out, "\n") {
+		name, version, _ := strings.Cut(strings.TrimRight(line, "\r"), "\t")
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		pkgs[name] = version
+	}
+
+	return pkgs
+}
+
+func compareGuestState(golden, current GuestState) Drift {
+	drift := Drift{
+		VM:       current.VM,
+		Image:    golden.Image,
+		Golden:   golden.Captured,
+		Captured: current.Captured,
+	}
+
+	for path, hash := range current.Files {
+		if orig, ok := golden.Files[path]; !ok {
+			drift.FilesAdded = append(drift.FilesAdded, path)
+		} else if orig != hash {
+			drift.FilesModified = append(drift.FilesModified, path)
+		}
+	}
+
+	for path := range golden.Files {
+		if _, ok := current.Files[path]; !ok {
+			drift.FilesRemoved = append(drift.FilesRemoved, path)
+		}
+	}
+
+	for name, version := range current.Packages {
+		if orig, ok := golden.Packages[name]; !ok {
+			drift.PackagesAdded = append(drift.PackagesAdded, name+" "+version)
+		} else if orig != version {
+			drift.PackagesChanged = append(drift.PackagesChanged, fmt.Sprintf("%s %s -> %s", name, orig, version))
+		}
+	}
+
+	for name, version := range golden.Packages {
+		if _, ok := current.Packages[name]; !ok {
+			drift.PackagesRemoved = append(drift.PackagesRemoved, name+" "+version)
+		}
+	}
+
+	for _, s := range [][]string{
+		drift.FilesAdded, drift.FilesRemoved, drift.FilesModified,
+		drift.PackagesAdded, drift.PackagesRemoved, drift.PackagesChanged,
+	} {
+		sort.Strings(s)
+	}
+
+	return drift
+}
+
+func goldenStatePath(image string) string {
+	return filepath.Join(common.PhenixBase, "golden-states", image+".json")
+}
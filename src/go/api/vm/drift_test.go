@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFileHashes(t *testing.T) {
+	out := "e3b0c442  /etc/passwd\r\n9f86d081  /etc/cron.d/my job\n\n"
+
+	files := parseFileHashes(out)
+
+	expected := map[string]string{"/etc/passwd": "e3b0c442", "/etc/cron.d/my job": "9f86d081"}
+
+	if !reflect.DeepEqual(files, expected) {
+		t.Logf("unexpected parsed file hashes: %v", files)
+		t.FailNow()
+	}
+}
+
+func TestCompareGuestState(t *testing.T) {
+	golden := GuestState{
+		Image:    "ubuntu.qc2",
+		Files:    map[string]string{"/etc/passwd": "aaa", "/etc/shadow": "bbb", "/etc/rc.local": "ccc"},
+		Packages: parsePackages("openssh-server\t1:8.9p1\ncurl\t7.81.0\ntelnet\t0.17\n"),
+	}
+
+	if d := compareGuestState(golden, golden); d.Drifted() {
+		t.Logf("expected no drift comparing golden state to itself, got %+v", d)
+		t.FailNow()
+	}
+
+	current := GuestState{
+		VM:       "student-1",
+		Files:    map[string]string{"/etc/passwd": "xxx", "/etc/shadow": "bbb", "/root/.ssh/authorized_keys": "ddd"},
+		Packages: parsePackages("openssh-server\t1:8.9p2\ncurl\t7.81.0\nnetcat\t1.218\n"),
+	}
+
+	d := compareGuestState(golden, current)
+
+	if !d.Drifted() {
+		t.Log("expected drift")
+		t.FailNow()
+	}
+
+	checks := map[string][2][]string{
+		"files added":      {d.FilesAdded, {"/root/.ssh/authorized_keys"}},
+		"files removed":    {d.FilesRemoved, {"/etc/rc.local"}},
+		"files modified":   {d.FilesModified, {"/etc/passwd"}},
+		"packages added":   {d.PackagesAdded, {"netcat 1.218"}},
+		"packages removed": {d.PackagesRemoved, {"telnet 0.17"}},
+		"packages changed": {d.PackagesChanged, {"openssh-server 1:8.9p1 -> 1:8.9p2"}},
+	}
+
+	for name, c := range checks {
+		if !reflect.DeepEqual(c[0], c[1]) {
+			t.Logf("unexpected %s: got %v, expected %v", name, c[0], c[1])
+			t.FailNow()
+		}
+	}
+}
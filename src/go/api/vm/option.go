@@ -1,5 +1,32 @@
 package vm
 
+import "phenix/util/mm"
+
+type ListOption func(*listOptions)
+
+type listOptions struct {
+	mm []mm.Option
+}
+
+func newListOptions(opts ...ListOption) listOptions {
+	var o listOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ListWithCachedInfo allows details for running VMs to be served from the
+// minimega VM info cache (see `mm.VM_INFO_TTL`). It should be used by callers
+// that list VMs frequently, like the UI VM table.
+func ListWithCachedInfo() ListOption {
+	return func(o *listOptions) {
+		o.mm = append(o.mm, mm.Cached())
+	}
+}
+
 type UpdateOption func(*updateOptions)
 
 type iface struct {
@@ -155,3 +182,67 @@ func InjectPartition(p int) RedeployOption {
 		o.part = p
 	}
 }
+
+// BroadcastOption is a function that configures options for broadcasting to a
+// group of VMs. It is used in `vm.Broadcast`.
+type BroadcastOption func(*broadcastOptions)
+
+type broadcastOptions struct {
+	vms     []string
+	labels  map[string]string
+	keys    string
+	command string
+	file    string
+}
+
+func newBroadcastOptions(opts ...BroadcastOption) broadcastOptions {
+	o := broadcastOptions{labels: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// BroadcastToVMs adds the VMs with the given names to the group being
+// broadcast to.
+func BroadcastToVMs(v ...string) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.vms = append(o.vms, v...)
+	}
+}
+
+// BroadcastToLabel adds the VMs with the given label key and value in the
+// topology to the group being broadcast to. VMs must match all the labels
+// provided.
+func BroadcastToLabel(k, v string) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.labels[k] = v
+	}
+}
+
+// BroadcastKeys sets the text typed into the console of each VM in the group.
+// Newlines are sent as the Return key and tabs as the Tab key.
+func BroadcastKeys(k string) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.keys = k
+	}
+}
+
+// BroadcastCommand sets the command executed by the cc agent in each VM in the
+// group.
+func BroadcastCommand(c string) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.command = c
+	}
+}
+
+// BroadcastFile sets the file (relative to the minimega files directory) sent
+// by the cc agent to each VM in the group. If a command is also set, it's
+// executed once the file has been sent.
+func BroadcastFile(f string) BroadcastOption {
+	return func(o *broadcastOptions) {
+		o.file = f
+	}
+}
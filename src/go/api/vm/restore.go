@@ -0,0 +1,383 @@
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/common"
+	"phenix/util/file"
+	"phenix/util/plog"
+)
+
+var (
+	ErrRestorePointExists   = errors.New("restore point already exists")
+	ErrRestorePointNotFound = errors.New("restore point not found")
+)
+
+// RestorePoint is a named snapshot of a VM. Restore points form a tree per VM:
+// the parent of a restore point is the restore point the VM was last created
+// from or restored to when it was taken.
+type RestorePoint struct {
+	Name     string          `json:"name"`
+	VM       string          `json:"vm"`
+	Parent   string          `json:"parent,omitempty"`
+	Created  time.Time       `json:"created"`
+	Current  bool            `json:"current,omitempty"`
+	Children []*RestorePoint `json:"children,omitempty"`
+}
+
+// RestorePolicy is the automatic cleanup policy for the restore points of a
+// VM, applied each time a restore point is created. Restore points older than
+// MaxAge are deleted, then the oldest restore points are deleted until at most
+// MaxPoints remain. The VM's current restore point is never deleted. Zero
+// values disable the corresponding cleanup.
+type RestorePolicy struct {
+	MaxPoints int           `json:"maxPoints,omitempty"`
+	MaxAge    time.Duration `json:"maxAge,omitempty"`
+}
+
+// restoreState is the restore point metadata for an experiment, persisted on
+// the headnode. The snapshots themselves live in the experiment files
+// directory on the cluster.
+type restoreState struct {
+	Points   map[string][]RestorePoint `json:"points"`
+	Current  map[string]string         `json:"current"`
+	Policies map[string]RestorePolicy  `json:"policies"`
+}
+
+var restoreMu sync.Mutex
+
+func init() {
+	// Restore point snapshots are deleted along with the experiment files
+	// directory, so their metadata goes too.
+	experiment.RegisterHook("delete", func(stage, name string) {
+		restoreMu.Lock()
+		defer restoreMu.Unlock()
+
+		if err := os.Remove(restoreStatePath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			plog.Error("deleting experiment restore points", "exp", name, "err", err)
+		}
+	})
+}
+
+// CreateRestorePoint snapshots the given running VM as a restore point with
+// the given name, then applies the VM's cleanup policy.
+func CreateRestorePoint(expName, vmName, name string, cb func(string)) error {
+	if name == "" || strings.ContainsAny(name, "/ ") || strings.Contains(name, "__") {
+		return fmt.Errorf("invalid restore point name %q", name)
+	}
+
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range state.Points[vmName] {
+		if p.Name == name {
+			return ErrRestorePointExists
+		}
+	}
+
+	if err := Snapshot(expName, vmName, name, cb); err != nil {
+		return fmt.Errorf("snapshotting VM %s: %w", vmName, err)
+	}
+
+	state.Points[vmName] = append(state.Points[vmName], RestorePoint{
+		Name:    name,
+		VM:      vmName,
+		Parent:  state.Current[vmName],
+		Created: time.Now().UTC(),
+	})
+
+	state.Current[vmName] = name
+
+	_, remove := prune(state.Points[vmName], name, state.Policies[vmName], time.Now())
+
+	for _, p := range remove {
+		if err := deleteRestorePoint(expName, state, p); err != nil {
+			return fmt.Errorf("cleaning up restore point %s: %w", p.Name, err)
+		}
+	}
+
+	return saveRestoreState(expName, state)
+}
+
+// RestorePoints returns the restore point trees for the given VM, sorted by
+// creation time. Snapshots of the VM not created as restore points are
+// included as roots, and restore points whose snapshots no longer exist on the
+// cluster are dropped.
+func RestorePoints(expName, vmName string) ([]*RestorePoint, error) {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := Snapshots(expName, vmName)
+	if err != nil {
+		return nil, fmt.Errorf("getting list of snapshots for VM: %w", err)
+	}
+
+	var (
+		existing = make(map[string]struct{})
+		points   []RestorePoint
+	)
+
+	for _, ss := range snapshots {
+		existing[strings.TrimPrefix(ss, vmName+"__")] = struct{}{}
+	}
+
+	for _, p := range state.Points[vmName] {
+		if _, ok := existing[p.Name]; ok {
+			points = append(points, p)
+			delete(existing, p.Name)
+		}
+	}
+
+	for name := range existing {
+		points = append(points, RestorePoint{Name: name, VM: vmName})
+	}
+
+	return restoreTree(points, state.Current[vmName]), nil
+}
+
+// RestoreToPoint restores the given VM to the restore point with the given
+// name, making it the parent of any restore points created afterwards.
+func RestoreToPoint(expName, vmName, name string) error {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return err
+	}
+
+	if err := Restore(expName, vmName, vmName+"__"+name); err != nil {
+		return fmt.Errorf("restoring VM %s to %s: %w", vmName, name, err)
+	}
+
+	state.Current[vmName] = name
+
+	return saveRestoreState(expName, state)
+}
+
+// DeleteRestorePoint deletes the restore point with the given name for the
+// given VM, including its snapshot files. Children of the deleted restore point
+// are moved to its parent.
+func DeleteRestorePoint(expName, vmName, name string) error {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range state.Points[vmName] {
+		if p.Name == name {
+			if err := deleteRestorePoint(expName, state, p); err != nil {
+				return err
+			}
+
+			return saveRestoreState(expName, state)
+		}
+	}
+
+	return ErrRestorePointNotFound
+}
+
+// SetRestorePolicy sets the automatic cleanup policy for the restore points of
+// the given VM.
+func SetRestorePolicy(expName, vmName string, policy RestorePolicy) error {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return err
+	}
+
+	state.Policies[vmName] = policy
+
+	return saveRestoreState(expName, state)
+}
+
+// GetRestorePolicy returns the automatic cleanup policy for the restore points
+// of the given VM.
+func GetRestorePolicy(expName, vmName string) (RestorePolicy, error) {
+	restoreMu.Lock()
+	defer restoreMu.Unlock()
+
+	state, err := loadRestoreState(expName)
+	if err != nil {
+		return RestorePolicy{}, err
+	}
+
+	return state.Policies[vmName], nil
+}
+
+func deleteRestorePoint(expName string, state *restoreState, point RestorePoint) error {
+	base := fmt.Sprintf("%s/files/%s__%s", expName, point.VM, point.Name)
+
+	for _, ext := range []string{".SNAP", ".qc2"} {
+		if err := file.DeleteFile(base + ext); err != nil {
+			return fmt.Errorf("deleting snapshot file for restore point %s: %w", point.Name, err)
+		}
+	}
+
+	// Use the parent from the state since it may have changed if other restore
+	// points were deleted while cleaning up.
+	parent := point.Parent
+
+	for _, p := range state.Points[point.VM] {
+		if p.Name == point.Name {
+			parent = p.Parent
+		}
+	}
+
+	var points []RestorePoint
+
+	for _, p := range state.Points[point.VM] {
+		if p.Name == point.Name {
+			continue
+		}
+
+		if p.Parent == point.Name {
+			p.Parent = parent
+		}
+
+		points = append(points, p)
+	}
+
+	state.Points[point.VM] = points
+
+	if state.Current[point.VM] == point.Name {
+		state.Current[point.VM] = parent
+	}
+
+	return nil
+}
+
+// prune returns the restore points to keep and remove per the given policy.
+func prune(points []RestorePoint, current string, policy RestorePolicy, now time.Time) ([]RestorePoint, []RestorePoint) {
+	var keep, remove []RestorePoint
+
+	for _, p := range points {
+		if policy.MaxAge > 0 && p.Name != current && now.Sub(p.Created) > policy.MaxAge {
+			remove = append(remove, p)
+			continue
+		}
+
+		keep = append(keep, p)
+	}
+
+	if policy.MaxPoints <= 0 || len(keep) <= policy.MaxPoints {
+		return keep, remove
+	}
+
+	sort.SliceStable(keep, func(i, j int) bool { return keep[i].Created.Before(keep[j].Created) })
+
+	var (
+		extra = len(keep) - policy.MaxPoints
+		kept  []RestorePoint
+	)
+
+	for _, p := range keep {
+		if extra > 0 && p.Name != current {
+			remove = append(remove, p)
+			extra--
+
+			continue
+		}
+
+		kept = append(kept, p)
+	}
+
+	return kept, remove
+}
+
+// restoreTree builds the restore point trees for the given restore points.
+// Restore points with missing parents become roots.
+func restoreTree(points []RestorePoint, current string) []*RestorePoint {
+	var (
+		nodes = make(map[string]*RestorePoint)
+		roots []*RestorePoint
+	)
+
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Created.Before(points[j].Created) })
+
+	for i := range points {
+		p := points[i]
+		p.Current = p.Name == current
+
+		nodes[p.Name] = &p
+	}
+
+	for _, p := range points {
+		node := nodes[p.Name]
+
+		if parent, ok := nodes[p.Parent]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots
+}
+
+func restoreStatePath(expName string) string {
+	return filepath.Join(common.PhenixBase, "restore-points", expName+".json")
+}
+
+func loadRestoreState(expName string) (*restoreState, error) {
+	state := &restoreState{
+		Points:   make(map[string][]RestorePoint),
+		Current:  make(map[string]string),
+		Policies: make(map[string]RestorePolicy),
+	}
+
+	body, err := os.ReadFile(restoreStatePath(expName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+
+		return nil, fmt.Errorf("reading restore points for experiment %s: %w", expName, err)
+	}
+
+	if err := json.Unmarshal(body, state); err != nil {
+		return nil, fmt.Errorf("parsing restore points for experiment %s: %w", expName, err)
+	}
+
+	return state, nil
+}
+
+func saveRestoreState(expName string, state *restoreState) error {
+	path := restoreStatePath(expName)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating restore points directory: %w", err)
+	}
+
+	body, _ := json.Marshal(state)
+
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("writing restore points for experiment %s: %w", expName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package vm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestorePrune(t *testing.T) {
+	now := time.Now()
+
+	points := []RestorePoint{
+		{Name: "base", Created: now.Add(-3 * time.Hour)},
+		{Name: "pre-malware", Parent: "base", Created: now.Add(-2 * time.Hour)},
+		{Name: "post-malware", Parent: "pre-malware", Created: now.Add(-1 * time.Hour)},
+		{Name: "post-patch", Parent: "base", Created: now},
+	}
+
+	keep, remove := prune(points, "post-patch", RestorePolicy{}, now)
+
+	if len(keep) != 4 || len(remove) != 0 {
+		t.Logf("expected empty policy to keep all restore points, removed %v", remove)
+		t.FailNow()
+	}
+
+	keep, remove = prune(points, "base", RestorePolicy{MaxAge: 90 * time.Minute}, now)
+
+	if len(keep) != 3 || len(remove) != 1 || remove[0].Name != "pre-malware" {
+		t.Logf("expected max age to only remove pre-malware, removed %v", remove)
+		t.FailNow()
+	}
+
+	keep, remove = prune(points, "base", RestorePolicy{MaxPoints: 2}, now)
+
+	if len(keep) != 2 || keep[0].Name != "base" || keep[1].Name != "post-patch" {
+		t.Logf("expected max points to keep base and post-patch, kept %v", keep)
+		t.FailNow()
+	}
+
+	if len(remove) != 2 {
+		t.Logf("expected max points to remove 2 restore points, removed %v", remove)
+		t.FailNow()
+	}
+}
+
+func TestRestoreTree(t *testing.T) {
+	now := time.Now()
+
+	points := []RestorePoint{
+		{Name: "post-patch", Parent: "base", Created: now},
+		{Name: "base", Created: now.Add(-3 * time.Hour)},
+		{Name: "post-malware", Parent: "pre-malware", Created: now.Add(-1 * time.Hour)},
+		{Name: "pre-malware", Parent: "base", Created: now.Add(-2 * time.Hour)},
+		{Name: "orphan", Parent: "deleted", Created: now.Add(-4 * time.Hour)},
+	}
+
+	roots := restoreTree(points, "post-malware")
+
+	if len(roots) != 2 || roots[0].Name != "orphan" || roots[1].Name != "base" {
+		t.Logf("expected orphan and base roots, got %v", roots)
+		t.FailNow()
+	}
+
+	base := roots[1]
+
+	if len(base.Children) != 2 || base.Children[0].Name != "pre-malware" || base.Children[1].Name != "post-patch" {
+		t.Logf("expected pre-malware and post-patch children of base, got %v", base.Children)
+		t.FailNow()
+	}
+
+	pre := base.Children[0]
+
+	if len(pre.Children) != 1 || !pre.Children[0].Current {
+		t.Log("expected post-malware to be the current child of pre-malware")
+		t.FailNow()
+	}
+}
@@ -2,8 +2,10 @@ package vm
 
 import (
 	"fmt"
+	"time"
 
 	"phenix/api/experiment"
+	"phenix/types"
 	"phenix/util/cache"
 	"phenix/util/mm"
 
@@ -23,7 +25,21 @@ type edge struct {
 	Length int `json:"length"`
 }
 
+// topologySearchExpiration is how long topology search indexes are cached for.
+// Cache keys include the revision of the experiment config, so the expiration
+// just cleans up indexes for old revisions.
+const topologySearchExpiration = 10 * time.Minute
+
+func topologySearchCacheKey(exp *types.Experiment) string {
+	return fmt.Sprintf("experiment|%s|search|%d", exp.Metadata.Name, exp.Metadata.Revision)
+}
+
 func Topology(exp string, ignore []string) (topology, error) {
+	e, err := experiment.Get(exp)
+	if err != nil {
+		return topology{}, fmt.Errorf("getting experiment %s: %w", exp, err)
+	}
+
 	vms, err := List(exp)
 	if err != nil {
 		return topology{}, fmt.Errorf("getting VMs: %w", err)
@@ -33,7 +49,7 @@ func Topology(exp string, ignore []string) (topology, error) {
 		networks = make(map[string]mm.VM)
 		search   TopologySearch
 
-		cacheKey = fmt.Sprintf("experiment|%s|search", exp)
+		cacheKey = topologySearchCacheKey(e)
 		cached   bool
 
 		nodes  []mm.VM
@@ -79,7 +95,7 @@ func Topology(exp string, ignore []string) (topology, error) {
 			}
 
 			if !cached {
-				// TODO: what if these change during an experiment (e.g., via user updates)?
+				// TODO: what if these change during an experiment (e.g., via DHCP)?
 				search.AddVLAN(iface, node.ID)
 				search.AddIP(vm.IPv4[i], node.ID)
 			}
@@ -99,15 +115,35 @@ func Topology(exp string, ignore []string) (topology, error) {
 	}
 
 	if !cached {
-		// TODO: cache with expire?
-		cache.Set(cacheKey, search)
+		cache.SetWithExpire(cacheKey, search, topologySearchExpiration)
+	}
+
+	return topology{Nodes: nodes, Edges: edges, Running: e.Running()}, nil
+}
+
+// GetTopologySearch returns the topology search index for the experiment with
+// the given name, building it if it isn't already cached for the experiment's
+// current spec.
+func GetTopologySearch(exp string) (TopologySearch, error) {
+	e, err := experiment.Get(exp)
+	if err != nil {
+		return TopologySearch{}, fmt.Errorf("getting experiment %s: %w", exp, err)
+	}
+
+	cacheKey := topologySearchCacheKey(e)
+
+	if val, ok := cache.Get(cacheKey); ok {
+		return val.(TopologySearch), nil
 	}
 
-	topo := topology{Nodes: nodes, Edges: edges}
+	if _, err := Topology(exp, nil); err != nil {
+		return TopologySearch{}, fmt.Errorf("getting experiment topology: %w", err)
+	}
 
-	if exp, err := experiment.Get(exp); err == nil {
-		topo.Running = exp.Running()
+	val, ok := cache.Get(cacheKey)
+	if !ok {
+		return TopologySearch{}, fmt.Errorf("experiment %s was updated while building topology search", exp)
 	}
 
-	return topo, nil
+	return val.(TopologySearch), nil
 }
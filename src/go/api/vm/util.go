@@ -58,6 +58,10 @@ func getVMImage(expName, vmName string) (string, error) {
 		return "", fmt.Errorf("getting vm %s for experiment %s", vmName, expName)
 	}
 
+	if len(vm.Hardware().Drives()) == 0 {
+		return "", fmt.Errorf("vm %s for experiment %s does not have any disks", vmName, expName)
+	}
+
 	// base image from topology
 	return vm.Hardware().Drives()[0].Image(), nil
 }
@@ -15,9 +15,12 @@ import (
 	"time"
 
 	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/file"
+	"phenix/util/journal"
 	"phenix/util/mm"
 	"phenix/util/mm/mmcli"
 
@@ -42,27 +45,110 @@ func Count(expName string) (int, error) {
 // List collects VMs, combining topology settings with running VM details if the
 // experiment is running. It returns a slice of VM structs and any errors
 // encountered while gathering them.
-func List(expName string) ([]mm.VM, error) {
+func List(expName string, opts ...ListOption) ([]mm.VM, error) {
 	if expName == "" {
 		return nil, fmt.Errorf("no experiment name provided")
 	}
 
-	exp, err := experiment.Get(expName)
-	if err != nil {
+	o := newListOptions(opts...)
+
+	c, _ := store.NewConfig("experiment/" + expName)
+
+	if err := store.Get(c); err != nil {
 		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
 	}
 
+	// Rendering VM details from the topology of large experiments is expensive,
+	// so they're cached until the experiment is updated.
+	val, err := types.RenderExperiment(*c, "vms", func(exp *types.Experiment) (any, error) {
+		return renderedVMs{Running: exp.Running(), VMs: topologyVMs(exp)}, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("rendering VMs for experiment %s: %w", expName, err)
+	}
+
 	var (
-		running = make(map[string]mm.VM)
-		vms     []mm.VM
+		rendered = val.(renderedVMs)
+		running  = make(map[string]mm.VM)
+		vms      []mm.VM
 	)
 
-	if exp.Running() {
-		for _, vm := range mm.GetVMInfo(mm.NS(expName)) {
+	if rendered.Running {
+		for _, vm := range mm.GetVMInfo(append(o.mm, mm.NS(expName))...) {
 			running[vm.Name] = vm
 		}
 	}
 
+	for _, vm := range rendered.VMs {
+		if vm.State == "EXTERNAL" {
+			vms = append(vms, vm)
+			continue
+		}
+
+		if details, ok := running[vm.Name]; ok {
+			vm.Host = details.Host
+			vm.State = details.State
+			vm.Running = details.Running
+			vm.Networks = details.Networks
+			vm.Taps = details.Taps
+			vm.IPv4 = details.IPv4
+			vm.Captures = details.Captures
+			vm.CdRom = details.CdRom
+			vm.Tags = details.Tags
+			vm.Uptime = details.Uptime
+			vm.CPUs = details.CPUs
+			vm.RAM = details.RAM
+			vm.Disk = details.Disk
+			vm.CCActive = details.CCActive
+			vm.GuestIPs = details.GuestIPs
+
+			// `vm.IPv4` could be nil/empty if minimega isn't reporting any IPs for it
+			if len(vm.IPv4) == 0 {
+				vm.IPv4 = make([]string, len(details.Networks))
+			}
+
+			// Since we get the IP from the experiment config, but the network name
+			// from minimega (to preserve iface to network ordering), make sure the
+			// ordering of IPs matches the odering of networks. We could just use a
+			// map here, but then the iface to network ordering that minimega ensures
+			// would be lost.
+			for idx, nw := range details.Networks {
+				// If it's set here, we got it from minimega, which is the source of truth
+				// for running experiments.
+				if vm.IPv4[idx] != "" {
+					continue
+				}
+
+				// At this point, `nw` will look something like `EXP_1 (101)`. In the
+				// experiment config, we just have `EXP_1` so we need to use that
+				// portion from minimega as the `Interfaces` map key.
+				if match := vlanAliasRegex.FindStringSubmatch(nw); match != nil {
+					vm.IPv4[idx] = vm.Interfaces[match[1]]
+				} else {
+					vm.IPv4[idx] = "n/a"
+				}
+			}
+		}
+
+		vms = append(vms, vm)
+	}
+
+	return vms, nil
+}
+
+// renderedVMs are the VMs rendered from an experiment's topology, along with
+// whether the experiment was running when they were rendered.
+type renderedVMs struct {
+	Running bool
+	VMs     []mm.VM
+}
+
+// topologyVMs renders the details of each VM in the given experiment's topology
+// with defaults and schedules applied, without any running VM details.
+func topologyVMs(exp *types.Experiment) []mm.VM {
+	var vms []mm.VM
+
 	for idx, node := range exp.Spec.Topology().Nodes() {
 		var (
 			disk            string
@@ -96,6 +182,7 @@ func List(expName string) ([]mm.VM, error) {
 			Type:            node.Type(),
 			OSType:          node.Hardware().OSType(),
 			Snapshot:        snapshot,
+			Persistent:      node.General().Persistent() != nil && *node.General().Persistent(),
 		}
 
 		for _, iface := range node.Network().Interfaces() {
@@ -109,48 +196,6 @@ func List(expName string) ([]mm.VM, error) {
 
 		if node.External() {
 			vm.State = "EXTERNAL"
-		} else if details, ok := running[vm.Name]; ok {
-			vm.Host = details.Host
-			vm.State = details.State
-			vm.Running = details.Running
-			vm.Networks = details.Networks
-			vm.Taps = details.Taps
-			vm.IPv4 = details.IPv4
-			vm.Captures = details.Captures
-			vm.CdRom = details.CdRom
-			vm.Tags = details.Tags
-			vm.Uptime = details.Uptime
-			vm.CPUs = details.CPUs
-			vm.RAM = details.RAM
-			vm.Disk = details.Disk
-			vm.CCActive = details.CCActive
-
-			// `vm.IPv4` could be nil/empty if minimega isn't reporting any IPs for it
-			if len(vm.IPv4) == 0 {
-				vm.IPv4 = make([]string, len(details.Networks))
-			}
-
-			// Since we get the IP from the experiment config, but the network name
-			// from minimega (to preserve iface to network ordering), make sure the
-			// ordering of IPs matches the odering of networks. We could just use a
-			// map here, but then the iface to network ordering that minimega ensures
-			// would be lost.
-			for idx, nw := range details.Networks {
-				// If it's set here, we got it from minimega, which is the source of truth
-				// for running experiments.
-				if vm.IPv4[idx] != "" {
-					continue
-				}
-
-				// At this point, `nw` will look something like `EXP_1 (101)`. In the
-				// experiment config, we just have `EXP_1` so we need to use that
-				// portion from minimega as the `Interfaces` map key.
-				if match := vlanAliasRegex.FindStringSubmatch(nw); match != nil {
-					vm.IPv4[idx] = vm.Interfaces[match[1]]
-				} else {
-					vm.IPv4[idx] = "n/a"
-				}
-			}
 		} else {
 			vm.Host = exp.Spec.Schedules()[vm.Name]
 		}
@@ -158,7 +203,7 @@ func List(expName string) ([]mm.VM, error) {
 		vms = append(vms, vm)
 	}
 
-	return vms, nil
+	return vms
 }
 
 // Get retrieves the VM with the given name from the experiment with the given
@@ -187,20 +232,24 @@ func Get(expName, vmName string) (*mm.VM, error) {
 		}
 
 		vm = &mm.VM{
-			ID:              idx,
-			Name:            node.General().Hostname(),
-			Experiment:      exp.Spec.ExperimentName(),
-			CPUs:            node.Hardware().VCPU(),
-			RAM:             node.Hardware().Memory(),
-			Disk:            util.GetMMFullPath(node.Hardware().Drives()[0].Image()),
-			InjectPartition: *node.Hardware().Drives()[0].InjectPartition(),
-			Interfaces:      make(map[string]string),
-			DoNotBoot:       *node.General().DoNotBoot(),
-			OSType:          string(node.Hardware().OSType()),
-			Metadata:        make(map[string]interface{}),
-			Labels:          node.Labels(),
-			Annotations:     node.Annotations(),
-			Snapshot:        *node.General().Snapshot(),
+			ID:          idx,
+			Name:        node.General().Hostname(),
+			Experiment:  exp.Spec.ExperimentName(),
+			CPUs:        node.Hardware().VCPU(),
+			RAM:         node.Hardware().Memory(),
+			Interfaces:  make(map[string]string),
+			DoNotBoot:   *node.General().DoNotBoot(),
+			OSType:      string(node.Hardware().OSType()),
+			Metadata:    make(map[string]interface{}),
+			Labels:      node.Labels(),
+			Annotations: node.Annotations(),
+			Snapshot:    *node.General().Snapshot(),
+			Persistent:  *node.General().Persistent(),
+		}
+
+		if drives := node.Hardware().Drives(); len(drives) > 0 {
+			vm.Disk = util.GetMMFullPath(drives[0].Image())
+			vm.InjectPartition = *drives[0].InjectPartition()
 		}
 
 		for _, iface := range node.Network().Interfaces() {
@@ -247,6 +296,7 @@ func Get(expName, vmName string) (*mm.VM, error) {
 	vm.RAM = details[0].RAM
 	vm.Disk = details[0].Disk
 	vm.CCActive = details[0].CCActive
+	vm.GuestIPs = details[0].GuestIPs
 
 	// `vm.IPv4` could be nil/empty if minimega isn't reporting any IPs for it
 	if len(vm.IPv4) == 0 {
@@ -318,6 +368,12 @@ func Update(opts ...UpdateOption) error {
 		vm.Hardware().SetMemory(o.mem)
 	}
 
+	if o.disk != "" || o.partition != 0 {
+		if len(vm.Hardware().Drives()) == 0 {
+			return fmt.Errorf("VM %s in experiment %s does not have any disks", o.vm, o.exp)
+		}
+	}
+
 	if o.disk != "" {
 		vm.Hardware().Drives()[0].SetImage(o.disk)
 	}
@@ -379,6 +435,8 @@ func Pause(expName, vmName string) error {
 		return fmt.Errorf("pausing VM: %w", err)
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s paused", vmName)
+
 	return nil
 }
 
@@ -401,8 +459,13 @@ func Restart(expName, vmName string) error {
 
 	//Using "system_reset" on a VM that is in the "QUIT" state fails
 	if state == "QUIT" {
-		return mm.StartVM(mm.NS(expName), mm.VMName(vmName))
+		if err := mm.StartVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
+			return err
+		}
 
+		journal.Record(expName, journal.CategoryVM, "", "VM %s restarted", vmName)
+
+		return nil
 	}
 
 	cmd := mmcli.NewNamespacedCommand(expName)
@@ -414,6 +477,8 @@ func Restart(expName, vmName string) error {
 		return fmt.Errorf("restarting VM %s: %w", vmName, err)
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s restarted", vmName)
+
 	return nil
 }
 
@@ -486,6 +551,8 @@ func Shutdown(expName, vmName string) error {
 		}
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s shut down", vmName)
+
 	return nil
 }
 
@@ -589,6 +656,8 @@ func Resume(expName, vmName string) error {
 		return fmt.Errorf("resuming VM: %w", err)
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s resumed", vmName)
+
 	return nil
 }
 
@@ -620,7 +689,7 @@ func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 				continue
 			}
 
-			if o.disk == "" {
+			if o.disk == "" && len(n.Hardware().Drives()) > 0 {
 				o.disk = n.Hardware().Drives()[0].Image()
 				o.part = *n.Hardware().Drives()[0].InjectPartition()
 			}
@@ -647,6 +716,8 @@ func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 		return fmt.Errorf("redeploying VM: %w", err)
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s redeployed", vmName)
+
 	return nil
 }
 
@@ -665,6 +736,8 @@ func Kill(expName, vmName string) error {
 		return fmt.Errorf("killing VM: %w", err)
 	}
 
+	journal.Record(expName, journal.CategoryVM, "", "VM %s killed", vmName)
+
 	return nil
 }
 
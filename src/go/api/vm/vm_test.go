@@ -0,0 +1,136 @@
+package vm
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+)
+
+func initExperiment(tb testing.TB, nodes int) {
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(tb.TempDir(), "phenix.bdb"))); err != nil {
+		tb.Log(err)
+		tb.FailNow()
+	}
+
+	var (
+		topo      []any
+		schedules = make(map[string]any)
+	)
+
+	for i := 0; i < nodes; i++ {
+		host := fmt.Sprintf("host-%d", i)
+
+		topo = append(topo, map[string]any{
+			"type":     "VirtualMachine",
+			"general":  map[string]any{"hostname": host},
+			"hardware": map[string]any{"vcpus": 1, "memory": 512, "os_type": "linux"},
+			"network": map[string]any{
+				"interfaces": []any{
+					map[string]any{"name": "eth0", "vlan": "EXP", "address": fmt.Sprintf("10.0.%d.%d", i/250, i%250+1)},
+				},
+			},
+		})
+
+		schedules[host] = "compute1"
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "large"},
+		Spec: map[string]any{
+			"experimentName": "large",
+			"topology":       map[string]any{"nodes": topo},
+			"schedules":      schedules,
+		},
+	}
+
+	if err := store.Create(c); err != nil {
+		tb.Log(err)
+		tb.FailNow()
+	}
+}
+
+func TestListRenderCache(t *testing.T) {
+	initExperiment(t, 3)
+
+	vms, err := List("large")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(vms) != 3 || vms[0].Host != "compute1" || vms[0].Interfaces["EXP"] != "10.0.0.1" {
+		t.Logf("unexpected VMs: %+v", vms)
+		t.FailNow()
+	}
+
+	// Modifying the returned VMs must not modify the cached ones.
+	vms[0].Host = "bogus"
+	vms[0].Interfaces["EXP"] = "bogus"
+
+	if vms, _ = List("large"); vms[0].Host != "compute1" || vms[0].Interfaces["EXP"] != "10.0.0.1" {
+		t.Logf("cached VMs were modified: %+v", vms[0])
+		t.FailNow()
+	}
+
+	exp, err := experiment.Get("large")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Updating the experiment invalidates the cached VMs.
+	exp.Spec.Schedules()["host-0"] = "compute2"
+
+	if err := exp.WriteToStore(false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if vms, _ = List("large"); vms[0].Host != "compute2" {
+		t.Logf("expected updated schedule after experiment update, got %s", vms[0].Host)
+		t.FailNow()
+	}
+}
+
+func BenchmarkList(b *testing.B) {
+	initExperiment(b, 1000)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := List("large"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListUncached(b *testing.B) {
+	initExperiment(b, 1000)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c, _ := store.NewConfig("experiment/large")
+
+		if err := store.Get(c); err != nil {
+			b.Fatal(err)
+		}
+
+		// Rendered outputs aren't cached for configs without a revision.
+		c.Metadata.Revision = 0
+
+		_, err := types.RenderExperiment(*c, "vms", func(exp *types.Experiment) (any, error) {
+			return renderedVMs{Running: exp.Running(), VMs: topologyVMs(exp)}, nil
+		})
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
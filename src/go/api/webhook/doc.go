@@ -0,0 +1,3 @@
+// Implementation of the phenix experiment status webhook, used to push
+// experiment status to external range management systems.
+package webhook
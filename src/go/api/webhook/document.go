@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"sort"
+	"time"
+
+	"phenix/types"
+)
+
+// SchemaVersion is the version of the status document schema. It's
+// incremented whenever a change is made to the document that isn't backwards
+// compatible, so external systems can detect documents they don't support.
+const SchemaVersion = 1
+
+// Document is the normalized experiment status document posted to the
+// external API.
+type Document struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Timestamp     time.Time `json:"timestamp"`
+
+	Experiment Status `json:"experiment"`
+}
+
+// Status is the normalized status of a single experiment. State is one of
+// `stopped`, `running`, or `deleted`.
+type Status struct {
+	Name      string            `json:"name"`
+	Topology  string            `json:"topology"`
+	Scenario  string            `json:"scenario,omitempty"`
+	State     string            `json:"state"`
+	StartTime string            `json:"startTime,omitempty"`
+	VMs       int               `json:"vms"`
+	VLANs     map[string]int    `json:"vlans,omitempty"`
+	Schedules map[string]string `json:"schedules,omitempty"`
+	Apps      []AppStatus       `json:"apps,omitempty"`
+}
+
+type AppStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Version string `json:"version,omitempty"`
+	Status  any    `json:"status,omitempty"`
+}
+
+// NewDocument returns the status document for the given experiment.
+func NewDocument(exp types.Experiment) Document {
+	status := Status{
+		Name:     exp.Metadata.Name,
+		Topology: exp.Metadata.Annotations["topology"],
+		Scenario: exp.Metadata.Annotations["scenario"],
+		State:    "stopped",
+	}
+
+	if exp.Spec != nil && exp.Spec.Topology() != nil {
+		status.VMs = len(exp.Spec.Topology().Nodes())
+	}
+
+	if exp.Running() {
+		status.State = "running"
+		status.StartTime = exp.Status.StartTime()
+	}
+
+	if exp.Status != nil {
+		status.VLANs = exp.Status.VLANs()
+		status.Schedules = exp.Status.Schedules()
+
+		var (
+			running  = exp.Status.AppRunning()
+			versions = exp.Status.AppVersions()
+			statuses = exp.Status.AppStatus()
+			names    = make(map[string]struct{})
+		)
+
+		for name := range running {
+			names[name] = struct{}{}
+		}
+
+		for name := range versions {
+			names[name] = struct{}{}
+		}
+
+		for name := range statuses {
+			names[name] = struct{}{}
+		}
+
+		for name := range names {
+			status.Apps = append(status.Apps, AppStatus{
+				Name:    name,
+				Running: running[name],
+				Version: versions[name],
+				Status:  statuses[name],
+			})
+		}
+
+		sort.Slice(status.Apps, func(i, j int) bool {
+			return status.Apps[i].Name < status.Apps[j].Name
+		})
+	}
+
+	return Document{
+		SchemaVersion: SchemaVersion,
+		Timestamp:     time.Now().UTC(),
+		Experiment:    status,
+	}
+}
+
+// newDeletedDocument returns the status document for an experiment that has
+// been deleted.
+func newDeletedDocument(name string) Document {
+	return Document{
+		SchemaVersion: SchemaVersion,
+		Timestamp:     time.Now().UTC(),
+		Experiment:    Status{Name: name, State: "deleted"},
+	}
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"path/filepath"
+	"time"
+
+	"phenix/util/common"
+)
+
+type Option func(*options)
+
+type options struct {
+	url      string
+	secret   string
+	interval time.Duration
+	retries  int
+	backoff  time.Duration
+	timeout  time.Duration
+
+	deadLetters string
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		interval:    5 * time.Second,
+		retries:     5,
+		backoff:     time.Second,
+		timeout:     10 * time.Second,
+		deadLetters: filepath.Join(common.PhenixBase, "webhook", "dead-letters.jsonl"),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithURL sets the URL of the external API status documents are posted to.
+func WithURL(u string) Option {
+	return func(o *options) {
+		o.url = u
+	}
+}
+
+// WithSecret sets the secret used to sign status documents. When set, the
+// hex-encoded HMAC-SHA256 of the request body is included in the
+// `X-Phenix-Signature` header of each request.
+func WithSecret(s string) Option {
+	return func(o *options) {
+		o.secret = s
+	}
+}
+
+// WithInterval sets how often experiment status is checked for changes.
+func WithInterval(i time.Duration) Option {
+	return func(o *options) {
+		if i > 0 {
+			o.interval = i
+		}
+	}
+}
+
+// WithRetries sets how many times delivery of a status document is retried,
+// with exponential backoff, before it's moved to the dead-letter queue.
+func WithRetries(r int) Option {
+	return func(o *options) {
+		if r >= 0 {
+			o.retries = r
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry. The delay is doubled for
+// each subsequent retry.
+func WithBackoff(b time.Duration) Option {
+	return func(o *options) {
+		o.backoff = b
+	}
+}
+
+// WithDeadLetters sets the path to the dead-letter queue file status
+// documents that couldn't be delivered are appended to.
+func WithDeadLetters(p string) Option {
+	return func(o *options) {
+		o.deadLetters = p
+	}
+}
+
+// Equal returns true if the given options configure the webhook the same way.
+func Equal(a, b []Option) bool {
+	return newOptions(a...) == newOptions(b...)
+}
@@ -0,0 +1,322 @@
+package webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/types"
+	"phenix/util/plog"
+)
+
+// Maximum number of status documents waiting to be delivered. Documents
+// generated while the queue is full go straight to the dead-letter queue.
+const queueSize = 256
+
+// Guards the dead-letter queue file.
+var deadLettersMu sync.Mutex
+
+// DeadLetter is a status document that couldn't be delivered to the external
+// API.
+type DeadLetter struct {
+	Document Document  `json:"document"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// Start periodically checks the status of all experiments and posts a status
+// document to the configured URL for each experiment whose status has changed,
+// including experiments that have been deleted. A document for every
+// experiment is posted when first started so the external system can sync
+// with phenix. Start blocks until the given context is canceled, and returns
+// immediately if no URL is configured.
+func Start(ctx context.Context, opts ...Option) {
+	o := newOptions(opts...)
+
+	if o.url == "" {
+		return
+	}
+
+	plog.Info("starting experiment status webhook", "url", o.url, "interval", o.interval)
+
+	var (
+		last   = make(map[string]string)
+		queue  = make(chan Document, queueSize)
+		ticker = time.NewTicker(o.interval)
+	)
+
+	defer ticker.Stop()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case doc := <-queue:
+				if err := deliver(ctx, o, doc); err != nil {
+					plog.Error("delivering experiment status", "exp", doc.Experiment.Name, "err", err)
+				}
+			}
+		}
+	}()
+
+	for {
+		exps, err := experiment.List()
+		if err != nil {
+			plog.Warn("listing experiments for status webhook", "err", err)
+		}
+
+		// Don't treat experiments as deleted when some of them couldn't be listed.
+		for _, doc := range changes(exps, last, err == nil) {
+			select {
+			case queue <- doc:
+			default:
+				appendDeadLetter(o, DeadLetter{Document: doc, Error: "delivery queue full", FailedAt: time.Now().UTC()})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Redeliver attempts to deliver each status document in the dead-letter queue
+// again. Documents that still can't be delivered are put back in the
+// dead-letter queue. It returns the number of documents delivered.
+func Redeliver(ctx context.Context, opts ...Option) (int, error) {
+	o := newOptions(opts...)
+
+	if o.url == "" {
+		return 0, fmt.Errorf("no webhook URL configured")
+	}
+
+	deadLettersMu.Lock()
+
+	letters, err := readDeadLetters(o.deadLetters)
+	if err == nil {
+		err = os.Remove(o.deadLetters)
+	}
+
+	deadLettersMu.Unlock()
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("reading dead-letter queue: %w", err)
+	}
+
+	var delivered int
+
+	for _, letter := range letters {
+		if err := post(ctx, o, letter.Document); err != nil {
+			letter.Attempts++
+			letter.Error = err.Error()
+			letter.FailedAt = time.Now().UTC()
+
+			appendDeadLetter(o, letter)
+			continue
+		}
+
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// DeadLetters returns the status documents currently in the dead-letter queue.
+func DeadLetters(opts ...Option) ([]DeadLetter, error) {
+	o := newOptions(opts...)
+
+	deadLettersMu.Lock()
+	defer deadLettersMu.Unlock()
+
+	letters, err := readDeadLetters(o.deadLetters)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading dead-letter queue: %w", err)
+	}
+
+	return letters, nil
+}
+
+// changes returns status documents for the given experiments whose status
+// differs from the last status recorded in last, updating last as it goes. If
+// complete is true, documents are also returned for experiments in last that
+// are no longer present.
+func changes(exps []types.Experiment, last map[string]string, complete bool) []Document {
+	var (
+		docs    []Document
+		present = make(map[string]struct{})
+	)
+
+	for _, exp := range exps {
+		doc := NewDocument(exp)
+		name := doc.Experiment.Name
+
+		present[name] = struct{}{}
+
+		// Timestamp isn't included so unchanged status compares equal.
+		key, err := json.Marshal(doc.Experiment)
+		if err != nil {
+			continue
+		}
+
+		if last[name] == string(key) {
+			continue
+		}
+
+		last[name] = string(key)
+		docs = append(docs, doc)
+	}
+
+	if complete {
+		for name := range last {
+			if _, ok := present[name]; !ok {
+				delete(last, name)
+				docs = append(docs, newDeletedDocument(name))
+			}
+		}
+	}
+
+	return docs
+}
+
+// deliver posts the given status document, retrying with exponential backoff
+// on failure. If the document still can't be delivered, it's appended to the
+// dead-letter queue.
+func deliver(ctx context.Context, o options, doc Document) error {
+	var (
+		backoff = o.backoff
+		err     error
+	)
+
+	for attempt := 0; attempt <= o.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+		}
+
+		if err = post(ctx, o, doc); err == nil {
+			return nil
+		}
+
+		plog.Debug("experiment status delivery failed", "exp", doc.Experiment.Name, "attempt", attempt+1, "err", err)
+	}
+
+	appendDeadLetter(o, DeadLetter{Document: doc, Attempts: o.retries + 1, Error: err.Error(), FailedAt: time.Now().UTC()})
+
+	return fmt.Errorf("moved to dead-letter queue after %d attempts: %w", o.retries+1, err)
+}
+
+func post(ctx context.Context, o options, doc Document) error {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("marshaling status document: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if o.secret != "" {
+		mac := hmac.New(sha256.New, []byte(o.secret))
+		mac.Write(body)
+
+		req.Header.Set("X-Phenix-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting status document: %w", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func appendDeadLetter(o options, letter DeadLetter) {
+	deadLettersMu.Lock()
+	defer deadLettersMu.Unlock()
+
+	body, err := json.Marshal(letter)
+	if err != nil {
+		plog.Error("marshaling dead letter", "exp", letter.Document.Experiment.Name, "err", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(o.deadLetters), 0755); err != nil {
+		plog.Error("creating dead-letter queue directory", "path", o.deadLetters, "err", err)
+		return
+	}
+
+	f, err := os.OpenFile(o.deadLetters, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		plog.Error("opening dead-letter queue", "path", o.deadLetters, "err", err)
+		return
+	}
+
+	defer f.Close()
+
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		plog.Error("writing dead letter", "path", o.deadLetters, "err", err)
+	}
+}
+
+func readDeadLetters(path string) ([]DeadLetter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var (
+		letters []DeadLetter
+		scanner = bufio.NewScanner(f)
+	)
+
+	// Status documents with lots of app status can be larger than the default
+	// max token size.
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		var letter DeadLetter
+
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			continue
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters, scanner.Err()
+}
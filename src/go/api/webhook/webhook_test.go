@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+)
+
+func TestChanges(t *testing.T) {
+	exp := types.Experiment{
+		Metadata: store.ConfigMetadata{Name: "test", Annotations: map[string]string{"topology": "foo"}},
+		Spec:     &v1.ExperimentSpec{ExperimentNameF: "test", TopologyF: &v1.TopologySpec{}},
+		Status:   new(v1.ExperimentStatus),
+	}
+
+	last := make(map[string]string)
+
+	docs := changes([]types.Experiment{exp}, last, true)
+	if len(docs) != 1 || docs[0].Experiment.State != "stopped" || docs[0].SchemaVersion != SchemaVersion {
+		t.Logf("expected single stopped document, got %+v", docs)
+		t.FailNow()
+	}
+
+	if docs := changes([]types.Experiment{exp}, last, true); len(docs) != 0 {
+		t.Logf("expected no documents for unchanged status, got %d", len(docs))
+		t.FailNow()
+	}
+
+	exp.Status.SetStartTime("2026-10-14T12:00:00Z")
+	exp.Status.SetAppRunning("soh", true)
+
+	docs = changes([]types.Experiment{exp}, last, true)
+	if len(docs) != 1 || docs[0].Experiment.State != "running" || len(docs[0].Experiment.Apps) != 1 {
+		t.Logf("expected single running document, got %+v", docs)
+		t.FailNow()
+	}
+
+	if docs := changes(nil, last, false); len(docs) != 0 {
+		t.Logf("expected no deleted documents for incomplete list, got %d", len(docs))
+		t.FailNow()
+	}
+
+	docs = changes(nil, last, true)
+	if len(docs) != 1 || docs[0].Experiment.State != "deleted" {
+		t.Logf("expected single deleted document, got %+v", docs)
+		t.FailNow()
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var (
+		fail     atomic.Bool
+		requests atomic.Int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+
+		if r.Header.Get("X-Phenix-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var doc Document
+
+		if err := json.Unmarshal(body, &doc); err != nil || doc.Experiment.Name != "test" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+
+	defer server.Close()
+
+	var (
+		ctx  = context.Background()
+		opts = []Option{
+			WithURL(server.URL),
+			WithSecret("secret"),
+			WithRetries(2),
+			WithBackoff(0),
+			WithDeadLetters(filepath.Join(t.TempDir(), "dead-letters.jsonl")),
+		}
+		o   = newOptions(opts...)
+		doc = newDeletedDocument("test")
+	)
+
+	if err := deliver(ctx, o, doc); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	fail.Store(true)
+	requests.Store(0)
+
+	if err := deliver(ctx, o, doc); err == nil {
+		t.Log("expected error delivering to failing API")
+		t.FailNow()
+	}
+
+	if requests.Load() != 3 {
+		t.Logf("expected 3 attempts, got %d", requests.Load())
+		t.FailNow()
+	}
+
+	letters, err := DeadLetters(opts...)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(letters) != 1 || letters[0].Attempts != 3 {
+		t.Logf("expected single dead letter with 3 attempts, got %+v", letters)
+		t.FailNow()
+	}
+
+	if delivered, _ := Redeliver(ctx, opts...); delivered != 0 {
+		t.Logf("expected no documents redelivered to failing API, got %d", delivered)
+		t.FailNow()
+	}
+
+	fail.Store(false)
+
+	if delivered, _ := Redeliver(ctx, opts...); delivered != 1 {
+		t.Logf("expected 1 document redelivered, got %d", delivered)
+		t.FailNow()
+	}
+
+	if letters, _ := DeadLetters(opts...); len(letters) != 0 {
+		t.Logf("expected empty dead-letter queue, got %d", len(letters))
+		t.FailNow()
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := []Option{WithURL("http://localhost/status"), WithRetries(3)}
+
+	if !Equal(a, []Option{WithURL("http://localhost/status"), WithRetries(3)}) {
+		t.Fatal("expected equal options to be equal")
+	}
+
+	if Equal(a, []Option{WithURL("http://localhost/status"), WithRetries(3), WithSecret("foo")}) {
+		t.Fatal("expected options with different secrets to differ")
+	}
+
+	if !Equal(nil, []Option{WithInterval(5 * time.Second)}) {
+		t.Fatal("expected default interval to equal unset interval")
+	}
+}
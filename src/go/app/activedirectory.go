@@ -0,0 +1,496 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	RegisterUserApp("activedirectory", func() App { return new(ActiveDirectory) })
+}
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: activedirectory
+      metadata:
+        domainController: dc01
+        interface: eth0            # default is the first static interface
+        domain: corp.example.com
+        netbiosName: CORP          # default is the first label of the domain
+        adminPassword: P@ssw0rd!   # domain Administrator password
+        safeModePassword: S@fe123! # default is adminPassword
+        ous:
+        - Engineering
+        - Engineering/Servers      # nested OUs are separated by slashes
+        groups:
+        - name: Engineers
+          ou: Engineering          # default is the Users container
+          scope: Global            # default is Global
+        users:
+        - username: alice
+          password: Al1ce!Pass
+          displayName: Alice Smith # default is username
+          ou: Engineering          # default is the Users container
+          groups:
+          - Engineers
+          - Domain Admins
+        members:                   # default is every other Windows node
+        - ws01
+        - ws02
+*/
+
+type ActiveDirectoryAppMetadata struct {
+	DomainController string                 `mapstructure:"domainController"`
+	Interface        string                 `mapstructure:"interface"`
+	Domain           string                 `mapstructure:"domain"`
+	NetBIOSName      string                 `mapstructure:"netbiosName"`
+	AdminPassword    string                 `mapstructure:"adminPassword"`
+	SafeModePassword string                 `mapstructure:"safeModePassword"`
+	OUs              []string               `mapstructure:"ous"`
+	Groups           []ActiveDirectoryGroup `mapstructure:"groups"`
+	Users            []ActiveDirectoryUser  `mapstructure:"users"`
+	Members          []string               `mapstructure:"members"`
+}
+
+type ActiveDirectoryGroup struct {
+	Name  string `mapstructure:"name"`
+	OU    string `mapstructure:"ou"`
+	Scope string `mapstructure:"scope"`
+}
+
+type ActiveDirectoryUser struct {
+	Username    string   `mapstructure:"username"`
+	Password    string   `mapstructure:"password"`
+	DisplayName string   `mapstructure:"displayName"`
+	OU          string   `mapstructure:"ou"`
+	Groups      []string `mapstructure:"groups"`
+}
+
+type activeDirectoryConfig struct {
+	Controller       ifaces.NodeSpec
+	Address          string
+	Domain           string
+	DN               string
+	NetBIOSName      string
+	AdminPassword    string
+	SafeModePassword string
+	OUs              []activeDirectoryObject
+	Groups           []activeDirectoryObject
+	Users            []activeDirectoryUser
+	Members          []ifaces.NodeSpec
+}
+
+// activeDirectoryObject is an OU or group to create in the directory, along
+// with the distinguished name of the container to create it in.
+type activeDirectoryObject struct {
+	Name  string
+	Path  string
+	DN    string
+	Scope string
+}
+
+type activeDirectoryUser struct {
+	ActiveDirectoryUser
+
+	Path string
+}
+
+var (
+	// Valid pre-Windows 2000 (sAMAccountName) usernames and NetBIOS domain names.
+	adUsernameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,20}$`)
+	adNetBIOSRegex  = regexp.MustCompile(`^[A-Za-z0-9-]{1,15}$`)
+)
+
+// ActiveDirectory promotes the Windows node given in the app metadata to the
+// domain controller of a new Active Directory forest, creates the OUs, groups,
+// and users given in the app metadata once it's promoted, and joins the
+// member Windows nodes to the domain. Everything is done by startup scripts
+// injected in the pre-start stage, which are safe to run on every boot: the
+// domain controller reboots once after being promoted, and members wait for the
+// domain to be resolvable via the domain controller before joining it (and
+// rebooting).
+type ActiveDirectory struct{}
+
+func (ActiveDirectory) Init(...Option) error {
+	return nil
+}
+
+func (ActiveDirectory) Name() string {
+	return "activedirectory"
+}
+
+func (ActiveDirectory) MetadataSchema() []byte {
+	return []byte(`
+type: object
+required:
+- domainController
+- domain
+- adminPassword
+properties:
+  domainController:
+    type: string
+  interface:
+    type: string
+  domain:
+    type: string
+  netbiosName:
+    type: string
+  adminPassword:
+    type: string
+  safeModePassword:
+    type: string
+  ous:
+    type: array
+    items:
+      type: string
+  groups:
+    type: array
+    items:
+      type: object
+      required:
+      - name
+      properties:
+        name:
+          type: string
+        ou:
+          type: string
+        scope:
+          type: string
+          enum:
+          - DomainLocal
+          - Global
+          - Universal
+      additionalProperties: false
+  users:
+    type: array
+    items:
+      type: object
+      required:
+      - username
+      - password
+      properties:
+        username:
+          type: string
+        password:
+          type: string
+        displayName:
+          type: string
+        ou:
+          type: string
+        groups:
+          type: array
+          items:
+            type: string
+      additionalProperties: false
+  members:
+    type: array
+    items:
+      type: string
+additionalProperties: false
+`)
+}
+
+// Validate checks that the domain controller, members, and directory objects
+// given in the app metadata are valid.
+func (this ActiveDirectory) Validate(exp *types.Experiment) error {
+	_, err := this.config(exp)
+	return err
+}
+
+func (ActiveDirectory) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this ActiveDirectory) PreStart(ctx context.Context, exp *types.Experiment) error {
+	cfg, err := this.config(exp)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	adDir := exp.Spec.BaseDir() + "/activedirectory"
+
+	if err := os.MkdirAll(adDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment activedirectory directory path: %w", err)
+	}
+
+	var (
+		dc     = adDir + "/" + cfg.Controller.General().Hostname() + "-dc.ps1"
+		member = adDir + "/member.ps1"
+	)
+
+	if err := tmpl.CreateFileFromTemplate("activedirectory_dc.tmpl", cfg, dc); err != nil {
+		return fmt.Errorf("generating domain controller startup script: %w", err)
+	}
+
+	cfg.Controller.AddInject(dc, "/phenix/startup/28-activedirectory.ps1", "0755", "")
+
+	if len(cfg.Members) == 0 {
+		return nil
+	}
+
+	if err := tmpl.CreateFileFromTemplate("activedirectory_member.tmpl", cfg, member); err != nil {
+		return fmt.Errorf("generating domain member startup script: %w", err)
+	}
+
+	for _, node := range cfg.Members {
+		node.AddInject(member, "/phenix/startup/28-activedirectory.ps1", "0755", "")
+	}
+
+	return nil
+}
+
+func (ActiveDirectory) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (ActiveDirectory) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (ActiveDirectory) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// config returns the domain configuration for the given experiment, or nil if
+// the app isn't configured for it.
+func (this ActiveDirectory) config(exp *types.Experiment) (*activeDirectoryConfig, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil, nil
+	}
+
+	var md ActiveDirectoryAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return nil, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	if md.DomainController == "" {
+		return nil, fmt.Errorf("no domain controller provided in %s app metadata", this.Name())
+	}
+
+	dc := exp.Spec.Topology().FindNodeByName(md.DomainController)
+	if dc == nil {
+		return nil, fmt.Errorf("domain controller %s not in topology", md.DomainController)
+	}
+
+	if dc.External() {
+		return nil, fmt.Errorf("domain controller %s cannot be an external node", md.DomainController)
+	}
+
+	if !strings.EqualFold(dc.Hardware().OSType(), "windows") {
+		return nil, fmt.Errorf("domain controller %s must be a Windows node (got OS type %s)", md.DomainController, dc.Hardware().OSType())
+	}
+
+	cfg := &activeDirectoryConfig{
+		Controller:       dc,
+		Domain:           strings.ToLower(strings.TrimSuffix(md.Domain, ".")),
+		NetBIOSName:      strings.ToUpper(md.NetBIOSName),
+		AdminPassword:    md.AdminPassword,
+		SafeModePassword: md.SafeModePassword,
+	}
+
+	for _, iface := range dc.Network().Interfaces() {
+		if iface.Address() == "" || strings.EqualFold(iface.Proto(), "dhcp") {
+			continue
+		}
+
+		if md.Interface == "" || strings.EqualFold(iface.Name(), md.Interface) {
+			cfg.Address = iface.Address()
+			break
+		}
+	}
+
+	if cfg.Address == "" {
+		if md.Interface != "" {
+			return nil, fmt.Errorf("no static IP address on interface %s of domain controller %s", md.Interface, md.DomainController)
+		}
+
+		return nil, fmt.Errorf("no static IP address on domain controller %s", md.DomainController)
+	}
+
+	labels := strings.Split(cfg.Domain, ".")
+
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("domain %s must have at least two labels (ie. corp.example.com)", md.Domain)
+	}
+
+	var dn []string
+
+	for _, label := range labels {
+		if !dnsLabelRegex.MatchString(label) {
+			return nil, fmt.Errorf("invalid domain %s", md.Domain)
+		}
+
+		dn = append(dn, "DC="+label)
+	}
+
+	cfg.DN = strings.Join(dn, ",")
+
+	if cfg.NetBIOSName == "" {
+		cfg.NetBIOSName = strings.ToUpper(labels[0])
+
+		if len(cfg.NetBIOSName) > 15 {
+			cfg.NetBIOSName = cfg.NetBIOSName[:15]
+		}
+	}
+
+	var errs error
+
+	if !adNetBIOSRegex.MatchString(cfg.NetBIOSName) {
+		errs = multierror.Append(errs, fmt.Errorf("invalid NetBIOS domain name %s", cfg.NetBIOSName))
+	}
+
+	if cfg.AdminPassword == "" {
+		errs = multierror.Append(errs, fmt.Errorf("no domain Administrator password provided"))
+	}
+
+	if cfg.SafeModePassword == "" {
+		cfg.SafeModePassword = cfg.AdminPassword
+	}
+
+	// Container DNs, keyed by OU path, that groups and users can be created in.
+	containers := map[string]string{"": "CN=Users," + cfg.DN}
+
+	for _, ou := range md.OUs {
+		var (
+			names = strings.Split(strings.Trim(ou, "/"), "/")
+			path  string
+		)
+
+		// Parent OUs are created first, whether or not they're listed.
+		for i, name := range names {
+			if name == "" || strings.ContainsAny(name, `,=+<>#;"\`) {
+				errs = multierror.Append(errs, fmt.Errorf("invalid OU %s", ou))
+				break
+			}
+
+			parent := path
+			path = strings.Join(names[:i+1], "/")
+
+			if _, ok := containers[path]; ok {
+				continue
+			}
+
+			parentDN := cfg.DN
+
+			if parent != "" {
+				parentDN = containers[parent]
+			}
+
+			obj := activeDirectoryObject{Name: name, Path: parentDN, DN: "OU=" + name + "," + parentDN}
+
+			cfg.OUs = append(cfg.OUs, obj)
+			containers[path] = obj.DN
+		}
+	}
+
+	container := func(kind, name, ou string) string {
+		dn, ok := containers[strings.Trim(ou, "/")]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf("OU %s for %s %s not in %s app metadata", ou, kind, name, this.Name()))
+		}
+
+		return dn
+	}
+
+	groups := make(map[string]struct{})
+
+	for _, g := range md.Groups {
+		if g.Name == "" {
+			errs = multierror.Append(errs, fmt.Errorf("no name provided for group"))
+			continue
+		}
+
+		if _, ok := groups[strings.ToLower(g.Name)]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("duplicate group %s", g.Name))
+			continue
+		}
+
+		groups[strings.ToLower(g.Name)] = struct{}{}
+
+		scope := g.Scope
+
+		switch strings.ToLower(scope) {
+		case "":
+			scope = "Global"
+		case "domainlocal", "global", "universal":
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("invalid scope %s for group %s", g.Scope, g.Name))
+		}
+
+		cfg.Groups = append(cfg.Groups, activeDirectoryObject{Name: g.Name, Path: container("group", g.Name, g.OU), Scope: scope})
+	}
+
+	users := make(map[string]struct{})
+
+	for _, u := range md.Users {
+		if !adUsernameRegex.MatchString(u.Username) {
+			errs = multierror.Append(errs, fmt.Errorf("invalid username %s (must be 1-20 letters, numbers, periods, underscores, or dashes)", u.Username))
+			continue
+		}
+
+		if _, ok := users[strings.ToLower(u.Username)]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("duplicate user %s", u.Username))
+			continue
+		}
+
+		users[strings.ToLower(u.Username)] = struct{}{}
+
+		if u.Password == "" {
+			errs = multierror.Append(errs, fmt.Errorf("no password provided for user %s", u.Username))
+		}
+
+		if u.DisplayName == "" {
+			u.DisplayName = u.Username
+		}
+
+		cfg.Users = append(cfg.Users, activeDirectoryUser{ActiveDirectoryUser: u, Path: container("user", u.Username, u.OU)})
+	}
+
+	if len(md.Members) > 0 {
+		for _, name := range md.Members {
+			node := exp.Spec.Topology().FindNodeByName(name)
+
+			switch {
+			case node == nil:
+				errs = multierror.Append(errs, fmt.Errorf("domain member %s not in topology", name))
+			case node.External() || !strings.EqualFold(node.Hardware().OSType(), "windows"):
+				errs = multierror.Append(errs, fmt.Errorf("domain member %s must be a Windows VM", name))
+			case node.General().Hostname() == dc.General().Hostname():
+				errs = multierror.Append(errs, fmt.Errorf("domain controller %s cannot also be a domain member", name))
+			default:
+				cfg.Members = append(cfg.Members, node)
+			}
+		}
+	} else {
+		for _, node := range TargetNodes(exp, this.Name()) {
+			if node.External() || !strings.EqualFold(node.Hardware().OSType(), "windows") {
+				continue
+			}
+
+			if node.General().Hostname() == dc.General().Hostname() {
+				continue
+			}
+
+			cfg.Members = append(cfg.Members, node)
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return cfg, nil
+}
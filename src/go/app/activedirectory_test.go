@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func activeDirectoryNodes() []*v1.Node {
+	node := func(hostname, os, address string) *v1.Node {
+		n := &v1.Node{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: hostname,
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: os,
+			},
+		}
+
+		if address != "" {
+			n.NetworkF = &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: address, MaskF: 24},
+				},
+			}
+		}
+
+		return n
+	}
+
+	return []*v1.Node{
+		node("dc01", "windows", "10.0.1.10"),
+		node("ws01", "windows", "10.0.1.20"),
+		node("ws02", "windows", "10.0.1.21"),
+		node("web", "linux", "10.0.1.30"),
+	}
+}
+
+func activeDirectoryExperiment(baseDir string, nodes []*v1.Node, md map[string]any) *types.Experiment {
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: &v2.ScenarioSpec{
+			AppsF: []*v2.ScenarioApp{{NameF: "activedirectory", MetadataF: md}},
+		},
+	}
+
+	return &types.Experiment{Spec: spec}
+}
+
+func TestActiveDirectoryApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "activedirectory-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	md := map[string]any{
+		"domainController": "dc01",
+		"domain":           "Corp.Example.com",
+		"adminPassword":    "it's-secret",
+		"ous":              []any{"Engineering/Servers"},
+		"groups": []any{
+			map[string]any{"name": "Engineers", "ou": "Engineering"},
+		},
+		"users": []any{
+			map[string]any{"username": "alice", "password": "Al1ce!", "ou": "Engineering", "groups": []any{"Engineers", "Domain Admins"}},
+			map[string]any{"username": "bob", "password": "B0b!"},
+		},
+		"members": []any{"ws01"},
+	}
+
+	nodes := activeDirectoryNodes()
+	exp := activeDirectoryExperiment(baseDir, nodes, md)
+
+	if err := GetApp("activedirectory").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[int]int{0: 1, 1: 1, 2: 0, 3: 0}
+
+	for i, count := range expected {
+		injects := nodes[i].Injections()
+
+		if len(injects) != count {
+			t.Logf("expected %d injections for host %s, got %d", count, nodes[i].General().Hostname(), len(injects))
+			t.FailNow()
+		}
+
+		if count > 0 && injects[0].Dst() != "/phenix/startup/28-activedirectory.ps1" {
+			t.Logf("unexpected injection destination for host %s: %s", nodes[i].General().Hostname(), injects[0].Dst())
+			t.FailNow()
+		}
+	}
+
+	files := map[string][]string{
+		"dc01-dc.ps1": {
+			"-DomainName 'corp.example.com'",
+			"-DomainNetbiosName 'CORP'",
+			"ConvertTo-SecureString 'it''s-secret'",
+			"Get-ADOrganizationalUnit -Identity 'OU=Engineering,DC=corp,DC=example,DC=com'",
+			"New-ADOrganizationalUnit -Name 'Servers' -Path 'OU=Engineering,DC=corp,DC=example,DC=com'",
+			"New-ADGroup -Name 'Engineers' -Path 'OU=Engineering,DC=corp,DC=example,DC=com' -GroupScope Global",
+			"-UserPrincipalName 'alice@corp.example.com'",
+			"-Path 'CN=Users,DC=corp,DC=example,DC=com'",
+			"Add-ADGroupMember -Identity 'Domain Admins' -Members 'alice'",
+		},
+		"member.ps1": {
+			"-ServerAddresses ('10.0.1.10')",
+			"'_ldap._tcp.dc._msdcs.corp.example.com'",
+			"PSCredential('CORP\\Administrator', $password)",
+		},
+	}
+
+	for name, contents := range files {
+		body, err := os.ReadFile(baseDir + "/activedirectory/" + name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, c := range contents {
+			if !strings.Contains(string(body), c) {
+				t.Logf("expected %s to contain %q, got: %s", name, c, body)
+				t.FailNow()
+			}
+		}
+	}
+
+	// Without members listed, every other Windows node joins the domain.
+	delete(md, "members")
+
+	nodes = activeDirectoryNodes()
+	exp = activeDirectoryExperiment(baseDir, nodes, md)
+
+	if err := GetApp("activedirectory").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(nodes[2].Injections()) != 1 || len(nodes[3].Injections()) != 0 {
+		t.Log("expected every other Windows node to be joined to the domain by default")
+		t.FailNow()
+	}
+}
+
+func TestActiveDirectoryAppValidate(t *testing.T) {
+	valid := func() map[string]any {
+		return map[string]any{"domainController": "dc01", "domain": "corp.example.com", "adminPassword": "secret"}
+	}
+
+	cases := map[string]func(map[string]any){
+		"missing controller": func(md map[string]any) { delete(md, "domainController") },
+		"linux controller":   func(md map[string]any) { md["domainController"] = "web" },
+		"single label":       func(md map[string]any) { md["domain"] = "corp" },
+		"missing password":   func(md map[string]any) { delete(md, "adminPassword") },
+		"unknown ou": func(md map[string]any) {
+			md["users"] = []any{map[string]any{"username": "alice", "password": "x", "ou": "Sales"}}
+		},
+		"invalid username": func(md map[string]any) {
+			md["users"] = []any{map[string]any{"username": "alice smith", "password": "x"}}
+		},
+		"invalid scope":     func(md map[string]any) { md["groups"] = []any{map[string]any{"name": "Engineers", "scope": "Forest"}} },
+		"linux member":      func(md map[string]any) { md["members"] = []any{"web"} },
+		"controller member": func(md map[string]any) { md["members"] = []any{"dc01"} },
+	}
+
+	for name, mutate := range cases {
+		md := valid()
+		mutate(md)
+
+		exp := activeDirectoryExperiment("", activeDirectoryNodes(), md)
+
+		if err := new(ActiveDirectory).Validate(exp); err == nil {
+			t.Logf("expected validation error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+
+	if err := new(ActiveDirectory).Validate(activeDirectoryExperiment("", activeDirectoryNodes(), valid())); err != nil {
+		t.Logf("expected valid metadata to pass validation, got %v", err)
+		t.FailNow()
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"phenix/types"
+	"phenix/util/journal"
 	"phenix/util/notes"
+	"phenix/util/perror"
 	"phenix/util/plog"
-	"phenix/util/pubsub"
 	"phenix/util/shell"
+	"phenix/util/subsystem"
 
 	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+	"golang.org/x/exp/slices"
 )
 
 // Action represents the different experiment lifecycle hooks.
@@ -35,9 +41,13 @@ type TriggerPublication struct {
 	Experiment string
 	Verb       string
 	App        string
+	Stage      Action
 	Resource   string
 	State      string
 	Error      error
+
+	// Set when State is progress.
+	Progress *Progress
 }
 
 const (
@@ -51,12 +61,12 @@ const (
 var (
 	apps = make(map[string]AppFactory)
 
-	defaultApps = map[string]struct{}{
-		"ntp":     {},
-		"serial":  {},
-		"startup": {},
-		"vrouter": {},
-	}
+	// builtinDefaultApps are the apps that can be default apps, all of which are
+	// default apps unless configured otherwise via SetDefaultApps.
+	builtinDefaultApps = []string{"ntp", "pxe", "serial", "startup", "vrouter"}
+
+	defaultApps   = make(map[string]struct{})
+	defaultAppsMu sync.RWMutex
 )
 
 var ErrUserAppAlreadyRegistered = fmt.Errorf("user app already registered")
@@ -64,12 +74,15 @@ var ErrUserAppAlreadyRegistered = fmt.Errorf("user app already registered")
 func init() {
 	// Default apps (always run)
 	apps["ntp"] = func() App { return new(NTP) }
+	apps["pxe"] = func() App { return new(PXE) }
 	apps["serial"] = func() App { return new(Serial) }
 	apps["startup"] = func() App { return new(Startup) }
 	apps["vrouter"] = func() App { return new(Vrouter) }
 
 	// External user apps
 	apps["user-shell"] = func() App { return new(UserApp) }
+
+	SetDefaultApps()
 }
 
 func RegisterUserApp(name string, factory AppFactory) error {
@@ -92,21 +105,48 @@ func List() []string {
 		}
 
 		// Don't include default apps in the list since they always get applied.
-		if _, ok := defaultApps[name]; ok {
+		if isDefaultApp(name) {
 			continue
 		}
 
 		names = append(names, name)
 	}
 
-	names = append(names, shell.FindCommandsWithPrefix(USER_APP_PREFIX)...)
+	seen := make(map[string]struct{})
+
+	for _, name := range names {
+		seen[name] = struct{}{}
+	}
+
+	users := shell.FindCommandsWithPrefix(USER_APP_PREFIX)
+
+	// Apps in the registry are listed even if their executable isn't in the
+	// PATH, since the registry entry can point to it directly.
+	for name := range registeredApps() {
+		users = append(users, name)
+	}
+
+	for _, name := range users {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
 
 	return names
 }
 
 // GetApp returns the phenix app with the given name. Preference is given to a
-// user app with the given name to allow users to override internal apps.
+// user app with the given name to allow users to override internal apps. Apps
+// belonging to optional subsystems that aren't compiled in or have been
+// disabled (ie. scorch) fail every stage instead.
 func GetApp(name string) App {
+	if err := subsystem.Check(name); err != nil {
+		return &UnavailableApp{name: name, err: err}
+	}
+
 	cmdName := USER_APP_PREFIX + name
 
 	// Default to shelling out to a user app with the given name so internal apps
@@ -115,6 +155,10 @@ func GetApp(name string) App {
 		return apps["user-shell"]()
 	}
 
+	if _, ok := RegisteredApp(name); ok {
+		return apps["user-shell"]()
+	}
+
 	app, ok := apps[name]
 	if !ok {
 		app = apps["user-shell"]
@@ -125,6 +169,9 @@ func GetApp(name string) App {
 
 // DefaultApps returns a slice of all the initialized default phenix apps.
 func DefaultApps() []string {
+	defaultAppsMu.RLock()
+	defer defaultAppsMu.RUnlock()
+
 	var apps []string
 
 	for app := range defaultApps {
@@ -134,6 +181,42 @@ func DefaultApps() []string {
 	return apps
 }
 
+// SetDefaultApps sets the apps applied to every experiment, which must be a
+// subset of the built-in default apps (ntp, pxe, serial, startup, and
+// vrouter). All the built-in default apps are used if none are given. Apps
+// removed from the default apps can still be applied via an experiment's
+// scenario.
+func SetDefaultApps(names ...string) error {
+	if len(names) == 0 {
+		names = builtinDefaultApps
+	}
+
+	updated := make(map[string]struct{})
+
+	for _, name := range names {
+		if !slices.Contains(builtinDefaultApps, name) {
+			return fmt.Errorf("unknown default app %s (expected one of %s)", name, strings.Join(builtinDefaultApps, ", "))
+		}
+
+		updated[name] = struct{}{}
+	}
+
+	defaultAppsMu.Lock()
+	defer defaultAppsMu.Unlock()
+
+	defaultApps = updated
+
+	return nil
+}
+
+func isDefaultApp(name string) bool {
+	defaultAppsMu.RLock()
+	defer defaultAppsMu.RUnlock()
+
+	_, ok := defaultApps[name]
+	return ok
+}
+
 // App is the interface that identifies all the required functionality for a
 // phenix app. Each experiment lifecycle hook function is passed a pointer to
 // the experiment the app is being applied to, and the lifecycle hook function
@@ -168,14 +251,141 @@ type App interface {
 	Cleanup(context.Context, *types.Experiment) error
 }
 
+// Validator is an optional interface a phenix app can implement to check its
+// metadata and topology prerequisites when an experiment is created, before any
+// experiment lifecycle stage is run. The experiment should not be modified.
+type Validator interface {
+	Validate(*types.Experiment) error
+}
+
+// ValidateApps validates the given experiment against all the default phenix
+// apps and any configured user apps that implement the Validator interface.
+// Rather than stopping at the first app to fail validation, all app validation
+// errors are aggregated into the error returned.
+func ValidateApps(exp *types.Experiment) error {
+	var errs error
+
+	validate := func(name, kind string) {
+		a := GetApp(name)
+		a.Init(Name(name))
+
+		v, ok := a.(Validator)
+		if !ok {
+			return
+		}
+
+		if err := v.Validate(exp); err != nil {
+			plog.Error(fmt.Sprintf("[✗] '%s' %s app (validate)", name, kind))
+			errs = multierror.Append(errs, perror.Errorf(perror.CodeValidation, "app/"+name, "validating %s app %s: %w", kind, name, err))
+
+			return
+		}
+
+		plog.Info(fmt.Sprintf("[✓] '%s' %s app (validate)", name, kind))
+	}
+
+	for _, name := range DefaultApps() {
+		validate(name, "default")
+	}
+
+	if exp.Spec.Scenario() != nil {
+		if before, after, err := scenarioAppGroups(exp.Spec.Scenario().Apps()); err != nil {
+			errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "experiment/"+exp.Metadata.Name, err))
+		} else {
+			if _, _, err := scenarioAppLevels(before); err != nil {
+				errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "experiment/"+exp.Metadata.Name, err))
+			}
+
+			if _, _, err := scenarioAppLevels(after, before...); err != nil {
+				errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "experiment/"+exp.Metadata.Name, err))
+			}
+		}
+
+		for _, app := range exp.Spec.Scenario().Apps() {
+			if app.Disabled() {
+				continue
+			}
+
+			if err := validateTargets(exp, app); err != nil {
+				errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "app/"+app.Name(), err))
+			}
+
+			// Don't validate default apps again if configured via the Scenario.
+			if isDefaultApp(app.Name()) {
+				continue
+			}
+
+			if _, err := appTimeout(app.Name(), app.Metadata(), NewOptions()); err != nil {
+				errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "app/"+app.Name(), err))
+			}
+
+			if _, err := appRetryPolicy(app.Name(), app.Metadata(), NewOptions()); err != nil {
+				errs = multierror.Append(errs, perror.Wrap(perror.CodeValidation, "app/"+app.Name(), err))
+			}
+
+			validate(app.Name(), "user")
+		}
+	}
+
+	return errs
+}
+
+// skipStage returns true if the experiment spec declares the given stage
+// should be skipped for the given app, marking the stage as skipped in the
+// experiment status if so.
+func skipStage(exp *types.Experiment, app string, stage Action) bool {
+	if !exp.Spec.SkipStage(app, string(stage)) {
+		return false
+	}
+
+	exp.Status.SetAppSkipped(app, string(stage))
+	return true
+}
+
 // ApplyApps applies all the default phenix apps and any configured user apps to
 // the given experiment for the given lifecycle phase. It returns any errors
-// encountered while applying the apps.
-func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error {
-	var (
-		options = NewOptions(opts...)
-		err     error
-	)
+// encountered while applying the apps. If an app fails in the configure,
+// pre-start, or post-start phase, apps already applied in that phase that
+// implement the Undoer interface are rolled back in reverse order.
+func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) (err error) {
+	options := NewOptions(opts...)
+
+	if !options.DryRun {
+		started := time.Now()
+		defer func() { observeStage(options.Stage, started, err) }()
+	}
+
+	if options.StageTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, options.StageTimeout)
+		defer cancel()
+
+		defer func() {
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %s after %v: %w", ErrStageTimeout, options.Stage, options.StageTimeout, err)
+			}
+		}()
+	}
+
+	if options.Stage == ACTIONCONFIG {
+		if err := validateMetadata(exp); err != nil {
+			return err
+		}
+	}
+
+	// Apps successfully applied so far in this stage, in the order they were
+	// applied, so they can be rolled back if a later app fails.
+	var applied []App
+
+	if rollbackStage(options.Stage) {
+		defer func() {
+			if err != nil && len(applied) > 0 {
+				plog.Warn(fmt.Sprintf("rolling back apps applied for %s stage", options.Stage), "exp", exp.Metadata.Name, "apps", len(applied))
+				rollbackApps(exp, applied, options.Stage)
+			}
+		}()
+	}
 
 	if options.Stage == ACTIONPRESTART {
 		// Reset status.apps for experiment. Note that this will get rid of any app
@@ -187,15 +397,169 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 		exp.Status.ResetAppStatus()
 	}
 
-	// Publish triggered app events so web broker can propogate the publish out to
-	// web clients. This was initially setup to help convey SOH status in the UI.
-	publish := func(app, state string, err error) {
-		pubsub.Publish("trigger-app", TriggerPublication{
-			Experiment: exp.Metadata.Name,
-			App:        app,
-			State:      state,
-			Error:      err,
-		})
+	// Publish progress events for apps so web broker can propogate the publish
+	// out to web clients (this was initially setup to help convey SOH status in
+	// the UI) and the configured progress handler can render them. Each app's run
+	// is also recorded in the experiment status, and each app invocation in the
+	// audit log once it completes.
+	//
+	// When previewing a dry run, the changes each app makes to the experiment are
+	// diffed instead, and nothing is recorded outside of the given experiment.
+	var (
+		started   = make(map[string]time.Time)
+		snapshots = make(map[string]specSnapshot)
+		startedMu sync.Mutex
+
+		preview  = options.DryRun && options.OnDiff != nil
+		progress = progressPublisher(exp.Metadata.Name, options.Stage, options.OnProgress, !preview)
+	)
+
+	publish := func(e ProgressEvent) {
+		progress(e)
+
+		var (
+			app   = e.App
+			state = e.Status
+			err   = e.Error
+		)
+
+		switch state {
+		case "progress":
+			return
+		case "start":
+			exp.Status.SetAppRun(app, string(options.Stage), "running", nil)
+		default:
+			exp.Status.SetAppRun(app, string(options.Stage), state, err)
+		}
+
+		if preview {
+			startedMu.Lock()
+			defer startedMu.Unlock()
+
+			switch state {
+			case "start":
+				if snap, err := snapshotSpec(exp); err == nil {
+					snapshots[app] = snap
+				}
+			case "success", "error":
+				before, ok := snapshots[app]
+				if !ok {
+					return
+				}
+
+				delete(snapshots, app)
+
+				after, err := snapshotSpec(exp)
+				if err != nil {
+					return
+				}
+
+				diff := diffSpec(app, options.Stage, before, after)
+
+				if e.Error != nil {
+					diff.Error = e.Error.Error()
+				}
+
+				options.OnDiff(diff)
+			}
+
+			return
+		}
+
+		switch state {
+		case "success":
+			journal.Record(exp.Metadata.Name, journal.CategoryApp, "", "app %s (%s) succeeded", app, options.Stage)
+		case "retrying":
+			journal.RecordWithDetails(exp.Metadata.Name, journal.CategoryApp, "", map[string]string{"error": err.Error()}, "app %s (%s) failed, retrying", app, options.Stage)
+		case "error":
+			journal.RecordWithDetails(exp.Metadata.Name, journal.CategoryApp, "", map[string]string{"error": err.Error()}, "app %s (%s) failed", app, options.Stage)
+		}
+
+		startedMu.Lock()
+		defer startedMu.Unlock()
+
+		switch state {
+		case "start":
+			started[app] = time.Now()
+		case "success", "error":
+			auditApp(ctx, exp, app, options.Stage, started[app], err)
+
+			if !options.DryRun {
+				observeApp(app, options.Stage, started[app], err)
+			}
+
+			delete(started, app)
+		}
+	}
+
+	var before, after []ifaces.ScenarioApp
+
+	if exp.Spec.Scenario() != nil {
+		if before, after, err = scenarioAppGroups(exp.Spec.Scenario().Apps()); err != nil {
+			return perror.Wrap(perror.CodeValidation, "experiment/"+exp.Metadata.Name, err)
+		}
+	}
+
+	// Scenario apps are applied in levels of dependency order, after the given
+	// apps applied earlier.
+	applyScenarioApps := func(apps []ifaces.ScenarioApp, earlier ...ifaces.ScenarioApp) error {
+		levels, parallel, err := scenarioAppLevels(apps, earlier...)
+		if err != nil {
+			return perror.Wrap(perror.CodeValidation, "experiment/"+exp.Metadata.Name, err)
+		}
+
+		// Apps are only run concurrently in the post-start stage, since apps can
+		// replace the experiment spec in the configure, pre-start, and cleanup
+		// stages, and reload the experiment from the store in the running stage.
+		// Other stages still apply apps in dependency order.
+		if parallel && options.Stage == ACTIONPOSTSTART {
+			for _, level := range levels {
+				done, err := applyScenarioAppsConcurrently(ctx, exp, level, options, publish)
+				applied = append(applied, done...)
+
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			running := func(name string, running bool) {
+				exp.Status.SetAppRunning(name, running)
+
+				if !preview {
+					exp.WriteToStore(true)
+				}
+			}
+
+			for _, level := range levels {
+				for _, app := range level {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+
+					a, ok, err := scenarioAppToApply(exp, app, options, publish)
+					if err != nil {
+						return err
+					}
+
+					if !ok {
+						continue
+					}
+
+					if err := applyScenarioApp(ctx, exp, a, app, options, publish, running); err != nil {
+						return err
+					}
+
+					applied = append(applied, a)
+				}
+			}
+		}
+
+		return nil
+	}
+
+	// Scenario apps with runBeforeDefaults set are applied before default apps.
+	if err := applyScenarioApps(before); err != nil {
+		return err
 	}
 
 	for _, name := range DefaultApps() {
@@ -203,144 +567,224 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 			return ctx.Err()
 		}
 
+		// Silently ignore running stage for default apps.
+		if options.Stage == ACTIONRUNNING {
+			continue
+		}
+
 		a := GetApp(name)
 		a.Init(Name(name), DryRun(options.DryRun))
 
-		publish(a.Name(), "start", nil)
+		if skipStage(exp, a.Name(), options.Stage) {
+			publish(ProgressEvent{App: a.Name(), Status: "skipped", Message: "skipped per experiment spec"})
+			continue
+		}
+
+		if _, ok := options.Skip[a.Name()]; ok {
+			publish(ProgressEvent{App: a.Name(), Status: "skipped", Message: "skipped per start profile"})
+			continue
+		}
+
+		var (
+			timeout, _ = appTimeout(a.Name(), nil, options)
+			policy, _  = appRetryPolicy(a.Name(), nil, options)
+			actx       = withProgress(ctx, a.Name(), publish)
+			retrying   = retryReporter(a.Name(), policy, publish)
+		)
+
+		call := func(f func(context.Context, *types.Experiment) error) error {
+			return runWithRetries(actx, policy, retrying, func() error {
+				return runWithTimeout(actx, a.Name(), timeout, func(ctx context.Context) error { return f(ctx, exp) })
+			})
+		}
+
+		publish(ProgressEvent{App: a.Name(), Status: "start"})
 
 		switch options.Stage {
 		case ACTIONCONFIG:
-			err = a.Configure(ctx, exp)
+			err = call(a.Configure)
 		case ACTIONPRESTART:
-			err = a.PreStart(ctx, exp)
+			err = call(a.PreStart)
 		case ACTIONPOSTSTART:
-			err = a.PostStart(ctx, exp)
-		case ACTIONRUNNING:
-			continue // silently ignore running stage for default apps
+			err = call(a.PostStart)
 		case ACTIONCLEANUP:
-			err = a.Cleanup(ctx, exp)
+			err = call(a.Cleanup)
 		}
 
 		if err != nil {
-			publish(a.Name(), "error", err)
+			publish(ProgressEvent{App: a.Name(), Status: "error", Error: err})
 
-			plog.Error(fmt.Sprintf("[✗] '%s' default app (%s)", a.Name(), options.Stage))
-			return fmt.Errorf("applying default app %s for action %s: %w", a.Name(), options.Stage, err)
+			// Default apps generate (and inject) files for VMs, so failures are
+			// classified as injection errors unless already classified.
+			return perror.Wrap(perror.CodeInjection, "app/"+a.Name(), fmt.Errorf("applying default app %s for action %s: %w", a.Name(), options.Stage, err))
 		}
 
-		publish(a.Name(), "success", nil)
+		publish(ProgressEvent{App: a.Name(), Status: "success"})
 
-		plog.Info(fmt.Sprintf("[✓] '%s' default app (%s)", a.Name(), options.Stage))
+		applied = append(applied, a)
 	}
 
-	if exp.Spec.Scenario() != nil {
-		for _, app := range exp.Spec.Scenario().Apps() {
-			if ctx.Err() != nil {
-				return ctx.Err()
-			}
-
-			// Don't apply default apps again if configured via the Scenario.
-			if _, ok := defaultApps[app.Name()]; ok {
-				continue
-			}
+	if err := applyScenarioApps(after, before...); err != nil {
+		return err
+	}
 
-			// Skip app if disabled, unless stage is ACTIONRUNNING
-			if app.Disabled() && options.Stage != ACTIONRUNNING {
-				continue
-			}
+	if options.Stage == ACTIONCONFIG || options.Stage == ACTIONPRESTART {
+		// just in case one of the apps added some nodes to the topology...
+		exp.Spec.Topology().Init(exp.Spec.DefaultBridge())
+	}
 
-			a := GetApp(app.Name())
-			a.Init(Name(app.Name()), DryRun(options.DryRun))
+	return nil
+}
 
-			publish(a.Name(), "start", nil)
+// scenarioAppToApply returns the app to apply for the given scenario app, or
+// false if the app should be skipped for the current stage. An error is
+// returned if the app is incompatible with this version of phenix.
+func scenarioAppToApply(exp *types.Experiment, app ifaces.ScenarioApp, options Options, publish func(ProgressEvent)) (App, bool, error) {
+	// Don't apply default apps again if configured via the Scenario.
+	if isDefaultApp(app.Name()) {
+		return nil, false, nil
+	}
 
-			switch options.Stage {
-			case ACTIONCONFIG:
-				exp.Status.SetAppRunning(app.Name(), true)
-				exp.WriteToStore(true)
+	// Skip app if disabled, unless stage is ACTIONRUNNING
+	if app.Disabled() && options.Stage != ACTIONRUNNING {
+		return nil, false, nil
+	}
 
-				err = a.Configure(ctx, exp)
+	if _, ok := options.Skip[app.Name()]; ok || (app.Optional() && options.SkipOptional) {
+		if options.Stage != ACTIONRUNNING {
+			publish(ProgressEvent{App: app.Name(), Status: "skipped", Message: "skipped per start profile"})
+			return nil, false, nil
+		}
+	}
 
-				exp.Status.SetAppRunning(app.Name(), false)
-				exp.WriteToStore(true)
-			case ACTIONPRESTART:
-				exp.Status.SetAppRunning(app.Name(), true)
-				exp.WriteToStore(true)
+	a := GetApp(app.Name())
+	a.Init(Name(app.Name()), DryRun(options.DryRun))
 
-				err = a.PreStart(ctx, exp)
+	if skipStage(exp, a.Name(), options.Stage) {
+		publish(ProgressEvent{App: a.Name(), Status: "skipped", Message: "skipped per experiment spec"})
+		return nil, false, nil
+	}
 
-				exp.Status.SetAppRunning(app.Name(), false)
-				exp.WriteToStore(true)
-			case ACTIONPOSTSTART:
-				exp.Status.SetAppRunning(app.Name(), true)
-				exp.WriteToStore(true)
+	// Apps that report their capabilities are only applied for the stages they
+	// implement. Apps whose capabilities can't be determined (ie. because the
+	// app isn't installed) are applied as usual and left to fail on their own.
+	if n, ok := a.(Negotiator); ok {
+		caps, err := n.Capabilities(exp)
+		if err != nil {
+			plog.Warn("unable to get app capabilities", "app", a.Name(), "err", err)
+		}
 
-				err = a.PostStart(ctx, exp)
+		if err := caps.Compatible(a.Name()); err != nil {
+			return nil, false, perror.Wrap(perror.CodeValidation, "app/"+a.Name(), err)
+		}
 
-				exp.Status.SetAppRunning(app.Name(), false)
-				exp.WriteToStore(true)
-			case ACTIONRUNNING:
-				if len(options.Filter) > 0 {
-					if _, ok := options.Filter[app.Name()]; !ok {
-						plog.Warn(fmt.Sprintf("Skipping '%s' experiment app (%s)", app.Name(), options.Stage))
-						continue
-					}
-				}
+		if !caps.Implements(options.Stage) {
+			publish(ProgressEvent{App: a.Name(), Status: "skipped", Message: "stage not implemented by app"})
+			return nil, false, nil
+		}
+	}
 
-				// Check to make sure this app isn't already running via an automatic
-				// periodic execution.
-				if running := exp.Status.AppRunning()[app.Name()]; running {
-					notes.AddInfo(ctx, false, fmt.Sprintf("app %s is currently already executing its running stage -- skipping", app.Name()))
-					continue
-				}
+	return a, true, nil
+}
 
-				exp.Status.SetAppRunning(app.Name(), true)
+// applyScenarioApp applies the given scenario app to the given experiment for
+// the current stage. The given running function is called to mark the app as
+// running (and not) in the experiment status, except for the running stage.
+func applyScenarioApp(ctx context.Context, exp *types.Experiment, a App, app ifaces.ScenarioApp, options Options, publish func(ProgressEvent), running func(string, bool)) error {
+	// Apps not applied for the running stage are checked for before the app's
+	// run is recorded, so they don't end up with a run that never finishes.
+	if options.Stage == ACTIONRUNNING {
+		if len(options.Filter) > 0 {
+			if _, ok := options.Filter[app.Name()]; !ok {
+				plog.Warn(fmt.Sprintf("Skipping '%s' experiment app (%s)", app.Name(), options.Stage))
+				return nil
+			}
+		}
 
-				if err := exp.WriteToStore(true); err != nil {
-					notes.AddErrors(ctx, false, fmt.Errorf("error updating store with experiment (%s): %v", exp.Spec.ExperimentName(), err))
-				}
+		// Check to make sure this app isn't already running via an automatic
+		// periodic execution.
+		if running := exp.Status.AppRunning()[app.Name()]; running {
+			notes.AddInfo(ctx, false, fmt.Sprintf("app %s is currently already executing its running stage -- skipping", app.Name()))
+			return nil
+		}
+	}
 
-				err = a.Running(ctx, exp)
+	timeout, err := appTimeout(a.Name(), app.Metadata(), options)
+	if err != nil {
+		publish(ProgressEvent{App: a.Name(), Status: "error", Error: err})
+		return perror.Wrap(perror.CodeValidation, "app/"+a.Name(), err)
+	}
 
-				exp.Reload() // reload experiment from store in case status was updated during run
-				exp.Status.SetAppRunning(app.Name(), false)
+	policy, err := appRetryPolicy(a.Name(), app.Metadata(), options)
+	if err != nil {
+		publish(ProgressEvent{App: a.Name(), Status: "error", Error: err})
+		return perror.Wrap(perror.CodeValidation, "app/"+a.Name(), err)
+	}
 
-				if err := exp.WriteToStore(true); err != nil {
-					notes.AddErrors(ctx, false, fmt.Errorf("error updating store with experiment (%s): %v", exp.Spec.ExperimentName(), err))
-				}
-			case ACTIONCLEANUP:
-				exp.Status.SetAppRunning(app.Name(), true)
-				exp.WriteToStore(true)
+	var (
+		actx     = withProgress(ctx, a.Name(), publish)
+		retrying = retryReporter(a.Name(), policy, publish)
+	)
 
-				err = a.Cleanup(ctx, exp)
+	call := func(f func(context.Context, *types.Experiment) error) error {
+		return runWithRetries(actx, policy, retrying, func() error {
+			return runWithTimeout(actx, a.Name(), timeout, func(ctx context.Context) error { return f(ctx, exp) })
+		})
+	}
 
-				exp.Status.SetAppRunning(app.Name(), false)
-				exp.WriteToStore(true)
-			}
+	publish(ProgressEvent{App: a.Name(), Status: "start"})
+
+	switch options.Stage {
+	case ACTIONCONFIG:
+		running(app.Name(), true)
+		err = call(a.Configure)
+		running(app.Name(), false)
+	case ACTIONPRESTART:
+		running(app.Name(), true)
+		err = call(a.PreStart)
+		running(app.Name(), false)
+	case ACTIONPOSTSTART:
+		running(app.Name(), true)
+		err = call(a.PostStart)
+		running(app.Name(), false)
+	case ACTIONRUNNING:
+		exp.Status.SetAppRunning(app.Name(), true)
+
+		if err := exp.WriteToStore(true); err != nil {
+			notes.AddErrors(ctx, false, fmt.Errorf("error updating store with experiment (%s): %v", exp.Spec.ExperimentName(), err))
+		}
 
-			if err != nil {
-				publish(a.Name(), "error", err)
+		err = call(a.Running)
 
-				if errors.Is(err, ErrUserAppNotFound) {
-					plog.Warn(fmt.Sprintf("[?] '%s' user app (%s)", a.Name(), options.Stage))
-					continue
-				}
+		exp.Reload() // reload experiment from store in case status was updated during run
+		exp.Status.SetAppRunning(app.Name(), false)
 
-				plog.Error(fmt.Sprintf("[✗] '%s' user app (%s)", a.Name(), options.Stage))
-				return fmt.Errorf("applying user app %s for action %s: %w", a.Name(), options.Stage, err)
+		// Write the experiment to the store once the result of the run has been
+		// recorded, since the running stage is applied outside of the experiment
+		// lifecycle.
+		defer func() {
+			if err := exp.WriteToStore(true); err != nil {
+				notes.AddErrors(ctx, false, fmt.Errorf("error updating store with experiment (%s): %v", exp.Spec.ExperimentName(), err))
 			}
+		}()
+	case ACTIONCLEANUP:
+		running(app.Name(), true)
+		err = call(a.Cleanup)
+		running(app.Name(), false)
+	}
 
-			publish(a.Name(), "success", nil)
+	if err != nil {
+		publish(ProgressEvent{App: a.Name(), Status: "error", Error: err})
 
-			plog.Info(fmt.Sprintf("[✓] '%s' user app (%s)", a.Name(), options.Stage))
+		if errors.Is(err, ErrUserAppNotFound) {
+			return nil
 		}
-	}
 
-	if options.Stage == ACTIONCONFIG || options.Stage == ACTIONPRESTART {
-		// just in case one of the apps added some nodes to the topology...
-		exp.Spec.Topology().Init(exp.Spec.DefaultBridge())
+		return perror.Wrap(perror.CodeUserApp, "app/"+a.Name(), fmt.Errorf("applying user app %s for action %s: %w", a.Name(), options.Stage, err))
 	}
 
+	publish(ProgressEvent{App: a.Name(), Status: "success"})
+
 	return nil
 }
 
@@ -351,7 +795,7 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 	if exp.Spec.Scenario() != nil {
 		for _, app := range exp.Spec.Scenario().Apps() {
 			// Don't consider default apps as candidates for running periodically.
-			if _, ok := defaultApps[app.Name()]; ok {
+			if isDefaultApp(app.Name()) {
 				continue
 			}
 
@@ -376,7 +820,10 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 						plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
 					}
 
-					timer := time.NewTimer(duration)
+					var (
+						timer   = time.NewTimer(duration)
+						publish = progressPublisher(exp.Spec.ExperimentName(), ACTIONRUNNING, nil, true)
+					)
 
 					for {
 						select {
@@ -398,6 +845,9 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 							// periodic runs.
 							if running := exp.Status.AppRunning()[app.Name()]; running {
 								plog.Info("[✓] app is currently already executing its running stage -- skipping", "app", app.Name())
+
+								// Wait for the next period rather than stopping periodic runs.
+								timer.Reset(duration)
 								continue
 							}
 
@@ -412,26 +862,31 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 							a.Init(Name(app.Name()))
 
 							exp.Status.SetAppRunning(app.Name(), true)
+							exp.Status.SetAppRun(app.Name(), string(ACTIONRUNNING), "running", nil)
 
 							if err := exp.WriteToStore(true); err != nil {
 								plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
 							}
 
-							pubsub.Publish("trigger-app", TriggerPublication{
-								Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "start",
-							})
+							publish(ProgressEvent{App: app.Name(), Status: "start"})
 
-							if err := a.Running(ctx, exp); err != nil {
-								pubsub.Publish("trigger-app", TriggerPublication{
-									Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "error", Error: err,
-								})
+							// Periodic runs honor the app's timeout metadata, if any, so a hung
+							// run doesn't block every run after it.
+							timeout, _ := appTimeout(app.Name(), app.Metadata(), NewOptions())
 
-								plog.Error("[✗] error periodically running app", "app", app.Name(), "err", err)
-							}
+							actx := withProgress(ctx, app.Name(), publish)
+
+							err := runWithTimeout(actx, app.Name(), timeout, func(ctx context.Context) error { return a.Running(ctx, exp) })
 
-							pubsub.Publish("trigger-app", TriggerPublication{
-								Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "success",
-							})
+							if err != nil {
+								publish(ProgressEvent{App: app.Name(), Status: "error", Error: err})
+
+								exp.Status.SetAppRun(app.Name(), string(ACTIONRUNNING), "error", err)
+							} else {
+								publish(ProgressEvent{App: app.Name(), Status: "success"})
+
+								exp.Status.SetAppRun(app.Name(), string(ACTIONRUNNING), "success", nil)
+							}
 
 							exp.Status.SetAppRunning(app.Name(), false)
 
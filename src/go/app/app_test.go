@@ -1,14 +1,114 @@
 package app
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"phenix/store"
+	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
 )
 
 // Helper test function(s) for app package.
 
+// testApp is a phenix app that calls the same function for every stage.
+type testApp struct {
+	name string
+	run  func(context.Context, *types.Experiment) error
+}
+
+func (this testApp) Init(...Option) error { return nil }
+func (this testApp) Name() string         { return this.name }
+
+func (this testApp) Configure(ctx context.Context, exp *types.Experiment) error {
+	return this.run(ctx, exp)
+}
+
+func (this testApp) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return this.run(ctx, exp)
+}
+
+func (this testApp) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return this.run(ctx, exp)
+}
+
+func (this testApp) Running(ctx context.Context, exp *types.Experiment) error {
+	return this.run(ctx, exp)
+}
+
+func (this testApp) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return this.run(ctx, exp)
+}
+
+// registerTestApp registers an app with the given name that calls the given
+// function for every stage, for the duration of the test.
+func registerTestApp(t *testing.T, name string, run func(context.Context, *types.Experiment) error) {
+	factory, ok := apps[name]
+
+	t.Cleanup(func() {
+		if ok {
+			apps[name] = factory
+		} else {
+			delete(apps, name)
+		}
+	})
+
+	apps[name] = func() App { return &testApp{name: name, run: run} }
+}
+
+// initTestStore initializes a new store for the duration of the test, restoring
+// the previous store once the test finishes so later tests don't use a store
+// whose files have been removed.
+func initTestStore(t *testing.T) {
+	prev := store.DefaultStore
+
+	if err := store.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	t.Cleanup(func() { store.DefaultStore = prev })
+}
+
+// newTestExperiment creates an experiment with the given scenario apps in a new
+// store and returns it decoded from the store.
+func newTestExperiment(t *testing.T, scenario ...map[string]any) *types.Experiment {
+	initTestStore(t)
+
+	apps := make([]any, len(scenario))
+
+	for i, app := range scenario {
+		apps[i] = app
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec: map[string]any{
+			"experimentName": "test",
+			"topology":       map[string]any{"nodes": []any{}},
+			"scenario":       map[string]any{"apps": apps},
+		},
+	}
+
+	if err := store.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return exp
+}
+
 func checkConfigureExpected(t *testing.T, nodes []ifaces.NodeSpec, expected [][]ifaces.NodeInjection) {
 	for i, node := range nodes {
 		inj := node.Injections()
@@ -43,3 +143,83 @@ func checkStartExpected(t *testing.T, nodes []ifaces.NodeSpec, expected [][]ifac
 		}
 	}
 }
+
+func TestSkipStage(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		SkipStagesF: map[string][]string{"soh": {"post-start", "cleanup"}},
+	}
+
+	exp := &types.Experiment{Spec: spec, Status: new(v1.ExperimentStatus)}
+
+	if !skipStage(exp, "soh", ACTIONPOSTSTART) {
+		t.Log("expected soh post-start stage to be skipped")
+		t.FailNow()
+	}
+
+	if skipStage(exp, "soh", ACTIONPRESTART) {
+		t.Log("expected soh pre-start stage to not be skipped")
+		t.FailNow()
+	}
+
+	if skipStage(exp, "tap", ACTIONPOSTSTART) {
+		t.Log("expected tap post-start stage to not be skipped")
+		t.FailNow()
+	}
+
+	skipStage(exp, "soh", ACTIONPOSTSTART)
+
+	if skipped := exp.Status.AppSkipped()["soh"]; len(skipped) != 1 || skipped[0] != "post-start" {
+		t.Logf("expected soh post-start stage to be marked skipped once, got %v", skipped)
+		t.FailNow()
+	}
+}
+
+func TestApplyAppsRunningStage(t *testing.T) {
+	var ran []string
+
+	run := func(name string) func(context.Context, *types.Experiment) error {
+		return func(context.Context, *types.Experiment) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+
+	registerTestApp(t, "poll", run("poll"))
+	registerTestApp(t, "busy", run("busy"))
+	registerTestApp(t, "other", run("other"))
+
+	exp := newTestExperiment(t,
+		map[string]any{"name": "poll"},
+		map[string]any{"name": "busy"},
+		map[string]any{"name": "other"},
+	)
+
+	// busy is already executing its running stage periodically.
+	exp.Status.SetAppRunning("busy", true)
+	exp.WriteToStore(true)
+
+	if err := ApplyApps(context.Background(), exp, Stage(ACTIONRUNNING), FilterApp("poll", "busy")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(ran) != 1 || ran[0] != "poll" {
+		t.Logf("expected only poll app to be run, got %v", ran)
+		t.FailNow()
+	}
+
+	runs := exp.Status.AppRuns()
+
+	if run := runs["poll"][string(ACTIONRUNNING)]; run == nil || run.Result() != "success" {
+		t.Logf("expected run of poll app to be recorded as a success, got %v", run)
+		t.FailNow()
+	}
+
+	// Apps not run don't get a run recorded that never finishes.
+	for _, name := range append([]string{"busy", "other"}, DefaultApps()...) {
+		if run, ok := runs[name][string(ACTIONRUNNING)]; ok {
+			t.Logf("expected no running stage run recorded for app %s, got %s", name, run.Result())
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"phenix/util/common"
+)
+
+// AppLogPath returns the path to the log file the output of the given custom
+// user app is written to for the given experiment. It's in the experiment's
+// files directory so it can be viewed along with the experiment's other files.
+func AppLogPath(exp, app string) string {
+	return filepath.Join(common.PhenixBase, "images", exp, "files", "logs", "apps", app+".log")
+}
+
+// appLog is the log file a custom user app's STDOUT and STDERR are written to
+// as the app runs, each app invocation being appended to the file after a
+// header identifying the stage being applied.
+type appLog struct {
+	sync.Mutex
+
+	f *os.File
+}
+
+func openAppLog(exp, app string, action Action) (*appLog, error) {
+	path := AppLogPath(exp, app)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating app log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening app log file: %w", err)
+	}
+
+	fmt.Fprintf(f, "==> %s %s <==\n", time.Now().Format(time.RFC3339), action)
+
+	return &appLog{f: f}, nil
+}
+
+// Write writes to the log file. STDOUT and STDERR are written concurrently, so
+// writes are serialized. Errors writing to the log file are ignored so they
+// don't cause the app itself to fail.
+func (this *appLog) Write(p []byte) (int, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.f.Write(p)
+
+	return len(p), nil
+}
+
+func (this *appLog) Close() error {
+	return this.f.Close()
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+// DEFAULT_ARTIFACT_MIME_TYPE is the MIME type of artifacts registered without
+// one whose type can't be determined from their file extension.
+const DEFAULT_ARTIFACT_MIME_TYPE = "application/octet-stream"
+
+// RegisterArtifact registers the file at the given path as an artifact
+// produced by the given app, so it's listed with the app's artifacts and
+// downloadable via the experiment files service. The path must be in the
+// experiment's files directory, and can be relative to it. The MIME type is
+// determined from the file extension if not provided. Registering an artifact
+// with the same name as one already registered by the app replaces it.
+func RegisterArtifact(exp *types.Experiment, app, name, path, mimeType, description string) error {
+	artifact := &v1.Artifact{
+		NameF:        name,
+		PathF:        path,
+		MIMETypeF:    mimeType,
+		DescriptionF: description,
+	}
+
+	return registerArtifact(exp, app, artifact)
+}
+
+func registerArtifact(exp *types.Experiment, app string, a ifaces.Artifact) error {
+	if exp.Status == nil {
+		return fmt.Errorf("experiment %s has no status to register artifacts in", exp.Metadata.Name)
+	}
+
+	if a.Name() == "" {
+		return fmt.Errorf("artifact registered by app %s missing name", app)
+	}
+
+	path, err := artifactPath(exp, a.Path())
+	if err != nil {
+		return fmt.Errorf("invalid path for artifact %s registered by app %s: %w", a.Name(), app, err)
+	}
+
+	mimeType := a.MIMEType()
+
+	if mimeType == "" {
+		if mimeType = mime.TypeByExtension(filepath.Ext(path)); mimeType == "" {
+			mimeType = DEFAULT_ARTIFACT_MIME_TYPE
+		}
+	} else if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+		return fmt.Errorf("invalid MIME type %s for artifact %s registered by app %s: %w", mimeType, a.Name(), app, err)
+	}
+
+	exp.Status.SetAppArtifact(app, &v1.Artifact{
+		NameF:        a.Name(),
+		PathF:        path,
+		MIMETypeF:    mimeType,
+		DescriptionF: a.Description(),
+		CreatedF:     a.Created(),
+	})
+
+	return nil
+}
+
+// artifactPath returns the given artifact path relative to the experiment's
+// files directory, ensuring it's in the files directory.
+func artifactPath(exp *types.Experiment, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no path provided")
+	}
+
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(exp.FilesDir(), path)
+		if err != nil {
+			return "", fmt.Errorf("path %s not in experiment files directory %s", path, exp.FilesDir())
+		}
+
+		path = rel
+	}
+
+	path = filepath.Clean(path)
+
+	if path == "." || path == ".." || strings.HasPrefix(path, "../") {
+		return "", fmt.Errorf("path %s not in experiment files directory %s", path, exp.FilesDir())
+	}
+
+	return path, nil
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+)
+
+func TestRegisterArtifact(t *testing.T) {
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+
+	if err := RegisterArtifact(exp, "dhcp", "config", exp.FilesDir()+"/dhcp/dnsmasq.conf", "", "generated config"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := RegisterArtifact(exp, "dhcp", "report", "reports/leases.json", "", ""); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// replaces the existing artifact with the same name
+	if err := RegisterArtifact(exp, "dhcp", "config", "dhcp/kea.conf", "text/plain", ""); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	arts := exp.Status.AppArtifacts()["dhcp"]
+
+	if len(arts) != 2 {
+		t.Logf("expected 2 artifacts, got %d", len(arts))
+		t.FailNow()
+	}
+
+	if arts[0].Path() != "dhcp/kea.conf" || arts[0].MIMEType() != "text/plain" || arts[0].Created() == "" {
+		t.Logf("unexpected replaced artifact: %+v", arts[0])
+		t.FailNow()
+	}
+
+	if arts[1].Path() != "reports/leases.json" || arts[1].MIMEType() != "application/json" {
+		t.Logf("unexpected artifact: %+v", arts[1])
+		t.FailNow()
+	}
+
+	invalid := []struct {
+		name, path, mimeType string
+	}{
+		{"", "report.txt", ""},
+		{"escape", "../other/report.txt", ""},
+		{"outside", "/etc/passwd", ""},
+		{"mime", "report.txt", "not a mime type"},
+	}
+
+	for _, i := range invalid {
+		if err := RegisterArtifact(exp, "dhcp", i.name, i.path, i.mimeType, ""); err == nil {
+			t.Logf("expected artifact %q at %s to be invalid", i.name, i.path)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/user"
+	"time"
+
+	"phenix/types"
+	"phenix/util/audit"
+)
+
+// auditApp records the given app's invocation for the given stage in the audit
+// log, including who triggered it, how long it ran for, how it exited, and the
+// host it ran on. The user is taken from the given context (see
+// `SetContextUser`), falling back to the local user running phenix.
+func auditApp(ctx context.Context, exp *types.Experiment, name string, stage Action, started time.Time, err error) {
+	details := map[string]any{
+		"experiment": exp.Metadata.Name,
+		"app":        name,
+		"stage":      string(stage),
+		"status":     "success",
+	}
+
+	if !started.IsZero() {
+		duration := time.Since(started)
+
+		details["duration"] = duration.Round(time.Millisecond).String()
+		details["durationMs"] = duration.Milliseconds()
+	}
+
+	if err != nil {
+		details["status"] = "error"
+		details["error"] = err.Error()
+
+		var exitErr *exec.ExitError
+
+		// Custom user apps are separate executables, so include their exit code.
+		if errors.As(err, &exitErr) {
+			details["exitCode"] = exitErr.ExitCode()
+		}
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		details["host"] = host
+	}
+
+	audit.Record(audit.Event{
+		User:     triggeredBy(ctx),
+		Action:   "app/" + string(stage),
+		Resource: "apps/" + name,
+		Details:  details,
+	})
+}
+
+func triggeredBy(ctx context.Context) string {
+	if u := GetContextUser(ctx); u != "" {
+		return u
+	}
+
+	// Only trust `SUDO_USER` env variable if we're currently running as root.
+	if sudo := os.Getenv("SUDO_USER"); sudo != "" && os.Geteuid() == 0 {
+		return sudo
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/types"
+	"phenix/util/plog"
+	"phenix/util/shell"
+)
+
+const (
+	// APP_API_VERSION is the version of the interface between phenix and user
+	// apps (the experiment passed to apps, the lifecycle stages, and the special
+	// exit codes). It's incremented when the interface changes in a way existing
+	// apps could silently break on.
+	APP_API_VERSION = 1
+
+	// MIN_APP_API_VERSION is the oldest app API version still supported.
+	MIN_APP_API_VERSION = 1
+
+	// CAPABILITIES_ARG is the argument user apps using the shell protocol are
+	// executed with (instead of a lifecycle stage) to report their capabilities.
+	CAPABILITIES_ARG = "capabilities"
+)
+
+// CAPABILITIES_TIMEOUT is how long phenix waits for a user app to report its
+// capabilities.
+var CAPABILITIES_TIMEOUT = 10 * time.Second
+
+var ErrIncompatibleApp = errors.New("app incompatible with phenix")
+
+// Capabilities is what an app reports it supports. Apps that don't report
+// their capabilities are assumed to implement every stage and be compatible.
+type Capabilities struct {
+	// APIVersion is the app API version the app was written against.
+	APIVersion int `json:"apiVersion" yaml:"apiVersion"`
+
+	// Stages are the lifecycle stages the app implements. All stages are assumed
+	// to be implemented if none are listed.
+	Stages []Action `json:"stages" yaml:"stages"`
+}
+
+// Implements returns true if the app implements the given stage.
+func (this Capabilities) Implements(stage Action) bool {
+	if len(this.Stages) == 0 {
+		return true
+	}
+
+	for _, s := range this.Stages {
+		if s == stage {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compatible returns an error wrapping ErrIncompatibleApp if the app API
+// version reported by the given app isn't supported by this version of phenix.
+func (this Capabilities) Compatible(name string) error {
+	switch {
+	case this.APIVersion == 0:
+		return nil
+	case this.APIVersion > APP_API_VERSION:
+		return fmt.Errorf("%w: app %s requires app API version %d, but this version of phenix only supports up to version %d (upgrade phenix)", ErrIncompatibleApp, name, this.APIVersion, APP_API_VERSION)
+	case this.APIVersion < MIN_APP_API_VERSION:
+		return fmt.Errorf("%w: app %s was written for app API version %d, but this version of phenix only supports version %d and later (upgrade the app)", ErrIncompatibleApp, name, this.APIVersion, MIN_APP_API_VERSION)
+	}
+
+	return nil
+}
+
+func (this Capabilities) validate() error {
+	for _, s := range this.Stages {
+		if _, ok := rpcMethods[s]; !ok {
+			return fmt.Errorf("unknown stage %s", s)
+		}
+	}
+
+	return nil
+}
+
+// Negotiator is implemented by apps that can report their capabilities.
+// ApplyApps only applies these apps for the stages they implement, and refuses
+// to apply them at all if they're incompatible.
+type Negotiator interface {
+	Capabilities(*types.Experiment) (Capabilities, error)
+}
+
+// Capabilities returns the capabilities of the user app. Capabilities declared
+// in the app's registry entry are used if present (and the app isn't pinned to
+// a specific version). Otherwise, apps using the shell protocol are executed
+// with the `capabilities` argument and are expected to write their
+// capabilities to STDOUT as JSON, ie.
+//
+//	{"apiVersion": 1, "stages": ["configure", "pre-start"]}
+//
+// Apps that don't (ie. exit with a non-zero status or write something else)
+// are assumed to implement every stage. Apps using the gRPC protocol report which
+// stages they implement when each stage is called.
+func (this UserApp) Capabilities(exp *types.Experiment) (Capabilities, error) {
+	if entry, ok := RegisteredApp(this.options.Name); ok && this.pinnedVersion(exp) == "" && (entry.APIVersion != 0 || len(entry.Stages) > 0) {
+		return Capabilities{APIVersion: entry.APIVersion, Stages: entry.Stages}, nil
+	}
+
+	exe, err := this.executable(exp)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	switch exe.protocol {
+	case "", USER_APP_PROTOCOL_SHELL:
+		caps, err := shellCapabilities(exe.cmd)
+		if err != nil {
+			return Capabilities{}, fmt.Errorf("getting capabilities of user app %s: %w", this.options.Name, err)
+		}
+
+		return caps, nil
+	}
+
+	return Capabilities{}, nil
+}
+
+type cachedCapabilities struct {
+	modified time.Time
+	caps     Capabilities
+}
+
+var (
+	capabilities   = make(map[string]cachedCapabilities)
+	capabilitiesMu sync.Mutex
+)
+
+// shellCapabilities gets the capabilities of the given shell protocol app
+// command. Capabilities are cached until the command's executable changes.
+func shellCapabilities(cmd string) (Capabilities, error) {
+	path, err := exec.LookPath(cmd)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("finding executable %s: %w", cmd, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("getting executable info for %s: %w", path, err)
+	}
+
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+
+	if cached, ok := capabilities[path]; ok && cached.modified.Equal(info.ModTime()) {
+		return cached.caps, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CAPABILITIES_TIMEOUT)
+	defer cancel()
+
+	opts := []shell.Option{
+		shell.Command(path),
+		shell.Args(CAPABILITIES_ARG),
+		shell.Stdin([]byte{}),
+	}
+
+	var caps Capabilities
+
+	// Apps written before the handshake existed are likely to fail or write
+	// nothing (or usage) when executed with an unknown argument.
+	if stdout, _, err := shell.ExecCommand(ctx, opts...); err != nil {
+		plog.Debug("user app did not report capabilities", "cmd", path, "err", err)
+	} else if out := strings.TrimSpace(string(stdout)); out != "" {
+		if err := json.Unmarshal([]byte(out), &caps); err != nil {
+			plog.Debug("user app did not report capabilities", "cmd", path, "err", err)
+			caps = Capabilities{}
+		} else if err := caps.validate(); err != nil {
+			return Capabilities{}, fmt.Errorf("invalid capabilities: %w", err)
+		}
+	}
+
+	capabilities[path] = cachedCapabilities{modified: info.ModTime(), caps: caps}
+
+	return caps, nil
+}
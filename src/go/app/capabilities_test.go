@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShellCapabilities(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		script = filepath.Join(dir, "phenix-app-test")
+		body   = "#!/bin/sh\n[ \"$1\" = capabilities ] && echo '{\"apiVersion\": 1, \"stages\": [\"configure\", \"cleanup\"]}'\n"
+	)
+
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	caps, err := shellCapabilities(script)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := caps.Compatible("test"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !caps.Implements(ACTIONCONFIG) || !caps.Implements(ACTIONCLEANUP) || caps.Implements(ACTIONPRESTART) {
+		t.Logf("unexpected stages %v", caps.Stages)
+		t.FailNow()
+	}
+
+	// Apps that fail when asked for their capabilities implement every stage.
+	legacy := filepath.Join(dir, "phenix-app-legacy")
+
+	if err := os.WriteFile(legacy, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if caps, err = shellCapabilities(legacy); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !caps.Implements(ACTIONPRESTART) {
+		t.Log("expected legacy app to implement every stage")
+		t.FailNow()
+	}
+}
+
+func TestCapabilitiesCompatible(t *testing.T) {
+	if err := (Capabilities{APIVersion: APP_API_VERSION + 1}).Compatible("test"); !errors.Is(err, ErrIncompatibleApp) {
+		t.Logf("expected incompatible app error, got %v", err)
+		t.FailNow()
+	}
+
+	if err := (Capabilities{}).Compatible("test"); err != nil {
+		t.Logf("expected app without API version to be compatible, got %v", err)
+		t.FailNow()
+	}
+}
@@ -3,9 +3,10 @@ package app
 import "context"
 
 type (
-	metadata   struct{}
-	triggerUI  struct{}
-	triggerCLI struct{}
+	metadata    struct{}
+	triggerUI   struct{}
+	triggerCLI  struct{}
+	triggerUser struct{}
 )
 
 func AddContextMetadata(ctx context.Context, key string, val any) context.Context {
@@ -37,6 +38,12 @@ func SetContextTriggerCLI(ctx context.Context) context.Context {
 	return context.WithValue(ctx, triggerCLI{}, struct{}{})
 }
 
+// SetContextUser sets the user that triggered the apps applied with the given
+// context, which is recorded in the audit log for each app invoked.
+func SetContextUser(ctx context.Context, u string) context.Context {
+	return context.WithValue(ctx, triggerUser{}, u)
+}
+
 func GetContextMetadata(ctx context.Context) map[string]any {
 	md := ctx.Value(metadata{})
 	if md != nil {
@@ -46,6 +53,16 @@ func GetContextMetadata(ctx context.Context) map[string]any {
 	return make(map[string]any)
 }
 
+// GetContextUser returns the user that triggered the apps applied with the
+// given context, if set.
+func GetContextUser(ctx context.Context) string {
+	if u, ok := ctx.Value(triggerUser{}).(string); ok {
+		return u
+	}
+
+	return ""
+}
+
 func IsContextTriggerUI(ctx context.Context) bool {
 	ok := ctx.Value(triggerUI{})
 	return ok != nil
@@ -0,0 +1,341 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/plog"
+)
+
+// DEPENDS_ON_KEY is the scenario app metadata key used to declare the apps an
+// app depends on, as a list of app names (or a single comma separated string).
+// Apps that declare dependencies (even an empty list) are applied once all the
+// apps they depend on have been applied, concurrently with other apps whose
+// dependencies have also been applied. Apps that don't declare dependencies
+// depend on the app listed before them in the scenario, which preserves the
+// original serial behavior.
+const DEPENDS_ON_KEY = "dependsOn"
+
+// scenarioAppGroups orders the given scenario apps by weight, keeping apps
+// with the same weight in scenario order, and splits them into the apps to
+// apply before the default apps (those with runBeforeDefaults set) and the
+// apps to apply after them. Apps applied before the default apps can't depend
+// on apps applied after them.
+func scenarioAppGroups(apps []ifaces.ScenarioApp) ([]ifaces.ScenarioApp, []ifaces.ScenarioApp, error) {
+	sorted := make([]ifaces.ScenarioApp, len(apps))
+	copy(sorted, apps)
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Weight() < sorted[j].Weight() })
+
+	var (
+		before, after []ifaces.ScenarioApp
+		later         = make(map[string]bool)
+	)
+
+	for _, app := range sorted {
+		if app.RunBeforeDefaults() {
+			before = append(before, app)
+		} else {
+			after = append(after, app)
+			later[app.Name()] = true
+		}
+	}
+
+	for _, app := range before {
+		names, _, err := dependsOn(app)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, name := range names {
+			if later[name] {
+				return nil, nil, fmt.Errorf("app %s runs before the default apps but depends on app %s, which doesn't", app.Name(), name)
+			}
+		}
+	}
+
+	return before, after, nil
+}
+
+// scenarioAppLevels groups the given scenario apps into levels, where each app
+// only depends on apps in earlier levels. Apps are kept in the given order
+// within each level. Dependencies on the given apps applied earlier are
+// ignored. It also returns true if any app declared dependencies, in which case
+// apps in the same level can be applied concurrently.
+func scenarioAppLevels(apps []ifaces.ScenarioApp, earlier ...ifaces.ScenarioApp) ([][]ifaces.ScenarioApp, bool, error) {
+	var (
+		index    = make(map[string]int)
+		applied  = make(map[string]bool)
+		deps     = make([][]int, len(apps))
+		declared bool
+	)
+
+	for i, app := range apps {
+		index[app.Name()] = i
+	}
+
+	for _, app := range earlier {
+		applied[app.Name()] = true
+	}
+
+	for i, app := range apps {
+		names, ok, err := dependsOn(app)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !ok {
+			if i > 0 {
+				deps[i] = []int{i - 1}
+			}
+
+			continue
+		}
+
+		declared = true
+
+		for _, name := range names {
+			if applied[name] {
+				continue
+			}
+
+			j, ok := index[name]
+			if !ok {
+				return nil, false, fmt.Errorf("app %s depends on app %s, which isn't in the scenario", app.Name(), name)
+			}
+
+			if j == i {
+				return nil, false, fmt.Errorf("app %s depends on itself", app.Name())
+			}
+
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	var (
+		levels [][]ifaces.ScenarioApp
+		done   = make(map[int]bool)
+	)
+
+	for len(done) < len(apps) {
+		var current []int
+
+		for i := range apps {
+			if done[i] {
+				continue
+			}
+
+			ready := true
+
+			for _, j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				current = append(current, i)
+			}
+		}
+
+		if len(current) == 0 {
+			var cycle []string
+
+			for i, app := range apps {
+				if !done[i] {
+					cycle = append(cycle, app.Name())
+				}
+			}
+
+			return nil, false, fmt.Errorf("dependency cycle between apps %s", strings.Join(cycle, ", "))
+		}
+
+		var group []ifaces.ScenarioApp
+
+		for _, i := range current {
+			done[i] = true
+			group = append(group, apps[i])
+		}
+
+		levels = append(levels, group)
+	}
+
+	return levels, declared, nil
+}
+
+// dependsOn returns the names of the apps the given app declares it depends on,
+// and whether the app declared dependencies at all.
+func dependsOn(app ifaces.ScenarioApp) ([]string, bool, error) {
+	val, ok := app.Metadata()[DEPENDS_ON_KEY]
+	if !ok {
+		return nil, false, nil
+	}
+
+	var names []string
+
+	switch val := val.(type) {
+	case nil:
+	case string:
+		for _, name := range strings.Split(val, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	case []string:
+		names = val
+	case []any:
+		for _, name := range val {
+			s, ok := name.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("invalid %s entry %v for app %s", DEPENDS_ON_KEY, name, app.Name())
+			}
+
+			names = append(names, s)
+		}
+	default:
+		return nil, false, fmt.Errorf("invalid %s for app %s (expected list of app names)", DEPENDS_ON_KEY, app.Name())
+	}
+
+	return names, true, nil
+}
+
+// applyScenarioAppsConcurrently applies the given scenario apps to the given
+// experiment concurrently for the current stage. Each app is applied to its own
+// copy of the experiment, and only the app's status and version are merged
+// back. The apps successfully applied are returned, along with the first error
+// encountered once all apps have finished.
+func applyScenarioAppsConcurrently(ctx context.Context, exp *types.Experiment, apps []ifaces.ScenarioApp, options Options, publish func(ProgressEvent)) ([]App, error) {
+	type job struct {
+		a   App
+		app ifaces.ScenarioApp
+		exp *types.Experiment
+	}
+
+	var jobs []job
+
+	for _, app := range apps {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		a, ok, err := scenarioAppToApply(exp, app, options, publish)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		cp, err := copyExperiment(exp)
+		if err != nil {
+			return nil, fmt.Errorf("copying experiment for app %s: %w", app.Name(), err)
+		}
+
+		jobs = append(jobs, job{a: a, app: app, exp: cp})
+	}
+
+	if len(jobs) > 1 {
+		names := make([]string, len(jobs))
+
+		for i, j := range jobs {
+			names[i] = j.app.Name()
+		}
+
+		plog.Info("applying user apps concurrently", "stage", options.Stage, "apps", names)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		applied []App
+		first   error
+	)
+
+	// Apps publish their results (which are recorded in the experiment status)
+	// concurrently.
+	publishUnsafe := publish
+
+	publish = func(e ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		publishUnsafe(e)
+	}
+
+	running := func(name string, running bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		exp.Status.SetAppRunning(name, running)
+		exp.WriteToStore(true)
+	}
+
+	for _, j := range jobs {
+		wg.Add(1)
+
+		go func(j job) {
+			defer wg.Done()
+
+			err := applyScenarioApp(ctx, j.exp, j.a, j.app, options, publish, running)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			name := j.a.Name()
+
+			if status, ok := j.exp.Status.AppStatus()[name]; ok {
+				exp.Status.SetAppStatus(name, status)
+			}
+
+			if version, ok := j.exp.Status.AppVersions()[name]; ok {
+				exp.Status.SetAppVersion(name, version)
+			}
+
+			if expects, ok := j.exp.Status.AppExpectations()[name]; ok {
+				exp.Status.ClearAppExpectations(name)
+
+				for _, e := range expects {
+					exp.Status.SetAppExpectation(name, e)
+				}
+			}
+
+			for _, a := range j.exp.Status.AppArtifacts()[name] {
+				exp.Status.SetAppArtifact(name, a)
+			}
+
+			if err == nil {
+				applied = append(applied, j.a)
+			} else if first == nil {
+				first = err
+			}
+		}(j)
+	}
+
+	wg.Wait()
+
+	return applied, first
+}
+
+// copyExperiment returns a deep copy of the given experiment.
+func copyExperiment(exp *types.Experiment) (*types.Experiment, error) {
+	data, err := json.Marshal(exp)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling experiment to JSON: %w", err)
+	}
+
+	cp := types.NewExperiment(exp.Metadata)
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("unmarshaling experiment from JSON: %w", err)
+	}
+
+	return cp, nil
+}
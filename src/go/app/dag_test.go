@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	v2 "phenix/types/version/v2"
+)
+
+func TestScenarioAppGroups(t *testing.T) {
+	apps := []ifaces.ScenarioApp{
+		&v2.ScenarioApp{NameF: "soh", WeightF: 10},
+		&v2.ScenarioApp{NameF: "ipam", RunBeforeDefaultsF: true},
+		&v2.ScenarioApp{NameF: "vrouter"},
+		&v2.ScenarioApp{NameF: "tap", WeightF: -1},
+		&v2.ScenarioApp{NameF: "dns", WeightF: -5, RunBeforeDefaultsF: true},
+	}
+
+	before, after, err := scenarioAppGroups(apps)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	names := func(apps []ifaces.ScenarioApp) []string {
+		var n []string
+
+		for _, app := range apps {
+			n = append(n, app.Name())
+		}
+
+		return n
+	}
+
+	if got := names(before); len(got) != 2 || got[0] != "dns" || got[1] != "ipam" {
+		t.Logf("expected dns, ipam to run before defaults, got %v", got)
+		t.FailNow()
+	}
+
+	if got := names(after); len(got) != 3 || got[0] != "tap" || got[1] != "vrouter" || got[2] != "soh" {
+		t.Logf("expected tap, vrouter, soh to run after defaults, got %v", got)
+		t.FailNow()
+	}
+
+	// Apps applied after the default apps can depend on apps applied before.
+	after[0].SetMetadata(map[string]any{DEPENDS_ON_KEY: "ipam"})
+
+	if _, _, err := scenarioAppLevels(after, before...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Apps applied before the default apps can't depend on apps applied after.
+	before[0].SetMetadata(map[string]any{DEPENDS_ON_KEY: []any{"soh"}})
+
+	if _, _, err := scenarioAppGroups(apps); err == nil {
+		t.Log("expected error for app run before defaults depending on app run after")
+		t.FailNow()
+	}
+}
+
+func TestScenarioAppLevels(t *testing.T) {
+	apps := []ifaces.ScenarioApp{
+		&v2.ScenarioApp{NameF: "ipam", MetadataF: map[string]any{DEPENDS_ON_KEY: []any{}}},
+		&v2.ScenarioApp{NameF: "dns", MetadataF: map[string]any{DEPENDS_ON_KEY: "ipam"}},
+		&v2.ScenarioApp{NameF: "tap", MetadataF: map[string]any{DEPENDS_ON_KEY: []any{}}},
+		&v2.ScenarioApp{NameF: "soh", MetadataF: map[string]any{DEPENDS_ON_KEY: "dns, tap"}},
+	}
+
+	levels, parallel, err := scenarioAppLevels(apps)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !parallel {
+		t.Log("expected apps declaring dependencies to be applied concurrently")
+		t.FailNow()
+	}
+
+	expected := [][]string{{"ipam", "tap"}, {"dns"}, {"soh"}}
+
+	if len(levels) != len(expected) {
+		t.Logf("expected %d levels, got %d", len(expected), len(levels))
+		t.FailNow()
+	}
+
+	for i, level := range levels {
+		if len(level) != len(expected[i]) {
+			t.Logf("expected level %d to be %v, got %d apps", i, expected[i], len(level))
+			t.FailNow()
+		}
+
+		for j, app := range level {
+			if app.Name() != expected[i][j] {
+				t.Logf("expected level %d to be %v, got %s at %d", i, expected[i], app.Name(), j)
+				t.FailNow()
+			}
+		}
+	}
+
+	// Apps that don't declare dependencies depend on the app before them.
+	serial := []ifaces.ScenarioApp{&v2.ScenarioApp{NameF: "ipam"}, &v2.ScenarioApp{NameF: "dns"}}
+
+	if levels, parallel, _ := scenarioAppLevels(serial); parallel || len(levels) != 2 {
+		t.Logf("expected apps without dependencies to be applied serially, got %d levels", len(levels))
+		t.FailNow()
+	}
+
+	apps[0].SetMetadata(map[string]any{DEPENDS_ON_KEY: "soh"})
+
+	if _, _, err := scenarioAppLevels(apps); err == nil {
+		t.Log("expected error for dependency cycle")
+		t.FailNow()
+	}
+
+	apps[0].SetMetadata(map[string]any{DEPENDS_ON_KEY: "missing"})
+
+	if _, _, err := scenarioAppLevels(apps); err == nil {
+		t.Log("expected error for dependency on app not in scenario")
+		t.FailNow()
+	}
+}
+
+func TestApplyAppsConcurrently(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		inflight int
+		max      int
+		done     = make(map[string]bool)
+		order    []string
+	)
+
+	// Each app runs long enough for apps applied concurrently to overlap.
+	run := func(name string, fail bool) func(context.Context, *types.Experiment) error {
+		return func(_ context.Context, exp *types.Experiment) error {
+			mu.Lock()
+			inflight++
+
+			if inflight > max {
+				max = inflight
+			}
+
+			order = append(order, name)
+			mu.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			inflight--
+			done[name] = true
+
+			exp.Status.SetAppStatus(name, map[string]any{"applied": true})
+
+			if fail {
+				return errors.New("app failed")
+			}
+
+			return nil
+		}
+	}
+
+	registerTestApp(t, "ipam", run("ipam", false))
+	registerTestApp(t, "scanner", run("scanner", false))
+	registerTestApp(t, "report", func(ctx context.Context, exp *types.Experiment) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !done["ipam"] || !done["scanner"] {
+			return errors.New("applied before the apps it depends on")
+		}
+
+		order = append(order, "report")
+		return nil
+	})
+
+	scenario := []map[string]any{
+		{"name": "ipam", "metadata": map[string]any{DEPENDS_ON_KEY: []any{}}},
+		{"name": "scanner", "metadata": map[string]any{DEPENDS_ON_KEY: []any{}}},
+		{"name": "report", "metadata": map[string]any{DEPENDS_ON_KEY: []any{"ipam", "scanner"}}},
+	}
+
+	opts := []Option{DryRun(true), SkipApp(builtinDefaultApps...)}
+
+	exp := newTestExperiment(t, scenario...)
+
+	if err := ApplyApps(context.Background(), exp, append(opts, Stage(ACTIONPOSTSTART))...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if max != 2 {
+		t.Logf("expected apps without dependencies on each other to be applied concurrently, got %d at once", max)
+		t.FailNow()
+	}
+
+	if len(order) != 3 || order[2] != "report" {
+		t.Logf("expected report to be applied last, got %v", order)
+		t.FailNow()
+	}
+
+	// Status set by apps on their copy of the experiment is merged back.
+	for _, name := range []string{"ipam", "scanner"} {
+		if _, ok := exp.Status.AppStatus()[name]; !ok {
+			t.Logf("expected status of concurrently applied app %s to be kept", name)
+			t.FailNow()
+		}
+
+		if run := exp.Status.AppRuns()[name][string(ACTIONPOSTSTART)]; run == nil || run.Result() != "success" {
+			t.Logf("expected run of app %s to be recorded as a success, got %v", name, run)
+			t.FailNow()
+		}
+	}
+
+	// Apps are only applied concurrently in the post-start stage.
+	max, order, done = 0, nil, make(map[string]bool)
+
+	if err := ApplyApps(context.Background(), exp, append(opts, Stage(ACTIONPRESTART))...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if max != 1 {
+		t.Logf("expected apps to be applied one at a time in the pre-start stage, got %d at once", max)
+		t.FailNow()
+	}
+
+	// Apps depending on a failed app aren't applied.
+	registerTestApp(t, "scanner", run("scanner", true))
+
+	max, order, done = 0, nil, make(map[string]bool)
+
+	if err := ApplyApps(context.Background(), exp, append(opts, Stage(ACTIONPOSTSTART))...); err == nil {
+		t.Log("expected error for failed app")
+		t.FailNow()
+	}
+
+	if len(order) != 2 || !done["ipam"] {
+		t.Logf("expected apps in the failed app's level to finish and later apps not to be applied, got %v", order)
+		t.FailNow()
+	}
+}
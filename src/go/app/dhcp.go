@@ -0,0 +1,437 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm/mmcli"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"inet.af/netaddr"
+)
+
+func init() {
+	RegisterUserApp("dhcp", func() App { return new(DHCP) })
+}
+
+// DefaultDHCPLeaseTime is the lease time used for networks served by the dhcp
+// app unless overridden in the host metadata.
+const DefaultDHCPLeaseTime = 12 * time.Hour
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: dhcp
+      hosts:
+      - hostname: dhcp-server
+        metadata:
+          server: kea # default is dnsmasq
+          networks:
+          - vlan: EXP_1
+            start: 10.0.1.100
+            end: 10.0.1.200
+            gateway: 10.0.1.254
+            dnsServers:
+            - 10.0.1.53
+            domain: exp.local
+            leaseTime: 1h
+      - hostname: rtr # minirouter
+        metadata:
+          networks:
+          - vlan: EXP_2
+            start: 10.0.2.100
+            end: 10.0.2.200
+*/
+
+type DHCPAppHostMetadata struct {
+	Server   string           `mapstructure:"server"`
+	Networks []DHCPAppNetwork `mapstructure:"networks"`
+}
+
+type DHCPAppNetwork struct {
+	VLAN       string   `mapstructure:"vlan"`
+	Start      string   `mapstructure:"start"`
+	End        string   `mapstructure:"end"`
+	Gateway    string   `mapstructure:"gateway"`
+	DNSServers []string `mapstructure:"dnsServers"`
+	Domain     string   `mapstructure:"domain"`
+	LeaseTime  string   `mapstructure:"leaseTime"`
+}
+
+type dhcpServer struct {
+	Node     ifaces.NodeSpec
+	Server   string
+	Networks []dhcpNetwork
+}
+
+type dhcpNetwork struct {
+	VLAN         string
+	Address      string
+	Subnet       string
+	Network      string
+	Netmask      string
+	Start        string
+	End          string
+	Gateway      string
+	DNSServers   []string
+	Domain       string
+	LeaseTime    int
+	Reservations []dhcpReservation
+}
+
+type dhcpReservation struct {
+	Hostname string
+	MAC      string
+	Address  string
+}
+
+// DHCP provisions DHCP servers on the topology nodes listed as hosts of the app
+// in an experiment's scenario. Each host serves the networks (VLANs) listed in
+// its metadata from its own interface on each VLAN, using either dnsmasq
+// (default) or Kea on Linux nodes, or the built-in DHCP server on minirouter
+// routers. Topology nodes with a DHCP interface on a served VLAN that also
+// have an address configured get a static reservation for that address (a
+// stable MAC address is generated for the interface if one isn't provided).
+type DHCP struct{}
+
+func (DHCP) Init(...Option) error {
+	return nil
+}
+
+func (DHCP) Name() string {
+	return "dhcp"
+}
+
+// Validate checks that each DHCP server and the networks it serves are valid.
+func (this DHCP) Validate(exp *types.Experiment) error {
+	_, err := this.servers(exp)
+	return err
+}
+
+func (DHCP) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this DHCP) PreStart(ctx context.Context, exp *types.Experiment) error {
+	servers, err := this.servers(exp)
+	if err != nil {
+		return err
+	}
+
+	dhcpDir := exp.Spec.BaseDir() + "/dhcp"
+
+	for _, server := range servers {
+		host := server.Node.General().Hostname()
+
+		switch server.Server {
+		case "dnsmasq":
+			cfg := fmt.Sprintf("%s/%s_dnsmasq.conf", dhcpDir, host)
+
+			if err := tmpl.CreateFileFromTemplate("dhcp_dnsmasq.tmpl", server, cfg); err != nil {
+				return fmt.Errorf("generating DHCP server config for host %s: %w", host, err)
+			}
+
+			server.Node.AddInject(cfg, "/etc/dnsmasq.d/phenix-dhcp.conf", "", "")
+		case "kea":
+			cfg := fmt.Sprintf("%s/%s_kea-dhcp4.conf", dhcpDir, host)
+
+			if err := tmpl.CreateFileFromTemplate("dhcp_kea.tmpl", server, cfg); err != nil {
+				return fmt.Errorf("generating DHCP server config for host %s: %w", host, err)
+			}
+
+			server.Node.AddInject(cfg, "/etc/kea/kea-dhcp4.conf", "", "")
+		}
+	}
+
+	return nil
+}
+
+// PostStart configures the DHCP server on minirouter routers, which is done
+// via minimega once the router is running instead of via an injected config.
+// This happens after the vrouter app has configured and committed the router.
+func (this DHCP) PostStart(ctx context.Context, exp *types.Experiment) error {
+	// Dry runs don't launch any VMs, so there are no routers to configure.
+	if exp.DryRun() {
+		return nil
+	}
+
+	servers, err := this.servers(exp)
+	if err != nil {
+		return err
+	}
+
+	for _, server := range servers {
+		if server.Server != "minirouter" {
+			continue
+		}
+
+		var (
+			host = server.Node.General().Hostname()
+			cmds []string
+		)
+
+		for _, n := range server.Networks {
+			if n.Start != "" {
+				cmds = append(cmds, fmt.Sprintf("router %s dhcp %s range %s %s", host, n.Address, n.Start, n.End))
+			}
+
+			if n.Gateway != "" {
+				cmds = append(cmds, fmt.Sprintf("router %s dhcp %s router %s", host, n.Address, n.Gateway))
+			}
+
+			for _, ns := range n.DNSServers {
+				cmds = append(cmds, fmt.Sprintf("router %s dhcp %s dns %s", host, n.Address, ns))
+			}
+
+			for _, r := range n.Reservations {
+				cmds = append(cmds, fmt.Sprintf("router %s dhcp %s static %s %s", host, n.Address, r.MAC, r.Address))
+			}
+		}
+
+		cmds = append(cmds, fmt.Sprintf("router %s commit", host))
+
+		cmd := mmcli.NewNamespacedCommand(exp.Metadata.Name)
+
+		for _, c := range cmds {
+			cmd.Command = c
+
+			if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+				return fmt.Errorf("configuring DHCP server for router %s: %w", host, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (DHCP) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (DHCP) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// servers returns the DHCP servers configured for the given experiment,
+// generating MAC addresses for reserved interfaces that don't have one.
+func (this DHCP) servers(exp *types.Experiment) ([]dhcpServer, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil, nil
+	}
+
+	var (
+		servers []dhcpServer
+		served  = make(map[string]string)
+		errs    error
+	)
+
+	for _, host := range app.Hosts() {
+		node := exp.Spec.Topology().FindNodeByName(host.Hostname())
+		if node == nil {
+			errs = multierror.Append(errs, fmt.Errorf("DHCP server %s not in topology", host.Hostname()))
+			continue
+		}
+
+		if node.External() {
+			errs = multierror.Append(errs, fmt.Errorf("DHCP server %s cannot be an external node", host.Hostname()))
+			continue
+		}
+
+		var md DHCPAppHostMetadata
+
+		if err := mapstructure.Decode(host.Metadata(), &md); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("decoding DHCP metadata for host %s: %w", host.Hostname(), err))
+			continue
+		}
+
+		server := dhcpServer{Node: node, Server: strings.ToLower(md.Server)}
+
+		var (
+			osType = strings.ToLower(node.Hardware().OSType())
+			router = strings.EqualFold(node.Type(), "router")
+		)
+
+		switch {
+		case router && osType == "minirouter":
+			if server.Server != "" && server.Server != "minirouter" {
+				errs = multierror.Append(errs, fmt.Errorf("DHCP server type %s not supported on minirouter %s", md.Server, host.Hostname()))
+				continue
+			}
+
+			server.Server = "minirouter"
+		case router:
+			errs = multierror.Append(errs, fmt.Errorf("DHCP server on router %s requires the minirouter OS type (got %s)", host.Hostname(), osType))
+			continue
+		case osType == "windows" || IsNetOS(osType):
+			errs = multierror.Append(errs, fmt.Errorf("DHCP server %s must be a Linux node (got OS type %s)", host.Hostname(), osType))
+			continue
+		default:
+			switch server.Server {
+			case "":
+				server.Server = "dnsmasq"
+			case "dnsmasq", "kea":
+			default:
+				errs = multierror.Append(errs, fmt.Errorf("unknown DHCP server type %s provided for host %s", md.Server, host.Hostname()))
+				continue
+			}
+		}
+
+		if len(md.Networks) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("no networks provided for DHCP server %s", host.Hostname()))
+			continue
+		}
+
+		for _, n := range md.Networks {
+			if other, ok := served[strings.ToLower(n.VLAN)]; ok {
+				errs = multierror.Append(errs, fmt.Errorf("VLAN %s served by both DHCP servers %s and %s", n.VLAN, other, host.Hostname()))
+				continue
+			}
+
+			served[strings.ToLower(n.VLAN)] = host.Hostname()
+
+			network, err := dhcpNetworkFor(exp, node, server.Server == "minirouter", n)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("DHCP server %s: %w", host.Hostname(), err))
+				continue
+			}
+
+			server.Networks = append(server.Networks, network)
+		}
+
+		servers = append(servers, server)
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return servers, nil
+}
+
+// dhcpNetworkFor returns the given network served by the given server node.
+// Routers default to serving their own address as the gateway.
+func dhcpNetworkFor(exp *types.Experiment, server ifaces.NodeSpec, router bool, md DHCPAppNetwork) (dhcpNetwork, error) {
+	var serverIface ifaces.NodeNetworkInterface
+
+	for _, iface := range server.Network().Interfaces() {
+		if strings.EqualFold(iface.VLAN(), md.VLAN) {
+			serverIface = iface
+			break
+		}
+	}
+
+	if serverIface == nil {
+		return dhcpNetwork{}, fmt.Errorf("no interface on VLAN %s", md.VLAN)
+	}
+
+	if serverIface.Address() == "" {
+		return dhcpNetwork{}, fmt.Errorf("no static IP address on VLAN %s", md.VLAN)
+	}
+
+	addr, err := netaddr.ParseIPPrefix(fmt.Sprintf("%s/%d", serverIface.Address(), serverIface.Mask()))
+	if err != nil {
+		return dhcpNetwork{}, fmt.Errorf("parsing address on VLAN %s: %w", md.VLAN, err)
+	}
+
+	subnet := addr.Masked()
+
+	network := dhcpNetwork{
+		VLAN:       md.VLAN,
+		Address:    addr.IP().String(),
+		Subnet:     subnet.String(),
+		Network:    subnet.IP().String(),
+		Netmask:    net.IP(net.CIDRMask(int(subnet.Bits()), 32)).String(),
+		Gateway:    md.Gateway,
+		DNSServers: md.DNSServers,
+		Domain:     md.Domain,
+	}
+
+	if (md.Start == "") != (md.End == "") {
+		return dhcpNetwork{}, fmt.Errorf("both start and end addresses must be provided for VLAN %s", md.VLAN)
+	}
+
+	if md.Start != "" {
+		start, err := netaddr.ParseIP(md.Start)
+		if err != nil || !subnet.Contains(start) {
+			return dhcpNetwork{}, fmt.Errorf("start address %s not in subnet %s of VLAN %s", md.Start, subnet, md.VLAN)
+		}
+
+		end, err := netaddr.ParseIP(md.End)
+		if err != nil || !subnet.Contains(end) {
+			return dhcpNetwork{}, fmt.Errorf("end address %s not in subnet %s of VLAN %s", md.End, subnet, md.VLAN)
+		}
+
+		if end.Less(start) {
+			return dhcpNetwork{}, fmt.Errorf("end address %s before start address %s for VLAN %s", md.End, md.Start, md.VLAN)
+		}
+
+		network.Start = start.String()
+		network.End = end.String()
+	}
+
+	for _, addr := range append([]string{md.Gateway}, md.DNSServers...) {
+		if addr == "" {
+			continue
+		}
+
+		if _, err := netaddr.ParseIP(addr); err != nil {
+			return dhcpNetwork{}, fmt.Errorf("invalid address %s for VLAN %s", addr, md.VLAN)
+		}
+	}
+
+	if network.Gateway == "" && router {
+		network.Gateway = network.Address
+	}
+
+	lease := DefaultDHCPLeaseTime
+
+	if md.LeaseTime != "" {
+		if lease, err = time.ParseDuration(md.LeaseTime); err != nil || lease < time.Minute {
+			return dhcpNetwork{}, fmt.Errorf("invalid lease time %s for VLAN %s (must be at least 1m)", md.LeaseTime, md.VLAN)
+		}
+	}
+
+	network.LeaseTime = int(lease.Seconds())
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node == server || node.External() {
+			continue
+		}
+
+		host := node.General().Hostname()
+
+		for idx, iface := range node.Network().Interfaces() {
+			if !strings.EqualFold(iface.VLAN(), md.VLAN) || !strings.EqualFold(iface.Proto(), "dhcp") || iface.Address() == "" {
+				continue
+			}
+
+			ip, err := netaddr.ParseIP(iface.Address())
+			if err != nil || !subnet.Contains(ip) {
+				return dhcpNetwork{}, fmt.Errorf("reserved address %s for node %s not in subnet %s of VLAN %s", iface.Address(), host, subnet, md.VLAN)
+			}
+
+			// A MAC address is required for a reservation, so generate a stable
+			// one if not provided.
+			if iface.MAC() == "" {
+				iface.SetMAC(stableMAC(exp.Spec.ExperimentName(), host, idx))
+			}
+
+			network.Reservations = append(network.Reservations, dhcpReservation{
+				Hostname: host,
+				MAC:      strings.ToLower(iface.MAC()),
+				Address:  ip.String(),
+			})
+		}
+	}
+
+	return network, nil
+}
@@ -0,0 +1,307 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestDHCPApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "dhcp-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "dnsmasq",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.1", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "kea",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_2", ProtoF: "static", AddressF: "10.0.2.1", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "plc",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "dhcp", AddressF: "10.0.1.10", MaskF: 24},
+					{NameF: "eth1", VLANF: "EXP_2", ProtoF: "dhcp", AddressF: "10.0.2.10", MaskF: 24, MACF: "00:11:22:33:44:55"},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "hmi",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "dhcp"},
+				},
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "dhcp",
+				HostsF: []*v2.ScenarioAppHost{
+					{
+						HostnameF: "dnsmasq",
+						MetadataF: map[string]any{
+							"networks": []any{
+								map[string]any{
+									"vlan":       "EXP_1",
+									"start":      "10.0.1.100",
+									"end":        "10.0.1.200",
+									"gateway":    "10.0.1.254",
+									"dnsServers": []any{"10.0.1.53", "10.0.1.54"},
+									"leaseTime":  "1h",
+								},
+							},
+						},
+					},
+					{
+						HostnameF: "kea",
+						MetadataF: map[string]any{
+							"server": "kea",
+							"networks": []any{
+								map[string]any{
+									"vlan":       "EXP_2",
+									"dnsServers": []any{"10.0.2.53"},
+									"domain":     "exp.local",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("dhcp").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	mac := nodes[2].NetworkF.InterfacesF[0].MACF
+
+	if mac != stableMAC("test", "plc", 0) {
+		t.Logf("expected generated MAC for reserved interface, got '%s'", mac)
+		t.FailNow()
+	}
+
+	if nodes[3].NetworkF.InterfacesF[0].MACF != "" {
+		t.Log("expected no MAC generated for dynamic interface")
+		t.FailNow()
+	}
+
+	for i, dst := range map[int]string{0: "/etc/dnsmasq.d/phenix-dhcp.conf", 1: "/etc/kea/kea-dhcp4.conf"} {
+		injects := nodes[i].Injections()
+
+		if len(injects) != 1 || injects[0].Dst() != dst {
+			t.Logf("expected single injection to %s for host %s, got %v", dst, nodes[i].General().Hostname(), injects)
+			t.FailNow()
+		}
+	}
+
+	conf, err := os.ReadFile(baseDir + "/dhcp/dnsmasq_dnsmasq.conf")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"dhcp-range=set:net0,10.0.1.100,10.0.1.200,255.255.255.0,3600",
+		"dhcp-option=tag:net0,option:router,10.0.1.254",
+		"dhcp-option=tag:net0,option:dns-server,10.0.1.53,10.0.1.54",
+		fmt.Sprintf("dhcp-host=%s,10.0.1.10,plc", mac),
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(conf), e) {
+			t.Logf("expected dnsmasq config to contain %q, got: %s", e, conf)
+			t.FailNow()
+		}
+	}
+
+	body, err := os.ReadFile(baseDir + "/dhcp/kea_kea-dhcp4.conf")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var kea struct {
+		Dhcp4 struct {
+			Subnet4 []struct {
+				Subnet        string           `json:"subnet"`
+				ValidLifetime int              `json:"valid-lifetime"`
+				Pools         []map[string]any `json:"pools"`
+				OptionData    []map[string]any `json:"option-data"`
+				Reservations  []map[string]any `json:"reservations"`
+			} `json:"subnet4"`
+		} `json:"Dhcp4"`
+	}
+
+	if err := json.Unmarshal(body, &kea); err != nil {
+		t.Logf("invalid Kea config: %v\n%s", err, body)
+		t.FailNow()
+	}
+
+	if len(kea.Dhcp4.Subnet4) != 1 {
+		t.Logf("expected 1 Kea subnet, got %d", len(kea.Dhcp4.Subnet4))
+		t.FailNow()
+	}
+
+	subnet := kea.Dhcp4.Subnet4[0]
+
+	if subnet.Subnet != "10.0.2.0/24" || subnet.ValidLifetime != 43200 || len(subnet.Pools) != 0 || len(subnet.OptionData) != 2 {
+		t.Logf("unexpected Kea subnet: %+v", subnet)
+		t.FailNow()
+	}
+
+	if len(subnet.Reservations) != 1 || subnet.Reservations[0]["hw-address"] != "00:11:22:33:44:55" || subnet.Reservations[0]["ip-address"] != "10.0.2.10" {
+		t.Logf("unexpected Kea reservations: %v", subnet.Reservations)
+		t.FailNow()
+	}
+}
+
+func TestDHCPAppValidate(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "Router",
+			GeneralF: &v1.General{
+				HostnameF: "vyos",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "vyos",
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "server",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.1", MaskF: 24},
+				},
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "dhcp",
+				HostsF: []*v2.ScenarioAppHost{
+					{
+						HostnameF: "vyos",
+						MetadataF: map[string]any{
+							"networks": []any{map[string]any{"vlan": "EXP_1"}},
+						},
+					},
+					{
+						HostnameF: "server",
+						MetadataF: map[string]any{
+							"server":   "isc",
+							"networks": []any{map[string]any{"vlan": "EXP_1"}},
+						},
+					},
+					{
+						HostnameF: "missing",
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		TopologyF: &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	err := DHCP{}.Validate(exp)
+	if err == nil {
+		t.Log("expected DHCP validation to fail")
+		t.FailNow()
+	}
+
+	// unsupported router OS, unknown server type, missing server node
+	if errs := err.(*multierror.Error).Errors; len(errs) != 3 {
+		t.Logf("expected 3 validation errors, got %d: %v", len(errs), err)
+		t.FailNow()
+	}
+
+	// range outside of the served subnet
+	scenario.AppsF[0].HostsF = []*v2.ScenarioAppHost{
+		{
+			HostnameF: "server",
+			MetadataF: map[string]any{
+				"networks": []any{map[string]any{"vlan": "EXP_1", "start": "10.0.2.100", "end": "10.0.2.200"}},
+			},
+		},
+	}
+
+	if err := (DHCP{}).Validate(exp); err == nil || !strings.Contains(err.Error(), "not in subnet 10.0.1.0/24") {
+		t.Logf("expected range validation error, got %v", err)
+		t.FailNow()
+	}
+}
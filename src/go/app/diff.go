@@ -0,0 +1,284 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"phenix/types"
+)
+
+// AppDiff is a structured diff of the changes an app made to an experiment
+// while being applied for a stage in dry-run mode. Changes to nodes that were
+// added by the app are not listed separately.
+type AppDiff struct {
+	App   string `json:"app"`
+	Stage Action `json:"stage"`
+
+	NodesAdded   []string `json:"nodesAdded,omitempty"`
+	NodesRemoved []string `json:"nodesRemoved,omitempty"`
+
+	InjectionsAdded   []InjectionDiff `json:"injectionsAdded,omitempty"`
+	InjectionsRemoved []InjectionDiff `json:"injectionsRemoved,omitempty"`
+
+	// Any other changes to the experiment spec (node settings, scenario app
+	// metadata, etc.) and experiment annotations.
+	Changes []SpecChange `json:"changes,omitempty"`
+
+	// Set if the app failed, in which case the diff only includes the changes
+	// made before it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Empty returns true if the app didn't change anything.
+func (this AppDiff) Empty() bool {
+	return len(this.NodesAdded) == 0 && len(this.NodesRemoved) == 0 &&
+		len(this.InjectionsAdded) == 0 && len(this.InjectionsRemoved) == 0 &&
+		len(this.Changes) == 0
+}
+
+// InjectionDiff is a file injection added to (or removed from) a node.
+type InjectionDiff struct {
+	Node string `json:"node"`
+	Src  string `json:"src"`
+	Dst  string `json:"dst"`
+}
+
+// SpecChange is a single value in the experiment spec that was added, removed,
+// or changed. Paths are dot-separated, with list items identified by name (or
+// hostname) when they have one and by index otherwise, ie.
+// `nodes[host-00].network.interfaces[0].address`.
+type SpecChange struct {
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// DiffHandler is called with the diff of the changes each app made to an
+// experiment when apps are applied in dry-run mode.
+type DiffHandler func(AppDiff)
+
+// specSnapshot is a flattened copy of an experiment spec used to diff the
+// changes an app makes to it.
+type specSnapshot struct {
+	nodes      []string
+	values     map[string]any
+	injections map[string][]InjectionDiff
+}
+
+// snapshotSpec returns a flattened copy of the given experiment's spec and
+// annotations.
+func snapshotSpec(exp *types.Experiment) (specSnapshot, error) {
+	snap := specSnapshot{
+		values:     make(map[string]any),
+		injections: make(map[string][]InjectionDiff),
+	}
+
+	flatten := func(prefix string, v any) error {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		var generic any
+
+		if err := json.Unmarshal(body, &generic); err != nil {
+			return err
+		}
+
+		flattenValue(prefix, generic, snap.values)
+		return nil
+	}
+
+	if err := flatten("annotations", exp.Metadata.Annotations); err != nil {
+		return snap, fmt.Errorf("flattening experiment annotations: %w", err)
+	}
+
+	if exp.Spec.Topology() != nil {
+		for _, node := range exp.Spec.Topology().Nodes() {
+			name := node.General().Hostname()
+			snap.nodes = append(snap.nodes, name)
+
+			for _, inject := range node.Injections() {
+				snap.injections[name] = append(snap.injections[name], InjectionDiff{Node: name, Src: inject.Src(), Dst: inject.Dst()})
+			}
+
+			if err := flatten(fmt.Sprintf("nodes[%s]", name), node); err != nil {
+				return snap, fmt.Errorf("flattening node %s: %w", name, err)
+			}
+		}
+	}
+
+	// Injections are diffed separately from the rest of each node.
+	for path := range snap.values {
+		if injectionPath(path) {
+			delete(snap.values, path)
+		}
+	}
+
+	var spec map[string]any
+
+	body, err := json.Marshal(exp.Spec)
+	if err != nil {
+		return snap, fmt.Errorf("flattening experiment spec: %w", err)
+	}
+
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return snap, fmt.Errorf("flattening experiment spec: %w", err)
+	}
+
+	// Nodes were already flattened above, keyed by hostname.
+	if topo, ok := spec["topology"].(map[string]any); ok {
+		delete(topo, "nodes")
+	}
+
+	flattenValue("", spec, snap.values)
+
+	return snap, nil
+}
+
+// diffSpec returns the changes between the given snapshots for the given app.
+func diffSpec(name string, stage Action, before, after specSnapshot) AppDiff {
+	diff := AppDiff{App: name, Stage: stage}
+
+	var (
+		existed = make(map[string]bool)
+		exists  = make(map[string]bool)
+	)
+
+	for _, n := range before.nodes {
+		existed[n] = true
+	}
+
+	for _, n := range after.nodes {
+		exists[n] = true
+
+		if !existed[n] {
+			diff.NodesAdded = append(diff.NodesAdded, n)
+		}
+	}
+
+	for _, n := range before.nodes {
+		if !exists[n] {
+			diff.NodesRemoved = append(diff.NodesRemoved, n)
+		}
+	}
+
+	for _, n := range after.nodes {
+		if existed[n] {
+			diff.InjectionsAdded = append(diff.InjectionsAdded, missingInjections(after.injections[n], before.injections[n])...)
+		}
+	}
+
+	for _, n := range before.nodes {
+		if exists[n] {
+			diff.InjectionsRemoved = append(diff.InjectionsRemoved, missingInjections(before.injections[n], after.injections[n])...)
+		}
+	}
+
+	for path, old := range before.values {
+		if node := nodeOfPath(path); node != "" && !exists[node] {
+			continue
+		}
+
+		if new, ok := after.values[path]; !ok {
+			diff.Changes = append(diff.Changes, SpecChange{Path: path, Old: old})
+		} else if !reflect.DeepEqual(old, new) {
+			diff.Changes = append(diff.Changes, SpecChange{Path: path, Old: old, New: new})
+		}
+	}
+
+	for path, new := range after.values {
+		if node := nodeOfPath(path); node != "" && !existed[node] {
+			continue
+		}
+
+		if _, ok := before.values[path]; !ok {
+			diff.Changes = append(diff.Changes, SpecChange{Path: path, New: new})
+		}
+	}
+
+	sort.Slice(diff.Changes, func(i, j int) bool { return diff.Changes[i].Path < diff.Changes[j].Path })
+
+	return diff
+}
+
+// flattenValue flattens the given generic JSON value into the given map of
+// paths to leaf values. Empty maps and lists are treated as missing.
+func flattenValue(path string, v any, values map[string]any) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+
+		return path + "." + key
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			flattenValue(join(key), value, values)
+		}
+	case []any:
+		for i, value := range v {
+			key := fmt.Sprintf("%s[%d]", path, i)
+
+			if m, ok := value.(map[string]any); ok {
+				for _, field := range []string{"name", "hostname"} {
+					if name, ok := m[field].(string); ok && name != "" {
+						key = fmt.Sprintf("%s[%s]", path, name)
+						break
+					}
+				}
+			}
+
+			flattenValue(key, value, values)
+		}
+	case nil:
+	default:
+		values[path] = v
+	}
+}
+
+// nodeOfPath returns the hostname of the node the given flattened path belongs
+// to, if any.
+func nodeOfPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "nodes[")
+	if !ok {
+		return ""
+	}
+
+	node, _, _ := strings.Cut(rest, "]")
+	return node
+}
+
+// injectionPath returns true if the given flattened path is for a node
+// injection.
+func injectionPath(path string) bool {
+	node := nodeOfPath(path)
+	return node != "" && strings.HasPrefix(path, "nodes["+node+"].injections[")
+}
+
+// missingInjections returns the injections in a that aren't in b.
+func missingInjections(a, b []InjectionDiff) []InjectionDiff {
+	var missing []InjectionDiff
+
+	for _, i := range a {
+		found := false
+
+		for _, j := range b {
+			if i == j {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, i)
+		}
+	}
+
+	return missing
+}
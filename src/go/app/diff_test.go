@@ -0,0 +1,68 @@
+package app
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+)
+
+func TestDiffSpec(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		TopologyF: &v1.TopologySpec{
+			NodesF: []*v1.Node{
+				{
+					TypeF:     "VirtualMachine",
+					GeneralF:  &v1.General{HostnameF: "host-00"},
+					HardwareF: &v1.Hardware{VCPUF: 1},
+				},
+			},
+		},
+	}
+
+	exp := &types.Experiment{Metadata: store.ConfigMetadata{Name: "test"}, Spec: spec}
+
+	before, err := snapshotSpec(exp)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	node := spec.TopologyF.NodesF[0]
+	node.HardwareF.VCPUF = 2
+	node.AddInject("/tmp/startup.sh", "/etc/phenix/startup.sh", "", "")
+
+	spec.TopologyF.NodesF = append(spec.TopologyF.NodesF, &v1.Node{
+		TypeF:    "VirtualMachine",
+		GeneralF: &v1.General{HostnameF: "host-01"},
+	})
+
+	after, err := snapshotSpec(exp)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	diff := diffSpec("test", ACTIONCONFIG, before, after)
+
+	if len(diff.NodesAdded) != 1 || diff.NodesAdded[0] != "host-01" {
+		t.Logf("expected host-01 to be added, got %v", diff.NodesAdded)
+		t.FailNow()
+	}
+
+	if len(diff.InjectionsAdded) != 1 || diff.InjectionsAdded[0].Dst != "/etc/phenix/startup.sh" {
+		t.Logf("expected startup injection to be added, got %v", diff.InjectionsAdded)
+		t.FailNow()
+	}
+
+	if len(diff.Changes) != 1 || diff.Changes[0].Path != "nodes[host-00].hardware.vcpus" {
+		t.Logf("expected only host-00 vcpus to change, got %+v", diff.Changes)
+		t.FailNow()
+	}
+
+	if diff := diffSpec("test", ACTIONCONFIG, after, after); !diff.Empty() {
+		t.Logf("expected empty diff, got %+v", diff)
+		t.FailNow()
+	}
+}
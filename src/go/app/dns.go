@@ -0,0 +1,490 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+	"inet.af/netaddr"
+)
+
+func init() {
+	RegisterUserApp("dns", func() App { return new(DNS) })
+}
+
+// DefaultDNSDomain is the domain records are generated in by the dns app if
+// neither the app metadata nor the site settings provide one.
+const DefaultDNSDomain = "phenix.local"
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: dns
+      metadata:
+        server: dns-server
+        interface: eth0 # default is the server's first statically addressed interface
+        domain: exp.local # default is the site domain, or phenix.local
+        forwarders:
+        - 8.8.8.8
+        records:
+          www: 10.0.1.10
+*/
+
+type DNSAppMetadata struct {
+	Server     string            `mapstructure:"server"`
+	Interface  string            `mapstructure:"interface"`
+	Domain     string            `mapstructure:"domain"`
+	Forwarders []string          `mapstructure:"forwarders"`
+	Records    map[string]string `mapstructure:"records"`
+}
+
+type dnsConfig struct {
+	Server     ifaces.NodeSpec
+	Address    string
+	Domain     string
+	Forwarders []string
+	Serial     string
+	Zones      []dnsZone
+}
+
+// dnsZone is a forward or reverse (/24 network) zone served by the DNS server.
+type dnsZone struct {
+	Name    string
+	File    string
+	Records []dnsRecord
+}
+
+type dnsRecord struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// Hostnames (and interface names) are only used in records if they're valid
+// DNS labels once lowercased.
+var dnsLabelRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// DNS deploys a BIND DNS server to the topology node given in the app metadata,
+// serving a zone generated from the hostnames and static interface addresses
+// of the topology nodes (plus any additional records in the app metadata),
+// along with reverse zones for each /24 network the addresses are in. Queries
+// for other domains are forwarded to the forwarders in the app metadata, if
+// any. All Linux and Windows nodes targeted by the app (that don't
+// already have DNS servers configured) are pointed at the DNS server, with the
+// domain as their DNS search domain, via startup injections.
+type DNS struct{}
+
+func (DNS) Init(...Option) error {
+	return nil
+}
+
+func (DNS) Name() string {
+	return "dns"
+}
+
+func (DNS) MetadataSchema() []byte {
+	return []byte(`
+type: object
+required:
+- server
+properties:
+  server:
+    type: string
+  interface:
+    type: string
+  domain:
+    type: string
+  forwarders:
+    type: array
+    items:
+      type: string
+  records:
+    type: object
+    additionalProperties:
+      type: string
+additionalProperties: false
+`)
+}
+
+// Validate checks that the DNS server and the records and forwarders given in
+// the app metadata are valid.
+func (this DNS) Validate(exp *types.Experiment) error {
+	_, err := this.config(exp)
+	return err
+}
+
+func (DNS) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this DNS) PreStart(ctx context.Context, exp *types.Experiment) error {
+	cfg, err := this.config(exp)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	var (
+		dnsDir = exp.Spec.BaseDir() + "/dns"
+		server = cfg.Server
+	)
+
+	files := []struct {
+		tmpl string
+		data any
+		src  string
+		dst  string
+	}{
+		{"dns_named_options.tmpl", cfg, dnsDir + "/named.conf.options", "/etc/bind/named.conf.options"},
+		{"dns_named_local.tmpl", cfg, dnsDir + "/named.conf.local", "/etc/bind/named.conf.local"},
+	}
+
+	for _, zone := range cfg.Zones {
+		data := struct {
+			dnsZone
+			Domain string
+			Serial string
+		}{
+			dnsZone: zone,
+			Domain:  cfg.Domain,
+			Serial:  cfg.Serial,
+		}
+
+		files = append(files, struct {
+			tmpl string
+			data any
+			src  string
+			dst  string
+		}{"dns_zone.tmpl", data, dnsDir + "/" + zone.File, "/etc/bind/zones/" + zone.File})
+	}
+
+	for _, f := range files {
+		if err := tmpl.CreateFileFromTemplate(f.tmpl, f.data, f.src); err != nil {
+			return fmt.Errorf("generating DNS server config %s: %w", f.dst, err)
+		}
+
+		server.AddInject(f.src, f.dst, "", "")
+	}
+
+	resolver := struct {
+		Server string
+		Domain string
+	}{
+		Server: cfg.Address,
+		Domain: cfg.Domain,
+	}
+
+	// Configure topology nodes (including the DNS server itself) to use the DNS
+	// server.
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() || strings.EqualFold(node.Type(), "router") {
+			continue
+		}
+
+		// Respect DNS servers explicitly configured for a node.
+		if hasDNS(node) {
+			continue
+		}
+
+		host := node.General().Hostname()
+
+		switch strings.ToLower(node.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
+			resolv := dnsDir + "/" + host + "-resolv.sh"
+
+			if err := tmpl.CreateFileFromTemplate("dns_resolv_linux.tmpl", resolver, resolv); err != nil {
+				return fmt.Errorf("generating Linux DNS resolver script for host %s: %w", host, err)
+			}
+
+			// Runs after the interfaces are configured (which appends any site DNS
+			// servers to the resolver config) so it takes precedence.
+			node.AddInject(resolv, "/etc/phenix/startup/3_resolv-start.sh", "0755", "")
+		case "windows":
+			resolv := dnsDir + "/" + host + "-resolv.ps1"
+
+			if err := tmpl.CreateFileFromTemplate("dns_resolv_windows.tmpl", resolver, resolv); err != nil {
+				return fmt.Errorf("generating Windows DNS resolver script for host %s: %w", host, err)
+			}
+
+			node.AddInject(resolv, "/phenix/startup/24-dns.ps1", "0755", "")
+		}
+	}
+
+	return nil
+}
+
+func (DNS) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (DNS) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (DNS) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// config returns the DNS server config for the given experiment, or nil if the
+// experiment doesn't include the app.
+func (this DNS) config(exp *types.Experiment) (*dnsConfig, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil, nil
+	}
+
+	var md DNSAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return nil, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	if md.Server == "" {
+		return nil, fmt.Errorf("no DNS server provided in %s app metadata", this.Name())
+	}
+
+	server := exp.Spec.Topology().FindNodeByName(md.Server)
+	if server == nil {
+		return nil, fmt.Errorf("DNS server %s not in topology", md.Server)
+	}
+
+	if server.External() {
+		return nil, fmt.Errorf("DNS server %s cannot be an external node", md.Server)
+	}
+
+	switch strings.ToLower(server.Hardware().OSType()) {
+	case "linux", "rhel", "centos":
+	default:
+		return nil, fmt.Errorf("DNS server %s must be a Linux node (got OS type %s)", md.Server, server.Hardware().OSType())
+	}
+
+	cfg := &dnsConfig{
+		Server:     server,
+		Domain:     strings.ToLower(strings.TrimSuffix(md.Domain, ".")),
+		Forwarders: md.Forwarders,
+		Serial:     time.Now().UTC().Format("2006010215"),
+	}
+
+	for _, iface := range server.Network().Interfaces() {
+		if iface.Address() == "" || strings.EqualFold(iface.Proto(), "dhcp") {
+			continue
+		}
+
+		if md.Interface == "" || strings.EqualFold(iface.Name(), md.Interface) {
+			cfg.Address = iface.Address()
+			break
+		}
+	}
+
+	if cfg.Address == "" {
+		if md.Interface != "" {
+			return nil, fmt.Errorf("no static IP address on interface %s of DNS server %s", md.Interface, md.Server)
+		}
+
+		return nil, fmt.Errorf("no static IP address on DNS server %s", md.Server)
+	}
+
+	if cfg.Domain == "" {
+		site, err := types.SiteSettings()
+		if err != nil {
+			return nil, fmt.Errorf("getting site settings: %w", err)
+		}
+
+		cfg.Domain = strings.ToLower(site.Domain)
+	}
+
+	if cfg.Domain == "" {
+		cfg.Domain = DefaultDNSDomain
+	}
+
+	for _, label := range strings.Split(cfg.Domain, ".") {
+		if !dnsLabelRegex.MatchString(label) {
+			return nil, fmt.Errorf("invalid DNS domain %s", cfg.Domain)
+		}
+	}
+
+	var errs error
+
+	for _, fwd := range cfg.Forwarders {
+		if _, err := netaddr.ParseIP(fwd); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid DNS forwarder %s", fwd))
+		}
+	}
+
+	// Forward records keyed by name, so records in the app metadata can override
+	// those generated from the topology.
+	records := make(map[string]netaddr.IP)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || node.Network() == nil {
+			continue
+		}
+
+		host := strings.ToLower(node.General().Hostname())
+
+		if !dnsLabelRegex.MatchString(host) {
+			plog.Warn("skipping DNS records for node with invalid DNS hostname", "node", node.General().Hostname())
+			continue
+		}
+
+		var addrs []ifaces.NodeNetworkInterface
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Address() != "" && !strings.EqualFold(iface.Proto(), "dhcp") {
+				addrs = append(addrs, iface)
+			}
+		}
+
+		for i, iface := range addrs {
+			ip, err := netaddr.ParseIP(iface.Address())
+			if err != nil {
+				continue
+			}
+
+			// The hostname resolves to the node's first address, and each address
+			// resolves from `<hostname>-<interface>` when a node has more than one.
+			if i == 0 {
+				records[host] = ip
+			}
+
+			if len(addrs) > 1 {
+				if name := host + "-" + strings.ToLower(iface.Name()); dnsLabelRegex.MatchString(name) {
+					records[name] = ip
+				}
+			}
+		}
+	}
+
+	for name, addr := range md.Records {
+		name = strings.ToLower(strings.TrimSuffix(name, "."+cfg.Domain))
+
+		ip, err := netaddr.ParseIP(addr)
+		if err != nil || !ip.Is4() {
+			errs = multierror.Append(errs, fmt.Errorf("invalid address %s for DNS record %s", addr, name))
+			continue
+		}
+
+		for _, label := range strings.Split(name, ".") {
+			if !dnsLabelRegex.MatchString(label) {
+				errs = multierror.Append(errs, fmt.Errorf("invalid name for DNS record %s", name))
+				break
+			}
+		}
+
+		records[name] = ip
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	cfg.Zones = dnsZones(cfg.Domain, cfg.Address, records)
+
+	return cfg, nil
+}
+
+// dnsZones returns the forward zone for the given domain and the reverse zones
+// for the given A records. Hostname records are preferred over interface
+// records as the target of PTR records for addresses that have both. The name
+// server record for the zones resolves to the given server address.
+func dnsZones(domain, server string, records map[string]netaddr.IP) []dnsZone {
+	var (
+		names   = make([]string, 0, len(records))
+		forward = dnsZone{Name: domain, File: "db." + domain}
+		reverse = make(map[string]*dnsZone)
+		ptrs    = make(map[netaddr.IP]string)
+	)
+
+	for name := range records {
+		names = append(names, name)
+	}
+
+	// Sorting by length first means hostnames come before interface names
+	// derived from them.
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) == len(names[j]) {
+			return names[i] < names[j]
+		}
+
+		return len(names[i]) < len(names[j])
+	})
+
+	for _, name := range names {
+		ip := records[name]
+
+		forward.Records = append(forward.Records, dnsRecord{Name: name, Type: "A", Value: ip.String()})
+
+		if _, ok := ptrs[ip]; ok || !ip.Is4() {
+			continue
+		}
+
+		ptrs[ip] = name
+
+		var (
+			octets = ip.As4()
+			zone   = fmt.Sprintf("%d.%d.%d.in-addr.arpa", octets[2], octets[1], octets[0])
+		)
+
+		if _, ok := reverse[zone]; !ok {
+			reverse[zone] = &dnsZone{Name: zone, File: "db." + zone}
+		}
+
+		reverse[zone].Records = append(reverse[zone].Records, dnsRecord{
+			Name:  fmt.Sprintf("%d", octets[3]),
+			Type:  "PTR",
+			Value: name + "." + domain + ".",
+		})
+	}
+
+	if _, ok := records["ns"]; !ok {
+		forward.Records = append(forward.Records, dnsRecord{Name: "ns", Type: "A", Value: server})
+	}
+
+	sort.Slice(forward.Records, func(i, j int) bool { return forward.Records[i].Name < forward.Records[j].Name })
+
+	var (
+		zones   = []dnsZone{forward}
+		revKeys = make([]string, 0, len(reverse))
+	)
+
+	for name := range reverse {
+		revKeys = append(revKeys, name)
+	}
+
+	sort.Strings(revKeys)
+
+	for _, name := range revKeys {
+		zone := *reverse[name]
+
+		sort.Slice(zone.Records, func(i, j int) bool { return zone.Records[i].Name < zone.Records[j].Name })
+
+		zones = append(zones, zone)
+	}
+
+	return zones
+}
+
+// hasDNS returns true if DNS servers are configured for any of the given node's
+// interfaces.
+func hasDNS(node ifaces.NodeSpec) bool {
+	if node.Network() == nil {
+		return false
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		if len(iface.DNS()) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestDNSApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "dns-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "dns",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.53", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "HMI",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "windows",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.10", MaskF: 24},
+					{NameF: "eth1", VLANF: "EXP_2", ProtoF: "static", AddressF: "10.0.2.10", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "plc",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_2", ProtoF: "static", AddressF: "10.0.2.20", MaskF: 24, DNSF: []string{"10.0.2.1"}},
+				},
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "dns",
+				MetadataF: map[string]any{
+					"server":     "dns",
+					"domain":     "exp.local",
+					"forwarders": []any{"8.8.8.8"},
+					"records":    map[string]any{"www": "10.0.1.80"},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("dns").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[int][]string{
+		0: {
+			"/etc/bind/named.conf.options",
+			"/etc/bind/named.conf.local",
+			"/etc/bind/zones/db.exp.local",
+			"/etc/bind/zones/db.1.0.10.in-addr.arpa",
+			"/etc/bind/zones/db.2.0.10.in-addr.arpa",
+			"/etc/phenix/startup/3_resolv-start.sh",
+		},
+		1: {"/phenix/startup/24-dns.ps1"},
+		2: nil, // DNS explicitly configured
+	}
+
+	for i, dsts := range expected {
+		injects := nodes[i].Injections()
+
+		if len(injects) != len(dsts) {
+			t.Logf("expected %d injections for host %s, got %d", len(dsts), nodes[i].General().Hostname(), len(injects))
+			t.FailNow()
+		}
+
+		for j, dst := range dsts {
+			if injects[j].Dst() != dst {
+				t.Logf("expected injection to %s for host %s, got %s", dst, nodes[i].General().Hostname(), injects[j].Dst())
+				t.FailNow()
+			}
+		}
+	}
+
+	zone, err := os.ReadFile(baseDir + "/dns/db.exp.local")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, e := range []string{
+		"dns IN A 10.0.1.53",
+		"hmi IN A 10.0.1.10",
+		"hmi-eth0 IN A 10.0.1.10",
+		"hmi-eth1 IN A 10.0.2.10",
+		"ns IN A 10.0.1.53",
+		"plc IN A 10.0.2.20",
+		"www IN A 10.0.1.80",
+	} {
+		if !strings.Contains(string(zone), e) {
+			t.Logf("expected zone to contain %q, got: %s", e, zone)
+			t.FailNow()
+		}
+	}
+
+	reverse, err := os.ReadFile(baseDir + "/dns/db.1.0.10.in-addr.arpa")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, e := range []string{"10 IN PTR hmi.exp.local.", "53 IN PTR dns.exp.local."} {
+		if !strings.Contains(string(reverse), e) {
+			t.Logf("expected reverse zone to contain %q, got: %s", e, reverse)
+			t.FailNow()
+		}
+	}
+
+	options, err := os.ReadFile(baseDir + "/dns/named.conf.options")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !strings.Contains(string(options), "8.8.8.8;") {
+		t.Logf("expected forwarder in named options, got: %s", options)
+		t.FailNow()
+	}
+
+	resolv, err := os.ReadFile(baseDir + "/dns/HMI-resolv.ps1")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !strings.Contains(string(resolv), "-ServerAddresses ('10.0.1.53')") || !strings.Contains(string(resolv), "@('exp.local')") {
+		t.Logf("unexpected Windows resolver script: %s", resolv)
+		t.FailNow()
+	}
+}
+
+func TestDNSAppValidate(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "dns",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "dhcp"},
+				},
+			},
+		},
+	}
+
+	cases := map[string]map[string]any{
+		"missing server":    {"domain": "exp.local"},
+		"unknown server":    {"server": "foo", "domain": "exp.local"},
+		"no static address": {"server": "dns", "domain": "exp.local"},
+	}
+
+	for name, md := range cases {
+		spec := &v1.ExperimentSpec{
+			ExperimentNameF: "test",
+			TopologyF:       &v1.TopologySpec{NodesF: nodes},
+			ScenarioF: &v2.ScenarioSpec{
+				AppsF: []*v2.ScenarioApp{{NameF: "dns", MetadataF: md}},
+			},
+		}
+
+		if err := new(DNS).Validate(&types.Experiment{Spec: spec}); err == nil {
+			t.Logf("expected validation error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+
+	nodes[0].NetworkF.InterfacesF[0].ProtoF = "static"
+	nodes[0].NetworkF.InterfacesF[0].AddressF = "10.0.1.53"
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: &v2.ScenarioSpec{
+			AppsF: []*v2.ScenarioApp{
+				{
+					NameF: "dns",
+					MetadataF: map[string]any{
+						"server":     "dns",
+						"domain":     "exp.local",
+						"forwarders": []any{"not-an-ip"},
+						"records":    map[string]any{"bad_name": "10.0.1.80", "www": "nope"},
+					},
+				},
+			},
+		},
+	}
+
+	err := new(DNS).Validate(&types.Experiment{Spec: spec})
+	if err == nil || strings.Count(err.Error(), "\n\t* ") != 3 {
+		t.Logf("expected three validation errors, got %v", err)
+		t.FailNow()
+	}
+}
@@ -10,6 +10,15 @@ Default Apps
   * vrouter.go: used to customize a virtual router image, including setting
                 interfaces, ACL rules, IPSec VPN settings, etc
 
+Site Settings
+
+Cluster-level site settings are configured via a Site config named `default`
+(ie. `phenix config create site.yml`). The startup app configures the site's
+DNS servers on interfaces with a default gateway for VMs with no DNS servers
+configured, and adds the site's domain suffix to their DNS search list. The ntp
+app uses the site's first NTP source for hosts with no source configured. The
+image builder uses the site's HTTP proxy when building images.
+
 Custom User Apps
 
 Custom user apps are interacted with through STDIN and STDOUT. The phenix
@@ -22,7 +31,9 @@ addition to the JSON blob, the custom user app should exit with a value of 0.
 If the custom user app is returning log(s) or any error messages, those
 should be written to STDERR (and in the case of an error, the exit value
 should be non-0). Custom user apps must 1) be in the user's PATH, 2) be
-executable, and 3) follow the naming convention `phenix-app-<name>`.
+executable, and 3) follow the naming convention `phenix-app-<name>`. Apps
+published as a git repository or OCI artifact with a `phenix-app.yml`
+manifest can be installed this way using `phenix app install <source>`.
 
 On the command line, the user app should expect the experiment stage to be
 passed as the one and only argument: configure, pre-start, post-start, or
@@ -40,6 +51,371 @@ each user app to add a top-level key to the `apps` JSON object with the
 name of the user app as the key and any metadata in a JSON object as the
 value.
 
+User apps that need long-running processes (ie. traffic generators or scoring
+engines) should start them in the `post-start` stage using `phenix daemon start
+<experiment> <name> -- <command> [args...]` rather than backgrounding them
+directly. Daemons started this way are restarted per their restart policy and
+are killed, along with any processes they spawn, when the experiment is stopped
+or deleted.
+
+User App Registry
+
+Custom user apps can also be registered by dropping a YAML or JSON file into
+the app registry directory (`/etc/phenix/apps.d` by default, configurable via
+`--app.registry-dir`). Registered apps are listed along with their description,
+version, and metadata schema, and the registry directory is watched by the UI
+server so apps can be added, updated, or removed without restarting phenix:
+
+  name: my-app                          # defaults to the file's base name
+  description: configures my-app on VMs
+  version: 1.2.0
+  executable: /opt/my-app/bin/my-app    # defaults to phenix-app-my-app in PATH
+  protocol: grpc                        # defaults to shell
+  schema:
+    type: object
+    properties: ...
+
+Relative executable paths are relative to the registry directory. Registry
+entries take precedence over apps installed via `phenix app install`, except
+when a scenario pins an app to a specific installed version.
+
+User App Environment
+
+Besides the `PHENIX_*` variables phenix sets, custom user apps can be given
+environment variables via the `env` key in their scenario metadata. Credentials
+should instead be declared via the `secretEnv` key, which maps each variable to
+a secret reference that's only resolved when the app is executed, so secrets
+don't end up in scenarios, app scripts, or the experiment passed on STDIN:
+
+  metadata:
+    env:
+      API_URL: http://10.0.0.1:8080
+    secretEnv:
+      API_TOKEN: api-token          # contents of /phenix/secrets/api-token
+      DB_PASSWORD: env:DB_PASSWORD  # environment of the phenix process
+      LICENSE: file:licenses/foo.lic
+
+Variable names starting with `PHENIX_` are reserved. The app fails if a
+referenced secret can't be found. Secret files must be in the directory given
+by the `--app.secrets-dir` option (relative `file:` paths are relative to it),
+and only environment variables matching the `--app.secret-env-allowlist`
+option can be referenced.
+
+User App Logs
+
+Everything a custom user app writes to STDOUT and STDERR is also appended, as
+it's written, to `logs/apps/<name>.log` in the experiment's files directory,
+with a header line marking the start of each stage. The log can be viewed with
+the experiment's other files, fetched via `/api/v1/experiments/<exp>/apps/<name>/log`,
+or followed live over the `/api/v1/experiments/<exp>/apps/<name>/log/ws`
+websocket.
+
+App Dependencies
+
+Scenario apps are applied in the order they're listed in the scenario. An app
+can instead declare the apps it depends on via the `dependsOn` key in its
+metadata, in which case it's applied once those apps have been applied. In the
+`post-start` stage, apps whose dependencies have all been applied are run
+concurrently (apps that don't declare dependencies still wait for the app
+listed before them). Each concurrent app is given its own copy of the
+experiment, so only changes it makes to its own app status are kept. The other
+stages apply apps one at a time in dependency order, since apps can replace the
+experiment spec in those stages.
+
+App Targets
+
+Scenario apps can limit the topology nodes they act on via the `targets` key
+(alongside `metadata` and `hosts`), which selects nodes by hostname (glob
+patterns like `web-*` are supported), labels (an empty label value matches any
+value), and node type (ie. `Router`):
+
+  targets:
+    hostnames: [web-*, db-01]
+    labels:
+      team: blue
+    types: [VirtualMachine]
+
+A node must match every criterion given, and criteria that aren't given match
+every node. Internal and plugin apps get the nodes they should act on via
+`TargetNodes` (or check individual nodes via `Targeted`), and custom user apps
+can read the targets from the scenario in the experiment passed to them.
+Experiment validation fails if an app's targets don't match any nodes.
+
+App Ordering
+
+Default apps are applied before scenario apps unless a scenario app sets
+`runBeforeDefaults`, in which case it's applied before the default apps (ie. a
+custom IPAM app that has to assign addresses before the `startup` default app
+generates its injections). Scenario apps can also set an integer `weight`
+(defaults to 0) to change the order they're applied in: within the apps applied
+before and after the default apps, apps with lower weights are applied first,
+and apps with the same weight are applied in the order they're listed. Apps
+applied before the default apps can't depend on apps applied after them.
+
+Running Stage
+
+The `running` stage is only applied while an experiment is running, either on
+demand (ie. triggered by a user from the web UI) or periodically. Setting
+`runPeriodically` for a scenario app (ie. `5m`) applies its `running` stage at
+that interval for as long as the experiment is running, which lets apps like
+traffic generators or health monitors adjust their behavior mid-experiment.
+Periodic runs are skipped while the app's `running` stage is already being
+applied.
+
+App Timeouts
+
+Each app lifecycle hook is passed a context that's canceled when the experiment
+operation is canceled (ie. phenix is interrupted) or a timeout elapses. The
+`StageTimeout` option limits how long all the apps together can run for a
+stage, and the `AppTimeout` option limits how long each app can run for a
+stage, either by default or for specific apps. A scenario app can also set its
+own timeout (ie. `10m`) via the `timeout` key in its metadata, which is used
+unless the `AppTimeout` option was given for the app. Custom user apps still
+running when their context is canceled are sent SIGTERM, then killed if they
+haven't exited 10 seconds later.
+
+App Capabilities
+
+Before a custom user app is applied for a stage, phenix negotiates with the
+app to find out which version of the app API (`APP_API_VERSION`) it was written
+against and which lifecycle stages it implements. Apps using the shell protocol
+are executed with the `capabilities` argument and write their capabilities to
+STDOUT as JSON (ie. `{"apiVersion": 1, "stages": ["configure", "cleanup"]}`),
+or declare them via `apiVersion` and `stages` in their app registry entry.
+Stages the app doesn't implement are skipped rather than the app being
+executed, and apps written for an app API version this version of phenix
+doesn't support are refused. Apps that don't report their capabilities are
+assumed to implement every stage and be compatible.
+
+App Sandboxes
+
+Custom user apps (using either protocol) can be run in a sandbox so a runaway
+app can't take down the phenix host. The `sandbox` key in a scenario app's
+metadata sets a wall-clock `timeout` for each execution of the app, limits the
+`memory`, `cpus`, and number of processes (`pids`) the app and its children
+can use via a cgroup (v2), limits the number of `openFiles` and the
+`fileSize` of files the app can write, and sets the `workDir` the app is run
+in (relative to the experiment's files directory). Sandboxed apps are run in
+their own process group, which is terminated as a whole when the app is
+canceled. Limits are in place before the app starts executing: the app is
+started directly in its cgroup (Linux 5.7+), and file limits are set via
+`prlimit`. The app fails to run if any of its limits can't be enforced, ie.
+when phenix isn't running as root or `prlimit` isn't installed.
+
+App Progress
+
+Apps that take a while to apply for a stage can report incremental progress so
+they don't appear to be hung. Internal apps call `ReportProgress` with the
+context passed to their lifecycle hook, and custom user apps write a line to
+STDERR starting with `PHENIX_PROGRESS`, followed by an optional percent
+complete and a message (ie. `PHENIX_PROGRESS 40 generating router configs`).
+Progress updates are logged and published to web clients as `progress` events
+for the app.
+
+Progress updates, along with each app starting, succeeding, failing, being
+skipped, or being retried, are emitted as structured `ProgressEvent`s. Events
+are always published to web clients (which the web server forwards over its
+websocket), and passed to the `ProgressHandler` given with the `OnProgress`
+option, which defaults to `LogProgress`. The CLI uses this to render app
+progress in color.
+
+App Diffs
+
+When apps are applied with the `DryRun` option and a `DiffHandler` given with
+the `OnDiff` option, the experiment spec is snapshotted before and after each
+app is applied, and the handler is called with an `AppDiff` listing the nodes
+and injections the app added or removed and any other spec values it changed.
+Nothing is recorded outside of the experiment the apps are applied to, so this
+can be used to preview an experiment's apps (`phenix experiment apps diff`) and
+to test scenarios against topologies without deploying them, ie. in CI for a
+repository of scenarios (`phenix scenario test`).
+
+App Artifacts
+
+Apps can register files they produce (ie. generated configs or reports) as
+artifacts of the experiment, so they're listed per app (`phenix experiment
+artifacts`) and categorized in the experiment files service instead of being
+left at paths no one knows to look for. Artifact files must be written to the
+experiment's files directory. Internal apps register artifacts via
+RegisterArtifact, and custom user apps by adding them to the experiment status
+they return in any stage, ie.
+
+	"appArtifacts": {
+	  "my-app": [
+	    {"name": "report", "path": "my-app/report.html", "mimeType": "text/html"}
+	  ]
+	}
+
+Paths are relative to the files directory, and the MIME type defaults to one
+based on the file extension. Registering an artifact with the same name as one
+already registered by the app replaces it. Artifacts are kept when the
+experiment is stopped, and are downloaded with their registered MIME type.
+
+App Rollback
+
+If an app fails while apps are being applied for the `configure`, `pre-start`,
+or `post-start` stage, the apps already applied for that stage are rolled back
+in reverse order so the experiment isn't left with only some of their changes.
+Apps opt in to rollback by implementing the `Undoer` interface, whose `Undo`
+method is passed the same experiment and the stage being rolled back. Apps that
+don't implement it (including custom user apps) are left as they are. Undo
+errors are logged and recorded in the experiment journal, but the error from
+the app that failed is what's returned.
+
+Network Appliances
+
+The `netos` app supports Cisco IOSv, Arista vEOS, and Juniper vSRX nodes via the
+`iosv`, `veos`, and `vsrx` OS types. In the `configure` stage it raises each
+appliance's CPUs and memory to its platform's minimum, sets its NIC driver, and
+adds a serial port. In the `pre-start` stage it generates the appliance's
+config from the node's interfaces, routes, and site settings, written to the
+`netos` directory in the experiment's base directory. Interfaces map to
+appliance interface names in order:
+
+  iosv: GigabitEthernet0/0, GigabitEthernet0/1, ...
+  veos: Management1, Ethernet1, Ethernet2, ...
+  vsrx: fxp0, ge-0/0/0, ge-0/0/1, ...
+
+Since appliance disks can't have files injected into them, the config is
+pushed over the appliance's serial console in the `post-start` stage by a
+script run on the cluster host (which requires `socat`). The stage fails if an
+appliance doesn't present a CLI prompt with its configured hostname before the
+`bootTimeout` in the app metadata elapses (20 minutes by default). Each
+appliance's state and interface mapping are tracked in the app's status.
+
+DHCP Servers
+
+The `dhcp` app provisions a DHCP server on each node listed as a host of the
+app, serving the VLANs listed in the host's `networks` metadata from the node's
+own address on each VLAN (with an optional `start`/`end` range, `gateway`,
+`dnsServers`, `domain`, and `leaseTime`). Linux nodes get a dnsmasq config
+(or a Kea config, if the host's `server` is `kea`) injected, and minirouter
+routers are configured via minimega in the `post-start` stage, defaulting the
+gateway to the router itself. Nodes with a `dhcp` interface on a served VLAN
+that also have an address get a static reservation for it.
+
+DNS Servers
+
+The `dns` app deploys a BIND config to the Linux node given as the `server` in
+its metadata, with a zone for the `domain` in its metadata (defaulting to the
+site domain, or `phenix.local`) generated from the hostnames and static
+addresses of the topology nodes, any additional `records`, and reverse zones
+for each /24 network. Other domains are resolved via the optional `forwarders`.
+The server node's image is expected to already have BIND installed. Every Linux
+and Windows node targeted by the app (including the server) is pointed at the
+server, with the domain as its search domain, unless it already has DNS servers
+configured.
+
+Firewall Rules
+
+The `firewall` app renders the allow/deny `rules` in its metadata (each with an
+optional `src`, `dst`, `port`, and `protocol`, where sources and destinations
+can be hostnames, addresses, or CIDRs) into inbound firewall rules for the
+nodes they're destined to, injected as startup scripts in the `configure`
+stage. Linux nodes get iptables rules (or nftables, via `backend`) and Windows
+nodes get Windows Firewall rules. With a `defaultPolicy` of `deny`, every
+targeted node gets a script that drops inbound traffic not explicitly allowed.
+The rules that would be rendered for each node can be listed, without applying
+the app, using `phenix experiment apps firewall <experiment>`.
+
+Syslog Collection
+
+The `syslog` app configures the `collector` node (Linux only) to receive syslog
+over `udp` or `tcp` using `rsyslog` or `fluent-bit`, writing a log per sending
+host under `/var/log/phenix`, and injects forwarding configs into the other
+Linux nodes (rsyslog) and Windows nodes (a startup script forwarding the event
+logs listed in `windowsLogs`). In the `running` stage and on `cleanup`, the
+collected logs are pulled off the collector via the C2 agent (so the collector
+must have miniccc installed) and registered as artifacts of the app, available
+from the experiment's files.
+
+Active Directory
+
+The `activedirectory` app promotes the Windows `domainController` node to the
+domain controller (and DNS server) of a new forest for `domain`, creates the
+`ous` (nested OUs are separated by slashes), `groups`, and `users` in its
+metadata, and joins the Windows `members` (every other targeted Windows node by
+default) to the domain using the domain Administrator (`adminPassword`). It's
+all done by startup scripts injected in the `pre-start` stage, which are safe to
+run on every boot: the domain controller reboots once after it's promoted, and
+members point their DNS at the domain controller and wait for the domain to
+resolve before joining it and rebooting.
+
+Guest Info
+
+The `guestinfo` app injects each targeted node's phenix identity as JSON into
+`/etc/phenix/guestinfo.json` (Linux) or `C:\phenix\guestinfo.json` (Windows) in
+the `pre-start` stage: its experiment, hostname, type, OS type, labels,
+interfaces, the node `annotations` listed in the app metadata, and the
+experiment-level `variables` in the app metadata merged with any `variables`
+given for the node in the app's `hosts`. In-guest scripts can use it to
+configure themselves, or fetch the same document from the guest callback API
+(`/api/v1/callback/guestinfo`) when the `callback` app is also configured. The
+app should be listed after any apps that change node settings.
+
+Traffic Generation
+
+The `traffic` app generates traffic between Linux nodes from the `flows` listed
+in its metadata, each naming a `client` and `server` node and optionally a
+`tool` (`iperf` by default, or `protonuke`), `protocol`, `port`, `rate`,
+`duration`, and `start` offset. A flow runs constantly by default, or in bursts
+given by its `profile`: `bursty` profiles alternate fixed `on` and `off`
+periods, while `random` profiles pick each period from a `min-max` range. A
+script starting the servers and flows is injected into each node in the
+`pre-start` stage, run via miniccc in the `post-start` stage, and told to stop
+everything in the `cleanup` stage. The tools must already be installed in the
+node images.
+
+Router HA Pairs
+
+The `vrouter` app's `haPairs` metadata declares pairs of routers sharing a
+virtual address on each of the interfaces listed in a pair's `vips` (keyed by
+topology interface name, which must be on the same VLAN for both routers) via a
+single VRRP `group`. The first router is the primary unless `priorities` are
+given, and `preempt: false` keeps a recovered primary from taking the addresses
+back. Vyatta and VyOS routers get the groups in their generated configs, while
+minirouter routers get a keepalived config injected into
+`/etc/keepalived/keepalived.conf` (keepalived must be installed in the image).
+
+Metadata Schemas
+
+Apps can publish an OpenAPI schema describing their scenario metadata. Internal
+and plugin apps do so by implementing the MetadataSchemaProvider interface, and
+custom user apps by including a schema in their manifest when installed via
+`phenix app install`. Before any apps are applied in the `configure` stage, the
+metadata of each enabled app in the experiment's scenario is validated against
+the app's schema (ignoring the `timeout` and `dependsOn` keys phenix handles for
+every app), and the stage fails with every violation found, each identified by
+the path to the offending value (e.g. `metadata.bridge.macTableSize`).
+
+Audit Trail
+
+Each app invoked for an experiment is recorded in the audit log once it
+completes, including the user that triggered it, the experiment and stage, how
+long it ran for, whether it succeeded (and its exit code, for custom user apps),
+and the host it ran on. The user is the web UI user that started, stopped, or
+triggered the experiment, or the local user running phenix otherwise. App
+events can be queried with `phenix audit --action app/` or via the `/audit` API.
+
+Plugin Apps
+
+Apps can also be built as Go plugins (`go build -buildmode=plugin`) and loaded
+in-process at startup from the directory given by the `--app.plugin-dir`
+option, avoiding the overhead of shelling out to a custom user app for each
+stage. A plugin must export a `NewApp` function with the signature
+`func() app.App`, and its app is registered using the name returned by the
+app's `Name` method. Plugins must be built with the same Go toolchain and
+phenix source as the phenix binary, and can't replace apps that are already
+registered.
+
+Loading plugins requires cgo, so the release build of phenix (built with
+CGO_ENABLED=0) refuses to start when `--app.plugin-dir` is set. To use plugins,
+build phenix with cgo enabled and build each plugin with the exact same flags
+(including any build tags), for example:
+
+  CGO_ENABLED=1 go build -trimpath -o bin/phenix main.go
+  CGO_ENABLED=1 go build -trimpath -buildmode=plugin -o foo.so ./foo
+
 Example Custom User App
 
   import json, sys
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"phenix/util/common"
+)
+
+const (
+	// ENV_KEY is the scenario app metadata key used to declare environment
+	// variables (ie. `API_URL: http://10.0.0.1`) that are injected into the
+	// process environment of custom user apps.
+	ENV_KEY = "env"
+
+	// SECRET_ENV_KEY is the scenario app metadata key used to declare
+	// environment variables whose values are resolved from secret references
+	// (ie. `DB_PASSWORD: db-password`) when custom user apps are executed, so
+	// credentials don't have to be hardcoded in scenarios or app scripts.
+	SECRET_ENV_KEY = "secretEnv"
+)
+
+// SECRETS_DIR is the directory secret references without a scheme are resolved
+// against, each secret being stored in a file named after it. Secrets
+// referenced by path must also be in this directory.
+var SECRETS_DIR = filepath.Join(common.PhenixBase, "secrets")
+
+// SECRET_ENV_ALLOWLIST is the list of environment variable names (or
+// filepath.Match patterns, ie. `PHENIX_SECRET_*`) of the phenix process that
+// secret references are allowed to resolve. No environment variables can be
+// referenced if empty.
+var SECRET_ENV_ALLOWLIST []string
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// appEnv returns the environment variables (as `KEY=VALUE` strings) declared
+// for the given app in the given metadata, with secret references resolved.
+// Errors never include secret values.
+func appEnv(name string, md map[string]any) ([]string, error) {
+	vars, err := envVars(name, md, ENV_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := envVars(name, md, SECRET_ENV_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+
+	for _, k := range sortedKeys(vars) {
+		env = append(env, k+"="+vars[k])
+	}
+
+	for _, k := range sortedKeys(refs) {
+		if _, ok := vars[k]; ok {
+			return nil, fmt.Errorf("environment variable %s for app %s declared in both %s and %s", k, name, ENV_KEY, SECRET_ENV_KEY)
+		}
+
+		val, err := resolveSecret(refs[k])
+		if err != nil {
+			return nil, fmt.Errorf("resolving secret for environment variable %s for app %s: %w", k, name, err)
+		}
+
+		env = append(env, k+"="+val)
+	}
+
+	return env, nil
+}
+
+// envVars returns the environment variables declared under the given metadata
+// key. Variable names must be valid shell identifiers and can't start with
+// `PHENIX_`, since those are reserved for the variables phenix sets itself.
+func envVars(name string, md map[string]any, key string) (map[string]string, error) {
+	val, ok := md[key]
+	if !ok {
+		return nil, nil
+	}
+
+	raw, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s for app %s (expected map of environment variables)", key, name)
+	}
+
+	vars := make(map[string]string)
+
+	for k, v := range raw {
+		if !envNameRegex.MatchString(k) {
+			return nil, fmt.Errorf("invalid environment variable name %s in %s for app %s", k, key, name)
+		}
+
+		if strings.HasPrefix(k, "PHENIX_") {
+			return nil, fmt.Errorf("environment variable %s in %s for app %s is reserved", k, key, name)
+		}
+
+		switch v.(type) {
+		case string, bool, int, int64, float64:
+			vars[k] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("invalid value for environment variable %s in %s for app %s (expected scalar)", k, key, name)
+		}
+	}
+
+	return vars, nil
+}
+
+// resolveSecret returns the value of the given secret reference, which can be
+// one of the following.
+//
+//	env:NAME    the NAME environment variable of the phenix process
+//	file:PATH   the contents of the file at PATH in SECRETS_DIR
+//	NAME        the contents of the NAME file in SECRETS_DIR
+//
+// Environment variables must be in SECRET_ENV_ALLOWLIST, and relative paths
+// are relative to SECRETS_DIR. Paths outside of SECRETS_DIR are rejected so
+// scenarios can't be used to read arbitrary files on the phenix host. Trailing
+// newlines are removed from secrets read from files.
+func resolveSecret(ref string) (string, error) {
+	scheme, value, ok := strings.Cut(ref, ":")
+	if !ok {
+		scheme, value = "", ref
+	}
+
+	if value == "" {
+		return "", fmt.Errorf("empty secret reference %q", ref)
+	}
+
+	switch scheme {
+	case "env":
+		if !secretEnvAllowed(value) {
+			return "", fmt.Errorf("environment variable %s not in secret environment allowlist", value)
+		}
+
+		val, ok := os.LookupEnv(value)
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s not set", ErrSecretNotFound, value)
+		}
+
+		return val, nil
+	case "file":
+		path, err := secretPath(value)
+		if err != nil {
+			return "", err
+		}
+
+		return readSecret(path)
+	case "":
+		if value != filepath.Base(value) || value == "." || value == ".." {
+			return "", fmt.Errorf("invalid secret name %s", value)
+		}
+
+		return readSecret(filepath.Join(SECRETS_DIR, value))
+	default:
+		return "", fmt.Errorf("unknown secret reference scheme %s", scheme)
+	}
+}
+
+func secretEnvAllowed(name string) bool {
+	for _, pattern := range SECRET_ENV_ALLOWLIST {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// secretPath returns the absolute path of the given secret file path, which
+// must be within SECRETS_DIR.
+func secretPath(path string) (string, error) {
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem == ".." {
+			return "", fmt.Errorf("invalid secret path %s", path)
+		}
+	}
+
+	dir, err := filepath.Abs(SECRETS_DIR)
+	if err != nil {
+		return "", fmt.Errorf("getting absolute path of secrets directory: %w", err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	path = filepath.Clean(path)
+
+	if rel, err := filepath.Rel(dir, path); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("secret path %s is not in secrets directory %s", path, SECRETS_DIR)
+	}
+
+	return path, nil
+}
+
+func readSecret(path string) (string, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+		}
+
+		return "", fmt.Errorf("reading secret %s: %w", path, err)
+	}
+
+	return strings.TrimRight(string(body), "\r\n"), nil
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+
+	defer func(dir string, allow []string) {
+		SECRETS_DIR = dir
+		SECRET_ENV_ALLOWLIST = allow
+	}(SECRETS_DIR, SECRET_ENV_ALLOWLIST)
+
+	SECRETS_DIR = filepath.Join(dir, "secrets")
+	SECRET_ENV_ALLOWLIST = []string{"PHENIX_SECRET_*"}
+
+	os.MkdirAll(filepath.Join(SECRETS_DIR, "licenses"), 0700)
+	os.WriteFile(filepath.Join(SECRETS_DIR, "api-token"), []byte("s3cr3t\n"), 0600)
+	os.WriteFile(filepath.Join(SECRETS_DIR, "licenses", "foo.lic"), []byte("license"), 0600)
+	os.WriteFile(filepath.Join(dir, "outside"), []byte("host file"), 0600)
+
+	t.Setenv("PHENIX_SECRET_DB", "hunter2")
+	t.Setenv("HOME_TOKEN", "not allowed")
+
+	valid := map[string]string{
+		"api-token":                          "s3cr3t",
+		"file:licenses/foo.lic":              "license",
+		"file:" + SECRETS_DIR + "/api-token": "s3cr3t",
+		"env:PHENIX_SECRET_DB":               "hunter2",
+	}
+
+	for ref, expected := range valid {
+		val, err := resolveSecret(ref)
+		if err != nil {
+			t.Logf("resolving %s: %v", ref, err)
+			t.FailNow()
+		}
+
+		if val != expected {
+			t.Logf("expected %s to resolve to %q, got %q", ref, expected, val)
+			t.FailNow()
+		}
+	}
+
+	invalid := []string{
+		"../outside",
+		"file:../outside",
+		"file:licenses/../../outside",
+		"file:" + filepath.Join(dir, "outside"),
+		"file:/etc/passwd",
+		"file:" + SECRETS_DIR,
+		"env:HOME_TOKEN",
+		"env:PATH",
+		"vault:foo",
+	}
+
+	for _, ref := range invalid {
+		if _, err := resolveSecret(ref); err == nil {
+			t.Logf("expected secret reference %s to be rejected", ref)
+			t.FailNow()
+		}
+	}
+}
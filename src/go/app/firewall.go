@@ -0,0 +1,558 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"inet.af/netaddr"
+)
+
+func init() {
+	RegisterUserApp("firewall", func() App { return new(Firewall) })
+}
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: firewall
+      metadata:
+        defaultPolicy: deny # default is allow
+        backend: nftables   # Linux backend; default is iptables
+        rules:
+        - action: allow
+          src: hmi          # hostname, address, CIDR, or any (default)
+          dst: plc          # hostname, address, CIDR, or any (default)
+          port: 502         # single port or range (ie. 8000-8080)
+          protocol: tcp     # tcp, udp, icmp, or any (default is tcp if a port is given)
+*/
+
+type FirewallAppMetadata struct {
+	DefaultPolicy string            `mapstructure:"defaultPolicy"`
+	Backend       string            `mapstructure:"backend"`
+	Rules         []FirewallAppRule `mapstructure:"rules"`
+}
+
+type FirewallAppRule struct {
+	Action   string `mapstructure:"action"`
+	Src      string `mapstructure:"src"`
+	Dst      string `mapstructure:"dst"`
+	Port     string `mapstructure:"port"`
+	Protocol string `mapstructure:"protocol"`
+}
+
+// FirewallNodeRules are the firewall rules rendered for a topology node by the
+// firewall app, along with where they're injected into the node.
+type FirewallNodeRules struct {
+	Host    string   `json:"host"`
+	Backend string   `json:"backend"`
+	Dst     string   `json:"dst"`
+	Rules   []string `json:"rules"`
+}
+
+// Firewall renders the allow/deny intents in the app metadata into inbound
+// firewall rules for each topology node targeted by the app that's the
+// destination of at least one rule (or for every targeted node if the default
+// policy is deny). Linux nodes get iptables (or nftables) rules and Windows
+// nodes get Windows Firewall rules, injected as startup scripts in the
+// configure stage. Rules are applied in the order they're given, so the first
+// rule matching a connection wins on Linux nodes; Windows Firewall always gives
+// deny rules precedence over allow rules.
+type Firewall struct{}
+
+func (Firewall) Init(...Option) error {
+	return nil
+}
+
+func (Firewall) Name() string {
+	return "firewall"
+}
+
+func (Firewall) MetadataSchema() []byte {
+	return []byte(`
+type: object
+required:
+- rules
+properties:
+  defaultPolicy:
+    type: string
+    enum:
+    - allow
+    - deny
+  backend:
+    type: string
+    enum:
+    - iptables
+    - nftables
+  rules:
+    type: array
+    items:
+      type: object
+      required:
+      - action
+      properties:
+        action:
+          type: string
+          enum:
+          - allow
+          - deny
+        src:
+          type: string
+        dst:
+          type: string
+        port:
+          oneOf:
+          - type: string
+          - type: integer
+        protocol:
+          type: string
+          enum:
+          - tcp
+          - udp
+          - icmp
+          - any
+      additionalProperties: false
+additionalProperties: false
+`)
+}
+
+// Validate checks that the rules in the app metadata are valid.
+func (this Firewall) Validate(exp *types.Experiment) error {
+	_, err := FirewallRules(exp)
+	return err
+}
+
+func (this Firewall) Configure(ctx context.Context, exp *types.Experiment) error {
+	rules, err := FirewallRules(exp)
+	if err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	fwDir := exp.Spec.BaseDir() + "/firewall"
+
+	if err := os.MkdirAll(fwDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment firewall directory path: %w", err)
+	}
+
+	for _, r := range rules {
+		node := exp.Spec.Topology().FindNodeByName(r.Host)
+
+		var (
+			name = "firewall_linux.tmpl"
+			src  = fwDir + "/" + r.Host + "-firewall.sh"
+		)
+
+		if r.Backend == "windows" {
+			name = "firewall_windows.tmpl"
+			src = fwDir + "/" + r.Host + "-firewall.ps1"
+		}
+
+		if err := tmpl.CreateFileFromTemplate(name, r, src); err != nil {
+			return fmt.Errorf("generating firewall script for host %s: %w", r.Host, err)
+		}
+
+		node.AddInject(src, r.Dst, "0755", "")
+	}
+
+	return nil
+}
+
+func (Firewall) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Firewall) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Firewall) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (Firewall) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// firewallRule is a firewall app rule resolved against the experiment
+// topology.
+type firewallRule struct {
+	allow    bool
+	sources  []string
+	dsts     []netaddr.IPPrefix
+	dstHost  string
+	proto    string
+	port     string
+	position int
+}
+
+// FirewallRules returns the firewall rules the firewall app renders for each
+// topology node in the given experiment, sorted by hostname, without changing
+// the experiment. Nil is returned if the experiment doesn't include the app.
+func FirewallRules(exp *types.Experiment) ([]FirewallNodeRules, error) {
+	app := exp.App("firewall")
+	if app == nil {
+		return nil, nil
+	}
+
+	var md FirewallAppMetadata
+
+	// Weakly decoded so ports can be given as integers.
+	if err := mapstructure.WeakDecode(app.Metadata(), &md); err != nil {
+		return nil, fmt.Errorf("decoding firewall app metadata: %w", err)
+	}
+
+	var errs error
+
+	switch md.DefaultPolicy {
+	case "", "allow", "deny":
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid default policy %s (expected allow or deny)", md.DefaultPolicy))
+	}
+
+	switch md.Backend {
+	case "", "iptables", "nftables":
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid backend %s (expected iptables or nftables)", md.Backend))
+	}
+
+	var rules []firewallRule
+
+	for i, r := range md.Rules {
+		rule, err := resolveFirewallRule(exp, r)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("rule %d: %w", i, err))
+			continue
+		}
+
+		rule.position = i
+		rules = append(rules, rule)
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	var (
+		deny   = md.DefaultPolicy == "deny"
+		linux  = md.Backend
+		result []FirewallNodeRules
+	)
+
+	if linux == "" {
+		linux = "iptables"
+	}
+
+	for _, node := range TargetNodes(exp, "firewall") {
+		// Router ACLs are configured via the vrouter app instead.
+		if node.External() || node.Network() == nil || strings.EqualFold(node.Type(), "router") {
+			continue
+		}
+
+		var (
+			host    = node.General().Hostname()
+			backend string
+			dst     string
+		)
+
+		switch strings.ToLower(node.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
+			// Runs after interfaces (and any mirror config) are configured.
+			backend, dst = linux, "/etc/phenix/startup/5_firewall-start.sh"
+		case "windows":
+			backend, dst = "windows", "/phenix/startup/26-firewall.ps1"
+		default:
+			continue
+		}
+
+		var matched []firewallRule
+
+		for _, rule := range rules {
+			if rule.appliesTo(node) {
+				matched = append(matched, rule)
+			}
+		}
+
+		if len(matched) == 0 && !deny {
+			continue
+		}
+
+		nr := FirewallNodeRules{Host: host, Backend: backend, Dst: dst}
+
+		for _, rule := range matched {
+			nr.Rules = append(nr.Rules, rule.render(backend)...)
+		}
+
+		nr.Rules = append(nr.Rules, firewallPolicy(backend, deny))
+
+		result = append(result, nr)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Host < result[j].Host })
+
+	return result, nil
+}
+
+func resolveFirewallRule(exp *types.Experiment, r FirewallAppRule) (firewallRule, error) {
+	var rule firewallRule
+
+	switch strings.ToLower(r.Action) {
+	case "allow":
+		rule.allow = true
+	case "deny":
+	default:
+		return rule, fmt.Errorf("invalid action %s (expected allow or deny)", r.Action)
+	}
+
+	src, _, err := firewallAddresses(exp, r.Src)
+	if err != nil {
+		return rule, fmt.Errorf("invalid src: %w", err)
+	}
+
+	for _, prefix := range src {
+		if prefix.Bits() == 32 {
+			rule.sources = append(rule.sources, prefix.IP().String())
+		} else {
+			rule.sources = append(rule.sources, prefix.String())
+		}
+	}
+
+	rule.dsts, rule.dstHost, err = firewallAddresses(exp, r.Dst)
+	if err != nil {
+		return rule, fmt.Errorf("invalid dst: %w", err)
+	}
+
+	rule.proto = strings.ToLower(r.Protocol)
+
+	if rule.proto == "" {
+		rule.proto = "any"
+
+		if r.Port != "" {
+			rule.proto = "tcp"
+		}
+	}
+
+	switch rule.proto {
+	case "tcp", "udp":
+	case "icmp", "any":
+		if r.Port != "" {
+			return rule, fmt.Errorf("port %s given for protocol %s", r.Port, rule.proto)
+		}
+	default:
+		return rule, fmt.Errorf("invalid protocol %s (expected tcp, udp, icmp, or any)", r.Protocol)
+	}
+
+	if r.Port != "" {
+		ports := strings.SplitN(r.Port, "-", 2)
+
+		for _, p := range ports {
+			if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+				return rule, fmt.Errorf("invalid port %s", r.Port)
+			}
+		}
+
+		if len(ports) == 2 {
+			first, _ := strconv.Atoi(ports[0])
+			last, _ := strconv.Atoi(ports[1])
+
+			if first > last {
+				return rule, fmt.Errorf("invalid port range %s", r.Port)
+			}
+		}
+
+		rule.port = r.Port
+	}
+
+	return rule, nil
+}
+
+// firewallAddresses resolves the given rule source or destination to the
+// addresses it matches. Hostnames resolve to the node's static addresses, and
+// an empty (or any) value resolves to no addresses, matching everything. The
+// hostname is also returned for values that are hostnames.
+func firewallAddresses(exp *types.Experiment, value string) ([]netaddr.IPPrefix, string, error) {
+	if value == "" || strings.EqualFold(value, "any") {
+		return nil, "", nil
+	}
+
+	if prefix, err := netaddr.ParseIPPrefix(value); err == nil {
+		return []netaddr.IPPrefix{prefix.Masked()}, "", nil
+	}
+
+	if ip, err := netaddr.ParseIP(value); err == nil {
+		return []netaddr.IPPrefix{netaddr.IPPrefixFrom(ip, 32)}, "", nil
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(value)
+	if node == nil {
+		return nil, "", fmt.Errorf("%s is not an address, CIDR, or node in the topology", value)
+	}
+
+	var addrs []netaddr.IPPrefix
+
+	if node.Network() != nil {
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Address() == "" || strings.EqualFold(iface.Proto(), "dhcp") {
+				continue
+			}
+
+			if ip, err := netaddr.ParseIP(iface.Address()); err == nil {
+				addrs = append(addrs, netaddr.IPPrefixFrom(ip, 32))
+			}
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, "", fmt.Errorf("node %s has no static IP addresses", value)
+	}
+
+	return addrs, node.General().Hostname(), nil
+}
+
+// appliesTo returns true if the given node is a destination of the rule.
+func (this firewallRule) appliesTo(node ifaces.NodeSpec) bool {
+	if this.dstHost != "" {
+		return this.dstHost == node.General().Hostname()
+	}
+
+	if len(this.dsts) == 0 {
+		return true
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		ip, err := netaddr.ParseIP(iface.Address())
+		if err != nil {
+			continue
+		}
+
+		for _, dst := range this.dsts {
+			if dst.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// render returns the commands for the rule for the given backend, one for each
+// source address (or a single command if the rule matches any source).
+func (this firewallRule) render(backend string) []string {
+	sources := this.sources
+
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+
+	var cmds []string
+
+	for _, src := range sources {
+		var cmd []string
+
+		switch backend {
+		case "iptables":
+			cmd = []string{"iptables -A INPUT"}
+
+			if src != "" {
+				cmd = append(cmd, "-s "+src)
+			}
+
+			if this.proto != "any" {
+				cmd = append(cmd, "-p "+this.proto)
+			}
+
+			if this.port != "" {
+				cmd = append(cmd, "--dport "+strings.Replace(this.port, "-", ":", 1))
+			}
+
+			if this.allow {
+				cmd = append(cmd, "-j ACCEPT")
+			} else {
+				cmd = append(cmd, "-j DROP")
+			}
+		case "nftables":
+			cmd = []string{"nft add rule inet phenix input"}
+
+			if src != "" {
+				cmd = append(cmd, "ip saddr "+src)
+			}
+
+			switch {
+			case this.port != "":
+				cmd = append(cmd, this.proto+" dport "+this.port)
+			case this.proto != "any":
+				cmd = append(cmd, "meta l4proto "+this.proto)
+			}
+
+			if this.allow {
+				cmd = append(cmd, "accept")
+			} else {
+				cmd = append(cmd, "drop")
+			}
+		case "windows":
+			action := "Block"
+
+			if this.allow {
+				action = "Allow"
+			}
+
+			cmd = []string{
+				fmt.Sprintf("New-NetFirewallRule -DisplayName 'phenix-%d' -Group 'phenix' -Direction Inbound -Action %s", this.position, action),
+			}
+
+			if src != "" {
+				cmd = append(cmd, "-RemoteAddress "+src)
+			}
+
+			if this.proto != "any" {
+				cmd = append(cmd, "-Protocol "+strings.ToUpper(this.proto))
+			}
+
+			if this.port != "" {
+				cmd = append(cmd, "-LocalPort "+this.port)
+			}
+		}
+
+		cmds = append(cmds, strings.Join(cmd, " "))
+	}
+
+	return cmds
+}
+
+// firewallPolicy returns the command setting the default inbound policy for
+// the given backend.
+func firewallPolicy(backend string, deny bool) string {
+	switch backend {
+	case "iptables":
+		if deny {
+			return "iptables -P INPUT DROP"
+		}
+
+		return "iptables -P INPUT ACCEPT"
+	case "nftables":
+		if deny {
+			return "nft chain inet phenix input '{ policy drop; }'"
+		}
+
+		return "nft chain inet phenix input '{ policy accept; }'"
+	default:
+		if deny {
+			return "Set-NetFirewallProfile -Profile Domain,Public,Private -Enabled True -DefaultInboundAction Block"
+		}
+
+		return "Set-NetFirewallProfile -Profile Domain,Public,Private -Enabled True -DefaultInboundAction Allow"
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func firewallTestNodes() []*v1.Node {
+	return []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "hmi",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "windows",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.10", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "plc",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.20", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "historian",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_2", ProtoF: "static", AddressF: "10.0.2.30", MaskF: 24},
+				},
+			},
+		},
+	}
+}
+
+func TestFirewallApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "firewall-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := firewallTestNodes()
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "firewall",
+				MetadataF: map[string]any{
+					"rules": []any{
+						map[string]any{"action": "allow", "src": "hmi", "dst": "plc", "port": 502},
+						map[string]any{"action": "deny", "dst": "10.0.1.0/24", "protocol": "icmp"},
+						map[string]any{"action": "deny", "src": "10.0.2.0/24", "dst": "hmi", "port": "3389-3390", "protocol": "udp"},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	rules, err := FirewallRules(exp)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string][]string{
+		"hmi": {
+			"New-NetFirewallRule -DisplayName 'phenix-1' -Group 'phenix' -Direction Inbound -Action Block -Protocol ICMP",
+			"New-NetFirewallRule -DisplayName 'phenix-2' -Group 'phenix' -Direction Inbound -Action Block -RemoteAddress 10.0.2.0/24 -Protocol UDP -LocalPort 3389-3390",
+			"Set-NetFirewallProfile -Profile Domain,Public,Private -Enabled True -DefaultInboundAction Allow",
+		},
+		"plc": {
+			"iptables -A INPUT -s 10.0.1.10 -p tcp --dport 502 -j ACCEPT",
+			"iptables -A INPUT -p icmp -j DROP",
+			"iptables -P INPUT ACCEPT",
+		},
+	}
+
+	// The historian isn't the destination of any rules.
+	if len(rules) != len(expected) {
+		t.Logf("expected rules for %d nodes, got %d", len(expected), len(rules))
+		t.FailNow()
+	}
+
+	for _, r := range rules {
+		if strings.Join(r.Rules, "\n") != strings.Join(expected[r.Host], "\n") {
+			t.Logf("unexpected rules for host %s: %v", r.Host, r.Rules)
+			t.FailNow()
+		}
+	}
+
+	if err := GetApp("firewall").Configure(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for i, dst := range map[int]string{0: "/phenix/startup/26-firewall.ps1", 1: "/etc/phenix/startup/5_firewall-start.sh"} {
+		injects := nodes[i].Injections()
+
+		if len(injects) != 1 || injects[0].Dst() != dst {
+			t.Logf("expected single injection to %s for host %s, got %v", dst, nodes[i].General().Hostname(), injects)
+			t.FailNow()
+		}
+	}
+
+	if len(nodes[2].Injections()) != 0 {
+		t.Log("expected no injections for host historian")
+		t.FailNow()
+	}
+
+	script, err := os.ReadFile(baseDir + "/firewall/plc-firewall.sh")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !strings.Contains(string(script), "iptables -F INPUT") || !strings.Contains(string(script), "-s 10.0.1.10 -p tcp --dport 502 -j ACCEPT") {
+		t.Logf("unexpected Linux firewall script: %s", script)
+		t.FailNow()
+	}
+}
+
+func TestFirewallAppDefaultDeny(t *testing.T) {
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "firewall",
+				MetadataF: map[string]any{
+					"defaultPolicy": "deny",
+					"backend":       "nftables",
+					"rules": []any{
+						map[string]any{"action": "allow", "src": "10.0.1.0/24", "port": "22"},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{NodesF: firewallTestNodes()},
+		ScenarioF:       scenario,
+	}
+
+	rules, err := FirewallRules(&types.Experiment{Spec: spec})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(rules) != 3 {
+		t.Logf("expected rules for every node with a default deny policy, got %d", len(rules))
+		t.FailNow()
+	}
+
+	expected := []string{
+		"nft add rule inet phenix input ip saddr 10.0.1.0/24 tcp dport 22 accept",
+		"nft chain inet phenix input '{ policy drop; }'",
+	}
+
+	if strings.Join(rules[0].Rules, "\n") != strings.Join(expected, "\n") {
+		t.Logf("unexpected rules for host %s: %v", rules[0].Host, rules[0].Rules)
+		t.FailNow()
+	}
+}
+
+func TestFirewallAppValidate(t *testing.T) {
+	cases := map[string]map[string]any{
+		"invalid action":     {"action": "reject"},
+		"unknown host":       {"action": "allow", "dst": "foo"},
+		"port without proto": {"action": "allow", "port": 22, "protocol": "icmp"},
+		"invalid port":       {"action": "allow", "port": 70000},
+		"invalid port range": {"action": "allow", "port": "90-80"},
+	}
+
+	for name, rule := range cases {
+		spec := &v1.ExperimentSpec{
+			ExperimentNameF: "test",
+			TopologyF:       &v1.TopologySpec{NodesF: firewallTestNodes()},
+			ScenarioF: &v2.ScenarioSpec{
+				AppsF: []*v2.ScenarioApp{
+					{NameF: "firewall", MetadataF: map[string]any{"rules": []any{rule}}},
+				},
+			},
+		}
+
+		if err := new(Firewall).Validate(&types.Experiment{Spec: spec}); err == nil {
+			t.Logf("expected validation error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+}
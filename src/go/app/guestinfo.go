@@ -0,0 +1,258 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+)
+
+func init() {
+	RegisterUserApp("guestinfo", func() App { return new(GuestInfoApp) })
+}
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: guestinfo
+      metadata:
+        annotations: # node annotations to include (none by default)
+        - team
+        variables:   # included for every node
+          range: blue
+          scoring: http://10.0.0.5:8080
+      hosts:
+      - hostname: web
+        metadata:
+          variables: # merged over (and override) the experiment variables
+            role: webserver
+*/
+
+type GuestInfoAppMetadata struct {
+	Annotations []string       `mapstructure:"annotations"`
+	Variables   map[string]any `mapstructure:"variables"`
+}
+
+type GuestInfoAppHostMetadata struct {
+	Variables map[string]any `mapstructure:"variables"`
+}
+
+// GuestInfo is the phenix identity of a node, as injected into the node by the
+// guestinfo app and served to it by the guest callback API.
+type GuestInfo struct {
+	Experiment  string               `json:"experiment"`
+	Hostname    string               `json:"hostname"`
+	Type        string               `json:"type"`
+	OSType      string               `json:"osType"`
+	Labels      map[string]string    `json:"labels,omitempty"`
+	Annotations map[string]any       `json:"annotations,omitempty"`
+	Interfaces  []GuestInfoInterface `json:"interfaces,omitempty"`
+	Variables   map[string]any       `json:"variables,omitempty"`
+}
+
+type GuestInfoInterface struct {
+	Name    string `json:"name"`
+	VLAN    string `json:"vlan,omitempty"`
+	Proto   string `json:"proto,omitempty"`
+	Address string `json:"address,omitempty"`
+	Mask    int    `json:"mask,omitempty"`
+	Gateway string `json:"gateway,omitempty"`
+	MAC     string `json:"mac,omitempty"`
+}
+
+// GuestInfoApp injects a JSON file describing each targeted node's phenix
+// identity (its experiment, hostname, labels, interfaces, selected annotations,
+// and the experiment and per-host variables given in the app metadata) into
+// the node in the pre-start stage, so in-guest scripts can configure
+// themselves based on it. The file is written to `/etc/phenix/guestinfo.json`
+// on Linux nodes and `C:\phenix\guestinfo.json` on Windows nodes. It should be
+// applied after any apps that change node settings.
+type GuestInfoApp struct{}
+
+func (GuestInfoApp) Init(...Option) error {
+	return nil
+}
+
+func (GuestInfoApp) Name() string {
+	return "guestinfo"
+}
+
+func (GuestInfoApp) MetadataSchema() []byte {
+	return []byte(`
+type: object
+properties:
+  annotations:
+    type: array
+    items:
+      type: string
+  variables:
+    type: object
+additionalProperties: false
+`)
+}
+
+// Validate checks that the app metadata, and the metadata of each host listed
+// for the app, can be decoded.
+func (this GuestInfoApp) Validate(exp *types.Experiment) error {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil
+	}
+
+	var md GuestInfoAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	var errs error
+
+	for _, host := range app.Hosts() {
+		if exp.Spec.Topology().FindNodeByName(host.Hostname()) == nil {
+			errs = multierror.Append(errs, fmt.Errorf("host %s not in topology", host.Hostname()))
+			continue
+		}
+
+		var hmd GuestInfoAppHostMetadata
+
+		if err := mapstructure.Decode(host.Metadata(), &hmd); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("decoding %s metadata for host %s: %w", this.Name(), host.Hostname(), err))
+		}
+	}
+
+	return errs
+}
+
+func (GuestInfoApp) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this GuestInfoApp) PreStart(ctx context.Context, exp *types.Experiment) error {
+	dir := exp.Spec.BaseDir() + "/guestinfo"
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating experiment guestinfo directory path: %w", err)
+	}
+
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() {
+			continue
+		}
+
+		info, err := NodeGuestInfo(exp, node)
+		if err != nil {
+			return err
+		}
+
+		body, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling guest info for node %s: %w", info.Hostname, err)
+		}
+
+		src := dir + "/" + info.Hostname + ".json"
+
+		if err := os.WriteFile(src, body, 0644); err != nil {
+			return fmt.Errorf("writing guest info for node %s: %w", info.Hostname, err)
+		}
+
+		if strings.EqualFold(node.Hardware().OSType(), "windows") {
+			node.AddInject(src, "/phenix/guestinfo.json", "0644", "")
+		} else {
+			node.AddInject(src, "/etc/phenix/guestinfo.json", "0644", "")
+		}
+	}
+
+	return nil
+}
+
+func (GuestInfoApp) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (GuestInfoApp) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (GuestInfoApp) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// NodeGuestInfo returns the phenix identity of the given node in the given
+// experiment. Annotations and variables are only included if the guestinfo app
+// is configured for the experiment.
+func NodeGuestInfo(exp *types.Experiment, node ifaces.NodeSpec) (GuestInfo, error) {
+	info := GuestInfo{
+		Experiment: exp.Metadata.Name,
+		Hostname:   node.General().Hostname(),
+		Type:       node.Type(),
+		Labels:     node.Labels(),
+	}
+
+	if node.Hardware() != nil {
+		info.OSType = node.Hardware().OSType()
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		info.Interfaces = append(info.Interfaces, GuestInfoInterface{
+			Name:    iface.Name(),
+			VLAN:    iface.VLAN(),
+			Proto:   iface.Proto(),
+			Address: iface.Address(),
+			Mask:    iface.Mask(),
+			Gateway: iface.Gateway(),
+			MAC:     iface.MAC(),
+		})
+	}
+
+	app := exp.App("guestinfo")
+	if app == nil {
+		return info, nil
+	}
+
+	var md GuestInfoAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return info, fmt.Errorf("decoding guestinfo app metadata: %w", err)
+	}
+
+	for _, key := range md.Annotations {
+		if val, ok := node.GetAnnotation(key); ok {
+			if info.Annotations == nil {
+				info.Annotations = make(map[string]any)
+			}
+
+			info.Annotations[key] = val
+		}
+	}
+
+	info.Variables = maps.Clone(md.Variables)
+
+	for _, host := range app.Hosts() {
+		if host.Hostname() != info.Hostname {
+			continue
+		}
+
+		var hmd GuestInfoAppHostMetadata
+
+		if err := mapstructure.Decode(host.Metadata(), &hmd); err != nil {
+			return info, fmt.Errorf("decoding guestinfo metadata for host %s: %w", info.Hostname, err)
+		}
+
+		if len(hmd.Variables) > 0 && info.Variables == nil {
+			info.Variables = make(map[string]any)
+		}
+
+		maps.Copy(info.Variables, hmd.Variables)
+	}
+
+	return info, nil
+}
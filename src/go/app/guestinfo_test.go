@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestGuestInfoApp(t *testing.T) {
+	baseDir := t.TempDir()
+
+	nodes := []*v1.Node{
+		{
+			TypeF:        "VirtualMachine",
+			LabelsF:      map[string]string{"tier": "web"},
+			AnnotationsF: map[string]any{"team": "blue", "internal": "x"},
+			GeneralF:     &v1.General{HostnameF: "web"},
+			HardwareF:    &v1.Hardware{OSTypeF: "linux"},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.5", MaskF: 24, GatewayF: "10.0.1.254"},
+				},
+			},
+		},
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "hmi"},
+			HardwareF: &v1.Hardware{OSTypeF: "windows"},
+		},
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "ext"},
+			HardwareF: &v1.Hardware{OSTypeF: "linux"},
+			ExternalF: func() *bool { b := true; return &b }(),
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "guestinfo",
+				MetadataF: map[string]any{
+					"annotations": []any{"team"},
+					"variables":   map[string]any{"range": "blue", "role": "generic"},
+				},
+				HostsF: []*v2.ScenarioAppHost{
+					{HostnameF: "web", MetadataF: map[string]any{"variables": map[string]any{"role": "webserver"}}},
+				},
+			},
+		},
+	}
+
+	exp := &types.Experiment{
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec: &v1.ExperimentSpec{
+			BaseDirF:  baseDir,
+			TopologyF: &v1.TopologySpec{NodesF: nodes},
+			ScenarioF: scenario,
+		},
+	}
+
+	if err := new(GuestInfoApp).Validate(exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := GetApp("guestinfo").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for i, dst := range []string{"/etc/phenix/guestinfo.json", "/phenix/guestinfo.json"} {
+		injects := nodes[i].Injections()
+
+		if len(injects) != 1 || injects[0].Dst() != dst {
+			t.Logf("expected guest info to be injected to %s for host %s", dst, nodes[i].General().Hostname())
+			t.FailNow()
+		}
+	}
+
+	if len(nodes[2].Injections()) != 0 {
+		t.Log("expected no guest info to be injected into external node")
+		t.FailNow()
+	}
+
+	body, err := os.ReadFile(baseDir + "/guestinfo/web.json")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var info GuestInfo
+
+	if err := json.Unmarshal(body, &info); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if info.Experiment != "test" || info.Hostname != "web" || info.OSType != "linux" || info.Labels["tier"] != "web" {
+		t.Logf("unexpected guest info identity: %+v", info)
+		t.FailNow()
+	}
+
+	if len(info.Annotations) != 1 || info.Annotations["team"] != "blue" {
+		t.Logf("expected only selected annotations in guest info, got %v", info.Annotations)
+		t.FailNow()
+	}
+
+	if info.Variables["range"] != "blue" || info.Variables["role"] != "webserver" {
+		t.Logf("expected host variables to override experiment variables, got %v", info.Variables)
+		t.FailNow()
+	}
+
+	if len(info.Interfaces) != 1 || info.Interfaces[0].Address != "10.0.1.5" || info.Interfaces[0].Gateway != "10.0.1.254" {
+		t.Logf("unexpected guest info interfaces: %+v", info.Interfaces)
+		t.FailNow()
+	}
+
+	// Host variables shouldn't leak into the experiment variables.
+	hmi, _ := NodeGuestInfo(exp, nodes[1])
+
+	if hmi.Variables["role"] != "generic" {
+		t.Logf("expected experiment variables for hmi, got %v", hmi.Variables)
+		t.FailNow()
+	}
+
+	scenario.AppsF[0].HostsF[0].HostnameF = "missing"
+
+	if err := new(GuestInfoApp).Validate(exp); err == nil {
+		t.Log("expected validation error for host not in topology")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"time"
+
+	"phenix/util/metrics"
+)
+
+var (
+	appDuration = metrics.NewHistogramVec(
+		"phenix_app_duration_seconds",
+		"Time taken to apply each app for each experiment lifecycle stage.",
+		metrics.DurationBuckets, "app", "stage", "status",
+	)
+
+	appFailures = metrics.NewCounterVec(
+		"phenix_app_failures_total",
+		"Number of times each app failed for each experiment lifecycle stage.",
+		"app", "stage",
+	)
+
+	stageDuration = metrics.NewHistogramVec(
+		"phenix_apply_apps_duration_seconds",
+		"Time taken to apply all apps for each experiment lifecycle stage.",
+		metrics.DurationBuckets, "stage", "status",
+	)
+)
+
+// observeApp records how long the given app took to apply for the given stage,
+// and whether it failed.
+func observeApp(name string, stage Action, started time.Time, err error) {
+	status := "success"
+
+	if err != nil {
+		status = "error"
+		appFailures.Inc(name, string(stage))
+	}
+
+	if !started.IsZero() {
+		appDuration.Observe(time.Since(started).Seconds(), name, string(stage), status)
+	}
+}
+
+// observeStage records how long all apps took to apply for the given stage.
+func observeStage(stage Action, started time.Time, err error) {
+	status := "success"
+
+	if err != nil {
+		status = "error"
+	}
+
+	stageDuration.Observe(time.Since(started).Seconds(), string(stage), status)
+}
@@ -0,0 +1,572 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+	"phenix/util/common"
+	"phenix/util/journal"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+func init() {
+	RegisterUserApp("netos", func() App { return new(NetOS) })
+}
+
+// DefaultNetOSBootTimeout is how long the netos app waits for a network
+// appliance to boot and apply its config unless overridden in the app metadata.
+const DefaultNetOSBootTimeout = 20 * time.Minute
+
+// DefaultNetOSRootPasswordHash is the encrypted root password (`phenix`) set on
+// vSRX nodes unless overridden in the host metadata, since Junos won't commit a
+// config without one.
+const DefaultNetOSRootPasswordHash = "$1$phenix$lkz3BHV7Oy/j4IhqMsXqG."
+
+// netOSPlatform describes how the VM for a network appliance OS type is
+// configured and how its interfaces are named.
+type netOSPlatform struct {
+	driver string
+	vcpu   int
+	memory int
+
+	// iface returns the appliance's name for the NIC at the given index.
+	iface func(int) string
+}
+
+var netOSPlatforms = map[string]netOSPlatform{
+	"iosv": {
+		driver: "e1000",
+		vcpu:   1,
+		memory: 512,
+		iface: func(i int) string {
+			return fmt.Sprintf("GigabitEthernet0/%d", i)
+		},
+	},
+	"veos": {
+		driver: "e1000",
+		vcpu:   1,
+		memory: 2048,
+		iface: func(i int) string {
+			if i == 0 {
+				return "Management1"
+			}
+
+			return fmt.Sprintf("Ethernet%d", i)
+		},
+	},
+	"vsrx": {
+		driver: "virtio-net-pci",
+		vcpu:   2,
+		memory: 4096,
+		iface: func(i int) string {
+			if i == 0 {
+				return "fxp0"
+			}
+
+			return fmt.Sprintf("ge-0/0/%d", i-1)
+		},
+	},
+}
+
+// IsNetOS returns true if the given OS type is a network appliance OS managed
+// by the netos app. Other apps shouldn't inject files into these nodes, since
+// their disks aren't Linux or Windows file systems.
+func IsNetOS(osType string) bool {
+	_, ok := netOSPlatforms[strings.ToLower(osType)]
+	return ok
+}
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: netos
+      metadata:
+        bootTimeout: 30m
+      hosts:
+      - hostname: rtr-1
+        metadata:
+          username: admin
+          password: s3cr3t
+          extraConfig:
+          - router ospf 1
+          - network 10.0.0.0 0.0.255.255 area 0
+      - hostname: fw-1
+        metadata:
+          rootPasswordHash: $6$...
+*/
+
+type NetOSAppMetadata struct {
+	BootTimeout string `mapstructure:"bootTimeout"`
+}
+
+type NetOSHostMetadata struct {
+	Username         string   `mapstructure:"username"`
+	Password         string   `mapstructure:"password"`
+	RootPasswordHash string   `mapstructure:"rootPasswordHash"`
+	ExtraConfig      []string `mapstructure:"extraConfig"`
+}
+
+// NetOSAppStatus tracks the zero-touch provisioning state of each network
+// appliance node, along with how the node's interfaces map to the appliance's
+// interface names.
+type NetOSAppStatus struct {
+	Hosts map[string]NetOSHostStatus `structs:"hosts" mapstructure:"hosts"`
+}
+
+type NetOSHostStatus struct {
+	Platform   string            `structs:"platform" mapstructure:"platform"`
+	State      string            `structs:"state" mapstructure:"state"`
+	Interfaces map[string]string `structs:"interfaces" mapstructure:"interfaces"`
+	Error      string            `structs:"error" mapstructure:"error"`
+}
+
+// netOSConfig is the data passed to the netos config templates.
+type netOSConfig struct {
+	Hostname         string
+	Username         string
+	Password         string
+	RootPasswordHash string
+	Domain           string
+	DNS              []string
+	NTP              []string
+	Interfaces       []netOSInterface
+	Routes           []netOSRoute
+	Gateway          string
+	Extra            []string
+}
+
+type netOSInterface struct {
+	Name        string
+	Description string
+	Proto       string
+	Address     string
+	Mask        int
+	Netmask     string
+	Management  bool
+}
+
+type netOSRoute struct {
+	Network string
+	Prefix  int
+	Netmask string
+	Next    string
+}
+
+// NetOS supports Cisco IOSv, Arista vEOS, and Juniper vSRX network appliance
+// nodes (os_type `iosv`, `veos`, and `vsrx`). It sizes each appliance's VM and
+// NICs for its platform, generates the appliance's config from the node's
+// topology, and pushes the config over the VM's serial console once it boots
+// since appliance disks can't have files injected into them.
+type NetOS struct{}
+
+func (NetOS) Init(...Option) error {
+	return nil
+}
+
+func (NetOS) Name() string {
+	return "netos"
+}
+
+func (NetOS) MetadataSchema() []byte {
+	return []byte(`
+type: object
+properties:
+  bootTimeout:
+    type: string
+    pattern: '^([0-9]+(\.[0-9]+)?(ns|us|ms|s|m|h))+$'
+additionalProperties: false
+`)
+}
+
+func (this NetOS) Configure(ctx context.Context, exp *types.Experiment) error {
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() {
+			continue
+		}
+
+		platform, ok := netOSPlatforms[strings.ToLower(node.Hardware().OSType())]
+		if !ok {
+			continue
+		}
+
+		if node.Hardware().VCPU() < platform.vcpu {
+			node.Hardware().SetVCPU(platform.vcpu)
+		}
+
+		if node.Hardware().Memory() < platform.memory {
+			node.Hardware().SetMemory(platform.memory)
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Type() != "serial" && iface.Driver() == "" {
+				iface.SetDriver(platform.driver)
+			}
+		}
+
+		// The config is pushed over the appliance's first serial port, which is
+		// also its console.
+		if _, ok := node.Advanced()["serial-ports"]; !ok {
+			node.AddAdvanced("serial-ports", "1")
+		}
+	}
+
+	return nil
+}
+
+func (this NetOS) PreStart(ctx context.Context, exp *types.Experiment) error {
+	site, err := types.SiteSettings()
+	if err != nil {
+		return fmt.Errorf("getting site settings: %w", err)
+	}
+
+	status := NetOSAppStatus{Hosts: make(map[string]NetOSHostStatus)}
+
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() {
+			continue
+		}
+
+		var (
+			osType       = strings.ToLower(node.Hardware().OSType())
+			platform, ok = netOSPlatforms[osType]
+			host         = node.General().Hostname()
+			md           NetOSHostMetadata
+		)
+
+		if !ok {
+			continue
+		}
+
+		// Host metadata is optional, so appliances not listed in the app's hosts
+		// are configured from the topology alone.
+		if app := exp.App(this.Name()); app != nil {
+			for _, h := range app.Hosts() {
+				if h.Hostname() != host {
+					continue
+				}
+
+				if err := h.ParseMetadata(&md); err != nil {
+					return fmt.Errorf("decoding %s app metadata for host %s: %w", this.Name(), host, err)
+				}
+			}
+		}
+
+		config := netOSNodeConfig(node, platform, md, site)
+
+		if osType == "vsrx" && config.RootPasswordHash == "" {
+			config.RootPasswordHash = DefaultNetOSRootPasswordHash
+		}
+
+		if err := tmpl.CreateFileFromTemplate("netos_"+osType+".tmpl", config, netOSConfigFile(exp, host)); err != nil {
+			return fmt.Errorf("generating %s config for host %s: %w", osType, host, err)
+		}
+
+		mapping := make(map[string]string)
+
+		for i, iface := range netOSInterfaces(node) {
+			mapping[iface.Name()] = platform.iface(i)
+		}
+
+		status.Hosts[host] = NetOSHostStatus{Platform: osType, State: "configured", Interfaces: mapping}
+	}
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	return nil
+}
+
+// PostStart pushes the generated config to each network appliance over its
+// serial console, waiting for each appliance to boot, apply its config, and
+// present a CLI prompt with its configured hostname.
+func (this NetOS) PostStart(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	var status NetOSAppStatus
+	if err := exp.Status.ParseAppStatus(this.Name(), &status); err != nil || len(status.Hosts) == 0 {
+		return nil
+	}
+
+	timeout, err := this.bootTimeout(exp)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		ready int
+		errs  []string
+	)
+
+	for host, hs := range status.Hosts {
+		wg.Add(1)
+
+		go func(host string, hs NetOSHostStatus) {
+			defer wg.Done()
+
+			err := this.provision(ctx, exp, host, hs.Platform, timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				hs.State = "failed"
+				hs.Error = err.Error()
+
+				errs = append(errs, fmt.Sprintf("host %s: %v", host, err))
+				journal.RecordWithDetails(exp.Metadata.Name, journal.CategoryApp, host, map[string]string{"error": err.Error()}, "provisioning %s appliance failed", hs.Platform)
+			} else {
+				hs.State = "ready"
+				ready++
+
+				journal.Record(exp.Metadata.Name, journal.CategoryApp, host, "%s appliance provisioned and ready", hs.Platform)
+				ReportProgress(ctx, ready*100/len(status.Hosts), "%s is ready (%d of %d appliances)", host, ready, len(status.Hosts))
+			}
+
+			status.Hosts[host] = hs
+		}(host, hs)
+	}
+
+	wg.Wait()
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("provisioning network appliances: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (NetOS) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (NetOS) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// provision starts a script on the cluster host the given appliance VM is
+// running on that pushes the appliance's config over its serial console, then
+// polls the script's status file until it reports the appliance is ready or
+// failed. The script is run in the background since minimega only processes
+// one command at a time.
+func (this NetOS) provision(ctx context.Context, exp *types.Experiment, host, platform string, timeout time.Duration) error {
+	vms := mm.GetVMInfo(mm.NS(exp.Metadata.Name), mm.VMName(host))
+	if len(vms) == 0 {
+		return fmt.Errorf("VM not found")
+	}
+
+	var (
+		vm     = vms[0]
+		vmDir  = fmt.Sprintf("%s/%d", common.MinimegaBase, vm.ID)
+		script = vmDir + "/phenix-netos.sh"
+		state  = vmDir + "/phenix-netos.status"
+	)
+
+	lines, err := netOSConfigLines(netOSConfigFile(exp, host), platform)
+	if err != nil {
+		return fmt.Errorf("reading generated config: %w", err)
+	}
+
+	data := map[string]any{
+		"Hostname": host,
+		"Platform": platform,
+		"Socket":   vmDir + "/serial0",
+		"Status":   state,
+		"Timeout":  int(timeout.Seconds()),
+		"Config":   lines,
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.GenerateFromTemplate("netos_serial.tmpl", data, &buf); err != nil {
+		return fmt.Errorf("generating serial console script: %w", err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	cmd := fmt.Sprintf(`bash -c "echo %s | base64 -d > %s && setsid nohup bash %s > /dev/null 2>&1 &"`, encoded, script, script)
+
+	if err := mm.MeshShell(vm.Host, cmd); err != nil {
+		return fmt.Errorf("starting serial console script on host %s: %w", vm.Host, err)
+	}
+
+	plog.Info("provisioning network appliance over serial console", "exp", exp.Metadata.Name, "vm", host, "platform", platform, "host", vm.Host)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for appliance to become ready: %w", ctx.Err())
+		case <-ticker.C:
+		}
+
+		out, _ := mm.MeshShellResponse(vm.Host, fmt.Sprintf(`bash -c "cat %s 2> /dev/null"`, state))
+		out = strings.TrimSpace(out)
+
+		switch {
+		case out == "ready":
+			return nil
+		case strings.HasPrefix(out, "failed"):
+			return fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(out, "failed:")))
+		}
+	}
+}
+
+func (this NetOS) bootTimeout(exp *types.Experiment) (time.Duration, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return DefaultNetOSBootTimeout, nil
+	}
+
+	var md NetOSAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return 0, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	if md.BootTimeout == "" {
+		return DefaultNetOSBootTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(md.BootTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bootTimeout for %s app: %w", this.Name(), err)
+	}
+
+	return timeout, nil
+}
+
+// netOSInterfaces returns the given node's interfaces that are NICs, in the
+// order they're attached to the VM.
+func netOSInterfaces(node ifaces.NodeSpec) []ifaces.NodeNetworkInterface {
+	var nics []ifaces.NodeNetworkInterface
+
+	for _, iface := range node.Network().Interfaces() {
+		if iface.Type() != "serial" {
+			nics = append(nics, iface)
+		}
+	}
+
+	return nics
+}
+
+// netOSNodeConfig converts the given node's network settings to the data passed
+// to the config template for its platform.
+func netOSNodeConfig(node ifaces.NodeSpec, platform netOSPlatform, md NetOSHostMetadata, site *v1.SiteSpec) netOSConfig {
+	config := netOSConfig{
+		Hostname:         node.General().Hostname(),
+		Username:         md.Username,
+		Password:         md.Password,
+		RootPasswordHash: md.RootPasswordHash,
+		Domain:           site.Domain,
+		NTP:              site.NTP,
+		Extra:            md.ExtraConfig,
+	}
+
+	dns := make(map[string]struct{})
+
+	for i, iface := range netOSInterfaces(node) {
+		ni := netOSInterface{
+			Name:        platform.iface(i),
+			Description: iface.VLAN(),
+			Proto:       strings.ToLower(iface.Proto()),
+			Address:     iface.Address(),
+			Mask:        iface.Mask(),
+			Netmask:     net.IP(net.CIDRMask(iface.Mask(), 32)).String(),
+			Management:  platform.iface(i) == "Management1" || platform.iface(i) == "fxp0",
+		}
+
+		config.Interfaces = append(config.Interfaces, ni)
+
+		if iface.Gateway() != "" && config.Gateway == "" {
+			config.Gateway = iface.Gateway()
+		}
+
+		for _, server := range iface.DNS() {
+			if _, ok := dns[server]; !ok {
+				dns[server] = struct{}{}
+				config.DNS = append(config.DNS, server)
+			}
+		}
+	}
+
+	if len(config.DNS) == 0 {
+		config.DNS = site.DNS
+	}
+
+	for _, route := range node.Network().Routes() {
+		_, network, err := net.ParseCIDR(route.Destination())
+		if err != nil {
+			continue
+		}
+
+		prefix, _ := network.Mask.Size()
+
+		config.Routes = append(config.Routes, netOSRoute{
+			Network: network.IP.String(),
+			Prefix:  prefix,
+			Netmask: net.IP(network.Mask).String(),
+			Next:    route.Next(),
+		})
+	}
+
+	return config
+}
+
+func netOSConfigFile(exp *types.Experiment, host string) string {
+	return filepath.Join(exp.Spec.BaseDir(), "netos", host+".cfg")
+}
+
+// netOSConfigLines returns the lines of the given generated config to type into
+// the appliance's CLI, skipping blank lines and comments.
+func netOSConfigLines(path, platform string) ([]string, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := "!"
+
+	if platform == "vsrx" {
+		comment = "#"
+	}
+
+	var (
+		lines   []string
+		scanner = bufio.NewScanner(bytes.NewReader(body))
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), comment) {
+			continue
+		}
+
+		// Single quote each line so it can be safely included in the serial
+		// console script.
+		lines = append(lines, "'"+strings.ReplaceAll(line, "'", `'\''`)+"'")
+	}
+
+	return lines, scanner.Err()
+}
@@ -0,0 +1,317 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func netOSTestExperiment(baseDir string) *types.Experiment {
+	nodes := []*v1.Node{
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "rtr-1"},
+			HardwareF: &v1.Hardware{OSTypeF: "veos", VCPUF: 1, MemoryF: 512},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "mgmt", VLANF: "MGMT", ProtoF: "dhcp"},
+					{NameF: "eth1", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.1", MaskF: 24, GatewayF: "10.0.1.254", DNSF: []string{"10.0.1.53"}},
+					{NameF: "eth2", VLANF: "EXP_2", ProtoF: "static", AddressF: "10.0.2.1", MaskF: 24, DriverF: "virtio-net-pci"},
+				},
+				RoutesF: []v1.Route{{DestinationF: "192.168.0.0/16", NextF: "10.0.2.254"}},
+			},
+		},
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "fw-1"},
+			HardwareF: &v1.Hardware{OSTypeF: "vSRX", VCPUF: 4, MemoryF: 8192},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "mgmt", VLANF: "MGMT", ProtoF: "dhcp"},
+					{NameF: "console", TypeF: "serial", DeviceF: "/dev/ttyS1"},
+					{NameF: "eth1", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.2", MaskF: 24},
+				},
+			},
+			AdvancedF: map[string]string{"serial-ports": "2"},
+		},
+		{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: "host-1"},
+			HardwareF: &v1.Hardware{OSTypeF: "linux", VCPUF: 1, MemoryF: 512},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.10", MaskF: 24},
+				},
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "netos",
+				HostsF: []*v2.ScenarioAppHost{
+					{
+						HostnameF: "rtr-1",
+						MetadataF: map[string]any{
+							"username":    "admin",
+							"password":    "s3cr3t",
+							"extraConfig": []any{"router ospf 1"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	return &types.Experiment{Spec: spec, Status: new(v1.ExperimentStatus)}
+}
+
+func TestNetOSAppConfigure(t *testing.T) {
+	exp := netOSTestExperiment(t.TempDir())
+
+	if err := GetApp("netos").Configure(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	nodes := exp.Spec.Topology().Nodes()
+
+	// Appliances are sized up to their platform minimum, but never down.
+	if hw := nodes[0].Hardware(); hw.VCPU() != 1 || hw.Memory() != 2048 {
+		t.Logf("expected vEOS node to be sized to 1 VCPU and 2048 MB, got %d and %d", hw.VCPU(), hw.Memory())
+		t.FailNow()
+	}
+
+	if hw := nodes[1].Hardware(); hw.VCPU() != 4 || hw.Memory() != 8192 {
+		t.Logf("expected vSRX node sizing to be kept, got %d VCPUs and %d MB", hw.VCPU(), hw.Memory())
+		t.FailNow()
+	}
+
+	if hw := nodes[2].Hardware(); hw.Memory() != 512 {
+		t.Logf("expected non-appliance node to be left alone, got %d MB", hw.Memory())
+		t.FailNow()
+	}
+
+	var (
+		veos = nodes[0].Network().Interfaces()
+		vsrx = nodes[1].Network().Interfaces()
+	)
+
+	if veos[0].Driver() != "e1000" || veos[1].Driver() != "e1000" || veos[2].Driver() != "virtio-net-pci" {
+		t.Logf("expected vEOS NICs without a driver to use e1000, got %s, %s, %s", veos[0].Driver(), veos[1].Driver(), veos[2].Driver())
+		t.FailNow()
+	}
+
+	if vsrx[0].Driver() != "virtio-net-pci" || vsrx[1].Driver() != "" {
+		t.Logf("expected vSRX NICs (but not serial interfaces) to use virtio-net-pci, got %s, %s", vsrx[0].Driver(), vsrx[1].Driver())
+		t.FailNow()
+	}
+
+	if ports := nodes[0].Advanced()["serial-ports"]; ports != "1" {
+		t.Logf("expected vEOS node to get a serial port, got %q", ports)
+		t.FailNow()
+	}
+
+	if ports := nodes[1].Advanced()["serial-ports"]; ports != "2" {
+		t.Logf("expected existing serial ports to be kept, got %q", ports)
+		t.FailNow()
+	}
+
+	if _, ok := nodes[2].Advanced()["serial-ports"]; ok {
+		t.Log("expected non-appliance node not to get a serial port")
+		t.FailNow()
+	}
+}
+
+func TestNetOSAppPreStart(t *testing.T) {
+	initTestStore(t)
+
+	site := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Site",
+		Metadata: store.ConfigMetadata{Name: types.DEFAULT_SITE},
+		Spec:     map[string]any{"domain": "exp.local", "dns": []any{"8.8.8.8"}, "ntp": []any{"10.0.0.123"}},
+	}
+
+	if err := store.Create(site); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	baseDir := t.TempDir()
+	exp := netOSTestExperiment(baseDir)
+
+	if err := GetApp("netos").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, "netos", "host-1.cfg")); err == nil {
+		t.Log("expected no config to be generated for non-appliance node")
+		t.FailNow()
+	}
+
+	veos, err := os.ReadFile(filepath.Join(baseDir, "netos", "rtr-1.cfg"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, e := range []string{
+		"hostname rtr-1",
+		"dns domain exp.local",
+		"ip name-server vrf default 10.0.1.53",
+		"ntp server 10.0.0.123",
+		"username admin privilege 15 secret s3cr3t",
+		"interface Management1",
+		"interface Ethernet1",
+		" ip address 10.0.1.1/24",
+		"interface Ethernet2",
+		"ip route 192.168.0.0/16 10.0.2.254",
+		"ip route 0.0.0.0/0 10.0.1.254",
+		"router ospf 1",
+	} {
+		if !strings.Contains(string(veos), e) {
+			t.Logf("expected vEOS config to contain %q, got: %s", e, veos)
+			t.FailNow()
+		}
+	}
+
+	// DNS servers configured on interfaces take precedence over the site's.
+	if strings.Contains(string(veos), "8.8.8.8") {
+		t.Logf("expected site DNS servers not to be used, got: %s", veos)
+		t.FailNow()
+	}
+
+	vsrx, err := os.ReadFile(filepath.Join(baseDir, "netos", "fw-1.cfg"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, e := range []string{
+		"set system host-name fw-1",
+		`set system root-authentication encrypted-password "` + DefaultNetOSRootPasswordHash + `"`,
+		"set system name-server 8.8.8.8",
+		"set interfaces fxp0 unit 0 family inet dhcp",
+		"set interfaces ge-0/0/0 unit 0 family inet address 10.0.1.2/24",
+	} {
+		if !strings.Contains(string(vsrx), e) {
+			t.Logf("expected vSRX config to contain %q, got: %s", e, vsrx)
+			t.FailNow()
+		}
+	}
+
+	var status NetOSAppStatus
+
+	if err := exp.Status.ParseAppStatus("netos", &status); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(status.Hosts) != 2 {
+		t.Logf("expected status for 2 appliances, got %v", status.Hosts)
+		t.FailNow()
+	}
+
+	expected := map[string]map[string]string{
+		"rtr-1": {"mgmt": "Management1", "eth1": "Ethernet1", "eth2": "Ethernet2"},
+		"fw-1":  {"mgmt": "fxp0", "eth1": "ge-0/0/0"},
+	}
+
+	for host, mapping := range expected {
+		hs := status.Hosts[host]
+
+		if hs.State != "configured" {
+			t.Logf("expected %s to be configured, got %s", host, hs.State)
+			t.FailNow()
+		}
+
+		if len(hs.Interfaces) != len(mapping) {
+			t.Logf("expected %d interfaces mapped for %s, got %v", len(mapping), host, hs.Interfaces)
+			t.FailNow()
+		}
+
+		for iface, name := range mapping {
+			if hs.Interfaces[iface] != name {
+				t.Logf("expected %s interface %s to map to %s, got %s", host, iface, name, hs.Interfaces[iface])
+				t.FailNow()
+			}
+		}
+	}
+
+	// Configs aren't pushed to appliances for dry runs.
+	exp.Status.SetStartTime("2026-01-01T00:00:00Z-DRYRUN")
+
+	if err := GetApp("netos").PostStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := exp.Status.ParseAppStatus("netos", &status); err != nil || status.Hosts["rtr-1"].State != "configured" {
+		t.Logf("expected appliance status to be left alone for dry run, got %v", status.Hosts)
+		t.FailNow()
+	}
+}
+
+func TestNetOSConfigLines(t *testing.T) {
+	var (
+		dir   = t.TempDir()
+		ios   = filepath.Join(dir, "rtr.cfg")
+		junos = filepath.Join(dir, "fw.cfg")
+	)
+
+	os.WriteFile(ios, []byte("! Generated by the phenix netos app.\nhostname rtr-1\n\ninterface Ethernet1\n description it's a link  \n !\n"), 0600)
+	os.WriteFile(junos, []byte("# Generated by the phenix netos app.\nset system host-name fw-1\n"), 0600)
+
+	lines, err := netOSConfigLines(ios, "veos")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{"'hostname rtr-1'", "'interface Ethernet1'", `' description it'\''s a link'`}
+
+	if len(lines) != len(expected) {
+		t.Logf("expected %d config lines, got %v", len(expected), lines)
+		t.FailNow()
+	}
+
+	for i, e := range expected {
+		if lines[i] != e {
+			t.Logf("expected config line %d to be %s, got %s", i, e, lines[i])
+			t.FailNow()
+		}
+	}
+
+	lines, err = netOSConfigLines(junos, "vsrx")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(lines) != 1 || lines[0] != "'set system host-name fw-1'" {
+		t.Logf("expected Junos comments to be skipped, got %v", lines)
+		t.FailNow()
+	}
+
+	if !IsNetOS("vEOS") || IsNetOS("linux") {
+		t.Log("expected only network appliance OS types to be netos managed")
+		t.FailNow()
+	}
+}
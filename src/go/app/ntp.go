@@ -65,7 +65,7 @@ func (NTP) Configure(ctx context.Context, exp *types.Experiment) error {
 	return nil
 }
 
-func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
+func (this NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 	var (
 		ntpDir  = exp.Spec.BaseDir() + "/ntp"
 		servers = exp.Spec.Topology().FindNodesWithLabels("ntp-server")
@@ -86,6 +86,23 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 				// Might be an empty string, but that's okay... for now.
 				defaultSource := amd.DefaultSource.IPAddress(exp)
 
+				// Fall back to the site's NTP sources, if any, for hosts that don't
+				// have a source configured.
+				site, err := types.SiteSettings()
+				if err != nil {
+					return fmt.Errorf("getting site settings: %w", err)
+				}
+
+				var siteSource string
+
+				if len(site.NTP) > 0 {
+					siteSource = site.NTP[0]
+				}
+
+				if defaultSource == "" {
+					defaultSource = siteSource
+				}
+
 				for _, host := range app.Hosts() {
 					node := exp.Spec.Topology().FindNodeByName(host.Hostname())
 					if node == nil {
@@ -103,7 +120,7 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 					if hmd.Client != "" {
 						if source == "" {
 							if defaultSource == "" {
-								return fmt.Errorf("no NTP source configured for host %s (and no default source configured)", host.Hostname())
+								return fmt.Errorf("no NTP source configured for host %s (and no default or site source configured)", host.Hostname())
 							}
 
 							source = defaultSource
@@ -136,11 +153,16 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 					}
 
 					if hmd.Server != "" {
+						if source == "" {
+							source = siteSource
+						}
+
 						switch strings.ToLower(hmd.Server) {
 						case "ntpd":
-							// It's okay if `source` is an empty string here. If it is, the
-							// template will generate a config for the NTP server that prefers
-							// the host's clock as the source.
+							// It's okay if `source` is an empty string here (no source
+							// configured for the host or the site). If it is, the template
+							// will generate a config for the NTP server that prefers the
+							// host's clock as the source.
 							if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", source, cfg); err != nil {
 								return fmt.Errorf("generating NTP server config for host %s: %w", host.Hostname(), err)
 							}
@@ -178,7 +200,7 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 	}
 
 	// Configure topology nodes as NTP clients.
-	for _, node := range exp.Spec.Topology().Nodes() {
+	for _, node := range TargetNodes(exp, this.Name()) {
 		if _, ok := node.Labels()["ntp-server"]; ok {
 			// Don't configure NTP server nodes as clients.
 			continue
@@ -188,6 +210,11 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 			continue
 		}
 
+		// Network appliances get their NTP servers from the netos app instead.
+		if IsNetOS(node.Hardware().OSType()) {
+			continue
+		}
+
 		ntpFile := ntpDir + "/" + node.General().Hostname() + "_ntp"
 
 		if strings.EqualFold(node.Type(), "router") {
@@ -1,5 +1,7 @@
 package app
 
+import "time"
+
 // Option is a function that configures options for a phenix app. It is used in
 // `app.Init`.
 type Option func(*Options)
@@ -10,12 +12,42 @@ type Options struct {
 	Name   string // used to set the app name
 	DryRun bool
 	Filter map[string]struct{}
+
+	// Apps to skip entirely, and whether to skip scenario apps marked as
+	// optional (used by experiment start profiles).
+	Skip         map[string]struct{}
+	SkipOptional bool
+
+	// How long all apps together are allowed to run for the stage, and how long
+	// each app is allowed to run for the stage, by default and for specific
+	// apps. Zero means there's no limit.
+	StageTimeout time.Duration
+	AppTimeout   time.Duration
+	AppTimeouts  map[string]time.Duration
+
+	// How failed apps are retried for the stage, by default and for specific
+	// apps.
+	AppRetry   RetryPolicy
+	AppRetries map[string]RetryPolicy
+
+	// Called with each progress event emitted while applying apps for the stage.
+	// Progress events are logged if nil.
+	OnProgress ProgressHandler
+
+	// Called with the diff of the changes each app made to the experiment when
+	// DryRun is set. When set, apps are previewed against the given experiment
+	// without recording their runs anywhere else.
+	OnDiff DiffHandler
 }
 
 // NewOptions returns an Options struct initialized with the given option list.
 func NewOptions(opts ...Option) Options {
 	o := Options{
 		Filter: make(map[string]struct{}),
+		Skip:   make(map[string]struct{}),
+
+		AppTimeouts: make(map[string]time.Duration),
+		AppRetries:  make(map[string]RetryPolicy),
 	}
 
 	for _, opt := range opts {
@@ -54,3 +86,78 @@ func FilterApp(a ...string) Option {
 		}
 	}
 }
+
+// SkipApp adds an app(s) to the list of apps to skip.
+func SkipApp(a ...string) Option {
+	return func(o *Options) {
+		for _, n := range a {
+			o.Skip[n] = struct{}{}
+		}
+	}
+}
+
+// SkipOptional sets whether scenario apps marked as optional are skipped.
+func SkipOptional(s bool) Option {
+	return func(o *Options) {
+		o.SkipOptional = s
+	}
+}
+
+// StageTimeout sets how long all apps together are allowed to run for the
+// stage before being canceled.
+func StageTimeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.StageTimeout = t
+	}
+}
+
+// AppTimeout sets how long the given app(s) are allowed to run for the stage
+// before being canceled, or how long each app is allowed to run by default if
+// no apps are given.
+func AppTimeout(t time.Duration, a ...string) Option {
+	return func(o *Options) {
+		if len(a) == 0 {
+			o.AppTimeout = t
+			return
+		}
+
+		for _, n := range a {
+			o.AppTimeouts[n] = t
+		}
+	}
+}
+
+// AppRetry sets how many times the given app(s) are retried for the stage if
+// they fail, and how long to wait before the first retry (doubled for each
+// retry after it), or the default retry policy for each app if no apps are
+// given.
+func AppRetry(retries int, backoff time.Duration, a ...string) Option {
+	return func(o *Options) {
+		policy := RetryPolicy{Retries: retries, Backoff: backoff}
+
+		if len(a) == 0 {
+			o.AppRetry = policy
+			return
+		}
+
+		for _, n := range a {
+			o.AppRetries[n] = policy
+		}
+	}
+}
+
+// OnProgress sets the handler called with each progress event emitted while
+// applying apps for the stage, replacing the default of logging them.
+func OnProgress(h ProgressHandler) Option {
+	return func(o *Options) {
+		o.OnProgress = h
+	}
+}
+
+// OnDiff sets the handler called with the diff of the changes each app made to
+// the experiment when previewing a dry run.
+func OnDiff(h DiffHandler) Option {
+	return func(o *Options) {
+		o.OnDiff = h
+	}
+}
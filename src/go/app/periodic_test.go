@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"phenix/types"
+)
+
+func TestPeriodicallyRunApps(t *testing.T) {
+	var (
+		runs = make(chan int32, 100)
+		n    int32
+	)
+
+	// The first run hangs until it times out, which shouldn't keep later runs
+	// from happening.
+	poll := func(ctx context.Context, _ *types.Experiment) error {
+		run := atomic.AddInt32(&n, 1)
+
+		if run == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		runs <- run
+		return nil
+	}
+
+	registerTestApp(t, "poll", poll)
+
+	exp := newTestExperiment(t,
+		map[string]any{"name": "poll", "runPeriodically": "20ms", "metadata": map[string]any{TIMEOUT_KEY: "50ms"}},
+		map[string]any{"name": "once"},
+	)
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		wg          sync.WaitGroup
+	)
+
+	defer cancel()
+
+	if err := PeriodicallyRunApps(ctx, &wg, exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	timeout := time.After(5 * time.Second)
+
+	for done := false; !done; {
+		select {
+		case run := <-runs:
+			done = run >= 3
+		case <-timeout:
+			t.Logf("expected periodic runs to continue after a timed out run, got %d runs", atomic.LoadInt32(&n))
+			t.FailNow()
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	if exp.Status.AppRunning()["poll"] {
+		t.Log("expected app to no longer be marked running once stopped")
+		t.FailNow()
+	}
+
+	if freq := exp.Status.AppFrequency()["poll"]; freq != "" {
+		t.Logf("expected app frequency to be cleared once stopped, got %s", freq)
+		t.FailNow()
+	}
+
+	run := exp.Status.AppRuns()["poll"][string(ACTIONRUNNING)]
+	if run == nil || run.Result() != "success" {
+		t.Logf("expected last periodic run to be recorded as a success, got %v", run)
+		t.FailNow()
+	}
+
+	if _, ok := exp.Status.AppRuns()["once"]; ok {
+		t.Log("expected app without a period not to be run")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// PLUGIN_SYMBOL is the symbol Go plugin apps must export. It must be a function
+// with the signature `func() app.App` that returns a new instance of the app,
+// and is registered using the app's name.
+const PLUGIN_SYMBOL = "NewApp"
+
+// ErrPluginsUnsupported is returned when loading plugin apps with a phenix
+// binary that can't load Go plugins, such as the release build, which is built
+// with CGO_ENABLED=0.
+var ErrPluginsUnsupported = errors.New("phenix binary was built without Go plugin support (requires CGO_ENABLED=1 on linux)")
+
+var (
+	// Tracks plugins already loaded, keyed by path, since a plugin can only be
+	// opened once per process.
+	plugins   = make(map[string]string)
+	pluginsMu sync.Mutex
+)
+
+// LoadPlugins loads each Go plugin app (`.so` file) in the given directory and
+// registers it alongside the internal apps, returning the names of the apps
+// loaded. Plugins must be built with `go build -buildmode=plugin` using the
+// same Go toolchain and phenix source as the phenix binary. Plugins that fail
+// to load (or whose app name is already registered) are skipped, and their
+// errors are returned together once all plugins have been tried. If this
+// phenix binary doesn't support Go plugins, ErrPluginsUnsupported is returned
+// without trying any of them.
+func LoadPlugins(dir string) ([]string, error) {
+	if !pluginsSupported {
+		return nil, ErrPluginsUnsupported
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("checking app plugin directory %s: %w", dir, err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.so"))
+	if err != nil {
+		return nil, fmt.Errorf("finding app plugins in %s: %w", dir, err)
+	}
+
+	sort.Strings(paths)
+
+	var (
+		names []string
+		errs  error
+	)
+
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	for _, path := range paths {
+		if name, ok := plugins[path]; ok {
+			names = append(names, name)
+			continue
+		}
+
+		name, err := loadPlugin(path)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("loading app plugin %s: %w", path, err))
+			continue
+		}
+
+		plugins[path] = name
+		names = append(names, name)
+
+		plog.Debug("loaded app plugin", "app", name, "path", path)
+	}
+
+	return names, errs
+}
+
+func loadPlugin(path string) (string, error) {
+	sym, err := lookupPluginSymbol(path, PLUGIN_SYMBOL)
+	if err != nil {
+		return "", err
+	}
+
+	var factory AppFactory
+
+	switch f := sym.(type) {
+	case func() App:
+		factory = f
+	case *func() App:
+		factory = *f
+	default:
+		return "", fmt.Errorf("%s symbol must be a func() app.App (got %T)", PLUGIN_SYMBOL, sym)
+	}
+
+	name := strings.TrimSpace(factory().Name())
+
+	if name == "" {
+		return "", fmt.Errorf("app name is required")
+	}
+
+	if err := RegisterUserApp(name, factory); err != nil {
+		return "", fmt.Errorf("registering app %s: %w", name, err)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,16 @@
+//go:build cgo && (linux || darwin || freebsd)
+
+package app
+
+import "plugin"
+
+const pluginsSupported = true
+
+func lookupPluginSymbol(path, symbol string) (plugin.Symbol, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Lookup(symbol)
+}
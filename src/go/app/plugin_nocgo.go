@@ -0,0 +1,11 @@
+//go:build !cgo || !(linux || darwin || freebsd)
+
+package app
+
+// The standard library's plugin package requires cgo, and without it every
+// call to plugin.Open fails, so plugin apps are rejected up front instead.
+const pluginsSupported = false
+
+func lookupPluginSymbol(path, symbol string) (any, error) {
+	return nil, ErrPluginsUnsupported
+}
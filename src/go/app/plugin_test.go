@@ -0,0 +1,45 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPlugins(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "bogus.so"), []byte("not a plugin"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	names, err := LoadPlugins(dir)
+
+	if !pluginsSupported {
+		// Binaries built without cgo must reject plugins with a clear error rather
+		// than failing to open each one.
+		if !errors.Is(err, ErrPluginsUnsupported) {
+			t.Logf("expected plugins unsupported error, got %v", err)
+			t.FailNow()
+		}
+
+		return
+	}
+
+	if err == nil || errors.Is(err, ErrPluginsUnsupported) {
+		t.Logf("expected error loading bogus plugin, got %v", err)
+		t.FailNow()
+	}
+
+	if len(names) != 0 {
+		t.Logf("expected no plugins to be loaded, got %v", names)
+		t.FailNow()
+	}
+
+	if _, err := LoadPlugins(filepath.Join(dir, "missing")); err == nil {
+		t.Log("expected error for missing plugin directory")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/util/plog"
+	"phenix/util/pubsub"
+)
+
+// PROGRESS_PREFIX prefixes the lines custom user apps write to STDERR to report
+// progress during a stage, followed by an optional percent complete (0-100) and
+// a message, ie. `PHENIX_PROGRESS 40 generating router configs`.
+const PROGRESS_PREFIX = "PHENIX_PROGRESS"
+
+// Progress is an incremental progress update reported by an app while it's
+// being applied for a stage. Percent is -1 if the app didn't report how
+// complete the stage is.
+type Progress struct {
+	Percent int    `json:"percent"`
+	Message string `json:"message"`
+}
+
+type progressKey struct{}
+
+// ReportProgress reports progress for the app being applied with the given
+// context. The percent should be between 0 and 100, or -1 if unknown. Progress
+// is emitted as a progress event for the app, and is silently ignored if the
+// context wasn't created for applying an app.
+func ReportProgress(ctx context.Context, percent int, format string, args ...any) {
+	report, ok := ctx.Value(progressKey{}).(func(Progress))
+	if !ok {
+		return
+	}
+
+	if percent > 100 {
+		percent = 100
+	}
+
+	if percent < -1 {
+		percent = -1
+	}
+
+	report(Progress{Percent: percent, Message: fmt.Sprintf(format, args...)})
+}
+
+// ProgressEvent is a structured event emitted as each app is applied to an
+// experiment for a stage. Status is one of start, success, error, skipped,
+// retrying, or progress. Kind is either default or user.
+type ProgressEvent struct {
+	Experiment string
+	App        string
+	Kind       string
+	Stage      Action
+	Status     string
+	Message    string
+	Error      error
+	Timestamp  time.Time
+
+	// Set when Status is progress.
+	Progress *Progress
+}
+
+// ProgressHandler is called with each progress event emitted while applying
+// apps. It's called synchronously, so it should not block for long.
+type ProgressHandler func(ProgressEvent)
+
+// LogProgress is the default progress handler, logging each event.
+func LogProgress(e ProgressEvent) {
+	var (
+		app  = fmt.Sprintf("'%s' %s app (%s)", e.App, e.Kind, e.Stage)
+		args = []any{"exp", e.Experiment}
+	)
+
+	if e.Error != nil {
+		args = append(args, "err", e.Error)
+	}
+
+	switch e.Status {
+	case "success":
+		plog.Info("[✓] "+app, args...)
+	case "error":
+		if errors.Is(e.Error, ErrUserAppNotFound) {
+			plog.Warn("[?] "+app, args...)
+		} else {
+			plog.Error("[✗] "+app, args...)
+		}
+	case "skipped", "retrying":
+		symbol := "[-] "
+
+		if e.Status == "retrying" {
+			symbol = "[↻] "
+		}
+
+		plog.Warn(symbol+app+" "+e.Message, args...)
+	case "progress":
+		if e.Progress.Percent < 0 {
+			plog.Info(fmt.Sprintf("[…] %s: %s", app, e.Progress.Message), args...)
+		} else {
+			plog.Info(fmt.Sprintf("[…] %s %d%%: %s", app, e.Progress.Percent, e.Progress.Message), args...)
+		}
+	}
+}
+
+// progressPublisher returns a function that publishes progress events for apps
+// applied to the given experiment for the given stage to the given handler
+// (LogProgress if nil), and to web clients (via the trigger-app topic) if
+// broadcast is true.
+func progressPublisher(exp string, stage Action, handler ProgressHandler, broadcast bool) func(ProgressEvent) {
+	if handler == nil {
+		handler = LogProgress
+	}
+
+	return func(e ProgressEvent) {
+		e.Experiment = exp
+		e.Stage = stage
+		e.Timestamp = time.Now()
+
+		if e.Kind == "" {
+			if isDefaultApp(e.App) {
+				e.Kind = "default"
+			} else {
+				e.Kind = "user"
+			}
+		}
+
+		if broadcast {
+			pubsub.Publish("trigger-app", TriggerPublication{
+				Experiment: exp,
+				App:        e.App,
+				Stage:      stage,
+				State:      e.Status,
+				Error:      e.Error,
+				Progress:   e.Progress,
+			})
+		}
+
+		handler(e)
+	}
+}
+
+// withProgress returns a copy of the given context that the given app can
+// report progress with while being applied, published with the given function.
+func withProgress(ctx context.Context, app string, publish func(ProgressEvent)) context.Context {
+	report := func(p Progress) {
+		publish(ProgressEvent{App: app, Status: "progress", Progress: &p})
+	}
+
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// parseProgress parses the given line written to STDERR by a custom user app,
+// returning false if it isn't a progress update.
+func parseProgress(line string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), PROGRESS_PREFIX)
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return 0, "", false
+	}
+
+	var (
+		fields  = strings.Fields(rest)
+		percent = -1
+	)
+
+	if len(fields) > 0 {
+		if p, err := strconv.Atoi(strings.TrimSuffix(fields[0], "%")); err == nil {
+			percent = p
+			fields = fields[1:]
+		}
+	}
+
+	return percent, strings.Join(fields, " "), true
+}
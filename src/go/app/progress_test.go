@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProgressPublisher(t *testing.T) {
+	var events []ProgressEvent
+
+	publish := progressPublisher("test", ACTIONPOSTSTART, func(e ProgressEvent) { events = append(events, e) }, false)
+
+	publish(ProgressEvent{App: "foo", Status: "start"})
+	ReportProgress(withProgress(context.Background(), "foo", publish), 150, "generating %d configs", 3)
+
+	if len(events) != 2 {
+		t.Logf("expected 2 progress events, got %d", len(events))
+		t.FailNow()
+	}
+
+	for _, e := range events {
+		if e.Experiment != "test" || e.Stage != ACTIONPOSTSTART || e.Kind != "user" || e.Timestamp.IsZero() {
+			t.Logf("expected event to be stamped with experiment, stage, and kind, got %+v", e)
+			t.FailNow()
+		}
+	}
+
+	if p := events[1].Progress; events[1].Status != "progress" || p == nil || p.Percent != 100 || p.Message != "generating 3 configs" {
+		t.Logf("unexpected progress event %+v", events[1])
+		t.FailNow()
+	}
+}
@@ -0,0 +1,302 @@
+package app
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/common"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// pxeRoot is the directory on the PXE server that boot files are injected into
+// and served from via TFTP (and HTTP, if the server image runs a web server).
+const pxeRoot = "/srv/pxe"
+
+type pxeServer struct {
+	Root    string
+	Address string
+	Network string
+	Netmask string
+	Clients []pxeClient
+}
+
+type pxeClient struct {
+	Hostname string
+	MAC      string
+	Address  string
+	Kernel   string
+	Initrd   string
+	Append   string
+	URL      string
+}
+
+// PXE provisions a DHCP/TFTP boot service (dnsmasq) on the node labeled with
+// `pxe-server` for all diskless nodes in the topology configured to boot from
+// the network. The value of the `pxe-server` label is the name of the server's
+// interface to serve boot requests on. Each PXE booted node gets its own iPXE
+// boot menu keyed off its MAC address.
+type PXE struct{}
+
+func (PXE) Init(...Option) error {
+	return nil
+}
+
+func (PXE) Name() string {
+	return "pxe"
+}
+
+// Validate checks that a PXE server is available to any PXE booted nodes and
+// that each node's boot configuration is valid.
+func (this PXE) Validate(exp *types.Experiment) error {
+	var (
+		servers = exp.Spec.Topology().FindNodesWithLabels("pxe-server")
+		errs    error
+	)
+
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() || node.Hardware().PXE() == nil {
+			continue
+		}
+
+		var (
+			host = node.General().Hostname()
+			pxe  = node.Hardware().PXE()
+		)
+
+		if len(servers) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("no node labeled as a pxe-server for PXE booted node %s", host))
+		}
+
+		if pxe.Kernel() == "" {
+			errs = multierror.Append(errs, fmt.Errorf("no PXE boot kernel provided for node %s", host))
+		}
+
+		switch strings.ToLower(pxe.Protocol()) {
+		case "tftp", "http":
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("unknown PXE boot protocol %s provided for node %s", pxe.Protocol(), host))
+		}
+	}
+
+	return errs
+}
+
+func (PXE) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this PXE) PreStart(ctx context.Context, exp *types.Experiment) error {
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() || node.Hardware().PXE() == nil {
+			continue
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	servers := exp.Spec.Topology().FindNodesWithLabels("pxe-server")
+
+	if len(servers) == 0 {
+		return fmt.Errorf("topology includes PXE booted nodes but no node is labeled as a pxe-server")
+	}
+
+	var (
+		server    = servers[0] // use first server if more than one present
+		ifaceName = server.Labels()["pxe-server"]
+		pxeDir    = exp.Spec.BaseDir() + "/pxe"
+		imageDir  = common.PhenixBase + "/images/"
+	)
+
+	if server.Hardware().PXE() != nil {
+		return fmt.Errorf("PXE server %s cannot itself be PXE booted", server.General().Hostname())
+	}
+
+	var serverIface ifaces.NodeNetworkInterface
+
+	for _, iface := range server.Network().Interfaces() {
+		if strings.EqualFold(iface.Name(), ifaceName) {
+			serverIface = iface
+			break
+		}
+	}
+
+	if serverIface == nil || serverIface.Address() == "" {
+		return fmt.Errorf("no IP address provided for PXE server interface %s", ifaceName)
+	}
+
+	_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", serverIface.Address(), serverIface.Mask()))
+	if err != nil {
+		return fmt.Errorf("parsing PXE server address: %w", err)
+	}
+
+	cfg := pxeServer{
+		Root:    pxeRoot,
+		Address: serverIface.Address(),
+		Network: network.IP.String(),
+		Netmask: net.IP(network.Mask).String(),
+	}
+
+	for _, node := range nodes {
+		var (
+			host = node.General().Hostname()
+			pxe  = node.Hardware().PXE()
+			boot ifaces.NodeNetworkInterface
+		)
+
+		// PXE booted nodes boot from the first interface on the same VLAN as the
+		// PXE server.
+		for idx, iface := range node.Network().Interfaces() {
+			if !strings.EqualFold(iface.VLAN(), serverIface.VLAN()) {
+				continue
+			}
+
+			// A MAC address is required for the boot service to know which boot
+			// menu to serve, so generate a stable one if not provided.
+			if iface.MAC() == "" {
+				iface.SetMAC(stableMAC(exp.Spec.ExperimentName(), host, idx))
+			}
+
+			boot = iface
+			break
+		}
+
+		if boot == nil {
+			return fmt.Errorf("PXE booted node %s has no interface on PXE server VLAN %s", host, serverIface.VLAN())
+		}
+
+		if boot.Address() == "" {
+			return fmt.Errorf("PXE booted node %s must have a static IP address on VLAN %s", host, boot.VLAN())
+		}
+
+		var proto string
+
+		switch strings.ToLower(pxe.Protocol()) {
+		case "tftp", "http":
+			proto = strings.ToLower(pxe.Protocol())
+		default:
+			return fmt.Errorf("unknown PXE boot protocol %s provided for node %s", pxe.Protocol(), host)
+		}
+
+		client := pxeClient{
+			Hostname: host,
+			MAC:      strings.ToLower(boot.MAC()),
+			Address:  boot.Address(),
+			Append:   pxeAppend(pxe.Append(), node.Kernel()),
+			URL:      fmt.Sprintf("%s://%s/%s", proto, cfg.Address, host),
+		}
+
+		// Boot files are injected into the PXE server's disk under a directory
+		// named after the PXE booted node.
+		for _, file := range []struct {
+			src  string
+			name *string
+		}{
+			{pxe.Kernel(), &client.Kernel},
+			{pxe.Initrd(), &client.Initrd},
+		} {
+			if file.src == "" {
+				continue
+			}
+
+			src := file.src
+
+			// Check if user provided an absolute path to the boot file. If not,
+			// prepend path with default image path.
+			if !filepath.IsAbs(src) {
+				src = imageDir + src
+			}
+
+			*file.name = filepath.Base(src)
+
+			server.AddInject(src, fmt.Sprintf("%s/%s/%s", pxeRoot, host, *file.name), "", "")
+		}
+
+		var (
+			menu = fmt.Sprintf("%s/%s.ipxe", pxeDir, host)
+			mac  = strings.ReplaceAll(client.MAC, ":", "-")
+		)
+
+		if err := tmpl.CreateFileFromTemplate("pxe_menu.tmpl", client, menu); err != nil {
+			return fmt.Errorf("generating PXE boot menu for node %s: %w", host, err)
+		}
+
+		server.AddInject(menu, fmt.Sprintf("%s/%s.ipxe", pxeRoot, mac), "", "")
+
+		cfg.Clients = append(cfg.Clients, client)
+	}
+
+	var (
+		dnsmasq = pxeDir + "/dnsmasq.conf"
+		boot    = pxeDir + "/boot.ipxe"
+	)
+
+	if err := tmpl.CreateFileFromTemplate("pxe_dnsmasq.tmpl", cfg, dnsmasq); err != nil {
+		return fmt.Errorf("generating PXE server config: %w", err)
+	}
+
+	if err := tmpl.CreateFileFromTemplate("pxe_boot.tmpl", cfg, boot); err != nil {
+		return fmt.Errorf("generating PXE boot script: %w", err)
+	}
+
+	server.AddInject(dnsmasq, "/etc/dnsmasq.d/phenix-pxe.conf", "", "")
+	server.AddInject(boot, pxeRoot+"/boot.ipxe", "", "")
+
+	return nil
+}
+
+func (PXE) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (PXE) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (PXE) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// stableMAC generates a locally administered MAC address that's stable across
+// deployments of the given experiment.
+func stableMAC(exp, host string, idx int) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s/%s/%d", exp, host, idx)))
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", sum[0], sum[1], sum[2])
+}
+
+// pxeAppend adds the kernel boot parameters and sysctl settings declared for a
+// node to its PXE kernel command line. Since PXE booted nodes have no disk to
+// inject a sysctl config into, sysctl settings are passed as `sysctl.` boot
+// parameters (supported by Linux 5.8+).
+func pxeAppend(orig string, kernel ifaces.NodeKernel) string {
+	args := []string{orig}
+	args = append(args, kernel.Args()...)
+
+	var keys []string
+
+	for key := range kernel.Sysctl() {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("sysctl.%s=%s", key, kernel.Sysctl()[key]))
+	}
+
+	return strings.TrimSpace(strings.Join(args, " "))
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func TestPXEApp(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "pxe-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	server := &v1.Node{
+		TypeF: "VirtualMachine",
+		LabelsF: map[string]string{
+			"pxe-server": "eth0",
+		},
+		GeneralF: &v1.General{
+			HostnameF: "server",
+		},
+		HardwareF: &v1.Hardware{
+			OSTypeF: "linux",
+		},
+		NetworkF: &v1.Network{
+			InterfacesF: []*v1.Interface{
+				{NameF: "eth0", VLANF: "EXP", AddressF: "10.0.0.1", MaskF: 24},
+			},
+		},
+	}
+
+	client := &v1.Node{
+		TypeF: "VirtualMachine",
+		GeneralF: &v1.General{
+			HostnameF: "plc",
+		},
+		HardwareF: &v1.Hardware{
+			OSTypeF: "linux",
+			PXEF: &v1.PXE{
+				KernelF:   "/phenix/images/pxe/vmlinuz",
+				InitrdF:   "pxe/initrd.img",
+				AppendF:   "console=ttyS0",
+				ProtocolF: "http",
+			},
+		},
+		NetworkF: &v1.Network{
+			InterfacesF: []*v1.Interface{
+				{NameF: "eth0", VLANF: "EXP", AddressF: "10.0.0.10", MaskF: 24},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF: &v1.TopologySpec{
+			NodesF: []*v1.Node{server, client},
+		},
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	app := GetApp("pxe")
+
+	if err := app.PreStart(context.TODO(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	mac := client.NetworkF.InterfacesF[0].MACF
+
+	if mac != stableMAC("test", "plc", 0) {
+		t.Logf("expected generated MAC for PXE booted node, got '%s'", mac)
+		t.FailNow()
+	}
+
+	injects := make(map[string]string)
+
+	for _, inject := range server.Injections() {
+		injects[inject.Dst()] = inject.Src()
+	}
+
+	expected := map[string]string{
+		"/etc/dnsmasq.d/phenix-pxe.conf": baseDir + "/pxe/dnsmasq.conf",
+		"/srv/pxe/boot.ipxe":             baseDir + "/pxe/boot.ipxe",
+		"/srv/pxe/plc/vmlinuz":           "/phenix/images/pxe/vmlinuz",
+		"/srv/pxe/plc/initrd.img":        "/phenix/images/pxe/initrd.img",
+		fmt.Sprintf("/srv/pxe/%s.ipxe", strings.ReplaceAll(mac, ":", "-")): baseDir + "/pxe/plc.ipxe",
+	}
+
+	for dst, src := range expected {
+		if injects[dst] != src {
+			t.Logf("expected injection %s -> %s, got '%s'", src, dst, injects[dst])
+			t.Fail()
+		}
+	}
+
+	menu, err := os.ReadFile(baseDir + "/pxe/plc.ipxe")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !strings.Contains(string(menu), "kernel http://10.0.0.1/plc/vmlinuz console=ttyS0") {
+		t.Logf("unexpected PXE boot menu: %s", menu)
+		t.Fail()
+	}
+
+	conf, err := os.ReadFile(baseDir + "/pxe/dnsmasq.conf")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !strings.Contains(string(conf), fmt.Sprintf("dhcp-host=%s,10.0.0.10,plc", mac)) {
+		t.Logf("unexpected dnsmasq config: %s", conf)
+		t.Fail()
+	}
+}
+
+func TestPXEAppValidate(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "plc1",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+				PXEF:    &v1.PXE{KernelF: "vmlinuz", ProtocolF: "nfs"},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "plc2",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+				PXEF:    &v1.PXE{},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		TopologyF: &v1.TopologySpec{
+			NodesF: nodes,
+		},
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	err := PXE{}.Validate(exp)
+	if err == nil {
+		t.Log("expected PXE validation to fail")
+		t.FailNow()
+	}
+
+	// missing server for both nodes, invalid protocol for plc1, missing kernel
+	// for plc2
+	if errs := err.(*multierror.Error).Errors; len(errs) != 4 {
+		t.Logf("expected 4 validation errors, got %d: %v", len(errs), err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,336 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/util/shell"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// APP_REGISTRY_DIR is the directory external user apps can be registered in by
+// dropping a YAML or JSON file describing the app into it. Changes to the
+// directory are picked up without restarting phenix when it's being watched
+// (see WatchRegistry).
+var APP_REGISTRY_DIR = "/etc/phenix/apps.d"
+
+// How often to check for the registry directory to be created when watching it
+// and it doesn't exist yet.
+var registryPollInterval = 10 * time.Second
+
+// RegistryEntry is the metadata registered for an external user app in the
+// app registry directory. Name defaults to the base name of the file the entry
+// is in, and Executable defaults to `phenix-app-<name>` in the PATH. Relative
+// executable paths are relative to the registry directory.
+type RegistryEntry struct {
+	Name        string         `yaml:"name" json:"name"`
+	Description string         `yaml:"description" json:"description"`
+	Version     string         `yaml:"version" json:"version"`
+	Executable  string         `yaml:"executable" json:"executable"`
+	Protocol    string         `yaml:"protocol" json:"protocol"`
+	Schema      map[string]any `yaml:"schema" json:"schema"`
+
+	// Capabilities of the app (see Capabilities). If either is set, the app
+	// isn't executed to report its capabilities.
+	APIVersion int      `yaml:"apiVersion" json:"apiVersion,omitempty"`
+	Stages     []Action `yaml:"stages" json:"stages,omitempty"`
+
+	// Path to the registry file the entry was loaded from.
+	File string `yaml:"-" json:"file"`
+}
+
+var (
+	registry       map[string]RegistryEntry
+	registryLoaded bool
+	registryMu     sync.RWMutex
+)
+
+// LoadRegistry (re)loads the app registry from the registry directory. A
+// missing registry directory is treated as an empty registry. Registry files
+// that can't be parsed are skipped, and returned as errors.
+func LoadRegistry() error {
+	entries := make(map[string]RegistryEntry)
+
+	files, err := os.ReadDir(APP_REGISTRY_DIR)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading app registry directory %s: %w", APP_REGISTRY_DIR, err)
+	}
+
+	var errs error
+
+	for _, file := range files {
+		if file.IsDir() || !registryFile(file.Name()) {
+			continue
+		}
+
+		path := filepath.Join(APP_REGISTRY_DIR, file.Name())
+
+		entry, err := loadRegistryEntry(path)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		if existing, ok := entries[entry.Name]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("app %s in %s already registered in %s", entry.Name, path, existing.File))
+			continue
+		}
+
+		entries[entry.Name] = entry
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = entries
+	registryLoaded = true
+
+	return errs
+}
+
+// RegisteredApp returns the registry entry for the given app, if any.
+func RegisteredApp(name string) (RegistryEntry, bool) {
+	entry, ok := registeredApps()[name]
+	return entry, ok
+}
+
+// WatchRegistry watches the registry directory for changes, reloading the app
+// registry each time a registry file is created, changed, or removed. If the
+// registry directory doesn't exist, it's checked for periodically until it
+// does. It blocks until the given context is canceled.
+func WatchRegistry(ctx context.Context) {
+	for {
+		if err := watchRegistry(ctx); err != nil {
+			plog.Error("watching app registry", "dir", APP_REGISTRY_DIR, "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(registryPollInterval):
+		}
+	}
+}
+
+// watchRegistry watches the registry directory until the context is canceled
+// or the directory is removed. It returns immediately if the directory doesn't
+// exist.
+func watchRegistry(ctx context.Context) error {
+	if _, err := os.Stat(APP_REGISTRY_DIR); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating watcher: %w", err)
+	}
+
+	defer watcher.Close()
+
+	if err := watcher.Add(APP_REGISTRY_DIR); err != nil {
+		return fmt.Errorf("watching directory: %w", err)
+	}
+
+	reload := func() {
+		if err := LoadRegistry(); err != nil {
+			plog.Warn("unable to load some app registry entries", "dir", APP_REGISTRY_DIR, "err", err)
+		}
+
+		plog.Info("app registry loaded", "dir", APP_REGISTRY_DIR, "apps", len(registeredApps()))
+	}
+
+	// Load the registry now that it's being watched so changes made before the
+	// watch was started aren't missed.
+	reload()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if filepath.Clean(event.Name) == filepath.Clean(APP_REGISTRY_DIR) {
+				if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+					reload()
+					return nil
+				}
+
+				continue
+			}
+
+			if registryFile(event.Name) {
+				reload()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// registeredApps returns the current app registry, loading it first if it
+// hasn't been loaded yet.
+func registeredApps() map[string]RegistryEntry {
+	registryMu.RLock()
+
+	if registryLoaded {
+		defer registryMu.RUnlock()
+		return registry
+	}
+
+	registryMu.RUnlock()
+
+	if err := LoadRegistry(); err != nil {
+		plog.Warn("unable to load some app registry entries", "dir", APP_REGISTRY_DIR, "err", err)
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return registry
+}
+
+func registryFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yml", ".yaml", ".json":
+		return !strings.HasPrefix(filepath.Base(name), ".")
+	}
+
+	return false
+}
+
+func loadRegistryEntry(path string) (RegistryEntry, error) {
+	var entry RegistryEntry
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return entry, fmt.Errorf("reading app registry file %s: %w", path, err)
+	}
+
+	// YAML is a superset of JSON, so this handles both.
+	if err := yaml.Unmarshal(body, &entry); err != nil {
+		return entry, fmt.Errorf("parsing app registry file %s: %w", path, err)
+	}
+
+	if entry.Name == "" {
+		entry.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if strings.ContainsAny(entry.Name, " /\t\n") {
+		return entry, fmt.Errorf("invalid app name %q in app registry file %s", entry.Name, path)
+	}
+
+	switch entry.Protocol {
+	case "", "shell", "grpc":
+	default:
+		return entry, fmt.Errorf("unknown protocol %s for app %s in app registry file %s", entry.Protocol, entry.Name, path)
+	}
+
+	if err := (Capabilities{APIVersion: entry.APIVersion, Stages: entry.Stages}).validate(); err != nil {
+		return entry, fmt.Errorf("invalid capabilities for app %s in app registry file %s: %w", entry.Name, path, err)
+	}
+
+	if entry.Executable != "" && !filepath.IsAbs(entry.Executable) && strings.ContainsRune(entry.Executable, filepath.Separator) {
+		entry.Executable = filepath.Join(filepath.Dir(path), entry.Executable)
+	}
+
+	entry.File = path
+
+	return entry, nil
+}
+
+// Info describes an app available to be used in experiment scenarios.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Protocol    string `json:"protocol,omitempty"`
+
+	// Source is where the app comes from: `internal` (compiled into phenix or
+	// loaded as a plugin), `installed` (installed via `phenix app install`),
+	// `registry` (registered in the app registry directory), or `path` (found
+	// in the PATH).
+	Source string `json:"source"`
+
+	// Schema is the app's scenario metadata schema, if it publishes one.
+	Schema map[string]any `json:"schema,omitempty"`
+}
+
+// Describe returns details for each of the apps returned by List, sorted by
+// name. Apps in the registry take precedence over apps installed via `phenix
+// app install`, which take precedence over internal apps.
+func Describe() []Info {
+	var (
+		infos = make(map[string]Info)
+		users = make(map[string]struct{})
+	)
+
+	for _, name := range shell.FindCommandsWithPrefix(USER_APP_PREFIX) {
+		users[name] = struct{}{}
+	}
+
+	for _, name := range List() {
+		info := Info{Name: name, Source: "internal"}
+
+		if _, ok := users[name]; ok {
+			info.Source = "path"
+		}
+
+		if entry, ok := RegisteredApp(name); ok {
+			info.Source = "registry"
+			info.Description = entry.Description
+			info.Version = entry.Version
+			info.Protocol = entry.Protocol
+			info.Schema = entry.Schema
+		} else if info.Source == "path" {
+			c, _ := store.NewConfig("app/" + name)
+
+			var spec v1.AppSpec
+
+			if store.Get(c) == nil && mapstructure.Decode(c.Spec, &spec) == nil {
+				info.Source = "installed"
+				info.Description = spec.Description
+				info.Version = spec.Version
+				info.Protocol = spec.Protocol
+				info.Schema = spec.Schema
+			}
+		}
+
+		infos[name] = info
+	}
+
+	list := make([]Info, 0, len(infos))
+
+	for _, info := range infos {
+		list = append(list, info)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
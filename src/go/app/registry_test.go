@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	dir := t.TempDir()
+
+	defer func(orig string) { APP_REGISTRY_DIR = orig }(APP_REGISTRY_DIR)
+	APP_REGISTRY_DIR = dir
+
+	files := map[string]string{
+		"foo.yml":   "description: foo app\nversion: 1.0.0\nexecutable: bin/foo\nprotocol: grpc\nschema:\n  type: object\n",
+		"bar.json":  `{"name": "baz", "description": "baz app"}`,
+		"bad.yaml":  "protocol: carrier-pigeon\n",
+		"notes.txt": "not a registry file",
+	}
+
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	if err := LoadRegistry(); err == nil {
+		t.Log("expected error for registry file with unknown protocol")
+		t.FailNow()
+	}
+
+	foo, ok := RegisteredApp("foo")
+	if !ok {
+		t.Log("expected foo app to be registered")
+		t.FailNow()
+	}
+
+	if foo.Version != "1.0.0" || foo.Protocol != "grpc" || foo.Schema["type"] != "object" {
+		t.Logf("unexpected registry entry for foo app: %+v", foo)
+		t.FailNow()
+	}
+
+	if expected := filepath.Join(dir, "bin/foo"); foo.Executable != expected {
+		t.Logf("expected executable %s, got %s", expected, foo.Executable)
+		t.FailNow()
+	}
+
+	if _, ok := RegisteredApp("baz"); !ok {
+		t.Log("expected baz app to be registered by name")
+		t.FailNow()
+	}
+
+	for _, name := range []string{"bar", "bad", "notes"} {
+		if _, ok := RegisteredApp(name); ok {
+			t.Logf("expected %s app to not be registered", name)
+			t.FailNow()
+		}
+	}
+
+	var listed bool
+
+	for _, name := range List() {
+		if name == "foo" {
+			listed = true
+		}
+	}
+
+	if !listed {
+		t.Log("expected foo app to be listed")
+		t.FailNow()
+	}
+
+	if _, ok := GetApp("foo").(*UserApp); !ok {
+		t.Log("expected foo app to be a user app")
+		t.FailNow()
+	}
+}
+
+func TestWatchRegistry(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "apps.d")
+
+	defer func(orig string, interval time.Duration) {
+		APP_REGISTRY_DIR = orig
+		registryPollInterval = interval
+	}(APP_REGISTRY_DIR, registryPollInterval)
+
+	APP_REGISTRY_DIR = dir
+	registryPollInterval = 10 * time.Millisecond
+
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		done        = make(chan struct{})
+	)
+
+	// Wait for the watcher to exit before the registry settings are restored.
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	go func() {
+		defer close(done)
+		WatchRegistry(ctx)
+	}()
+
+	wait := func(name string, registered bool) {
+		deadline := time.Now().Add(5 * time.Second)
+
+		for time.Now().Before(deadline) {
+			if _, ok := RegisteredApp(name); ok == registered {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Logf("expected %s app registered to be %v", name, registered)
+		t.FailNow()
+	}
+
+	// The registry directory doesn't exist yet, so it should be picked up once
+	// it's created.
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "foo.yml"), []byte("description: foo app\n"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	wait("foo", true)
+
+	if err := os.Remove(filepath.Join(dir, "foo.yml")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	wait("foo", false)
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// RETRIES_KEY and RETRY_BACKOFF_KEY are the scenario app metadata keys used
+	// to retry each stage of an app that fails up to the given number of times
+	// (ie. `3`), waiting the given backoff (ie. `10s`) before the first retry and
+	// twice as long before each retry after it. Retry policies set via the
+	// AppRetry option take precedence.
+	RETRIES_KEY       = "retries"
+	RETRY_BACKOFF_KEY = "retryBackoff"
+
+	// DEFAULT_RETRY_BACKOFF is the backoff used if an app is retried without one
+	// being set.
+	DEFAULT_RETRY_BACKOFF = 5 * time.Second
+)
+
+// RetryPolicy is how many times an app that fails is retried for a stage, and
+// how long to wait before the first retry.
+type RetryPolicy struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// appRetryPolicy returns the retry policy for the given app for the current
+// stage. The given metadata is the app's scenario metadata, if any.
+func appRetryPolicy(name string, md map[string]any, options Options) (RetryPolicy, error) {
+	if policy, ok := options.AppRetries[name]; ok {
+		return policy, nil
+	}
+
+	policy := options.AppRetry
+
+	if val, ok := md[RETRIES_KEY]; ok {
+		// Metadata decoded from JSON or YAML may be any numeric type.
+		switch v := val.(type) {
+		case int:
+			policy.Retries = v
+		case int64:
+			policy.Retries = int(v)
+		case uint64:
+			policy.Retries = int(v)
+		case float64:
+			policy.Retries = int(v)
+		default:
+			return RetryPolicy{}, fmt.Errorf("invalid %s for app %s (expected integer)", RETRIES_KEY, name)
+		}
+
+		if policy.Retries < 0 {
+			return RetryPolicy{}, fmt.Errorf("invalid %s for app %s (expected non-negative integer)", RETRIES_KEY, name)
+		}
+	}
+
+	if val, ok := md[RETRY_BACKOFF_KEY]; ok {
+		str, ok := val.(string)
+		if !ok {
+			return RetryPolicy{}, fmt.Errorf("invalid %s for app %s (expected duration string)", RETRY_BACKOFF_KEY, name)
+		}
+
+		backoff, err := time.ParseDuration(str)
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("invalid %s for app %s: %w", RETRY_BACKOFF_KEY, name, err)
+		}
+
+		policy.Backoff = backoff
+	}
+
+	return policy, nil
+}
+
+// runWithRetries calls the given function, calling it again per the given
+// retry policy if it fails. The given retrying function is called with the
+// attempt that failed, the error it failed with, and the backoff before the
+// next attempt. Retries stop once the given context is canceled, and apps that
+// don't exist aren't retried.
+func runWithRetries(ctx context.Context, policy RetryPolicy, retrying func(int, error, time.Duration), f func() error) error {
+	backoff := policy.Backoff
+
+	if backoff <= 0 {
+		backoff = DEFAULT_RETRY_BACKOFF
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+
+		if err == nil || attempt > policy.Retries || errors.Is(err, ErrUserAppNotFound) || ctx.Err() != nil {
+			return err
+		}
+
+		retrying(attempt, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// retryReporter returns a function that reports each failed attempt of the
+// given app that's about to be retried as a retrying progress event, which is
+// also recorded as a retrying result for the app's run in the experiment
+// status.
+func retryReporter(name string, policy RetryPolicy, publish func(ProgressEvent)) func(int, error, time.Duration) {
+	return func(attempt int, err error, backoff time.Duration) {
+		publish(ProgressEvent{
+			App:     name,
+			Status:  "retrying",
+			Message: fmt.Sprintf("failed attempt %d of %d -- retrying in %v", attempt, policy.Retries+1, backoff),
+			Error:   fmt.Errorf("attempt %d of %d: %w", attempt, policy.Retries+1, err),
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunWithRetries(t *testing.T) {
+	var (
+		policy   = RetryPolicy{Retries: 2, Backoff: time.Millisecond}
+		calls    int
+		attempts []int
+		backoffs []time.Duration
+	)
+
+	retrying := func(attempt int, _ error, backoff time.Duration) {
+		attempts = append(attempts, attempt)
+		backoffs = append(backoffs, backoff)
+	}
+
+	err := runWithRetries(context.Background(), policy, retrying, func() error {
+		calls++
+
+		if calls < 3 {
+			return errors.New("minimega not ready")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Logf("expected success on third attempt, got %v", err)
+		t.FailNow()
+	}
+
+	if len(attempts) != 2 || attempts[1] != 2 {
+		t.Logf("expected 2 retried attempts, got %v", attempts)
+		t.FailNow()
+	}
+
+	if backoffs[1] != 2*time.Millisecond {
+		t.Logf("expected backoff to double, got %v", backoffs)
+		t.FailNow()
+	}
+
+	calls = 0
+
+	err = runWithRetries(context.Background(), policy, retrying, func() error {
+		calls++
+		return ErrUserAppNotFound
+	})
+
+	if !errors.Is(err, ErrUserAppNotFound) || calls != 1 {
+		t.Logf("expected missing user app not to be retried, got %d calls", calls)
+		t.FailNow()
+	}
+}
+
+func TestAppRetryPolicy(t *testing.T) {
+	md := map[string]any{RETRIES_KEY: float64(3), RETRY_BACKOFF_KEY: "10s"}
+
+	policy, err := appRetryPolicy("foo", md, NewOptions(AppRetry(1, time.Second)))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if policy.Retries != 3 || policy.Backoff != 10*time.Second {
+		t.Logf("expected metadata to override default retry policy, got %+v", policy)
+		t.FailNow()
+	}
+
+	policy, _ = appRetryPolicy("foo", md, NewOptions(AppRetry(0, 0, "foo")))
+	if policy.Retries != 0 {
+		t.Logf("expected app retry option to override metadata, got %+v", policy)
+		t.FailNow()
+	}
+
+	if _, err := appRetryPolicy("foo", map[string]any{RETRIES_KEY: "three"}, NewOptions()); err == nil {
+		t.Log("expected error for invalid retries metadata")
+		t.FailNow()
+	}
+}
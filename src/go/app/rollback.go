@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"phenix/types"
+	"phenix/util/journal"
+	"phenix/util/plog"
+)
+
+// Undoer is an optional interface a phenix app can implement to revert the
+// changes it made to an experiment for the given lifecycle stage. If an app
+// fails while apps are being applied for the configure, pre-start, or
+// post-start stages, Undo is called for each app already applied in that stage,
+// in reverse order, so the experiment isn't left with some apps' changes only
+// partially applied.
+type Undoer interface {
+	Undo(context.Context, *types.Experiment, Action) error
+}
+
+// rollbackStage returns true if apps already applied for the given stage
+// should be rolled back when a later app fails.
+func rollbackStage(stage Action) bool {
+	switch stage {
+	case ACTIONCONFIG, ACTIONPRESTART, ACTIONPOSTSTART:
+		return true
+	default:
+		return false
+	}
+}
+
+// rollbackApps calls Undo, in reverse order, for each of the given applied apps
+// that implement the Undoer interface. Undo errors are logged rather than
+// returned so the error that caused the rollback is what's reported.
+func rollbackApps(exp *types.Experiment, applied []App, stage Action) {
+	// The context used to apply the apps may have been canceled (ie. the stage
+	// timed out), but apps still need to be given a chance to undo their
+	// changes.
+	ctx := context.Background()
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		a := applied[i]
+
+		u, ok := a.(Undoer)
+		if !ok {
+			continue
+		}
+
+		if err := u.Undo(ctx, exp, stage); err != nil {
+			plog.Error(fmt.Sprintf("[✗] '%s' app (%s undo)", a.Name(), stage), "err", err)
+			journal.RecordWithDetails(exp.Metadata.Name, journal.CategoryApp, "", map[string]string{"error": err.Error()}, "undoing app %s (%s) failed", a.Name(), stage)
+
+			continue
+		}
+
+		plog.Info(fmt.Sprintf("[✓] '%s' app (%s undo)", a.Name(), stage))
+		journal.Record(exp.Metadata.Name, journal.CategoryApp, "", "app %s (%s) undone", a.Name(), stage)
+	}
+}
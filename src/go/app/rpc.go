@@ -0,0 +1,456 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"phenix/scheduler"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/plog"
+	"phenix/util/shell"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RPC_SERVICE is the gRPC service user apps using the gRPC protocol serve.
+	// It has a unary method for each lifecycle stage (`Configure`, `PreStart`,
+	// `PostStart`, `Running`, and `Cleanup`). Apps only need to implement the
+	// methods for the stages they care about; stages the app responds to with
+	// `Unimplemented` are skipped.
+	RPC_SERVICE = "phenix.app.v1.UserApp"
+
+	// RPC_VERSION is the version of the gRPC protocol, included in the handshake
+	// apps write to STDOUT once they're serving.
+	RPC_VERSION = 1
+
+	// RPC_COOKIE_ENV is the environment variable phenix sets (to RPC_COOKIE)
+	// when executing gRPC apps, so apps can tell they're being run by phenix as
+	// a plugin and not directly by a user.
+	RPC_COOKIE_ENV = "PHENIX_APP_PLUGIN"
+	RPC_COOKIE     = "c5ab7d3c2e0b4c8e9d6e3b1a7f2f4e60"
+)
+
+// RPC_HANDSHAKE_TIMEOUT is how long phenix waits for a gRPC app to write its
+// handshake to STDOUT after being executed.
+var RPC_HANDSHAKE_TIMEOUT = 10 * time.Second
+
+// Messages are encoded as JSON rather than protobuf so the experiment can be
+// exchanged as-is, and so apps in any language with a gRPC library can
+// implement the service without generated code. Apps must register a codec
+// for the `json` content subtype (ie. `application/grpc+json`).
+const rpcCodecName = "json"
+
+func init() {
+	encoding.RegisterCodec(rpcCodec{})
+}
+
+type rpcCodec struct{}
+
+func (rpcCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (rpcCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (rpcCodec) Name() string                               { return rpcCodecName }
+
+// RPCRequest is the request sent to each lifecycle stage method of a gRPC app.
+type RPCRequest struct {
+	Experiment json.RawMessage `json:"experiment"`
+	DryRun     bool            `json:"dryrun"`
+}
+
+// RPCResponse is the response returned by each lifecycle stage method of a
+// gRPC app. Experiment is the experiment as updated by the app, which can be
+// left empty if the app didn't modify it. Schedule is the name of a scheduler
+// to schedule the experiment with before running the stage again, just like
+// shell apps exiting with EXIT_SCHEDULE.
+type RPCResponse struct {
+	Experiment json.RawMessage `json:"experiment,omitempty"`
+	Schedule   string          `json:"schedule,omitempty"`
+}
+
+// ScheduleError can be returned by apps served with `ServeRPCApp` to have
+// phenix schedule the experiment with the given scheduler and then run the
+// stage again.
+type ScheduleError struct {
+	Scheduler string
+}
+
+func (this ScheduleError) Error() string {
+	return "experiment needs to be scheduled with " + this.Scheduler
+}
+
+var rpcMethods = map[Action]string{
+	ACTIONCONFIG:    "Configure",
+	ACTIONPRESTART:  "PreStart",
+	ACTIONPOSTSTART: "PostStart",
+	ACTIONRUNNING:   "Running",
+	ACTIONCLEANUP:   "Cleanup",
+}
+
+// rpcOut runs the given action for a gRPC app by executing the app, waiting
+// for it to write its handshake to STDOUT, and calling the action's method
+// over the Unix socket included in the handshake. The app is stopped once the
+// method returns. Anything else the app writes to STDOUT or STDERR is written
+// to the app's log, and progress updates written to STDERR are reported.
+func (this UserApp) rpcOut(ctx context.Context, action Action, exp *types.Experiment, cmdName string, env []string) error {
+	data, err := json.Marshal(exp)
+	if err != nil {
+		return fmt.Errorf("marshaling experiment to JSON: %w", err)
+	}
+
+	var output io.Writer = io.Discard
+
+	if log, err := openAppLog(exp.Metadata.Name, this.options.Name, action); err == nil {
+		defer log.Close()
+
+		output = log
+	} else {
+		plog.Warn("unable to open user app log", "app", this.options.Name, "exp", exp.Metadata.Name, "err", err)
+	}
+
+	var (
+		stdout = &rpcStdout{output: output, addr: make(chan string, 1)}
+		stderr = &rpcStderr{ctx: ctx, output: output}
+	)
+
+	sandbox, err := appSandbox(this.options.Name, exp)
+	if err != nil {
+		return err
+	}
+
+	var (
+		cmd    *exec.Cmd
+		sc     *shell.SandboxedCmd
+		parent = ctx
+		kill   = func(sig syscall.Signal) { cmd.Process.Signal(sig) }
+	)
+
+	// Apps using the gRPC protocol are sandboxed the same as apps using the shell
+	// protocol, with the sandbox timeout covering the app's whole run.
+	if sandbox != nil {
+		if sc, err = sandbox.Command(cmdName); err != nil {
+			return fmt.Errorf("sandboxing user app %s: %w", this.options.Name, err)
+		}
+
+		defer sc.Close()
+
+		cmd = sc.Cmd
+		kill = func(sig syscall.Signal) { sc.Signal(sig) }
+
+		if sandbox.Timeout > 0 {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithTimeout(ctx, sandbox.Timeout)
+			defer cancel()
+		}
+	} else {
+		cmd = exec.Command(cmdName)
+	}
+
+	// sandboxErr reports errors caused by the app exceeding its sandbox limits as
+	// such.
+	sandboxErr := func(err error) error {
+		switch {
+		case sandbox != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil:
+			return fmt.Errorf("%w after %v: %w", shell.ErrSandboxTimeout, sandbox.Timeout, err)
+		case sc != nil && sc.OOMKilled():
+			return fmt.Errorf("%w: %w", shell.ErrSandboxMemory, err)
+		}
+
+		return err
+	}
+
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, RPC_COOKIE_ENV+"="+RPC_COOKIE)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting user app %s command %s: %w", this.options.Name, cmdName, err)
+	}
+
+	exited := make(chan struct{})
+
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	defer stopRPCApp(kill, exited)
+
+	var addr string
+
+	select {
+	case addr = <-stdout.addr:
+	case <-exited:
+		return sandboxErr(fmt.Errorf("user app %s command %s exited before handshake", this.options.Name, cmdName))
+	case <-time.After(RPC_HANDSHAKE_TIMEOUT):
+		return fmt.Errorf("timed out waiting for user app %s command %s handshake", this.options.Name, cmdName)
+	case <-ctx.Done():
+		return sandboxErr(ctx.Err())
+	}
+
+	conn, err := grpc.DialContext(
+		ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", addr)
+		}),
+	)
+
+	if err != nil {
+		return sandboxErr(fmt.Errorf("connecting to user app %s: %w", this.options.Name, err))
+	}
+
+	defer conn.Close()
+
+	var (
+		method = fmt.Sprintf("/%s/%s", RPC_SERVICE, rpcMethods[action])
+		req    = RPCRequest{Experiment: data, DryRun: this.options.DryRun}
+		resp   RPCResponse
+	)
+
+	if err := conn.Invoke(ctx, method, &req, &resp, grpc.CallContentSubtype(rpcCodecName)); err != nil {
+		if status.Code(err) == codes.Unimplemented {
+			plog.Debug("user app does not implement stage", "app", this.options.Name, "stage", action)
+			return nil
+		}
+
+		return sandboxErr(fmt.Errorf("user app %s stage %s failed: %w", this.options.Name, action, err))
+	}
+
+	if resp.Schedule != "" {
+		if err := scheduler.Schedule(resp.Schedule, exp.Spec); err != nil {
+			return fmt.Errorf("scheduling experiment with %s: %w", resp.Schedule, err)
+		}
+
+		return this.run(ctx, action, exp)
+	}
+
+	return this.applyResult(action, exp, resp.Experiment)
+}
+
+// rpcStdout copies what a gRPC app writes to STDOUT to the given writer,
+// watching for the handshake the app writes once it's serving. The handshake
+// is a single line of the form `<version>|unix|<address>`, and the address of
+// the Unix socket the app is serving on is sent on the addr channel. A
+// handshake with the wrong protocol version is ignored, and will result in a
+// handshake timeout.
+type rpcStdout struct {
+	output io.Writer
+	addr   chan string
+
+	buf  []byte
+	done bool
+}
+
+func (this *rpcStdout) Write(p []byte) (int, error) {
+	this.output.Write(p)
+
+	if this.done {
+		return len(p), nil
+	}
+
+	this.buf = append(this.buf, p...)
+
+	for {
+		idx := bytes.IndexByte(this.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		fields := strings.Split(strings.TrimSpace(string(this.buf[:idx])), "|")
+		this.buf = this.buf[idx+1:]
+
+		if len(fields) != 3 || fields[1] != "unix" {
+			continue
+		}
+
+		if v, _ := strconv.Atoi(fields[0]); v != RPC_VERSION {
+			plog.Error("user app uses unsupported gRPC protocol version", "version", fields[0], "expected", RPC_VERSION)
+			continue
+		}
+
+		this.addr <- fields[2]
+		this.done = true
+		this.buf = nil
+
+		break
+	}
+
+	return len(p), nil
+}
+
+// rpcStderr copies what a gRPC app writes to STDERR to the given writer,
+// reporting any progress updates written by the app.
+type rpcStderr struct {
+	ctx    context.Context
+	output io.Writer
+
+	buf []byte
+}
+
+func (this *rpcStderr) Write(p []byte) (int, error) {
+	this.output.Write(p)
+	this.buf = append(this.buf, p...)
+
+	for {
+		idx := bytes.IndexByte(this.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		if percent, msg, ok := parseProgress(string(this.buf[:idx])); ok {
+			ReportProgress(this.ctx, percent, "%s", msg)
+		}
+
+		this.buf = this.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// stopRPCApp gives a gRPC app a chance to exit gracefully before killing it.
+func stopRPCApp(kill func(syscall.Signal), exited chan struct{}) {
+	kill(syscall.SIGTERM)
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		kill(syscall.SIGKILL)
+		<-exited
+	}
+}
+
+// ServeRPCApp serves the given app as a user app using the gRPC protocol. It's
+// meant to be called from the main function of user apps written in Go, and
+// blocks until phenix stops the app. The app's `Init` method is called with
+// the app's name (derived from the executable name) and dry run setting before
+// each stage method is called.
+func ServeRPCApp(a App) error {
+	if os.Getenv(RPC_COOKIE_ENV) != RPC_COOKIE {
+		return fmt.Errorf("this is a phenix app and is meant to be executed by phenix")
+	}
+
+	dir, err := os.MkdirTemp("", "phenix-app-")
+	if err != nil {
+		return fmt.Errorf("creating socket directory: %w", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	addr := filepath.Join(dir, "app.sock")
+
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+
+	name := strings.TrimPrefix(filepath.Base(os.Args[0]), USER_APP_PREFIX)
+
+	server := grpc.NewServer()
+	server.RegisterService(rpcServiceDesc(name), a)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+
+	go func() {
+		<-sigs
+		server.GracefulStop()
+	}()
+
+	fmt.Printf("%d|unix|%s\n", RPC_VERSION, addr)
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("serving app: %w", err)
+	}
+
+	return nil
+}
+
+func rpcServiceDesc(name string) *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: RPC_SERVICE,
+		HandlerType: (*App)(nil),
+	}
+
+	for action, method := range rpcMethods {
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: method,
+			Handler:    rpcHandler(name, action),
+		})
+	}
+
+	return desc
+}
+
+func rpcHandler(name string, action Action) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		var req RPCRequest
+
+		if err := dec(&req); err != nil {
+			return nil, err
+		}
+
+		exp := types.NewExperiment(store.ConfigMetadata{})
+
+		if err := json.Unmarshal(req.Experiment, exp); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unmarshaling experiment from JSON: %v", err)
+		}
+
+		a := srv.(App)
+
+		if err := a.Init(Name(name), DryRun(req.DryRun)); err != nil {
+			return nil, status.Errorf(codes.Internal, "initializing app: %v", err)
+		}
+
+		var err error
+
+		switch action {
+		case ACTIONCONFIG:
+			err = a.Configure(ctx, exp)
+		case ACTIONPRESTART:
+			err = a.PreStart(ctx, exp)
+		case ACTIONPOSTSTART:
+			err = a.PostStart(ctx, exp)
+		case ACTIONRUNNING:
+			err = a.Running(ctx, exp)
+		case ACTIONCLEANUP:
+			err = a.Cleanup(ctx, exp)
+		}
+
+		if err != nil {
+			var sched ScheduleError
+
+			if errors.As(err, &sched) {
+				return &RPCResponse{Schedule: sched.Scheduler}, nil
+			}
+
+			return nil, status.Error(codes.Unknown, err.Error())
+		}
+
+		data, err := json.Marshal(exp)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "marshaling experiment to JSON: %v", err)
+		}
+
+		return &RPCResponse{Experiment: data}, nil
+	}
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+	"phenix/util/common"
+	"phenix/util/shell"
+
+	"google.golang.org/grpc"
+)
+
+type rpcTestApp struct {
+	options Options
+}
+
+func (this *rpcTestApp) Init(opts ...Option) error {
+	this.options = NewOptions(opts...)
+	return nil
+}
+
+func (this rpcTestApp) Name() string {
+	return this.options.Name
+}
+
+func (rpcTestApp) Configure(context.Context, *types.Experiment) error {
+	return nil
+}
+
+func (rpcTestApp) PreStart(context.Context, *types.Experiment) error {
+	return ScheduleError{Scheduler: "round-robin"}
+}
+
+func (rpcTestApp) PostStart(context.Context, *types.Experiment) error {
+	return nil
+}
+
+func (rpcTestApp) Running(context.Context, *types.Experiment) error {
+	return nil
+}
+
+func (this rpcTestApp) Cleanup(_ context.Context, exp *types.Experiment) error {
+	exp.Status.SetAppStatus(this.options.Name, "cleaned")
+	return nil
+}
+
+func TestRPCApp(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "app.sock")
+
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(rpcServiceDesc("test"), new(rpcTestApp))
+
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(
+		addr,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return new(net.Dialer).DialContext(ctx, "unix", addr)
+		}),
+	)
+
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer conn.Close()
+
+	exp := types.NewExperiment(store.ConfigMetadata{Name: "test"})
+	data, _ := json.Marshal(exp)
+
+	invoke := func(method string) RPCResponse {
+		var resp RPCResponse
+
+		err := conn.Invoke(context.Background(), "/"+RPC_SERVICE+"/"+method, &RPCRequest{Experiment: data}, &resp, grpc.CallContentSubtype(rpcCodecName))
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		return resp
+	}
+
+	if resp := invoke("PreStart"); resp.Schedule != "round-robin" {
+		t.Logf("expected experiment to be scheduled with round-robin, got '%s'", resp.Schedule)
+		t.FailNow()
+	}
+
+	resp := invoke("Cleanup")
+
+	if err := (UserApp{options: Options{Name: "test"}}).applyResult(ACTIONCLEANUP, exp, resp.Experiment); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if status := exp.Status.AppStatus()["test"]; status != "cleaned" {
+		t.Logf("expected app status to be updated, got %v", status)
+		t.FailNow()
+	}
+}
+
+func TestRPCHandshake(t *testing.T) {
+	stdout := &rpcStdout{output: io.Discard, addr: make(chan string, 1)}
+
+	stdout.Write([]byte("starting up\n2|unix|/tmp/old.sock\n1|unix|/tmp/ap"))
+	stdout.Write([]byte("p.sock\nserving\n"))
+
+	select {
+	case addr := <-stdout.addr:
+		if addr != "/tmp/app.sock" {
+			t.Logf("expected address /tmp/app.sock, got %s", addr)
+			t.FailNow()
+		}
+	default:
+		t.Log("expected handshake")
+		t.FailNow()
+	}
+}
+
+func TestRPCAppSandbox(t *testing.T) {
+	dir := t.TempDir()
+
+	defer func(base string) { common.PhenixBase = base }(common.PhenixBase)
+
+	common.PhenixBase = dir
+
+	// The app never completes its handshake, so it's only stopped once its
+	// sandbox timeout elapses.
+	cmd := filepath.Join(dir, "phenix-app-sleepy")
+	script := "#!/bin/sh\npwd > started\nsleep 10\n"
+
+	if err := os.WriteFile(cmd, []byte(script), 0755); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	workDir := filepath.Join(dir, "work")
+
+	exp := &types.Experiment{
+		Metadata: store.ConfigMetadata{Name: "test"},
+		Spec: &v1.ExperimentSpec{
+			ExperimentNameF: "test",
+			ScenarioF: &v2.ScenarioSpec{
+				AppsF: []*v2.ScenarioApp{
+					{
+						NameF:     "sleepy",
+						MetadataF: map[string]any{SANDBOX_KEY: map[string]any{"timeout": "200ms", "workDir": workDir}},
+					},
+				},
+			},
+		},
+		Status: new(v1.ExperimentStatus),
+	}
+
+	app := UserApp{options: NewOptions(Name("sleepy"))}
+
+	started := time.Now()
+
+	err := app.rpcOut(context.Background(), ACTIONCONFIG, exp, cmd, nil)
+	if !errors.Is(err, shell.ErrSandboxTimeout) {
+		t.Logf("expected sandbox timeout error, got %v", err)
+		t.FailNow()
+	}
+
+	if time.Since(started) > RPC_HANDSHAKE_TIMEOUT {
+		t.Log("expected app to be stopped once its sandbox timeout elapsed")
+		t.FailNow()
+	}
+
+	pwd, err := os.ReadFile(filepath.Join(workDir, "started"))
+	if err != nil || strings.TrimSpace(string(pwd)) != workDir {
+		t.Logf("expected app to be run in its sandbox working directory, got %q (%v)", pwd, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/types"
+	"phenix/util/shell"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// SANDBOX_KEY is the scenario app metadata key used to limit what custom user
+// apps (using either protocol) can do each time they're executed, ie.
+//
+//	sandbox:
+//	  timeout: 30m
+//	  memory: 2G
+//	  cpus: 1.5
+//	  pids: 256
+//	  openFiles: 1024
+//	  fileSize: 10G
+//	  workDir: my-app
+//
+// The working directory, if relative, is relative to the experiment's files
+// directory.
+const SANDBOX_KEY = "sandbox"
+
+type sandboxConfig struct {
+	Timeout   string  `mapstructure:"timeout"`
+	Memory    string  `mapstructure:"memory"`
+	CPUs      float64 `mapstructure:"cpus"`
+	Pids      int64   `mapstructure:"pids"`
+	OpenFiles uint64  `mapstructure:"openFiles"`
+	FileSize  string  `mapstructure:"fileSize"`
+	WorkDir   string  `mapstructure:"workDir"`
+}
+
+// appSandbox returns the sandbox the given user app should be executed in, if
+// any, per its scenario metadata for the given experiment.
+func appSandbox(name string, exp *types.Experiment) (*shell.Sandbox, error) {
+	app := exp.App(name)
+	if app == nil {
+		return nil, nil
+	}
+
+	val, ok := app.Metadata()[SANDBOX_KEY]
+	if !ok {
+		return nil, nil
+	}
+
+	var config sandboxConfig
+
+	if err := mapstructure.WeakDecode(val, &config); err != nil {
+		return nil, fmt.Errorf("invalid %s for app %s: %w", SANDBOX_KEY, name, err)
+	}
+
+	sb := shell.Sandbox{
+		CPUMax:  config.CPUs,
+		PidsMax: config.Pids,
+		NoFile:  config.OpenFiles,
+		WorkDir: config.WorkDir,
+	}
+
+	var err error
+
+	if config.Timeout != "" {
+		if sb.Timeout, err = time.ParseDuration(config.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid %s timeout for app %s: %w", SANDBOX_KEY, name, err)
+		}
+	}
+
+	if config.Memory != "" {
+		if sb.MemoryMax, err = parseBytes(config.Memory); err != nil {
+			return nil, fmt.Errorf("invalid %s memory for app %s: %w", SANDBOX_KEY, name, err)
+		}
+	}
+
+	if config.FileSize != "" {
+		size, err := parseBytes(config.FileSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s file size for app %s: %w", SANDBOX_KEY, name, err)
+		}
+
+		sb.FileSize = uint64(size)
+	}
+
+	if sb.CPUMax < 0 || sb.PidsMax < 0 {
+		return nil, fmt.Errorf("invalid %s for app %s: limits cannot be negative", SANDBOX_KEY, name)
+	}
+
+	if sb.WorkDir != "" && !filepath.IsAbs(sb.WorkDir) {
+		sb.WorkDir = filepath.Join(exp.FilesDir(), sb.WorkDir)
+	}
+
+	return &sb, nil
+}
+
+// parseBytes parses the given size (ie. `512M` or `2G`) into bytes. Sizes
+// without a unit are in bytes, and units are powers of 1024.
+func parseBytes(size string) (int64, error) {
+	var (
+		str  = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+		mult = int64(1)
+	)
+
+	if str == "" {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	switch str[len(str)-1] {
+	case 'K':
+		mult = 1 << 10
+	case 'M':
+		mult = 1 << 20
+	case 'G':
+		mult = 1 << 30
+	case 'T':
+		mult = 1 << 40
+	}
+
+	if mult > 1 {
+		str = str[:len(str)-1]
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,33 @@
+package app
+
+import "testing"
+
+func TestParseBytes(t *testing.T) {
+	sizes := map[string]int64{
+		"1024":  1024,
+		"512M":  512 << 20,
+		"2G":    2 << 30,
+		"1.5gb": 3 << 29,
+		"64k":   64 << 10,
+	}
+
+	for size, expected := range sizes {
+		actual, err := parseBytes(size)
+		if err != nil {
+			t.Logf("parsing %s: %v", size, err)
+			t.FailNow()
+		}
+
+		if actual != expected {
+			t.Logf("expected %s to be %d bytes, got %d", size, expected, actual)
+			t.FailNow()
+		}
+	}
+
+	for _, size := range []string{"", "G", "-1M", "lots"} {
+		if _, err := parseBytes(size); err == nil {
+			t.Logf("expected error parsing %q", size)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/perror"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataSchemaProvider is an optional interface apps can implement to publish
+// an OpenAPI schema (as YAML or JSON) describing their scenario metadata. The
+// metadata configured for the app in an experiment's scenario is validated
+// against it during the configure stage. External user apps publish their
+// schema via the `schema` file in their manifest when installed, or via the
+// `schema` key in their app registry entry, instead.
+type MetadataSchemaProvider interface {
+	MetadataSchema() []byte
+}
+
+// Scenario app metadata keys handled by phenix itself for every app, which are
+// removed from the metadata before it's validated against the app's schema.
+var reservedMetadataKeys = []string{TIMEOUT_KEY, DEPENDS_ON_KEY, ENV_KEY, SECRET_ENV_KEY}
+
+// MetadataSchema returns the schema published for the given app's scenario
+// metadata, or nil if the app doesn't publish one.
+func MetadataSchema(name string) (*openapi3.Schema, error) {
+	var body []byte
+
+	if p, ok := GetApp(name).(MetadataSchemaProvider); ok {
+		body = p.MetadataSchema()
+	} else if entry, ok := RegisteredApp(name); ok && entry.Schema != nil {
+		var err error
+
+		if body, err = json.Marshal(entry.Schema); err != nil {
+			return nil, fmt.Errorf("marshaling metadata schema for app %s: %w", name, err)
+		}
+	} else {
+		c, _ := store.NewConfig("app/" + name)
+
+		if err := store.Get(c); err != nil {
+			return nil, nil
+		}
+
+		var spec v1.AppSpec
+
+		if err := mapstructure.Decode(c.Spec, &spec); err != nil || spec.Schema == nil {
+			return nil, nil
+		}
+
+		var err error
+
+		if body, err = json.Marshal(spec.Schema); err != nil {
+			return nil, fmt.Errorf("marshaling metadata schema for app %s: %w", name, err)
+		}
+	}
+
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var raw map[string]any
+
+	// YAML is a superset of JSON, so this handles both.
+	if err := yaml.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("parsing metadata schema for app %s: %w", name, err)
+	}
+
+	body, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling metadata schema for app %s: %w", name, err)
+	}
+
+	var schema openapi3.Schema
+
+	if err := json.Unmarshal(body, &schema); err != nil {
+		return nil, fmt.Errorf("parsing metadata schema for app %s: %w", name, err)
+	}
+
+	if err := schema.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid metadata schema for app %s: %w", name, err)
+	}
+
+	return &schema, nil
+}
+
+// validateMetadata validates the metadata of each enabled app in the given
+// experiment's scenario against the schema the app publishes, if any. All the
+// schema violations found are aggregated into the error returned, each
+// identified by the path to the offending metadata value.
+func validateMetadata(exp *types.Experiment) error {
+	if exp.Spec.Scenario() == nil {
+		return nil
+	}
+
+	var errs error
+
+	for _, app := range exp.Spec.Scenario().Apps() {
+		if app.Disabled() {
+			continue
+		}
+
+		name := app.Name()
+
+		schema, err := MetadataSchema(name)
+		if err != nil {
+			errs = multierror.Append(errs, perror.Errorf(perror.CodeValidation, "app/"+name, "%w", err))
+			continue
+		}
+
+		if schema == nil {
+			continue
+		}
+
+		md := make(map[string]any)
+
+		for k, v := range app.Metadata() {
+			md[k] = v
+		}
+
+		for _, k := range reservedMetadataKeys {
+			delete(md, k)
+		}
+
+		// Round trip the metadata through JSON so it only contains the generic
+		// types the schema validator expects.
+		body, err := json.Marshal(md)
+		if err != nil {
+			errs = multierror.Append(errs, perror.Errorf(perror.CodeValidation, "app/"+name, "marshaling metadata for app %s: %w", name, err))
+			continue
+		}
+
+		var value any
+
+		if err := json.Unmarshal(body, &value); err != nil {
+			errs = multierror.Append(errs, perror.Errorf(perror.CodeValidation, "app/"+name, "unmarshaling metadata for app %s: %w", name, err))
+			continue
+		}
+
+		if err := schema.VisitJSON(value, openapi3.MultiErrors()); err != nil {
+			errs = multierror.Append(errs, perror.Errorf(perror.CodeValidation, "app/"+name, "invalid metadata for app %s: %s", name, strings.Join(schemaErrors(err), "; ")))
+		}
+	}
+
+	return errs
+}
+
+// schemaErrors flattens the given schema validation error into one message per
+// violation, each prefixed with the path to the offending metadata value.
+func schemaErrors(err error) []string {
+	var multi openapi3.MultiError
+
+	if errors.As(err, &multi) {
+		var msgs []string
+
+		for _, e := range multi {
+			msgs = append(msgs, schemaErrors(e)...)
+		}
+
+		return msgs
+	}
+
+	var schemaErr *openapi3.SchemaError
+
+	if errors.As(err, &schemaErr) {
+		path := "metadata"
+
+		if ptr := schemaErr.JSONPointer(); len(ptr) > 0 {
+			path += "." + strings.Join(ptr, ".")
+		}
+
+		return []string{fmt.Sprintf("%s: %s", path, schemaErr.Reason)}
+	}
+
+	return []string{err.Error()}
+}
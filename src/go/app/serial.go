@@ -21,9 +21,9 @@ func (Serial) Name() string {
 	return "serial"
 }
 
-func (Serial) Configure(ctx context.Context, exp *types.Experiment) error {
+func (this Serial) Configure(ctx context.Context, exp *types.Experiment) error {
 	// loop through nodes
-	for _, node := range exp.Spec.Topology().Nodes() {
+	for _, node := range TargetNodes(exp, this.Name()) {
 		if node.External() {
 			continue
 		}
@@ -67,9 +67,9 @@ func (Serial) Configure(ctx context.Context, exp *types.Experiment) error {
 	return nil
 }
 
-func (Serial) PreStart(ctx context.Context, exp *types.Experiment) error {
+func (this Serial) PreStart(ctx context.Context, exp *types.Experiment) error {
 	// loop through nodes
-	for _, node := range exp.Spec.Topology().Nodes() {
+	for _, node := range TargetNodes(exp, this.Name()) {
 		if node.External() {
 			continue
 		}
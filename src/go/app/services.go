@@ -0,0 +1,526 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+	"phenix/util/tap"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"inet.af/netaddr"
+)
+
+// SHARED_SERVICES_NAMESPACE is the minimega namespace shared service VLANs
+// are reserved in and shared service host taps are created in.
+const SHARED_SERVICES_NAMESPACE = "phenix-shared-services"
+
+// Serializes updates to shared service attachments so concurrently started
+// and stopped experiments don't clobber each other's reference counts.
+var sharedServicesMu sync.Mutex
+
+func init() {
+	RegisterUserApp("shared-services", func() App { return new(SharedServices) })
+}
+
+type SharedServicesMetadata struct {
+	Attachments []SharedServiceAttachment `mapstructure:"attachments"`
+}
+
+// SharedServiceAttachment attaches a gateway node in the experiment to a
+// shared service network. The gateway node is the only node in the experiment
+// given an interface on the shared service network.
+type SharedServiceAttachment struct {
+	Service   string `mapstructure:"service"`
+	Gateway   string `mapstructure:"gateway"`
+	Interface string `mapstructure:"interface"`
+	Address   string `mapstructure:"address"`
+}
+
+type SharedServicesStatus struct {
+	Services []string `structs:"services" mapstructure:"services"`
+}
+
+// SharedServiceStatus is stored as the status of shared service configs,
+// tracking the experiments currently attached to the service (and the gateway
+// addresses they're using) so the service is only torn down once the last
+// experiment is detached.
+type SharedServiceStatus struct {
+	Attached map[string][]string `structs:"attached" mapstructure:"attached"`
+	Tap      *tap.Tap            `structs:"tap" mapstructure:"tap"`
+}
+
+type SharedServices struct{}
+
+func (SharedServices) Init(...Option) error {
+	return nil
+}
+
+func (SharedServices) Name() string {
+	return "shared-services"
+}
+
+func (this SharedServices) Validate(exp *types.Experiment) error {
+	md, err := this.metadata(exp)
+	if err != nil {
+		return err
+	}
+
+	var (
+		errs     error
+		attached = make(map[string]string)
+	)
+
+	for _, a := range md.Attachments {
+		svc, err := GetSharedService(a.Service)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		if gw, ok := attached[a.Service]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("shared service %s already attached via gateway %s", a.Service, gw))
+			continue
+		}
+
+		attached[a.Service] = a.Gateway
+
+		node := exp.Spec.Topology().FindNodeByName(a.Gateway)
+		if node == nil {
+			errs = multierror.Append(errs, fmt.Errorf("gateway %s for shared service %s not in topology", a.Gateway, a.Service))
+			continue
+		}
+
+		if node.External() {
+			errs = multierror.Append(errs, fmt.Errorf("gateway %s for shared service %s cannot be an external node", a.Gateway, a.Service))
+		}
+
+		if a.Address == "" {
+			continue
+		}
+
+		addr, err := netaddr.ParseIPPrefix(a.Address)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid address %s for gateway %s (expected CIDR notation)", a.Address, a.Gateway))
+			continue
+		}
+
+		subnet, err := netaddr.ParseIPPrefix(svc.Spec.Subnet)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid subnet %s for shared service %s", svc.Spec.Subnet, a.Service))
+			continue
+		}
+
+		if !subnet.Contains(addr.IP()) {
+			errs = multierror.Append(errs, fmt.Errorf("address %s for gateway %s not in shared service %s subnet %s", a.Address, a.Gateway, a.Service, svc.Spec.Subnet))
+		}
+	}
+
+	return errs
+}
+
+func (this SharedServices) Configure(ctx context.Context, exp *types.Experiment) error {
+	md, err := this.metadata(exp)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range md.Attachments {
+		svc, err := GetSharedService(a.Service)
+		if err != nil {
+			return err
+		}
+
+		if err := checkSharedServiceVLAN(exp, svc); err != nil {
+			return err
+		}
+
+		node := exp.Spec.Topology().FindNodeByName(a.Gateway)
+		if node == nil {
+			return fmt.Errorf("gateway %s for shared service %s not in topology", a.Gateway, a.Service)
+		}
+
+		name := a.Interface
+		if name == "" {
+			name = "shared-" + a.Service
+		}
+
+		var exists bool
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Name() == name {
+				exists = true
+				break
+			}
+		}
+
+		// Configure may be run again for the same experiment (ie. when it's
+		// reconfigured), so don't add the interface twice.
+		if exists {
+			continue
+		}
+
+		bridge := svc.Spec.Bridge
+		if bridge == "" {
+			bridge = exp.Spec.DefaultBridge()
+		}
+
+		// Using the VLAN ID (instead of an alias) puts the interface on the same
+		// VLAN no matter which experiment namespace the gateway is in.
+		iface := node.AddNetworkInterface("ethernet", name, strconv.Itoa(svc.Spec.VLAN))
+		iface.SetBridge(bridge)
+
+		if a.Address == "" {
+			iface.SetProto("dhcp")
+			continue
+		}
+
+		addr, err := netaddr.ParseIPPrefix(a.Address)
+		if err != nil {
+			return fmt.Errorf("invalid address %s for gateway %s: %w", a.Address, a.Gateway, err)
+		}
+
+		iface.SetProto("static")
+		iface.SetAddress(addr.IP().String())
+		iface.SetMask(int(addr.Bits()))
+	}
+
+	return nil
+}
+
+func (this SharedServices) PreStart(ctx context.Context, exp *types.Experiment) error {
+	// Dry runs don't launch any VMs, so there's no need to bring up any shared
+	// services.
+	if exp.DryRun() {
+		return nil
+	}
+
+	md, err := this.metadata(exp)
+	if err != nil {
+		return err
+	}
+
+	var status SharedServicesStatus
+
+	for _, a := range md.Attachments {
+		if err := AttachSharedService(a.Service, exp.Metadata.Name, a.Address); err != nil {
+			// Detach from any shared services already attached to so they're not
+			// left up on behalf of an experiment that failed to start.
+			for _, svc := range status.Services {
+				DetachSharedService(svc, exp.Metadata.Name)
+			}
+
+			return fmt.Errorf("attaching to shared service %s: %w", a.Service, err)
+		}
+
+		status.Services = append(status.Services, a.Service)
+	}
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	return nil
+}
+
+func (SharedServices) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (SharedServices) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this SharedServices) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	var status SharedServicesStatus
+
+	if err := exp.Status.ParseAppStatus(this.Name(), &status); err != nil {
+		// Nothing was attached (ie. the experiment was a dry run).
+		return nil
+	}
+
+	var errs error
+
+	for _, svc := range status.Services {
+		if err := DetachSharedService(svc, exp.Metadata.Name); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("detaching from shared service %s: %w", svc, err))
+		}
+	}
+
+	return errs
+}
+
+func (this SharedServices) metadata(exp *types.Experiment) (SharedServicesMetadata, error) {
+	var md SharedServicesMetadata
+
+	app := exp.App(this.Name())
+	if app == nil {
+		return md, nil
+	}
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return md, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	return md, nil
+}
+
+// GetSharedService returns the shared service with the given name.
+func GetSharedService(name string) (*types.Service, error) {
+	c, _ := store.NewConfig("service/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("shared service %s not found", name)
+	}
+
+	spec := new(v1.ServiceSpec)
+
+	if err := mapstructure.Decode(c.Spec, spec); err != nil {
+		return nil, fmt.Errorf("decoding shared service spec: %w", err)
+	}
+
+	return &types.Service{Metadata: c.Metadata, Spec: spec}, nil
+}
+
+// AttachSharedService attaches the given experiment to the given shared
+// service. If this is the first experiment attached to the service, the
+// service's VLAN is reserved in minimega and the service's host tap (if
+// configured) is created. Attaching an experiment that's already attached only
+// updates the gateway address recorded for it.
+func AttachSharedService(name, exp, addr string) error {
+	sharedServicesMu.Lock()
+	defer sharedServicesMu.Unlock()
+
+	c, spec, status, err := sharedServiceConfig(name)
+	if err != nil {
+		return err
+	}
+
+	if addr != "" {
+		for other, addrs := range status.Attached {
+			if other == exp {
+				continue
+			}
+
+			for _, a := range addrs {
+				if a == addr {
+					return fmt.Errorf("address %s already in use by experiment %s", addr, other)
+				}
+			}
+		}
+	}
+
+	if len(status.Attached) == 0 {
+		if err := provisionSharedService(name, spec, status); err != nil {
+			return err
+		}
+	}
+
+	var addrs []string
+
+	if addr != "" {
+		addrs = []string{addr}
+	}
+
+	status.Attached[exp] = addrs
+
+	plog.Info("attached experiment to shared service", "exp", exp, "service", name, "attached", len(status.Attached))
+
+	return saveSharedServiceStatus(c, status)
+}
+
+// DetachSharedService detaches the given experiment from the given shared
+// service. The service's VLAN reservation and host tap are only torn down
+// once the last experiment attached to the service is detached.
+func DetachSharedService(name, exp string) error {
+	sharedServicesMu.Lock()
+	defer sharedServicesMu.Unlock()
+
+	c, spec, status, err := sharedServiceConfig(name)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := status.Attached[exp]; !ok {
+		return nil
+	}
+
+	delete(status.Attached, exp)
+
+	plog.Info("detached experiment from shared service", "exp", exp, "service", name, "attached", len(status.Attached))
+
+	if len(status.Attached) == 0 {
+		if err := teardownSharedService(name, spec, status); err != nil {
+			// Still record the detachment so the service gets provisioned again
+			// the next time an experiment attaches to it.
+			saveSharedServiceStatus(c, status)
+			return err
+		}
+	}
+
+	return saveSharedServiceStatus(c, status)
+}
+
+func sharedServiceConfig(name string) (*store.Config, *v1.ServiceSpec, *SharedServiceStatus, error) {
+	c, _ := store.NewConfig("service/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, nil, nil, fmt.Errorf("shared service %s not found", name)
+	}
+
+	spec := new(v1.ServiceSpec)
+
+	if err := mapstructure.Decode(c.Spec, spec); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding shared service spec: %w", err)
+	}
+
+	status := new(SharedServiceStatus)
+
+	if c.Status != nil {
+		if err := mapstructure.Decode(c.Status, status); err != nil {
+			return nil, nil, nil, fmt.Errorf("decoding shared service status: %w", err)
+		}
+	}
+
+	if status.Attached == nil {
+		status.Attached = make(map[string][]string)
+	}
+
+	return c, spec, status, nil
+}
+
+func saveSharedServiceStatus(c *store.Config, status *SharedServiceStatus) error {
+	c.Status = structs.MapDefaultCase(status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating shared service %s status: %w", c.Metadata.Name, err)
+	}
+
+	return nil
+}
+
+func provisionSharedService(name string, spec *v1.ServiceSpec, status *SharedServiceStatus) error {
+	plog.Info("provisioning shared service", "service", name, "vlan", spec.VLAN)
+
+	// Reserving the VLAN ID keeps minimega from allocating it to another
+	// experiment's VLAN alias while the shared service is in use.
+	if err := reserveSharedServiceVLAN(name, spec.VLAN); err != nil {
+		return err
+	}
+
+	if spec.Tap == nil {
+		return nil
+	}
+
+	t := &tap.Tap{
+		Name:     fmt.Sprintf("%s-svctap", util.RandomString(8)),
+		Bridge:   spec.Bridge,
+		VLAN:     name,
+		IP:       spec.Tap.IP,
+		External: tap.External{Enabled: spec.Tap.ExternalAccess},
+	}
+
+	t.Init("phenix", tap.Experiment(SHARED_SERVICES_NAMESPACE), tap.UsedPairs(Tap{}.discoverUsedPairs()))
+
+	if _, err := t.Create(mm.Headnode()); err != nil {
+		clearSharedServiceVLAN(name)
+		return fmt.Errorf("creating host tap for shared service %s: %w", name, err)
+	}
+
+	status.Tap = t
+
+	return nil
+}
+
+func teardownSharedService(name string, spec *v1.ServiceSpec, status *SharedServiceStatus) error {
+	plog.Info("tearing down shared service", "service", name, "vlan", spec.VLAN)
+
+	var errs error
+
+	if t := status.Tap; t != nil {
+		t.Init("phenix", tap.Experiment(SHARED_SERVICES_NAMESPACE))
+
+		if err := t.Delete(mm.Headnode()); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("deleting host tap for shared service %s: %w", name, err))
+		}
+
+		status.Tap = nil
+	}
+
+	if err := clearSharedServiceVLAN(name); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return errs
+}
+
+func reserveSharedServiceVLAN(name string, id int) error {
+	cmd := mmcli.NewNamespacedCommand(SHARED_SERVICES_NAMESPACE)
+	cmd.Command = fmt.Sprintf("vlans add %s %d", name, id)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("reserving VLAN %d for shared service %s: %w", id, name, err)
+	}
+
+	return nil
+}
+
+func clearSharedServiceVLAN(name string) error {
+	cmd := mmcli.NewNamespacedCommand(SHARED_SERVICES_NAMESPACE)
+	cmd.Command = "clear vlans " + name
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("clearing VLAN reservation for shared service %s: %w", name, err)
+	}
+
+	// Clearing VLANs in minimega is by prefix, so restore the reservations of
+	// any other shared services in use whose name starts with this one.
+	configs, err := store.List("Service")
+	if err != nil {
+		return nil
+	}
+
+	for _, c := range configs {
+		other := c.Metadata.Name
+
+		if other == name || !strings.HasPrefix(other, name) {
+			continue
+		}
+
+		if _, spec, status, err := sharedServiceConfig(other); err == nil && len(status.Attached) > 0 {
+			reserveSharedServiceVLAN(other, spec.VLAN)
+		}
+	}
+
+	return nil
+}
+
+// checkSharedServiceVLAN ensures the given shared service's VLAN doesn't
+// collide with any VLANs the given experiment may be allocated.
+func checkSharedServiceVLAN(exp *types.Experiment, svc *types.Service) error {
+	vlans := exp.Spec.VLANs()
+
+	if min, max := vlans.Min(), vlans.Max(); min != 0 && max != 0 {
+		if svc.Spec.VLAN >= min && svc.Spec.VLAN <= max {
+			return fmt.Errorf("shared service %s VLAN %d is within experiment VLAN range %d-%d", svc.Metadata.Name, svc.Spec.VLAN, min, max)
+		}
+	}
+
+	for alias, id := range vlans.Aliases() {
+		if id == svc.Spec.VLAN {
+			return fmt.Errorf("shared service %s VLAN %d already used by experiment VLAN alias %s", svc.Metadata.Name, svc.Spec.VLAN, alias)
+		}
+	}
+
+	return nil
+}
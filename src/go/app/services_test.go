@@ -0,0 +1,38 @@
+package app
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+)
+
+func TestCheckSharedServiceVLAN(t *testing.T) {
+	svc := &types.Service{
+		Metadata: store.ConfigMetadata{Name: "internet"},
+		Spec:     &v1.ServiceSpec{VLAN: 3000, Subnet: "10.255.0.0/24"},
+	}
+
+	spec := &v1.ExperimentSpec{
+		VLANsF: &v1.VLANSpec{MinF: 100, MaxF: 200},
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := checkSharedServiceVLAN(exp, svc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	spec.VLANsF.MaxF = 3500
+
+	if err := checkSharedServiceVLAN(exp, svc); err == nil {
+		t.Errorf("expected error for shared service VLAN within experiment VLAN range")
+	}
+
+	spec.VLANsF = &v1.VLANSpec{AliasesF: map[string]int{"EXP": 3000}}
+
+	if err := checkSharedServiceVLAN(exp, svc); err == nil {
+		t.Errorf("expected error for shared service VLAN used by experiment VLAN alias")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"phenix/tmpl"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/mm"
@@ -47,6 +48,11 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 		return fmt.Errorf("creating experiment startup directory path: %w", err)
 	}
 
+	site, err := types.SiteSettings()
+	if err != nil {
+		return fmt.Errorf("getting site settings: %w", err)
+	}
+
 	for _, node := range exp.Spec.Topology().Nodes() {
 		// check for duplicate IPs (including any non-minimega topology nodes)
 		if node.Network() != nil && node.Network().Interfaces() != nil {
@@ -77,7 +83,13 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 			}
 		}
 
-		if node.External() {
+		if node.External() || !Targeted(exp, this.Name(), node) {
+			continue
+		}
+
+		// Diskless (PXE booted) nodes don't have a disk image to check or inject
+		// startup configs into.
+		if len(node.Hardware().Drives()) == 0 {
 			continue
 		}
 
@@ -99,12 +111,16 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 			continue
 		}
 
+		setSiteDNS(node, site.DNS)
+
 		switch strings.ToLower(node.Hardware().OSType()) {
 		case "linux", "rhel", "centos":
 			var (
 				hostnameFile = startupDir + "/" + node.General().Hostname() + "-hostname.sh"
 				timezoneFile = startupDir + "/" + node.General().Hostname() + "-timezone.sh"
 				ifaceFile    = startupDir + "/" + node.General().Hostname() + "-interfaces.sh"
+
+				locale = nodeLocale(exp, node)
 			)
 
 			node.AddInject(
@@ -127,6 +143,10 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 
 			timeZone := "Etc/UTC"
 
+			if locale.Timezone != "" {
+				timeZone = locale.Timezone
+			}
+
 			if err := tmpl.CreateFileFromTemplate("linux_hostname.tmpl", node.General().Hostname(), hostnameFile); err != nil {
 				return fmt.Errorf("generating linux hostname script: %w", err)
 			}
@@ -135,9 +155,86 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 				return fmt.Errorf("generating linux timezone script: %w", err)
 			}
 
-			if err := tmpl.CreateFileFromTemplate("linux_interfaces.tmpl", node, ifaceFile); err != nil {
+			if locale.Locale != "" || locale.Keyboard != "" {
+				localeFile := startupDir + "/" + node.General().Hostname() + "-locale.sh"
+
+				node.AddInject(
+					localeFile,
+					"/etc/phenix/startup/2_locale-start.sh",
+					"0755", "",
+				)
+
+				if err := tmpl.CreateFileFromTemplate("linux_locale.tmpl", locale, localeFile); err != nil {
+					return fmt.Errorf("generating linux locale script: %w", err)
+				}
+			}
+
+			// Temporary struct to send to the Linux interfaces template.
+			data := struct {
+				ifaces.NodeSpec
+				Domain string
+			}{
+				NodeSpec: node,
+				Domain:   site.Domain,
+			}
+
+			if err := tmpl.CreateFileFromTemplate("linux_interfaces.tmpl", data, ifaceFile); err != nil {
 				return fmt.Errorf("generating linux interfaces script: %w", err)
 			}
+
+			if site.Mirror != nil && site.Mirror.URL != "" {
+				mirrorFile := startupDir + "/" + node.General().Hostname() + "-mirror.sh"
+
+				node.AddInject(
+					mirrorFile,
+					"/etc/phenix/startup/4_mirror-start.sh",
+					"0755", "",
+				)
+
+				data := struct {
+					Mirror v1.SiteMirror
+					startupPackages
+				}{
+					Mirror:          *site.Mirror,
+					startupPackages: nodePackages(exp, node),
+				}
+
+				if err := tmpl.CreateFileFromTemplate("linux_mirror.tmpl", data, mirrorFile); err != nil {
+					return fmt.Errorf("generating linux package mirror script: %w", err)
+				}
+			} else if pkgs := nodePackages(exp, node); !pkgs.empty() {
+				plog.Warn("not installing declared packages - no package mirror configured in site settings", "node", node.General().Hostname())
+			}
+
+			if sysctl := node.Kernel().Sysctl(); len(sysctl) > 0 {
+				sysctlFile := startupDir + "/" + node.General().Hostname() + "-sysctl.conf"
+
+				node.AddInject(
+					sysctlFile,
+					"/etc/sysctl.d/90-phenix.conf",
+					"0644", "",
+				)
+
+				if err := tmpl.CreateFileFromTemplate("linux_sysctl.tmpl", sysctl, sysctlFile); err != nil {
+					return fmt.Errorf("generating linux sysctl config: %w", err)
+				}
+			}
+
+			// Kernel boot parameters are added to the bootloader config by a startup
+			// script that runs first and reboots the VM once so they take effect.
+			if args := node.Kernel().Args(); len(args) > 0 {
+				kernelFile := startupDir + "/" + node.General().Hostname() + "-kernel-args.sh"
+
+				node.AddInject(
+					kernelFile,
+					"/etc/phenix/startup/0_kernel-args-start.sh",
+					"0755", "",
+				)
+
+				if err := tmpl.CreateFileFromTemplate("linux_kernel_args.tmpl", args, kernelFile); err != nil {
+					return fmt.Errorf("generating linux kernel args script: %w", err)
+				}
+			}
 		case "windows":
 			startupFile := startupDir + "/" + node.General().Hostname() + "-startup.ps1"
 
@@ -173,22 +270,22 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 			data := struct {
 				Node     ifaces.NodeSpec
 				Metadata map[string]interface{}
+				Domain   string
+				Locale   startupLocale
+				Mirror   *v1.SiteMirror
+				Packages startupPackages
 			}{
 				Node:     node,
-				Metadata: make(map[string]interface{}),
+				Metadata: startupHostMetadata(exp, node.General().Hostname()),
+				Domain:   site.Domain,
+				Locale:   windowsLocale(node, nodeLocale(exp, node)),
+				Packages: nodePackages(exp, node),
 			}
 
-			// Check to see if a scenario exists for this experiment and if it
-			// contains a "startup" app. If so, see if this node has a metadata entry
-			// in the scenario app configuration.
-			for _, app := range exp.Apps() {
-				if app.Name() == "startup" {
-					for _, host := range app.Hosts() {
-						if host.Hostname() == node.General().Hostname() {
-							data.Metadata = host.Metadata()
-						}
-					}
-				}
+			if site.Mirror != nil && site.Mirror.URL != "" {
+				data.Mirror = site.Mirror
+			} else if !data.Packages.empty() {
+				plog.Warn("not installing declared packages - no package mirror configured in site settings", "node", node.General().Hostname())
 			}
 
 			if err := tmpl.CreateFileFromTemplate("windows_startup.tmpl", data, startupFile); err != nil {
@@ -200,8 +297,129 @@ func (this Startup) PreStart(ctx context.Context, exp *types.Experiment) error {
 	return nil
 }
 
-func (Startup) PostStart(ctx context.Context, exp *types.Experiment) error {
-	for _, node := range exp.Spec.Topology().Nodes() {
+// startupHostMetadata returns the metadata for the given host in the startup
+// app configured in the experiment's scenario, if any.
+func startupHostMetadata(exp *types.Experiment, hostname string) map[string]interface{} {
+	for _, app := range exp.Apps() {
+		if app.Name() == "startup" {
+			for _, host := range app.Hosts() {
+				if host.Hostname() == hostname {
+					return host.Metadata()
+				}
+			}
+		}
+	}
+
+	return make(map[string]interface{})
+}
+
+// startupPackages are the packages declared for a node in its startup app host
+// metadata, which the startup app installs from the site package mirror.
+// Packages are installed with the node's OS package manager (apt or yum on
+// Linux), while Pip and Choco packages are installed with pip and chocolatey.
+type startupPackages struct {
+	Packages []string `mapstructure:"packages"`
+	Pip      []string `mapstructure:"pip"`
+	Choco    []string `mapstructure:"choco"`
+}
+
+func (this startupPackages) empty() bool {
+	return len(this.Packages) == 0 && len(this.Pip) == 0 && len(this.Choco) == 0
+}
+
+// nodePackages returns the packages declared for the given node.
+func nodePackages(exp *types.Experiment, node ifaces.NodeSpec) startupPackages {
+	var pkgs startupPackages
+
+	if err := mapstructure.Decode(startupHostMetadata(exp, node.General().Hostname()), &pkgs); err != nil {
+		plog.Error("decoding declared packages from startup app metadata", "node", node.General().Hostname(), "err", err)
+	}
+
+	return pkgs
+}
+
+// startupLocale is the locale, timezone, and keyboard layout the startup app
+// configures on a node. Empty values are left as configured in the image.
+type startupLocale struct {
+	Locale   string
+	Timezone string
+	Keyboard string
+}
+
+// nodeLocale returns the locale settings for the given node, with any settings
+// configured for the node overriding those configured for the experiment.
+func nodeLocale(exp *types.Experiment, node ifaces.NodeSpec) startupLocale {
+	var locale startupLocale
+
+	for _, l := range []ifaces.LocaleSpec{exp.Spec.Locale(), node.Locale()} {
+		if l == nil {
+			continue
+		}
+
+		if l.Locale() != "" {
+			locale.Locale = l.Locale()
+		}
+
+		if l.Timezone() != "" {
+			locale.Timezone = l.Timezone()
+		}
+
+		if l.Keyboard() != "" {
+			locale.Keyboard = l.Keyboard()
+		}
+	}
+
+	return locale
+}
+
+// windowsLocale converts the given locale settings for use on a Windows node.
+// POSIX locales (ie. `de_DE.UTF-8`) are converted to Windows culture names
+// (ie. `de-DE`). Windows expects Windows time zone IDs (ie. `W. Europe
+// Standard Time`) and keyboard input method tips (ie. `0407:00000407`), so
+// IANA time zones and X11 keyboard layouts (typically set for the whole
+// experiment) are skipped; Windows values should be set on Windows nodes.
+func windowsLocale(node ifaces.NodeSpec, locale startupLocale) startupLocale {
+	if locale.Locale != "" {
+		culture, _, _ := strings.Cut(locale.Locale, ".")
+		locale.Locale = strings.ReplaceAll(culture, "_", "-")
+	}
+
+	if strings.Contains(locale.Timezone, "/") {
+		plog.Warn("skipping IANA time zone for Windows node", "node", node.General().Hostname(), "timezone", locale.Timezone)
+		locale.Timezone = ""
+	}
+
+	if locale.Keyboard != "" && !strings.Contains(locale.Keyboard, ":") {
+		plog.Warn("skipping non-Windows keyboard layout for Windows node", "node", node.General().Hostname(), "keyboard", locale.Keyboard)
+		locale.Keyboard = ""
+	}
+
+	return locale
+}
+
+// setSiteDNS configures the given site DNS servers on the node's statically
+// configured interfaces that have a default gateway, unless DNS servers were
+// already configured for any of the node's interfaces.
+func setSiteDNS(node ifaces.NodeSpec, dns []string) {
+	if len(dns) == 0 || node.Network() == nil {
+		return
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		if len(iface.DNS()) > 0 {
+			return
+		}
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		if iface.Gateway() != "" && !strings.EqualFold(iface.Proto(), "dhcp") {
+			iface.SetDNS(dns)
+		}
+	}
+}
+
+func (this Startup) PostStart(ctx context.Context, exp *types.Experiment) error {
+	for _, node := range TargetNodes(exp, this.Name()) {
 		if node.External() {
 			continue
 		}
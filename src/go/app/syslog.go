@@ -0,0 +1,435 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	RegisterUserApp("syslog", func() App { return new(Syslog) })
+}
+
+const (
+	// SYSLOG_COLLECTOR_DIR is the directory logs are written to on the collector.
+	SYSLOG_COLLECTOR_DIR = "/var/log/phenix"
+
+	// SYSLOG_FILES_DIR is the directory, relative to the experiment files
+	// directory, logs pulled from the collector are written to.
+	SYSLOG_FILES_DIR = "syslog"
+)
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: syslog
+      metadata:
+        collector: syslog-collector
+        server: fluent-bit # default is rsyslog
+        protocol: tcp      # default is udp
+        port: 5140         # default is 514
+        windowsLogs:       # default is System and Application
+        - System
+        - Security
+*/
+
+type SyslogAppMetadata struct {
+	Collector   string   `mapstructure:"collector"`
+	Server      string   `mapstructure:"server"`
+	Protocol    string   `mapstructure:"protocol"`
+	Port        int      `mapstructure:"port"`
+	WindowsLogs []string `mapstructure:"windowsLogs"`
+}
+
+type syslogConfig struct {
+	Collector   ifaces.NodeSpec
+	Address     string
+	Server      string
+	Protocol    string
+	Port        int
+	Dir         string
+	WindowsLogs []string
+}
+
+// Syslog stands up a syslog collector (rsyslog or Fluent Bit) on the topology
+// node given in the app metadata and configures every other Linux and Windows
+// node targeted by the app to forward its logs to the collector. Linux nodes
+// forward via rsyslog, and Windows nodes via a scheduled task that forwards
+// new events from the configured event logs. Logs written by the collector are
+// pulled into the experiment files each time the app's running stage is
+// applied, and once more when the experiment is stopped, and registered as
+// artifacts of the app.
+type Syslog struct{}
+
+func (Syslog) Init(...Option) error {
+	return nil
+}
+
+func (Syslog) Name() string {
+	return "syslog"
+}
+
+func (Syslog) MetadataSchema() []byte {
+	return []byte(`
+type: object
+required:
+- collector
+properties:
+  collector:
+    type: string
+  server:
+    type: string
+    enum:
+    - rsyslog
+    - fluent-bit
+  protocol:
+    type: string
+    enum:
+    - udp
+    - tcp
+  port:
+    type: integer
+    minimum: 1
+    maximum: 65535
+  windowsLogs:
+    type: array
+    items:
+      type: string
+additionalProperties: false
+`)
+}
+
+// Validate checks that the collector and settings given in the app metadata
+// are valid.
+func (this Syslog) Validate(exp *types.Experiment) error {
+	_, err := this.config(exp)
+	return err
+}
+
+func (Syslog) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this Syslog) PreStart(ctx context.Context, exp *types.Experiment) error {
+	cfg, err := this.config(exp)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	syslogDir := exp.Spec.BaseDir() + "/syslog"
+
+	if err := os.MkdirAll(syslogDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment syslog directory path: %w", err)
+	}
+
+	var (
+		collector = cfg.Collector.General().Hostname()
+		src       = syslogDir + "/" + collector + "-collector.conf"
+	)
+
+	switch cfg.Server {
+	case "rsyslog":
+		if err := tmpl.CreateFileFromTemplate("syslog_rsyslog_collector.tmpl", cfg, src); err != nil {
+			return fmt.Errorf("generating rsyslog collector config: %w", err)
+		}
+
+		cfg.Collector.AddInject(src, "/etc/rsyslog.d/10-phenix-collector.conf", "", "")
+	case "fluent-bit":
+		if err := tmpl.CreateFileFromTemplate("syslog_fluentbit_collector.tmpl", cfg, src); err != nil {
+			return fmt.Errorf("generating Fluent Bit collector config: %w", err)
+		}
+
+		cfg.Collector.AddInject(src, "/etc/fluent-bit/fluent-bit.conf", "", "")
+	}
+
+	var (
+		linux   = syslogDir + "/linux-forward.conf"
+		windows = syslogDir + "/windows-forward.ps1"
+		startup = syslogDir + "/windows-startup.ps1"
+	)
+
+	if err := tmpl.CreateFileFromTemplate("syslog_linux_forward.tmpl", cfg, linux); err != nil {
+		return fmt.Errorf("generating Linux syslog forwarding config: %w", err)
+	}
+
+	if err := tmpl.CreateFileFromTemplate("syslog_windows_forward.tmpl", cfg, windows); err != nil {
+		return fmt.Errorf("generating Windows syslog forwarding script: %w", err)
+	}
+
+	if err := tmpl.CreateFileFromTemplate("syslog_windows_startup.tmpl", cfg, startup); err != nil {
+		return fmt.Errorf("generating Windows syslog startup script: %w", err)
+	}
+
+	for _, node := range TargetNodes(exp, this.Name()) {
+		if node.External() || strings.EqualFold(node.Type(), "router") {
+			continue
+		}
+
+		if node.General().Hostname() == collector {
+			continue
+		}
+
+		switch strings.ToLower(node.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
+			node.AddInject(linux, "/etc/rsyslog.d/90-phenix-forward.conf", "", "")
+		case "windows":
+			node.AddInject(windows, "/phenix/syslog-forward.ps1", "", "")
+			node.AddInject(startup, "/phenix/startup/27-syslog.ps1", "0755", "")
+		}
+	}
+
+	return nil
+}
+
+func (Syslog) PostStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// Running pulls the logs written by the collector into the experiment files.
+func (this Syslog) Running(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	cfg, err := this.config(exp)
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	return this.pull(ctx, exp, cfg)
+}
+
+// Cleanup pulls the logs written by the collector into the experiment files
+// one last time before the collector is killed. Failing to pull the logs
+// doesn't keep the experiment from being stopped.
+func (this Syslog) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	cfg, err := this.config(exp)
+	if err != nil || cfg == nil {
+		return nil
+	}
+
+	if err := this.pull(ctx, exp, cfg); err != nil {
+		plog.Warn("pulling syslog collector logs", "exp", exp.Metadata.Name, "collector", cfg.Collector.General().Hostname(), "err", err)
+	}
+
+	return nil
+}
+
+// pull copies the logs written by the collector into the experiment files via
+// the collector's miniccc agent, registering each log file as an artifact.
+func (this Syslog) pull(ctx context.Context, exp *types.Experiment, cfg *syslogConfig) error {
+	collector := cfg.Collector.General().Hostname()
+
+	opts := []mm.C2Option{
+		mm.C2NS(exp.Metadata.Name),
+		mm.C2VM(collector),
+		mm.C2Context(ctx),
+		mm.C2Timeout(5 * time.Minute),
+		mm.C2Wait(),
+		mm.C2Command(fmt.Sprintf(`bash -c "tar -C %s -czf - . | base64 -w 0"`, SYSLOG_COLLECTOR_DIR)),
+	}
+
+	id, err := mm.ExecC2Command(opts...)
+	if err != nil {
+		return fmt.Errorf("archiving logs on collector %s: %w", collector, err)
+	}
+
+	resp, err := mm.GetC2Response(mm.C2NS(exp.Metadata.Name), mm.C2VM(collector), mm.C2CommandID(id), mm.C2ResponseTypeStdout())
+	if err != nil {
+		return fmt.Errorf("getting logs from collector %s: %w", collector, err)
+	}
+
+	archive, err := base64.StdEncoding.DecodeString(strings.TrimSpace(resp))
+	if err != nil {
+		return fmt.Errorf("decoding logs from collector %s: %w", collector, err)
+	}
+
+	files, err := extractSyslogArchive(archive, filepath.Join(exp.FilesDir(), SYSLOG_FILES_DIR))
+	if err != nil {
+		return fmt.Errorf("extracting logs from collector %s: %w", collector, err)
+	}
+
+	for _, f := range files {
+		var (
+			path = filepath.Join(SYSLOG_FILES_DIR, f)
+			name = strings.TrimSuffix(f, filepath.Ext(f))
+			desc = fmt.Sprintf("Logs forwarded to syslog collector %s by %s", collector, name)
+		)
+
+		if err := RegisterArtifact(exp, this.Name(), name, path, "text/plain", desc); err != nil {
+			return fmt.Errorf("registering log file %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// extractSyslogArchive extracts the regular files in the given gzipped tarball
+// into the given directory, returning their paths relative to the directory.
+// Nested paths are flattened, and paths that would escape the directory are
+// skipped.
+func extractSyslogArchive(archive []byte, dir string) ([]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("reading log archive: %w", err)
+	}
+
+	defer gz.Close()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating syslog files directory: %w", err)
+	}
+
+	var (
+		tr    = tar.NewReader(gz)
+		files []string
+	)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return files, fmt.Errorf("reading log archive: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Base(filepath.Clean(hdr.Name))
+
+		if name == "." || name == ".." || name == "/" {
+			continue
+		}
+
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return files, fmt.Errorf("creating log file %s: %w", name, err)
+		}
+
+		_, err = io.Copy(f, tr)
+		f.Close()
+
+		if err != nil {
+			return files, fmt.Errorf("writing log file %s: %w", name, err)
+		}
+
+		files = append(files, name)
+	}
+
+	return files, nil
+}
+
+// config returns the syslog config for the given experiment, or nil if the
+// experiment doesn't include the app.
+func (this Syslog) config(exp *types.Experiment) (*syslogConfig, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil, nil
+	}
+
+	var md SyslogAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return nil, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	if md.Collector == "" {
+		return nil, fmt.Errorf("no collector provided in %s app metadata", this.Name())
+	}
+
+	collector := exp.Spec.Topology().FindNodeByName(md.Collector)
+	if collector == nil {
+		return nil, fmt.Errorf("syslog collector %s not in topology", md.Collector)
+	}
+
+	if collector.External() {
+		return nil, fmt.Errorf("syslog collector %s cannot be an external node", md.Collector)
+	}
+
+	switch strings.ToLower(collector.Hardware().OSType()) {
+	case "linux", "rhel", "centos":
+	default:
+		return nil, fmt.Errorf("syslog collector %s must be a Linux node (got OS type %s)", md.Collector, collector.Hardware().OSType())
+	}
+
+	cfg := &syslogConfig{
+		Collector:   collector,
+		Server:      strings.ToLower(md.Server),
+		Protocol:    strings.ToLower(md.Protocol),
+		Port:        md.Port,
+		Dir:         SYSLOG_COLLECTOR_DIR,
+		WindowsLogs: md.WindowsLogs,
+	}
+
+	for _, iface := range collector.Network().Interfaces() {
+		if iface.Address() != "" && !strings.EqualFold(iface.Proto(), "dhcp") {
+			cfg.Address = iface.Address()
+			break
+		}
+	}
+
+	var errs error
+
+	if cfg.Address == "" {
+		errs = multierror.Append(errs, fmt.Errorf("no static IP address on syslog collector %s", md.Collector))
+	}
+
+	switch cfg.Server {
+	case "":
+		cfg.Server = "rsyslog"
+	case "rsyslog", "fluent-bit":
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid syslog server %s (expected rsyslog or fluent-bit)", md.Server))
+	}
+
+	switch cfg.Protocol {
+	case "":
+		cfg.Protocol = "udp"
+	case "udp", "tcp":
+	default:
+		errs = multierror.Append(errs, fmt.Errorf("invalid syslog protocol %s (expected udp or tcp)", md.Protocol))
+	}
+
+	switch {
+	case cfg.Port == 0:
+		cfg.Port = 514
+	case cfg.Port < 0 || cfg.Port > 65535:
+		errs = multierror.Append(errs, fmt.Errorf("invalid syslog port %d", md.Port))
+	}
+
+	if len(cfg.WindowsLogs) == 0 {
+		cfg.WindowsLogs = []string{"System", "Application"}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return cfg, nil
+}
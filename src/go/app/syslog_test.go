@@ -0,0 +1,246 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestSyslogApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "syslog-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "collector",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.5", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "web",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "hmi",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "windows",
+			},
+		},
+		{
+			TypeF: "Router",
+			GeneralF: &v1.General{
+				HostnameF: "rtr",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "syslog",
+				MetadataF: map[string]any{
+					"collector":   "collector",
+					"protocol":    "tcp",
+					"port":        5140,
+					"windowsLogs": []any{"Security"},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("syslog").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[int][]string{
+		0: {"/etc/rsyslog.d/10-phenix-collector.conf"},
+		1: {"/etc/rsyslog.d/90-phenix-forward.conf"},
+		2: {"/phenix/syslog-forward.ps1", "/phenix/startup/27-syslog.ps1"},
+		3: nil,
+	}
+
+	for i, dsts := range expected {
+		injects := nodes[i].Injections()
+
+		if len(injects) != len(dsts) {
+			t.Logf("expected %d injections for host %s, got %d", len(dsts), nodes[i].General().Hostname(), len(injects))
+			t.FailNow()
+		}
+
+		for j, dst := range dsts {
+			if injects[j].Dst() != dst {
+				t.Logf("expected injection to %s for host %s, got %s", dst, nodes[i].General().Hostname(), injects[j].Dst())
+				t.FailNow()
+			}
+		}
+	}
+
+	files := map[string][]string{
+		"collector-collector.conf": {`module(load="imtcp")`, `port="5140"`, `/var/log/phenix/%HOSTNAME%.log`},
+		"linux-forward.conf":       {"*.* @@10.0.1.5:5140"},
+		"windows-forward.ps1":      {"$logs = @('Security')", "TcpClient('10.0.1.5', 5140)"},
+	}
+
+	for name, contents := range files {
+		body, err := os.ReadFile(baseDir + "/syslog/" + name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, c := range contents {
+			if !strings.Contains(string(body), c) {
+				t.Logf("expected %s to contain %q, got: %s", name, c, body)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestSyslogAppValidate(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "collector",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "windows",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.5", MaskF: 24},
+				},
+			},
+		},
+	}
+
+	cases := map[string]map[string]any{
+		"missing collector": {"server": "rsyslog"},
+		"unknown collector": {"collector": "foo"},
+		"windows collector": {"collector": "collector"},
+	}
+
+	for name, md := range cases {
+		spec := &v1.ExperimentSpec{
+			ExperimentNameF: "test",
+			TopologyF:       &v1.TopologySpec{NodesF: nodes},
+			ScenarioF: &v2.ScenarioSpec{
+				AppsF: []*v2.ScenarioApp{{NameF: "syslog", MetadataF: md}},
+			},
+		}
+
+		if err := new(Syslog).Validate(&types.Experiment{Spec: spec}); err == nil {
+			t.Logf("expected validation error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+
+	nodes[0].HardwareF.OSTypeF = "linux"
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: &v2.ScenarioSpec{
+			AppsF: []*v2.ScenarioApp{
+				{NameF: "syslog", MetadataF: map[string]any{"collector": "collector", "server": "syslog-ng", "protocol": "relp"}},
+			},
+		},
+	}
+
+	err := new(Syslog).Validate(&types.Experiment{Spec: spec})
+	if err == nil || strings.Count(err.Error(), "\n\t* ") != 2 {
+		t.Logf("expected two validation errors, got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestExtractSyslogArchive(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		gz  = gzip.NewWriter(&buf)
+		tw  = tar.NewWriter(gz)
+	)
+
+	entries := []struct {
+		name string
+		typ  byte
+		body string
+	}{
+		{"./", tar.TypeDir, ""},
+		{"./web.log", tar.TypeReg, "web log\n"},
+		{"../../etc/passwd", tar.TypeReg, "escaped\n"},
+		{"./link", tar.TypeSymlink, ""},
+	}
+
+	for _, e := range entries {
+		tw.WriteHeader(&tar.Header{Name: e.name, Typeflag: e.typ, Mode: 0644, Size: int64(len(e.body))})
+		tw.Write([]byte(e.body))
+	}
+
+	tw.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+
+	files, err := extractSyslogArchive(buf.Bytes(), dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if strings.Join(files, ",") != "web.log,passwd" {
+		t.Logf("expected web.log and flattened passwd to be extracted, got %v", files)
+		t.FailNow()
+	}
+
+	body, _ := os.ReadFile(dir + "/web.log")
+
+	if string(body) != "web log\n" {
+		t.Logf("unexpected contents of extracted web.log: %q", body)
+		t.FailNow()
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"math/rand"
 	"time"
 
+	"phenix/store"
 	"phenix/types"
 	"phenix/util"
 	"phenix/util/mm"
 	"phenix/util/tap"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
 	"golang.org/x/exp/slices"
 	"inet.af/netaddr"
 )
@@ -139,9 +141,22 @@ func (this *Tap) Cleanup(ctx context.Context, exp *types.Experiment) error {
 func (this Tap) discoverUsedPairs() []netaddr.IPPrefix {
 	var pairs []netaddr.IPPrefix
 
+	// Host taps for shared services in use also use pairs from the same subnet.
+	if services, err := store.List("Service"); err == nil {
+		for _, svc := range services {
+			var status SharedServiceStatus
+
+			if err := mapstructure.Decode(svc.Status, &status); err == nil && status.Tap != nil {
+				if pair, err := netaddr.ParseIPPrefix(status.Tap.Subnet); err == nil {
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+
 	running, err := types.Experiments(true)
 	if err != nil {
-		return nil
+		return pairs
 	}
 
 	for _, exp := range running {
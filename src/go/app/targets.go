@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+	"path"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+)
+
+// Targeted returns true if the given node is targeted by the scenario app with
+// the given name in the given experiment. Every node is targeted by apps that
+// aren't configured in the experiment's scenario or that don't declare targets.
+func Targeted(exp *types.Experiment, name string, node ifaces.NodeSpec) bool {
+	app := exp.App(name)
+	if app == nil {
+		return true
+	}
+
+	targets := app.Targets()
+	if targets == nil {
+		return true
+	}
+
+	return targets.Match(node)
+}
+
+// TargetNodes returns the topology nodes in the given experiment targeted by
+// the scenario app with the given name.
+func TargetNodes(exp *types.Experiment, name string) []ifaces.NodeSpec {
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if Targeted(exp, name, node) {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
+
+// validateTargets checks that the hostname patterns in the given scenario app's
+// targets are valid and that its targets match at least one topology node.
+func validateTargets(exp *types.Experiment, app ifaces.ScenarioApp) error {
+	targets := app.Targets()
+	if targets == nil {
+		return nil
+	}
+
+	for _, h := range targets.Hostnames() {
+		if _, err := path.Match(h, ""); err != nil {
+			return fmt.Errorf("invalid target hostname pattern %s for app %s: %w", h, app.Name(), err)
+		}
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if targets.Match(node) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("targets for app %s don't match any topology nodes", app.Name())
+}
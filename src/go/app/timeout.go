@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TIMEOUT_KEY is the scenario app metadata key used to limit how long each
+// stage of an app is allowed to run for (ie. `10m`) before it's canceled.
+// Timeouts set via the AppTimeout option take precedence.
+const TIMEOUT_KEY = "timeout"
+
+var (
+	ErrAppTimeout   = errors.New("app timed out")
+	ErrStageTimeout = errors.New("stage timed out")
+)
+
+// appTimeout returns how long the given app is allowed to run for the current
+// stage, or zero if there's no limit. The given metadata is the app's scenario
+// metadata, if any.
+func appTimeout(name string, md map[string]any, options Options) (time.Duration, error) {
+	if timeout, ok := options.AppTimeouts[name]; ok {
+		return timeout, nil
+	}
+
+	if val, ok := md[TIMEOUT_KEY]; ok {
+		str, ok := val.(string)
+		if !ok {
+			return 0, fmt.Errorf("invalid %s for app %s (expected duration string)", TIMEOUT_KEY, name)
+		}
+
+		timeout, err := time.ParseDuration(str)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s for app %s: %w", TIMEOUT_KEY, name, err)
+		}
+
+		return timeout, nil
+	}
+
+	return options.AppTimeout, nil
+}
+
+// runWithTimeout calls the given function with a context that's canceled once
+// the given timeout elapses (never if zero). If the timeout elapsed, the error
+// returned wraps ErrAppTimeout. Apps are expected to return once their context
+// is canceled; user apps have their process terminated.
+func runWithTimeout(ctx context.Context, name string, timeout time.Duration, f func(context.Context) error) error {
+	if timeout <= 0 {
+		return f(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := f(ctx)
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s after %v: %v", ErrAppTimeout, name, timeout, err)
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"phenix/types"
+)
+
+func TestAppTimeout(t *testing.T) {
+	md := map[string]any{TIMEOUT_KEY: "5m"}
+
+	timeout, err := appTimeout("soh", md, NewOptions(AppTimeout(time.Minute)))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if timeout != 5*time.Minute {
+		t.Logf("expected app metadata timeout to take precedence over default timeout, got %v", timeout)
+		t.FailNow()
+	}
+
+	timeout, _ = appTimeout("soh", md, NewOptions(AppTimeout(time.Minute), AppTimeout(time.Second, "soh")))
+
+	if timeout != time.Second {
+		t.Logf("expected per-app timeout option to take precedence over app metadata, got %v", timeout)
+		t.FailNow()
+	}
+
+	timeout, _ = appTimeout("tap", nil, NewOptions(AppTimeout(time.Minute), AppTimeout(time.Second, "soh")))
+
+	if timeout != time.Minute {
+		t.Logf("expected default timeout for app without timeout, got %v", timeout)
+		t.FailNow()
+	}
+
+	if timeout, _ := appTimeout("tap", nil, NewOptions()); timeout != 0 {
+		t.Logf("expected no timeout by default, got %v", timeout)
+		t.FailNow()
+	}
+
+	for _, val := range []any{"soon", 10} {
+		if _, err := appTimeout("soh", map[string]any{TIMEOUT_KEY: val}, NewOptions()); err == nil {
+			t.Logf("expected invalid timeout %v to be rejected", val)
+			t.FailNow()
+		}
+	}
+}
+
+func TestApplyAppsTimeouts(t *testing.T) {
+	hang := func(ctx context.Context, _ *types.Experiment) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	registerTestApp(t, "hang", hang)
+
+	opts := []Option{Stage(ACTIONPOSTSTART), DryRun(true), SkipApp(builtinDefaultApps...)}
+
+	exp := newTestExperiment(t, map[string]any{"name": "hang", "metadata": map[string]any{TIMEOUT_KEY: "50ms"}})
+
+	err := ApplyApps(context.Background(), exp, opts...)
+	if !errors.Is(err, ErrAppTimeout) {
+		t.Logf("expected app timeout error, got %v", err)
+		t.FailNow()
+	}
+
+	if errors.Is(err, ErrStageTimeout) {
+		t.Logf("expected app timeout not to be reported as stage timeout, got %v", err)
+		t.FailNow()
+	}
+
+	if run := exp.Status.AppRuns()["hang"]; run == nil || run[string(ACTIONPOSTSTART)] == nil || run[string(ACTIONPOSTSTART)].Result() != "error" {
+		t.Logf("expected timed out app run to be recorded as an error, got %v", run)
+		t.FailNow()
+	}
+
+	exp = newTestExperiment(t, map[string]any{"name": "hang"})
+
+	err = ApplyApps(context.Background(), exp, append(opts, StageTimeout(50*time.Millisecond))...)
+	if !errors.Is(err, ErrStageTimeout) {
+		t.Logf("expected stage timeout error, got %v", err)
+		t.FailNow()
+	}
+
+	// Apps can't outlive the stage timeout, even if their own timeout is longer.
+	exp = newTestExperiment(t, map[string]any{"name": "hang", "metadata": map[string]any{TIMEOUT_KEY: "1h"}})
+
+	started := time.Now()
+
+	err = ApplyApps(context.Background(), exp, append(opts, StageTimeout(50*time.Millisecond))...)
+	if !errors.Is(err, ErrStageTimeout) || time.Since(started) > 5*time.Second {
+		t.Logf("expected stage timeout error, got %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,555 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	RegisterUserApp("traffic", func() App { return new(Traffic) })
+}
+
+// TRAFFIC_SCRIPT is the path the traffic script is injected to on nodes.
+const TRAFFIC_SCRIPT = "/etc/phenix/traffic.sh"
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: traffic
+      metadata:
+        tool: iperf            # default tool for flows (iperf or protonuke)
+        flows:
+        - name: bulk           # default is flow-<index>
+          client: ws01
+          server: fileserver
+          protocol: udp        # iperf: tcp (default) or udp
+          port: 5202           # iperf only, default is 5201
+          rate: 10M            # iperf: target bitrate
+          duration: 10m        # default is until the experiment is stopped
+          start: 30s           # delay after the post-start stage
+        - client: ws02
+          server: web
+          tool: protonuke
+          protocol: https      # protonuke: http (default), https, ssh, smtp, ftp, or dns
+          rate: 500ms          # protonuke: time between requests
+          profile:
+            type: random       # constant (default), bursty, or random
+            on: 5s-30s         # bursty: duration, random: min-max range
+            off: 1m-5m
+*/
+
+type TrafficAppMetadata struct {
+	Tool  string        `mapstructure:"tool"`
+	Flows []TrafficFlow `mapstructure:"flows"`
+}
+
+type TrafficFlow struct {
+	Name     string         `mapstructure:"name"`
+	Client   string         `mapstructure:"client"`
+	Server   string         `mapstructure:"server"`
+	Tool     string         `mapstructure:"tool"`
+	Protocol string         `mapstructure:"protocol"`
+	Port     int            `mapstructure:"port"`
+	Rate     string         `mapstructure:"rate"`
+	Duration string         `mapstructure:"duration"`
+	Start    string         `mapstructure:"start"`
+	Profile  TrafficProfile `mapstructure:"profile"`
+}
+
+type TrafficProfile struct {
+	Type string `mapstructure:"type"`
+	On   string `mapstructure:"on"`
+	Off  string `mapstructure:"off"`
+}
+
+// trafficNode is the traffic generator config for a single node, used to
+// generate its traffic script.
+type trafficNode struct {
+	Node    ifaces.NodeSpec
+	Servers []trafficCommand
+	Flows   []trafficCommand
+}
+
+// trafficCommand is a traffic server or client flow run on a node. Times are
+// in seconds, and the on and off periods of random flows are `min:max` ranges.
+type trafficCommand struct {
+	Name     string
+	Command  string
+	Start    int
+	Duration int
+	Profile  string
+	On       string
+	Off      string
+}
+
+var (
+	trafficNameRegex      = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	trafficIPerfRateRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[KMGkmg]?$`)
+
+	trafficProtocols = map[string][]string{
+		"iperf":     {"tcp", "udp"},
+		"protonuke": {"http", "https", "ssh", "smtp", "ftp", "dns"},
+	}
+)
+
+// Traffic generates traffic between topology nodes from the flows given in the
+// app metadata, using iperf3 or protonuke (which must be installed in the node
+// images). Each flow has a client, a server, and optionally a rate, duration,
+// and start offset, and can be run constantly or in bursts of fixed or random
+// length. A traffic script is injected into every Linux node involved in a flow
+// in the pre-start stage, and run via the nodes' miniccc agents in the
+// post-start stage to start the servers and flows, which run in the background
+// until they complete or are stopped in the cleanup stage. Their output is
+// logged to `/var/log/phenix/traffic` on each node.
+type Traffic struct{}
+
+func (Traffic) Init(...Option) error {
+	return nil
+}
+
+func (Traffic) Name() string {
+	return "traffic"
+}
+
+func (Traffic) MetadataSchema() []byte {
+	return []byte(`
+type: object
+required:
+- flows
+properties:
+  tool:
+    type: string
+    enum:
+    - iperf
+    - protonuke
+  flows:
+    type: array
+    items:
+      type: object
+      required:
+      - client
+      - server
+      properties:
+        name:
+          type: string
+        client:
+          type: string
+        server:
+          type: string
+        tool:
+          type: string
+          enum:
+          - iperf
+          - protonuke
+        protocol:
+          type: string
+        port:
+          type: integer
+          minimum: 1
+          maximum: 65535
+        rate:
+          type: string
+        duration:
+          type: string
+        start:
+          type: string
+        profile:
+          type: object
+          properties:
+            type:
+              type: string
+              enum:
+              - constant
+              - bursty
+              - random
+            "on":
+              type: string
+            "off":
+              type: string
+          additionalProperties: false
+      additionalProperties: false
+additionalProperties: false
+`)
+}
+
+// Validate checks that the flows given in the app metadata are valid.
+func (this Traffic) Validate(exp *types.Experiment) error {
+	_, err := this.config(exp)
+	return err
+}
+
+func (Traffic) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this Traffic) PreStart(ctx context.Context, exp *types.Experiment) error {
+	nodes, err := this.config(exp)
+	if err != nil || len(nodes) == 0 {
+		return err
+	}
+
+	trafficDir := exp.Spec.BaseDir() + "/traffic"
+
+	if err := os.MkdirAll(trafficDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment traffic directory path: %w", err)
+	}
+
+	for _, node := range nodes {
+		src := trafficDir + "/" + node.Node.General().Hostname() + ".sh"
+
+		if err := tmpl.CreateFileFromTemplate("traffic_linux.tmpl", node, src); err != nil {
+			return fmt.Errorf("generating traffic script for node %s: %w", node.Node.General().Hostname(), err)
+		}
+
+		node.Node.AddInject(src, TRAFFIC_SCRIPT, "0755", "")
+	}
+
+	return nil
+}
+
+// PostStart starts the traffic servers and flows on each node involved in a
+// flow.
+func (this Traffic) PostStart(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	nodes, err := this.config(exp)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		_, err := mm.ExecC2Command(
+			mm.C2NS(exp.Metadata.Name),
+			mm.C2VM(node.Node.General().Hostname()),
+			mm.C2SkipActiveClientCheck(true),
+			mm.C2Command("bash "+TRAFFIC_SCRIPT+" start"),
+		)
+
+		if err != nil {
+			return fmt.Errorf("starting traffic on node %s: %w", node.Node.General().Hostname(), err)
+		}
+	}
+
+	return nil
+}
+
+func (Traffic) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// Cleanup stops the traffic servers and flows on each node involved in a flow.
+// Failing to stop them doesn't keep the experiment from being stopped, since
+// they're killed along with the nodes anyway.
+func (this Traffic) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	nodes, err := this.config(exp)
+	if err != nil {
+		return nil
+	}
+
+	for _, node := range nodes {
+		_, err := mm.ExecC2Command(
+			mm.C2NS(exp.Metadata.Name),
+			mm.C2VM(node.Node.General().Hostname()),
+			mm.C2Context(ctx),
+			mm.C2Command("bash "+TRAFFIC_SCRIPT+" stop"),
+		)
+
+		if err != nil {
+			plog.Warn("stopping traffic", "exp", exp.Metadata.Name, "vm", node.Node.General().Hostname(), "err", err)
+		}
+	}
+
+	return nil
+}
+
+// config returns the traffic generator config for each node involved in a
+// flow, in the order the nodes are first referenced by the flows.
+func (this Traffic) config(exp *types.Experiment) ([]*trafficNode, error) {
+	app := exp.App(this.Name())
+	if app == nil {
+		return nil, nil
+	}
+
+	var md TrafficAppMetadata
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return nil, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	var (
+		nodes   []*trafficNode
+		byName  = make(map[string]*trafficNode)
+		servers = make(map[string]struct{})
+		names   = make(map[string]struct{})
+		errs    error
+	)
+
+	node := func(role, name string) *trafficNode {
+		if n, ok := byName[name]; ok {
+			return n
+		}
+
+		n := exp.Spec.Topology().FindNodeByName(name)
+
+		switch {
+		case n == nil:
+			errs = multierror.Append(errs, fmt.Errorf("traffic %s %s not in topology", role, name))
+			return nil
+		case n.External():
+			errs = multierror.Append(errs, fmt.Errorf("traffic %s %s cannot be an external node", role, name))
+			return nil
+		}
+
+		switch strings.ToLower(n.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("traffic %s %s must be a Linux node (got OS type %s)", role, name, n.Hardware().OSType()))
+			return nil
+		}
+
+		tn := &trafficNode{Node: n}
+
+		nodes = append(nodes, tn)
+		byName[name] = tn
+
+		return tn
+	}
+
+	for i, f := range md.Flows {
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("flow-%d", i)
+		}
+
+		if !trafficNameRegex.MatchString(f.Name) {
+			errs = multierror.Append(errs, fmt.Errorf("invalid name for flow %s (must only contain letters, numbers, underscores, and dashes)", f.Name))
+			continue
+		}
+
+		if _, ok := names[f.Name]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("duplicate flow %s", f.Name))
+			continue
+		}
+
+		names[f.Name] = struct{}{}
+
+		flow, server, address, err := trafficFlow(exp, f, md.Tool)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("flow %s: %w", f.Name, err))
+			continue
+		}
+
+		var (
+			client = node("client", f.Client)
+			target = node("server", f.Server)
+		)
+
+		if client == nil || target == nil {
+			continue
+		}
+
+		if address == "" {
+			errs = multierror.Append(errs, fmt.Errorf("flow %s: no static IP address on server %s", f.Name, f.Server))
+			continue
+		}
+
+		flow.Command += " " + address
+		client.Flows = append(client.Flows, flow)
+
+		// Flows sharing a server share its server process.
+		if _, ok := servers[f.Server+"|"+server.Command]; !ok {
+			servers[f.Server+"|"+server.Command] = struct{}{}
+			target.Servers = append(target.Servers, server)
+		}
+	}
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	return nodes, nil
+}
+
+// trafficFlow returns the client flow (minus the server address, which is
+// returned separately) and server commands for the given flow.
+func trafficFlow(exp *types.Experiment, f TrafficFlow, tool string) (trafficCommand, trafficCommand, string, error) {
+	var flow, server trafficCommand
+
+	if f.Tool != "" {
+		tool = f.Tool
+	}
+
+	if tool == "" {
+		tool = "iperf"
+	}
+
+	protocols, ok := trafficProtocols[tool]
+	if !ok {
+		return flow, server, "", fmt.Errorf("unknown tool %s", tool)
+	}
+
+	protocol := strings.ToLower(f.Protocol)
+
+	if protocol == "" {
+		protocol = protocols[0]
+	}
+
+	if !slices.Contains(protocols, protocol) {
+		return flow, server, "", fmt.Errorf("unsupported %s protocol %s", tool, f.Protocol)
+	}
+
+	flow = trafficCommand{Name: f.Name, Profile: "constant"}
+
+	var err error
+
+	if flow.Start, err = trafficSeconds(f.Start); err != nil {
+		return flow, server, "", fmt.Errorf("invalid start offset: %w", err)
+	}
+
+	if flow.Duration, err = trafficSeconds(f.Duration); err != nil {
+		return flow, server, "", fmt.Errorf("invalid duration: %w", err)
+	}
+
+	switch strings.ToLower(f.Profile.Type) {
+	case "", "constant":
+	case "bursty":
+		on, err := trafficSeconds(f.Profile.On)
+		if err != nil || on == 0 {
+			return flow, server, "", fmt.Errorf("bursty profile requires an 'on' duration")
+		}
+
+		off, err := trafficSeconds(f.Profile.Off)
+		if err != nil || off == 0 {
+			return flow, server, "", fmt.Errorf("bursty profile requires an 'off' duration")
+		}
+
+		flow.Profile, flow.On, flow.Off = "bursty", strconv.Itoa(on), strconv.Itoa(off)
+	case "random":
+		if flow.On, err = trafficRange(f.Profile.On); err != nil {
+			return flow, server, "", fmt.Errorf("invalid 'on' range for random profile: %w", err)
+		}
+
+		if flow.Off, err = trafficRange(f.Profile.Off); err != nil {
+			return flow, server, "", fmt.Errorf("invalid 'off' range for random profile: %w", err)
+		}
+
+		flow.Profile = "random"
+	default:
+		return flow, server, "", fmt.Errorf("unknown profile %s", f.Profile.Type)
+	}
+
+	switch tool {
+	case "iperf":
+		port := f.Port
+
+		if port == 0 {
+			port = 5201
+		}
+
+		server = trafficCommand{Name: fmt.Sprintf("iperf-%d", port), Command: fmt.Sprintf("iperf3 -s -p %d", port)}
+		flow.Command = fmt.Sprintf("iperf3 -t 0 -p %d", port)
+
+		if protocol == "udp" {
+			flow.Command += " -u"
+		}
+
+		if f.Rate != "" {
+			if !trafficIPerfRateRegex.MatchString(f.Rate) {
+				return flow, server, "", fmt.Errorf("invalid iperf rate %s (ie. 10M)", f.Rate)
+			}
+
+			flow.Command += " -b " + f.Rate
+		}
+
+		flow.Command += " -c"
+	case "protonuke":
+		server = trafficCommand{Name: "protonuke-" + protocol, Command: "protonuke -serve -" + protocol}
+		flow.Command = "protonuke -" + protocol
+
+		if f.Rate != "" {
+			if _, err := time.ParseDuration(f.Rate); err != nil {
+				return flow, server, "", fmt.Errorf("invalid protonuke rate %s (ie. 500ms)", f.Rate)
+			}
+
+			flow.Command += " -u " + f.Rate
+		}
+	}
+
+	var address string
+
+	if n := exp.Spec.Topology().FindNodeByName(f.Server); n != nil {
+		for _, iface := range n.Network().Interfaces() {
+			if iface.Address() != "" && !strings.EqualFold(iface.Proto(), "dhcp") {
+				address = iface.Address()
+				break
+			}
+		}
+	}
+
+	return flow, server, address, nil
+}
+
+// trafficSeconds returns the given duration in whole seconds (rounded up), or
+// zero if it's empty.
+func trafficSeconds(d string) (int, error) {
+	if d == "" {
+		return 0, nil
+	}
+
+	dur, err := time.ParseDuration(d)
+	if err != nil {
+		return 0, err
+	}
+
+	if dur < 0 {
+		return 0, fmt.Errorf("duration %s cannot be negative", d)
+	}
+
+	return int(math.Ceil(dur.Seconds())), nil
+}
+
+// trafficRange returns the given `min-max` duration range (or maximum duration,
+// with a minimum of one second) as a `min:max` range of whole seconds.
+func trafficRange(r string) (string, error) {
+	lo, hi, ok := strings.Cut(r, "-")
+	if !ok {
+		lo, hi = "1s", lo
+	}
+
+	min, err := trafficSeconds(lo)
+	if err != nil {
+		return "", err
+	}
+
+	max, err := trafficSeconds(hi)
+	if err != nil {
+		return "", err
+	}
+
+	if min == 0 || max < min {
+		return "", fmt.Errorf("range %s must be a positive min-max range (ie. 5s-30s)", r)
+	}
+
+	return fmt.Sprintf("%d:%d", min, max), nil
+}
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+)
+
+func TestTrafficApp(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "traffic-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "server",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.5", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "client",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "idle",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "traffic",
+				MetadataF: map[string]any{
+					"flows": []any{
+						map[string]any{
+							"name":     "bulk",
+							"client":   "client",
+							"server":   "server",
+							"protocol": "udp",
+							"rate":     "10M",
+							"duration": "10m",
+							"start":    "1m30s",
+						},
+						map[string]any{
+							"name":    "bulk2",
+							"client":  "client",
+							"server":  "server",
+							"profile": map[string]any{"type": "bursty", "on": "10s", "off": "1m"},
+						},
+						map[string]any{
+							"client":   "client",
+							"server":   "server",
+							"tool":     "protonuke",
+							"protocol": "https",
+							"rate":     "500ms",
+							"profile":  map[string]any{"type": "random", "on": "5s-30s", "off": "2m"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        baseDir,
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF:       scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("traffic").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[int]int{0: 1, 1: 1, 2: 0}
+
+	for i, count := range expected {
+		injects := nodes[i].Injections()
+
+		if len(injects) != count {
+			t.Logf("expected %d injections for host %s, got %d", count, nodes[i].General().Hostname(), len(injects))
+			t.FailNow()
+		}
+
+		if count > 0 && injects[0].Dst() != TRAFFIC_SCRIPT {
+			t.Logf("expected injection to %s for host %s, got %s", TRAFFIC_SCRIPT, nodes[i].General().Hostname(), injects[0].Dst())
+			t.FailNow()
+		}
+	}
+
+	files := map[string][]string{
+		"server.sh": {
+			"setsid iperf3 -s -p 5201 >> $LOGS/server-iperf-5201.log",
+			"setsid protonuke -serve -https >> $LOGS/server-protonuke-https.log",
+		},
+		"client.sh": {
+			"flow bulk 90 600 constant 0 0 iperf3 -t 0 -p 5201 -u -b 10M -c 10.0.1.5 >>",
+			"flow bulk2 0 0 bursty 10 60 iperf3 -t 0 -p 5201 -c 10.0.1.5 >>",
+			"flow flow-2 0 0 random 5:30 1:120 protonuke -https -u 500ms 10.0.1.5 >>",
+		},
+	}
+
+	for name, contents := range files {
+		body, err := os.ReadFile(baseDir + "/traffic/" + name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, c := range contents {
+			if !strings.Contains(string(body), c) {
+				t.Logf("expected %s to contain %q, got: %s", name, c, body)
+				t.FailNow()
+			}
+		}
+	}
+
+	body, _ := os.ReadFile(baseDir + "/traffic/server.sh")
+
+	if strings.Count(string(body), "iperf3 -s") != 1 {
+		t.Logf("expected flows sharing a server to share its server process, got: %s", body)
+		t.FailNow()
+	}
+}
+
+func TestTrafficAppValidate(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "server",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "static", AddressF: "10.0.1.5", MaskF: 24},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "dhcp",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "linux",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", VLANF: "EXP_1", ProtoF: "dhcp"},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "hmi",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "windows",
+			},
+		},
+	}
+
+	flow := func(f map[string]any) map[string]any {
+		return map[string]any{"flows": []any{f}}
+	}
+
+	cases := map[string]map[string]any{
+		"unknown client":   flow(map[string]any{"client": "foo", "server": "server"}),
+		"windows client":   flow(map[string]any{"client": "hmi", "server": "server"}),
+		"no server IP":     flow(map[string]any{"client": "server", "server": "dhcp"}),
+		"unknown tool":     flow(map[string]any{"client": "server", "server": "server", "tool": "trex"}),
+		"bad protocol":     flow(map[string]any{"client": "server", "server": "server", "protocol": "http"}),
+		"bad rate":         flow(map[string]any{"client": "server", "server": "server", "rate": "fast"}),
+		"bad duration":     flow(map[string]any{"client": "server", "server": "server", "duration": "10"}),
+		"bad name":         flow(map[string]any{"client": "server", "server": "server", "name": "a flow"}),
+		"bursty no off":    flow(map[string]any{"client": "server", "server": "server", "profile": map[string]any{"type": "bursty", "on": "5s"}}),
+		"bad random range": flow(map[string]any{"client": "server", "server": "server", "profile": map[string]any{"type": "random", "on": "30s-5s", "off": "1m"}}),
+		"duplicate names": {"flows": []any{
+			map[string]any{"name": "a", "client": "server", "server": "server"},
+			map[string]any{"name": "a", "client": "server", "server": "server"},
+		}},
+	}
+
+	for name, md := range cases {
+		spec := &v1.ExperimentSpec{
+			ExperimentNameF: "test",
+			TopologyF:       &v1.TopologySpec{NodesF: nodes},
+			ScenarioF: &v2.ScenarioSpec{
+				AppsF: []*v2.ScenarioApp{{NameF: "traffic", MetadataF: md}},
+			},
+		}
+
+		if err := new(Traffic).Validate(&types.Experiment{Spec: spec}); err == nil {
+			t.Logf("expected validation error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: &v2.ScenarioSpec{
+			AppsF: []*v2.ScenarioApp{{NameF: "traffic", MetadataF: flow(map[string]any{"client": "dhcp", "server": "server"})}},
+		},
+	}
+
+	if err := new(Traffic).Validate(&types.Experiment{Spec: spec}); err != nil {
+		t.Logf("expected no validation error, got %v", err)
+		t.FailNow()
+	}
+}
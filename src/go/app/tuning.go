@@ -0,0 +1,467 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	RegisterUserApp("tuning", func() App { return new(Tuning) })
+}
+
+// DefaultTuningSysctls are the kernel parameters applied to experiment hosts
+// by the tuning app unless overridden in the app metadata. The defaults keep
+// large topologies from exhausting the ARP/NDP neighbor tables and the
+// connection tracking table, which otherwise causes hard to diagnose packet
+// loss between VMs.
+var DefaultTuningSysctls = map[string]string{
+	"net.ipv4.neigh.default.gc_thresh1": "8192",
+	"net.ipv4.neigh.default.gc_thresh2": "32768",
+	"net.ipv4.neigh.default.gc_thresh3": "65536",
+	"net.ipv6.neigh.default.gc_thresh1": "8192",
+	"net.ipv6.neigh.default.gc_thresh2": "32768",
+	"net.ipv6.neigh.default.gc_thresh3": "65536",
+	"net.netfilter.nf_conntrack_max":    "1048576",
+}
+
+// DefaultTuningBridge is the OVS bridge configuration applied to the
+// experiment bridge by the tuning app unless overridden in the app metadata.
+var DefaultTuningBridge = TuningAppBridge{MACAgingTime: 600, MACTableSize: 65536}
+
+/*
+spec:
+  scenario:
+    apps:
+    - name: tuning
+      metadata:
+        sysctls:
+          net.netfilter.nf_conntrack_max: 2097152
+          net.ipv6.neigh.default.gc_thresh3: "" # don't apply this default
+        hugepages: 1024
+        bridge:
+          macAgingTime: 300
+          macTableSize: 131072
+*/
+
+type TuningAppMetadata struct {
+	Sysctls   map[string]any   `mapstructure:"sysctls"`
+	Hugepages int              `mapstructure:"hugepages"`
+	Bridge    *TuningAppBridge `mapstructure:"bridge"`
+}
+
+type TuningAppBridge struct {
+	MACAgingTime int `mapstructure:"macAgingTime"`
+	MACTableSize int `mapstructure:"macTableSize"`
+}
+
+// TuningAppStatus tracks the original values of everything tuned on each
+// experiment host so they can be reverted at cleanup. An empty original OVS
+// bridge value means the key wasn't set.
+type TuningAppStatus struct {
+	Hosts map[string]TuningHostStatus `structs:"hosts" mapstructure:"hosts"`
+}
+
+type TuningHostStatus struct {
+	Sysctls     map[string]string `structs:"sysctls" mapstructure:"sysctls"`
+	Bridge      string            `structs:"bridge" mapstructure:"bridge"`
+	OtherConfig map[string]string `structs:"otherConfig" mapstructure:"otherConfig"`
+}
+
+// Tuning applies recommended kernel and OVS tunings to the cluster hosts an
+// experiment is scheduled on before it launches, and reverts them at cleanup.
+// Hosts shared with other running experiments using the tuning app are only
+// reverted once the last of those experiments is cleaned up. If experiments
+// sharing a host configure different values, the last experiment started wins.
+type Tuning struct{}
+
+func (Tuning) Init(...Option) error {
+	return nil
+}
+
+func (Tuning) Name() string {
+	return "tuning"
+}
+
+func (Tuning) MetadataSchema() []byte {
+	return []byte(`
+type: object
+properties:
+  sysctls:
+    type: object
+    additionalProperties:
+      anyOf:
+      - type: string
+      - type: number
+  hugepages:
+    type: integer
+    minimum: 0
+  bridge:
+    type: object
+    properties:
+      macAgingTime:
+        type: integer
+        minimum: 0
+      macTableSize:
+        type: integer
+        minimum: 0
+    additionalProperties: false
+additionalProperties: false
+`)
+}
+
+func (Tuning) Configure(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this Tuning) PreStart(ctx context.Context, exp *types.Experiment) error {
+	// Dry runs don't launch any VMs, so there's no need to tune any hosts.
+	if exp.DryRun() {
+		return nil
+	}
+
+	md, err := this.metadata(exp)
+	if err != nil {
+		return err
+	}
+
+	var (
+		sysctls = tuningSysctls(md)
+		keys    = sortedKeys(sysctls)
+		shared  = this.sharedHosts(exp.Metadata.Name)
+		status  = TuningAppStatus{Hosts: make(map[string]TuningHostStatus)}
+	)
+
+	hosts, err := tuningHosts(exp)
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts {
+		// Keep the original values recorded by another experiment already tuning
+		// this host, since the current values are already tuned.
+		if orig, ok := shared[host]; ok {
+			status.Hosts[host] = TuningHostStatus{Sysctls: orig.Sysctls}
+		} else {
+			out, err := mm.MeshShellResponse(host, "sysctl -e "+strings.Join(keys, " "))
+			if err != nil {
+				return fmt.Errorf("getting current kernel parameters on host %s: %w", host, err)
+			}
+
+			status.Hosts[host] = TuningHostStatus{Sysctls: parseSysctls(out)}
+		}
+
+		var args []string
+
+		for _, key := range keys {
+			if _, ok := status.Hosts[host].Sysctls[key]; !ok {
+				plog.Warn("kernel parameter not available on host", "host", host, "param", key)
+				continue
+			}
+
+			args = append(args, key+"="+sysctls[key])
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		if err := mm.MeshShell(host, "sysctl -w "+strings.Join(args, " ")); err != nil {
+			return fmt.Errorf("tuning kernel parameters on host %s: %w", host, err)
+		}
+
+		plog.Info("tuned kernel parameters on host", "exp", exp.Metadata.Name, "host", host, "params", len(args))
+	}
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	return nil
+}
+
+// PostStart tunes the experiment bridge, since minimega doesn't create it until
+// VMs are launched.
+func (this Tuning) PostStart(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	md, err := this.metadata(exp)
+	if err != nil {
+		return err
+	}
+
+	var status TuningAppStatus
+	if err := exp.Status.ParseAppStatus(this.Name(), &status); err != nil {
+		return fmt.Errorf("getting experiment status for %s app: %w", this.Name(), err)
+	}
+
+	var (
+		bridge = exp.Spec.DefaultBridge()
+		config = tuningOtherConfig(md)
+		keys   = sortedKeys(config)
+		shared = this.sharedHosts(exp.Metadata.Name)
+	)
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for host, hs := range status.Hosts {
+		if orig, ok := shared[host]; ok && orig.Bridge == bridge {
+			hs.OtherConfig = orig.OtherConfig
+		} else {
+			hs.OtherConfig = make(map[string]string)
+
+			for _, key := range keys {
+				// `ovs-vsctl get` fails if the key isn't set, so no response just means
+				// there's nothing to restore.
+				out, _ := mm.MeshShellResponse(host, fmt.Sprintf("ovs-vsctl get bridge %s other_config:%s", bridge, key))
+				hs.OtherConfig[key] = strings.Trim(out, `"`)
+			}
+		}
+
+		var args []string
+
+		for _, key := range keys {
+			args = append(args, fmt.Sprintf("other_config:%s=%s", key, config[key]))
+		}
+
+		if err := mm.MeshShell(host, fmt.Sprintf("ovs-vsctl set bridge %s %s", bridge, strings.Join(args, " "))); err != nil {
+			return fmt.Errorf("tuning bridge %s on host %s: %w", bridge, host, err)
+		}
+
+		hs.Bridge = bridge
+		status.Hosts[host] = hs
+	}
+
+	exp.Status.SetAppStatus(this.Name(), status)
+
+	return nil
+}
+
+func (Tuning) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this Tuning) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	if exp.DryRun() {
+		return nil
+	}
+
+	var status TuningAppStatus
+	if err := exp.Status.ParseAppStatus(this.Name(), &status); err != nil {
+		// Nothing was tuned if the experiment failed before pre-start.
+		return nil
+	}
+
+	var (
+		shared = this.sharedHosts(exp.Metadata.Name)
+		errs   error
+	)
+
+	for host, hs := range status.Hosts {
+		if _, ok := shared[host]; ok {
+			plog.Info("not reverting tuning on host still used by another experiment", "exp", exp.Metadata.Name, "host", host)
+			continue
+		}
+
+		if len(hs.Sysctls) > 0 {
+			var args []string
+
+			for _, key := range sortedKeys(hs.Sysctls) {
+				args = append(args, key+"="+hs.Sysctls[key])
+			}
+
+			if err := mm.MeshShell(host, "sysctl -w "+strings.Join(args, " ")); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("reverting kernel parameters on host %s: %w", host, err))
+			}
+		}
+
+		// The bridge is deleted along with the experiment if no other experiment is
+		// using it, but revert anyway in case it's still in use.
+		for _, key := range sortedKeys(hs.OtherConfig) {
+			cmd := fmt.Sprintf("ovs-vsctl set bridge %s other_config:%s=%s", hs.Bridge, key, hs.OtherConfig[key])
+
+			if hs.OtherConfig[key] == "" {
+				cmd = fmt.Sprintf("ovs-vsctl remove bridge %s other_config %s", hs.Bridge, key)
+			}
+
+			if err := mm.MeshShell(host, cmd); err != nil {
+				plog.Warn("unable to revert bridge tuning on host", "host", host, "bridge", hs.Bridge, "key", key, "err", err)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (this Tuning) metadata(exp *types.Experiment) (TuningAppMetadata, error) {
+	var md TuningAppMetadata
+
+	app := exp.App(this.Name())
+	if app == nil {
+		return md, nil
+	}
+
+	if err := app.ParseMetadata(&md); err != nil {
+		return md, fmt.Errorf("decoding %s app metadata: %w", this.Name(), err)
+	}
+
+	return md, nil
+}
+
+// sharedHosts returns the tuning status of hosts tuned by running experiments
+// other than the given one.
+func (this Tuning) sharedHosts(name string) map[string]TuningHostStatus {
+	shared := make(map[string]TuningHostStatus)
+
+	running, err := types.Experiments(true)
+	if err != nil {
+		return shared
+	}
+
+	for _, exp := range running {
+		if exp.Metadata.Name == name {
+			continue
+		}
+
+		var status TuningAppStatus
+		if err := exp.Status.ParseAppStatus(this.Name(), &status); err != nil {
+			continue
+		}
+
+		for host, hs := range status.Hosts {
+			shared[host] = hs
+		}
+	}
+
+	return shared
+}
+
+// tuningHosts returns the cluster hosts the experiment's VMs targeted by the
+// tuning app are scheduled on.
+// If any VMs aren't scheduled, minimega places them when they're launched, so
+// all schedulable cluster hosts are returned.
+func tuningHosts(exp *types.Experiment) ([]string, error) {
+	var (
+		schedules = exp.Spec.Schedules()
+		seen      = make(map[string]struct{})
+		hosts     []string
+	)
+
+	for _, node := range TargetNodes(exp, "tuning") {
+		if node.External() {
+			continue
+		}
+
+		if _, ok := schedules[node.General().Hostname()]; !ok {
+			cluster, err := mm.GetClusterHosts(true)
+			if err != nil {
+				return nil, fmt.Errorf("getting list of cluster hosts: %w", err)
+			}
+
+			for _, host := range cluster {
+				hosts = append(hosts, host.Name)
+			}
+
+			sort.Strings(hosts)
+
+			return hosts, nil
+		}
+	}
+
+	for _, host := range schedules {
+		if _, ok := seen[host]; ok || host == "" {
+			continue
+		}
+
+		seen[host] = struct{}{}
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	return hosts, nil
+}
+
+// tuningSysctls merges the default kernel parameters with the ones in the app
+// metadata. Parameters set to an empty value in the metadata aren't applied.
+func tuningSysctls(md TuningAppMetadata) map[string]string {
+	sysctls := make(map[string]string)
+
+	for k, v := range DefaultTuningSysctls {
+		sysctls[k] = v
+	}
+
+	for k, v := range md.Sysctls {
+		if v == nil || fmt.Sprint(v) == "" {
+			delete(sysctls, k)
+			continue
+		}
+
+		sysctls[k] = fmt.Sprint(v)
+	}
+
+	if md.Hugepages > 0 {
+		sysctls["vm.nr_hugepages"] = fmt.Sprint(md.Hugepages)
+	}
+
+	return sysctls
+}
+
+// tuningOtherConfig returns the OVS bridge `other_config` values to apply.
+// Zero values in the app metadata aren't applied.
+func tuningOtherConfig(md TuningAppMetadata) map[string]string {
+	bridge := DefaultTuningBridge
+
+	if md.Bridge != nil {
+		bridge = *md.Bridge
+	}
+
+	config := make(map[string]string)
+
+	if bridge.MACAgingTime > 0 {
+		config["mac-aging-time"] = fmt.Sprint(bridge.MACAgingTime)
+	}
+
+	if bridge.MACTableSize > 0 {
+		config["mac-table-size"] = fmt.Sprint(bridge.MACTableSize)
+	}
+
+	return config
+}
+
+// parseSysctls parses the `key = value` output of `sysctl`.
+func parseSysctls(out string) map[string]string {
+	sysctls := make(map[string]string)
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		sysctls[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return sysctls
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestTuningSysctls(t *testing.T) {
+	md := TuningAppMetadata{
+		Sysctls: map[string]any{
+			"net.netfilter.nf_conntrack_max":    2097152,
+			"net.ipv6.neigh.default.gc_thresh3": "",
+			"fs.inotify.max_user_instances":     "8192",
+		},
+		Hugepages: 1024,
+	}
+
+	sysctls := tuningSysctls(md)
+
+	if sysctls["net.netfilter.nf_conntrack_max"] != "2097152" {
+		t.Logf("expected conntrack max to be overridden, got %s", sysctls["net.netfilter.nf_conntrack_max"])
+		t.FailNow()
+	}
+
+	if _, ok := sysctls["net.ipv6.neigh.default.gc_thresh3"]; ok {
+		t.Log("expected empty sysctl value to remove default")
+		t.FailNow()
+	}
+
+	if sysctls["fs.inotify.max_user_instances"] != "8192" || sysctls["vm.nr_hugepages"] != "1024" {
+		t.Logf("expected additional sysctls to be included, got %v", sysctls)
+		t.FailNow()
+	}
+
+	if sysctls["net.ipv4.neigh.default.gc_thresh3"] != DefaultTuningSysctls["net.ipv4.neigh.default.gc_thresh3"] {
+		t.Log("expected defaults to be included")
+		t.FailNow()
+	}
+}
+
+func TestTuningOtherConfig(t *testing.T) {
+	config := tuningOtherConfig(TuningAppMetadata{})
+
+	if config["mac-aging-time"] != "600" || config["mac-table-size"] != "65536" {
+		t.Logf("expected default bridge config, got %v", config)
+		t.FailNow()
+	}
+
+	config = tuningOtherConfig(TuningAppMetadata{Bridge: &TuningAppBridge{MACTableSize: 131072}})
+
+	if _, ok := config["mac-aging-time"]; ok || config["mac-table-size"] != "131072" {
+		t.Logf("expected only mac table size to be set, got %v", config)
+		t.FailNow()
+	}
+}
+
+func TestParseSysctls(t *testing.T) {
+	out := "net.ipv4.neigh.default.gc_thresh1 = 128\nnet.netfilter.nf_conntrack_max = 262144\n"
+
+	sysctls := parseSysctls(out)
+
+	if len(sysctls) != 2 || sysctls["net.ipv4.neigh.default.gc_thresh1"] != "128" || sysctls["net.netfilter.nf_conntrack_max"] != "262144" {
+		t.Logf("unexpected parsed sysctls: %v", sysctls)
+		t.FailNow()
+	}
+}
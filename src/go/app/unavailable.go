@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"phenix/types"
+)
+
+// UnavailableApp stands in for an app belonging to an optional subsystem that
+// isn't available, so experiments using the app fail validation (and each app
+// stage) rather than shelling out to a user app that doesn't exist. Cleanup
+// doesn't fail though, so experiments using the app can still be stopped.
+type UnavailableApp struct {
+	name string
+	err  error
+}
+
+func (UnavailableApp) Init(...Option) error {
+	return nil
+}
+
+func (this UnavailableApp) Name() string {
+	return this.name
+}
+
+func (this UnavailableApp) Configure(context.Context, *types.Experiment) error {
+	return this.unavailable()
+}
+
+func (this UnavailableApp) PreStart(context.Context, *types.Experiment) error {
+	return this.unavailable()
+}
+
+func (this UnavailableApp) PostStart(context.Context, *types.Experiment) error {
+	return this.unavailable()
+}
+
+func (this UnavailableApp) Running(context.Context, *types.Experiment) error {
+	return this.unavailable()
+}
+
+func (UnavailableApp) Cleanup(context.Context, *types.Experiment) error {
+	return nil
+}
+
+func (this UnavailableApp) Validate(*types.Experiment) error {
+	return this.unavailable()
+}
+
+func (this UnavailableApp) unavailable() error {
+	return fmt.Errorf("app %s unavailable: %w", this.name, this.err)
+}
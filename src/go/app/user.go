@@ -10,20 +10,35 @@ import (
 	"strings"
 
 	"phenix/scheduler"
+	"phenix/store"
 	"phenix/types"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/mm"
+	"phenix/util/plog"
 	"phenix/util/shell"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 const (
 	EXIT_SCHEDULE int = 101
 )
 
+// Protocols user apps can use to exchange the experiment with phenix. Shell
+// apps are executed once per stage with the experiment on STDIN, while gRPC
+// apps are served as plugins (see `ServeRPCApp`).
+const (
+	USER_APP_PROTOCOL_SHELL = "shell"
+	USER_APP_PROTOCOL_GRPC  = "grpc"
+)
+
 var (
-	USER_APP_PREFIX    = "phenix-app-"
-	ErrUserAppNotFound = errors.New("user app not found")
+	USER_APP_PREFIX           = "phenix-app-"
+	ErrUserAppNotFound        = errors.New("user app not found")
+	ErrUserAppVersionNotFound = errors.New("user app version not installed")
 )
 
 type UserApp struct {
@@ -41,7 +56,7 @@ func (this UserApp) Name() string {
 }
 
 func (this UserApp) Configure(ctx context.Context, exp *types.Experiment) error {
-	if err := this.shellOut(ctx, ACTIONCONFIG, exp); err != nil {
+	if err := this.run(ctx, ACTIONCONFIG, exp); err != nil {
 		return fmt.Errorf("running user app: %w", err)
 	}
 
@@ -49,7 +64,7 @@ func (this UserApp) Configure(ctx context.Context, exp *types.Experiment) error
 }
 
 func (this UserApp) PreStart(ctx context.Context, exp *types.Experiment) error {
-	if err := this.shellOut(ctx, ACTIONPRESTART, exp); err != nil {
+	if err := this.run(ctx, ACTIONPRESTART, exp); err != nil {
 		return fmt.Errorf("running user app: %w", err)
 	}
 
@@ -57,7 +72,7 @@ func (this UserApp) PreStart(ctx context.Context, exp *types.Experiment) error {
 }
 
 func (this UserApp) PostStart(ctx context.Context, exp *types.Experiment) error {
-	if err := this.shellOut(ctx, ACTIONPOSTSTART, exp); err != nil {
+	if err := this.run(ctx, ACTIONPOSTSTART, exp); err != nil {
 		return fmt.Errorf("running user app: %w", err)
 	}
 
@@ -65,7 +80,7 @@ func (this UserApp) PostStart(ctx context.Context, exp *types.Experiment) error
 }
 
 func (this UserApp) Running(ctx context.Context, exp *types.Experiment) error {
-	if err := this.shellOut(ctx, ACTIONRUNNING, exp); err != nil {
+	if err := this.run(ctx, ACTIONRUNNING, exp); err != nil {
 		return fmt.Errorf("running user app: %w", err)
 	}
 
@@ -73,27 +88,175 @@ func (this UserApp) Running(ctx context.Context, exp *types.Experiment) error {
 }
 
 func (this UserApp) Cleanup(ctx context.Context, exp *types.Experiment) error {
-	if err := this.shellOut(ctx, ACTIONCLEANUP, exp); err != nil {
+	if err := this.run(ctx, ACTIONCLEANUP, exp); err != nil {
 		return fmt.Errorf("running user app: %w", err)
 	}
 
 	return nil
 }
 
-func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Experiment) error {
-	cmdName := USER_APP_PREFIX + this.options.Name
+// Validate ensures the version of the app pinned in the experiment scenario (if
+// any) has been installed, and that the environment variables and sandbox
+// declared for the app are valid. Secret references aren't resolved until the app is executed.
+func (this UserApp) Validate(exp *types.Experiment) error {
+	if app := exp.App(this.options.Name); app != nil {
+		for _, key := range []string{ENV_KEY, SECRET_ENV_KEY} {
+			if _, err := envVars(this.options.Name, app.Metadata(), key); err != nil {
+				return err
+			}
+		}
+
+		if _, err := appSandbox(this.options.Name, exp); err != nil {
+			return err
+		}
+	}
+
+	if this.pinnedVersion(exp) == "" {
+		return nil
+	}
+
+	if _, err := this.executable(exp); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (this UserApp) pinnedVersion(exp *types.Experiment) string {
+	if exp.Spec.Scenario() == nil {
+		return ""
+	}
+
+	if app := exp.Spec.Scenario().App(this.options.Name); app != nil {
+		return app.Version()
+	}
+
+	return ""
+}
+
+// userAppExecutable is the command to execute for a user app, along with the
+// installed version of the app the command corresponds to (if known) and the
+// protocol the app speaks.
+type userAppExecutable struct {
+	cmd      string
+	version  string
+	protocol string
+}
+
+// executable returns the command to execute for the app. If the experiment
+// scenario pins the app to a specific version, the executable kept for that
+// version when it was installed via `phenix app install` is used instead of
+// the one in the PATH. Otherwise, apps in the app registry use the executable,
+// version, and protocol from their registry entry. Apps not installed via
+// `phenix app install` or registered in the app registry are assumed to use the
+// shell protocol.
+func (this UserApp) executable(exp *types.Experiment) (userAppExecutable, error) {
+	var (
+		cmdName = USER_APP_PREFIX + this.options.Name
+		pinned  = this.pinnedVersion(exp)
+
+		c, _ = store.NewConfig("app/" + this.options.Name)
+		spec v1.AppSpec
+	)
+
+	registered := store.Get(c) == nil && mapstructure.Decode(c.Spec, &spec) == nil
+
+	if pinned != "" {
+		if !registered {
+			return userAppExecutable{}, fmt.Errorf("user app %s version %s pinned but app not installed: %w", this.options.Name, pinned, ErrUserAppVersionNotFound)
+		}
+
+		version, ok := spec.Versions[pinned]
+		if !ok {
+			return userAppExecutable{}, fmt.Errorf("user app %s version %s: %w", this.options.Name, pinned, ErrUserAppVersionNotFound)
+		}
+
+		return userAppExecutable{cmd: version.Executable, version: pinned, protocol: version.Protocol}, nil
+	}
+
+	// Apps in the registry take precedence over apps installed via `phenix app
+	// install` since the registry is managed outside of phenix.
+	if entry, ok := RegisteredApp(this.options.Name); ok && entry.Executable != "" {
+		return userAppExecutable{cmd: entry.Executable, version: entry.Version, protocol: entry.Protocol}, nil
+	}
+
+	path, err := exec.LookPath(cmdName)
+	if err != nil {
+		return userAppExecutable{}, fmt.Errorf("external user app %s does not exist in your path: %w", cmdName, ErrUserAppNotFound)
+	}
+
+	if entry, ok := RegisteredApp(this.options.Name); ok {
+		return userAppExecutable{cmd: cmdName, version: entry.Version, protocol: entry.Protocol}, nil
+	}
 
-	if !shell.CommandExists(cmdName) {
-		return fmt.Errorf("external user app %s does not exist in your path: %w", cmdName, ErrUserAppNotFound)
+	// Only report the registered version if the app in the PATH is the one that
+	// was installed via `phenix app install`.
+	if registered && path == spec.Executable {
+		return userAppExecutable{cmd: cmdName, version: spec.Version, protocol: spec.Protocol}, nil
+	}
+
+	return userAppExecutable{cmd: cmdName}, nil
+}
+
+// prepare gets the app's executable and environment variables ready for the
+// app to be run, updating the experiment with the cluster hosts and the app
+// version being run.
+func (this UserApp) prepare(exp *types.Experiment) (userAppExecutable, []string, error) {
+	exe, err := this.executable(exp)
+	if err != nil {
+		return exe, nil, err
+	}
+
+	if exe.version != "" {
+		exp.Status.SetAppVersion(this.options.Name, exe.version)
 	}
 
 	cluster, err := mm.GetClusterHosts(true)
 	if err != nil {
-		return fmt.Errorf("getting cluster hosts: %w", err)
+		return exe, nil, fmt.Errorf("getting cluster hosts: %w", err)
 	}
 
 	exp.Hosts = cluster
 
+	env := []string{
+		"PHENIX_DIR=" + common.PhenixBase,
+		"PHENIX_FILES_DIR=" + exp.FilesDir(),
+		"PHENIX_LOG_LEVEL=" + util.GetEnv("PHENIX_LOG_LEVEL", "DEBUG"),
+		"PHENIX_LOG_FILE=" + util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
+		"PHENIX_DRYRUN=" + strconv.FormatBool(this.options.DryRun),
+		"PHENIX_STORE_ENDPOINT=" + common.StoreEndpoint,
+	}
+
+	if app := exp.App(this.options.Name); app != nil {
+		vars, err := appEnv(this.options.Name, app.Metadata())
+		if err != nil {
+			return exe, nil, err
+		}
+
+		env = append(env, vars...)
+	}
+
+	return exe, env, nil
+}
+
+// run runs the app for the given action using the protocol the app speaks.
+func (this UserApp) run(ctx context.Context, action Action, exp *types.Experiment) error {
+	exe, env, err := this.prepare(exp)
+	if err != nil {
+		return err
+	}
+
+	switch exe.protocol {
+	case "", USER_APP_PROTOCOL_SHELL:
+		return this.shellOut(ctx, action, exp, exe.cmd, env)
+	case USER_APP_PROTOCOL_GRPC:
+		return this.rpcOut(ctx, action, exp, exe.cmd, env)
+	default:
+		return fmt.Errorf("user app %s uses unknown protocol %s", this.options.Name, exe.protocol)
+	}
+}
+
+func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Experiment, cmdName string, env []string) error {
 	data, err := json.Marshal(exp)
 	if err != nil {
 		return fmt.Errorf("marshaling experiment to JSON: %w", err)
@@ -104,16 +267,56 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 		shell.Args(string(action)),
 		shell.Stdin(data),
 		shell.SplitBytes(),
-		shell.Env(
-			"PHENIX_DIR="+common.PhenixBase,
-			"PHENIX_FILES_DIR="+exp.FilesDir(),
-			"PHENIX_LOG_LEVEL="+util.GetEnv("PHENIX_LOG_LEVEL", "DEBUG"),
-			"PHENIX_LOG_FILE="+util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
-			"PHENIX_DRYRUN="+strconv.FormatBool(this.options.DryRun),
-			"PHENIX_STORE_ENDPOINT="+common.StoreEndpoint,
-		),
+		shell.Env(env...),
 	}
 
+	sandbox, err := appSandbox(this.options.Name, exp)
+	if err != nil {
+		return err
+	}
+
+	if sandbox != nil {
+		opts = append(opts, shell.WithSandbox(*sandbox))
+	}
+
+	// The output of the user app is also written to a log file in the
+	// experiment's files directory as it runs to help with debugging failures.
+	if log, err := openAppLog(exp.Metadata.Name, this.options.Name, action); err == nil {
+		defer log.Close()
+
+		opts = append(opts, shell.TeeStdout(log), shell.TeeStderr(log))
+	} else {
+		plog.Warn("unable to open user app log", "app", this.options.Name, "exp", exp.Metadata.Name, "err", err)
+	}
+
+	// Progress updates written to STDERR by the user app are reported as they're
+	// written.
+	var (
+		stderr = make(chan []byte)
+		done   = make(chan struct{})
+	)
+
+	defer close(done)
+
+	opts = append(opts, shell.StreamStderr(stderr))
+
+	go func() {
+		for {
+			select {
+			case line, ok := <-stderr:
+				if !ok {
+					return
+				}
+
+				if percent, msg, ok := parseProgress(string(line)); ok {
+					ReportProgress(ctx, percent, "%s", msg)
+				}
+			case <-done: // command failed to start
+				return
+			}
+		}
+	}()
+
 	stdOut, stdErr, err := shell.ExecCommand(ctx, opts...)
 	if err != nil {
 		var exitErr *exec.ExitError
@@ -129,7 +332,7 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 					return fmt.Errorf("scheduling experiment with %s: %w", sched, err)
 				}
 
-				return this.shellOut(ctx, action, exp)
+				return this.run(ctx, action, exp)
 			}
 		}
 
@@ -143,13 +346,20 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 	// If the user app didn't make any modifications, then we don't require it to
 	// output an experiment config. So, if there's nothing on STDOUT then just
 	// return immediately without error.
-	if len(stdOut) == 0 {
+	return this.applyResult(action, exp, stdOut)
+}
+
+// applyResult updates the experiment with the parts of the experiment returned
+// by the app that the app is allowed to modify for the given action. The app
+// isn't required to return the experiment if it didn't modify it.
+func (this UserApp) applyResult(action Action, exp *types.Experiment, data []byte) error {
+	if len(data) == 0 {
 		return nil
 	}
 
 	result := types.NewExperiment(exp.Metadata)
 
-	if err := json.Unmarshal(stdOut, &result); err != nil {
+	if err := json.Unmarshal(data, &result); err != nil {
 		return fmt.Errorf("unmarshaling experiment from JSON: %w", err)
 	}
 
@@ -160,12 +370,33 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 		if metadata, ok := result.Status.AppStatus()[this.options.Name]; ok {
 			exp.Status.SetAppStatus(this.options.Name, metadata)
 		}
+
+		// Apps register health expectations for VMs as a set, replacing any
+		// expectations they registered previously.
+		if expects, ok := result.Status.AppExpectations()[this.options.Name]; ok {
+			exp.Status.ClearAppExpectations(this.options.Name)
+
+			for _, e := range expects {
+				exp.Status.SetAppExpectation(this.options.Name, e)
+			}
+		}
 	case ACTIONCLEANUP:
 		exp.SetSpec(result.Spec)
 
 		if metadata, ok := result.Status.AppStatus()[this.options.Name]; ok {
 			exp.Status.SetAppStatus(this.options.Name, metadata)
 		}
+
+		exp.Status.ClearAppExpectations(this.options.Name)
+	}
+
+	// Artifacts can be registered in any stage. Unlike expectations, they're
+	// kept until replaced since the files they refer to outlive the stage that
+	// produced them.
+	for _, a := range result.Status.AppArtifacts()[this.options.Name] {
+		if err := registerArtifact(exp, this.options.Name, a); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -97,6 +98,51 @@ func (this NATRule) TranslationPort() string {
 	return ""
 }
 
+// VRRPConfig is a VRRP group configured on a router interface.
+type VRRPConfig struct {
+	Group    int    `mapstructure:"group"`
+	Address  string `mapstructure:"address"`
+	Priority int    `mapstructure:"priority"`
+	Preempt  *bool  `mapstructure:"preempt"`
+
+	// Needs to be set via configuration code.
+	ifaceIndex int
+}
+
+func (this VRRPConfig) InterfaceIndex() int {
+	return this.ifaceIndex
+}
+
+func (this VRRPConfig) NoPreempt() bool {
+	return this.Preempt != nil && !*this.Preempt
+}
+
+// InterfaceConfig is the per-interface router configuration provided in app
+// host metadata, keyed by topology interface name.
+type InterfaceConfig struct {
+	DHCPRelay []string    `mapstructure:"dhcpRelay"`
+	VRRP      *VRRPConfig `mapstructure:"vrrp"`
+}
+
+// DHCPRelayConfig is the router-wide DHCP relay configuration generated from
+// per-interface DHCP relay metadata.
+type DHCPRelayConfig struct {
+	Interfaces []string
+	Servers    []string
+}
+
+type DNSForwardingConfig struct {
+	ListenOn    []string          `mapstructure:"listenOn"`
+	NameServers []string          `mapstructure:"nameServers"`
+	Domains     map[string]string `mapstructure:"domains"`
+	CacheSize   int               `mapstructure:"cacheSize"`
+
+	// Need to be set via configuration code.
+	Interfaces []string `mapstructure:"-"`
+	Addresses  []string `mapstructure:"-"`
+	AllowFrom  []string `mapstructure:"-"`
+}
+
 type Vrouter struct {
 	ipsecPresharedKeys map[string]string
 }
@@ -148,8 +194,36 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 		}
 	}
 
+	// VRRP groups generated from HA pairs, keyed by router hostname.
+	var ha map[string][]VRRPConfig
+
+	for _, app := range exp.Apps() {
+		if app.Name() == "vrouter" {
+			var amd VrouterAppMetadata
+
+			if err := mapstructure.Decode(app.Metadata(), &amd); err != nil {
+				return fmt.Errorf("decoding vrouter app metadata: %w", err)
+			}
+
+			// Routes are added to the topology here so they're included in both the
+			// Vyatta/VyOS configs generated below and the minirouter configs applied
+			// in the post-start stage.
+			if amd.AutoRoutes {
+				addAutoRoutes(exp.Spec.Topology().Nodes())
+			}
+
+			var err error
+
+			if ha, err = this.processHAPairs(exp.Spec.Topology(), amd.HAPairs); err != nil {
+				return fmt.Errorf("processing HA pair metadata: %w", err)
+			}
+		}
+	}
+
+	vrouterDir := exp.Spec.BaseDir() + "/vrouter"
+
 	// loop through nodes
-	for _, node := range exp.Spec.Topology().Nodes() {
+	for _, node := range TargetNodes(exp, "vrouter") {
 		if node.External() {
 			continue
 		}
@@ -158,12 +232,30 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 			continue
 		}
 
-		// We ignore os_type `minirouter` here since its config is handled entirely
-		// in the post-start stage. We also don't log if `minirouter` since it is
-		// supported, just not here. Including os_type `linux` is for legacy
-		// support.
+		// Other than the keepalived config for HA pairs, os_type `minirouter`
+		// config is handled entirely in the post-start stage, so it's skipped here.
+		if strings.EqualFold(node.Hardware().OSType(), "minirouter") {
+			if vrrp := ha[node.General().Hostname()]; len(vrrp) > 0 {
+				keepalivedFile := vrouterDir + "/" + node.General().Hostname() + "-keepalived.conf"
+
+				if err := os.MkdirAll(vrouterDir, 0755); err != nil {
+					return fmt.Errorf("creating experiment vrouter directory path: %w", err)
+				}
+
+				if err := tmpl.CreateFileFromTemplate("keepalived.tmpl", vrrp, keepalivedFile); err != nil {
+					return fmt.Errorf("generating keepalived config for host %s: %w", node.General().Hostname(), err)
+				}
+
+				node.AddInject(keepalivedFile, "/etc/keepalived/keepalived.conf", "", "")
+			}
+
+			continue
+		}
+
+		// Network appliance OS types are handled by the netos app, so they're
+		// skipped without logging. Including os_type `linux` is for legacy support.
 		if !util.StringSliceContains([]string{"vyatta", "vyos", "linux"}, strings.ToLower(node.Hardware().OSType())) {
-			if strings.ToLower(node.Hardware().OSType()) != "minirouter" {
+			if !IsNetOS(node.Hardware().OSType()) {
 				fmt.Printf("  === OS Type %s for Node Type %s unsupported ===\n", node.Hardware().OSType(), node.Type())
 			}
 
@@ -177,7 +269,6 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 
 		var (
 			isVyos       = strings.EqualFold(node.Hardware().OSType(), "vyos")
-			vrouterDir   = exp.Spec.BaseDir() + "/vrouter"
 			vyattaFile   = vrouterDir + "/" + node.General().Hostname() + ".boot"
 			vyattaConfig = "/opt/vyatta/etc/config/config.boot"
 		)
@@ -255,12 +346,41 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 						data["snat"] = sources
 						data["dnat"] = destinations
 
+						relay, vrrp, err := this.processInterfaces(md, node.Network().Interfaces())
+						if err != nil {
+							return fmt.Errorf("processing interface metadata for host %s: %w", host.Hostname(), err)
+						}
+
+						data["dhcp-relay"] = relay
+						data["vrrp"] = vrrp
+
+						dns, err := this.processDNSForwarding(md, node.Network().Interfaces())
+						if err != nil {
+							return fmt.Errorf("processing DNS forwarding metadata for host %s: %w", host.Hostname(), err)
+						}
+
+						data["dns-forwarding"] = dns
+
 						break
 					}
 				}
 			}
 		}
 
+		if vrrp := ha[node.General().Hostname()]; len(vrrp) > 0 {
+			existing, _ := data["vrrp"].([]VRRPConfig)
+
+			for _, group := range vrrp {
+				for _, e := range existing {
+					if e.ifaceIndex == group.ifaceIndex {
+						return fmt.Errorf("VRRP for interface eth%d of host %s configured in both host metadata and an HA pair", group.ifaceIndex, node.General().Hostname())
+					}
+				}
+			}
+
+			data["vrrp"] = append(existing, vrrp...)
+		}
+
 		// If app host metadata didn't include NAT configs, then see if NAT was
 		// specified in the topology network config.
 		if _, ok := data["nat"]; !ok {
@@ -333,7 +453,7 @@ func (Vrouter) PostStart(ctx context.Context, exp *types.Experiment) error {
 		}
 	}
 
-	for _, node := range exp.Spec.Topology().Nodes() {
+	for _, node := range TargetNodes(exp, "vrouter") {
 		if node.External() {
 			continue
 		}
@@ -755,6 +875,304 @@ func (this *Vrouter) processNAT(md map[string]interface{}, nets []ifaces.NodeNet
 	return sources, destinations, nil
 }
 
+// processInterfaces processes the per-interface DHCP relay and VRRP metadata
+// for a router. DHCP relay servers are collected into a single router-wide
+// relay config, since Vyatta and VyOS only support one relay instance. The
+// interfaces used to reach the DHCP servers are added to the relay config
+// automatically.
+func (this *Vrouter) processInterfaces(md map[string]interface{}, nets []ifaces.NodeNetworkInterface) (*DHCPRelayConfig, []VRRPConfig, error) {
+	if _, ok := md["interfaces"]; !ok {
+		return nil, nil, nil
+	}
+
+	var configs map[string]InterfaceConfig
+
+	if err := mapstructure.Decode(md["interfaces"], &configs); err != nil {
+		return nil, nil, fmt.Errorf("decoding interface config: %w", err)
+	}
+
+	var (
+		relay   DHCPRelayConfig
+		vrrp    []VRRPConfig
+		servers []string
+	)
+
+	for idx, iface := range nets {
+		config, ok := configs[iface.Name()]
+		if !ok {
+			continue
+		}
+
+		delete(configs, iface.Name())
+
+		if len(config.DHCPRelay) > 0 {
+			relay.Interfaces = appendUnique(relay.Interfaces, fmt.Sprintf("eth%d", idx))
+
+			for _, server := range config.DHCPRelay {
+				if net.ParseIP(server) == nil {
+					return nil, nil, fmt.Errorf("invalid DHCP relay server %s for interface %s", server, iface.Name())
+				}
+
+				servers = appendUnique(servers, server)
+			}
+		}
+
+		if config.VRRP != nil {
+			if config.VRRP.Group < 1 || config.VRRP.Group > 255 {
+				return nil, nil, fmt.Errorf("invalid VRRP group %d for interface %s (must be 1-255)", config.VRRP.Group, iface.Name())
+			}
+
+			if config.VRRP.Address == "" {
+				return nil, nil, fmt.Errorf("no VRRP address provided for interface %s", iface.Name())
+			}
+
+			config.VRRP.ifaceIndex = idx
+			vrrp = append(vrrp, *config.VRRP)
+		}
+	}
+
+	for name := range configs {
+		return nil, nil, fmt.Errorf("interface %s not found", name)
+	}
+
+	if len(servers) == 0 {
+		return nil, vrrp, nil
+	}
+
+	// Make sure the interfaces used to reach the DHCP servers are included in
+	// the relay config so replies from the servers are relayed back to clients.
+	for _, server := range servers {
+		ip := netaddr.MustParseIP(server)
+
+		for idx, iface := range nets {
+			if iface.Address() == "" {
+				continue
+			}
+
+			addr, err := netaddr.ParseIP(iface.Address())
+			if err != nil {
+				continue
+			}
+
+			if netaddr.IPPrefixFrom(addr, uint8(iface.Mask())).Masked().Contains(ip) {
+				relay.Interfaces = appendUnique(relay.Interfaces, fmt.Sprintf("eth%d", idx))
+				break
+			}
+		}
+	}
+
+	relay.Servers = servers
+
+	return &relay, vrrp, nil
+}
+
+// processHAPairs processes the HA pair metadata for the vrouter app, returning
+// the VRRP groups to configure on each router keyed by hostname. Each pair uses
+// a single VRRP group (the pair's index plus one by default) for all of its
+// virtual addresses, and the routers must be on the same VLAN for each
+// interface given.
+func (this *Vrouter) processHAPairs(topo ifaces.TopologySpec, pairs []VrouterHAPair) (map[string][]VRRPConfig, error) {
+	groups := make(map[string][]VRRPConfig)
+
+	for i, pair := range pairs {
+		name := pair.Name
+
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
+		}
+
+		if len(pair.Routers) != 2 || pair.Routers[0] == pair.Routers[1] {
+			return nil, fmt.Errorf("HA pair %s must have two different routers", name)
+		}
+
+		group := pair.Group
+
+		if group == 0 {
+			group = i + 1
+		}
+
+		if group < 1 || group > 255 {
+			return nil, fmt.Errorf("invalid VRRP group %d for HA pair %s (must be 1-255)", group, name)
+		}
+
+		priorities := pair.Priorities
+
+		if len(priorities) == 0 {
+			priorities = []int{200, 100}
+		}
+
+		if len(priorities) != 2 {
+			return nil, fmt.Errorf("HA pair %s must have a priority for each router", name)
+		}
+
+		for _, p := range priorities {
+			// Priority 255 is reserved for routers that own the virtual address.
+			if p < 1 || p > 254 {
+				return nil, fmt.Errorf("invalid VRRP priority %d for HA pair %s (must be 1-254)", p, name)
+			}
+		}
+
+		if len(pair.VIPs) == 0 {
+			return nil, fmt.Errorf("no virtual addresses provided for HA pair %s", name)
+		}
+
+		var routers []ifaces.NodeSpec
+
+		for _, host := range pair.Routers {
+			node := topo.FindNodeByName(host)
+
+			if node == nil {
+				return nil, fmt.Errorf("router %s in HA pair %s not in topology", host, name)
+			}
+
+			if !strings.EqualFold(node.Type(), "router") && !strings.EqualFold(node.Type(), "firewall") {
+				return nil, fmt.Errorf("node %s in HA pair %s is not a router or firewall", host, name)
+			}
+
+			routers = append(routers, node)
+		}
+
+		names := make([]string, 0, len(pair.VIPs))
+
+		for iface := range pair.VIPs {
+			names = append(names, iface)
+		}
+
+		// Sorting keeps the generated configs deterministic.
+		sort.Strings(names)
+
+		for _, ifaceName := range names {
+			var (
+				vip  = pair.VIPs[ifaceName]
+				vlan string
+			)
+
+			for j, node := range routers {
+				var (
+					host = node.General().Hostname()
+					idx  = -1
+					nic  ifaces.NodeNetworkInterface
+				)
+
+				for k, iface := range node.Network().Interfaces() {
+					if iface.Name() == ifaceName {
+						idx, nic = k, iface
+						break
+					}
+				}
+
+				if nic == nil {
+					return nil, fmt.Errorf("interface %s in HA pair %s not found on router %s", ifaceName, name, host)
+				}
+
+				if j == 0 {
+					vlan = nic.VLAN()
+				} else if !strings.EqualFold(nic.VLAN(), vlan) {
+					return nil, fmt.Errorf("interface %s in HA pair %s must be on the same VLAN for both routers", ifaceName, name)
+				}
+
+				addr := vip
+
+				if !strings.Contains(addr, "/") {
+					addr = fmt.Sprintf("%s/%d", vip, nic.Mask())
+				}
+
+				prefix, err := netaddr.ParseIPPrefix(addr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid virtual address %s for interface %s in HA pair %s", vip, ifaceName, name)
+				}
+
+				if ip, err := netaddr.ParseIP(nic.Address()); err == nil {
+					if !prefix.Masked().Contains(ip) {
+						return nil, fmt.Errorf("virtual address %s not in the subnet of interface %s on router %s", vip, ifaceName, host)
+					}
+
+					if prefix.IP() == ip {
+						return nil, fmt.Errorf("virtual address %s is the address of interface %s on router %s", vip, ifaceName, host)
+					}
+				}
+
+				for _, e := range groups[host] {
+					if e.ifaceIndex == idx {
+						return nil, fmt.Errorf("interface %s on router %s is in more than one HA pair", ifaceName, host)
+					}
+				}
+
+				groups[host] = append(groups[host], VRRPConfig{
+					Group:      group,
+					Address:    addr,
+					Priority:   priorities[j],
+					Preempt:    pair.Preempt,
+					ifaceIndex: idx,
+				})
+			}
+		}
+	}
+
+	return groups, nil
+}
+
+// processDNSForwarding processes the DNS forwarding metadata for a router. DNS
+// forwarding listens on the given router interfaces and only allows queries
+// from the subnets of those interfaces.
+func (this *Vrouter) processDNSForwarding(md map[string]interface{}, nets []ifaces.NodeNetworkInterface) (*DNSForwardingConfig, error) {
+	if _, ok := md["dnsForwarding"]; !ok {
+		return nil, nil
+	}
+
+	var dns DNSForwardingConfig
+
+	if err := mapstructure.Decode(md["dnsForwarding"], &dns); err != nil {
+		return nil, fmt.Errorf("decoding DNS forwarding config: %w", err)
+	}
+
+	if len(dns.ListenOn) == 0 {
+		return nil, fmt.Errorf("no DNS forwarding listen interfaces provided")
+	}
+
+	for _, name := range dns.ListenOn {
+		var found bool
+
+		for idx, iface := range nets {
+			if iface.Name() != name {
+				continue
+			}
+
+			addr, err := netaddr.ParseIP(iface.Address())
+			if err != nil {
+				return nil, fmt.Errorf("DNS forwarding interface %s has no static address", name)
+			}
+
+			dns.Interfaces = append(dns.Interfaces, fmt.Sprintf("eth%d", idx))
+			dns.Addresses = append(dns.Addresses, addr.String())
+			dns.AllowFrom = appendUnique(dns.AllowFrom, netaddr.IPPrefixFrom(addr, uint8(iface.Mask())).Masked().String())
+
+			found = true
+			break
+		}
+
+		if !found {
+			return nil, fmt.Errorf("DNS forwarding interface %s not found", name)
+		}
+	}
+
+	for domain, server := range dns.Domains {
+		if net.ParseIP(server) == nil {
+			return nil, fmt.Errorf("invalid DNS server %s for domain %s", server, domain)
+		}
+	}
+
+	return &dns, nil
+}
+
+func appendUnique(s []string, v string) []string {
+	if util.StringSliceContains(s, v) {
+		return s
+	}
+
+	return append(s, v)
+}
+
 func configureNTP(exp *types.Experiment, hostname string) (string, error) {
 	// Check to see if a scenario exists for this experiment and if it contains
 	// a "ntp" app. If so, use it to configure NTP for the experiment.
@@ -843,3 +1261,152 @@ func generateSecret(n int) string {
 
 	return string(b)
 }
+
+// VrouterAppMetadata is the app-wide vrouter configuration provided in the
+// scenario.
+type VrouterAppMetadata struct {
+	// AutoRoutes enables computing static routes for all routers in the
+	// topology from the subnets they're connected to.
+	AutoRoutes bool `mapstructure:"autoRoutes"`
+
+	// HAPairs are pairs of routers sharing virtual addresses via VRRP.
+	HAPairs []VrouterHAPair `mapstructure:"haPairs"`
+}
+
+// VrouterHAPair is a pair of routers sharing a virtual address on each of the
+// given interfaces via VRRP. The first router is the primary unless priorities
+// are given.
+type VrouterHAPair struct {
+	Name       string            `mapstructure:"name"`
+	Routers    []string          `mapstructure:"routers"`
+	Group      int               `mapstructure:"group"`
+	Priorities []int             `mapstructure:"priorities"`
+	Preempt    *bool             `mapstructure:"preempt"`
+	VIPs       map[string]string `mapstructure:"vips"`
+}
+
+type routerSubnet struct {
+	prefix netaddr.IPPrefix
+	addr   netaddr.IP
+}
+
+// addAutoRoutes adds a static route to each router for every subnet in the
+// topology the router isn't directly connected to, using the shortest path (in
+// router hops) between routers that share a subnet. Routers running OSPF are
+// ignored, and destinations a router already has a route for are left as-is.
+func addAutoRoutes(nodes []ifaces.NodeSpec) {
+	var (
+		routers []ifaces.NodeSpec
+		subnets = make(map[string][]routerSubnet)
+		members = make(map[netaddr.IPPrefix][]string)
+	)
+
+	for _, node := range nodes {
+		if node.External() || node.Network() == nil || node.Network().OSPF() != nil {
+			continue
+		}
+
+		if !strings.EqualFold(node.Type(), "router") && !strings.EqualFold(node.Type(), "firewall") {
+			continue
+		}
+
+		host := node.General().Hostname()
+
+		for _, iface := range node.Network().Interfaces() {
+			if !strings.EqualFold(iface.Proto(), "static") || iface.Mask() <= 0 {
+				continue
+			}
+
+			ip, err := netaddr.ParseIP(iface.Address())
+			if err != nil {
+				continue
+			}
+
+			prefix := netaddr.IPPrefixFrom(ip, uint8(iface.Mask())).Masked()
+
+			subnets[host] = append(subnets[host], routerSubnet{prefix: prefix, addr: ip})
+			members[prefix] = appendUnique(members[prefix], host)
+		}
+
+		if len(subnets[host]) > 0 {
+			routers = append(routers, node)
+		}
+	}
+
+	// Sorting keeps the chosen path deterministic when there are multiple
+	// shortest paths to a subnet.
+	for prefix := range members {
+		sort.Strings(members[prefix])
+	}
+
+	for host := range subnets {
+		sort.Slice(subnets[host], func(i, j int) bool {
+			return subnets[host][i].prefix.String() < subnets[host][j].prefix.String()
+		})
+	}
+
+	addrOn := func(host string, prefix netaddr.IPPrefix) netaddr.IP {
+		for _, s := range subnets[host] {
+			if s.prefix == prefix {
+				return s.addr
+			}
+		}
+
+		return netaddr.IP{}
+	}
+
+	for _, node := range routers {
+		src := node.General().Hostname()
+
+		routed := make(map[string]struct{})
+
+		for _, s := range subnets[src] {
+			routed[s.prefix.String()] = struct{}{}
+		}
+
+		for _, r := range node.Network().Routes() {
+			routed[r.Destination()] = struct{}{}
+		}
+
+		type hop struct {
+			host string
+			next netaddr.IP
+			cost int
+		}
+
+		var (
+			visited = map[string]struct{}{src: {}}
+			queue   = []hop{{host: src}}
+		)
+
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+
+			for _, s := range subnets[cur.host] {
+				if _, ok := routed[s.prefix.String()]; !ok {
+					node.AddNetworkRoute(s.prefix.String(), cur.next.String(), cur.cost)
+					routed[s.prefix.String()] = struct{}{}
+				}
+
+				for _, peer := range members[s.prefix] {
+					if _, ok := visited[peer]; ok {
+						continue
+					}
+
+					visited[peer] = struct{}{}
+
+					next := cur.next
+
+					// The next hop is the directly connected peer's address on the shared
+					// subnet.
+					if cur.host == src {
+						next = addrOn(peer, s.prefix)
+					}
+
+					queue = append(queue, hop{host: peer, next: next, cost: cur.cost + 1})
+				}
+			}
+		}
+	}
+}
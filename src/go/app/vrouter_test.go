@@ -1,14 +1,19 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
 	v1 "phenix/types/version/v1"
+	v2 "phenix/types/version/v2"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 func TestVrouterApp(t *testing.T) {
@@ -86,3 +91,278 @@ func TestVrouterApp(t *testing.T) {
 
 	checkStartExpected(t, spec.Topology().Nodes(), expected)
 }
+
+func TestVrouterInterfaceServices(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "vrouter-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	nodes := []*v1.Node{
+		{
+			TypeF: "Router",
+			GeneralF: &v1.General{
+				HostnameF: "router",
+			},
+			HardwareF: &v1.Hardware{
+				OSTypeF: "vyos",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "IF0", VLANF: "SERVERS", ProtoF: "static", AddressF: "10.0.0.254", MaskF: 24},
+					{NameF: "IF1", VLANF: "USERS", ProtoF: "static", AddressF: "10.1.0.2", MaskF: 24},
+				},
+			},
+		},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF: "vrouter",
+				HostsF: []*v2.ScenarioAppHost{
+					{
+						HostnameF: "router",
+						MetadataF: map[string]any{
+							"interfaces": map[string]any{
+								"IF1": map[string]any{
+									"dhcpRelay": []any{"10.0.0.5"},
+									"vrrp":      map[string]any{"group": 10, "address": "10.1.0.1/24", "priority": 200, "preempt": false},
+								},
+							},
+							"dnsForwarding": map[string]any{
+								"listenOn":    []any{"IF1"},
+								"nameServers": []any{"10.0.0.5"},
+								"domains":     map[string]any{"corp.local": "10.0.0.5"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		BaseDirF:  baseDir,
+		TopologyF: &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("vrouter").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	body, err := os.ReadFile(baseDir + "/vrouter/router.boot")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	config := strings.Join(strings.Fields(string(body)), " ")
+
+	expected := []string{
+		"dhcp-relay { interface eth1 interface eth0 server 10.0.0.5 }",
+		"group VRRP-eth1-10 { interface eth1 virtual-address 10.1.0.1/24 vrid 10 priority 200 no-preempt }",
+		"listen-address 10.1.0.2 allow-from 10.1.0.0/24 name-server 10.0.0.5 domain corp.local { server 10.0.0.5 }",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(config, e) {
+			t.Logf("expected generated config to contain %q", e)
+			t.FailNow()
+		}
+	}
+}
+
+func TestVrouterAutoRoutes(t *testing.T) {
+	router := func(name string, addrs ...string) *v1.Node {
+		node := &v1.Node{TypeF: "Router", GeneralF: &v1.General{HostnameF: name}, NetworkF: &v1.Network{}}
+
+		for i, addr := range addrs {
+			node.NetworkF.InterfacesF = append(node.NetworkF.InterfacesF, &v1.Interface{
+				NameF: fmt.Sprintf("IF%d", i), ProtoF: "static", AddressF: addr, MaskF: 24,
+			})
+		}
+
+		return node
+	}
+
+	// lan1 (10.0.1.0/24) - r1 - 10.0.12.0/24 - r2 - 10.0.23.0/24 - r3 - lan3 (10.0.3.0/24)
+	var (
+		r1 = router("r1", "10.0.1.1", "10.0.12.1")
+		r2 = router("r2", "10.0.12.2", "10.0.23.2")
+		r3 = router("r3", "10.0.23.3", "10.0.3.1")
+	)
+
+	r3.AddNetworkRoute("10.0.1.0/24", "10.0.23.254", 5)
+
+	addAutoRoutes([]ifaces.NodeSpec{r1, r2, r3})
+
+	routes := func(node *v1.Node) map[string]string {
+		m := make(map[string]string)
+
+		for _, r := range node.Network().Routes() {
+			m[r.Destination()] = r.Next()
+		}
+
+		return m
+	}
+
+	expected := map[*v1.Node]map[string]string{
+		r1: {"10.0.23.0/24": "10.0.12.2", "10.0.3.0/24": "10.0.12.2"},
+		r2: {"10.0.1.0/24": "10.0.12.1", "10.0.3.0/24": "10.0.23.3"},
+		r3: {"10.0.1.0/24": "10.0.23.254", "10.0.12.0/24": "10.0.23.2"},
+	}
+
+	for node, e := range expected {
+		got := routes(node)
+
+		if len(got) != len(e) {
+			t.Logf("expected %d routes for %s, got %v", len(e), node.General().Hostname(), got)
+			t.FailNow()
+		}
+
+		for dest, next := range e {
+			if got[dest] != next {
+				t.Logf("expected route to %s via %s for %s, got %v", dest, next, node.General().Hostname(), got)
+				t.FailNow()
+			}
+		}
+	}
+
+	// Running again shouldn't add duplicate routes.
+	addAutoRoutes([]ifaces.NodeSpec{r1, r2, r3})
+
+	if len(r1.Network().Routes()) != 2 {
+		t.Logf("expected auto routes to not be duplicated, got %d routes", len(r1.Network().Routes()))
+		t.FailNow()
+	}
+}
+
+func TestVrouterHAPairs(t *testing.T) {
+	baseDir, err := os.MkdirTemp("", "vrouter-app-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(baseDir)
+
+	router := func(name, os string, addrs ...string) *v1.Node {
+		node := &v1.Node{
+			TypeF:     "Router",
+			GeneralF:  &v1.General{HostnameF: name},
+			HardwareF: &v1.Hardware{OSTypeF: os},
+			NetworkF:  &v1.Network{},
+		}
+
+		for i, addr := range addrs {
+			node.NetworkF.InterfacesF = append(node.NetworkF.InterfacesF, &v1.Interface{
+				NameF: fmt.Sprintf("IF%d", i), VLANF: fmt.Sprintf("VLAN%d", i), ProtoF: "static", AddressF: addr, MaskF: 24,
+			})
+		}
+
+		return node
+	}
+
+	nodes := []*v1.Node{
+		router("rtr-a", "vyos", "10.0.0.2", "10.1.0.2"),
+		router("rtr-b", "minirouter", "10.0.0.3", "10.1.0.3"),
+	}
+
+	pair := map[string]any{
+		"routers": []any{"rtr-a", "rtr-b"},
+		"group":   20,
+		"preempt": false,
+		"vips":    map[string]any{"IF0": "10.0.0.1", "IF1": "10.1.0.1/24"},
+	}
+
+	scenario := &v2.ScenarioSpec{
+		AppsF: []*v2.ScenarioApp{
+			{
+				NameF:     "vrouter",
+				MetadataF: map[string]any{"haPairs": []any{pair}},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		BaseDirF:  baseDir,
+		TopologyF: &v1.TopologySpec{NodesF: nodes},
+		ScenarioF: scenario,
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	if err := GetApp("vrouter").PreStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if injects := nodes[1].Injections(); len(injects) != 1 || injects[0].Dst() != "/etc/keepalived/keepalived.conf" {
+		t.Logf("expected keepalived config to be injected into minirouter, got %v", injects)
+		t.FailNow()
+	}
+
+	files := map[string][]string{
+		"rtr-a.boot": {
+			"group VRRP-eth0-20 { interface eth0 virtual-address 10.0.0.1/24 vrid 20 priority 200 no-preempt }",
+			"group VRRP-eth1-20 { interface eth1 virtual-address 10.1.0.1/24 vrid 20 priority 200 no-preempt }",
+		},
+		"rtr-b-keepalived.conf": {
+			"vrrp_instance VRRP-eth0-20 { state BACKUP interface eth0 virtual_router_id 20 priority 100 advert_int 1 nopreempt virtual_ipaddress { 10.0.0.1/24 } }",
+			"vrrp_instance VRRP-eth1-20 { state BACKUP interface eth1 virtual_router_id 20 priority 100 advert_int 1 nopreempt virtual_ipaddress { 10.1.0.1/24 } }",
+		},
+	}
+
+	for name, expected := range files {
+		body, err := os.ReadFile(baseDir + "/vrouter/" + name)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		config := strings.Join(strings.Fields(string(body)), " ")
+
+		for _, e := range expected {
+			if !strings.Contains(config, e) {
+				t.Logf("expected %s to contain %q, got: %s", name, e, config)
+				t.FailNow()
+			}
+		}
+	}
+
+	cases := map[string]map[string]any{
+		"single router":   {"routers": []any{"rtr-a"}, "vips": map[string]any{"IF0": "10.0.0.1"}},
+		"unknown router":  {"routers": []any{"rtr-a", "foo"}, "vips": map[string]any{"IF0": "10.0.0.1"}},
+		"no vips":         {"routers": []any{"rtr-a", "rtr-b"}},
+		"bad priority":    {"routers": []any{"rtr-a", "rtr-b"}, "priorities": []any{255, 100}, "vips": map[string]any{"IF0": "10.0.0.1"}},
+		"unknown iface":   {"routers": []any{"rtr-a", "rtr-b"}, "vips": map[string]any{"IF2": "10.0.0.1"}},
+		"vip not in net":  {"routers": []any{"rtr-a", "rtr-b"}, "vips": map[string]any{"IF0": "10.9.0.1"}},
+		"vip is iface IP": {"routers": []any{"rtr-a", "rtr-b"}, "vips": map[string]any{"IF0": "10.0.0.2"}},
+	}
+
+	for name, pair := range cases {
+		if _, err := new(Vrouter).processHAPairs(exp.Spec.Topology(), []VrouterHAPair{decodeHAPair(t, pair)}); err == nil {
+			t.Logf("expected HA pair error for case '%s'", name)
+			t.FailNow()
+		}
+	}
+}
+
+func decodeHAPair(t *testing.T, md map[string]any) VrouterHAPair {
+	var pair VrouterHAPair
+
+	if err := mapstructure.Decode(md, &pair); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return pair
+}
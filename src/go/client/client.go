@@ -0,0 +1,296 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// Header used to authenticate API requests with a token.
+	authHeader = "X-phenix-auth-token"
+
+	// Header used to reference an approval granted for an operation that
+	// requires one.
+	approvalHeader = "X-Phenix-Approval"
+)
+
+// ErrApprovalRequired is wrapped by the error returned for operations that
+// require approval (see `ApprovalRequiredError`).
+var ErrApprovalRequired = errors.New("approval required")
+
+// APIError is returned for API requests that fail. Code and Resource are only
+// set for failures the API classifies.
+type APIError struct {
+	Status   int               `json:"-"`
+	Message  string            `json:"message"`
+	Code     string            `json:"code,omitempty"`
+	Resource string            `json:"resource,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (this APIError) Error() string {
+	return fmt.Sprintf("%s (%d)", this.Message, this.Status)
+}
+
+// ApprovalRequiredError is returned for operations that require approval when
+// no approval was provided. The operation can be retried with the approval ID
+// (see `WithApproval`) once it's been approved.
+type ApprovalRequiredError struct {
+	ID        string `json:"id"`
+	Operation string `json:"operation"`
+}
+
+func (this ApprovalRequiredError) Error() string {
+	return fmt.Sprintf("%s: %s operation requires approval %s", ErrApprovalRequired, this.Operation, this.ID)
+}
+
+func (ApprovalRequiredError) Unwrap() error {
+	return ErrApprovalRequired
+}
+
+// IsNotFound returns true if the given error is an API error for a resource
+// that doesn't exist.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+type Option func(*options)
+
+type options struct {
+	token    string
+	username string
+	password string
+	client   *http.Client
+}
+
+// WithToken authenticates requests with the given API token (ie. one created
+// for a user via the UI or `phenix ui` user tokens).
+func WithToken(t string) Option {
+	return func(o *options) {
+		o.token = t
+	}
+}
+
+// WithCredentials authenticates requests by logging in with the given username
+// and password on the first request, and again if the token from the previous
+// login expires.
+func WithCredentials(u, p string) Option {
+	return func(o *options) {
+		o.username = u
+		o.password = p
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests (ie. to configure
+// TLS or timeouts). The default HTTP client is used otherwise.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.client = c
+	}
+}
+
+// Client is a phenix API client. It's safe for concurrent use.
+type Client struct {
+	base    *url.URL
+	options options
+
+	sync.Mutex
+	token string
+}
+
+// New returns a client for the phenix API served at the given endpoint (ie.
+// `http://localhost:3000`). The `/api/v1` prefix is added to the endpoint path
+// if not already included.
+func New(endpoint string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint %s: %w", endpoint, err)
+	}
+
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %s (expected scheme and host)", endpoint)
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	if !strings.HasSuffix(base.Path, "/api/v1") {
+		base.Path += "/api/v1"
+	}
+
+	o := options{client: http.DefaultClient}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Client{base: base, options: o, token: o.token}, nil
+}
+
+// Login logs in with the given username and password, authenticating requests
+// made after it with the token returned.
+func (this *Client) Login(ctx context.Context, username, password string) (*User, error) {
+	req := map[string]string{"user": username, "pass": password}
+
+	var resp struct {
+		User  User   `json:"user"`
+		Token string `json:"token"`
+	}
+
+	if err := this.send(ctx, http.MethodPost, "/login", nil, req, &resp, nil); err != nil {
+		return nil, fmt.Errorf("logging in as %s: %w", username, err)
+	}
+
+	this.Lock()
+	this.token = resp.Token
+	this.Unlock()
+
+	return &resp.User, nil
+}
+
+// RequestOption modifies individual API requests.
+type RequestOption func(*http.Request)
+
+// WithApproval references the given approval for operations that require one.
+func WithApproval(id string) RequestOption {
+	return func(r *http.Request) {
+		r.Header.Set(approvalHeader, id)
+	}
+}
+
+// do makes an authenticated request, logging in first (and again if the token
+// is rejected) if credentials were provided.
+func (this *Client) do(ctx context.Context, method, path string, query url.Values, body, result any, opts ...RequestOption) error {
+	this.Lock()
+	token := this.token
+	this.Unlock()
+
+	if token == "" && this.options.username != "" {
+		if _, err := this.Login(ctx, this.options.username, this.options.password); err != nil {
+			return err
+		}
+	}
+
+	err := this.send(ctx, method, path, query, body, result, opts)
+
+	var apiErr *APIError
+
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized && this.options.username != "" {
+		if _, err := this.Login(ctx, this.options.username, this.options.password); err != nil {
+			return err
+		}
+
+		return this.send(ctx, method, path, query, body, result, opts)
+	}
+
+	return err
+}
+
+// send makes a single request. Bodies that are byte slices are sent as is (as
+// YAML); anything else is sent as JSON. Results that are byte slice pointers are set
+// to the raw response body; anything else is decoded from JSON.
+func (this *Client) send(ctx context.Context, method, path string, query url.Values, body, result any, opts []RequestOption) error {
+	u := *this.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	var (
+		reader      io.Reader
+		contentType string
+	)
+
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+		contentType = "application/x-yaml"
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshaling request body: %w", err)
+		}
+
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	this.Lock()
+
+	if this.token != "" {
+		req.Header.Set(authHeader, "Bearer "+this.token)
+	}
+
+	this.Unlock()
+
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	resp, err := this.options.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response for %s %s: %w", method, path, err)
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		var approval ApprovalRequiredError
+
+		if err := json.Unmarshal(data, &approval); err == nil && approval.ID != "" {
+			return &approval
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{Status: resp.StatusCode}
+
+		// Most API errors are JSON, but some handlers respond with plain text.
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+
+		return apiErr
+	}
+
+	switch r := result.(type) {
+	case nil:
+	case *[]byte:
+		*r = data
+	default:
+		if len(data) == 0 {
+			return nil
+		}
+
+		if err := json.Unmarshal(data, result); err != nil {
+			return fmt.Errorf("decoding response for %s %s: %w", method, path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if req["user"] != "admin" || req["pass"] != "secret" {
+			http.Error(w, "invalid creds", http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"user": {"username": "admin"}, "token": "abc123"}`))
+	})
+
+	mux.HandleFunc("/api/v1/experiments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(authHeader) != "Bearer abc123" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"experiments": [{"name": "foo", "running": true, "vm_count": 2, "vms": [{"name": "host-00", "dnb": true}]}]}`))
+	})
+
+	mux.HandleFunc("/api/v1/experiments/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "unable to get experiment missing", "code": "not-found", "resource": "experiment/missing"}`))
+	})
+
+	mux.HandleFunc("/api/v1/experiments/foo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(approvalHeader) == "" {
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id": "1234", "operation": "experiment-delete"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newTestServer(t)
+
+	c, err := New(server.URL, WithCredentials("admin", "secret"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	ctx := context.Background()
+
+	exps, err := c.ListExperiments(ctx)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(exps) != 1 || exps[0].Name != "foo" || exps[0].VMCount != 2 || !exps[0].VMs[0].DoNotBoot {
+		t.Logf("unexpected experiments %+v", exps)
+		t.FailNow()
+	}
+
+	_, err = c.GetExperiment(ctx, "missing")
+
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) || apiErr.Resource != "experiment/missing" || !IsNotFound(err) {
+		t.Logf("expected not found API error, got %v", err)
+		t.FailNow()
+	}
+
+	err = c.DeleteExperiment(ctx, "foo")
+
+	var approval *ApprovalRequiredError
+
+	if !errors.As(err, &approval) || approval.ID != "1234" || !errors.Is(err, ErrApprovalRequired) {
+		t.Logf("expected approval required error, got %v", err)
+		t.FailNow()
+	}
+
+	if err := c.DeleteExperiment(ctx, "foo", WithApproval(approval.ID)); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestClientBadCredentials(t *testing.T) {
+	server := newTestServer(t)
+
+	c, _ := New(server.URL+"/api/v1/", WithCredentials("admin", "wrong"))
+
+	_, err := c.ListExperiments(context.Background())
+
+	var apiErr *APIError
+
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || apiErr.Message != "invalid creds" {
+		t.Logf("expected unauthorized API error, got %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// ListConfigs lists the configs of the given kind (all kinds if empty). Config
+// specs aren't included.
+func (this *Client) ListConfigs(ctx context.Context, kind string) ([]Config, error) {
+	query := make(url.Values)
+
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+
+	var resp struct {
+		Configs []Config `json:"configs"`
+	}
+
+	if err := this.do(ctx, "GET", "/configs", query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Configs, nil
+}
+
+func (this *Client) GetConfig(ctx context.Context, kind, name string) (*Config, error) {
+	var cfg Config
+
+	if err := this.do(ctx, "GET", configPath(kind, name), nil, nil, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// CreateConfig creates the given config, which the API validates against the
+// schema for its kind.
+func (this *Client) CreateConfig(ctx context.Context, cfg Config) error {
+	return this.do(ctx, "POST", "/configs", nil, cfg, nil)
+}
+
+// CreateConfigFromYAML creates a config from the given YAML document (ie. the
+// contents of a topology file).
+func (this *Client) CreateConfigFromYAML(ctx context.Context, data []byte) error {
+	return this.do(ctx, "POST", "/configs", nil, data, nil)
+}
+
+func (this *Client) UpdateConfig(ctx context.Context, cfg Config) (*Config, error) {
+	var updated Config
+
+	if err := this.do(ctx, "PUT", configPath(cfg.Kind, cfg.Metadata.Name), nil, cfg, &updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteConfig deletes the given config. If deleting configs requires
+// approval, an `*ApprovalRequiredError` is returned unless an approval is
+// provided.
+func (this *Client) DeleteConfig(ctx context.Context, kind, name string, opts ...RequestOption) error {
+	return this.do(ctx, "DELETE", configPath(kind, name), nil, nil, nil, opts...)
+}
+
+func configPath(kind, name string) string {
+	return "/configs/" + url.PathEscape(kind) + "/" + url.PathEscape(name)
+}
@@ -0,0 +1,13 @@
+// Package client is a typed Go client for the phenix REST API served by
+// `phenix ui` (under /api/v1), covering experiments, configs, VMs, and
+// experiment files. It handles authentication, either with an existing API
+// token or by logging in with a username and password, and decodes API
+// errors into `*APIError` values.
+//
+//	c, err := client.New("http://localhost:3000", client.WithCredentials("admin", "secret"))
+//	if err != nil {
+//		return err
+//	}
+//
+//	exps, err := c.ListExperiments(ctx)
+package client
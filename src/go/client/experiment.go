@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+func (this *Client) ListExperiments(ctx context.Context) ([]Experiment, error) {
+	var resp struct {
+		Experiments []Experiment `json:"experiments"`
+	}
+
+	if err := this.do(ctx, "GET", "/experiments", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Experiments, nil
+}
+
+func (this *Client) GetExperiment(ctx context.Context, name string) (*Experiment, error) {
+	var exp Experiment
+
+	if err := this.do(ctx, "GET", "/experiments/"+url.PathEscape(name), nil, nil, &exp); err != nil {
+		return nil, err
+	}
+
+	return &exp, nil
+}
+
+func (this *Client) CreateExperiment(ctx context.Context, req CreateExperimentRequest) (*Experiment, error) {
+	var exp Experiment
+
+	if err := this.do(ctx, "POST", "/experiments", nil, req, &exp); err != nil {
+		return nil, err
+	}
+
+	return &exp, nil
+}
+
+// DeleteExperiment deletes the given experiment. If deleting experiments
+// requires approval, an `*ApprovalRequiredError` is returned unless an approval
+// is provided.
+func (this *Client) DeleteExperiment(ctx context.Context, name string, opts ...RequestOption) error {
+	return this.do(ctx, "DELETE", "/experiments/"+url.PathEscape(name), nil, nil, nil, opts...)
+}
+
+// StartExperiment starts the given experiment, returning once it has started.
+func (this *Client) StartExperiment(ctx context.Context, name string, opts StartExperimentOptions) (*Experiment, error) {
+	query := make(url.Values)
+
+	if opts.Profile != "" {
+		query.Set("profile", opts.Profile)
+	}
+
+	if opts.BestEffort {
+		query.Set("bestEffort", "true")
+	}
+
+	if opts.StageTimeout > 0 {
+		query.Set("stageTimeout", opts.StageTimeout.String())
+	}
+
+	if opts.AppTimeout > 0 {
+		query.Set("appTimeout", opts.AppTimeout.String())
+	}
+
+	if opts.AppRetries > 0 {
+		query.Set("appRetries", strconv.Itoa(opts.AppRetries))
+
+		if opts.AppRetryBackoff > 0 {
+			query.Set("appRetryBackoff", opts.AppRetryBackoff.String())
+		}
+	}
+
+	var exp Experiment
+
+	if err := this.do(ctx, "POST", "/experiments/"+url.PathEscape(name)+"/start", query, nil, &exp); err != nil {
+		return nil, err
+	}
+
+	return &exp, nil
+}
+
+// StopExperiment stops the given experiment. If stopping experiments requires
+// approval, an `*ApprovalRequiredError` is returned unless an approval is
+// provided.
+func (this *Client) StopExperiment(ctx context.Context, name string, opts ...RequestOption) (*Experiment, error) {
+	var exp Experiment
+
+	if err := this.do(ctx, "POST", "/experiments/"+url.PathEscape(name)+"/stop", nil, nil, &exp, opts...); err != nil {
+		return nil, err
+	}
+
+	return &exp, nil
+}
+
+// ListExperimentFiles lists the files generated for the given experiment,
+// optionally filtered by the given filter.
+func (this *Client) ListExperimentFiles(ctx context.Context, name, filter string) ([]File, error) {
+	query := make(url.Values)
+
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+
+	var resp struct {
+		Files []File `json:"files"`
+	}
+
+	if err := this.do(ctx, "GET", "/experiments/"+url.PathEscape(name)+"/files", query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Files, nil
+}
+
+// GetExperimentFile downloads the given experiment file, as listed by
+// `ListExperimentFiles`.
+func (this *Client) GetExperimentFile(ctx context.Context, name string, file File) ([]byte, error) {
+	query := url.Values{"path": {file.Path}}
+
+	var data []byte
+
+	if err := this.do(ctx, "GET", "/experiments/"+url.PathEscape(name)+"/files/"+url.PathEscape(file.Name), query, nil, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
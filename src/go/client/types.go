@@ -0,0 +1,131 @@
+package client
+
+import "time"
+
+// User is the user authenticated by a successful login.
+type User struct {
+	Username      string   `json:"username"`
+	FirstName     string   `json:"first_name"`
+	LastName      string   `json:"last_name"`
+	ResourceNames []string `json:"resource_names"`
+	Role          Role     `json:"role"`
+}
+
+type Role struct {
+	Name     string   `json:"name"`
+	Policies []Policy `json:"policies"`
+}
+
+type Policy struct {
+	Resources     []string `json:"resources"`
+	ResourceNames []string `json:"resourceNames"`
+	Verbs         []string `json:"verbs"`
+}
+
+type VLAN struct {
+	VLAN  int    `json:"vlan"`
+	Alias string `json:"alias"`
+}
+
+type Experiment struct {
+	Name       string   `json:"name"`
+	Topology   string   `json:"topology"`
+	Scenario   string   `json:"scenario"`
+	StartTime  string   `json:"start_time"`
+	Running    bool     `json:"running"`
+	Status     string   `json:"status"`
+	VLANMin    int      `json:"vlan_min"`
+	VLANMax    int      `json:"vlan_max"`
+	VLANs      []VLAN   `json:"vlans"`
+	VMs        []VM     `json:"vms"`
+	Apps       []string `json:"apps"`
+	VMCount    int      `json:"vm_count"`
+	DelayedVMs int      `json:"delayed_vms"`
+}
+
+// CreateExperimentRequest is used to create an experiment from an existing
+// topology (and optionally scenario) config.
+type CreateExperimentRequest struct {
+	Name           string   `json:"name"`
+	Topology       string   `json:"topology"`
+	Scenario       string   `json:"scenario,omitempty"`
+	VLANMin        int      `json:"vlan_min,omitempty"`
+	VLANMax        int      `json:"vlan_max,omitempty"`
+	DisabledApps   []string `json:"disabled_apps,omitempty"`
+	DeployMode     string   `json:"deploy_mode,omitempty"`
+	DefaultBridge  string   `json:"default_bridge,omitempty"`
+	UseGREMesh     bool     `json:"use_gre_mesh,omitempty"`
+	WorkflowBranch string   `json:"workflow_branch,omitempty"`
+	Environment    string   `json:"environment,omitempty"`
+}
+
+// StartExperimentOptions are the optional settings used to start an
+// experiment. Zero values use the server defaults.
+type StartExperimentOptions struct {
+	Profile         string
+	BestEffort      bool
+	StageTimeout    time.Duration
+	AppTimeout      time.Duration
+	AppRetries      int
+	AppRetryBackoff time.Duration
+}
+
+type Capture struct {
+	VM        string `json:"vm"`
+	Interface int    `json:"interface"`
+	Filepath  string `json:"filepath"`
+}
+
+type VM struct {
+	Name            string    `json:"name"`
+	Experiment      string    `json:"experiment"`
+	Host            string    `json:"host"`
+	IPv4            []string  `json:"ipv4"`
+	CPUs            int       `json:"cpus"`
+	RAM             int       `json:"ram"`
+	Disk            string    `json:"disk"`
+	Uptime          float64   `json:"uptime"`
+	Networks        []string  `json:"networks"`
+	Taps            []string  `json:"taps"`
+	Captures        []Capture `json:"captures"`
+	DoNotBoot       bool      `json:"dnb"`
+	Running         bool      `json:"running"`
+	Busy            bool      `json:"busy"`
+	State           string    `json:"state"`
+	CdRom           string    `json:"cdRom"`
+	Tags            []string  `json:"tags"`
+	CCActive        bool      `json:"ccActive"`
+	External        bool      `json:"external"`
+	DelayedStart    string    `json:"delayed_start"`
+	Snapshot        bool      `json:"snapshot"`
+	InjectPartition int       `json:"inject_partition"`
+}
+
+// Config is a phenix config (ie. a topology, scenario, or image), identified by
+// its kind and name.
+type Config struct {
+	Version  string         `json:"apiVersion"`
+	Kind     string         `json:"kind"`
+	Metadata ConfigMetadata `json:"metadata"`
+	Spec     map[string]any `json:"spec,omitempty"`
+	Status   map[string]any `json:"status,omitempty"`
+}
+
+type ConfigMetadata struct {
+	Name        string            `json:"name"`
+	Created     string            `json:"created,omitempty"`
+	Updated     string            `json:"updated,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// File is a file generated for an experiment (ie. a packet capture or app
+// output). Path is used to download it.
+type File struct {
+	Name       string   `json:"name"`
+	Path       string   `json:"path"`
+	Date       string   `json:"date"`
+	Size       int64    `json:"size"`
+	Categories []string `json:"categories"`
+	PlainText  bool     `json:"plainText"`
+	IsDir      bool     `json:"isDir"`
+}
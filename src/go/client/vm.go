@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+func (this *Client) ListVMs(ctx context.Context, exp string) ([]VM, error) {
+	var resp struct {
+		VMs []VM `json:"vms"`
+	}
+
+	if err := this.do(ctx, "GET", vmsPath(exp), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.VMs, nil
+}
+
+func (this *Client) GetVM(ctx context.Context, exp, name string) (*VM, error) {
+	var vm VM
+
+	if err := this.do(ctx, "GET", vmPath(exp, name), nil, nil, &vm); err != nil {
+		return nil, err
+	}
+
+	return &vm, nil
+}
+
+// StartVM starts (unpauses) the given VM in a running experiment.
+func (this *Client) StartVM(ctx context.Context, exp, name string) (*VM, error) {
+	var vm VM
+
+	if err := this.do(ctx, "POST", vmPath(exp, name)+"/start", nil, nil, &vm); err != nil {
+		return nil, err
+	}
+
+	return &vm, nil
+}
+
+// StopVM stops (pauses) the given VM in a running experiment.
+func (this *Client) StopVM(ctx context.Context, exp, name string) (*VM, error) {
+	var vm VM
+
+	if err := this.do(ctx, "POST", vmPath(exp, name)+"/stop", nil, nil, &vm); err != nil {
+		return nil, err
+	}
+
+	return &vm, nil
+}
+
+// DeleteVM kills the given VM in a running experiment. If killing VMs requires
+// approval, an `*ApprovalRequiredError` is returned unless an approval is
+// provided.
+func (this *Client) DeleteVM(ctx context.Context, exp, name string, opts ...RequestOption) error {
+	return this.do(ctx, "DELETE", vmPath(exp, name), nil, nil, nil, opts...)
+}
+
+func vmsPath(exp string) string {
+	return "/experiments/" + url.PathEscape(exp) + "/vms"
+}
+
+func vmPath(exp, name string) string {
+	return vmsPath(exp) + "/" + url.PathEscape(name)
+}
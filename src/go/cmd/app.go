@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"phenix/api/userapp"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/spf13/cobra"
+)
+
+func newAppCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "app",
+		Short: "User app management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newAppInstallCmd() *cobra.Command {
+	desc := `Install a user app
+
+  Fetches a user app from a git repository, an OCI artifact (prefixed with
+  oci://, requires oras), or a local directory. The app source must include a
+  phenix-app.yml manifest at its root specifying the app's name, version,
+  executable, and the executable's sha256 checksum, and optionally a schema
+  for the app's scenario metadata. Once verified, the executable is installed
+  as phenix-app-<name> and the app's metadata is registered with phenix.
+
+  Each version of an app installed is also kept by phenix, so scenarios can
+  pin an app to a previously installed version using the app's 'version'
+  setting (ie. version: 1.2.0). Installing a new version of an app does not
+  require --force; reinstalling an already installed version does.`
+
+	example := `
+  phenix app install https://github.com/example/phenix-app-foo.git --ref v1.2.0
+  phenix app install oci://ghcr.io/example/phenix-app-foo:1.2.0
+  phenix app install ./phenix-app-foo --dir /opt/phenix/bin`
+
+	cmd := &cobra.Command{
+		Use:     "install <source>",
+		Short:   "Install a user app",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := []userapp.InstallOption{
+				userapp.InstallWithRef(MustGetString(cmd.Flags(), "ref")),
+				userapp.InstallWithDir(MustGetString(cmd.Flags(), "dir")),
+				userapp.InstallWithForce(MustGetBool(cmd.Flags(), "force")),
+			}
+
+			app, err := userapp.Install(sigterm.CancelContext(context.Background()), args[0], opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to install app from %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s app (version %s) was installed to %s\n", app.Metadata.Name, app.Spec.Version, app.Spec.Executable)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("ref", "", "Git branch or tag to install")
+	cmd.Flags().String("dir", userapp.DefaultInstallDir, "Directory to install the app executable to (must be in phenix's PATH)")
+	cmd.Flags().Bool("force", false, "Replace the app version if it's already installed")
+
+	return cmd
+}
+
+func newAppListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Display a table of installed user apps",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apps, err := userapp.List()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get list of installed apps")
+				return err.Humanized()
+			}
+
+			if len(apps) == 0 {
+				fmt.Printf("\nThere are no installed apps\n\n")
+			} else {
+				printer.PrintTableOfApps(os.Stdout, apps...)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newAppScaffoldCmd() *cobra.Command {
+	desc := `Generate a new user app
+
+  Generates a working user app skeleton in Go or Python, including handling of
+  each experiment stage wired to the JSON contract phenix uses when running
+  user apps, a stub schema for the app's scenario metadata, tests, a Makefile,
+  and a phenix-app.yml manifest. Once generated, the app can be tested and
+  installed using 'make test' and 'make install'.
+
+  The app is generated in the phenix-app-<name> directory unless --dir is
+  provided.`
+
+	example := `
+  phenix app scaffold my-app
+  phenix app scaffold --lang python --dir ./apps/my-app my-app`
+
+	cmd := &cobra.Command{
+		Use:     "scaffold <name>",
+		Short:   "Generate a new user app",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := []userapp.ScaffoldOption{
+				userapp.ScaffoldWithLang(MustGetString(cmd.Flags(), "lang")),
+				userapp.ScaffoldWithDir(MustGetString(cmd.Flags(), "dir")),
+				userapp.ScaffoldWithForce(MustGetBool(cmd.Flags(), "force")),
+			}
+
+			files, err := userapp.Scaffold(args[0], opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to generate app %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s app was generated\n\n", args[0])
+
+			for _, f := range files {
+				fmt.Printf("  %s\n", f)
+			}
+
+			fmt.Println()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("lang", "go", "Language to generate the app in (go or python)")
+	cmd.Flags().String("dir", "", "Directory to generate the app in (defaults to phenix-app-<name>)")
+	cmd.Flags().Bool("force", false, "Overwrite files in an existing directory")
+
+	return cmd
+}
+
+func init() {
+	appCmd := newAppCmd()
+
+	appCmd.AddCommand(newAppInstallCmd())
+	appCmd.AddCommand(newAppListCmd())
+	appCmd.AddCommand(newAppScaffoldCmd())
+
+	rootCmd.AddCommand(appCmd)
+}
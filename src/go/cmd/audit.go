@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"phenix/util"
+	"phenix/util/audit"
+	"phenix/util/printer"
+
+	"github.com/spf13/cobra"
+)
+
+func newAuditCmd() *cobra.Command {
+	desc := `Display the audit log
+
+  Used to display events recorded in the audit log, including each app invoked
+  for an experiment (who triggered it, the experiment and stage, how long it
+  ran, how it exited, and the host it ran on). Use --action and --resource to
+  limit events to those whose action or resource starts with the given value,
+  and --since to limit them to recent events (as a duration or RFC3339 time).`
+
+	example := `
+  phenix audit --since 24h
+  phenix audit --action app/ --experiment <experiment name>
+  phenix audit --user alice --limit 20`
+
+	cmd := &cobra.Command{
+		Use:     "audit",
+		Short:   "Display the audit log",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := audit.Filter{
+				User:       MustGetString(cmd.Flags(), "user"),
+				Action:     MustGetString(cmd.Flags(), "action"),
+				Resource:   MustGetString(cmd.Flags(), "resource"),
+				Experiment: MustGetString(cmd.Flags(), "experiment"),
+				Limit:      MustGetInt(cmd.Flags(), "limit"),
+			}
+
+			if since := MustGetString(cmd.Flags(), "since"); since != "" {
+				if d, err := time.ParseDuration(since); err == nil {
+					filter.Since = time.Now().Add(-d)
+				} else if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+					err := util.HumanizeError(err, "Invalid --since value provided (expected duration or RFC3339 time)")
+					return err.Humanized()
+				}
+			}
+
+			events, err := audit.Query(filter)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get audit events")
+				return err.Humanized()
+			}
+
+			if len(events) == 0 {
+				fmt.Println("There are no matching audit events")
+				return nil
+			}
+
+			printer.PrintTableOfAuditEvents(os.Stdout, events)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("user", "", "Only include events by the given user")
+	cmd.Flags().String("action", "", "Only include events whose action starts with the given value (ie. app/)")
+	cmd.Flags().String("resource", "", "Only include events whose resource starts with the given value")
+	cmd.Flags().String("experiment", "", "Only include events for the given experiment")
+	cmd.Flags().String("since", "", "Only include events since the given duration (ie. 2h) or RFC3339 time")
+	cmd.Flags().Int("limit", 0, "Only include the given number of most recent events")
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newAuditCmd())
+}
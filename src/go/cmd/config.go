@@ -8,6 +8,8 @@ import (
 	"strings"
 
 	"phenix/api/config"
+	"phenix/api/renumber"
+	"phenix/store"
 	"phenix/util"
 	"phenix/util/printer"
 
@@ -34,7 +36,7 @@ func configKindArgsValidator(multi, allowAll bool) cobra.PositionalArgs {
 				return fmt.Errorf("Expected an argument in the form of <config kind>/<config name>")
 			}
 
-			kinds := []string{"topology", "scenario", "experiment", "image", "user", "role"}
+			kinds := []string{"topology", "scenario", "experiment", "image", "user", "role", "template", "app", "service", "appliance", "overlay", "site", "view"}
 
 			if allowAll {
 				kinds = append(kinds, "all")
@@ -112,15 +114,65 @@ func newConfigListCmd() *cobra.Command {
 	return cmd
 }
 
+func newConfigSearchCmd() *cobra.Command {
+	desc := `Search stored configuration files
+
+  Searches stored configs using free text and field terms. Free text terms are
+  matched against config names, annotations, and specs. Supported field terms
+  are:
+
+    kind:<kind>                    configs of the given kind
+    annotation:<key>[=<value>]     configs with the given annotation
+    ip:<address or subnet>         topologies/experiments with a node in subnet
+    app:<name>                     scenarios/experiments using the given app
+    app-key:<key>                  scenarios/experiments with app metadata key`
+
+	example := `
+  phenix config search kind:topology ip:10.1.0.0/24
+  phenix config search app:vrouter app-key:dhcp
+  phenix config search annotation:owner=ops substation`
+
+	cmd := &cobra.Command{
+		Use:     "search <query>",
+		Short:   "Search stored configuration files",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configs, err := config.Search(config.ParseSearchQuery(strings.Join(args, " ")))
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to search configurations")
+				return err.Humanized()
+			}
+
+			fmt.Println()
+
+			if len(configs) == 0 {
+				fmt.Println("There are no configurations matching the query")
+			} else {
+				printer.PrintTableOfConfigs(os.Stdout, configs)
+			}
+
+			fmt.Println()
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func newConfigGetCmd() *cobra.Command {
 	desc := `Get a configuration
 
   This subcommand is used to get a specific configuration file by kind/name.
   Valid options for kinds of configuration files are the same as described
-  for the parent config command.`
+  for the parent config command. Use --environment to view a topology or
+  scenario with the overlays for an environment applied.`
 
 	example := `
   phenix config get topology/foo
+  phenix config get topology/foo --environment site-a
   phenix config get scenario/bar
   phenix config get experiment/foobar`
 
@@ -131,9 +183,19 @@ func newConfigGetCmd() *cobra.Command {
 		Example: example,
 		Args:    configKindArgsValidator(false, false),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			upgraded := MustGetBool(cmd.Flags(), "show-upgraded")
+			var (
+				upgraded = MustGetBool(cmd.Flags(), "show-upgraded")
+				env      = MustGetString(cmd.Flags(), "environment")
+				c        *store.Config
+				err      error
+			)
+
+			if env != "" {
+				c, err = config.Render(args[0], env)
+			} else {
+				c, err = config.Get(args[0], upgraded)
+			}
 
-			c, err := config.Get(args[0], upgraded)
 			if err != nil {
 				err := util.HumanizeError(err, "Unable to get the "+args[0]+" configuration")
 				return err.Humanized()
@@ -146,6 +208,10 @@ func newConfigGetCmd() *cobra.Command {
 
 			output := MustGetString(cmd.Flags(), "output")
 
+			if format := userPreferences().OutputFormat; format != "" && !cmd.Flags().Changed("output") {
+				output = format
+			}
+
 			switch output {
 			case "yaml":
 				m, err := yaml.Marshal(c)
@@ -181,9 +247,10 @@ func newConfigGetCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringP("output", "o", "yaml", "Configuration output format ('yaml' or 'json')")
+	cmd.Flags().StringP("output", "o", "yaml", "Configuration output format ('yaml' or 'json'; defaults to user preference if set)")
 	cmd.Flags().BoolP("pretty", "p", false, "Pretty print the JSON output")
 	cmd.Flags().BoolP("show-upgraded", "u", false, "Show upgraded version of config (if not already latest version)")
+	cmd.Flags().StringP("environment", "e", "", "Show config with the overlays for the given environment applied")
 
 	return cmd
 }
@@ -331,14 +398,90 @@ func newConfigDeleteCmd() *cobra.Command {
 	return cmd
 }
 
+func newConfigRenumberCmd() *cobra.Command {
+	desc := `Renumber the IP addresses used by configurations
+
+  This subcommand is used to renumber every IPv4 address used by one or more
+  topology, scenario, or experiment configurations (node interfaces, gateways,
+  routes, OSPF networks, ACL rules, app metadata, etc.) from one address plan
+  to another. Each --map flag renumbers a network to a network at least as
+  large, keeping each address's offset within the network (ie. with
+  10.0.0.0/16=172.16.0.0/16, 10.0.3.7 becomes 172.16.3.7).
+
+  The changes are only previewed unless --apply is provided, in which case the
+  configurations are updated in the store. Nothing is updated if a
+  configuration uses a network spanning a network being renumbered, or already
+  uses addresses in a network being renumbered to.`
+
+	example := `
+  phenix config renumber topology/foo scenario/foo --map 10.0.0.0/16=172.16.0.0/16
+  phenix config renumber topology/foo --map 10.1.0.0/24=10.11.0.0/24 --map 10.2.0.0/24=10.12.0.0/24 --apply`
+
+	cmd := &cobra.Command{
+		Use:     "renumber <kind/name> ...",
+		Short:   "Renumber the IP addresses used by configurations",
+		Long:    desc,
+		Example: example,
+		Args:    configKindArgsValidator(true, false),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := renumber.ParsePlan(MustGetStringArray(cmd.Flags(), "map")...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to parse renumbering plan")
+				return err.Humanized()
+			}
+
+			apply := MustGetBool(cmd.Flags(), "apply")
+
+			changes, err := renumber.Configs(plan, apply, args...)
+
+			if MustGetBool(cmd.Flags(), "json") {
+				m, err := json.MarshalIndent(changes, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshaling changes to JSON: %w", err)
+				}
+
+				fmt.Println(string(m))
+			} else if len(changes) > 0 {
+				printer.PrintTableOfRenumberChanges(os.Stdout, changes)
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to renumber configurations")
+				return err.Humanized()
+			}
+
+			switch {
+			case len(changes) == 0:
+				fmt.Println("No addresses to renumber")
+			case apply:
+				fmt.Printf("Renumbered %d value(s)\n", len(changes))
+			default:
+				fmt.Println("Preview only -- use --apply to update the configurations")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArray("map", nil, "Network to renumber and network to renumber it to (<from>=<to>)")
+	cmd.Flags().Bool("apply", false, "Update the configurations in the store")
+	cmd.Flags().Bool("json", false, "Output changes as JSON")
+
+	cmd.MarkFlagRequired("map")
+
+	return cmd
+}
+
 func init() {
 	configCmd := newConfigCmd()
 
 	configCmd.AddCommand(newConfigListCmd())
 	configCmd.AddCommand(newConfigGetCmd())
+	configCmd.AddCommand(newConfigSearchCmd())
 	configCmd.AddCommand(newConfigCreateCmd())
 	configCmd.AddCommand(newConfigEditCmd())
 	configCmd.AddCommand(newConfigDeleteCmd())
+	configCmd.AddCommand(newConfigRenumberCmd())
 
 	rootCmd.AddCommand(configCmd)
 }
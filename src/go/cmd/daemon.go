@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"phenix/util"
+	"phenix/util/daemon"
+	"phenix/util/printer"
+
+	"github.com/spf13/cobra"
+)
+
+func newDaemonCmd() *cobra.Command {
+	desc := `App daemon management
+
+  Used to supervise long-running processes (ie. traffic generators or scoring
+  engines) for an experiment. Daemons are restarted per their restart policy
+  and are killed, along with any processes they spawn, when the experiment is
+  stopped or deleted.`
+
+	cmd := &cobra.Command{
+		Use:   "daemon",
+		Short: "App daemon management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newDaemonStartCmd() *cobra.Command {
+	desc := `Start supervising a daemon for an experiment
+
+  Used to start the given command as a supervised daemon for the given
+  experiment. Daemon output is appended to a log file in the experiment's
+  daemon directory unless --log is provided. Restart policies are never,
+  on-failure, and always.`
+
+	example := `
+  phenix daemon start myexp trafficgen -- /usr/local/bin/trafficgen --rate 100
+  phenix daemon start myexp scorer --restart always --max-restarts 5 -- python3 scorer.py`
+
+	cmd := &cobra.Command{
+		Use:     "start <experiment name> <daemon name> -- <command> [args...]",
+		Short:   "Start supervising a daemon for an experiment",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := daemon.Spec{
+				Name:        args[1],
+				Command:     args[2],
+				Args:        args[3:],
+				Restart:     daemon.RestartPolicy(MustGetString(cmd.Flags(), "restart")),
+				MaxRestarts: MustGetInt(cmd.Flags(), "max-restarts"),
+				LogFile:     MustGetString(cmd.Flags(), "log"),
+				Dir:         MustGetString(cmd.Flags(), "dir"),
+			}
+
+			spec.Env, _ = cmd.Flags().GetStringSlice("env")
+			spec.Backoff, _ = cmd.Flags().GetDuration("backoff")
+
+			d, err := daemon.Start(args[0], spec)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to start daemon "+args[1])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Daemon %s started for experiment %s (logging to %s)\n", d.Name, d.Experiment, d.LogFile)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("restart", string(daemon.RestartOnFailure), "restart policy (never, on-failure, or always)")
+	cmd.Flags().Int("max-restarts", 0, "maximum number of restarts (0 for no limit)")
+	cmd.Flags().Duration("backoff", 0, "time to wait before restarting (defaults to 5s)")
+	cmd.Flags().String("log", "", "file to append daemon output to")
+	cmd.Flags().String("dir", "", "working directory for daemon")
+	cmd.Flags().StringSlice("env", nil, "additional environment variables for daemon (KEY=VALUE)")
+
+	return cmd
+}
+
+func newDaemonListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list [experiment name]",
+		Short: "Table of daemons for an experiment (or all experiments)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var exp string
+
+			if len(args) > 0 {
+				exp = args[0]
+			}
+
+			daemons, err := daemon.List(exp)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to list daemons")
+				return err.Humanized()
+			}
+
+			if len(daemons) == 0 {
+				fmt.Println("There are no daemons available")
+			} else {
+				printer.PrintTableOfDaemons(os.Stdout, daemons...)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newDaemonStopCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stop <experiment name> [daemon name...]",
+		Short: "Stop daemons for an experiment (all daemons if none are named)",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if err := daemon.StopAll(args[0]); err != nil {
+					err := util.HumanizeError(err, "Unable to stop daemons for experiment "+args[0])
+					return err.Humanized()
+				}
+
+				fmt.Printf("All daemons stopped for experiment %s\n", args[0])
+
+				return nil
+			}
+
+			for _, name := range args[1:] {
+				if err := daemon.Stop(args[0], name); err != nil {
+					err := util.HumanizeError(err, "Unable to stop daemon "+name)
+					return err.Humanized()
+				}
+
+				fmt.Printf("Daemon %s stopped for experiment %s\n", name, args[0])
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newDaemonSuperviseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "supervise <status file>",
+		Short:  "Supervise a daemon (used internally by phenix)",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		// Supervisors don't need (and shouldn't hold) the store or any of the
+		// other global state initialized for other commands.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := daemon.Supervise(args[0], os.Stdin); err != nil {
+				return fmt.Errorf("supervising daemon: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func init() {
+	daemonCmd := newDaemonCmd()
+
+	daemonCmd.AddCommand(newDaemonStartCmd())
+	daemonCmd.AddCommand(newDaemonListCmd())
+	daemonCmd.AddCommand(newDaemonStopCmd())
+	daemonCmd.AddCommand(newDaemonSuperviseCmd())
+
+	rootCmd.AddCommand(daemonCmd)
+}
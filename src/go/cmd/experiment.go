@@ -2,24 +2,34 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"phenix/api/config"
 	"phenix/api/experiment"
+	"phenix/api/health"
+	"phenix/api/inventory"
+	"phenix/api/readme"
 	"phenix/api/scorch/scorchexe"
+	"phenix/api/timeline"
 	"phenix/app"
 	"phenix/scheduler"
 	"phenix/types"
 	"phenix/util"
+	"phenix/util/anonymize"
+	"phenix/util/journal"
 	"phenix/util/notes"
 	"phenix/util/plog"
 	"phenix/util/printer"
 	"phenix/util/sigterm"
+	"phenix/util/subsystem"
 
 	"github.com/spf13/cobra"
 )
@@ -82,6 +92,234 @@ func newExperimentAppsCmd() *cobra.Command {
 	return cmd
 }
 
+func newExperimentAppsDiffCmd() *cobra.Command {
+	desc := `Display the changes each app would make to an experiment
+
+  Used to preview the effects of an experiment's apps before starting it. Apps
+  are applied to a copy of the experiment in dry-run mode for the configure and
+  pre-start stages (or the stages given), and the nodes, injections, and other
+  settings each app adds, removes, or changes are displayed. The experiment
+  itself is not changed.`
+
+	cmd := &cobra.Command{
+		Use:     "diff <experiment name>",
+		Short:   "Display the changes each app would make to an experiment",
+		Long:    desc,
+		Example: "  phenix experiment apps diff <experiment name>\n  phenix experiment apps diff <experiment name> --stage pre-start --json",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			var stages []app.Action
+
+			for _, stage := range MustGetStringArray(cmd.Flags(), "stage") {
+				stages = append(stages, app.Action(stage))
+			}
+
+			diffs, err := experiment.DiffApps(context.Background(), name, stages...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to diff apps for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if MustGetBool(cmd.Flags(), "json") {
+				m, err := json.MarshalIndent(diffs, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshaling app diffs to JSON: %w", err)
+				}
+
+				fmt.Println(string(m))
+				return nil
+			}
+
+			if len(diffs) == 0 {
+				fmt.Printf("No apps would be applied to the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfAppDiffs(os.Stdout, diffs)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArray("stage", nil, "Stage(s) to diff apps for (configure, pre-start; defaults to both)")
+	cmd.Flags().Bool("json", false, "Output diffs as JSON")
+
+	return cmd
+}
+
+func newExperimentAppRunsCmd() *cobra.Command {
+	desc := `Display the status of the apps applied to an experiment
+
+  Used to display the result of the last time each app was applied to an
+  experiment for each lifecycle stage, including when it started and ended and
+  the error it failed with, if any.`
+
+	cmd := &cobra.Command{
+		Use:     "app-runs <experiment name>",
+		Short:   "Display the status of the apps applied to an experiment",
+		Long:    desc,
+		Example: "  phenix experiment app-runs <experiment name>",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			runs := exp.Status.AppRuns()
+
+			if len(runs) == 0 {
+				fmt.Printf("No apps have been applied to the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfAppRuns(os.Stdout, runs)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newExperimentHealthCmd() *cobra.Command {
+	desc := `Display the health of each VM in an experiment
+
+  Used to display the health of each VM in a running experiment, aggregated
+  from state of health checks, expectations registered by apps, crash
+  detection, and CPU load thresholds. By default, only checks that aren't ok
+  are displayed.`
+
+	cmd := &cobra.Command{
+		Use:     "health <experiment name>",
+		Short:   "Display the health of each VM in an experiment",
+		Long:    desc,
+		Example: "  phenix experiment health <experiment name>",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			report, err := health.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get the health of the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if !report.Running {
+				fmt.Printf("The %s experiment is not running\n", name)
+				return nil
+			}
+
+			fmt.Printf("Overall health of the %s experiment: %s\n", name, report.Severity)
+
+			all := MustGetBool(cmd.Flags(), "all")
+			printer.PrintTableOfVMHealth(os.Stdout, report, all)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("all", false, "Display all checks, including those that are ok")
+
+	return cmd
+}
+
+func newExperimentAppsFirewallCmd() *cobra.Command {
+	desc := `Display the firewall rules the firewall app would render for an experiment
+
+  Used to review the firewall rules generated for each node from the allow and
+  deny intents in the experiment's firewall app metadata without applying the
+  app. Rules are listed in the order they're applied on each node, ending with
+  the node's default inbound policy.`
+
+	cmd := &cobra.Command{
+		Use:     "firewall <experiment name>",
+		Short:   "Display the firewall rules the firewall app would render for an experiment",
+		Long:    desc,
+		Example: "  phenix experiment apps firewall <experiment name>\n  phenix experiment apps firewall <experiment name> --json",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			rules, err := app.FirewallRules(exp)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to render firewall rules for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if MustGetBool(cmd.Flags(), "json") {
+				m, err := json.MarshalIndent(rules, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshaling firewall rules to JSON: %w", err)
+				}
+
+				fmt.Println(string(m))
+				return nil
+			}
+
+			if len(rules) == 0 {
+				fmt.Printf("No firewall rules would be rendered for the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfFirewallRules(os.Stdout, rules)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Output firewall rules as JSON")
+
+	return cmd
+}
+
+func newExperimentArtifactsCmd() *cobra.Command {
+	desc := `Display the artifacts registered by apps for an experiment
+
+  Used to display the files apps registered as artifacts of an experiment (ie.
+  generated configs and reports). Artifacts can be downloaded by their path
+  via the experiment files service.`
+
+	cmd := &cobra.Command{
+		Use:     "artifacts <experiment name>",
+		Short:   "Display the artifacts registered by apps for an experiment",
+		Long:    desc,
+		Example: "  phenix experiment artifacts <experiment name>",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			artifacts, err := experiment.Artifacts(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get the artifacts of the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if len(artifacts) == 0 {
+				fmt.Printf("No artifacts registered for the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfArtifacts(os.Stdout, artifacts)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func newExperimentSchedulersCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "schedulers",
@@ -115,7 +353,9 @@ func newExperimentCreateCmd() *cobra.Command {
   phenix experiment create <experiment name> -t <topology name or /path/to/filename>
   phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename>
   phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> -d </path/to/dir/>
-  phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> --disabled-apps "app1,app2"`
+  phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> --disabled-apps "app1,app2"
+  phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> --skip-stage "soh:post-start,app1:cleanup"
+  phenix experiment create <experiment name> -t <topology name> -s <scenario name> --environment site-a`
 
 	cmd := &cobra.Command{
 		Use:     "create <experiment name>",
@@ -167,6 +407,36 @@ func newExperimentCreateCmd() *cobra.Command {
 				disabledApps[idx] = strings.TrimSpace(disabledApps[idx])
 			}
 
+			skips, err := cmd.Flags().GetStringSlice("skip-stage")
+			if err != nil {
+				err := util.HumanizeError(err, "Bad list of skip-stage provided: %v", skips)
+				return err.Humanized()
+			}
+
+			skipStages := make(map[string][]string)
+
+			for _, skip := range skips {
+				app, stage, ok := strings.Cut(strings.TrimSpace(skip), ":")
+				if !ok || app == "" || stage == "" {
+					err := util.HumanizeError(fmt.Errorf("invalid stage skip %s", skip), "Stage skips must be provided as <app>:<stage>")
+					return err.Humanized()
+				}
+
+				skipStages[app] = append(skipStages[app], stage)
+			}
+
+			deps, err := cmd.Flags().GetStringSlice("depends-on")
+			if err != nil {
+				err := util.HumanizeError(err, "Bad list of depends-on provided: %v", deps)
+				return err.Humanized()
+			}
+
+			groups, err := cmd.Flags().GetStringSlice("concurrency-group")
+			if err != nil {
+				err := util.HumanizeError(err, "Bad list of concurrency groups provided: %v", groups)
+				return err.Humanized()
+			}
+
 			opts := []experiment.CreateOption{
 				experiment.CreateWithName(args[0]),
 				experiment.CreateWithTopology(topology),
@@ -175,7 +445,14 @@ func newExperimentCreateCmd() *cobra.Command {
 				experiment.CreateWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
 				experiment.CreateWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
 				experiment.CreatedWithDisabledApplications(disabledApps),
+				experiment.CreateWithSkipStages(skipStages),
+				experiment.CreateWithDependencies(deps, MustGetString(cmd.Flags(), "depends-timeout")),
+				experiment.CreateWithConcurrencyGroups(groups),
+				experiment.CreateWithExternalBridges(MustGetStringArray(cmd.Flags(), "external-bridge")),
+				experiment.CreateWithLocale(MustGetString(cmd.Flags(), "locale"), MustGetString(cmd.Flags(), "timezone"), MustGetString(cmd.Flags(), "keyboard")),
 				experiment.CreateWithDefaultBridge(MustGetString(cmd.Flags(), "default-bridge")),
+				experiment.CreateWithOwner(getCurrentUsername()),
+				experiment.CreateWithEnvironment(MustGetString(cmd.Flags(), "environment")),
 			}
 
 			ctx := notes.Context(context.Background(), false)
@@ -201,6 +478,15 @@ func newExperimentCreateCmd() *cobra.Command {
 	cmd.Flags().Int("vlan-min", 0, "VLAN pool minimum")
 	cmd.Flags().Int("vlan-max", 0, "VLAN pool maximum")
 	cmd.Flags().StringSlice("disabled-apps", []string{}, "Comma separated ist of apps to disable")
+	cmd.Flags().StringSlice("skip-stage", []string{}, "Comma separated list of app stages to skip, as <app>:<stage> (optional)")
+	cmd.Flags().StringSlice("depends-on", []string{}, "Comma separated list of external resources the experiment depends on, as <url|mount|experiment>:<target> (optional)")
+	cmd.Flags().String("depends-timeout", "", "How long to wait for each dependency when starting the experiment, ie. 5m (checked once if not set)")
+	cmd.Flags().StringSlice("concurrency-group", []string{}, "Comma separated list of concurrency groups the experiment is a member of; only one member of a group can run at a time (optional)")
+	cmd.Flags().StringArray("external-bridge", nil, "Pre-existing OVS bridge to use, as <name>[:<alias>=<id>,...][:no-destroy] with any externally-managed VLANs on it (can be repeated)")
+	cmd.Flags().String("locale", "", "Locale to configure on VMs, ie. de_DE.UTF-8 (optional)")
+	cmd.Flags().String("timezone", "", "Timezone to configure on VMs, ie. Europe/Berlin (optional)")
+	cmd.Flags().String("keyboard", "", "Keyboard layout to configure on VMs, ie. de (optional)")
+	cmd.Flags().StringP("environment", "e", "", "Environment to apply topology and scenario overlays for (optional)")
 	return cmd
 }
 
@@ -343,7 +629,14 @@ func newExperimentStartCmd() *cobra.Command {
 	returning. If Ctrl+c is pressed, the experiment will continue to run but
 	the running stage will no longer continue to be triggered for any apps
 	configured (via the scenario) to have their running stage triggered
-	periodically.`
+	periodically.
+
+	The --profile flag controls expensive start behaviors in one switch. The
+	'full' profile (default) starts the experiment exactly as configured. The
+	'lite' profile skips the state-of-health app and any scenario apps marked
+	as optional, uses snapshot disks for all VMs so disk writes aren't
+	persisted, and reduces VM start delays to a tenth of their configured
+	value.`
 
 	cmd := &cobra.Command{
 		Use:   "start <experiment name>",
@@ -391,6 +684,13 @@ func newExperimentStartCmd() *cobra.Command {
 					experiment.StartWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
 					experiment.StartWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
 					experiment.StartWithMMErrorsAsWarnings(MustGetBool(cmd.Flags(), "treat-mm-errors-as-warnings")),
+					experiment.StartWithProfile(MustGetString(cmd.Flags(), "profile")),
+					experiment.StartWithBestEffort(MustGetBool(cmd.Flags(), "best-effort")),
+					experiment.StartWithStageTimeout(MustGetDuration(cmd.Flags(), "stage-timeout")),
+					experiment.StartWithAppTimeout(MustGetDuration(cmd.Flags(), "app-timeout")),
+					experiment.StartWithAppRetries(MustGetInt(cmd.Flags(), "app-retries"), MustGetDuration(cmd.Flags(), "app-retry-backoff")),
+					experiment.StartWithProgress(printAppProgress),
+					experiment.StartWithQueue(MustGetBool(cmd.Flags(), "queue")),
 				}
 
 				if err := experiment.Start(ctx, opts...); err != nil {
@@ -408,6 +708,14 @@ func newExperimentStartCmd() *cobra.Command {
 					if err := app.PeriodicallyRunApps(ctx, &wg, &exp); err != nil {
 						plog.Error("scheduling experiment apps to run periodically", "err", err)
 					}
+
+					if err := timeline.Start(ctx, &wg, &exp); err != nil {
+						plog.Error("starting experiment timeline", "err", err)
+					}
+
+					if err := experiment.LaunchDeferred(ctx, &wg, &exp); err != nil {
+						plog.Error("launching deferred VMs", "err", err)
+					}
 				}
 			}
 
@@ -422,10 +730,17 @@ func newExperimentStartCmd() *cobra.Command {
 	}
 
 	cmd.Flags().Bool("dry-run", false, "Do everything but actually call out to minimega")
-	cmd.Flags().Bool("honor-run-periodically", false, "Periodically trigger running stage in apps if configured in scenario")
+	cmd.Flags().Bool("honor-run-periodically", false, "Periodically trigger running stage in apps and execute timeline events if configured in scenario")
 	cmd.Flags().Bool("treat-mm-errors-as-warnings", false, "Treat errors from minimega as warnings instead of failing")
 	cmd.Flags().Int("vlan-min", 0, "VLAN pool minimum")
 	cmd.Flags().Int("vlan-max", 0, "VLAN pool maximum")
+	cmd.Flags().String("profile", "full", "Start profile to use (full or lite)")
+	cmd.Flags().Bool("best-effort", false, "Launch as many VMs as fit on the cluster and defer the rest until capacity is available")
+	cmd.Flags().Bool("queue", false, "Wait for running members of the experiment's concurrency groups to stop instead of failing to start")
+	cmd.Flags().Duration("stage-timeout", 0, "Cancel apps still running after this long in each stage (no limit if 0)")
+	cmd.Flags().Duration("app-timeout", 0, "Cancel each app still running after this long in each stage, unless overridden by the app's timeout metadata (no limit if 0)")
+	cmd.Flags().Int("app-retries", 0, "Retry each app that fails up to this many times in each stage, unless overridden by the app's retries metadata")
+	cmd.Flags().Duration("app-retry-backoff", app.DEFAULT_RETRY_BACKOFF, "Wait this long before retrying an app that failed (doubled for each retry after the first)")
 
 	return cmd
 }
@@ -471,7 +786,7 @@ func newExperimentStopCmd() *cobra.Command {
 					continue
 				}
 
-				if err := experiment.Stop(exp.Metadata.Name); err != nil {
+				if err := experiment.Stop(exp.Metadata.Name, experiment.StopWithProgress(printAppProgress)); err != nil {
 					err := util.HumanizeError(err, "Problem encountered while stopping the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
@@ -531,12 +846,12 @@ func newExperimentRestartCmd() *cobra.Command {
 					continue
 				}
 
-				if err := experiment.Stop(exp.Metadata.Name); err != nil {
+				if err := experiment.Stop(exp.Metadata.Name, experiment.StopWithProgress(printAppProgress)); err != nil {
 					err := util.HumanizeError(err, "Unable to stop the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
 
-				if err := experiment.Start(ctx, experiment.StartWithName(exp.Metadata.Name), experiment.StartWithDryRun(dryrun)); err != nil {
+				if err := experiment.Start(ctx, experiment.StartWithName(exp.Metadata.Name), experiment.StartWithDryRun(dryrun), experiment.StartWithProgress(printAppProgress)); err != nil {
 					err := util.HumanizeError(err, "Unable to start the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
@@ -674,6 +989,518 @@ func newExperimentTriggerRunningCmd() *cobra.Command {
 	return cmd
 }
 
+func newExperimentInventoryCmd() *cobra.Command {
+	desc := `Collect or query guest software inventory for an experiment
+
+  Used to collect installed packages, running processes, and running services
+  from the VMs in a running experiment via minimega's C2, or to query the
+  inventory previously collected. Providing VM names limits the VMs inventory
+  is collected from or displayed for. Use the --package and --version flags to
+  find VMs running a package at a specific version (ie. --package openssl
+  --version "<1.1.1").`
+
+	cmd := &cobra.Command{
+		Use:   "inventory <experiment name> [<vm name> ...]",
+		Short: "Collect or query guest software inventory",
+		Long:  desc,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name = args[0]
+				vms  = args[1:]
+				pkg  = MustGetString(cmd.Flags(), "package")
+
+				ctx = sigterm.CancelContext(context.Background())
+			)
+
+			if MustGetBool(cmd.Flags(), "collect") {
+				opts := []inventory.CollectOption{
+					inventory.CollectFromHosts(vms...),
+					inventory.CollectWithC2Timeout(MustGetDuration(cmd.Flags(), "c2-timeout")),
+				}
+
+				if _, err := inventory.Collect(ctx, name, opts...); err != nil {
+					err := util.HumanizeError(err, "Unable to collect inventory for the "+name+" experiment")
+					return err.Humanized()
+				}
+			}
+
+			if pkg != "" {
+				matches, err := inventory.Query(name, pkg, MustGetString(cmd.Flags(), "version"))
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to query inventory for the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				printer.PrintTableOfInventoryMatches(os.Stdout, matches)
+				return nil
+			}
+
+			hosts, err := inventory.Get(name, vms...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get inventory for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			printer.PrintTableOfInventories(os.Stdout, hosts)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("collect", false, "Collect inventory from VMs before displaying it")
+	cmd.Flags().Duration("c2-timeout", 1*time.Minute, "Time to wait for each C2 command when collecting inventory")
+	cmd.Flags().String("package", "", "Only display VMs with the given package installed")
+	cmd.Flags().String("version", "", "Version constraint for the --package flag (ie. '<1.1.1')")
+
+	return cmd
+}
+
+func newExperimentReadmeCmd() *cobra.Command {
+	desc := `Get or set the README for an experiment
+
+  Used to manage the operator notes (markdown) stored with an experiment. By
+  default the raw README is displayed. Use --render to substitute live values
+  for the experiment (ie. {{ ip "host" "eth0" }}, {{ vlan "EXP" }}, {{ console
+  "host" }}, and {{ credential "name" }}), and --set to replace the README
+  with the contents of the given file (an empty file removes the README).`
+
+	cmd := &cobra.Command{
+		Use:   "readme <experiment name>",
+		Short: "Get or set the README for an experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if path := MustGetString(cmd.Flags(), "set"); path != "" {
+				md, err := os.ReadFile(path)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to read README file "+path)
+					return err.Humanized()
+				}
+
+				if err := readme.Set(name, string(md)); err != nil {
+					err := util.HumanizeError(err, "Unable to set README for the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				fmt.Printf("README for the %s experiment updated\n", name)
+				return nil
+			}
+
+			var (
+				md  string
+				err error
+			)
+
+			if MustGetBool(cmd.Flags(), "render") {
+				md, err = readme.Render(name, readme.RenderWithBaseURL(MustGetString(cmd.Flags(), "base-url")))
+			} else {
+				md, err = readme.Get(name)
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get README for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			fmt.Println(md)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("set", "", "Path to markdown file to set as the experiment README")
+	cmd.Flags().Bool("render", false, "Substitute live experiment values into the README")
+	cmd.Flags().String("base-url", "", "Base URL of the phenix UI to use for console links when rendering")
+
+	return cmd
+}
+
+func newExperimentBundleCmd() *cobra.Command {
+	desc := `Export an experiment bundle
+
+  Used to export a gzipped tarball containing the experiment config, its state
+  of health data, and its files (ie. packet captures). Use --anonymize to
+  consistently remap IP addresses, MAC addresses, and node hostnames in all the
+  bundle contents so it can be shared externally. Remapping uses the key in the
+  file given by --key (generated if it doesn't exist yet), so bundles exported
+  with the same key remap addresses the same way. Files that can't be
+  anonymized are left out of anonymized bundles.`
+
+	example := `
+  phenix experiment bundle <experiment name> --output exp.tgz
+  phenix experiment bundle <experiment name> --output exp.tgz --anonymize --key ./dataset.key`
+
+	cmd := &cobra.Command{
+		Use:     "bundle <experiment name>",
+		Short:   "Export an experiment bundle",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name   = args[0]
+				output = MustGetString(cmd.Flags(), "output")
+				opts   = []experiment.BundleOption{experiment.BundleWithFiles(!MustGetBool(cmd.Flags(), "no-files"))}
+			)
+
+			if output == "" {
+				output = name + ".tgz"
+			}
+
+			if MustGetBool(cmd.Flags(), "anonymize") {
+				path := MustGetString(cmd.Flags(), "key")
+
+				if path == "" {
+					path = experiment.AnonymizeKeyPath()
+				}
+
+				key, err := anonymize.LoadKey(path)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to load anonymization key")
+					return err.Humanized()
+				}
+
+				anon, err := experiment.Anonymizer(name, key)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to anonymize the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				opts = append(opts, experiment.BundleWithAnonymizer(anon))
+			}
+
+			f, err := os.Create(output)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to create output file "+output)
+				return err.Humanized()
+			}
+
+			defer f.Close()
+
+			skipped, err := experiment.Bundle(name, f, opts...)
+			if err != nil {
+				os.Remove(output)
+
+				err := util.HumanizeError(err, "Unable to export bundle for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			for _, s := range skipped {
+				fmt.Printf("  === file %s left out of bundle ===\n", s)
+			}
+
+			fmt.Printf("Bundle for the %s experiment exported to %s\n", name, output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Output file (defaults to <experiment name>.tgz)")
+	cmd.Flags().Bool("anonymize", false, "Anonymize IP addresses, MAC addresses, and hostnames in bundle contents")
+	cmd.Flags().String("key", "", "Anonymization key file (defaults to anonymize.key in the phenix base directory)")
+	cmd.Flags().Bool("no-files", false, "Leave experiment files out of the bundle")
+
+	return cmd
+}
+
+func newExperimentJournalCmd() *cobra.Command {
+	desc := `Display the event timeline for an experiment
+
+  Used to display the timeline of significant events for an experiment:
+  lifecycle changes, app results, VM state changes, SoH alerts, user actions,
+  and manual annotations (see 'phenix experiment annotate'). Use --category to
+  limit the timeline to a comma separated list of categories and --since to
+  limit it to recent events (as a duration or RFC3339 time).`
+
+	example := `
+  phenix experiment journal <experiment name>
+  phenix experiment journal <experiment name> --category app,soh --since 2h`
+
+	cmd := &cobra.Command{
+		Use:     "journal <experiment name>",
+		Short:   "Display the event timeline for an experiment",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name   = args[0]
+				filter journal.Filter
+				err    error
+			)
+
+			if filter.Categories, err = journal.ParseCategories(MustGetString(cmd.Flags(), "category")); err != nil {
+				err := util.HumanizeError(err, "Invalid timeline category provided")
+				return err.Humanized()
+			}
+
+			if since := MustGetString(cmd.Flags(), "since"); since != "" {
+				if d, err := time.ParseDuration(since); err == nil {
+					filter.Since = time.Now().Add(-d)
+				} else if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+					err := util.HumanizeError(err, "Invalid --since value provided (expected duration or RFC3339 time)")
+					return err.Humanized()
+				}
+			}
+
+			entries, err := journal.Timeline(name, filter)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get timeline for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if len(entries) == 0 {
+				fmt.Printf("There are no timeline entries for the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfJournalEntries(os.Stdout, entries)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("category", "", "Comma separated list of categories to include (lifecycle, app, vm, soh, user, annotation)")
+	cmd.Flags().String("since", "", "Only include entries since the given duration (ie. 2h) or RFC3339 time")
+
+	return cmd
+}
+
+func newExperimentAnnotateCmd() *cobra.Command {
+	desc := `Annotate the event timeline for an experiment
+
+  Used to add a manual annotation (ie. "red team began phase 2") to the
+  timeline for an experiment. Annotations are added at the current time unless
+  an RFC3339 time is given with --at.`
+
+	example := `
+  phenix experiment annotate <experiment name> red team began phase 2
+  phenix experiment annotate <experiment name> --at 2024-05-01T14:00:00Z white cell paused exercise`
+
+	cmd := &cobra.Command{
+		Use:     "annotate <experiment name> <message...>",
+		Short:   "Annotate the event timeline for an experiment",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name     = args[0]
+				at       time.Time
+				username string
+			)
+
+			if v := MustGetString(cmd.Flags(), "at"); v != "" {
+				var err error
+
+				if at, err = time.Parse(time.RFC3339, v); err != nil {
+					err := util.HumanizeError(err, "Invalid --at value provided (expected RFC3339 time)")
+					return err.Humanized()
+				}
+			}
+
+			if u, err := user.Current(); err == nil {
+				username = u.Username
+			}
+
+			if _, err := journal.Annotate(name, username, strings.Join(args[1:], " "), at); err != nil {
+				err := util.HumanizeError(err, "Unable to annotate timeline for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Annotation added to timeline for the %s experiment\n", name)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("at", "", "RFC3339 time of the annotation (defaults to now)")
+
+	return cmd
+}
+
+func newExperimentPersistentCmd() *cobra.Command {
+	desc := `Display or reset the persistent disks for an experiment
+
+  Used to display the nodes in an experiment configured to keep their disk
+  across experiment restarts (via 'persistent: true' in the node's general
+  settings), along with the disk and cluster host each persists to. Use --reset
+  to delete the persistent disks of a stopped experiment (or just the nodes
+  given with --node) so they're created again from their base images the next
+  time the experiment is started.`
+
+	example := `
+  phenix experiment persistent <experiment name>
+  phenix experiment persistent <experiment name> --reset --node db-server`
+
+	cmd := &cobra.Command{
+		Use:     "persistent <experiment name>",
+		Short:   "Display or reset the persistent disks for an experiment",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if MustGetBool(cmd.Flags(), "reset") {
+				nodes, _ := cmd.Flags().GetStringSlice("node")
+
+				if err := experiment.ResetPersistentDisks(name, nodes...); err != nil {
+					err := util.HumanizeError(err, "Unable to reset persistent disks for the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				fmt.Printf("Persistent disks reset for the %s experiment\n", name)
+
+				return nil
+			}
+
+			disks, err := experiment.PersistentDisks(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get persistent disks for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if len(disks) == 0 {
+				fmt.Printf("There are no nodes with persistent disks in the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfPersistentDisks(os.Stdout, disks...)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("reset", false, "Delete persistent disks so they're created again on the next start")
+	cmd.Flags().StringSlice("node", nil, "Only reset the persistent disks of the given nodes")
+
+	return cmd
+}
+
+func newExperimentStartAtCmd() *cobra.Command {
+	desc := `Schedule a stopped experiment to be started later
+
+  Used to schedule a stopped experiment to be started at the given time, given
+  either as an RFC3339 timestamp or as a duration from now (ie. 2h30m). With no
+  time given, the experiment's current scheduled start is displayed. Use the
+  --cancel flag to cancel a scheduled start.
+
+  Scheduled starts are carried out by the phenix UI server, which also
+  preloads the experiment's images onto cluster hosts ahead of time (see the
+  --image-preload flag of 'phenix ui').`
+
+	example := `
+  phenix experiment start-at <experiment name> 2026-01-02T08:00:00Z
+  phenix experiment start-at <experiment name> 12h
+  phenix experiment start-at <experiment name> --cancel`
+
+	cmd := &cobra.Command{
+		Use:     "start-at <experiment name> [time]",
+		Short:   "Schedule a stopped experiment to be started later",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if MustGetBool(cmd.Flags(), "cancel") {
+				if err := experiment.ScheduleStart(name, time.Time{}, ""); err != nil {
+					err := util.HumanizeError(err, "Unable to cancel scheduled start of the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				fmt.Printf("Scheduled start of the %s experiment canceled\n", name)
+
+				return nil
+			}
+
+			if len(args) == 1 {
+				exp, err := experiment.Get(name)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to get the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				at, ok := experiment.ScheduledStart(*exp)
+				if !ok {
+					fmt.Printf("The %s experiment has no scheduled start\n", name)
+					return nil
+				}
+
+				fmt.Printf("The %s experiment is scheduled to start at %s\n", name, at.Local().Format(time.RFC3339))
+
+				return nil
+			}
+
+			at, err := time.Parse(time.RFC3339, args[1])
+			if err != nil {
+				dur, derr := time.ParseDuration(args[1])
+				if derr != nil {
+					return fmt.Errorf("invalid time %s (must be RFC3339 timestamp or duration)", args[1])
+				}
+
+				at = time.Now().Add(dur)
+			}
+
+			if err := experiment.ScheduleStart(name, at, ""); err != nil {
+				err := util.HumanizeError(err, "Unable to schedule start of the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s experiment is scheduled to start at %s\n", name, at.Local().Format(time.RFC3339))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("cancel", false, "Cancel the experiment's scheduled start")
+
+	return cmd
+}
+
+func newExperimentPreloadCmd() *cobra.Command {
+	desc := `Preload the images for a stopped experiment onto cluster hosts
+
+  Used to copy the images used by the VMs in a stopped experiment to the
+  cluster hosts they're scheduled on ahead of starting it, so the start isn't
+  slowed down by image transfers. VMs that aren't scheduled on a specific host
+  have their images copied to every cluster host.`
+
+	cmd := &cobra.Command{
+		Use:   "preload <experiment name>",
+		Short: "Preload the images for a stopped experiment onto cluster hosts",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name = args[0]
+				ctx  = sigterm.CancelContext(context.Background())
+			)
+
+			preload, err := experiment.PreloadImages(ctx, name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to preload images for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if len(preload.Transfers) == 0 {
+				fmt.Printf("No images need to be preloaded for the %s experiment\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfPreloadTransfers(os.Stdout, preload.Transfers...)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func newExperimentScorchCmd() *cobra.Command {
 	desc := `Start a Scorch run for an experiment
 
@@ -681,10 +1508,11 @@ func newExperimentScorchCmd() *cobra.Command {
 	default, the run ID used will be 0 if not provided.`
 
 	cmd := &cobra.Command{
-		Use:   "scorch <experiment name>",
-		Short: "Start a Scorch run for experiment",
-		Long:  desc,
-		Args:  cobra.MinimumNArgs(1),
+		Use:         "scorch <experiment name>",
+		Short:       "Start a Scorch run for experiment",
+		Long:        desc,
+		Args:        cobra.MinimumNArgs(1),
+		Annotations: map[string]string{"subsystem": subsystem.SCORCH},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var (
 				name = args[0]
@@ -724,18 +1552,33 @@ func init() {
 	experimentCmd := newExperimentCmd()
 
 	experimentCmd.AddCommand(newExperimentListCmd())
-	experimentCmd.AddCommand(newExperimentAppsCmd())
+	appsCmd := newExperimentAppsCmd()
+	appsCmd.AddCommand(newExperimentAppsDiffCmd())
+	appsCmd.AddCommand(newExperimentAppsFirewallCmd())
+
+	experimentCmd.AddCommand(appsCmd)
+	experimentCmd.AddCommand(newExperimentAppRunsCmd())
+	experimentCmd.AddCommand(newExperimentHealthCmd())
+	experimentCmd.AddCommand(newExperimentArtifactsCmd())
 	experimentCmd.AddCommand(newExperimentSchedulersCmd())
 	experimentCmd.AddCommand(newExperimentCreateCmd())
 	experimentCmd.AddCommand(newExperimentEditCmd())
 	experimentCmd.AddCommand(newExperimentDeleteCmd())
 	experimentCmd.AddCommand(newExperimentScheduleCmd())
+	experimentCmd.AddCommand(newExperimentPersistentCmd())
 	experimentCmd.AddCommand(newExperimentStartCmd())
+	experimentCmd.AddCommand(newExperimentStartAtCmd())
+	experimentCmd.AddCommand(newExperimentPreloadCmd())
 	experimentCmd.AddCommand(newExperimentStopCmd())
 	experimentCmd.AddCommand(newExperimentRestartCmd())
 	experimentCmd.AddCommand(newExperimentReconfigureCmd())
 	experimentCmd.AddCommand(newExperimentTriggerRunningCmd())
+	experimentCmd.AddCommand(newExperimentInventoryCmd())
+	experimentCmd.AddCommand(newExperimentReadmeCmd())
 	experimentCmd.AddCommand(newExperimentScorchCmd())
+	experimentCmd.AddCommand(newExperimentBundleCmd())
+	experimentCmd.AddCommand(newExperimentJournalCmd())
+	experimentCmd.AddCommand(newExperimentAnnotateCmd())
 
 	rootCmd.AddCommand(experimentCmd)
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"phenix/api/export"
+	"phenix/util"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportCmd() *cobra.Command {
+	desc := `Export a topology to vSphere
+
+  Used to translate a topology, or the topology of an existing experiment, into
+  vSphere constructs so the same scenario can be reproduced outside of
+  minimega. Each topology VLAN becomes a distributed port group and each VM
+  becomes a VM spec connected to the port groups for its interfaces.
+
+  The 'vsphere' format outputs a JSON plan of port groups and VM specs, while
+  the 'terraform' format outputs a Terraform configuration using the vSphere
+  provider. When exporting an experiment, VLAN IDs already assigned to the
+  experiment are used; otherwise VLAN IDs are assigned starting at --vlan-min.
+
+  VM disk images are expected to have been converted to VMDKs and uploaded to
+  the datastore folder given by --disk-folder.`
+
+	example := `
+  phenix export topology/<topology name>
+  phenix export experiment/<experiment name> --format terraform --datastore datastore1 --output main.tf`
+
+	cmd := &cobra.Command{
+		Use:     "export <kind/name>",
+		Short:   "Export a topology to vSphere",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				w      io.Writer = os.Stdout
+				output           = MustGetString(cmd.Flags(), "output")
+			)
+
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to create output file "+output)
+					return err.Humanized()
+				}
+
+				defer f.Close()
+
+				w = f
+			}
+
+			opts := []export.Option{
+				export.ExportWithDatacenter(MustGetString(cmd.Flags(), "datacenter")),
+				export.ExportWithCluster(MustGetString(cmd.Flags(), "cluster")),
+				export.ExportWithDatastore(MustGetString(cmd.Flags(), "datastore")),
+				export.ExportWithSwitch(MustGetString(cmd.Flags(), "switch")),
+				export.ExportWithDiskFolder(MustGetString(cmd.Flags(), "disk-folder")),
+				export.ExportWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
+			}
+
+			if err := export.Config(args[0], MustGetString(cmd.Flags(), "format"), w, opts...); err != nil {
+				err := util.HumanizeError(err, "Unable to export "+args[0])
+				return err.Humanized()
+			}
+
+			if output != "" {
+				fmt.Printf("%s exported to %s\n", args[0], output)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", export.FORMAT_VSPHERE, "Export format ('vsphere' or 'terraform')")
+	cmd.Flags().StringP("output", "o", "", "File to write export to (defaults to STDOUT)")
+	cmd.Flags().String("datacenter", "", "vSphere datacenter name")
+	cmd.Flags().String("cluster", "", "vSphere compute cluster name")
+	cmd.Flags().String("datastore", "", "vSphere datastore name")
+	cmd.Flags().String("switch", "", "vSphere distributed virtual switch name")
+	cmd.Flags().String("disk-folder", "phenix", "Datastore folder containing VM disk images (as VMDKs)")
+	cmd.Flags().Int("vlan-min", 100, "First VLAN ID to assign to VLANs without one")
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newExportCmd())
+}
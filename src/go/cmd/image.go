@@ -1,3 +1,5 @@
+//go:build !noimage
+
 package cmd
 
 import (
@@ -12,14 +14,18 @@ import (
 	"phenix/util"
 	"phenix/util/notes"
 	"phenix/util/printer"
+	"phenix/util/sigterm"
+	"phenix/util/subsystem"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func newImageCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "image",
-		Short: "Virtual disk image management",
+		Use:         "image",
+		Short:       "Virtual disk image management",
+		Annotations: map[string]string{"subsystem": subsystem.IMAGE},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
@@ -483,6 +489,64 @@ func newImageUpdateCmd() *cobra.Command {
 	return cmd
 }
 
+func newImageImportCmd() *cobra.Command {
+	desc := `Import a vendor appliance disk image
+
+  Used to import a vendor appliance packaged as an OVA or OVF. The package's
+  disks are converted to qcow2 images in the minimega files directory, and the
+  appliance is registered as an Appliance configuration, including a node
+  template built from the CPU, memory, and NICs described in the package.
+
+  The node template is printed once the import is complete and can be copied
+  into a topology, updating its hostname and interface VLANs as needed.`
+
+	example := `
+  phenix image import --format ova /path/to/appliance.ova
+  phenix image import --format ovf --name firewall /path/to/appliance.ovf`
+
+	cmd := &cobra.Command{
+		Use:     "import <file>",
+		Short:   "Import a vendor appliance disk image",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("Must provide a file to import")
+			}
+
+			opts := []image.ImportOption{
+				image.ImportWithFormat(MustGetString(cmd.Flags(), "format")),
+				image.ImportWithName(MustGetString(cmd.Flags(), "name")),
+				image.ImportWithForce(MustGetBool(cmd.Flags(), "force")),
+			}
+
+			appliance, err := image.Import(sigterm.CancelContext(context.Background()), args[0], opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to import "+args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Imported appliance %s (disks: %s)\n\n", appliance.Metadata.Name, strings.Join(appliance.Spec.Disks, ", "))
+
+			node, err := yaml.Marshal(appliance.Spec.Node)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to print node template for "+appliance.Metadata.Name)
+				return err.Humanized()
+			}
+
+			fmt.Println(string(node))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("format", "", "Format of file to import ('ova' or 'ovf'; defaults to file extension)")
+	cmd.Flags().StringP("name", "n", "", "Name to use for the appliance and its disk images (defaults to file name)")
+	cmd.Flags().BoolP("force", "f", false, "Replace an existing appliance with the same name")
+
+	return cmd
+}
+
 func newImageInjectMinicccCmd() *cobra.Command {
 	desc := `Inject the miniccc agent into a disk image
 
@@ -561,6 +625,8 @@ func newImageInjectMiniExeCmd() *cobra.Command {
 func init() {
 	imageCmd := newImageCmd()
 
+	subsystem.Register(subsystem.IMAGE)
+
 	imageCmd.AddCommand(newImageListCmd())
 	imageCmd.AddCommand(newImageCreateCmd())
 	imageCmd.AddCommand(newImageCreateFromCmd())
@@ -570,6 +636,7 @@ func init() {
 	imageCmd.AddCommand(newImageAppendCmd())
 	imageCmd.AddCommand(newImageRemoveCmd())
 	imageCmd.AddCommand(newImageUpdateCmd())
+	imageCmd.AddCommand(newImageImportCmd())
 	imageCmd.AddCommand(newImageInjectMinicccCmd())
 	imageCmd.AddCommand(newImageInjectMiniExeCmd())
 
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"phenix/api/config"
+	"phenix/api/importer"
+	"phenix/util"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newImportCmd() *cobra.Command {
+	desc := `Import a network lab as a topology
+
+  Used to convert an existing containerlab topology file or GNS3 project into
+  a phenix topology config. Containerlab 'linux' nodes and GNS3 Docker nodes
+  become container VMs, while all other supported nodes become KVM VMs. Each
+  point-to-point link becomes its own VLAN, and links to bridges, switches, or
+  hubs share a single VLAN per bridge, switch, or hub.
+
+  Lab images are converted to phenix image names unless mapped to an existing
+  image using --image. Any lab constructs that couldn't be converted are
+  reported once the import is complete.
+
+  The topology config is created in the store unless --output is provided, in
+  which case it's written to the given file instead.`
+
+	example := `
+  phenix import /path/to/lab.clab.yml
+  phenix import /path/to/project.gns3 --name my-lab --output my-lab.yml
+  phenix import /path/to/lab.clab.yml --image ghcr.io/nokia/srlinux=srlinux.qc2`
+
+	cmd := &cobra.Command{
+		Use:     "import <file>",
+		Short:   "Import a network lab as a topology",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			images, err := cmd.Flags().GetStringToString("image")
+			if err != nil {
+				err := util.HumanizeError(err, "Bad list of image mappings provided")
+				return err.Humanized()
+			}
+
+			opts := []importer.Option{
+				importer.ImportWithFormat(MustGetString(cmd.Flags(), "format")),
+				importer.ImportWithName(MustGetString(cmd.Flags(), "name")),
+				importer.ImportWithImages(images),
+			}
+
+			result, err := importer.File(args[0], opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to import "+args[0])
+				return err.Humanized()
+			}
+
+			name := result.Config.Metadata.Name
+
+			if output := MustGetString(cmd.Flags(), "output"); output != "" {
+				body, err := yaml.Marshal(result.Config)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to convert topology "+name+" to YAML")
+					return err.Humanized()
+				}
+
+				if err := os.WriteFile(output, body, 0644); err != nil {
+					err := util.HumanizeError(err, "Unable to write topology "+name+" to "+output)
+					return err.Humanized()
+				}
+
+				fmt.Printf("Topology %s written to %s\n", name, output)
+			} else {
+				if _, err := config.Create(config.CreateFromConfig(result.Config), config.CreateWithValidation()); err != nil {
+					err := util.HumanizeError(err, "Unable to create topology "+name)
+					return err.Humanized()
+				}
+
+				fmt.Printf("Topology %s created\n", name)
+			}
+
+			if len(result.Warnings) > 0 {
+				fmt.Println("\nThe following lab constructs were not fully imported:")
+
+				for _, w := range result.Warnings {
+					fmt.Printf("  - %s\n", w)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("format", "", "Format of file to import ('containerlab' or 'gns3'; defaults based on file extension)")
+	cmd.Flags().StringP("name", "n", "", "Name to use for the topology (defaults to lab name)")
+	cmd.Flags().StringToString("image", nil, "Map lab images to existing phenix images (ie. alpine:latest=alpine_rootfs.tgz)")
+	cmd.Flags().StringP("output", "o", "", "Write topology config to file instead of creating it in the store")
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newImportCmd())
+}
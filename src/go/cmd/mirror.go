@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"phenix/api/mirror"
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/common"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/spf13/cobra"
+)
+
+func newMirrorCmd() *cobra.Command {
+	desc := `In-cluster package mirror management
+
+  Used to serve the apt, yum, pip, and chocolatey repositories guests install
+  packages from in air-gapped ranges. Repositories are kept in the 'apt',
+  'yum', 'pip', and 'choco' subdirectories of the mirror directory. Set the
+  'mirror.url' site setting to the URL guests reach the mirror at to have the
+  startup app point guests at it. Packages declared in a node's startup app
+  host metadata ('packages' for apt/yum, 'pip', and 'choco') are installed
+  from the mirror when the node boots.`
+
+	cmd := &cobra.Command{
+		Use:   "mirror",
+		Short: "In-cluster package mirror management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newMirrorServeCmd() *cobra.Command {
+	desc := `Serve the package mirror
+
+  Used to serve the repositories in the mirror directory over HTTP until
+  interrupted. The mirror is read-only.`
+
+	example := `
+  phenix mirror serve
+  phenix mirror serve --dir /data/mirror --listen 10.255.0.254:8080`
+
+	cmd := &cobra.Command{
+		Use:     "serve",
+		Short:   "Serve the package mirror",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				ctx  = sigterm.CancelContext(context.Background())
+				dir  = mirrorDir(cmd)
+				addr = MustGetString(cmd.Flags(), "listen")
+			)
+
+			if err := mirror.Serve(ctx, dir, addr); err != nil {
+				err := util.HumanizeError(err, "Unable to serve package mirror")
+				return err.Humanized()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("dir", "", "mirror directory (defaults to 'mirror' in the phenix base directory)")
+	cmd.Flags().String("listen", ":8080", "address to serve the package mirror on")
+
+	return cmd
+}
+
+func newMirrorStatusCmd() *cobra.Command {
+	desc := `Show package mirror status
+
+  Used to list the repositories in the mirror directory, along with the mirror
+  URL guests are pointed at per the site settings (if any).`
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show package mirror status",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := mirrorDir(cmd)
+
+			repos, err := mirror.Repos(dir)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get package mirror repositories")
+				return err.Humanized()
+			}
+
+			site, err := types.SiteSettings()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get site settings")
+				return err.Humanized()
+			}
+
+			if site.Mirror == nil || site.Mirror.URL == "" {
+				fmt.Println("Guests are not pointed at a package mirror (no mirror.url site setting)")
+			} else {
+				fmt.Printf("Guests are pointed at the package mirror at %s\n", site.Mirror.URL)
+			}
+
+			if len(repos) == 0 {
+				fmt.Printf("There are no repositories in %s\n", dir)
+				return nil
+			}
+
+			printer.PrintTableOfMirrorRepos(os.Stdout, repos...)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("dir", "", "mirror directory (defaults to 'mirror' in the phenix base directory)")
+
+	return cmd
+}
+
+func mirrorDir(cmd *cobra.Command) string {
+	if dir := MustGetString(cmd.Flags(), "dir"); dir != "" {
+		return dir
+	}
+
+	return filepath.Join(common.PhenixBase, "mirror")
+}
+
+func init() {
+	mirrorCmd := newMirrorCmd()
+
+	mirrorCmd.AddCommand(newMirrorServeCmd())
+	mirrorCmd.AddCommand(newMirrorStatusCmd())
+
+	rootCmd.AddCommand(mirrorCmd)
+}
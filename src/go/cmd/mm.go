@@ -34,6 +34,10 @@ func newMMCmd() *cobra.Command {
 				return cmd.Help()
 			}
 
+			if !cmd.Flags().Changed("namespace") {
+				namespace = userPreferences().DefaultNamespace
+			}
+
 			mm, err := miniclient.Dial(common.MinimegaBase)
 			if err != nil {
 				return util.HumanizeError(err, "Unable to conect to minimega").Humanized()
@@ -65,7 +69,7 @@ func newMMCmd() *cobra.Command {
 	}
 
 	cmd.Flags().Bool("attach", false, "Attach to minimega console instead of sending commands")
-	cmd.Flags().String("namespace", "", "Default minimega namespace to use")
+	cmd.Flags().String("namespace", "", "Default minimega namespace to use (defaults to user preference if set)")
 
 	return cmd
 }
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -15,6 +16,15 @@ func MustGetString(flags *pflag.FlagSet, name string) string {
 	return val
 }
 
+func MustGetStringArray(flags *pflag.FlagSet, name string) []string {
+	val, err := flags.GetStringArray(name)
+	if err != nil {
+		panic(fmt.Sprintf("Getting value for %s: %v", name, err))
+	}
+
+	return val
+}
+
 func MustGetBool(flags *pflag.FlagSet, name string) bool {
 	val, err := flags.GetBool(name)
 	if err != nil {
@@ -32,3 +42,12 @@ func MustGetInt(flags *pflag.FlagSet, name string) int {
 
 	return val
 }
+
+func MustGetDuration(flags *pflag.FlagSet, name string) time.Duration {
+	val, err := flags.GetDuration(name)
+	if err != nil {
+		panic(fmt.Sprintf("Getting value for %s: %v", name, err))
+	}
+
+	return val
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os/user"
+
+	"phenix/util"
+	"phenix/web/rbac"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newPreferencesCmd() *cobra.Command {
+	desc := `User preference management
+
+  Used to view and set the preferences stored for a phēnix user, which are
+  shared with the web UI. The CLI uses the preferences of the phēnix user with
+  the same name as the current system user (if one exists) as defaults, ie. the
+  default output format for 'phenix config get' and the default namespace for
+  'phenix mm'.`
+
+	cmd := &cobra.Command{
+		Use:   "preferences",
+		Short: "User preference management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().String("user", "", "phēnix user to manage preferences for (defaults to current system user)")
+
+	return cmd
+}
+
+func newPreferencesGetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "Show user preferences",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u, err := preferencesUser(cmd.Flags())
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user")
+				return err.Humanized()
+			}
+
+			prefs := u.Preferences()
+
+			fmt.Printf("Default namespace: %s\n", prefs.DefaultNamespace)
+			fmt.Printf("Output format:     %s\n", prefs.OutputFormat)
+
+			if len(prefs.Views) > 0 {
+				fmt.Println("Saved views:")
+
+				for _, v := range prefs.Views {
+					fmt.Printf("  %s/%s (filter: %q)\n", v.Table, v.Name, v.Filter)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newPreferencesSetCmd() *cobra.Command {
+	example := `
+  phenix preferences set --default-namespace prod --output-format json`
+
+	cmd := &cobra.Command{
+		Use:     "set",
+		Short:   "Set user preferences",
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			u, err := preferencesUser(cmd.Flags())
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user")
+				return err.Humanized()
+			}
+
+			prefs := u.Preferences()
+
+			if cmd.Flags().Changed("default-namespace") {
+				prefs.DefaultNamespace = MustGetString(cmd.Flags(), "default-namespace")
+			}
+
+			if cmd.Flags().Changed("output-format") {
+				prefs.OutputFormat = MustGetString(cmd.Flags(), "output-format")
+			}
+
+			if err := u.UpdatePreferences(prefs); err != nil {
+				err := util.HumanizeError(err, "Unable to update preferences for user "+u.Username())
+				return err.Humanized()
+			}
+
+			fmt.Printf("Preferences updated for user %s\n", u.Username())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("default-namespace", "", "Default namespace (empty to unset)")
+	cmd.Flags().String("output-format", "", "Default output format ('yaml' or 'json', empty to unset)")
+
+	return cmd
+}
+
+// preferencesUser returns the phēnix user named by the `--user` flag, or the
+// one with the same name as the current system user.
+func preferencesUser(flags *pflag.FlagSet) (*rbac.User, error) {
+	name := MustGetString(flags, "user")
+
+	if name == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, fmt.Errorf("getting current system user: %w", err)
+		}
+
+		name = u.Username
+	}
+
+	return rbac.GetUser(name)
+}
+
+// userPreferences returns the preferences of the phēnix user with the same
+// name as the current system user, or empty preferences if there isn't one.
+func userPreferences() v1.UserPreferences {
+	u, err := user.Current()
+	if err != nil {
+		return v1.UserPreferences{}
+	}
+
+	pu, err := rbac.GetUser(u.Username)
+	if err != nil {
+		return v1.UserPreferences{}
+	}
+
+	return pu.Preferences()
+}
+
+func init() {
+	preferencesCmd := newPreferencesCmd()
+
+	preferencesCmd.AddCommand(newPreferencesGetCmd())
+	preferencesCmd.AddCommand(newPreferencesSetCmd())
+
+	rootCmd.AddCommand(preferencesCmd)
+}
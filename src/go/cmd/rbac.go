@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"phenix/util"
+	"phenix/web/rbac"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newRBACCmd() *cobra.Command {
+	desc := `Role-based access control utilities
+
+  Used to check what phēnix users (and their API tokens) are allowed to do,
+  and to test proposed role changes before they're applied.`
+
+	cmd := &cobra.Command{
+		Use:   "rbac",
+		Short: "Role-based access control utilities",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newRBACCanICmd() *cobra.Command {
+	desc := `Check whether a user can perform a verb on a resource
+
+  Used to check whether the given phēnix user (or the user the given API
+  token belongs to) is allowed to perform the given verb on the given
+  resource, optionally limited to a specific resource name. The phēnix user
+  with the same name as the current system user is checked if neither --user
+  nor --token is provided. Exits non-zero if the verb is not allowed.`
+
+	example := `
+  phenix rbac can-i get experiments
+  phenix rbac can-i delete vms exp1/vm1 --user bob`
+
+	cmd := &cobra.Command{
+		Use:     "can-i <verb> <resource> [resource name]",
+		Short:   "Check whether a user can perform a verb on a resource",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			check := rbac.Check{Verb: args[0], Resource: args[1]}
+
+			if len(args) == 3 {
+				check.Name = args[2]
+			}
+
+			var (
+				uname = MustGetString(cmd.Flags(), "user")
+				token = MustGetString(cmd.Flags(), "token")
+				u     *rbac.User
+				err   error
+			)
+
+			switch {
+			case token != "":
+				u, err = rbac.UserFromToken(token)
+			case uname != "":
+				u, err = rbac.GetUser(uname)
+			default:
+				var current *user.User
+
+				if current, err = user.Current(); err == nil {
+					u, err = rbac.GetUser(current.Username)
+				}
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user to check")
+				return err.Humanized()
+			}
+
+			role, err := u.Role()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get role for user "+u.Username())
+				return err.Humanized()
+			}
+
+			role, _ = role.WithViews()
+
+			if !role.CanI(check) {
+				return fmt.Errorf("no - user %s (%s) cannot %s", u.Username(), role.Spec.Name, check)
+			}
+
+			fmt.Printf("yes - user %s (%s) can %s\n", u.Username(), role.Spec.Name, check)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("user", "", "phēnix user to check")
+	cmd.Flags().String("token", "", "API token to check the user of")
+
+	return cmd
+}
+
+func newRBACTestCmd() *cobra.Command {
+	desc := `Test a role against a suite of expectations
+
+  Used to validate a role against a suite of expectations before applying it.
+  The role can be given as the path to a (proposed) role config file or as the
+  name of an existing role. The expectations file is a YAML (or JSON) list of
+  checks and whether they're expected to be allowed, ie.
+
+    - verb: get
+      resource: experiments
+      allowed: true
+    - verb: delete
+      resource: vms
+      name: prod/*
+      allowed: false
+
+  Exits non-zero if any expectation fails. If --apply is provided and all
+  expectations pass, the role config file is created or updated in the store.`
+
+	example := `
+  phenix rbac test ./experiment-user.yml ./expectations.yml --apply
+  phenix rbac test global-viewer ./expectations.yml`
+
+	cmd := &cobra.Command{
+		Use:     "test <role file or name> <expectations file>",
+		Short:   "Test a role against a suite of expectations",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				role *rbac.Role
+				err  error
+			)
+
+			if _, statErr := os.Stat(args[0]); statErr == nil {
+				role, err = rbac.RoleFromFile(args[0])
+			} else {
+				if MustGetBool(cmd.Flags(), "apply") {
+					return fmt.Errorf("--apply requires a role config file")
+				}
+
+				role, err = rbac.RoleFromConfig(args[0])
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get role "+args[0])
+				return err.Humanized()
+			}
+
+			body, err := os.ReadFile(args[1])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to read expectations file")
+				return err.Humanized()
+			}
+
+			var expectations []rbac.Expectation
+
+			if err := yaml.Unmarshal(body, &expectations); err != nil {
+				err := util.HumanizeError(err, "Unable to parse expectations file")
+				return err.Humanized()
+			}
+
+			results, passed := role.Test(expectations...)
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Verb", "Resource", "Name", "Expected", "Actual", "Result"})
+			table.SetAutoWrapText(false)
+
+			for _, r := range results {
+				result := "PASS"
+
+				if !r.Passed {
+					result = "FAIL"
+				}
+
+				table.Append([]string{r.Verb, r.Resource, r.Name, allowedString(r.Allowed), allowedString(r.Actual), result})
+			}
+
+			table.Render()
+
+			if !passed {
+				var failed int
+
+				for _, r := range results {
+					if !r.Passed {
+						failed++
+					}
+				}
+
+				return fmt.Errorf("%d of %d expectation(s) failed for role %s", failed, len(results), role.Spec.Name)
+			}
+
+			if MustGetBool(cmd.Flags(), "apply") {
+				if err := role.Apply(); err != nil {
+					err := util.HumanizeError(err, "Unable to apply role "+role.Spec.Name)
+					return err.Humanized()
+				}
+
+				fmt.Printf("All expectations passed - role %s applied\n", role.Spec.Name)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("apply", false, "Create or update the role config in the store if all expectations pass")
+
+	return cmd
+}
+
+func allowedString(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+
+	return "denied"
+}
+
+func init() {
+	rbacCmd := newRBACCmd()
+
+	rbacCmd.AddCommand(newRBACCanICmd())
+	rbacCmd.AddCommand(newRBACTestCmd())
+
+	rootCmd.AddCommand(rbacCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"phenix/api/retention"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/spf13/cobra"
+)
+
+func newRetentionCmd() *cobra.Command {
+	desc := `Experiment data retention management
+
+  Used to apply experiment data retention policies, which limit how old packet
+  captures and state of health results can get, how many scorch runs are kept,
+  and how large experiment files directories can grow. Policies are defined in
+  a YAML retention policy file. To apply policies periodically, pass the
+  retention policy file to 'phenix ui' with --retention-policies.`
+
+	cmd := &cobra.Command{
+		Use:   "retention",
+		Short: "Experiment data retention management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newRetentionRunCmd() *cobra.Command {
+	desc := `Apply experiment data retention policies
+
+  Used to apply the retention policies defined in the given retention policy
+  file to all experiments (or just the named experiments, if provided). Use
+  --dry-run to report the files that would be deleted without deleting them.`
+
+	example := `
+  phenix retention run retention.yml --dry-run
+  phenix retention run retention.yml long-range`
+
+	cmd := &cobra.Command{
+		Use:     "run <retention policy file> [experiment name...]",
+		Short:   "Apply experiment data retention policies",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policies, err := retention.Load(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to load retention policies")
+				return err.Humanized()
+			}
+
+			var (
+				ctx    = sigterm.CancelContext(context.Background())
+				dryRun = MustGetBool(cmd.Flags(), "dry-run")
+			)
+
+			report, err := retention.Run(ctx, policies, dryRun, args[1:]...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to apply retention policies")
+				return err.Humanized()
+			}
+
+			if len(report.Actions) == 0 {
+				fmt.Println("No files are subject to deletion by the retention policies")
+			} else {
+				printer.PrintTableOfRetentionActions(os.Stdout, report)
+
+				if dryRun {
+					fmt.Printf("%d file(s) (%s) would be deleted\n", len(report.Actions), retention.FormatSize(report.Freed()))
+				} else {
+					fmt.Printf("Freed %s\n", retention.FormatSize(report.Freed()))
+				}
+			}
+
+			if len(report.Errors) > 0 {
+				for _, e := range report.Errors {
+					fmt.Fprintln(os.Stderr, e)
+				}
+
+				return fmt.Errorf("%d error(s) applying retention policies", len(report.Errors))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "report files that would be deleted without deleting them")
+
+	return cmd
+}
+
+func init() {
+	retentionCmd := newRetentionCmd()
+
+	retentionCmd.AddCommand(newRetentionRunCmd())
+
+	rootCmd.AddCommand(retentionCmd)
+}
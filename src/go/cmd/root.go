@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -13,12 +14,15 @@ import (
 	"strings"
 
 	"phenix/api/config"
-	_ "phenix/api/scorch"
+	"phenix/api/health"
+	"phenix/app"
 	"phenix/store"
 	"phenix/util"
 	"phenix/util/common"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
 	"phenix/util/plog"
-	"phenix/web"
+	"phenix/util/subsystem"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
@@ -31,6 +35,10 @@ var (
 	hostnameSuffixes string
 	storeEndpoint    string
 	errFile          string
+
+	// onUsersConfigChange is called when the users config file changes, if set
+	// (only the UI server acts on changes to it).
+	onUsersConfigChange func()
 )
 
 var rootCmd = &cobra.Command{
@@ -49,6 +57,26 @@ var rootCmd = &cobra.Command{
 		}
 
 		common.UseGREMesh = viper.GetBool("use-gre-mesh")
+		common.QEMUArgAllowlist = viper.GetStringSlice("vm.qemu-arg-allowlist")
+		common.VMConfigAllowlist = viper.GetStringSlice("vm.config-allowlist")
+		common.SigningPolicy = viper.GetString("signing.policy")
+		common.SigningNamespacePolicies = viper.GetStringMapString("signing.namespace-policies")
+		common.SigningPublicKeys = viper.GetStringSlice("signing.public-keys")
+
+		if err := subsystem.Disable(viper.GetStringSlice("subsystems.disabled")...); err != nil {
+			return fmt.Errorf("disabling subsystems: %w", err)
+		}
+
+		if err := checkSubsystem(cmd); err != nil {
+			return err
+		}
+
+		mm.C2_DISPATCH_RATE = viper.GetFloat64("cc.dispatch-rate")
+		mm.C2_DISPATCH_HOST_LIMIT = viper.GetInt("cc.host-concurrency")
+
+		mmcli.POOL_SIZE = viper.GetInt("minimega.pool-size")
+		health.CPU_LOAD_WARNING = viper.GetFloat64("health.cpu-load-warning")
+		health.CPU_LOAD_CRITICAL = viper.GetFloat64("health.cpu-load-critical")
 
 		// check for global options set by UI server
 		if common.UnixSocket != "" {
@@ -110,6 +138,7 @@ var rootCmd = &cobra.Command{
 		)
 
 		common.ErrorFile = errFile
+		common.AuditFile = viper.GetString("log.audit-file")
 		common.StoreEndpoint = endpoint
 
 		if err := store.Init(store.Endpoint(endpoint)); err != nil {
@@ -124,6 +153,23 @@ var rootCmd = &cobra.Command{
 			return fmt.Errorf("unable to initialize default configs: %w", err)
 		}
 
+		if dir := viper.GetString("app.plugin-dir"); dir != "" {
+			// Plugins that fail to load shouldn't keep the rest of phenix from
+			// working, so only warn about them. A binary that can't load plugins
+			// at all is a configuration error though.
+			if _, err := app.LoadPlugins(dir); err != nil {
+				if errors.Is(err, app.ErrPluginsUnsupported) {
+					return fmt.Errorf("unable to use --app.plugin-dir %s: %w", dir, err)
+				}
+
+				plog.Warn("unable to load some app plugins", "dir", dir, "err", err)
+			}
+		}
+
+		app.APP_REGISTRY_DIR = viper.GetString("app.registry-dir")
+		app.SECRETS_DIR = viper.GetString("app.secrets-dir")
+		app.SECRET_ENV_ALLOWLIST = viper.GetStringSlice("app.secret-env-allowlist")
+
 		return nil
 	},
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -183,8 +229,8 @@ func init() {
 		viper.WatchConfig()
 
 		viper.OnConfigChange(func(e fsnotify.Event) {
-			if strings.TrimSuffix(filepath.Base(e.Name), filepath.Ext(e.Name)) == "users" {
-				web.ConfigureUsers(viper.GetStringSlice("ui.users"))
+			if strings.TrimSuffix(filepath.Base(e.Name), filepath.Ext(e.Name)) == "users" && onUsersConfigChange != nil {
+				onUsersConfigChange()
 			}
 		})
 	}
@@ -197,6 +243,21 @@ func init() {
 	rootCmd.PersistentFlags().String("bridge-mode", "", "bridge naming mode for experiments ('auto' uses experiment name for bridge; 'manual' uses user-specified bridge name, or 'phenix' if not specified) (options: manual | auto)")
 	rootCmd.PersistentFlags().String("deploy-mode", "", "deploy mode for minimega VMs (options: all | no-headnode | only-headnode)")
 	rootCmd.PersistentFlags().Bool("use-gre-mesh", false, "use GRE tunnels between mesh nodes for VLAN trunking")
+	rootCmd.PersistentFlags().StringSlice("vm.qemu-arg-allowlist", nil, "QEMU arguments topology nodes are allowed to append via 'qemu_append' (none allowed if empty)")
+	rootCmd.PersistentFlags().StringSlice("vm.config-allowlist", nil, "minimega vm config settings topology nodes are allowed to override via 'advanced' (all allowed if empty)")
+	rootCmd.PersistentFlags().String("signing.policy", "off", "default signature verification policy for configs and disk images when starting experiments (off, warn, enforce)")
+	rootCmd.PersistentFlags().StringToString("signing.namespace-policies", nil, "signature verification policies for specific namespaces (ie. prod=enforce,dev=warn)")
+	rootCmd.PersistentFlags().StringSlice("signing.public-keys", nil, "paths to public keys trusted to sign configs and disk images")
+	rootCmd.PersistentFlags().Float64("cc.dispatch-rate", mm.C2_DISPATCH_RATE, "cc commands dispatched to VMs en masse (ie. broadcasts and state of health checks) started per second (no limit if 0)")
+	rootCmd.PersistentFlags().Int("cc.host-concurrency", mm.C2_DISPATCH_HOST_LIMIT, "cc commands dispatched to VMs en masse allowed in flight at once per cluster host (no limit if 0)")
+	rootCmd.PersistentFlags().String("app.plugin-dir", "", "directory of Go plugin apps (.so files) to load at startup (none loaded if empty)")
+	rootCmd.PersistentFlags().StringSlice("subsystems.disabled", nil, fmt.Sprintf("optional subsystems to disable (options: %s)", strings.Join(subsystem.All, " | ")))
+	rootCmd.PersistentFlags().Int("minimega.pool-size", mmcli.POOL_SIZE, "connections to minimega kept open for running commands concurrently")
+	rootCmd.PersistentFlags().Float64("health.cpu-load-warning", health.CPU_LOAD_WARNING, "CPU load (per vCPU) at which VM health is reported as warning")
+	rootCmd.PersistentFlags().Float64("health.cpu-load-critical", health.CPU_LOAD_CRITICAL, "CPU load (per vCPU) at which VM health is reported as critical")
+	rootCmd.PersistentFlags().String("app.registry-dir", app.APP_REGISTRY_DIR, "directory of external user app registry files (watched for changes by the UI server)")
+	rootCmd.PersistentFlags().String("app.secrets-dir", app.SECRETS_DIR, "directory secrets referenced by user app secretEnv metadata must be in")
+	rootCmd.PersistentFlags().StringSlice("app.secret-env-allowlist", nil, "phenix environment variables (or glob patterns) user app secretEnv metadata is allowed to reference (none allowed if empty)")
 	rootCmd.PersistentFlags().String("unix-socket", "/tmp/phenix.sock", "phēnix unix socket to listen on (ui subcommand) or connect to")
 
 	if uid == "0" {
@@ -205,11 +266,13 @@ func init() {
 
 		rootCmd.PersistentFlags().StringVar(&storeEndpoint, "store.endpoint", "bolt:///etc/phenix/store.bdb", "endpoint for storage service")
 		rootCmd.PersistentFlags().StringVar(&errFile, "log.error-file", "/var/log/phenix/error.log", "log fatal errors to file")
+		rootCmd.PersistentFlags().String("log.audit-file", "/var/log/phenix/audit.log", "log audit events to file")
 
 		common.LogFile = "/var/log/phenix/phenix.log"
 	} else {
 		rootCmd.PersistentFlags().StringVar(&storeEndpoint, "store.endpoint", fmt.Sprintf("bolt://%s/.phenix.bdb", home), "endpoint for storage service")
 		rootCmd.PersistentFlags().StringVar(&errFile, "log.error-file", fmt.Sprintf("%s/.phenix.err", home), "log fatal errors to file")
+		rootCmd.PersistentFlags().String("log.audit-file", fmt.Sprintf("%s/.phenix.audit", home), "log audit events to file")
 
 		common.LogFile = fmt.Sprintf("%s/.phenix.log", home)
 	}
@@ -217,6 +280,40 @@ func init() {
 	viper.BindPFlags(rootCmd.PersistentFlags())
 }
 
+// reloadConfig re-reads the phenix config file, then merges in the users
+// config file again, if found.
+func reloadConfig() error {
+	var notFound viper.ConfigFileNotFoundError
+
+	viper.SetConfigName("config")
+
+	if err := viper.ReadInConfig(); err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	viper.SetConfigName("users")
+
+	if err := viper.MergeInConfig(); err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("merging users config file: %w", err)
+	}
+
+	return nil
+}
+
+// checkSubsystem returns an error if the given command, or any of its parent
+// commands, belongs to an optional subsystem that has been disabled.
+func checkSubsystem(cmd *cobra.Command) error {
+	for c := cmd; c != nil; c = c.Parent() {
+		if name, ok := c.Annotations["subsystem"]; ok {
+			if err := subsystem.Check(name); err != nil {
+				return fmt.Errorf("running %s: %w", cmd.CommandPath(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func getCurrentUserInfo() (string, string) {
 	u, err := user.Current()
 	if err != nil {
@@ -245,3 +342,17 @@ func getCurrentUserInfo() (string, string) {
 
 	return uid, home
 }
+
+func getCurrentUsername() string {
+	// Only trust `SUDO_USER` env variable if we're currently running as root.
+	if sudo := os.Getenv("SUDO_USER"); sudo != "" && os.Geteuid() == 0 {
+		return sudo
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return u.Username
+}
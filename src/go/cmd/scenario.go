@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"phenix/api/matrix"
+	"phenix/app"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/spf13/cobra"
+)
+
+func newScenarioCmd() *cobra.Command {
+	desc := `Scenario management
+
+  Used to test scenarios without deploying them.`
+
+	cmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Scenario management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newScenarioTestCmd() *cobra.Command {
+	desc := `Test scenarios against topologies in dry-run mode
+
+  Used to test scenario config files, ie. in CI for a repository of scenarios.
+  Each scenario is instantiated as a dry-run experiment against each of the
+  given topology files, or against a small synthetic topology if none are
+  given. The synthetic topology has a Linux VM, a Windows VM, a Linux VM for
+  each host listed in the scenario's apps, and any nodes added with --node (as
+  'hostname[:os]', for hosts apps only reference in their metadata).
+
+  The scenario's apps are validated and applied for the configure, pre-start,
+  post-start, and cleanup stages (or the stages given), then the experiment spec
+  is checked for IP conflicts and file injections whose source doesn't exist.
+  Exits non-zero if any scenario fails. User apps that aren't installed where
+  the tests are run can be skipped with --skip-app.`
+
+	example := `
+  phenix scenario test scenarios/*.yml
+  phenix scenario test scenario.yml --topology topology.yml --skip-app my-app
+  phenix scenario test scenario.yml --node collector --node hmi:windows --json`
+
+	cmd := &cobra.Command{
+		Use:     "test <scenario file...>",
+		Short:   "Test scenarios against topologies in dry-run mode",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := []matrix.Option{
+				matrix.WithTopologies(MustGetStringArray(cmd.Flags(), "topology")...),
+				matrix.WithSkipApps(MustGetStringArray(cmd.Flags(), "skip-app")...),
+			}
+
+			for _, n := range MustGetStringArray(cmd.Flags(), "node") {
+				node, err := matrix.ParseNode(n)
+				if err != nil {
+					return err
+				}
+
+				opts = append(opts, matrix.WithNodes(node))
+			}
+
+			for _, stage := range MustGetStringArray(cmd.Flags(), "stage") {
+				opts = append(opts, matrix.WithStages(app.Action(stage)))
+			}
+
+			ctx := sigterm.CancelContext(context.Background())
+
+			results, err := matrix.Run(ctx, args, opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to test scenarios")
+				return err.Humanized()
+			}
+
+			if MustGetBool(cmd.Flags(), "json") {
+				m, err := json.MarshalIndent(results, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshaling scenario test results to JSON: %w", err)
+				}
+
+				fmt.Println(string(m))
+			} else {
+				printer.PrintTableOfMatrixResults(os.Stdout, results)
+			}
+
+			var failed int
+
+			for _, res := range results {
+				if !res.Passed {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d scenario test(s) failed", failed, len(results))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArray("topology", nil, "Topology file(s) to test scenarios against (defaults to a synthetic topology)")
+	cmd.Flags().StringArray("node", nil, "Node(s) to add to the synthetic topology (hostname[:os])")
+	cmd.Flags().StringArray("stage", nil, "Stage(s) to apply apps for (configure, pre-start, post-start, cleanup; defaults to all)")
+	cmd.Flags().StringArray("skip-app", nil, "Scenario app(s) to skip")
+	cmd.Flags().Bool("json", false, "Output results as JSON")
+
+	return cmd
+}
+
+func init() {
+	scenarioCmd := newScenarioCmd()
+
+	scenarioCmd.AddCommand(newScenarioTestCmd())
+
+	rootCmd.AddCommand(scenarioCmd)
+}
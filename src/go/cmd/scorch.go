@@ -0,0 +1,104 @@
+//go:build !noscorch
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"phenix/api/scorch"
+	"phenix/util"
+	"phenix/util/sigterm"
+	"phenix/util/subsystem"
+
+	"github.com/spf13/cobra"
+)
+
+func newScorchCmd() *cobra.Command {
+	desc := `SCORCH component management
+
+  Used to develop and test SCORCH components. Use 'phenix experiment scorch'
+  to trigger SCORCH runs for an experiment.`
+
+	cmd := &cobra.Command{
+		Use:         "scorch",
+		Short:       "SCORCH component management",
+		Long:        desc,
+		Annotations: map[string]string{"subsystem": subsystem.SCORCH},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newScorchTestComponentCmd() *cobra.Command {
+	desc := `Run a SCORCH user component against a recorded run context
+
+  Each time a user component is run in an experiment, the context it was run
+  with is recorded in its artifact directory as 'context-<stage>.json'. This
+  command runs the component again with that context, without a live
+  experiment, so component authors can iterate quickly. The component is told
+  the experiment is a dry run unless --dry-run=false is set, and artifacts are
+  written to a temporary files directory unless --files-dir is set.`
+
+	example := `
+  phenix scorch test-component context-start.json
+  phenix scorch test-component --stage stop --exe ./my-component context-start.json`
+
+	cmd := &cobra.Command{
+		Use:     "test-component <run context file>",
+		Short:   "Run a SCORCH user component against a recorded run context",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				ctx  = sigterm.CancelContext(context.Background())
+				opts = []scorch.TestOption{
+					scorch.TestWithStage(scorch.Action(MustGetString(cmd.Flags(), "stage"))),
+					scorch.TestWithExecutable(MustGetString(cmd.Flags(), "exe")),
+					scorch.TestWithFilesDir(MustGetString(cmd.Flags(), "files-dir")),
+					scorch.TestWithDryRun(MustGetBool(cmd.Flags(), "dry-run")),
+				}
+			)
+
+			result, err := scorch.TestComponent(ctx, args[0], opts...)
+
+			if result != nil {
+				os.Stdout.Write(result.Stdout)
+				os.Stderr.Write(result.Stderr)
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to test SCORCH component")
+				return err.Humanized()
+			}
+
+			fmt.Printf("\nComponent files directory: %s\n", result.FilesDir)
+
+			for _, a := range result.Artifacts {
+				fmt.Printf("  %s\n", a)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("stage", "", "Stage to run the component for (defaults to the recorded stage)")
+	cmd.Flags().String("exe", "", "Path to the component executable (defaults to phenix-scorch-component-<type> in PATH)")
+	cmd.Flags().String("files-dir", "", "Experiment files directory to pass to the component (defaults to a temporary directory)")
+	cmd.Flags().Bool("dry-run", true, "Tell the component the experiment is a dry run")
+
+	return cmd
+}
+
+func init() {
+	scorchCmd := newScorchCmd()
+
+	scorchCmd.AddCommand(newScorchTestComponentCmd())
+
+	rootCmd.AddCommand(scorchCmd)
+}
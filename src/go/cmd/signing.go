@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+
+	"phenix/api/signing"
+	"phenix/store"
+	"phenix/util"
+	"phenix/util/common"
+
+	"github.com/spf13/cobra"
+)
+
+func newSigningCmd() *cobra.Command {
+	desc := `Config and disk image signing
+
+  Configs and disk images can be signed with Ed25519 keys so their signatures
+  can be verified when experiments are started. Topology and scenario configs
+  must be signed before an experiment is created from them, since signatures
+  are verified against the configs as they were at creation time. Verification
+  is controlled per namespace via the --signing.policy and
+  --signing.namespace-policies global flags, and trusted public keys are
+  provided via --signing.public-keys.`
+
+	cmd := &cobra.Command{
+		Use:   "signing",
+		Short: "Config and disk image signing",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newSigningKeygenCmd() *cobra.Command {
+	desc := `Generate a signing key pair
+
+  Writes the private key to <path>.key and the public key to <path>.pub.`
+
+	cmd := &cobra.Command{
+		Use:   "keygen <path>",
+		Short: "Generate a signing key pair",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := signing.GenerateKeys(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to generate signing key pair")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Signing key pair %s written to %s.key and %s.pub\n", id, args[0], args[0])
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSigningSignConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "sign-config <kind/name>",
+		Short:   "Sign a config stored in phenix",
+		Example: "  phenix signing sign-config topology/foo --key ~/phenix.key",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signing.LoadPrivateKey(MustGetString(cmd.Flags(), "key"))
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to load signing key")
+				return err.Humanized()
+			}
+
+			c, err := store.NewConfig(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to sign config %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := store.Get(c); err != nil {
+				err := util.HumanizeError(err, "Unable to get config %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := signing.SignConfig(c, key); err != nil {
+				err := util.HumanizeError(err, "Unable to sign config %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := store.Update(c); err != nil {
+				err := util.HumanizeError(err, "Unable to update config %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s config was signed\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("key", "", "Path to private signing key")
+	cmd.MarkFlagRequired("key")
+
+	return cmd
+}
+
+func newSigningSignImageCmd() *cobra.Command {
+	desc := `Sign a disk image
+
+  Writes a detached signature alongside the disk image with a .sig extension.
+  Relative image paths are relative to the minimega files directory.`
+
+	cmd := &cobra.Command{
+		Use:     "sign-image <image>",
+		Short:   "Sign a disk image",
+		Long:    desc,
+		Example: "  phenix signing sign-image ubuntu.qc2 --key ~/phenix.key",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := signing.LoadPrivateKey(MustGetString(cmd.Flags(), "key"))
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to load signing key")
+				return err.Humanized()
+			}
+
+			if err := signing.SignFile(util.GetMMFullPath(args[0]), key); err != nil {
+				err := util.HumanizeError(err, "Unable to sign disk image %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s disk image was signed\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("key", "", "Path to private signing key")
+	cmd.MarkFlagRequired("key")
+
+	return cmd
+}
+
+func newSigningVerifyCmd() *cobra.Command {
+	desc := `Verify a config or disk image signature
+
+  Verifies the signature of the given config (<kind/name>) or disk image
+  (--image) against the trusted public keys provided via --signing.public-keys.`
+
+	cmd := &cobra.Command{
+		Use:   "verify <kind/name | image>",
+		Short: "Verify a config or disk image signature",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, err := signing.LoadPublicKeys(common.SigningPublicKeys...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to load trusted public keys")
+				return err.Humanized()
+			}
+
+			if MustGetBool(cmd.Flags(), "image") {
+				if err := signing.VerifyFile(util.GetMMFullPath(args[0]), keys); err != nil {
+					err := util.HumanizeError(err, "Unable to verify disk image %s", args[0])
+					return err.Humanized()
+				}
+
+				fmt.Printf("The %s disk image signature is valid\n", args[0])
+
+				return nil
+			}
+
+			c, err := store.NewConfig(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to verify config %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := store.Get(c); err != nil {
+				err := util.HumanizeError(err, "Unable to get config %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := signing.VerifyConfig(*c, keys); err != nil {
+				err := util.HumanizeError(err, "Unable to verify config %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s config signature is valid\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("image", false, "Verify a disk image instead of a config")
+
+	return cmd
+}
+
+func init() {
+	signingCmd := newSigningCmd()
+
+	signingCmd.AddCommand(newSigningKeygenCmd())
+	signingCmd.AddCommand(newSigningSignConfigCmd())
+	signingCmd.AddCommand(newSigningSignImageCmd())
+	signingCmd.AddCommand(newSigningVerifyCmd())
+
+	rootCmd.AddCommand(signingCmd)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"phenix/api/smoke"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/spf13/cobra"
+)
+
+func newSmokeCmd() *cobra.Command {
+	desc := `Experiment smoke test management
+
+  Used to run experiment smoke tests and view their pass/fail history. Smoke
+  tests are defined in a YAML smoke test file. To run smoke tests nightly, pass
+  the smoke test file to 'phenix ui' with --smoke-tests.`
+
+	cmd := &cobra.Command{
+		Use:   "smoke",
+		Short: "Experiment smoke test management",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newSmokeRunCmd() *cobra.Command {
+	desc := `Run experiment smoke tests
+
+  Used to run the smoke tests defined in the given smoke test file (or just the
+  named tests, if provided). Each test creates an experiment from its topology
+  and scenario on its reserved hosts, checks its state of health, runs its
+  checks, then stops and deletes the experiment. Results are recorded in the
+  smoke test history.`
+
+	example := `
+  phenix smoke run smoke.yml
+  phenix smoke run smoke.yml nightly-range`
+
+	cmd := &cobra.Command{
+		Use:     "run <smoke test file> [test name...]",
+		Short:   "Run experiment smoke tests",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tests, err := smoke.Load(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to load smoke tests")
+				return err.Humanized()
+			}
+
+			var (
+				ctx    = sigterm.CancelContext(context.Background())
+				names  = args[1:]
+				failed int
+			)
+
+			for _, t := range tests {
+				if len(names) > 0 && !util.StringSliceContains(names, t.Name) {
+					continue
+				}
+
+				fmt.Printf("Running smoke test %s\n", t.Name)
+
+				res, err := smoke.Run(ctx, t)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to run smoke test "+t.Name)
+					return err.Humanized()
+				}
+
+				printer.PrintTableOfSmokeResults(os.Stdout, []smoke.Result{res})
+
+				if !res.Passed {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d smoke test(s) failed", failed)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newSmokeHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [test name]",
+		Short: "View experiment smoke test pass/fail history",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var test string
+
+			if len(args) > 0 {
+				test = args[0]
+			}
+
+			results, err := smoke.History(test)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get smoke test history")
+				return err.Humanized()
+			}
+
+			if len(results) == 0 {
+				fmt.Println("There are no recorded smoke test results")
+				return nil
+			}
+
+			printer.PrintTableOfSmokeResults(os.Stdout, results)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func init() {
+	smokeCmd := newSmokeCmd()
+
+	smokeCmd.AddCommand(newSmokeRunCmd())
+	smokeCmd.AddCommand(newSmokeHistoryCmd())
+
+	rootCmd.AddCommand(smokeCmd)
+}
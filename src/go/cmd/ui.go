@@ -1,3 +1,5 @@
+//go:build !noui
+
 package cmd
 
 import (
@@ -5,9 +7,13 @@ import (
 	"os"
 	"time"
 
+	"phenix/api/retention"
+	"phenix/api/smoke"
+	"phenix/api/webhook"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/plog"
+	"phenix/util/subsystem"
 	"phenix/web"
 
 	"github.com/spf13/cobra"
@@ -17,29 +23,22 @@ import (
 func newUICmd() *cobra.Command {
 	desc := `Run the phenix UI server
 
-  Starts the UI server on the IP:port provided.`
+  Starts the UI server on the IP:port provided.
+
+  Sending the server SIGHUP (or calling the /api/v1/options/reload API)
+  reloads the log level, authentication settings, status webhook, default
+  apps, and default scheduler from the config files without restarting the
+  server. Other settings require a restart.`
 	cmd := &cobra.Command{
-		Use:   "ui",
-		Short: "Run the phenix UI",
-		Long:  desc,
+		Use:         "ui",
+		Short:       "Run the phenix UI",
+		Long:        desc,
+		Annotations: map[string]string{"subsystem": subsystem.UI},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := web.Init(); err != nil {
 				return fmt.Errorf("initializing web package: %w", err)
 			}
 
-			opts := []web.ServerOption{
-				web.ServeOnEndpoint(viper.GetString("ui.listen-endpoint")),
-				web.ServeBasePath(viper.GetString("ui.base-path")),
-				web.ServeWithJWTKey(viper.GetString("ui.jwt-signing-key")),
-				web.ServeWithJWTLifetime(viper.GetDuration("ui.jwt-lifetime")),
-				web.ServeWithUsers(viper.GetStringSlice("ui.users")),
-				web.ServeWithTLS(viper.GetString("ui.tls-key"), viper.GetString("ui.tls-cert")),
-				web.ServeMinimegaLogs(viper.GetString("ui.logs.minimega-path")),
-				web.ServeWithFeatures(viper.GetStringSlice("ui.features")),
-				web.ServeWithProxyAuthHeader(viper.GetString("ui.proxy-auth-header")),
-				web.ServeWithUnixSocketGid(viper.GetInt("unix-socket-gid")),
-			}
-
 			if endpoint := viper.GetString("ui.unix-socket-endpoint"); endpoint != "" {
 				plog.Warn("The --ui.unix-socket-endpoint option for the ui subcommand is DEPRECATED. Use the root phenix --unix-socket option instead.")
 
@@ -69,22 +68,22 @@ func newUICmd() *cobra.Command {
 
 			if viper.GetString("ui.minimega-path") != "" {
 				fmt.Fprintln(os.Stderr, "--minimega-path is deprecated; use --minimega-console instead")
-				opts = append(opts, web.ServeMinimegaConsole(true))
-			} else if viper.GetBool("ui.minimega-console") {
-				opts = append(opts, web.ServeMinimegaConsole(true))
 			}
 
-			if MustGetBool(cmd.Flags(), "log-requests") {
-				opts = append(opts, web.ServeWithMiddlewareLogging("requests"))
+			opts, err := uiServerOptions(cmd)
+			if err != nil {
+				return util.HumanizeError(err, "Unable to configure UI").Humanized()
 			}
 
-			if MustGetBool(cmd.Flags(), "log-full") {
-				opts = append(opts, web.ServeWithMiddlewareLogging("full"))
-			}
+			// Reloading re-reads the config files, so only settings not overridden by
+			// command line flags or environment variables can be changed by a reload.
+			opts = append(opts, web.ServeWithReloader(func() ([]web.ServerOption, error) {
+				if err := reloadConfig(); err != nil {
+					return nil, err
+				}
 
-			if MustGetBool(cmd.Flags(), "unbundled") {
-				opts = append(opts, web.ServeUnbundled())
-			}
+				return uiServerOptions(cmd)
+			}))
 
 			if err := web.Start(opts...); err != nil {
 				return util.HumanizeError(err, "Unable to serve UI").Humanized()
@@ -112,6 +111,21 @@ func newUICmd() *cobra.Command {
 	cmd.Flags().StringSlice("features", nil, "list of features to enable (options: vm-mount)")
 	cmd.Flags().String("minimega-path", "", "path to minimega executable (for console access) - DEPRECATED (use --minimega-console instead)")
 	cmd.Flags().Bool("minimega-console", false, "enable minimega console access in UI")
+	cmd.Flags().Duration("approvals.window", 0, "require a second user to approve destructive operations within this window (disabled if 0)")
+	cmd.Flags().StringSlice("approvals.operations", nil, "destructive operations requiring approval (options: experiment-delete, experiment-stop, vm-kill, config-delete; defaults to all)")
+	cmd.Flags().String("callback-endpoint", "", "endpoint to serve the guest callback API on, reachable from guests via the management network (disabled if empty)")
+	cmd.Flags().Duration("guest-ip-discovery", 30*time.Second, "how often to discover guest IPs of running VMs from cc agents, DHCP leases, and ARP tables (disabled if 0)")
+	cmd.Flags().Bool("metrics", false, "serve app and experiment lifecycle metrics for Prometheus at /metrics (unauthenticated)")
+	cmd.Flags().Duration("scheduled-starts", time.Minute, "how often to check for experiments whose scheduled start is due (disabled if 0)")
+	cmd.Flags().Duration("image-preload", time.Hour, "how long before an experiment's scheduled start to preload its images onto cluster hosts (disabled if 0)")
+	cmd.Flags().String("smoke-tests", "", "path to smoke test file defining experiment smoke tests to run nightly")
+	cmd.Flags().String("retention-policies", "", "path to retention policy file defining experiment data retention policies to apply periodically")
+	cmd.Flags().StringSlice("default-apps", nil, "default apps applied to every experiment (options: ntp, pxe, serial, startup, vrouter; defaults to all)")
+	cmd.Flags().String("default-scheduler", "", "scheduler used when scheduling an experiment without specifying a scheduler")
+	cmd.Flags().String("status-webhook.url", "", "URL of external API to post experiment status changes to")
+	cmd.Flags().String("status-webhook.secret", "", "secret used to sign experiment status documents posted to external API")
+	cmd.Flags().Duration("status-webhook.interval", 5*time.Second, "how often to check experiment status for changes")
+	cmd.Flags().Int("status-webhook.retries", 5, "number of times to retry posting experiment status before moving it to dead-letter queue")
 
 	viper.BindPFlag("ui.listen-endpoint", cmd.Flags().Lookup("listen-endpoint"))
 	viper.BindPFlag("ui.unix-socket-endpoint", cmd.Flags().Lookup("unix-socket-endpoint"))
@@ -130,6 +144,21 @@ func newUICmd() *cobra.Command {
 	viper.BindPFlag("ui.features", cmd.Flags().Lookup("features"))
 	viper.BindPFlag("ui.minimega-path", cmd.Flags().Lookup("minimega-path"))
 	viper.BindPFlag("ui.minimega-console", cmd.Flags().Lookup("minimega-console"))
+	viper.BindPFlag("ui.approvals.window", cmd.Flags().Lookup("approvals.window"))
+	viper.BindPFlag("ui.approvals.operations", cmd.Flags().Lookup("approvals.operations"))
+	viper.BindPFlag("ui.callback-endpoint", cmd.Flags().Lookup("callback-endpoint"))
+	viper.BindPFlag("ui.guest-ip-discovery", cmd.Flags().Lookup("guest-ip-discovery"))
+	viper.BindPFlag("ui.metrics", cmd.Flags().Lookup("metrics"))
+	viper.BindPFlag("ui.scheduled-starts", cmd.Flags().Lookup("scheduled-starts"))
+	viper.BindPFlag("ui.image-preload", cmd.Flags().Lookup("image-preload"))
+	viper.BindPFlag("ui.smoke-tests", cmd.Flags().Lookup("smoke-tests"))
+	viper.BindPFlag("ui.retention-policies", cmd.Flags().Lookup("retention-policies"))
+	viper.BindPFlag("ui.default-apps", cmd.Flags().Lookup("default-apps"))
+	viper.BindPFlag("ui.default-scheduler", cmd.Flags().Lookup("default-scheduler"))
+	viper.BindPFlag("ui.status-webhook.url", cmd.Flags().Lookup("status-webhook.url"))
+	viper.BindPFlag("ui.status-webhook.secret", cmd.Flags().Lookup("status-webhook.secret"))
+	viper.BindPFlag("ui.status-webhook.interval", cmd.Flags().Lookup("status-webhook.interval"))
+	viper.BindPFlag("ui.status-webhook.retries", cmd.Flags().Lookup("status-webhook.retries"))
 
 	viper.BindEnv("ui.listen-endpoint")
 	viper.BindEnv("ui.unix-socket-endpoint")
@@ -148,6 +177,21 @@ func newUICmd() *cobra.Command {
 	viper.BindEnv("ui.features")
 	viper.BindEnv("ui.minimega-path")
 	viper.BindEnv("ui.minimega-console")
+	viper.BindEnv("ui.approvals.window")
+	viper.BindEnv("ui.approvals.operations")
+	viper.BindEnv("ui.callback-endpoint")
+	viper.BindEnv("ui.guest-ip-discovery")
+	viper.BindEnv("ui.metrics")
+	viper.BindEnv("ui.scheduled-starts")
+	viper.BindEnv("ui.image-preload")
+	viper.BindEnv("ui.smoke-tests")
+	viper.BindEnv("ui.retention-policies")
+	viper.BindEnv("ui.default-apps")
+	viper.BindEnv("ui.default-scheduler")
+	viper.BindEnv("ui.status-webhook.url")
+	viper.BindEnv("ui.status-webhook.secret")
+	viper.BindEnv("ui.status-webhook.interval")
+	viper.BindEnv("ui.status-webhook.retries")
 
 	cmd.Flags().Bool("log-requests", false, "Log API requests")
 	cmd.Flags().Bool("log-full", false, "Log API requests and responses")
@@ -163,6 +207,89 @@ func newUICmd() *cobra.Command {
 	return cmd
 }
 
+// uiServerOptions returns the UI server options for the current
+// configuration. It's called again each time the server configuration is
+// reloaded, so it shouldn't have side effects.
+func uiServerOptions(cmd *cobra.Command) ([]web.ServerOption, error) {
+	opts := []web.ServerOption{
+		web.ServeOnEndpoint(viper.GetString("ui.listen-endpoint")),
+		web.ServeBasePath(viper.GetString("ui.base-path")),
+		web.ServeWithJWTKey(viper.GetString("ui.jwt-signing-key")),
+		web.ServeWithJWTLifetime(viper.GetDuration("ui.jwt-lifetime")),
+		web.ServeWithUsers(viper.GetStringSlice("ui.users")),
+		web.ServeWithTLS(viper.GetString("ui.tls-key"), viper.GetString("ui.tls-cert")),
+		web.ServeMinimegaLogs(viper.GetString("ui.logs.minimega-path")),
+		web.ServeWithFeatures(viper.GetStringSlice("ui.features")),
+		web.ServeWithProxyAuthHeader(viper.GetString("ui.proxy-auth-header")),
+		web.ServeWithUnixSocketGid(viper.GetInt("unix-socket-gid")),
+		web.ServeWithLogLevel(viper.GetString("log.level")),
+		web.ServeWithDefaultApps(viper.GetStringSlice("ui.default-apps")),
+		web.ServeWithDefaultScheduler(viper.GetString("ui.default-scheduler")),
+		web.ServeWithGuestIPDiscovery(viper.GetDuration("ui.guest-ip-discovery")),
+		web.ServeWithMetrics(viper.GetBool("ui.metrics")),
+		web.ServeWithScheduledStarts(viper.GetDuration("ui.scheduled-starts"), viper.GetDuration("ui.image-preload")),
+	}
+
+	if viper.GetString("ui.minimega-path") != "" || viper.GetBool("ui.minimega-console") {
+		opts = append(opts, web.ServeMinimegaConsole(true))
+	}
+
+	if u := viper.GetString("ui.status-webhook.url"); u != "" {
+		opts = append(opts, web.ServeWithStatusWebhook(
+			webhook.WithURL(u),
+			webhook.WithSecret(viper.GetString("ui.status-webhook.secret")),
+			webhook.WithInterval(viper.GetDuration("ui.status-webhook.interval")),
+			webhook.WithRetries(viper.GetInt("ui.status-webhook.retries")),
+		))
+	}
+
+	if path := viper.GetString("ui.smoke-tests"); path != "" {
+		tests, err := smoke.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading smoke tests: %w", err)
+		}
+
+		opts = append(opts, web.ServeWithSmokeTests(tests))
+	}
+
+	if path := viper.GetString("ui.retention-policies"); path != "" {
+		policies, err := retention.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading retention policies: %w", err)
+		}
+
+		opts = append(opts, web.ServeWithRetention(policies))
+	}
+
+	if endpoint := viper.GetString("ui.callback-endpoint"); endpoint != "" {
+		opts = append(opts, web.ServeWithCallbackEndpoint(endpoint))
+	}
+
+	if window := viper.GetDuration("ui.approvals.window"); window > 0 {
+		opts = append(opts, web.ServeWithApprovals(window, viper.GetStringSlice("ui.approvals.operations")...))
+	}
+
+	if MustGetBool(cmd.Flags(), "log-requests") {
+		opts = append(opts, web.ServeWithMiddlewareLogging("requests"))
+	}
+
+	if MustGetBool(cmd.Flags(), "log-full") {
+		opts = append(opts, web.ServeWithMiddlewareLogging("full"))
+	}
+
+	if MustGetBool(cmd.Flags(), "unbundled") {
+		opts = append(opts, web.ServeUnbundled())
+	}
+
+	return opts, nil
+}
+
 func init() {
 	rootCmd.AddCommand(newUICmd())
+
+	onUsersConfigChange = func() {
+		web.ConfigureUsers(viper.GetStringSlice("ui.users"))
+	}
+
+	subsystem.Register(subsystem.UI)
 }
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"phenix/api/usage"
+	"phenix/util"
+	"phenix/util/printer"
+
+	"github.com/spf13/cobra"
+)
+
+func newUsageCmd() *cobra.Command {
+	desc := `Display a resource usage report
+
+  Reports vCPU-hours, memory GB-hours, and disk GB-hours consumed by running
+  experiments, grouped by experiment owner or namespace. By default, the report
+  covers the current calendar month. Use --month to report on a different
+  calendar month or --window to report on a rolling window ending now.`
+
+	example := `
+  phenix usage --month 2024-03
+  phenix usage --window 720h --group-by namespace
+  phenix usage --user alice`
+
+	cmd := &cobra.Command{
+		Use:     "usage",
+		Short:   "Display a resource usage report",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				month  = MustGetString(cmd.Flags(), "month")
+				window = MustGetDuration(cmd.Flags(), "window")
+			)
+
+			if month != "" && window != 0 {
+				return fmt.Errorf("only one of --month or --window can be provided")
+			}
+
+			opts := []usage.ReportOption{
+				usage.ReportGroupBy(usage.GroupBy(MustGetString(cmd.Flags(), "group-by"))),
+				usage.ReportForUser(MustGetString(cmd.Flags(), "user")),
+				usage.ReportForNamespace(MustGetString(cmd.Flags(), "namespace")),
+			}
+
+			if month != "" {
+				t, err := time.ParseInLocation("2006-01", month, time.Local)
+				if err != nil {
+					err := util.HumanizeError(err, "Invalid month %s provided (expected YYYY-MM)", month)
+					return err.Humanized()
+				}
+
+				opts = append(opts, usage.ReportForMonth(t))
+			} else if window != 0 {
+				opts = append(opts, usage.ReportForWindow(window))
+			}
+
+			report, err := usage.Generate(opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to generate usage report")
+				return err.Humanized()
+			}
+
+			fmt.Printf("\nUsage from %s to %s\n\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+
+			if len(report.Entries) == 0 {
+				fmt.Printf("There is no recorded usage for this period\n\n")
+			} else {
+				printer.PrintTableOfUsage(os.Stdout, report)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("month", "", "Calendar month to report on (YYYY-MM)")
+	cmd.Flags().Duration("window", 0, "Rolling window ending now to report on (ie. 720h)")
+	cmd.Flags().String("group-by", "user", "Group usage by user or namespace")
+	cmd.Flags().String("user", "", "Only include usage for experiments owned by the given user")
+	cmd.Flags().String("namespace", "", "Only include usage for the given namespace")
+
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(newUsageCmd())
+}
@@ -1,17 +1,26 @@
 package cmd
 
 import (
+	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"phenix/api/experiment"
+	"phenix/api/webhook"
+	"phenix/app"
 	"phenix/util"
+	"phenix/util/sigterm"
 	"phenix/web/rbac"
 
+	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newUtilCmd() *cobra.Command {
@@ -117,11 +126,110 @@ func newUtilRoleTableCmd() *cobra.Command {
 	return cmd
 }
 
+func newUtilWebhookDeadLettersCmd() *cobra.Command {
+	desc := `Print or redeliver undelivered experiment status documents
+
+  Experiment status documents that couldn't be posted to the external API
+  configured with 'phenix ui --status-webhook.url' are kept in a dead-letter
+  queue. Use --redeliver to post them again once the external API is
+  available. The webhook URL and secret configured for the UI are used unless
+  --url is provided.`
+
+	cmd := &cobra.Command{
+		Use:   "webhook-dead-letters",
+		Short: "Print or redeliver undelivered experiment status documents",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if MustGetBool(cmd.Flags(), "redeliver") {
+				u := MustGetString(cmd.Flags(), "url")
+				if u == "" {
+					u = viper.GetString("ui.status-webhook.url")
+				}
+
+				opts := []webhook.Option{
+					webhook.WithURL(u),
+					webhook.WithSecret(viper.GetString("ui.status-webhook.secret")),
+				}
+
+				delivered, err := webhook.Redeliver(sigterm.CancelContext(context.Background()), opts...)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to redeliver experiment status documents")
+					return err.Humanized()
+				}
+
+				fmt.Printf("%d experiment status documents redelivered\n", delivered)
+			}
+
+			letters, err := webhook.DeadLetters()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to read experiment status dead-letter queue")
+				return err.Humanized()
+			}
+
+			if len(letters) == 0 {
+				fmt.Println("\nThere are no undelivered experiment status documents")
+				return nil
+			}
+
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"Experiment", "State", "Generated", "Attempts", "Failed", "Error"})
+
+			for _, l := range letters {
+				table.Append([]string{
+					l.Document.Experiment.Name,
+					l.Document.Experiment.State,
+					l.Document.Timestamp.Format(time.RFC3339),
+					strconv.Itoa(l.Attempts),
+					l.FailedAt.Format(time.RFC3339),
+					l.Error,
+				})
+			}
+
+			table.Render()
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("redeliver", false, "Post undelivered experiment status documents again")
+	cmd.Flags().String("url", "", "URL of external API to redeliver to (defaults to UI status webhook URL)")
+
+	return cmd
+}
+
+// printAppProgress renders progress events emitted while applying apps for an
+// experiment to STDOUT in color.
+func printAppProgress(e app.ProgressEvent) {
+	name := fmt.Sprintf("'%s' %s app (%s)", e.App, e.Kind, e.Stage)
+
+	switch e.Status {
+	case "success":
+		color.New(color.FgBlue).Printf("[✓] %s\n", name)
+	case "error":
+		if errors.Is(e.Error, app.ErrUserAppNotFound) {
+			color.New(color.FgYellow).Printf("[?] %s\n", name)
+		} else {
+			color.New(color.FgRed).Printf("[✗] %s: %v\n", name, e.Error)
+		}
+	case "skipped":
+		color.New(color.FgYellow).Printf("[-] %s %s\n", name, e.Message)
+	case "retrying":
+		color.New(color.FgYellow).Printf("[↻] %s %s: %v\n", name, e.Message, errors.Unwrap(e.Error))
+	case "progress":
+		if e.Progress.Percent < 0 {
+			fmt.Printf("[…] %s: %s\n", name, e.Progress.Message)
+		} else {
+			fmt.Printf("[…] %s %d%%: %s\n", name, e.Progress.Percent, e.Progress.Message)
+		}
+	}
+}
+
 func init() {
 	utilCmd := newUtilCmd()
 
 	utilCmd.AddCommand(newUtilAppJsonCmd())
 	utilCmd.AddCommand(newUtilRoleTableCmd())
+	utilCmd.AddCommand(newUtilWebhookDeadLettersCmd())
 
 	rootCmd.AddCommand(utilCmd)
 }
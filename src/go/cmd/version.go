@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"phenix/util/subsystem"
 	"phenix/version"
 
 	"github.com/spf13/cobra"
@@ -14,6 +15,21 @@ func newVersionCmd() *cobra.Command {
 		Short: "print version information",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Printf("%s (commit %s) %s\n", version.Tag, version.Commit, version.Date)
+
+			fmt.Println("subsystems:")
+
+			for _, s := range subsystem.List() {
+				status := "enabled"
+
+				if !s.Compiled {
+					status = "not compiled"
+				} else if s.Disabled {
+					status = "disabled"
+				}
+
+				fmt.Printf("  %-8s %s\n", s.Name, status)
+			}
+
 			return nil
 		},
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"phenix/api/vlan"
 	"phenix/util"
@@ -141,11 +142,58 @@ func newVlanRangeCmd() *cobra.Command {
 	return cmd
 }
 
+func newVlanStatsCmd() *cobra.Command {
+	desc := `View VLAN and tap statistics for an experiment
+
+  Used to view the byte, packet, and drop counters OVS tracks for the taps of
+  each running VM in an experiment, grouped by VLAN. The counters are sampled
+  twice, --interval apart, to compute per second rates. For taps, RX is
+  traffic received from the VM and TX is traffic sent to the VM.`
+
+	cmd := &cobra.Command{
+		Use:   "stats <experiment name>",
+		Short: "View VLAN and tap statistics for an experiment",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Must provide an experiment name")
+			}
+
+			var (
+				exp         = args[0]
+				interval, _ = cmd.Flags().GetDuration("interval")
+			)
+
+			if _, err := vlan.Stats(vlan.Experiment(exp)); err != nil {
+				err := util.HumanizeError(err, "Unable to get VLAN stats for the "+exp+" experiment")
+				return err.Humanized()
+			}
+
+			time.Sleep(interval)
+
+			stats, err := vlan.Stats(vlan.Experiment(exp))
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get VLAN stats for the "+exp+" experiment")
+				return err.Humanized()
+			}
+
+			printer.PrintTableOfVLANStats(os.Stdout, stats)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("interval", 2*time.Second, "Time between samples used to compute rates")
+
+	return cmd
+}
+
 func init() {
 	vlanCmd := newVlanCmd()
 
 	vlanCmd.AddCommand(newVlanAliasCmd())
 	vlanCmd.AddCommand(newVlanRangeCmd())
+	vlanCmd.AddCommand(newVlanStatsCmd())
 
 	rootCmd.AddCommand(vlanCmd)
 }
@@ -1,15 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/user"
 	"regexp"
 	"strconv"
+	"time"
 
+	"phenix/api/experiment"
 	"phenix/api/vm"
 	"phenix/util"
+	"phenix/util/audit"
 	"phenix/util/mm"
+	"phenix/util/plog"
 	"phenix/util/printer"
+	"phenix/util/sigterm"
 
 	"github.com/spf13/cobra"
 )
@@ -30,7 +37,10 @@ func newVMInfoCmd() *cobra.Command {
 	desc := `Table of virtual machine(s)
 	
   Used to display a table of virtual machine(s) for a specific experiment; 
-  virtual machine name is optional, when included will display only that VM.`
+  virtual machine name is optional, when included will display only that VM.
+  For running experiments, guest IPs are first discovered from cc agents, DHCP
+  leases, and cluster host ARP tables so VMs using DHCP (or reconfigured after
+  boot) show their actual addresses.`
 
 	cmd := &cobra.Command{
 		Use:   "info <experiment name> <vm name>",
@@ -41,6 +51,12 @@ func newVMInfoCmd() *cobra.Command {
 				return fmt.Errorf("Must provide an experiment name")
 			}
 
+			if MustGetBool(cmd.Flags(), "discover-ips") && experiment.Running(args[0]) {
+				if err := mm.DiscoverGuestIPs(args[0]); err != nil {
+					plog.Warn("discovering guest IPs", "exp", args[0], "err", err)
+				}
+			}
+
 			switch len(args) {
 			case 1:
 				vms, err := vm.List(args[0])
@@ -66,6 +82,8 @@ func newVMInfoCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool("discover-ips", true, "Discover guest IPs of running VMs before displaying them")
+
 	return cmd
 }
 
@@ -320,6 +338,112 @@ func newVMKillCmd() *cobra.Command {
 	return cmd
 }
 
+func newVMBroadcastCmd() *cobra.Command {
+	desc := `Broadcast keystrokes or a command to a group of VMs
+
+  Used to type the same keystrokes into the consoles of, or execute the same
+  command via cc in, a group of running VMs at once (ie. to reset all student
+  workstations in a class). The group is made up of the VMs named with --vms
+  and the VMs whose topology labels match all the --label flags provided.
+  Exactly one of --keys or --command (and/or --file) must be provided. Newlines
+  in --keys are typed as the Return key. Commands and files are dispatched to
+  the group at a limited rate, with a limited number in flight per cluster host
+  (see --cc.dispatch-rate and --cc.host-concurrency), and the command returns
+  once every VM has responded. Commands still queued when the command is
+  interrupted are canceled.`
+
+	example := `
+  phenix vm broadcast myexp --label role=student --keys $'reset-workstation\n'
+  phenix vm broadcast myexp --vms ws-1,ws-2 --command 'shutdown -r now'
+  phenix vm broadcast myexp --label role=student --file setup.sh --command 'bash /tmp/miniccc/files/setup.sh'`
+
+	cmd := &cobra.Command{
+		Use:     "broadcast <experiment name>",
+		Short:   "Broadcast keystrokes or a command to a group of VMs",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("Must provide an experiment name")
+			}
+
+			var (
+				expName   = args[0]
+				vms, _    = cmd.Flags().GetStringSlice("vms")
+				labels, _ = cmd.Flags().GetStringToString("label")
+				keys      = MustGetString(cmd.Flags(), "keys")
+				command   = MustGetString(cmd.Flags(), "command")
+				file      = MustGetString(cmd.Flags(), "file")
+				opts      = []vm.BroadcastOption{vm.BroadcastToVMs(vms...), vm.BroadcastKeys(keys), vm.BroadcastCommand(command), vm.BroadcastFile(file)}
+				details   = map[string]any{"vms": vms, "labels": labels}
+				username  = "unknown"
+			)
+
+			for k, v := range labels {
+				opts = append(opts, vm.BroadcastToLabel(k, v))
+			}
+
+			if command != "" || file != "" {
+				details["command"] = command
+				details["file"] = file
+			} else {
+				details["keys"] = keys
+			}
+
+			if u, err := user.Current(); err == nil {
+				username = u.Username
+			}
+
+			audit.Record(audit.Event{
+				User:     username,
+				Action:   "vms/broadcast",
+				Resource: "experiments/" + expName,
+				Details:  details,
+			})
+
+			ctx := sigterm.CancelContext(context.Background())
+
+			results, batch, err := vm.Broadcast(ctx, expName, opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to broadcast to VM group")
+				return err.Humanized()
+			}
+
+			if batch != nil {
+				if _, err := batch.Wait(ctx); err != nil {
+					fmt.Println("Broadcast interrupted, canceling commands not yet completed")
+					<-batch.Done()
+				}
+
+				for i, r := range batch.Results() {
+					results[i].Error = r.Error
+				}
+			}
+
+			var failed int
+
+			for _, result := range results {
+				if result.Error != "" {
+					fmt.Printf("%s: %s\n", result.VM, result.Error)
+					failed++
+				}
+			}
+
+			fmt.Printf("Broadcast to %d of %d VMs in the %s experiment\n", len(results)-failed, len(results), expName)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSlice("vms", nil, "Comma separated list of VMs to include in the group")
+	cmd.Flags().StringToString("label", nil, "Include VMs with the given topology label in the group (ie. role=student)")
+	cmd.Flags().String("keys", "", "Keystrokes to type into each VM console")
+	cmd.Flags().String("command", "", "Command to execute via cc in each VM")
+	cmd.Flags().String("file", "", "File (relative to the minimega files directory) to send via cc to each VM before executing --command")
+
+	return cmd
+}
+
 func newVMSetCmd() *cobra.Command {
 	desc := `Set configuration value for a VM
 	
@@ -640,6 +764,156 @@ func newVMCaptureCmd() *cobra.Command {
 	return cmd
 }
 
+func newVMRestorePointCmd() *cobra.Command {
+	desc := `Manage named restore points for a VM
+
+  Restore points are named snapshots of a running VM (ie. "pre-malware",
+  "post-patch"). Each restore point's parent is the restore point the VM was
+  last created from or restored to, so restore points form a tree per VM.`
+
+	cmd := &cobra.Command{
+		Use:   "restore-point",
+		Short: "Manage named restore points for a VM",
+		Long:  desc,
+	}
+
+	create := &cobra.Command{
+		Use:   "create <experiment name> <vm name> <restore point name>",
+		Short: "Create a named restore point for a running VM",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				expName = args[0]
+				vmName  = args[1]
+				name    = args[2]
+			)
+
+			if err := vm.CreateRestorePoint(expName, vmName, name, nil); err != nil {
+				err := util.HumanizeError(err, "Unable to create the "+name+" restore point for the "+vmName+" VM")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Restore point %s was created for the %s VM in the %s experiment\n", name, vmName, expName)
+
+			return nil
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list <experiment name> <vm name>",
+		Short: "Show the restore point tree for a VM",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			points, err := vm.RestorePoints(args[0], args[1])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get restore points for the "+args[1]+" VM")
+				return err.Humanized()
+			}
+
+			if len(points) == 0 {
+				fmt.Printf("\nThere are no restore points for the %s VM\n\n", args[1])
+				return nil
+			}
+
+			var printPoints func([]*vm.RestorePoint, string)
+
+			printPoints = func(points []*vm.RestorePoint, indent string) {
+				for _, p := range points {
+					var (
+						created string
+						current string
+					)
+
+					if !p.Created.IsZero() {
+						created = " (" + p.Created.Local().Format(time.RFC3339) + ")"
+					}
+
+					if p.Current {
+						current = " *"
+					}
+
+					fmt.Printf("%s%s%s%s\n", indent, p.Name, created, current)
+
+					printPoints(p.Children, indent+"  ")
+				}
+			}
+
+			printPoints(points, "")
+
+			return nil
+		},
+	}
+
+	restore := &cobra.Command{
+		Use:   "restore <experiment name> <vm name> <restore point name>",
+		Short: "Restore a running VM to a named restore point",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vm.RestoreToPoint(args[0], args[1], args[2]); err != nil {
+				err := util.HumanizeError(err, "Unable to restore the "+args[1]+" VM to the "+args[2]+" restore point")
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s VM in the %s experiment was restored to %s\n", args[1], args[0], args[2])
+
+			return nil
+		},
+	}
+
+	del := &cobra.Command{
+		Use:   "delete <experiment name> <vm name> <restore point name>",
+		Short: "Delete a named restore point",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := vm.DeleteRestorePoint(args[0], args[1], args[2]); err != nil {
+				err := util.HumanizeError(err, "Unable to delete the "+args[2]+" restore point")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Restore point %s was deleted for the %s VM in the %s experiment\n", args[2], args[1], args[0])
+
+			return nil
+		},
+	}
+
+	policy := &cobra.Command{
+		Use:   "policy <experiment name> <vm name>",
+		Short: "Set the automatic cleanup policy for a VM's restore points",
+		Long: `Set the automatic cleanup policy for a VM's restore points
+
+  The policy is applied each time a restore point is created for the VM.
+  Restore points older than --max-age are deleted, then the oldest restore
+  points are deleted until at most --max-points remain. The VM's current
+  restore point is never deleted. Zero values disable the cleanup.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxAge, _ := cmd.Flags().GetDuration("max-age")
+
+			policy := vm.RestorePolicy{MaxPoints: MustGetInt(cmd.Flags(), "max-points"), MaxAge: maxAge}
+
+			if err := vm.SetRestorePolicy(args[0], args[1], policy); err != nil {
+				err := util.HumanizeError(err, "Unable to set the restore point policy for the "+args[1]+" VM")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Restore point policy was set for the %s VM in the %s experiment\n", args[1], args[0])
+
+			return nil
+		},
+	}
+
+	policy.Flags().Int("max-points", 0, "Maximum number of restore points to keep (0 for no limit)")
+	policy.Flags().Duration("max-age", 0, "Maximum age of restore points to keep (0 for no limit)")
+
+	cmd.AddCommand(create)
+	cmd.AddCommand(list)
+	cmd.AddCommand(restore)
+	cmd.AddCommand(del)
+	cmd.AddCommand(policy)
+
+	return cmd
+}
+
 func newVMMemorySnapshotCmd() *cobra.Command {
 	desc := `Create an ELF memory snapshot of the VM
 	
@@ -682,6 +956,94 @@ func newVMMemorySnapshotCmd() *cobra.Command {
 	return cmd
 }
 
+func newVMDriftCmd() *cobra.Command {
+	desc := `Detect drift of a VM from its source image
+
+  A golden state is recorded per disk image from a VM freshly booted from the
+  image, capturing hashes of files under key paths and the installed packages
+  via cc. Running VMs using the image can then be checked against the golden
+  state, ie. to verify machines were reset between exercise runs or to spot
+  unintended persistence.`
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Detect drift of a VM from its source image",
+		Long:  desc,
+	}
+
+	golden := &cobra.Command{
+		Use:   "golden <experiment name> <vm name>",
+		Short: "Record the golden state for a VM's source image",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, _ := cmd.Flags().GetStringSlice("path")
+
+			state, err := vm.RecordGoldenState(sigterm.CancelContext(context.Background()), args[0], args[1], paths)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to record golden state from the "+args[1]+" VM")
+				return err.Humanized()
+			}
+
+			fmt.Printf("Golden state for image %s recorded from the %s VM (%d files, %d packages)\n", state.Image, args[1], len(state.Files), len(state.Packages))
+
+			return nil
+		},
+	}
+
+	golden.Flags().StringSlice("path", nil, "Guest path to hash (can be specified multiple times; defaults to common persistence locations)")
+
+	check := &cobra.Command{
+		Use:   "check <experiment name> <vm name>",
+		Short: "Check a running VM for drift from its source image",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			drift, err := vm.CheckDrift(sigterm.CancelContext(context.Background()), args[0], args[1])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to check the "+args[1]+" VM for drift")
+				return err.Humanized()
+			}
+
+			if !drift.Drifted() {
+				fmt.Printf("No drift detected for the %s VM from image %s\n", args[1], drift.Image)
+				return nil
+			}
+
+			fmt.Printf("Drift detected for the %s VM from image %s:\n", args[1], drift.Image)
+
+			sections := []struct {
+				name  string
+				items []string
+			}{
+				{"Files added", drift.FilesAdded},
+				{"Files removed", drift.FilesRemoved},
+				{"Files modified", drift.FilesModified},
+				{"Packages added", drift.PackagesAdded},
+				{"Packages removed", drift.PackagesRemoved},
+				{"Packages changed", drift.PackagesChanged},
+			}
+
+			for _, s := range sections {
+				if len(s.items) == 0 {
+					continue
+				}
+
+				fmt.Printf("  %s:\n", s.name)
+
+				for _, item := range s.items {
+					fmt.Printf("    %s\n", item)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.AddCommand(golden)
+	cmd.AddCommand(check)
+
+	return cmd
+}
+
 func init() {
 	vmCmd := newVMCmd()
 
@@ -693,10 +1055,13 @@ func init() {
 	vmCmd.AddCommand(newVMRedeployCmd())
 	vmCmd.AddCommand(newVMShutdownCmd())
 	vmCmd.AddCommand(newVMKillCmd())
+	vmCmd.AddCommand(newVMBroadcastCmd())
 	vmCmd.AddCommand(newVMSetCmd())
 	vmCmd.AddCommand(newVMNetCmd())
 	vmCmd.AddCommand(newVMCaptureCmd())
 	vmCmd.AddCommand(newVMMemorySnapshotCmd())
+	vmCmd.AddCommand(newVMRestorePointCmd())
+	vmCmd.AddCommand(newVMDriftCmd())
 
 	rootCmd.AddCommand(vmCmd)
 }
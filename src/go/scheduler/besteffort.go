@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// PRIORITY_ANNOTATION is the node annotation used to set a VM's launch priority
+// for best-effort starts. VMs with higher priorities are placed first when not
+// every VM fits on the cluster. VMs default to a priority of 0.
+const PRIORITY_ANNOTATION = "phenix/priority"
+
+// Priority returns the best-effort launch priority of the given node.
+func Priority(node ifaces.NodeSpec) int {
+	val, ok := node.GetAnnotation(PRIORITY_ANNOTATION)
+	if !ok {
+		return 0
+	}
+
+	switch val := val.(type) {
+	case int:
+		return val
+	case float64:
+		return int(val)
+	case string:
+		if p, err := strconv.Atoi(val); err == nil {
+			return p
+		}
+	}
+
+	plog.Warn("invalid node priority annotation", "vm", node.General().Hostname(), "priority", fmt.Sprint(val))
+
+	return 0
+}
+
+// BestEffort determines which of the given nodes fit on the given cluster
+// hosts, based on the memory not yet committed on each host, and returns the
+// names of the nodes that don't fit. Nodes are placed in priority order
+// (highest first, then in the order given). A node that doesn't fit on the
+// host it's scheduled on is moved to the host with the most uncommitted memory
+// that has enough (and has the node's required capabilities), updating the
+// given schedules. Hosts whose memory is unknown are assumed to have room.
+func BestEffort(nodes []ifaces.NodeSpec, schedules map[string]string, cluster mm.Hosts) []string {
+	free := make(map[string]int)
+
+	for _, host := range cluster {
+		if host.MemTotal > 0 {
+			free[host.Name] = host.MemTotal - host.MemCommit
+		}
+	}
+
+	var candidates []ifaces.NodeSpec
+
+	for _, node := range nodes {
+		if !node.External() {
+			candidates = append(candidates, node)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return Priority(candidates[i]) > Priority(candidates[j])
+	})
+
+	var deferred []string
+
+	for _, node := range candidates {
+		var (
+			name    = node.General().Hostname()
+			mem     = node.Hardware().Memory()
+			current = schedules[name]
+		)
+
+		if avail, known := free[current]; current != "" && (!known || avail >= mem) {
+			if known {
+				free[current] -= mem
+			}
+
+			continue
+		}
+
+		var (
+			required = RequiredCapabilities(node)
+			best     string
+		)
+
+		for _, host := range cluster {
+			avail, known := free[host.Name]
+			if !known || avail < mem {
+				continue
+			}
+
+			if len(required) > 0 && (host.Capabilities == nil || len(host.Capabilities.Missing(required...)) > 0) {
+				continue
+			}
+
+			if best == "" || avail > free[best] {
+				best = host.Name
+			}
+		}
+
+		if best == "" {
+			deferred = append(deferred, name)
+			continue
+		}
+
+		if current != "" {
+			plog.Info("moving VM to host with capacity for best-effort start", "vm", name, "from", current, "to", best)
+		}
+
+		free[best] -= mem
+		schedules[name] = best
+	}
+
+	return deferred
+}
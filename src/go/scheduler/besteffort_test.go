@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"testing"
+
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+	"phenix/util/mm"
+)
+
+func TestBestEffort(t *testing.T) {
+	newNode := func(name string, mem int, priority any) ifaces.NodeSpec {
+		node := &v1.Node{
+			TypeF:     "VirtualMachine",
+			GeneralF:  &v1.General{HostnameF: name},
+			HardwareF: &v1.Hardware{VCPUF: 1, MemoryF: mem},
+		}
+
+		if priority != nil {
+			node.AnnotationsF = map[string]interface{}{PRIORITY_ANNOTATION: priority}
+		}
+
+		return node
+	}
+
+	nodes := []ifaces.NodeSpec{
+		newNode("low", 2048, nil),
+		newNode("high", 4096, "10"),
+		newNode("mid", 2048, 5),
+		newNode("moved", 1024, float64(5)),
+	}
+
+	schedules := map[string]string{
+		"low":   "compute0",
+		"high":  "compute0",
+		"mid":   "compute0",
+		"moved": "compute0",
+	}
+
+	hosts := mm.Hosts(
+		[]mm.Host{
+			{Name: "compute0", MemTotal: 8192, MemCommit: 2048},
+			{Name: "compute1", MemTotal: 2048, MemCommit: 1024},
+		},
+	)
+
+	deferred := BestEffort(nodes, schedules, hosts)
+
+	if len(deferred) != 1 || deferred[0] != "low" {
+		t.Logf("expected only low to be deferred, got %v", deferred)
+		t.FailNow()
+	}
+
+	// high and mid (placed first) fill compute0, so moved goes to compute1 and
+	// there's no room left for low.
+	expected := map[string]string{
+		"low":   "compute0",
+		"high":  "compute0",
+		"mid":   "compute0",
+		"moved": "compute1",
+	}
+
+	for vm, host := range expected {
+		if schedules[vm] != host {
+			t.Logf("expected %s -> %s, got %s -> %s", vm, host, vm, schedules[vm])
+			t.FailNow()
+		}
+	}
+}
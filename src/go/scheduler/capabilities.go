@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+// HOST_REQUIRES_ANNOTATION is the node annotation used to list the host
+// capability labels a VM requires (ie. `kvm`, `arch:x86_64`, `cpu:avx2`,
+// `hugepages`, or `sriov`). It can be a list of labels or a comma-separated
+// string of labels.
+const HOST_REQUIRES_ANNOTATION = "phenix/host-requires"
+
+// RequiredCapabilities returns the host capability labels required by the
+// given node.
+func RequiredCapabilities(node ifaces.NodeSpec) []string {
+	val, ok := node.GetAnnotation(HOST_REQUIRES_ANNOTATION)
+	if !ok {
+		return nil
+	}
+
+	var labels []string
+
+	switch val := val.(type) {
+	case string:
+		for _, label := range strings.Split(val, ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				labels = append(labels, label)
+			}
+		}
+	case []interface{}:
+		for _, label := range val {
+			if label, ok := label.(string); ok && label != "" {
+				labels = append(labels, strings.TrimSpace(label))
+			}
+		}
+	case []string:
+		labels = val
+	}
+
+	return labels
+}
+
+// enforceCapabilities ensures every VM requiring host capabilities is scheduled
+// on a host that has them, moving VMs placed by the scheduling algorithm to the
+// capable host with the fewest VMs if necessary. VMs manually scheduled on a
+// host missing required capabilities result in an error. Hosts whose
+// capabilities are unknown are assumed to be capable.
+func enforceCapabilities(spec ifaces.ExperimentSpec, manual map[string]struct{}) error {
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if len(RequiredCapabilities(node)) > 0 {
+			nodes = append(nodes, node)
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	cluster, err := mm.GetClusterHosts(true)
+	if err != nil {
+		return fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	vms := make(map[string]int)
+
+	for _, host := range spec.Schedules() {
+		vms[host]++
+	}
+
+	for _, node := range nodes {
+		var (
+			name     = node.General().Hostname()
+			required = RequiredCapabilities(node)
+			current  = spec.Schedules()[name]
+		)
+
+		if host := cluster.FindHostByName(current); host != nil {
+			if host.Capabilities == nil {
+				plog.Warn("capabilities unknown for scheduled host", "vm", name, "host", current)
+				continue
+			}
+
+			missing := host.Capabilities.Missing(required...)
+			if len(missing) == 0 {
+				continue
+			}
+
+			if _, ok := manual[name]; ok {
+				return fmt.Errorf("VM %s manually scheduled on host %s, which is missing required capabilities %s", name, current, strings.Join(missing, ", "))
+			}
+		}
+
+		var capable string
+
+		for _, host := range cluster {
+			if host.Capabilities == nil || len(host.Capabilities.Missing(required...)) > 0 {
+				continue
+			}
+
+			if capable == "" || vms[host.Name] < vms[capable] {
+				capable = host.Name
+			}
+		}
+
+		if capable == "" {
+			return fmt.Errorf("no cluster host has the capabilities required by VM %s (%s)", name, strings.Join(required, ", "))
+		}
+
+		plog.Info("rescheduling VM to host with required capabilities", "vm", name, "from", current, "to", capable)
+
+		vms[current]--
+		vms[capable]++
+
+		spec.Schedules()[name] = capable
+	}
+
+	return nil
+}
@@ -10,6 +10,15 @@ Default Schedulers
   * subnet-compute.go:     assigns experiment VMs to cluster nodes based on
                            interface VLAN assignments
 
+Best-Effort Placement
+
+When an experiment is started with the best-effort option, VMs are checked
+against the memory not yet committed on each cluster node after scheduling
+(see besteffort.go). VMs that don't fit where they're scheduled are moved to
+another node if one has room, otherwise they're deferred and launched later as
+capacity becomes available. VMs are placed in priority order, highest first,
+using the `phenix/priority` node annotation (default 0).
+
 Custom User Schedulers
 
 Custom user schedulers are interacted with through STDIN and STDOUT. The
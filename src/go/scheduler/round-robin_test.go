@@ -208,3 +208,82 @@ func TestRoundRobinSchedulerSomePrescheduled(t *testing.T) {
 		}
 	}
 }
+
+func TestRoundRobinSchedulerCapabilities(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		TopologyF: &v1.TopologySpec{
+			NodesF: []*v1.Node{
+				{
+					TypeF:        "VirtualMachine",
+					AnnotationsF: map[string]interface{}{HOST_REQUIRES_ANNOTATION: "kvm, cpu:avx2"},
+					GeneralF:     &v1.General{HostnameF: "foo"},
+					HardwareF:    &v1.Hardware{VCPUF: 1, MemoryF: 512},
+				},
+				{
+					TypeF:        "VirtualMachine",
+					AnnotationsF: map[string]interface{}{HOST_REQUIRES_ANNOTATION: []interface{}{"arch:aarch64"}},
+					GeneralF:     &v1.General{HostnameF: "bar"},
+					HardwareF:    &v1.Hardware{VCPUF: 1, MemoryF: 512},
+				},
+				{
+					TypeF:     "VirtualMachine",
+					GeneralF:  &v1.General{HostnameF: "baz"},
+					HardwareF: &v1.Hardware{VCPUF: 1, MemoryF: 512},
+				},
+			},
+		},
+		SchedulesF: make(map[string]string),
+	}
+
+	hosts := mm.Hosts(
+		[]mm.Host{
+			{
+				Name:         "compute0",
+				Capabilities: &mm.HostCapabilities{Arch: "x86_64"},
+			},
+			{
+				Name:         "compute1",
+				Capabilities: &mm.HostCapabilities{Arch: "aarch64", KVM: true},
+			},
+			{
+				Name:         "compute2",
+				Capabilities: &mm.HostCapabilities{Arch: "x86_64", KVM: true, CPUFlags: []string{"avx2"}},
+			},
+		},
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+	m.EXPECT().GetClusterHosts(true).Return(hosts, nil).Times(2)
+
+	mm.DefaultMM = m
+
+	if err := Schedule("round-robin", spec); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string]string{
+		"foo": "compute2",
+		"bar": "compute1",
+		"baz": "compute2",
+	}
+
+	for vm, host := range expected {
+		if spec.SchedulesF[vm] != host {
+			t.Logf("expected %s -> %s, got %s", vm, host, spec.SchedulesF[vm])
+			t.FailNow()
+		}
+	}
+
+	spec.SchedulesF = map[string]string{"bar": "compute0"}
+
+	m.EXPECT().GetClusterHosts(true).Return(hosts, nil).Times(2)
+
+	if err := Schedule("round-robin", spec); err == nil {
+		t.Log("expected error for manually scheduled VM on host missing capabilities")
+		t.FailNow()
+	}
+}
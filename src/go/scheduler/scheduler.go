@@ -1,12 +1,22 @@
 package scheduler
 
 import (
+	"fmt"
+	"sync"
+
 	ifaces "phenix/types/interfaces"
+	"phenix/util/perror"
 	"phenix/util/shell"
 )
 
 var schedulers = make(map[string]Scheduler)
 
+// The scheduler used when no scheduler is given, configurable via SetDefault.
+var defaultScheduler struct {
+	sync.RWMutex
+	name string
+}
+
 // Scheduler is the interface that identifies all the required functionality for
 // a phenix scheduler.
 type Scheduler interface {
@@ -35,12 +45,54 @@ func List() []string {
 	return names
 }
 
+// SetDefault sets the scheduler used when Schedule is called without a
+// scheduler name. An empty name means a scheduler must always be given.
+func SetDefault(name string) {
+	defaultScheduler.Lock()
+	defer defaultScheduler.Unlock()
+
+	defaultScheduler.name = name
+}
+
+// Default returns the scheduler used when Schedule is called without a
+// scheduler name.
+func Default() string {
+	defaultScheduler.RLock()
+	defer defaultScheduler.RUnlock()
+
+	return defaultScheduler.name
+}
+
+// Schedule runs the given scheduler against the given experiment, then ensures
+// every VM requiring specific host capabilities was scheduled on a host with
+// those capabilities. The default scheduler is used if no scheduler is given.
+// Errors are returned with a `perror.CodeScheduling` code for the experiment.
 func Schedule(name string, spec ifaces.ExperimentSpec) error {
+	if name == "" {
+		if name = Default(); name == "" {
+			return perror.Wrap(perror.CodeScheduling, "experiment/"+spec.ExperimentName(), fmt.Errorf("no scheduler provided and no default scheduler configured"))
+		}
+	}
+
 	scheduler, ok := schedulers[name]
 	if !ok {
 		scheduler = new(userScheduler)
 		scheduler.Init(Name(name))
 	}
 
-	return scheduler.Schedule(spec)
+	// Track VMs manually scheduled before running the scheduler so they aren't
+	// moved to another host.
+	manual := make(map[string]struct{})
+
+	for vm := range spec.Schedules() {
+		manual[vm] = struct{}{}
+	}
+
+	resource := "experiment/" + spec.ExperimentName()
+
+	if err := scheduler.Schedule(spec); err != nil {
+		return perror.Wrap(perror.CodeScheduling, resource, err)
+	}
+
+	return perror.Wrap(perror.CodeScheduling, resource, enforceCapabilities(spec, manual))
 }
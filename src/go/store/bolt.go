@@ -41,6 +41,10 @@ func (this *BoltDB) Init(opts ...Option) error {
 func (this *BoltDB) open() error {
 	this.Lock()
 
+	if this.path == "" {
+		return ErrNotInitialized
+	}
+
 	var err error
 
 	this.db, err = bbolt.Open(this.path, 0600, &bbolt.Options{NoFreelistSync: true})
@@ -62,7 +66,11 @@ func (this *BoltDB) Close() error {
 }
 
 func (this *BoltDB) List(kinds ...string) (Configs, error) {
-	this.open()
+	if err := this.open(); err != nil {
+		this.Close()
+		return nil, fmt.Errorf("opening Bolt database: %w", err)
+	}
+
 	defer this.Close()
 
 	var configs Configs
@@ -139,6 +147,10 @@ func (this *BoltDB) Create(c *Config) error {
 
 	c.Metadata.Updated = now
 
+	if err := this.revise(c); err != nil {
+		return err
+	}
+
 	v, err := json.Marshal(c)
 	if err != nil {
 		return fmt.Errorf("marshaling config JSON: %w", err)
@@ -161,6 +173,10 @@ func (this *BoltDB) Update(c *Config) error {
 
 	c.Metadata.Updated = time.Now().Format(time.RFC3339)
 
+	if err := this.revise(c); err != nil {
+		return err
+	}
+
 	v, err := json.Marshal(c)
 	if err != nil {
 		return fmt.Errorf("marshaling config JSON: %w", err)
@@ -204,7 +220,11 @@ func (this *BoltDB) Delete(c *Config) error {
 }
 
 func (this *BoltDB) GetEvents() (Events, error) {
-	this.open()
+	if err := this.open(); err != nil {
+		this.Close()
+		return nil, fmt.Errorf("opening Bolt database: %w", err)
+	}
+
 	defer this.Close()
 
 	if err := this.ensureBucket("events"); err != nil {
@@ -337,7 +357,13 @@ func (this *BoltDB) GetEvent(e *Event) error {
 }
 
 func (this *BoltDB) AddEvent(e Event) error {
-	this.open()
+	// Events can be recorded (ie. to an experiment timeline) before the store
+	// is initialized, so don't assume the database opened.
+	if err := this.open(); err != nil {
+		this.Close()
+		return fmt.Errorf("opening Bolt database: %w", err)
+	}
+
 	defer this.Close()
 
 	v, err := json.Marshal(e)
@@ -389,6 +415,31 @@ func (this *BoltDB) put(b, k string, v []byte) error {
 	return nil
 }
 
+// revise sets the revision of the given config to the next revision for its
+// kind. Revisions keep increasing across config deletions, so a revision is
+// never reused for different content.
+func (this *BoltDB) revise(c *Config) error {
+	if err := this.ensureBucket(c.Kind); err != nil {
+		return err
+	}
+
+	err := this.db.Update(func(tx *bbolt.Tx) error {
+		rev, err := tx.Bucket([]byte(c.Kind)).NextSequence()
+		if err != nil {
+			return err
+		}
+
+		c.Metadata.Revision = int64(rev)
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("getting next revision for config %s/%s: %w", c.Kind, c.Metadata.Name, err)
+	}
+
+	return nil
+}
+
 func (this *BoltDB) ensureBucket(name string) error {
 	return this.db.Update(func(tx *bbolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte(name))
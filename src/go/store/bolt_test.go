@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -132,3 +133,74 @@ func TestConfigDelete(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestConfigListNotInitialized(t *testing.T) {
+	b := NewBoltDB()
+
+	if _, err := b.List("Site"); !errors.Is(err, ErrNotInitialized) {
+		t.Logf("expected not initialized error, got %v", err)
+		t.FailNow()
+	}
+}
+
+func TestConfigRevision(t *testing.T) {
+	f, err := ioutil.TempFile("/tmp", "phenix")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.Remove(f.Name())
+
+	b := NewBoltDB()
+
+	if err := b.Init(Endpoint("bolt://" + f.Name())); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var c Config
+
+	if err := yaml.Unmarshal([]byte(topology), &c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := b.Create(&c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := b.Update(&c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	updated := c.Metadata.Revision
+
+	if err := b.Get(&c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if c.Metadata.Revision != updated || updated < 2 {
+		t.Logf("expected stored revision %d after update, got %d", updated, c.Metadata.Revision)
+		t.FailNow()
+	}
+
+	// Revisions aren't reused when a config is deleted and created again.
+	if err := b.Delete(&c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := b.Create(&c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if c.Metadata.Revision <= updated {
+		t.Logf("expected revision greater than %d after recreating config, got %d", updated, c.Metadata.Revision)
+		t.FailNow()
+	}
+}
@@ -69,6 +69,8 @@ func (this Etcd) List(kinds ...string) (Configs, error) {
 				return nil, fmt.Errorf("unmarshaling config JSON: %w", err)
 			}
 
+			c.Metadata.Revision = e.ModRevision
+
 			configs = append(configs, c)
 		}
 	}
@@ -94,6 +96,8 @@ func (this Etcd) Get(c *Config) error {
 		return fmt.Errorf("unmarshaling config JSON: %w", err)
 	}
 
+	c.Metadata.Revision = e.ModRevision
+
 	return nil
 }
 
@@ -114,10 +118,14 @@ func (this Etcd) Create(c *Config) error {
 		return fmt.Errorf("marshaling config JSON: %w", err)
 	}
 
-	if _, err := this.cli.Put(context.Background(), key, string(v)); err != nil {
+	resp, err := this.cli.Put(context.Background(), key, string(v))
+	if err != nil {
 		return fmt.Errorf("writing config JSON to Etcd: %w", err)
 	}
 
+	// Etcd revisions are used for config revisions.
+	c.Metadata.Revision = resp.Header.Revision
+
 	return nil
 }
 
@@ -137,10 +145,14 @@ func (this Etcd) Update(c *Config) error {
 		return fmt.Errorf("marshaling config JSON: %w", err)
 	}
 
-	if _, err := this.cli.Put(context.Background(), key, string(v)); err != nil {
+	resp, err := this.cli.Put(context.Background(), key, string(v))
+	if err != nil {
 		return fmt.Errorf("writing config JSON to Etcd: %w", err)
 	}
 
+	// Etcd revisions are used for config revisions.
+	c.Metadata.Revision = resp.Header.Revision
+
 	return nil
 }
 
@@ -5,6 +5,10 @@ import "fmt"
 var (
 	ErrExist    = fmt.Errorf("config already exists")
 	ErrNotExist = fmt.Errorf("config does not exist")
+
+	// ErrNotInitialized is returned when the store is used before it has been
+	// initialized (ie. in tests or by packages used outside of phenix).
+	ErrNotInitialized = fmt.Errorf("config store not initialized")
 )
 
 // Store is the interface that identifies all the required functionality for a
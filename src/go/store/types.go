@@ -37,6 +37,7 @@ type ConfigMetadata struct {
 	Name        string      `json:"name" yaml:"name"`
 	Created     string      `json:"created" yaml:"created"`
 	Updated     string      `json:"updated" yaml:"updated"`
+	Revision    int64       `json:"revision,omitempty" yaml:"revision,omitempty"`
 	Annotations Annotations `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 }
 
@@ -88,6 +89,7 @@ func NewConfigFromFile(path string) (*Config, error) {
 	// ensure users aren't trying to set these values
 	c.Metadata.Created = ""
 	c.Metadata.Updated = ""
+	c.Metadata.Revision = 0
 
 	return &c, nil
 }
@@ -115,6 +117,7 @@ func NewConfigFromJSON(body []byte, replacements ...string) (*Config, error) {
 	// ensure users aren't trying to set these values
 	c.Metadata.Created = ""
 	c.Metadata.Updated = ""
+	c.Metadata.Revision = 0
 
 	return &c, nil
 }
@@ -142,6 +145,7 @@ func NewConfigFromYAML(body []byte, replacements ...string) (*Config, error) {
 	// ensure users aren't trying to set these values
 	c.Metadata.Created = ""
 	c.Metadata.Updated = ""
+	c.Metadata.Revision = 0
 
 	return &c, nil
 }
@@ -205,6 +209,8 @@ const (
 	EventTypeError   EventType = "error"
 	EventTypeUnknown EventType = "unknown"
 	EventTypeHistory EventType = "history"
+	EventTypeUsage   EventType = "usage"
+	EventTypeJournal EventType = "journal"
 )
 
 type Event struct {
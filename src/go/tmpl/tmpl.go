@@ -60,6 +60,10 @@ func GenerateFromTemplate(name string, data interface{}, w io.Writer) error {
 		"stringsJoin": func(s []string, sep string) string {
 			return strings.Join(s, sep)
 		},
+		// Quotes the given string as a PowerShell string literal.
+		"psQuote": func(s string) string {
+			return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		},
 	}
 
 	tmpl := template.Must(template.New(name).Funcs(funcs).Parse(string(MustAsset(name))))
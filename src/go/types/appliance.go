@@ -0,0 +1,11 @@
+package types
+
+import (
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+)
+
+type Appliance struct {
+	Metadata store.ConfigMetadata `json:"metadata"`
+	Spec     *v1.ApplianceSpec    `json:"spec"`
+}
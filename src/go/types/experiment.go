@@ -4,17 +4,25 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"phenix/store"
 	ifaces "phenix/types/interfaces"
 	"phenix/types/version"
+	"phenix/util/cache"
 	"phenix/util/common"
 	"phenix/util/mm"
 
 	"github.com/activeshadow/structs"
 	"github.com/mitchellh/mapstructure"
+	"github.com/mohae/deepcopy"
 )
 
+// renderCacheExpiration is how long rendered experiment outputs are cached for.
+// Cache entries are keyed by config revision, so an updated experiment never
+// uses a stale entry; the expiration just cleans up entries for old revisions.
+const renderCacheExpiration = 10 * time.Minute
+
 type Experiment struct {
 	Metadata store.ConfigMetadata    `json:"metadata" yaml:"metadata"` // experiment configuration metadata
 	Spec     ifaces.ExperimentSpec   `json:"spec" yaml:"spec"`         // reference to latest versioned experiment spec
@@ -141,6 +149,40 @@ func (this Experiment) FilesDir() string {
 	return filepath.Join(common.PhenixBase, "images", this.Metadata.Name, "files")
 }
 
+// RenderExperiment returns the output (ie. VM details with defaults and
+// schedules applied) rendered by the given function from the experiment decoded
+// from the given config, caching it by name and the config's revision. The
+// experiment is only decoded when the output isn't already cached for the
+// config's revision, since decoding large experiments is expensive, and outputs
+// are rendered again once the config is updated in the store. Each call returns
+// its own copy of the output so callers are free to modify it.
+func RenderExperiment(c store.Config, name string, render func(*Experiment) (any, error)) (any, error) {
+	key := fmt.Sprintf("experiment|%s|%d|render|%s", c.Metadata.Name, c.Metadata.Revision, name)
+
+	// Configs not read from the store don't have a revision to key on.
+	if c.Metadata.Revision != 0 {
+		if val, ok := cache.Get(key); ok {
+			return deepcopy.Copy(val), nil
+		}
+	}
+
+	exp, err := DecodeExperimentFromConfig(c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment %s: %w", c.Metadata.Name, err)
+	}
+
+	val, err := render(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Metadata.Revision != 0 {
+		cache.SetWithExpire(key, deepcopy.Copy(val), renderCacheExpiration)
+	}
+
+	return val, nil
+}
+
 func Experiments(running bool) ([]*Experiment, error) {
 	configs, err := store.List("Experiment")
 	if err != nil {
@@ -26,6 +26,14 @@ type ExperimentSpec interface {
 	Schedules() map[string]string
 	DeployMode() string
 	UseGREMesh() bool
+	SkipStages() map[string][]string
+	SkipStage(string, string) bool
+	Dependencies() []DependencySpec
+	ConcurrencyGroups() []string
+	ExternalBridges() []ExternalBridgeSpec
+	ExternalBridge(string) ExternalBridgeSpec
+	Locale() LocaleSpec
+	SnapshotName(string) string
 
 	SetExperimentName(string)
 	SetBaseDir(string)
@@ -37,25 +45,126 @@ type ExperimentSpec interface {
 	SetScenario(ScenarioSpec)
 	SetDeployMode(string)
 	SetUseGREMesh(bool)
+	SetSkipStages(map[string][]string)
+	AddDependency(string, string, string)
+	SetConcurrencyGroups([]string)
+	AddExternalBridge(string, map[string]int, bool)
+	SetLocale(string, string, string)
 
 	VerifyScenario(context.Context) error
 	ScheduleNode(string, string) error
 }
 
+// DependencySpec is an external resource that must be available before an
+// experiment starts. Supported types are `url` (the target URL is reachable),
+// `mount` (the target path is a mount point on the experiment hosts) and
+// `experiment` (the target experiment is running). The timeout is how long to
+// wait for the dependency to become available (ie. `5m`).
+type DependencySpec interface {
+	Type() string
+	Target() string
+	Timeout() string
+}
+
+// ExternalBridgeSpec is a pre-existing OVS bridge managed outside of phenix
+// (ie. part of a larger lab fabric owned by the site network team) that
+// experiment VMs are connected to. VLANs are the externally-managed VLANs on
+// the bridge, as aliases mapped to VLAN IDs, that topology interfaces can use.
+// Phenix never creates external bridges, and never deletes those with
+// NoDestroy set, even at cleanup.
+type ExternalBridgeSpec interface {
+	Name() string
+	VLANs() map[string]int
+	NoDestroy() bool
+}
+
+// AppRun is the status of the last time an app was applied for a lifecycle
+// stage. Start and end times are RFC3339 formatted, and the result is one of
+// running, retrying, success, error, or skipped. Attempts are the failed
+// attempts of the run that were retried.
+type AppRun interface {
+	Start() string
+	End() string
+	Result() string
+	Error() string
+	Attempts() []AppAttempt
+}
+
+// AppAttempt is a failed attempt of an app run that was retried. The time it
+// failed is RFC3339 formatted.
+type AppAttempt interface {
+	Failed() string
+	Error() string
+}
+
+// LocaleSpec is the locale (ie. `de_DE.UTF-8`), timezone (ie. `Europe/Berlin`)
+// and keyboard layout (ie. `de`) configured on VMs by the startup app. It's set
+// for the experiment as a whole and can be overridden per node. Empty values
+// are left as configured in the VM image.
+type LocaleSpec interface {
+	Locale() string
+	Timezone() string
+	Keyboard() string
+}
+
+// Expectation is something an app expects to hold true for a VM while the
+// experiment is running, along with whether it currently does. Severity is the
+// health severity of the VM if the expectation isn't met (warning or
+// critical), and Updated is RFC3339 formatted.
+type Expectation interface {
+	VM() string
+	Name() string
+	Severity() string
+	Met() bool
+	Message() string
+	Updated() string
+}
+
+// Artifact is a file an app produced (ie. a generated config or a report),
+// registered so it can be discovered and downloaded via the experiment files
+// service. Path is relative to the experiment's files directory, and Created is
+// RFC3339 formatted.
+type Artifact interface {
+	Name() string
+	Path() string
+	MIMEType() string
+	Description() string
+	Created() string
+}
+
 type ExperimentStatus interface {
 	Init() error
 
 	StartTime() string
+	Starting() string
 	AppStatus() map[string]any
 	AppFrequency() map[string]string
 	AppRunning() map[string]bool
+	AppSkipped() map[string][]string
+	AppVersions() map[string]string
+	AppRuns() map[string]map[string]AppRun
+	AppExpectations() map[string][]Expectation
+	AppArtifacts() map[string][]Artifact
+	Deferred() []string
+	PersistentDisks() map[string]string
 	VLANs() map[string]int
 	Schedules() map[string]string
 
 	SetStartTime(string)
+	SetStarting(string)
 	SetAppStatus(string, any)
 	SetAppFrequency(string, string)
 	SetAppRunning(string, bool)
+	SetAppSkipped(string, string)
+	SetAppVersion(string, string)
+	SetAppRun(string, string, string, error)
+	SetAppExpectation(string, Expectation)
+	ClearAppExpectations(string)
+	SetAppArtifact(string, Artifact)
+	ClearAppArtifacts(string)
+	SetDeferred([]string)
+	SetPersistentDisk(string, string)
+	ClearPersistentDisk(string)
 	SetVLANs(map[string]int)
 	SetSchedule(map[string]string)
 
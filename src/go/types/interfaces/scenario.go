@@ -11,19 +11,36 @@ type ScenarioApp interface {
 	AssetDir() string
 	Metadata() map[string]any
 	Hosts() []ScenarioAppHost
+	Targets() ScenarioAppTargets
 	RunPeriodically() string
 	Disabled() bool
+	Optional() bool
+	Version() string
+	Weight() int
+	RunBeforeDefaults() bool
 
 	SetAssetDir(string)
 	SetMetadata(map[string]any)
 	SetHosts([]ScenarioAppHost)
 	SetRunPeriodically(string)
 	SetDisabled(bool)
+	SetOptional(bool)
+	SetVersion(string)
+	SetWeight(int)
+	SetRunBeforeDefaults(bool)
 
 	ParseMetadata(any) error
 	ParseHostMetadata(string, any) error
 }
 
+type ScenarioAppTargets interface {
+	Hostnames() []string
+	Labels() map[string]string
+	Types() []string
+
+	Match(NodeSpec) bool
+}
+
 type ScenarioAppHost interface {
 	Hostname() string
 	Metadata() map[string]any
@@ -29,8 +29,11 @@ type NodeSpec interface {
 	Network() NodeNetwork
 	Injections() []NodeInjection
 	Delay() NodeDelay
+	Kernel() NodeKernel
+	Locale() LocaleSpec
 	Advanced() map[string]string
 	Overrides() map[string]string
+	QEMUAppend() []string
 	Commands() []string
 	External() bool
 
@@ -58,8 +61,10 @@ type NodeGeneral interface {
 	Snapshot() *bool
 	SetSnapshot(bool)
 	DoNotBoot() *bool
+	Persistent() *bool
 
 	SetDoNotBoot(bool)
+	SetPersistent(bool)
 }
 
 type NodeHardware interface {
@@ -68,6 +73,7 @@ type NodeHardware interface {
 	Memory() int
 	OSType() string
 	Drives() []NodeDrive
+	PXE() NodePXE
 
 	SetVCPU(int)
 	SetMemory(int)
@@ -75,6 +81,13 @@ type NodeHardware interface {
 	AddDrive(string, int) NodeDrive
 }
 
+type NodePXE interface {
+	Kernel() string
+	Initrd() string
+	Append() string
+	Protocol() string
+}
+
 type NodeDrive interface {
 	Image() string
 	Interface() string
@@ -129,6 +142,7 @@ type NodeNetworkInterface interface {
 	SetBridge(string)
 	SetAutostart(bool)
 	SetMAC(string)
+	SetDriver(string)
 	SetMTU(int)
 	SetAddress(string)
 	SetMask(int)
@@ -212,6 +226,11 @@ type NodeDelay interface {
 	C2() []NodeC2Delay
 }
 
+type NodeKernel interface {
+	Args() []string
+	Sysctl() map[string]string
+}
+
 type NodeC2Delay interface {
 	Hostname() string
 	UseUUID() bool
@@ -28,6 +28,13 @@ components:
           - Topology
           - Scenario
           - Experiment
+          - Template
+          - App
+          - Service
+          - Appliance
+          - Overlay
+          - Site
+          - View
         metadata:
           type: object
           required:
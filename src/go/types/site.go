@@ -0,0 +1,50 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DEFAULT_SITE is the name of the Site config used for the cluster's site
+// settings.
+const DEFAULT_SITE = "default"
+
+type Site struct {
+	Metadata store.ConfigMetadata `json:"metadata"`
+	Spec     *v1.SiteSpec         `json:"spec"`
+}
+
+// SiteSettings returns the cluster's site settings from the default Site
+// config. Empty site settings are returned if there's no default Site config,
+// or if the store hasn't been initialized (ie. when apps are run in tests).
+func SiteSettings() (*v1.SiteSpec, error) {
+	configs, err := store.List("Site")
+	if err != nil {
+		if errors.Is(err, store.ErrNotInitialized) {
+			return new(v1.SiteSpec), nil
+		}
+
+		return nil, fmt.Errorf("getting site configs from store: %w", err)
+	}
+
+	spec := new(v1.SiteSpec)
+
+	for _, c := range configs {
+		if c.Metadata.Name != DEFAULT_SITE {
+			continue
+		}
+
+		if err := mapstructure.Decode(c.Spec, spec); err != nil {
+			return nil, fmt.Errorf("decoding site spec: %w", err)
+		}
+
+		break
+	}
+
+	return spec, nil
+}
@@ -3,10 +3,13 @@ package types
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"phenix/store"
 	"phenix/types/version"
+	"phenix/util/perror"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -15,17 +18,23 @@ var ErrValidationFailed = fmt.Errorf("config validation failed")
 
 // ValidateConfigSpec validates the spec in the given config using the
 // appropriate `openapi3.Schema` validator. Any validation errors encountered
-// are returned.
+// are returned, with a `perror.CodeValidation` code for the config.
 func ValidateConfigSpec(c store.Config) error {
+	resource := strings.ToLower(c.Kind) + "/" + c.Metadata.Name
+
 	if g := c.APIGroup(); g != store.API_GROUP {
 		if g == "" {
-			return fmt.Errorf("%w: missing API group -- expected %s", ErrValidationFailed, store.API_GROUP)
+			return perror.Errorf(perror.CodeValidation, resource, "%w: missing API group -- expected %s", ErrValidationFailed, store.API_GROUP)
 		}
 
-		return fmt.Errorf("%w: invalid API group %s: expected %s", ErrValidationFailed, g, store.API_GROUP)
+		return perror.Errorf(perror.CodeValidation, resource, "%w: invalid API group %s: expected %s", ErrValidationFailed, g, store.API_GROUP)
 	}
 
 	if err := ValidateConfig(c); err != nil {
+		if errors.Is(err, ErrValidationFailed) {
+			return perror.Errorf(perror.CodeValidation, resource, "validating config: %w", err)
+		}
+
 		return fmt.Errorf("validating config: %w", err)
 	}
 
@@ -43,7 +52,7 @@ func ValidateConfigSpec(c store.Config) error {
 	json.Unmarshal(data, &spec)
 
 	if err := v.VisitJSON(spec); err != nil {
-		return fmt.Errorf("%w: %v", ErrValidationFailed, err)
+		return perror.Errorf(perror.CodeValidation, resource, "%w: %v", ErrValidationFailed, err)
 	}
 
 	return nil
@@ -59,6 +59,14 @@ func (this Node) Delay() ifaces.NodeDelay {
 	return new(Delay)
 }
 
+func (this Node) Kernel() ifaces.NodeKernel {
+	return new(Kernel)
+}
+
+func (this Node) Locale() ifaces.LocaleSpec {
+	return new(Locale)
+}
+
 func (Node) Advanced() map[string]string {
 	return nil
 }
@@ -67,6 +75,10 @@ func (Node) Overrides() map[string]string {
 	return nil
 }
 
+func (Node) QEMUAppend() []string {
+	return nil
+}
+
 func (Node) Commands() []string {
 	return nil
 }
@@ -169,7 +181,7 @@ type General struct {
 	VMTypeF      string `json:"vm_type" yaml:"vm_type" structs:"vm_type" mapstructure:"vm_type"`
 	SnapshotF    *bool  `json:"snapshot" yaml:"snapshot" structs:"snapshot" mapstructure:"snapshot"`
 	DoNotBootF   *bool  `json:"do_not_boot" yaml:"do_not_boot" structs:"do_not_boot" mapstructure:"do_not_boot"`
-
+	PersistentF  *bool  `json:"persistent,omitempty" yaml:"persistent,omitempty" structs:"persistent,omitempty" mapstructure:"persistent,omitempty"`
 }
 
 func (this General) Hostname() string {
@@ -199,6 +211,14 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+func (this General) Persistent() *bool {
+	return this.PersistentF
+}
+
+func (this *General) SetPersistent(b bool) {
+	this.PersistentF = &b
+}
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus,string" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
@@ -233,6 +253,10 @@ func (this Hardware) Drives() []ifaces.NodeDrive {
 	return drives
 }
 
+func (this Hardware) PXE() ifaces.NodePXE {
+	return nil
+}
+
 func (this *Hardware) SetVCPU(v int) {
 	this.VCPUF = v
 }
@@ -325,6 +349,30 @@ func (this Delay) C2() []ifaces.NodeC2Delay {
 	return nil
 }
 
+type Kernel struct{}
+
+func (this Kernel) Args() []string {
+	return nil
+}
+
+func (this Kernel) Sysctl() map[string]string {
+	return nil
+}
+
+type Locale struct{}
+
+func (this Locale) Locale() string {
+	return ""
+}
+
+func (this Locale) Timezone() string {
+	return ""
+}
+
+func (this Locale) Keyboard() string {
+	return ""
+}
+
 func (this *Node) SetDefaults() {
 	if this.GeneralF.VMTypeF == "" {
 		this.GeneralF.VMTypeF = "kvm"
@@ -0,0 +1,33 @@
+package v1
+
+// AppSpec is the metadata registered for an external user app installed via
+// `phenix app install`. The app's executable lives in the phenix app path as
+// `phenix-app-<name>`. Every version of the app installed is also kept so
+// scenarios can pin the app to a specific version.
+type AppSpec struct {
+	Description string `yaml:"description" json:"description" structs:"description" mapstructure:"description"`
+	Version     string `yaml:"version" json:"version" structs:"version" mapstructure:"version"`
+	Source      string `yaml:"source" json:"source" structs:"source" mapstructure:"source"`
+	Executable  string `yaml:"executable" json:"executable" structs:"executable" mapstructure:"executable"`
+	Checksum    string `yaml:"checksum" json:"checksum" structs:"checksum" mapstructure:"checksum"`
+
+	// Protocol is the protocol the app uses to exchange the experiment with
+	// phenix (`shell` or `grpc`). Empty means `shell`.
+	Protocol string `yaml:"protocol,omitempty" json:"protocol,omitempty" structs:"protocol,omitempty" mapstructure:"protocol,omitempty"`
+
+	// Schema is the (optional) OpenAPI schema describing the app's scenario
+	// metadata, used by the UI when configuring the app.
+	Schema map[string]interface{} `yaml:"schema,omitempty" json:"schema,omitempty" structs:"schema,omitempty" mapstructure:"schema,omitempty"`
+
+	// Versions are all the versions of the app installed, keyed by version.
+	Versions map[string]AppVersion `yaml:"versions,omitempty" json:"versions,omitempty" structs:"versions,omitempty" mapstructure:"versions,omitempty"`
+}
+
+// AppVersion is a specific version of an installed user app. Its executable
+// lives outside of the phenix app path so it's only used when pinned.
+type AppVersion struct {
+	Source     string `yaml:"source" json:"source" structs:"source" mapstructure:"source"`
+	Executable string `yaml:"executable" json:"executable" structs:"executable" mapstructure:"executable"`
+	Checksum   string `yaml:"checksum" json:"checksum" structs:"checksum" mapstructure:"checksum"`
+	Protocol   string `yaml:"protocol,omitempty" json:"protocol,omitempty" structs:"protocol,omitempty" mapstructure:"protocol,omitempty"`
+}
@@ -0,0 +1,13 @@
+package v1
+
+// ApplianceSpec is a vendor appliance imported from an OVA/OVF package. The
+// node is a template for adding the appliance to a topology, built from the
+// hardware described in the package; its hostname and interface VLANs should
+// be updated when it's used.
+type ApplianceSpec struct {
+	Description string   `yaml:"description" json:"description" structs:"description" mapstructure:"description"`
+	Source      string   `yaml:"source" json:"source" structs:"source" mapstructure:"source"`
+	Format      string   `yaml:"format" json:"format" structs:"format" mapstructure:"format"`
+	Disks       []string `yaml:"disks" json:"disks" structs:"disks" mapstructure:"disks"`
+	Node        *Node    `yaml:"node" json:"node" structs:"node" mapstructure:"node"`
+}
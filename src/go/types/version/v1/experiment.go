@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	ifaces "phenix/types/interfaces"
 	v2 "phenix/types/version/v2"
@@ -82,6 +83,64 @@ type ExperimentSpec struct {
 	SchedulesF      map[string]string `json:"schedules" yaml:"schedules" structs:"schedules" mapstructure:"schedules"`
 	DeployModeF     string            `json:"deployMode" yaml:"deployMode" structs:"deployMode" mapstructure:"deployMode"`
 	UseGREMeshF     bool              `json:"useGREMesh" yaml:"useGREMesh" structs:"useGREMesh" mapstructure:"useGREMesh"`
+
+	// Map of app names to the app stages (ie. `post-start`) to skip when
+	// applying apps to the experiment.
+	SkipStagesF map[string][]string `json:"skipStages,omitempty" yaml:"skipStages,omitempty" structs:"skipStages" mapstructure:"skipStages"`
+
+	// External resources that must be available before the experiment starts.
+	DependenciesF []*Dependency `json:"dependencies,omitempty" yaml:"dependencies,omitempty" structs:"dependencies" mapstructure:"dependencies"`
+
+	// Concurrency groups (ie. a shared hardware-in-the-loop rig) the experiment
+	// is a member of. Only one member of a group can run at a time.
+	ConcurrencyGroupsF []string `json:"concurrencyGroups,omitempty" yaml:"concurrencyGroups,omitempty" structs:"concurrencyGroups" mapstructure:"concurrencyGroups"`
+
+	// Pre-existing OVS bridges managed outside of phenix.
+	ExternalBridgesF []*ExternalBridge `json:"externalBridges,omitempty" yaml:"externalBridges,omitempty" structs:"externalBridges" mapstructure:"externalBridges"`
+
+	// Locale, timezone, and keyboard layout configured on VMs by the startup app
+	// (unless overridden by a node).
+	LocaleF *Locale `json:"locale,omitempty" yaml:"locale,omitempty" structs:"locale" mapstructure:"locale"`
+}
+
+type Dependency struct {
+	TypeF    string `json:"type" yaml:"type" structs:"type" mapstructure:"type"`
+	TargetF  string `json:"target" yaml:"target" structs:"target" mapstructure:"target"`
+	TimeoutF string `json:"timeout,omitempty" yaml:"timeout,omitempty" structs:"timeout" mapstructure:"timeout"`
+}
+
+func (this Dependency) Type() string {
+	return this.TypeF
+}
+
+func (this Dependency) Target() string {
+	return this.TargetF
+}
+
+func (this Dependency) Timeout() string {
+	return this.TimeoutF
+}
+
+type ExternalBridge struct {
+	NameF      string         `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	VLANsF     map[string]int `json:"vlans,omitempty" yaml:"vlans,omitempty" structs:"vlans" mapstructure:"vlans"`
+	NoDestroyF bool           `json:"noDestroy,omitempty" yaml:"noDestroy,omitempty" structs:"noDestroy" mapstructure:"noDestroy"`
+}
+
+func (this ExternalBridge) Name() string {
+	return this.NameF
+}
+
+func (this ExternalBridge) VLANs() map[string]int {
+	if this.VLANsF == nil {
+		return make(map[string]int)
+	}
+
+	return this.VLANsF
+}
+
+func (this ExternalBridge) NoDestroy() bool {
+	return this.NoDestroyF
 }
 
 func (this *ExperimentSpec) Init() error {
@@ -126,10 +185,37 @@ func (this *ExperimentSpec) Init() error {
 				if _, ok := this.VLANsF.AliasesF[i.VLANF]; !ok {
 					this.VLANsF.AliasesF[i.VLANF] = 0
 				}
+
+				// Interfaces using an externally-managed VLAN are connected to the
+				// external bridge the VLAN is on, unless a bridge was set for the
+				// interface in the topology. The bridge is then treated as set in the
+				// topology so it isn't reset to the default bridge later on.
+				if i.BridgeF != this.DefaultBridgeF {
+					continue
+				}
+
+				for _, b := range this.ExternalBridgesF {
+					if _, ok := b.VLANsF[i.VLANF]; ok {
+						setInTopo := true
+
+						i.BridgeF = b.NameF
+						i.BridgeSetInTopo = &setInTopo
+
+						break
+					}
+				}
 			}
 		}
 	}
 
+	// Externally-managed VLANs always use the VLAN ID assigned to them outside
+	// of phenix.
+	for _, b := range this.ExternalBridgesF {
+		for alias, id := range b.VLANsF {
+			this.VLANsF.AliasesF[alias] = id
+		}
+	}
+
 	return nil
 }
 
@@ -201,6 +287,68 @@ func (this ExperimentSpec) UseGREMesh() bool {
 	return this.UseGREMeshF
 }
 
+func (this ExperimentSpec) SkipStages() map[string][]string {
+	if this.SkipStagesF == nil {
+		return make(map[string][]string)
+	}
+
+	return this.SkipStagesF
+}
+
+func (this ExperimentSpec) SkipStage(app, stage string) bool {
+	for _, s := range this.SkipStagesF[app] {
+		if s == stage {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (this ExperimentSpec) Dependencies() []ifaces.DependencySpec {
+	deps := make([]ifaces.DependencySpec, len(this.DependenciesF))
+
+	for i, d := range this.DependenciesF {
+		deps[i] = d
+	}
+
+	return deps
+}
+
+func (this ExperimentSpec) ConcurrencyGroups() []string {
+	return this.ConcurrencyGroupsF
+}
+
+func (this ExperimentSpec) ExternalBridges() []ifaces.ExternalBridgeSpec {
+	bridges := make([]ifaces.ExternalBridgeSpec, len(this.ExternalBridgesF))
+
+	for i, b := range this.ExternalBridgesF {
+		bridges[i] = b
+	}
+
+	return bridges
+}
+
+// ExternalBridge returns the external bridge with the given name, or nil if
+// the bridge isn't an external bridge.
+func (this ExperimentSpec) ExternalBridge(name string) ifaces.ExternalBridgeSpec {
+	for _, b := range this.ExternalBridgesF {
+		if b.NameF == name {
+			return b
+		}
+	}
+
+	return nil
+}
+
+func (this ExperimentSpec) Locale() ifaces.LocaleSpec {
+	if this.LocaleF == nil {
+		return new(Locale)
+	}
+
+	return this.LocaleF
+}
+
 func (this *ExperimentSpec) SetVLANAlias(a string, i int, f bool) error {
 	if this.VLANsF == nil {
 		this.VLANsF = &VLANSpec{AliasesF: make(map[string]int)}
@@ -265,6 +413,31 @@ func (this *ExperimentSpec) SetUseGREMesh(g bool) {
 	this.UseGREMeshF = g
 }
 
+func (this *ExperimentSpec) SetSkipStages(s map[string][]string) {
+	this.SkipStagesF = s
+}
+
+func (this *ExperimentSpec) AddDependency(typ, target, timeout string) {
+	this.DependenciesF = append(this.DependenciesF, &Dependency{TypeF: typ, TargetF: target, TimeoutF: timeout})
+}
+
+func (this *ExperimentSpec) SetConcurrencyGroups(g []string) {
+	this.ConcurrencyGroupsF = g
+}
+
+func (this *ExperimentSpec) AddExternalBridge(name string, vlans map[string]int, noDestroy bool) {
+	this.ExternalBridgesF = append(this.ExternalBridgesF, &ExternalBridge{NameF: name, VLANsF: vlans, NoDestroyF: noDestroy})
+}
+
+func (this *ExperimentSpec) SetLocale(locale, timezone, keyboard string) {
+	if locale == "" && timezone == "" && keyboard == "" {
+		this.LocaleF = nil
+		return
+	}
+
+	this.LocaleF = &Locale{LocaleF: locale, TimezoneF: timezone, KeyboardF: keyboard}
+}
+
 func (this ExperimentSpec) VerifyScenario(ctx context.Context) error {
 	if this.ScenarioF == nil {
 		return nil
@@ -302,11 +475,154 @@ type ExperimentStatus struct {
 	AppsF      map[string]any    `json:"apps" yaml:"apps" structs:"apps" mapstructure:"apps"`
 	VLANsF     map[string]int    `json:"vlans" yaml:"vlans" structs:"vlans" mapstructure:"vlans"`
 
+	// Used to track when the experiment started being started (RFC3339), as a
+	// claim on its concurrency groups until it's running.
+	StartingF string `json:"starting,omitempty" yaml:"starting,omitempty" structs:"starting" mapstructure:"starting"`
+
 	// Used to track details of an app's running stage. Requires special attention
 	// since it can be run periodically in the background and/or triggered
 	// manually via the CLI or UI.
 	FrequencyF map[string]string `json:"appRunningStageFrequency,omitempty" yaml:"appRunningStageFrequency,omitempty" structs:"appRunningStageFrequency" mapstructure:"appRunningStageFrequency"`
 	RunningF   map[string]bool   `json:"appRunningStageStatus,omitempty" yaml:"appRunningStageStatus,omitempty" structs:"appRunningStageStatus" mapstructure:"appRunningStageStatus"`
+
+	// Used to track app stages skipped per the experiment spec.
+	SkippedF map[string][]string `json:"appSkippedStages,omitempty" yaml:"appSkippedStages,omitempty" structs:"appSkippedStages" mapstructure:"appSkippedStages"`
+
+	// Used to track the version of each user app executed, as registered via
+	// `phenix app install`.
+	VersionsF map[string]string `json:"appVersions,omitempty" yaml:"appVersions,omitempty" structs:"appVersions" mapstructure:"appVersions"`
+
+	// Used to track the last run of each app for each stage, keyed by app name
+	// and then stage.
+	RunsF map[string]map[string]*AppRun `json:"appRuns,omitempty" yaml:"appRuns,omitempty" structs:"appRuns" mapstructure:"appRuns"`
+
+	// Used to track VMs deferred by a best-effort start until there's cluster
+	// capacity to launch them.
+	DeferredF []string `json:"deferredVMs,omitempty" yaml:"deferredVMs,omitempty" structs:"deferredVMs" mapstructure:"deferredVMs"`
+
+	// Used to track health expectations registered by apps for VMs, keyed by
+	// app name.
+	ExpectationsF map[string][]*Expectation `json:"appExpectations,omitempty" yaml:"appExpectations,omitempty" structs:"appExpectations" mapstructure:"appExpectations"`
+
+	// Used to track artifacts registered by apps, keyed by app name. Like
+	// persistent disks, these are kept when the experiment is stopped since the
+	// files they refer to are too.
+	ArtifactsF map[string][]*Artifact `json:"appArtifacts,omitempty" yaml:"appArtifacts,omitempty" structs:"appArtifacts" mapstructure:"appArtifacts"`
+
+	// Used to track the persistent disks created for nodes, keyed by node
+	// hostname, along with the cluster host each disk lives on. Unlike the rest
+	// of the status, these are kept when the experiment is stopped.
+	PersistentDisksF map[string]string `json:"persistentDisks,omitempty" yaml:"persistentDisks,omitempty" structs:"persistentDisks" mapstructure:"persistentDisks"`
+}
+
+type Expectation struct {
+	VMF       string `json:"vm" yaml:"vm" structs:"vm" mapstructure:"vm"`
+	NameF     string `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	SeverityF string `json:"severity,omitempty" yaml:"severity,omitempty" structs:"severity" mapstructure:"severity"`
+	MetF      bool   `json:"met" yaml:"met" structs:"met" mapstructure:"met"`
+	MessageF  string `json:"message,omitempty" yaml:"message,omitempty" structs:"message" mapstructure:"message"`
+	UpdatedF  string `json:"updated,omitempty" yaml:"updated,omitempty" structs:"updated" mapstructure:"updated"`
+}
+
+func (this Expectation) VM() string {
+	return this.VMF
+}
+
+func (this Expectation) Name() string {
+	return this.NameF
+}
+
+func (this Expectation) Severity() string {
+	return this.SeverityF
+}
+
+func (this Expectation) Met() bool {
+	return this.MetF
+}
+
+func (this Expectation) Message() string {
+	return this.MessageF
+}
+
+func (this Expectation) Updated() string {
+	return this.UpdatedF
+}
+
+type AppRun struct {
+	StartF  string `json:"start" yaml:"start" structs:"start" mapstructure:"start"`
+	EndF    string `json:"end,omitempty" yaml:"end,omitempty" structs:"end" mapstructure:"end"`
+	ResultF string `json:"result" yaml:"result" structs:"result" mapstructure:"result"`
+	ErrorF  string `json:"error,omitempty" yaml:"error,omitempty" structs:"error" mapstructure:"error"`
+
+	// Used to track failed attempts of the run that were retried.
+	AttemptsF []*AppAttempt `json:"attempts,omitempty" yaml:"attempts,omitempty" structs:"attempts" mapstructure:"attempts"`
+}
+
+type AppAttempt struct {
+	FailedF string `json:"failed" yaml:"failed" structs:"failed" mapstructure:"failed"`
+	ErrorF  string `json:"error" yaml:"error" structs:"error" mapstructure:"error"`
+}
+
+func (this AppAttempt) Failed() string {
+	return this.FailedF
+}
+
+func (this AppAttempt) Error() string {
+	return this.ErrorF
+}
+
+func (this AppRun) Start() string {
+	return this.StartF
+}
+
+func (this AppRun) End() string {
+	return this.EndF
+}
+
+func (this AppRun) Result() string {
+	return this.ResultF
+}
+
+func (this AppRun) Error() string {
+	return this.ErrorF
+}
+
+func (this AppRun) Attempts() []ifaces.AppAttempt {
+	attempts := make([]ifaces.AppAttempt, len(this.AttemptsF))
+
+	for i, attempt := range this.AttemptsF {
+		attempts[i] = attempt
+	}
+
+	return attempts
+}
+
+type Artifact struct {
+	NameF        string `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	PathF        string `json:"path" yaml:"path" structs:"path" mapstructure:"path"`
+	MIMETypeF    string `json:"mimeType,omitempty" yaml:"mimeType,omitempty" structs:"mimeType" mapstructure:"mimeType"`
+	DescriptionF string `json:"description,omitempty" yaml:"description,omitempty" structs:"description" mapstructure:"description"`
+	CreatedF     string `json:"created,omitempty" yaml:"created,omitempty" structs:"created" mapstructure:"created"`
+}
+
+func (this Artifact) Name() string {
+	return this.NameF
+}
+
+func (this Artifact) Path() string {
+	return this.PathF
+}
+
+func (this Artifact) MIMEType() string {
+	return this.MIMETypeF
+}
+
+func (this Artifact) Description() string {
+	return this.DescriptionF
+}
+
+func (this Artifact) Created() string {
+	return this.CreatedF
 }
 
 func (this *ExperimentStatus) Init() error {
@@ -353,6 +669,76 @@ func (this ExperimentStatus) AppRunning() map[string]bool {
 	return this.RunningF
 }
 
+func (this ExperimentStatus) AppSkipped() map[string][]string {
+	if this.SkippedF == nil {
+		return make(map[string][]string)
+	}
+
+	return this.SkippedF
+}
+
+func (this ExperimentStatus) AppVersions() map[string]string {
+	if this.VersionsF == nil {
+		return make(map[string]string)
+	}
+
+	return this.VersionsF
+}
+
+func (this ExperimentStatus) AppRuns() map[string]map[string]ifaces.AppRun {
+	runs := make(map[string]map[string]ifaces.AppRun)
+
+	for app, stages := range this.RunsF {
+		runs[app] = make(map[string]ifaces.AppRun)
+
+		for stage, run := range stages {
+			runs[app][stage] = run
+		}
+	}
+
+	return runs
+}
+
+func (this ExperimentStatus) AppExpectations() map[string][]ifaces.Expectation {
+	expectations := make(map[string][]ifaces.Expectation)
+
+	for app, expects := range this.ExpectationsF {
+		for _, e := range expects {
+			expectations[app] = append(expectations[app], e)
+		}
+	}
+
+	return expectations
+}
+
+func (this ExperimentStatus) AppArtifacts() map[string][]ifaces.Artifact {
+	artifacts := make(map[string][]ifaces.Artifact)
+
+	for app, arts := range this.ArtifactsF {
+		for _, a := range arts {
+			artifacts[app] = append(artifacts[app], a)
+		}
+	}
+
+	return artifacts
+}
+
+func (this ExperimentStatus) Starting() string {
+	return this.StartingF
+}
+
+func (this ExperimentStatus) Deferred() []string {
+	return this.DeferredF
+}
+
+func (this ExperimentStatus) PersistentDisks() map[string]string {
+	if this.PersistentDisksF == nil {
+		return make(map[string]string)
+	}
+
+	return this.PersistentDisksF
+}
+
 func (this ExperimentStatus) VLANs() map[string]int {
 	if this.VLANsF == nil {
 		return make(map[string]int)
@@ -412,6 +798,162 @@ func (this *ExperimentStatus) SetAppRunning(a string, r bool) {
 	this.RunningF[a] = r
 }
 
+func (this *ExperimentStatus) SetAppSkipped(a, stage string) {
+	if this.SkippedF == nil {
+		this.SkippedF = make(map[string][]string)
+	}
+
+	for _, s := range this.SkippedF[a] {
+		if s == stage {
+			return
+		}
+	}
+
+	this.SkippedF[a] = append(this.SkippedF[a], stage)
+}
+
+func (this *ExperimentStatus) SetAppVersion(a, v string) {
+	if this.VersionsF == nil {
+		this.VersionsF = make(map[string]string)
+	}
+
+	this.VersionsF[a] = v
+}
+
+// SetAppRun records the given result for the last run of the given app for the
+// given stage. A result of running starts a new run, and a result of retrying
+// records a failed attempt of the current run that's being retried; any other
+// result ends the current run, or records a run that starts and ends now if the
+// app wasn't running (ie. it was skipped).
+func (this *ExperimentStatus) SetAppRun(a, stage, result string, err error) {
+	if this.RunsF == nil {
+		this.RunsF = make(map[string]map[string]*AppRun)
+	}
+
+	if this.RunsF[a] == nil {
+		this.RunsF[a] = make(map[string]*AppRun)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	run := this.RunsF[a][stage]
+
+	if result == "running" || run == nil || (run.ResultF != "running" && run.ResultF != "retrying") {
+		run = &AppRun{StartF: now}
+		this.RunsF[a][stage] = run
+	}
+
+	run.ResultF = result
+
+	if result == "retrying" {
+		attempt := &AppAttempt{FailedF: now}
+
+		if err != nil {
+			attempt.ErrorF = err.Error()
+		}
+
+		run.AttemptsF = append(run.AttemptsF, attempt)
+		return
+	}
+
+	if result != "running" {
+		run.EndF = now
+	}
+
+	if err != nil {
+		run.ErrorF = err.Error()
+	}
+}
+
+// SetAppExpectation registers the given expectation for the given app,
+// replacing any expectation already registered by the app with the same VM and
+// name. The updated time is set to now if not provided.
+func (this *ExperimentStatus) SetAppExpectation(a string, e ifaces.Expectation) {
+	if this.ExpectationsF == nil {
+		this.ExpectationsF = make(map[string][]*Expectation)
+	}
+
+	expect := &Expectation{
+		VMF:       e.VM(),
+		NameF:     e.Name(),
+		SeverityF: e.Severity(),
+		MetF:      e.Met(),
+		MessageF:  e.Message(),
+		UpdatedF:  e.Updated(),
+	}
+
+	if expect.UpdatedF == "" {
+		expect.UpdatedF = time.Now().Format(time.RFC3339)
+	}
+
+	for i, existing := range this.ExpectationsF[a] {
+		if existing.VMF == expect.VMF && existing.NameF == expect.NameF {
+			this.ExpectationsF[a][i] = expect
+			return
+		}
+	}
+
+	this.ExpectationsF[a] = append(this.ExpectationsF[a], expect)
+}
+
+func (this *ExperimentStatus) ClearAppExpectations(a string) {
+	delete(this.ExpectationsF, a)
+}
+
+// SetAppArtifact registers the given artifact for the given app, replacing any
+// artifact already registered by the app with the same name. The created time
+// is set to now if not provided.
+func (this *ExperimentStatus) SetAppArtifact(a string, art ifaces.Artifact) {
+	if this.ArtifactsF == nil {
+		this.ArtifactsF = make(map[string][]*Artifact)
+	}
+
+	artifact := &Artifact{
+		NameF:        art.Name(),
+		PathF:        art.Path(),
+		MIMETypeF:    art.MIMEType(),
+		DescriptionF: art.Description(),
+		CreatedF:     art.Created(),
+	}
+
+	if artifact.CreatedF == "" {
+		artifact.CreatedF = time.Now().Format(time.RFC3339)
+	}
+
+	for i, existing := range this.ArtifactsF[a] {
+		if existing.NameF == artifact.NameF {
+			this.ArtifactsF[a][i] = artifact
+			return
+		}
+	}
+
+	this.ArtifactsF[a] = append(this.ArtifactsF[a], artifact)
+}
+
+func (this *ExperimentStatus) ClearAppArtifacts(a string) {
+	delete(this.ArtifactsF, a)
+}
+
+func (this *ExperimentStatus) SetStarting(s string) {
+	this.StartingF = s
+}
+
+func (this *ExperimentStatus) SetDeferred(d []string) {
+	this.DeferredF = d
+}
+
+func (this *ExperimentStatus) SetPersistentDisk(node, host string) {
+	if this.PersistentDisksF == nil {
+		this.PersistentDisksF = make(map[string]string)
+	}
+
+	this.PersistentDisksF[node] = host
+}
+
+func (this *ExperimentStatus) ClearPersistentDisk(node string) {
+	delete(this.PersistentDisksF, node)
+}
+
 func (this *ExperimentStatus) SetVLANs(v map[string]int) {
 	if this.VLANsF == nil {
 		this.VLANsF = make(map[string]int)
@@ -450,4 +992,7 @@ func (this *ExperimentStatus) ResetAppStatus() {
 
 	this.FrequencyF = nil
 	this.RunningF = nil
+	this.SkippedF = nil
+	this.VersionsF = nil
+	this.ExpectationsF = nil
 }
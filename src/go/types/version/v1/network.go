@@ -250,6 +250,10 @@ func (this *Interface) SetMAC(mac string) {
 	this.MACF = mac
 }
 
+func (this *Interface) SetDriver(driver string) {
+	this.DriverF = driver
+}
+
 func (this *Interface) SetMTU(mtu int) {
 	this.MTUF = mtu
 }
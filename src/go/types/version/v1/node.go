@@ -20,7 +20,10 @@ type Node struct {
 	InjectionsF  []*Injection           `json:"injections" yaml:"injections" structs:"injections" mapstructure:"injections"`
 	AdvancedF    map[string]string      `json:"advanced" yaml:"advanced" structs:"advanced" mapstructure:"advanced"`
 	OverridesF   map[string]string      `json:"overrides" yaml:"overrides" structs:"overrides" mapstructure:"overrides"`
+	QEMUAppendF  []string               `json:"qemu_append" yaml:"qemu_append" structs:"qemu_append" mapstructure:"qemu_append"`
 	DelayF       *Delay                 `json:"delay" yaml:"delay" structs:"delay" mapstructure:"delay"`
+	KernelF      *Kernel                `json:"kernel" yaml:"kernel" structs:"kernel" mapstructure:"kernel"`
+	LocaleF      *Locale                `json:"locale" yaml:"locale" structs:"locale" mapstructure:"locale"`
 	CommandsF    []string               `json:"commands" yaml:"commands" structs:"commands" mapstructure:"commands"`
 	ExternalF    *bool                  `json:"external" yaml:"external" structs:"external" mapstructure:"external"`
 }
@@ -67,6 +70,22 @@ func (this Node) Delay() ifaces.NodeDelay {
 	return this.DelayF
 }
 
+func (this Node) Kernel() ifaces.NodeKernel {
+	if this.KernelF == nil {
+		return new(Kernel)
+	}
+
+	return this.KernelF
+}
+
+func (this Node) Locale() ifaces.LocaleSpec {
+	if this.LocaleF == nil {
+		return new(Locale)
+	}
+
+	return this.LocaleF
+}
+
 func (this Node) Advanced() map[string]string {
 	return this.AdvancedF
 }
@@ -75,6 +94,10 @@ func (this Node) Overrides() map[string]string {
 	return this.OverridesF
 }
 
+func (this Node) QEMUAppend() []string {
+	return this.QEMUAppendF
+}
+
 func (this Node) Commands() []string {
 	return this.CommandsF
 }
@@ -257,6 +280,10 @@ type General struct {
 	VMTypeF      string `json:"vm_type" yaml:"vm_type" structs:"vm_type" mapstructure:"vm_type"`
 	SnapshotF    *bool  `json:"snapshot" yaml:"snapshot" structs:"snapshot" mapstructure:"snapshot"`
 	DoNotBootF   *bool  `json:"do_not_boot" yaml:"do_not_boot" structs:"do_not_boot" mapstructure:"do_not_boot"`
+
+	// Used to keep the node's writable disk across experiment restarts instead
+	// of discarding changes made to it when the experiment is stopped.
+	PersistentF *bool `json:"persistent,omitempty" yaml:"persistent,omitempty" structs:"persistent,omitempty" mapstructure:"persistent,omitempty"`
 }
 
 func (this *General) Hostname() string {
@@ -317,12 +344,30 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+func (this *General) Persistent() *bool {
+	if this == nil {
+		return nil
+	}
+
+	if this.PersistentF == nil {
+		persistent := false
+		return &persistent
+	}
+
+	return this.PersistentF
+}
+
+func (this *General) SetPersistent(b bool) {
+	this.PersistentF = &b
+}
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
 	MemoryF int      `json:"memory" yaml:"memory" structs:"memory" mapstructure:"memory"`
 	OSTypeF string   `json:"os_type" yaml:"os_type" structs:"os_type" mapstructure:"os_type"`
 	DrivesF []*Drive `json:"drives" yaml:"drives" structs:"drives" mapstructure:"drives"`
+	PXEF    *PXE     `json:"pxe" yaml:"pxe" structs:"pxe" mapstructure:"pxe"`
 }
 
 func (this *Hardware) CPU() string {
@@ -371,6 +416,14 @@ func (this *Hardware) Drives() []ifaces.NodeDrive {
 	return drives
 }
 
+func (this *Hardware) PXE() ifaces.NodePXE {
+	if this == nil || this.PXEF == nil {
+		return nil
+	}
+
+	return this.PXEF
+}
+
 func (this *Hardware) SetVCPU(v int) {
 	this.VCPUF = v
 }
@@ -426,6 +479,33 @@ func (this *Drive) SetInjectPartition(p *int) {
 	this.InjectPartitionF = p
 }
 
+type PXE struct {
+	KernelF   string `json:"kernel" yaml:"kernel" structs:"kernel" mapstructure:"kernel"`
+	InitrdF   string `json:"initrd" yaml:"initrd" structs:"initrd" mapstructure:"initrd"`
+	AppendF   string `json:"append" yaml:"append" structs:"append" mapstructure:"append"`
+	ProtocolF string `json:"protocol" yaml:"protocol" structs:"protocol" mapstructure:"protocol"`
+}
+
+func (this PXE) Kernel() string {
+	return this.KernelF
+}
+
+func (this PXE) Initrd() string {
+	return this.InitrdF
+}
+
+func (this PXE) Append() string {
+	return this.AppendF
+}
+
+func (this PXE) Protocol() string {
+	if this.ProtocolF == "" {
+		return "tftp"
+	}
+
+	return this.ProtocolF
+}
+
 type Injection struct {
 	SrcF         string `json:"src" yaml:"src" structs:"src" mapstructure:"src"`
 	DstF         string `json:"dst" yaml:"dst" structs:"dst" mapstructure:"dst"`
@@ -538,6 +618,37 @@ func (this Delay) C2() []ifaces.NodeC2Delay {
 	return delays
 }
 
+type Kernel struct {
+	ArgsF   []string          `json:"args" yaml:"args" structs:"args" mapstructure:"args"`
+	SysctlF map[string]string `json:"sysctl" yaml:"sysctl" structs:"sysctl" mapstructure:"sysctl"`
+}
+
+func (this Kernel) Args() []string {
+	return this.ArgsF
+}
+
+func (this Kernel) Sysctl() map[string]string {
+	return this.SysctlF
+}
+
+type Locale struct {
+	LocaleF   string `json:"locale,omitempty" yaml:"locale,omitempty" structs:"locale" mapstructure:"locale"`
+	TimezoneF string `json:"timezone,omitempty" yaml:"timezone,omitempty" structs:"timezone" mapstructure:"timezone"`
+	KeyboardF string `json:"keyboard,omitempty" yaml:"keyboard,omitempty" structs:"keyboard" mapstructure:"keyboard"`
+}
+
+func (this Locale) Locale() string {
+	return this.LocaleF
+}
+
+func (this Locale) Timezone() string {
+	return this.TimezoneF
+}
+
+func (this Locale) Keyboard() string {
+	return this.KeyboardF
+}
+
 type C2Delay struct {
 	HostnameF string `json:"hostname" yaml:"hostname" structs:"hostname" mapstructure:"hostname"`
 	UseUUIDF  bool   `json:"useUUID" yaml:"useUUID" structs:"useUUID" mapstructure:"useUUID"`
@@ -0,0 +1,10 @@
+package v1
+
+// OverlaySpec is an environment specific patch for a base topology or scenario
+// config (ie. `topology/range`). When an experiment is created for the
+// environment, the patch is merged into the base config before it's used.
+type OverlaySpec struct {
+	Base        string                 `yaml:"base" json:"base" structs:"base" mapstructure:"base"`
+	Environment string                 `yaml:"environment" json:"environment" structs:"environment" mapstructure:"environment"`
+	Patch       map[string]interface{} `yaml:"patch" json:"patch" structs:"patch" mapstructure:"patch"`
+}
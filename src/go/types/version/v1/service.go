@@ -0,0 +1,21 @@
+package v1
+
+// ServiceSpec is a cluster-level shared service network (ie. a common
+// "internet" enclave or logging stack) that multiple experiments can attach
+// gateway nodes to. The VLAN is reserved cluster-wide by phenix while at least
+// one experiment is attached to the service.
+type ServiceSpec struct {
+	Description string      `yaml:"description" json:"description" structs:"description" mapstructure:"description"`
+	VLAN        int         `yaml:"vlan" json:"vlan" structs:"vlan" mapstructure:"vlan"`
+	Bridge      string      `yaml:"bridge" json:"bridge" structs:"bridge" mapstructure:"bridge"`
+	Subnet      string      `yaml:"subnet" json:"subnet" structs:"subnet" mapstructure:"subnet"`
+	Tap         *ServiceTap `yaml:"tap,omitempty" json:"tap,omitempty" structs:"tap,omitempty" mapstructure:"tap,omitempty"`
+}
+
+// ServiceTap is an optional host tap created on the head node while the
+// shared service is in use, providing the service network with a host
+// interface and (optionally) external access.
+type ServiceTap struct {
+	IP             string `yaml:"ip" json:"ip" structs:"ip" mapstructure:"ip"`
+	ExternalAccess bool   `yaml:"externalAccess" json:"externalAccess" structs:"externalAccess" mapstructure:"externalAccess"`
+}
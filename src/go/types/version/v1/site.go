@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SiteSpec holds cluster-level site settings (upstream DNS servers, NTP
+// sources, HTTP proxy, domain suffix, and package mirror) that default apps and
+// the image builder use automatically, so they don't have to be repeated in
+// every topology and image config.
+type SiteSpec struct {
+	DNS    []string    `yaml:"dns" json:"dns" structs:"dns" mapstructure:"dns"`
+	NTP    []string    `yaml:"ntp" json:"ntp" structs:"ntp" mapstructure:"ntp"`
+	Proxy  *SiteProxy  `yaml:"proxy,omitempty" json:"proxy,omitempty" structs:"proxy,omitempty" mapstructure:"proxy,omitempty"`
+	Domain string      `yaml:"domain" json:"domain" structs:"domain" mapstructure:"domain"`
+	Mirror *SiteMirror `yaml:"mirror,omitempty" json:"mirror,omitempty" structs:"mirror,omitempty" mapstructure:"mirror,omitempty"`
+}
+
+type SiteProxy struct {
+	HTTP    string   `yaml:"http" json:"http" structs:"http" mapstructure:"http"`
+	HTTPS   string   `yaml:"https" json:"https" structs:"https" mapstructure:"https"`
+	NoProxy []string `yaml:"noProxy" json:"noProxy" structs:"noProxy" mapstructure:"noProxy"`
+}
+
+// Env returns the proxy settings as environment variables, in both lower and
+// upper case since tools differ on which they honor.
+func (this SiteProxy) Env() []string {
+	var env []string
+
+	add := func(name, value string) {
+		if value != "" {
+			env = append(env, name+"="+value, strings.ToUpper(name)+"="+value)
+		}
+	}
+
+	add("http_proxy", this.HTTP)
+	add("https_proxy", this.HTTPS)
+	add("no_proxy", strings.Join(this.NoProxy, ","))
+
+	return env
+}
+
+// SiteMirror is the in-cluster package mirror (ie. served by `phenix mirror
+// serve`) guests are pointed at to install packages in air-gapped ranges. URL
+// is the base URL guests reach the mirror at, which serves each repository
+// type from its own directory (`apt`, `yum`, `pip`, and `choco`). Insecure
+// disables signature checks, for mirrors of unsigned packages.
+type SiteMirror struct {
+	URL      string `yaml:"url" json:"url" structs:"url" mapstructure:"url"`
+	Insecure bool   `yaml:"insecure" json:"insecure" structs:"insecure" mapstructure:"insecure"`
+}
+
+// Apt returns the URL of the mirror's apt repository.
+func (this SiteMirror) Apt() string {
+	return this.repo("apt")
+}
+
+// Yum returns the URL of the mirror's yum repository.
+func (this SiteMirror) Yum() string {
+	return this.repo("yum")
+}
+
+// Pip returns the URL of the mirror's pip (PEP 503) package index.
+func (this SiteMirror) Pip() string {
+	return this.repo("pip/simple")
+}
+
+// Choco returns the URL of the mirror's chocolatey package source.
+func (this SiteMirror) Choco() string {
+	return this.repo("choco")
+}
+
+// Host returns the host (without port) of the mirror's URL.
+func (this SiteMirror) Host() string {
+	u, err := url.Parse(this.URL)
+	if err != nil {
+		return ""
+	}
+
+	return u.Hostname()
+}
+
+func (this SiteMirror) repo(path string) string {
+	return strings.TrimSuffix(this.URL, "/") + "/" + path + "/"
+}
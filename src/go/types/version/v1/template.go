@@ -0,0 +1,24 @@
+package v1
+
+// TemplateSpec is an admin-approved experiment template. Users allowed to
+// instantiate a template can only create experiments using one of the
+// template's topologies and scenarios, and the resulting experiment must fall
+// within the template's limits.
+type TemplateSpec struct {
+	Description string         `yaml:"description" json:"description" structs:"description" mapstructure:"description"`
+	Topologies  []string       `yaml:"topologies" json:"topologies" structs:"topologies" mapstructure:"topologies"`
+	Scenarios   []string       `yaml:"scenarios" json:"scenarios" structs:"scenarios" mapstructure:"scenarios"`
+	VLANMin     int            `yaml:"vlanMin" json:"vlanMin" structs:"vlanMin" mapstructure:"vlanMin"`
+	VLANMax     int            `yaml:"vlanMax" json:"vlanMax" structs:"vlanMax" mapstructure:"vlanMax"`
+	Limits      TemplateLimits `yaml:"limits" json:"limits" structs:"limits" mapstructure:"limits"`
+}
+
+type TemplateLimits struct {
+	// MaxVMs is the maximum number of VMs the experiment can boot. Zero means
+	// no limit.
+	MaxVMs int `yaml:"maxVMs" json:"maxVMs" structs:"maxVMs" mapstructure:"maxVMs"`
+
+	// AllowedImages are file globs (ie. `ubuntu-*.qc2`) matched against the
+	// base name of each VM disk image. An empty list allows all images.
+	AllowedImages []string `yaml:"allowedImages" json:"allowedImages" structs:"allowedImages" mapstructure:"allowedImages"`
+}
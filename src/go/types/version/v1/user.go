@@ -8,4 +8,25 @@ type UserSpec struct {
 	Role      *RoleSpec `yaml:"rbac" json:"rbac" structs:"rbac" mapstructure:"rbac"`
 
 	Tokens map[string]string `yaml:"tokens" json:"tokens" structs:"tokens" mapstructure:"tokens"`
+
+	Preferences *UserPreferences `yaml:"preferences,omitempty" json:"preferences,omitempty" structs:"preferences" mapstructure:"preferences"`
+}
+
+// UserPreferences are the personalized defaults for a user, stored
+// server-side so they're consistent across the CLI and UI on any machine.
+type UserPreferences struct {
+	DefaultNamespace string      `yaml:"defaultNamespace,omitempty" json:"default_namespace,omitempty" structs:"default_namespace" mapstructure:"default_namespace"`
+	OutputFormat     string      `yaml:"outputFormat,omitempty" json:"output_format,omitempty" structs:"output_format" mapstructure:"output_format"`
+	Views            []SavedView `yaml:"views,omitempty" json:"views,omitempty" structs:"views" mapstructure:"views"`
+}
+
+// SavedView is a named set of filters, columns and sorting for a table (ie.
+// the VM table) saved by a user.
+type SavedView struct {
+	Name           string   `yaml:"name" json:"name" structs:"name" mapstructure:"name"`
+	Table          string   `yaml:"table" json:"table" structs:"table" mapstructure:"table"`
+	Filter         string   `yaml:"filter,omitempty" json:"filter,omitempty" structs:"filter" mapstructure:"filter"`
+	Columns        []string `yaml:"columns,omitempty" json:"columns,omitempty" structs:"columns" mapstructure:"columns"`
+	SortColumn     string   `yaml:"sortColumn,omitempty" json:"sort_column,omitempty" structs:"sort_column" mapstructure:"sort_column"`
+	SortDescending bool     `yaml:"sortDescending,omitempty" json:"sort_descending,omitempty" structs:"sort_descending" mapstructure:"sort_descending"`
 }
@@ -0,0 +1,41 @@
+package v1
+
+import "path/filepath"
+
+// ViewSpec scopes the experiments matching Experiments (which can be glob
+// patterns) to the topology nodes on the given team (ie. labeled `team: blue`)
+// and with all the given labels, for users whose role is one of Roles. This
+// lets several teams share an exercise experiment while each only sees and
+// controls its own VMs.
+type ViewSpec struct {
+	Experiments []string          `yaml:"experiments" json:"experiments" structs:"experiments" mapstructure:"experiments"`
+	Team        string            `yaml:"team,omitempty" json:"team,omitempty" structs:"team,omitempty" mapstructure:"team,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty" structs:"labels,omitempty" mapstructure:"labels,omitempty"`
+	Roles       []string          `yaml:"roles" json:"roles" structs:"roles" mapstructure:"roles"`
+}
+
+// Includes returns true if the given experiment is scoped by the view.
+func (this ViewSpec) Includes(exp string) bool {
+	for _, e := range this.Experiments {
+		if matched, _ := filepath.Match(e, exp); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Selects returns true if a node with the given labels is part of the view.
+func (this ViewSpec) Selects(labels map[string]string) bool {
+	if this.Team != "" && labels["team"] != this.Team {
+		return false
+	}
+
+	for k, v := range this.Labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
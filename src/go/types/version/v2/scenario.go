@@ -2,6 +2,9 @@ package v2
 
 import (
 	"fmt"
+	"path"
+	"strings"
+
 	ifaces "phenix/types/interfaces"
 
 	"github.com/mitchellh/mapstructure"
@@ -40,13 +43,18 @@ func (this *ScenarioSpec) App(name string) ifaces.ScenarioApp {
 }
 
 type ScenarioApp struct {
-	NameF            string             `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
-	FromScenarioF    string             `json:"fromScenario,omitempty" yaml:"fromScenario,omitempty" structs:"fromScenario" mapstructure:"fromScenario"`
-	AssetDirF        string             `json:"assetDir,omitempty" yaml:"assetDir,omitempty" structs:"assetDir" mapstructure:"assetDir"`
-	MetadataF        map[string]any     `json:"metadata,omitempty" yaml:"metadata,omitempty" structs:"metadata" mapstructure:"metadata"`
-	HostsF           []*ScenarioAppHost `json:"hosts,omitempty" yaml:"hosts,omitempty" structs:"hosts" mapstructure:"hosts"`
-	RunPeriodicallyF string             `json:"runPeriodically,omitempty" yaml:"runPeriodically,omitempty" structs:"runPeriodically" mapstructure:"runPeriodically"`
-	DisabledF        bool               `json:"disabled,omitempty" yaml:"disabled,omitempty" structs:"disabled" mapstructure:"disabled"`
+	NameF              string              `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	FromScenarioF      string              `json:"fromScenario,omitempty" yaml:"fromScenario,omitempty" structs:"fromScenario" mapstructure:"fromScenario"`
+	AssetDirF          string              `json:"assetDir,omitempty" yaml:"assetDir,omitempty" structs:"assetDir" mapstructure:"assetDir"`
+	MetadataF          map[string]any      `json:"metadata,omitempty" yaml:"metadata,omitempty" structs:"metadata" mapstructure:"metadata"`
+	HostsF             []*ScenarioAppHost  `json:"hosts,omitempty" yaml:"hosts,omitempty" structs:"hosts" mapstructure:"hosts"`
+	TargetsF           *ScenarioAppTargets `json:"targets,omitempty" yaml:"targets,omitempty" structs:"targets" mapstructure:"targets"`
+	RunPeriodicallyF   string              `json:"runPeriodically,omitempty" yaml:"runPeriodically,omitempty" structs:"runPeriodically" mapstructure:"runPeriodically"`
+	DisabledF          bool                `json:"disabled,omitempty" yaml:"disabled,omitempty" structs:"disabled" mapstructure:"disabled"`
+	OptionalF          bool                `json:"optional,omitempty" yaml:"optional,omitempty" structs:"optional" mapstructure:"optional"`
+	VersionF           string              `json:"version,omitempty" yaml:"version,omitempty" structs:"version" mapstructure:"version"`
+	WeightF            int                 `json:"weight,omitempty" yaml:"weight,omitempty" structs:"weight" mapstructure:"weight"`
+	RunBeforeDefaultsF bool                `json:"runBeforeDefaults,omitempty" yaml:"runBeforeDefaults,omitempty" structs:"runBeforeDefaults" mapstructure:"runBeforeDefaults"`
 }
 
 func (this ScenarioApp) Name() string {
@@ -75,6 +83,14 @@ func (this ScenarioApp) Hosts() []ifaces.ScenarioAppHost {
 	return hosts
 }
 
+func (this ScenarioApp) Targets() ifaces.ScenarioAppTargets {
+	if this.TargetsF == nil {
+		return nil
+	}
+
+	return this.TargetsF
+}
+
 func (this ScenarioApp) RunPeriodically() string {
 	return this.RunPeriodicallyF
 }
@@ -83,6 +99,22 @@ func (this ScenarioApp) Disabled() bool {
 	return this.DisabledF
 }
 
+func (this ScenarioApp) Optional() bool {
+	return this.OptionalF
+}
+
+func (this ScenarioApp) Version() string {
+	return this.VersionF
+}
+
+func (this ScenarioApp) Weight() int {
+	return this.WeightF
+}
+
+func (this ScenarioApp) RunBeforeDefaults() bool {
+	return this.RunBeforeDefaultsF
+}
+
 func (this *ScenarioApp) SetAssetDir(dir string) {
 	this.AssetDirF = dir
 }
@@ -109,6 +141,22 @@ func (this *ScenarioApp) SetDisabled(d bool) {
 	this.DisabledF = d
 }
 
+func (this *ScenarioApp) SetOptional(o bool) {
+	this.OptionalF = o
+}
+
+func (this *ScenarioApp) SetVersion(v string) {
+	this.VersionF = v
+}
+
+func (this *ScenarioApp) SetWeight(w int) {
+	this.WeightF = w
+}
+
+func (this *ScenarioApp) SetRunBeforeDefaults(b bool) {
+	this.RunBeforeDefaultsF = b
+}
+
 func (this ScenarioApp) ParseMetadata(md any) error {
 	if this.MetadataF == nil {
 		return fmt.Errorf("missing metadata for app %s", this.NameF)
@@ -135,6 +183,87 @@ func (this ScenarioApp) ParseHostMetadata(name string, md any) error {
 	return fmt.Errorf("missing host %s for app %s", name, this.NameF)
 }
 
+// ScenarioAppTargets selects the topology nodes a scenario app acts on. A node
+// is targeted if its hostname matches one of the hostnames (which can be glob
+// patterns), it has all of the labels (an empty label value matches any value),
+// and its type is one of the types. Criteria that aren't set match every node.
+type ScenarioAppTargets struct {
+	HostnamesF []string          `json:"hostnames,omitempty" yaml:"hostnames,omitempty" structs:"hostnames" mapstructure:"hostnames"`
+	LabelsF    map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" structs:"labels" mapstructure:"labels"`
+	TypesF     []string          `json:"types,omitempty" yaml:"types,omitempty" structs:"types" mapstructure:"types"`
+}
+
+func (this *ScenarioAppTargets) Hostnames() []string {
+	if this == nil {
+		return nil
+	}
+
+	return this.HostnamesF
+}
+
+func (this *ScenarioAppTargets) Labels() map[string]string {
+	if this == nil {
+		return nil
+	}
+
+	return this.LabelsF
+}
+
+func (this *ScenarioAppTargets) Types() []string {
+	if this == nil {
+		return nil
+	}
+
+	return this.TypesF
+}
+
+func (this *ScenarioAppTargets) Match(node ifaces.NodeSpec) bool {
+	if this == nil {
+		return true
+	}
+
+	if len(this.HostnamesF) > 0 {
+		var matched bool
+
+		for _, h := range this.HostnamesF {
+			if ok, _ := path.Match(h, node.General().Hostname()); ok {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	labels := node.Labels()
+
+	for k, v := range this.LabelsF {
+		l, ok := labels[k]
+		if !ok || (v != "" && l != v) {
+			return false
+		}
+	}
+
+	if len(this.TypesF) > 0 {
+		var matched bool
+
+		for _, t := range this.TypesF {
+			if strings.EqualFold(t, node.Type()) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 type ScenarioAppHost struct {
 	HostnameF string         `json:"hostname" yaml:"hostname" structs:"hostname" mapstructure:"hostname"`
 	MetadataF map[string]any `json:"metadata" yaml:"metadata" structs:"metadata" mapstructure:"metadata"`
@@ -132,6 +132,239 @@ components:
         username:
           type: string
           example: johndoe@example.com
+    Template:
+      type: object
+      required:
+      - topologies
+      properties:
+        description:
+          type: string
+          example: Intro to networking lab
+        topologies:
+          type: array
+          minItems: 1
+          items:
+            type: string
+          example:
+          - intro-topo
+        scenarios:
+          type: array
+          items:
+            type: string
+          example:
+          - intro-scenario
+        vlanMin:
+          type: integer
+          minimum: 0
+          example: 100
+        vlanMax:
+          type: integer
+          minimum: 0
+          example: 200
+        limits:
+          type: object
+          properties:
+            maxVMs:
+              type: integer
+              minimum: 0
+              example: 10
+            allowedImages:
+              type: array
+              items:
+                type: string
+              example:
+              - ubuntu-*.qc2
+              - minirouter.qc2
+    App:
+      type: object
+      required:
+      - version
+      - executable
+      - checksum
+      properties:
+        description:
+          type: string
+          example: Configures foo on all VMs
+        version:
+          type: string
+          example: 1.2.0
+        source:
+          type: string
+          example: https://github.com/example/phenix-app-foo.git
+        executable:
+          type: string
+          example: /usr/local/bin/phenix-app-foo
+        checksum:
+          type: string
+          example: sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+        protocol:
+          type: string
+          enum:
+          - shell
+          - grpc
+        schema:
+          type: object
+        versions:
+          type: object
+          additionalProperties:
+            type: object
+            required:
+            - executable
+            - checksum
+            properties:
+              source:
+                type: string
+              executable:
+                type: string
+                example: /phenix/apps/foo/1.2.0/phenix-app-foo
+              checksum:
+                type: string
+              protocol:
+                type: string
+    Service:
+      type: object
+      required:
+      - vlan
+      - subnet
+      properties:
+        description:
+          type: string
+          example: Shared internet enclave
+        vlan:
+          type: integer
+          minimum: 1
+          maximum: 4094
+          example: 3000
+        bridge:
+          type: string
+          example: phenix
+        subnet:
+          type: string
+          example: 10.255.0.0/24
+        tap:
+          type: object
+          required:
+          - ip
+          properties:
+            ip:
+              type: string
+              example: 10.255.0.254/24
+            externalAccess:
+              type: boolean
+              default: false
+    Appliance:
+      type: object
+      required:
+      - disks
+      - node
+      properties:
+        description:
+          type: string
+          example: Vendor firewall appliance
+        source:
+          type: string
+          example: /tmp/firewall.ova
+        format:
+          type: string
+          enum:
+          - ova
+          - ovf
+          example: ova
+        disks:
+          type: array
+          items:
+            type: string
+          example:
+          - firewall.qc2
+        node:
+          $ref: '#/components/schemas/minimega_node'
+    Overlay:
+      type: object
+      required:
+      - base
+      - environment
+      - patch
+      properties:
+        base:
+          type: string
+          pattern: '^(topology|scenario)/[a-zA-Z0-9_@.-]+$'
+          example: topology/range
+        environment:
+          type: string
+          minLength: 1
+          example: site-a
+        patch:
+          type: object
+          additionalProperties: true
+    Site:
+      type: object
+      properties:
+        dns:
+          type: array
+          items:
+            type: string
+            example: 10.0.0.53
+        ntp:
+          type: array
+          items:
+            type: string
+            example: ntp.example.com
+        proxy:
+          type: object
+          nullable: true
+          properties:
+            http:
+              type: string
+              example: http://proxy.example.com:3128
+            https:
+              type: string
+              example: http://proxy.example.com:3128
+            noProxy:
+              type: array
+              items:
+                type: string
+                example: .example.com
+        domain:
+          type: string
+          example: lab.example.com
+        mirror:
+          type: object
+          required:
+          - url
+          properties:
+            url:
+              type: string
+              example: http://10.255.0.254:8080
+            insecure:
+              type: boolean
+              default: false
+    View:
+      type: object
+      required:
+      - experiments
+      - roles
+      properties:
+        experiments:
+          type: array
+          minItems: 1
+          items:
+            type: string
+            example: exercise-*
+        team:
+          type: string
+          example: blue
+        labels:
+          type: object
+          additionalProperties:
+            type: string
+          example:
+            team: blue
+        roles:
+          type: array
+          minItems: 1
+          items:
+            type: string
+            example: blue-team
     Topology:
       type: object
       required:
@@ -163,6 +396,12 @@ components:
               assetDir:
                 type: string
                 example: /phenix/topologies/example-topo/assets
+              weight:
+                type: integer
+                example: -10
+              runBeforeDefaults:
+                type: boolean
+                example: true
               metadata:
                 type: object
                 nullable: true
@@ -189,6 +428,27 @@ components:
                         setting0: true
                         setting1: 42
                         setting2: universe key
+              targets:
+                type: object
+                nullable: true
+                properties:
+                  hostnames:
+                    type: array
+                    items:
+                      type: string
+                      example: web-*
+                  labels:
+                    type: object
+                    additionalProperties:
+                      type: string
+                    example:
+                      team: blue
+                  types:
+                    type: array
+                    items:
+                      type: string
+                      example: Router
+                additionalProperties: false
     Experiment:
       type: object
       required:
@@ -227,6 +487,75 @@ components:
             type: string
           example:
             ADServer: compute1
+        skipStages:
+          type: object
+          nullable: true
+          additionalProperties:
+            type: array
+            items:
+              type: string
+              enum:
+              - configure
+              - pre-start
+              - post-start
+              - running
+              - cleanup
+          example:
+            soh:
+            - post-start
+        dependencies:
+          type: array
+          nullable: true
+          items:
+            type: object
+            required:
+            - type
+            - target
+            properties:
+              type:
+                type: string
+                enum:
+                - url
+                - mount
+                - experiment
+              target:
+                type: string
+                example: http://repo.example.com
+              timeout:
+                type: string
+                example: 5m
+        concurrencyGroups:
+          type: array
+          nullable: true
+          items:
+            type: string
+          example:
+          - hil-rig
+        externalBridges:
+          type: array
+          nullable: true
+          items:
+            type: object
+            required:
+            - name
+            properties:
+              name:
+                type: string
+                maxLength: 15
+                example: labfabric
+              vlans:
+                type: object
+                additionalProperties:
+                  type: integer
+                  minimum: 1
+                  maximum: 4094
+                example:
+                  SITE_MGMT: 310
+              noDestroy:
+                type: boolean
+                default: false
+        locale:
+          $ref: '#/components/schemas/locale'
     minimega_node:
       type: object
       required:
@@ -267,11 +596,20 @@ components:
               default: false
               example: false
               nullable: true
+            persistent:
+              type: boolean
+              default: false
+              example: false
+              nullable: true
         hardware:
           type: object
           required:
           - os_type
-          - drives
+          anyOf:
+          - required:
+            - drives
+          - required:
+            - pxe
           properties:
             cpu:
               type: string
@@ -293,9 +631,12 @@ components:
               type: string
               enum:
               - centos
+              - iosv
               - linux
               - minirouter
               - rhel
+              - veos
+              - vsrx
               - vyatta
               - vyos
               - windows
@@ -343,6 +684,30 @@ components:
                     default: 1
                     example: 2
                     nullable: true
+            pxe:
+              type: object
+              nullable: true
+              required:
+              - kernel
+              properties:
+                kernel:
+                  type: string
+                  minLength: 1
+                  example: /phenix/images/pxe/vmlinuz
+                initrd:
+                  type: string
+                  example: /phenix/images/pxe/initrd.img
+                append:
+                  type: string
+                  example: console=ttyS0 ip=dhcp
+                protocol:
+                  type: string
+                  enum:
+                  - tftp
+                  - http
+                  - ""
+                  default: tftp
+                  example: http
         network:
           type: object
           nullable: true
@@ -512,6 +877,27 @@ components:
               permissions:
                 type: string
                 example: '0664'
+        locale:
+          $ref: '#/components/schemas/locale'
+        kernel:
+          type: object
+          nullable: true
+          properties:
+            args:
+              type: array
+              nullable: true
+              items:
+                type: string
+              example:
+              - mitigations=off
+              - console=ttyS0
+            sysctl:
+              type: object
+              nullable: true
+              additionalProperties:
+                type: string
+              example:
+                net.ipv4.ip_forward: "1"
         delay:
           type: object
           nullable: true
@@ -536,6 +922,15 @@ components:
           nullable: true
           additionalProperties:
             type: string
+        qemu_append:
+          type: array
+          nullable: true
+          items:
+            type: string
+          example:
+          - -usb
+          - -device
+          - usb-host,vendorid=0x046d,productid=0xc52b
         commands:
           type: array
           nullable: true
@@ -643,6 +1038,19 @@ components:
                   vlan:
                     type: string
                     example: EXP-1
+    locale:
+      type: object
+      nullable: true
+      properties:
+        locale:
+          type: string
+          example: de_DE.UTF-8
+        timezone:
+          type: string
+          example: Europe/Berlin
+        keyboard:
+          type: string
+          example: de
     iface:
       type: object
       required:
@@ -23,6 +23,13 @@ var StoredVersion = map[string]string{
 	"Image":      "v1",
 	"User":       "v1",
 	"Role":       "v1",
+	"Template":   "v1",
+	"App":        "v1",
+	"Service":    "v1",
+	"Appliance":  "v1",
+	"Overlay":    "v1",
+	"Site":       "v1",
+	"View":       "v1",
 	"Node":       "v1",
 	"Ruleset":    "v1",
 }
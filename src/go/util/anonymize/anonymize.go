@@ -0,0 +1,266 @@
+// Package anonymize consistently remaps IP addresses, MAC addresses, and
+// hostnames in exported artifacts (text files and packet captures) using a key
+// file, so datasets can be shared without leaking internal addressing.
+package anonymize
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"inet.af/netaddr"
+)
+
+// KeySize is the size, in bytes, of generated anonymization keys.
+const KeySize = 32
+
+var ErrUnsupportedFile = errors.New("unsupported file type for anonymization")
+
+var (
+	// MAC addresses are matched before IPv6 addresses so they aren't mistaken
+	// for one another. IP addresses can include a prefix length.
+	addrRegex = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b|\b(?:\d{1,3}\.){3}\d{1,3}(?:/\d{1,2})?\b|(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{1,4}(?:/\d{1,3})?\b`)
+	macRegex  = regexp.MustCompile(`(?i)^[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}$`)
+)
+
+// LoadKey reads the hex encoded anonymization key from the given file,
+// generating a new random key and writing it to the file if it doesn't exist
+// yet. Using the same key file keeps remapping consistent across exports.
+func LoadKey(path string) ([]byte, error) {
+	body, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(body)))
+		if err != nil {
+			return nil, fmt.Errorf("decoding anonymization key %s: %w", path, err)
+		}
+
+		if len(key) == 0 {
+			return nil, fmt.Errorf("anonymization key %s is empty", path)
+		}
+
+		return key, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading anonymization key %s: %w", path, err)
+	}
+
+	key := make([]byte, KeySize)
+
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating anonymization key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating anonymization key directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("writing anonymization key %s: %w", path, err)
+	}
+
+	return key, nil
+}
+
+// Anonymizer consistently remaps IP addresses, MAC addresses, and hostnames
+// using a key. IP addresses are remapped in a prefix-preserving manner, so
+// addresses in the same subnet before remapping are in the same (remapped)
+// subnet after. Special addresses (unspecified, loopback, link-local,
+// multicast, and broadcast) are left as-is.
+type Anonymizer struct {
+	key       []byte
+	hostnames *regexp.Regexp
+
+	sync.Mutex
+	ips map[netaddr.IP]netaddr.IP
+}
+
+// New returns an anonymizer using the given key that also remaps the given
+// hostnames (ie. the hostnames of the nodes in an experiment topology).
+func New(key []byte, hostnames ...string) *Anonymizer {
+	this := &Anonymizer{key: key, ips: make(map[netaddr.IP]netaddr.IP)}
+
+	var quoted []string
+
+	for _, h := range hostnames {
+		if h != "" {
+			quoted = append(quoted, regexp.QuoteMeta(h))
+		}
+	}
+
+	if len(quoted) > 0 {
+		// Longer hostnames first so a hostname that's a prefix of another doesn't
+		// win the match.
+		sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+
+		this.hostnames = regexp.MustCompile(`\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	}
+
+	return this
+}
+
+// IP returns the remapped version of the given IP address.
+func (this *Anonymizer) IP(ip netaddr.IP) netaddr.IP {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsMulticast() || ip.IsLinkLocalUnicast() || ip == netaddr.IPv4(255, 255, 255, 255) {
+		return ip
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if mapped, ok := this.ips[ip]; ok {
+		return mapped
+	}
+
+	var (
+		orig = ip.IPAddr().IP
+		out  = make([]byte, len(orig))
+	)
+
+	if ip.Is4() {
+		orig = orig.To4()
+		out = out[:4]
+	}
+
+	// Each output bit is the input bit flipped by a pseudorandom function of
+	// the input bits preceding it, which preserves prefixes (Crypto-PAn style).
+	for i := 0; i < len(orig)*8; i++ {
+		prefix := make([]byte, len(orig))
+
+		for b := 0; b < i; b++ {
+			prefix[b/8] |= orig[b/8] & (0x80 >> (b % 8))
+		}
+
+		mac := hmac.New(sha256.New, this.key)
+		mac.Write([]byte{byte(len(orig)), byte(i)})
+		mac.Write(prefix)
+
+		flip := mac.Sum(nil)[0] & 0x01
+
+		bit := (orig[i/8] >> (7 - i%8)) & 0x01
+		out[i/8] |= (bit ^ flip) << (7 - i%8)
+	}
+
+	mapped, _ := netaddr.FromStdIP(net.IP(out))
+	this.ips[ip] = mapped
+
+	return mapped
+}
+
+// MAC returns the remapped version of the given MAC address. Remapped
+// addresses are locally administered unicast addresses. Broadcast and
+// multicast addresses are left as-is.
+func (this *Anonymizer) MAC(mac net.HardwareAddr) net.HardwareAddr {
+	if len(mac) != 6 || mac[0]&0x01 == 0x01 || bytes.Equal(mac, make([]byte, 6)) {
+		return mac
+	}
+
+	h := hmac.New(sha256.New, this.key)
+	h.Write([]byte("mac"))
+	h.Write(mac)
+
+	mapped := net.HardwareAddr(h.Sum(nil)[:6])
+	mapped[0] = (mapped[0] | 0x02) &^ 0x01
+
+	return mapped
+}
+
+// Hostname returns the remapped version of the given hostname.
+func (this *Anonymizer) Hostname(name string) string {
+	h := hmac.New(sha256.New, this.key)
+	h.Write([]byte("host"))
+	h.Write([]byte(strings.ToLower(name)))
+
+	return "host-" + hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// Text remaps all the IP addresses, MAC addresses, and known hostnames in the
+// given text.
+func (this *Anonymizer) Text(data []byte) []byte {
+	data = addrRegex.ReplaceAllFunc(data, func(match []byte) []byte {
+		s := string(match)
+
+		if macRegex.MatchString(s) {
+			mac, err := net.ParseMAC(s)
+			if err != nil {
+				return match
+			}
+
+			mapped := this.MAC(mac).String()
+
+			if strings.Contains(s, "-") {
+				mapped = strings.ReplaceAll(mapped, ":", "-")
+			}
+
+			if s == strings.ToUpper(s) {
+				mapped = strings.ToUpper(mapped)
+			}
+
+			return []byte(mapped)
+		}
+
+		addr, bits, hasBits := strings.Cut(s, "/")
+
+		ip, err := netaddr.ParseIP(addr)
+		if err != nil {
+			return match
+		}
+
+		mapped := this.IP(ip)
+
+		if !hasBits {
+			return []byte(mapped.String())
+		}
+
+		n, err := strconv.Atoi(bits)
+		if err != nil || n > int(ip.BitLen()) {
+			return match
+		}
+
+		prefix, _ := mapped.Prefix(uint8(n))
+
+		return []byte(prefix.String())
+	})
+
+	if this.hostnames != nil {
+		data = this.hostnames.ReplaceAllFunc(data, func(match []byte) []byte {
+			return []byte(this.Hostname(string(match)))
+		})
+	}
+
+	return data
+}
+
+// File remaps the given file contents based on the file's name. Files with a
+// `.pcap` extension are treated as packet captures and other files as text.
+// ErrUnsupportedFile is returned for binary files that aren't packet captures.
+func (this *Anonymizer) File(name string, data []byte) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(name), ".pcap") {
+		var buf bytes.Buffer
+
+		if err := this.PCAP(bytes.NewReader(data), &buf); err != nil {
+			return nil, fmt.Errorf("anonymizing packet capture %s: %w", name, err)
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, name)
+	}
+
+	return this.Text(data), nil
+}
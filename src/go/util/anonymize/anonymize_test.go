@@ -0,0 +1,196 @@
+package anonymize
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestIPPrefixPreserving(t *testing.T) {
+	a := New(testKey)
+
+	var (
+		x = a.IP(netaddr.MustParseIP("10.1.0.10"))
+		y = a.IP(netaddr.MustParseIP("10.1.0.20"))
+		z = a.IP(netaddr.MustParseIP("10.2.0.10"))
+	)
+
+	if x == netaddr.MustParseIP("10.1.0.10") {
+		t.Log("expected address to be remapped")
+		t.FailNow()
+	}
+
+	px, _ := x.Prefix(24)
+	py, _ := y.Prefix(24)
+	pz, _ := z.Prefix(24)
+
+	if px != py || px == pz {
+		t.Logf("expected remapping to preserve subnets, got %s %s %s", x, y, z)
+		t.FailNow()
+	}
+
+	if New(testKey).IP(netaddr.MustParseIP("10.1.0.10")) != x {
+		t.Log("expected remapping to be consistent for the same key")
+		t.FailNow()
+	}
+
+	if New([]byte("other")).IP(netaddr.MustParseIP("10.1.0.10")) == x {
+		t.Log("expected remapping to differ for a different key")
+		t.FailNow()
+	}
+
+	for _, ip := range []string{"127.0.0.1", "0.0.0.0", "255.255.255.255", "224.0.0.5", "fe80::1"} {
+		if a.IP(netaddr.MustParseIP(ip)).String() != ip {
+			t.Logf("expected special address %s to be left as-is", ip)
+			t.FailNow()
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	a := New(testKey, "rtu-1", "historian")
+
+	text := "rtu-1 (10.1.0.10/24, 00:16:3E:AA:BB:CC) polls historian at 10.1.0.20 on 10.1.0.0/24 via fd00::1 at 12:34:56"
+
+	out := string(a.Text([]byte(text)))
+
+	for _, leaked := range []string{"rtu-1", "historian", "10.1.0.", "00:16:3E:AA:BB:CC", "fd00::1"} {
+		if strings.Contains(out, leaked) {
+			t.Logf("expected %s to be anonymized: %s", leaked, out)
+			t.FailNow()
+		}
+	}
+
+	subnet, _ := a.IP(netaddr.MustParseIP("10.1.0.10")).Prefix(24)
+
+	if !strings.Contains(out, " on "+subnet.String()+" ") {
+		t.Logf("expected subnet to be remapped to %s: %s", subnet, out)
+		t.FailNow()
+	}
+
+	if !strings.Contains(out, a.Hostname("rtu-1")) || !strings.HasSuffix(out, "at 12:34:56") {
+		t.Logf("unexpected anonymized text: %s", out)
+		t.FailNow()
+	}
+}
+
+func TestPCAP(t *testing.T) {
+	a := New(testKey)
+
+	var (
+		srcMAC = net.HardwareAddr{0x00, 0x16, 0x3e, 0x00, 0x00, 0x01}
+		dstMAC = net.HardwareAddr{0x00, 0x16, 0x3e, 0x00, 0x00, 0x02}
+	)
+
+	// Ethernet + IPv4 + UDP with valid checksums.
+	frame := append(append([]byte{}, dstMAC...), srcMAC...)
+	frame = append(frame, 0x08, 0x00)
+
+	ip := []byte{0x45, 0, 0, 32, 0, 0, 0, 0, 64, 17, 0, 0, 10, 1, 0, 10, 10, 1, 0, 20}
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip))
+
+	udp := []byte{0x30, 0x39, 0x00, 0x35, 0, 12, 0, 0, 'p', 'i', 'n', 'g'}
+	binary.BigEndian.PutUint16(udp[6:], udpChecksum(ip[12:20], udp))
+
+	frame = append(frame, ip...)
+	frame = append(frame, udp...)
+
+	var capture bytes.Buffer
+
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeEthernet)
+	capture.Write(header)
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(frame)))
+	capture.Write(record)
+	capture.Write(frame)
+
+	out, err := a.File(filepath.Join("captures", "test.pcap"), capture.Bytes())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	packet := out[40:]
+
+	if !bytes.Equal(packet[6:12], a.MAC(srcMAC)) || bytes.Equal(packet[6:12], srcMAC) {
+		t.Logf("expected source MAC to be remapped, got %s", net.HardwareAddr(packet[6:12]))
+		t.FailNow()
+	}
+
+	var (
+		outIP  = packet[14:34]
+		outUDP = packet[34:]
+	)
+
+	if got, _ := netaddr.FromStdIP(net.IP(outIP[12:16])); got != a.IP(netaddr.MustParseIP("10.1.0.10")) {
+		t.Logf("expected source IP to be remapped, got %s", got)
+		t.FailNow()
+	}
+
+	if checksum(outIP) != 0 {
+		t.Log("expected valid IPv4 header checksum")
+		t.FailNow()
+	}
+
+	if binary.BigEndian.Uint16(outUDP[6:]) != udpChecksum(outIP[12:20], append(append([]byte{}, outUDP[:6]...), append([]byte{0, 0}, outUDP[8:]...)...)) {
+		t.Log("expected valid UDP checksum")
+		t.FailNow()
+	}
+
+	if _, err := a.File("test.pcap", []byte("not a capture at all!!!!")); err == nil {
+		t.Log("expected error for invalid capture")
+		t.FailNow()
+	}
+
+	if _, err := a.File("disk.qc2", []byte{0xff, 0xfe, 0x00}); err == nil {
+		t.Log("expected error for binary file")
+		t.FailNow()
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anonymize.key")
+
+	key, err := LoadKey(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(key) != KeySize {
+		t.Logf("expected generated key of size %d, got %d", KeySize, len(key))
+		t.FailNow()
+	}
+
+	again, err := LoadKey(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(key, again) {
+		t.Log("expected existing key to be loaded")
+		t.FailNow()
+	}
+}
+
+// udpChecksum computes the UDP checksum over the IPv4 pseudo-header for the
+// given addresses and the given segment (with a zero checksum field).
+func udpChecksum(addrs, segment []byte) uint16 {
+	pseudo := append([]byte{}, addrs...)
+	pseudo = append(pseudo, 0, 17, byte(len(segment)>>8), byte(len(segment)))
+	pseudo = append(pseudo, segment...)
+
+	return checksum(pseudo)
+}
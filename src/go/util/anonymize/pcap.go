@@ -0,0 +1,243 @@
+package anonymize
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"inet.af/netaddr"
+)
+
+const linkTypeEthernet = 1
+
+var ErrUnsupportedPCAP = errors.New("unsupported packet capture format")
+
+// PCAP remaps the MAC and IP addresses in the Ethernet, ARP, IPv4, and IPv6
+// headers of each packet in the given libpcap formatted capture, updating IP
+// header and TCP/UDP/ICMPv6 checksums to match. Only Ethernet captures (the
+// kind minimega creates) are supported.
+func (this *Anonymizer) PCAP(r io.Reader, w io.Writer) error {
+	header := make([]byte, 24)
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("reading capture header: %w", err)
+	}
+
+	var order binary.ByteOrder
+
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4, 0xa1b23c4d: // microsecond and nanosecond resolution
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		order = binary.BigEndian
+	default:
+		return fmt.Errorf("%w: not a libpcap capture", ErrUnsupportedPCAP)
+	}
+
+	if link := order.Uint32(header[20:]) & 0x0fffffff; link != linkTypeEthernet {
+		return fmt.Errorf("%w: link type %d", ErrUnsupportedPCAP, link)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("writing capture header: %w", err)
+	}
+
+	record := make([]byte, 16)
+
+	for {
+		if _, err := io.ReadFull(r, record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("reading packet header: %w", err)
+		}
+
+		packet := make([]byte, order.Uint32(record[8:]))
+
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return fmt.Errorf("reading packet: %w", err)
+		}
+
+		this.ethernet(packet)
+
+		if _, err := w.Write(record); err != nil {
+			return fmt.Errorf("writing packet header: %w", err)
+		}
+
+		if _, err := w.Write(packet); err != nil {
+			return fmt.Errorf("writing packet: %w", err)
+		}
+	}
+}
+
+// ethernet remaps the given Ethernet frame in place. Headers truncated by the
+// capture's snap length are left as-is.
+func (this *Anonymizer) ethernet(frame []byte) {
+	if len(frame) < 14 {
+		return
+	}
+
+	copy(frame[0:6], this.MAC(net.HardwareAddr(frame[0:6])))
+	copy(frame[6:12], this.MAC(net.HardwareAddr(frame[6:12])))
+
+	var (
+		etherType = binary.BigEndian.Uint16(frame[12:])
+		payload   = frame[14:]
+	)
+
+	// skip 802.1Q and 802.1ad VLAN tags
+	for (etherType == 0x8100 || etherType == 0x88a8) && len(payload) >= 4 {
+		etherType = binary.BigEndian.Uint16(payload[2:])
+		payload = payload[4:]
+	}
+
+	switch etherType {
+	case 0x0800:
+		this.ipv4(payload)
+	case 0x86dd:
+		this.ipv6(payload)
+	case 0x0806:
+		this.arp(payload)
+	}
+}
+
+func (this *Anonymizer) ipv4(packet []byte) {
+	if len(packet) < 20 {
+		return
+	}
+
+	ihl := int(packet[0]&0x0f) * 4
+
+	if ihl < 20 || len(packet) < ihl {
+		return
+	}
+
+	old := append([]byte(nil), packet[12:20]...)
+
+	this.replaceIP(packet[12:16])
+	this.replaceIP(packet[16:20])
+
+	binary.BigEndian.PutUint16(packet[10:], 0)
+	binary.BigEndian.PutUint16(packet[10:], checksum(packet[:ihl]))
+
+	// Only the first fragment of a packet includes the transport header.
+	if binary.BigEndian.Uint16(packet[6:])&0x1fff != 0 {
+		return
+	}
+
+	this.transport(packet[9], packet[ihl:], old, packet[12:20])
+}
+
+func (this *Anonymizer) ipv6(packet []byte) {
+	if len(packet) < 40 {
+		return
+	}
+
+	old := append([]byte(nil), packet[8:40]...)
+
+	this.replaceIP(packet[8:24])
+	this.replaceIP(packet[24:40])
+
+	// Extension headers aren't followed, so only transport headers directly
+	// following the IPv6 header have their checksums updated.
+	this.transport(packet[6], packet[40:], old, packet[8:40])
+}
+
+func (this *Anonymizer) arp(packet []byte) {
+	// Only Ethernet/IPv4 ARP is supported.
+	if len(packet) < 28 || binary.BigEndian.Uint16(packet[2:]) != 0x0800 || packet[4] != 6 || packet[5] != 4 {
+		return
+	}
+
+	copy(packet[8:14], this.MAC(net.HardwareAddr(packet[8:14])))
+	this.replaceIP(packet[14:18])
+	copy(packet[18:24], this.MAC(net.HardwareAddr(packet[18:24])))
+	this.replaceIP(packet[24:28])
+}
+
+// transport updates the checksum of the given transport segment to account for
+// the pseudo-header addresses changing.
+func (this *Anonymizer) transport(proto byte, segment, before, after []byte) {
+	var offset int
+
+	switch proto {
+	case 6: // TCP
+		offset = 16
+	case 17: // UDP
+		offset = 6
+	case 58: // ICMPv6
+		offset = 2
+	default:
+		return
+	}
+
+	if len(segment) < offset+2 {
+		return
+	}
+
+	sum := binary.BigEndian.Uint16(segment[offset:])
+
+	// A zero UDP checksum means no checksum was computed.
+	if proto == 17 && sum == 0 {
+		return
+	}
+
+	binary.BigEndian.PutUint16(segment[offset:], adjustChecksum(sum, before, after))
+}
+
+func (this *Anonymizer) replaceIP(b []byte) {
+	ip, ok := netaddr.FromStdIP(net.IP(b))
+	if !ok {
+		return
+	}
+
+	mapped := this.IP(ip).IPAddr().IP
+
+	if len(b) == 4 {
+		mapped = mapped.To4()
+	} else {
+		mapped = mapped.To16()
+	}
+
+	copy(b, mapped)
+}
+
+// checksum computes the Internet checksum (RFC 1071) of the given data.
+func checksum(data []byte) uint16 {
+	var sum uint32
+
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+
+	return ^uint16(sum)
+}
+
+// adjustChecksum incrementally updates the given checksum for the covered data
+// changing (RFC 1624). The data before and after must be the same, even,
+// length.
+func adjustChecksum(sum uint16, before, after []byte) uint16 {
+	acc := uint32(^sum)
+
+	for i := 0; i+1 < len(before); i += 2 {
+		acc += uint32(^binary.BigEndian.Uint16(before[i:]))
+		acc += uint32(binary.BigEndian.Uint16(after[i:]))
+	}
+
+	for acc > 0xffff {
+		acc = (acc >> 16) + (acc & 0xffff)
+	}
+
+	return ^uint16(acc)
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/util/common"
+	"phenix/util/journal"
+	"phenix/util/plog"
+)
+
+// Guards the audit log file.
+var mu sync.Mutex
+
+// Event is a single audit log record. Action is namespaced by the feature
+// recording the event (ie. `approval/request`), and Resource is what the
+// action was performed on (ie. `experiments/foo`).
+type Event struct {
+	Time     time.Time      `json:"time"`
+	User     string         `json:"user"`
+	Action   string         `json:"action"`
+	Resource string         `json:"resource"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// Record appends the given event to the audit log (see `common.AuditFile`).
+// The event time is set to now if not already set. Failures to write to the
+// audit log are logged but otherwise ignored so they don't prevent the
+// audited action.
+func Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	plog.Info("audit event", "user", e.User, "action", e.Action, "resource", e.Resource)
+
+	// Actions on experiments are also added to the experiment's timeline.
+	if exp, ok := strings.CutPrefix(e.Resource, "experiments/"); ok && exp != "" {
+		exp, _, _ = strings.Cut(exp, "/")
+		journal.Record(exp, journal.CategoryUser, e.User, "%s", e.Action)
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		plog.Error("marshaling audit event", "action", e.Action, "err", err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(common.AuditFile), 0755); err != nil {
+		plog.Error("creating audit log directory", "path", common.AuditFile, "err", err)
+		return
+	}
+
+	f, err := os.OpenFile(common.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		plog.Error("opening audit log", "path", common.AuditFile, "err", err)
+		return
+	}
+
+	defer f.Close()
+
+	if _, err := f.Write(append(body, '\n')); err != nil {
+		plog.Error("writing audit event", "path", common.AuditFile, "err", err)
+	}
+}
+
+// Filter selects the audit events returned by Query. Zero value fields match
+// every event.
+type Filter struct {
+	User string
+
+	// Action and Resource match events whose action or resource starts with the
+	// given value (ie. `app/` matches every app event).
+	Action   string
+	Resource string
+
+	// Experiment matches events on the given experiment, either as the event
+	// resource (ie. `experiments/foo/...`) or its `experiment` detail.
+	Experiment string
+
+	Since time.Time
+	Until time.Time
+
+	// Only return this many of the most recent matching events.
+	Limit int
+}
+
+// Query returns the events in the audit log (see `common.AuditFile`) that match
+// the given filter, oldest first. Malformed lines in the audit log are skipped.
+func Query(f Filter) ([]Event, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	file, err := os.Open(common.AuditFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("opening audit log %s: %w", common.AuditFile, err)
+	}
+
+	defer file.Close()
+
+	var (
+		events  []Event
+		scanner = bufio.NewScanner(file)
+	)
+
+	// Event details can make for long lines.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var e Event
+
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+
+		if f.matches(e) {
+			events = append(events, e)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log %s: %w", common.AuditFile, err)
+	}
+
+	if f.Limit > 0 && len(events) > f.Limit {
+		events = events[len(events)-f.Limit:]
+	}
+
+	return events, nil
+}
+
+func (this Filter) matches(e Event) bool {
+	if this.User != "" && e.User != this.User {
+		return false
+	}
+
+	if !strings.HasPrefix(e.Action, this.Action) || !strings.HasPrefix(e.Resource, this.Resource) {
+		return false
+	}
+
+	if this.Experiment != "" {
+		resource := "experiments/" + this.Experiment
+
+		if e.Resource != resource && !strings.HasPrefix(e.Resource, resource+"/") && e.Details["experiment"] != this.Experiment {
+			return false
+		}
+	}
+
+	if !this.Since.IsZero() && e.Time.Before(this.Since) {
+		return false
+	}
+
+	if !this.Until.IsZero() && e.Time.After(this.Until) {
+		return false
+	}
+
+	return true
+}
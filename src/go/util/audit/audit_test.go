@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"phenix/util/common"
+)
+
+func TestQuery(t *testing.T) {
+	common.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+
+	if events, err := Query(Filter{}); err != nil || events != nil {
+		t.Logf("expected no events or error for missing audit log, got %v, %v", events, err)
+		t.FailNow()
+	}
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	Record(Event{Time: start, User: "alice", Action: "app/configure", Resource: "apps/tuning", Details: map[string]any{"experiment": "foo"}})
+	Record(Event{Time: start.Add(time.Hour), User: "bob", Action: "app/pre-start", Resource: "apps/tuning", Details: map[string]any{"experiment": "bar"}})
+	Record(Event{Time: start.Add(2 * time.Hour), User: "alice", Action: "share/create", Resource: "experiments/foo/vms/vm-1"})
+
+	// Malformed lines are skipped.
+	f, _ := os.OpenFile(common.AuditFile, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("not json\n")
+	f.Close()
+
+	cases := map[string]struct {
+		filter   Filter
+		expected []string
+	}{
+		"all":        {Filter{}, []string{"app/configure", "app/pre-start", "share/create"}},
+		"user":       {Filter{User: "alice"}, []string{"app/configure", "share/create"}},
+		"action":     {Filter{Action: "app/"}, []string{"app/configure", "app/pre-start"}},
+		"experiment": {Filter{Experiment: "foo"}, []string{"app/configure", "share/create"}},
+		"since":      {Filter{Since: start.Add(30 * time.Minute)}, []string{"app/pre-start", "share/create"}},
+		"limit":      {Filter{Limit: 1}, []string{"share/create"}},
+	}
+
+	for name, c := range cases {
+		events, err := Query(c.filter)
+		if err != nil {
+			t.Logf("%s: %v", name, err)
+			t.FailNow()
+		}
+
+		if len(events) != len(c.expected) {
+			t.Logf("%s: expected %d events, got %+v", name, len(c.expected), events)
+			t.FailNow()
+		}
+
+		for i, e := range events {
+			if e.Action != c.expected[i] {
+				t.Logf("%s: expected event %d to be %s, got %s", name, i, c.expected[i], e.Action)
+				t.FailNow()
+			}
+		}
+	}
+}
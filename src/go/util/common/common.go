@@ -32,12 +32,33 @@ var (
 
 	LogFile    = "/var/log/phenix/phenix.log"
 	ErrorFile  = "/var/log/phenix/error.log"
+	AuditFile  = "/var/log/phenix/audit.log"
 	UnixSocket = "/tmp/phenix.sock"
 
 	StoreEndpoint    string
 	HostnameSuffixes string
 
 	UseGREMesh bool
+
+	// QEMUArgAllowlist is the list of QEMU arguments (ie. `-device`) topology
+	// nodes are allowed to pass to QEMU via `qemu_append`. If empty, no extra
+	// QEMU arguments are allowed.
+	QEMUArgAllowlist []string
+
+	// VMConfigAllowlist is the list of minimega `vm config` settings topology
+	// nodes are allowed to override via `advanced`. If empty, all settings are
+	// allowed.
+	VMConfigAllowlist []string
+
+	// SigningPolicy is the default signature verification mode (off, warn, or
+	// enforce) used when starting experiments. SigningNamespacePolicies
+	// overrides it for specific namespaces.
+	SigningPolicy            string
+	SigningNamespacePolicies map[string]string
+
+	// SigningPublicKeys is the list of paths to public keys trusted to sign
+	// configs and disk images.
+	SigningPublicKeys []string
 )
 
 func TrimHostnameSuffixes(str string) string {
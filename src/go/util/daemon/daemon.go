@@ -0,0 +1,335 @@
+// Package daemon supervises long-running helper processes (ie. traffic
+// generators or scoring engines) started by apps for an experiment. Each
+// daemon is run by a detached `phenix daemon supervise` process, so daemons
+// keep running (and are restarted per their restart policy) no matter which
+// phenix process started them. Daemons are tracked per experiment on disk and
+// reaped, along with any processes they spawned, when the experiment is
+// stopped or deleted.
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"phenix/util/common"
+)
+
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+var (
+	ErrDaemonExists   = errors.New("daemon already running")
+	ErrDaemonNotFound = errors.New("daemon not found")
+
+	// StopTimeout is how long daemons are given to exit after being sent
+	// SIGTERM before being killed.
+	StopTimeout = 10 * time.Second
+)
+
+// Spec describes a daemon to supervise.
+type Spec struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+
+	// Restart is the restart policy for the daemon. It defaults to on-failure.
+	Restart RestartPolicy `json:"restart,omitempty"`
+
+	// MaxRestarts is the maximum number of times the daemon will be restarted.
+	// Zero means there's no limit.
+	MaxRestarts int `json:"maxRestarts,omitempty"`
+
+	// Backoff is how long to wait before restarting the daemon. It defaults to
+	// 5s.
+	Backoff time.Duration `json:"backoff,omitempty"`
+
+	// LogFile is the file daemon output is appended to. It defaults to
+	// `<name>.log` in the experiment's daemon directory.
+	LogFile string `json:"logFile,omitempty"`
+}
+
+// Status is the supervision status of a daemon, written by its supervisor.
+type Status struct {
+	State    string    `json:"state"`
+	PID      int       `json:"pid,omitempty"`
+	Restarts int       `json:"restarts"`
+	LastExit string    `json:"lastExit,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Daemon is a daemon being supervised for an experiment.
+type Daemon struct {
+	Spec
+
+	Experiment    string    `json:"experiment"`
+	SupervisorPID int       `json:"supervisorPID"`
+	Started       time.Time `json:"started"`
+
+	Status Status `json:"status"`
+}
+
+// Running returns true if the daemon's supervisor is still running.
+func (this Daemon) Running() bool {
+	return alive(this.SupervisorPID)
+}
+
+// Start starts supervising the given daemon for the given experiment. An error
+// wrapping ErrDaemonExists is returned if a daemon with the same name is
+// already running for the experiment.
+func Start(exp string, spec Spec) (*Daemon, error) {
+	if spec.Name == "" || strings.ContainsAny(spec.Name, "/ ") {
+		return nil, fmt.Errorf("invalid daemon name %q", spec.Name)
+	}
+
+	if spec.Command == "" {
+		return nil, fmt.Errorf("no command provided for daemon %s", spec.Name)
+	}
+
+	switch spec.Restart {
+	case "":
+		spec.Restart = RestartOnFailure
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return nil, fmt.Errorf("invalid restart policy %s (expected never, on-failure, or always)", spec.Restart)
+	}
+
+	if spec.Backoff == 0 {
+		spec.Backoff = 5 * time.Second
+	}
+
+	if spec.LogFile == "" {
+		spec.LogFile = filepath.Join(dir(exp), spec.Name+".log")
+	}
+
+	if d, err := Get(exp, spec.Name); err == nil && d.Running() {
+		return nil, fmt.Errorf("%w: %s for experiment %s", ErrDaemonExists, spec.Name, exp)
+	}
+
+	if err := os.MkdirAll(dir(exp), 0755); err != nil {
+		return nil, fmt.Errorf("creating daemon directory: %w", err)
+	}
+
+	os.Remove(statusPath(exp, spec.Name))
+
+	phenix, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("getting phenix executable: %w", err)
+	}
+
+	body, _ := json.Marshal(spec)
+
+	cmd := exec.Command(phenix, "daemon", "supervise", statusPath(exp, spec.Name))
+	cmd.Stdin = bytes.NewReader(body)
+
+	// The supervisor gets its own session (and process group) so it isn't
+	// killed along with the process that started it, and so it and everything
+	// it spawns can be reaped together.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting supervisor for daemon %s: %w", spec.Name, err)
+	}
+
+	d := &Daemon{
+		Spec:          spec,
+		Experiment:    exp,
+		SupervisorPID: cmd.Process.Pid,
+		Started:       time.Now().UTC(),
+	}
+
+	// Reap the supervisor if it exits while this process is still running. If
+	// this process exits first, the supervisor is reparented to init.
+	go cmd.Wait()
+
+	body, _ = json.Marshal(d)
+
+	if err := os.WriteFile(recordPath(exp, spec.Name), body, 0644); err != nil {
+		return nil, fmt.Errorf("writing daemon record for %s: %w", spec.Name, err)
+	}
+
+	return d, nil
+}
+
+// Get returns the given daemon for the given experiment.
+func Get(exp, name string) (*Daemon, error) {
+	body, err := os.ReadFile(recordPath(exp, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s for experiment %s", ErrDaemonNotFound, name, exp)
+		}
+
+		return nil, fmt.Errorf("reading daemon record for %s: %w", name, err)
+	}
+
+	var d Daemon
+
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, fmt.Errorf("parsing daemon record for %s: %w", name, err)
+	}
+
+	if body, err := os.ReadFile(statusPath(exp, name)); err == nil {
+		json.Unmarshal(body, &d.Status)
+	}
+
+	if !d.Running() && d.Status.State != "exited" && d.Status.State != "failed" {
+		d.Status.State = "dead"
+	}
+
+	return &d, nil
+}
+
+// List returns the daemons for the given experiment, or for all experiments
+// if no experiment is given, sorted by experiment and name.
+func List(exp string) ([]Daemon, error) {
+	pattern := filepath.Join(common.PhenixBase, "daemons", "*", "*.json")
+
+	if exp != "" {
+		pattern = filepath.Join(dir(exp), "*.json")
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("listing daemons: %w", err)
+	}
+
+	var daemons []Daemon
+
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".status.json") {
+			continue
+		}
+
+		var (
+			e    = filepath.Base(filepath.Dir(m))
+			name = strings.TrimSuffix(filepath.Base(m), ".json")
+		)
+
+		d, err := Get(e, name)
+		if err != nil {
+			return nil, err
+		}
+
+		daemons = append(daemons, *d)
+	}
+
+	sort.Slice(daemons, func(i, j int) bool {
+		if daemons[i].Experiment == daemons[j].Experiment {
+			return daemons[i].Name < daemons[j].Name
+		}
+
+		return daemons[i].Experiment < daemons[j].Experiment
+	})
+
+	return daemons, nil
+}
+
+// Stop stops supervising the given daemon for the given experiment, killing
+// it and any processes it spawned.
+func Stop(exp, name string) error {
+	d, err := Get(exp, name)
+	if err != nil {
+		return err
+	}
+
+	if d.Running() {
+		if err := reap(d.SupervisorPID); err != nil {
+			return fmt.Errorf("stopping daemon %s: %w", name, err)
+		}
+	}
+
+	os.Remove(recordPath(exp, name))
+	os.Remove(statusPath(exp, name))
+
+	return nil
+}
+
+// StopAll stops all the daemons for the given experiment.
+func StopAll(exp string) error {
+	daemons, err := List(exp)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+
+	for _, d := range daemons {
+		if err := Stop(exp, d.Name); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("stopping daemons for experiment %s: %s", exp, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// reap sends SIGTERM to the process group led by the given PID, then SIGKILL
+// if the leader hasn't exited by StopTimeout.
+func reap(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+
+	deadline := time.Now().Add(StopTimeout)
+
+	for time.Now().Before(deadline) {
+		if !alive(pid) {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Always kill the group in case any processes ignored SIGTERM.
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+
+	return nil
+}
+
+func alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// Zombies (ie. supervisors started by this process that have exited) still
+	// accept signals, so check the process state too.
+	if stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		if fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:])); len(fields) > 0 && fields[0] == "Z" {
+			return false
+		}
+	}
+
+	return syscall.Kill(pid, 0) == nil
+}
+
+func dir(exp string) string {
+	return filepath.Join(common.PhenixBase, "daemons", exp)
+}
+
+func recordPath(exp, name string) string {
+	return filepath.Join(dir(exp), name+".json")
+}
+
+func statusPath(exp, name string) string {
+	return filepath.Join(dir(exp), name+".status.json")
+}
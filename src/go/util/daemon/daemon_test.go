@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldRestart(t *testing.T) {
+	failed := errors.New("exit status 1")
+
+	cases := []struct {
+		name     string
+		spec     Spec
+		err      error
+		restarts int
+		expected bool
+	}{
+		{"never", Spec{Restart: RestartNever}, failed, 0, false},
+		{"on-failure failed", Spec{Restart: RestartOnFailure}, failed, 0, true},
+		{"on-failure succeeded", Spec{Restart: RestartOnFailure}, nil, 0, false},
+		{"always succeeded", Spec{Restart: RestartAlways}, nil, 0, true},
+		{"max restarts", Spec{Restart: RestartAlways, MaxRestarts: 2}, failed, 2, false},
+		{"below max restarts", Spec{Restart: RestartAlways, MaxRestarts: 2}, failed, 1, true},
+	}
+
+	for _, c := range cases {
+		if actual := shouldRestart(c.spec, c.err, c.restarts); actual != c.expected {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestSupervise(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "test.status.json")
+		log  = filepath.Join(dir, "test.log")
+	)
+
+	spec := Spec{
+		Name:        "test",
+		Command:     "sh",
+		Args:        []string{"-c", "echo run; exit 1"},
+		Restart:     RestartOnFailure,
+		MaxRestarts: 2,
+		Backoff:     10 * time.Millisecond,
+		LogFile:     log,
+	}
+
+	if err := supervise(context.Background(), spec, path); err != nil {
+		t.Fatalf("supervising daemon: %v", err)
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading status: %v", err)
+	}
+
+	var status Status
+
+	if err := json.Unmarshal(body, &status); err != nil {
+		t.Fatalf("parsing status: %v", err)
+	}
+
+	if status.State != "failed" {
+		t.Errorf("expected state failed, got %s", status.State)
+	}
+
+	if status.Restarts != 2 {
+		t.Errorf("expected 2 restarts, got %d", status.Restarts)
+	}
+
+	if status.LastExit != "exit status 1" {
+		t.Errorf("expected last exit 'exit status 1', got %q", status.LastExit)
+	}
+
+	output, _ := os.ReadFile(log)
+
+	if runs := strings.Count(string(output), "run\n"); runs != 3 {
+		t.Errorf("expected 3 runs logged, got %d", runs)
+	}
+}
+
+func TestSuperviseCanceled(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "test.status.json")
+	)
+
+	spec := Spec{
+		Name:    "test",
+		Command: "sleep",
+		Args:    []string{"60"},
+		Restart: RestartAlways,
+		Backoff: time.Second,
+		LogFile: filepath.Join(dir, "test.log"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := supervise(ctx, spec, path); err != nil {
+		t.Fatalf("supervising daemon: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("supervisor took %v to stop daemon", elapsed)
+	}
+
+	body, _ := os.ReadFile(path)
+
+	var status Status
+	json.Unmarshal(body, &status)
+
+	if status.State != "stopped" {
+		t.Errorf("expected state stopped, got %s", status.State)
+	}
+}
@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Supervise runs the daemon described by the JSON spec read from the given
+// reader, restarting it per its restart policy and writing its status to the
+// given path, until the daemon exits for good or the supervisor is sent
+// SIGTERM or SIGINT. It's run by `phenix daemon supervise` and not meant to be
+// called directly.
+func Supervise(path string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("reading daemon spec: %w", err)
+	}
+
+	var spec Spec
+
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return fmt.Errorf("parsing daemon spec: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	return supervise(ctx, spec, path)
+}
+
+func supervise(ctx context.Context, spec Spec, path string) error {
+	var status Status
+
+	write := func(state string) {
+		status.State = state
+		status.Updated = time.Now().UTC()
+
+		body, _ := json.Marshal(status)
+
+		// Written to a temporary file first so readers never see a partial status.
+		tmp := path + ".tmp"
+
+		if err := os.WriteFile(tmp, body, 0644); err == nil {
+			os.Rename(tmp, path)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(spec.LogFile), 0755); err != nil {
+		return fmt.Errorf("creating daemon log directory: %w", err)
+	}
+
+	log, err := os.OpenFile(spec.LogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("opening daemon log file: %w", err)
+	}
+
+	defer log.Close()
+
+	for {
+		cmd := exec.Command(spec.Command, spec.Args...)
+		cmd.Env = append(os.Environ(), spec.Env...)
+		cmd.Dir = spec.Dir
+		cmd.Stdout = log
+		cmd.Stderr = log
+
+		if err = cmd.Start(); err == nil {
+			status.PID = cmd.Process.Pid
+			write("running")
+
+			done := make(chan error, 1)
+			go func() { done <- cmd.Wait() }()
+
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				cmd.Process.Signal(syscall.SIGTERM)
+
+				select {
+				case <-done:
+				case <-time.After(StopTimeout):
+					cmd.Process.Kill()
+					<-done
+				}
+
+				status.PID = 0
+				write("stopped")
+
+				return nil
+			}
+		}
+
+		status.PID = 0
+		status.LastExit = exitDescription(err)
+
+		fmt.Fprintf(log, "=== daemon %s exited: %s ===\n", spec.Name, status.LastExit)
+
+		if !shouldRestart(spec, err, status.Restarts) {
+			if err == nil {
+				write("exited")
+			} else {
+				write("failed")
+			}
+
+			return nil
+		}
+
+		write("restarting")
+
+		select {
+		case <-ctx.Done():
+			write("stopped")
+			return nil
+		case <-time.After(spec.Backoff):
+		}
+
+		status.Restarts++
+	}
+}
+
+// shouldRestart determines if a daemon that exited with the given error (nil
+// for a zero exit status) should be restarted per its spec, given the number of
+// times it's already been restarted.
+func shouldRestart(spec Spec, err error, restarts int) bool {
+	if spec.MaxRestarts > 0 && restarts >= spec.MaxRestarts {
+		return false
+	}
+
+	switch spec.Restart {
+	case RestartAlways:
+		return true
+	case RestartOnFailure, "":
+		return err != nil
+	default:
+		return false
+	}
+}
+
+func exitDescription(err error) string {
+	var exitErr *exec.ExitError
+
+	switch {
+	case err == nil:
+		return "exit status 0"
+	case errors.As(err, &exitErr):
+		return exitErr.Error()
+	default:
+		return fmt.Sprintf("failed to start: %v", err)
+	}
+}
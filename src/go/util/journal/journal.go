@@ -0,0 +1,204 @@
+// Package journal records the event timeline for experiments: lifecycle
+// changes, app results, VM state changes, SoH alerts, user actions, and manual
+// annotations. Entries are kept in the store as events so the timeline can be
+// displayed while an experiment runs and used to reconstruct an exercise after
+// the fact. Entries are kept after an experiment is deleted.
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/util/pubsub"
+)
+
+type Category string
+
+const (
+	CategoryLifecycle  Category = "lifecycle"
+	CategoryApp        Category = "app"
+	CategoryVM         Category = "vm"
+	CategorySoH        Category = "soh"
+	CategoryUser       Category = "user"
+	CategoryAnnotation Category = "annotation"
+)
+
+var ErrEmptyAnnotation = errors.New("annotation message is empty")
+
+// Entry is a single event in an experiment's timeline.
+type Entry struct {
+	ID         string            `json:"id"`
+	Time       time.Time         `json:"time"`
+	Experiment string            `json:"experiment"`
+	Category   Category          `json:"category"`
+	User       string            `json:"user,omitempty"`
+	Message    string            `json:"message"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// Filter limits the entries returned by `Timeline`. Zero values don't limit
+// results.
+type Filter struct {
+	Categories []Category
+	Since      time.Time
+	Until      time.Time
+}
+
+// Record adds an entry to the timeline for the given experiment. The user can
+// be empty for events not caused by a user. Failures to record the entry are
+// logged but otherwise ignored so they don't prevent the recorded action.
+func Record(exp string, cat Category, user, format string, args ...any) {
+	add(exp, cat, user, time.Now(), nil, fmt.Sprintf(format, args...))
+}
+
+// RecordWithDetails is the same as `Record`, but also includes the given
+// details with the entry.
+func RecordWithDetails(exp string, cat Category, user string, details map[string]string, format string, args ...any) {
+	add(exp, cat, user, time.Now(), details, fmt.Sprintf(format, args...))
+}
+
+// Annotate adds a manual annotation (ie. "red team began phase 2") by the
+// given user to the timeline for the given experiment. If the given time is
+// zero, the annotation is added at the current time.
+func Annotate(exp, user, message string, at time.Time) (*Entry, error) {
+	message = strings.TrimSpace(message)
+
+	if message == "" {
+		return nil, ErrEmptyAnnotation
+	}
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	entry, err := add(exp, CategoryAnnotation, user, at, nil, message)
+	if err != nil {
+		return nil, fmt.Errorf("adding annotation to timeline for experiment %s: %w", exp, err)
+	}
+
+	return entry, nil
+}
+
+// Timeline returns the timeline entries for the given experiment matching the
+// given filter, oldest first.
+func Timeline(exp string, filter Filter) ([]Entry, error) {
+	// Not all stores support getting events by type and metadata, so filter
+	// them here instead.
+	events, err := store.GetEvents()
+	if err != nil {
+		return nil, fmt.Errorf("getting timeline for experiment %s: %w", exp, err)
+	}
+
+	cats := make(map[Category]bool)
+
+	for _, c := range filter.Categories {
+		cats[c] = true
+	}
+
+	var entries []Entry
+
+	for _, e := range events {
+		if e.Type != store.EventTypeJournal || e.Metadata["experiment"] != exp {
+			continue
+		}
+
+		entry := fromEvent(e)
+
+		if len(cats) > 0 && !cats[entry.Category] {
+			continue
+		}
+
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			continue
+		}
+
+		if !filter.Until.IsZero() && entry.Time.After(filter.Until) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries, nil
+}
+
+// ParseCategories parses a comma separated list of categories.
+func ParseCategories(list string) ([]Category, error) {
+	var cats []Category
+
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+
+		switch cat := Category(strings.ToLower(c)); cat {
+		case "":
+			continue
+		case CategoryLifecycle, CategoryApp, CategoryVM, CategorySoH, CategoryUser, CategoryAnnotation:
+			cats = append(cats, cat)
+		default:
+			return nil, fmt.Errorf("unknown timeline category %s", c)
+		}
+	}
+
+	return cats, nil
+}
+
+func add(exp string, cat Category, user string, at time.Time, details map[string]string, message string) (*Entry, error) {
+	event := store.NewEvent("%s", message).
+		WithMetadata("experiment", exp).
+		WithMetadata("category", string(cat))
+
+	event.Type = store.EventTypeJournal
+	event.Timestamp = at
+
+	if user != "" {
+		event.WithMetadata("user", user)
+	}
+
+	for k, v := range details {
+		event.WithMetadata("detail."+k, v)
+	}
+
+	if err := store.AddEvent(*event); err != nil {
+		plog.Error("recording timeline entry", "exp", exp, "category", cat, "err", err)
+		return nil, err
+	}
+
+	entry := fromEvent(*event)
+
+	// Lets the web broker push new entries out to UI clients.
+	pubsub.Publish("journal", entry)
+
+	return &entry, nil
+}
+
+func fromEvent(e store.Event) Entry {
+	entry := Entry{
+		ID:         e.ID,
+		Time:       e.Timestamp,
+		Experiment: e.Metadata["experiment"],
+		Category:   Category(e.Metadata["category"]),
+		User:       e.Metadata["user"],
+		Message:    e.Message,
+	}
+
+	for k, v := range e.Metadata {
+		if name, ok := strings.CutPrefix(k, "detail."); ok {
+			if entry.Details == nil {
+				entry.Details = make(map[string]string)
+			}
+
+			entry.Details[name] = v
+		}
+	}
+
+	return entry
+}
@@ -0,0 +1,83 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestTimeline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var events store.Events
+
+	m := store.NewMockStore(ctrl)
+
+	m.EXPECT().AddEvent(gomock.Any()).DoAndReturn(func(e store.Event) error {
+		events = append(events, e)
+		return nil
+	}).AnyTimes()
+
+	m.EXPECT().GetEvents().DoAndReturn(func() (store.Events, error) {
+		return events, nil
+	}).AnyTimes()
+
+	store.DefaultStore = m
+
+	start := time.Now()
+
+	Record("foo", CategoryLifecycle, "", "experiment started")
+	RecordWithDetails("foo", CategoryApp, "", map[string]string{"error": "boom"}, "app %s (%s) failed", "soh", "post-start")
+	Record("bar", CategoryLifecycle, "", "experiment started")
+
+	if _, err := Annotate("foo", "alice", "red team began phase 2", start.Add(-time.Hour)); err != nil {
+		t.Fatalf("annotating timeline: %v", err)
+	}
+
+	if _, err := Annotate("foo", "alice", "  ", time.Time{}); err != ErrEmptyAnnotation {
+		t.Errorf("expected empty annotation error, got %v", err)
+	}
+
+	entries, err := Timeline("foo", Filter{})
+	if err != nil {
+		t.Fatalf("getting timeline: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+
+	// The annotation was backdated, so it should be first.
+	if entries[0].Category != CategoryAnnotation || entries[0].User != "alice" {
+		t.Errorf("expected annotation by alice first, got %+v", entries[0])
+	}
+
+	if entries[2].Details["error"] != "boom" || entries[2].Message != "app soh (post-start) failed" {
+		t.Errorf("unexpected app entry %+v", entries[2])
+	}
+
+	entries, _ = Timeline("foo", Filter{Categories: []Category{CategoryApp, CategoryLifecycle}, Since: start})
+
+	if len(entries) != 2 {
+		t.Errorf("expected 2 filtered entries, got %d", len(entries))
+	}
+}
+
+func TestParseCategories(t *testing.T) {
+	cats, err := ParseCategories("app, SoH,,annotation")
+	if err != nil {
+		t.Fatalf("parsing categories: %v", err)
+	}
+
+	if len(cats) != 3 || cats[1] != CategorySoH {
+		t.Errorf("unexpected categories %v", cats)
+	}
+
+	if _, err := ParseCategories("app,bogus"); err == nil {
+		t.Errorf("expected error for unknown category")
+	}
+}
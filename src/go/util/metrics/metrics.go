@@ -0,0 +1,285 @@
+// Package metrics provides counters and histograms that are exposed in the
+// Prometheus text exposition format, ie. via the UI server's /metrics endpoint.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DurationBuckets are the default histogram buckets (in seconds) used for
+// durations, ranging from fast built-in apps to slow experiment starts.
+var DurationBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+var (
+	registry   []collector
+	registryMu sync.Mutex
+)
+
+type collector interface {
+	write(*bufio.Writer)
+}
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = append(registry, c)
+}
+
+// metric is the name, help text, and label names shared by counters and
+// histograms.
+type metric struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// key returns the label set for the given label values, formatted as it's
+// written in the exposition format (without braces).
+func (this metric) key(values []string) string {
+	if len(values) != len(this.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", this.name, len(this.labels), len(values)))
+	}
+
+	pairs := make([]string, len(values))
+
+	for i, v := range values {
+		pairs[i] = fmt.Sprintf("%s=%q", this.labels[i], v)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (this metric) header(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", this.name, this.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", this.name, kind)
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	metric
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec creates and registers a counter with the given name, help
+// text, and label names.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		metric: metric{name: name, help: help, labels: labels},
+		values: make(map[string]float64),
+	}
+
+	register(c)
+
+	return c
+}
+
+// Inc increments the counter for the given label values by one.
+func (this *CounterVec) Inc(values ...string) {
+	this.Add(1, values...)
+}
+
+// Add increments the counter for the given label values by the given amount.
+func (this *CounterVec) Add(v float64, values ...string) {
+	key := this.key(values)
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.values[key] += v
+}
+
+// Value returns the current value of the counter for the given label values.
+func (this *CounterVec) Value(values ...string) float64 {
+	key := this.key(values)
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	return this.values[key]
+}
+
+func (this *CounterVec) write(w *bufio.Writer) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.header(w, "counter")
+
+	keys := make([]string, 0, len(this.values))
+
+	for key := range this.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", this.name, braces(key), formatFloat(this.values[key]))
+	}
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	metric
+
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec creates and registers a histogram with the given name, help
+// text, bucket upper bounds, and label names.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{
+		metric:  metric{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+
+	register(h)
+
+	return h
+}
+
+// Observe adds the given value to the histogram for the given label values.
+func (this *HistogramVec) Observe(v float64, values ...string) {
+	key := this.key(values)
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	h, ok := this.values[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(this.buckets))}
+		this.values[key] = h
+	}
+
+	for i, b := range this.buckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+
+	h.count++
+	h.sum += v
+}
+
+// Count returns the number of values observed by the histogram for the given
+// label values.
+func (this *HistogramVec) Count(values ...string) uint64 {
+	key := this.key(values)
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if h, ok := this.values[key]; ok {
+		return h.count
+	}
+
+	return 0
+}
+
+func (this *HistogramVec) write(w *bufio.Writer) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	this.header(w, "histogram")
+
+	keys := make([]string, 0, len(this.values))
+
+	for key := range this.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var (
+			h          = this.values[key]
+			cumulative uint64
+		)
+
+		for i, b := range this.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", this.name, braces(key, fmt.Sprintf("le=%q", formatFloat(b))), cumulative)
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", this.name, braces(key, `le="+Inf"`), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", this.name, braces(key), formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", this.name, braces(key), h.count)
+	}
+}
+
+// Write writes all registered metrics to the given writer in the Prometheus
+// text exposition format.
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	buf := bufio.NewWriter(w)
+
+	for _, c := range collectors {
+		c.write(buf)
+	}
+
+	return buf.Flush()
+}
+
+// Handler returns an HTTP handler that serves all registered metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		if err := Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// braces returns the given label sets joined and wrapped in braces, or nothing
+// if there are no labels.
+func braces(keys ...string) string {
+	var nonempty []string
+
+	for _, k := range keys {
+		if k != "" {
+			nonempty = append(nonempty, k)
+		}
+	}
+
+	if len(nonempty) == 0 {
+		return ""
+	}
+
+	return "{" + strings.Join(nonempty, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
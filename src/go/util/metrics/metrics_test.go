@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	var (
+		counter   = NewCounterVec("test_failures_total", "Test failures.", "app")
+		histogram = NewHistogramVec("test_duration_seconds", "Test durations.", []float64{1, 5}, "app")
+	)
+
+	counter.Inc("foo")
+	counter.Add(2, "foo")
+
+	histogram.Observe(0.5, "foo")
+	histogram.Observe(3, "foo")
+	histogram.Observe(10, "foo")
+
+	var buf bytes.Buffer
+
+	if err := Write(&buf); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"# TYPE test_failures_total counter",
+		`test_failures_total{app="foo"} 3`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{app="foo",le="1"} 1`,
+		`test_duration_seconds_bucket{app="foo",le="5"} 2`,
+		`test_duration_seconds_bucket{app="foo",le="+Inf"} 3`,
+		`test_duration_seconds_sum{app="foo"} 13.5`,
+		`test_duration_seconds_count{app="foo"} 3`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(buf.String(), e+"\n") {
+			t.Logf("expected metrics output to contain %q, got:\n%s", e, buf.String())
+			t.FailNow()
+		}
+	}
+}
@@ -83,12 +83,36 @@ type C2ParallelCommand struct {
 	ExpectedStderr func(string) error
 }
 
+// ScheduleC2ParallelCommand executes the given C2 command in the background,
+// adding its outcome to the command's state group. Commands are subject to the
+// same rate and per-host limits as tasks dispatched via `DispatchC2`, holding
+// their host's slot until their responses have been checked.
 func ScheduleC2ParallelCommand(ctx context.Context, cmd *C2ParallelCommand) {
 	cmd.Wait.Add(1)
 
 	go func() {
 		defer cmd.Wait.Done()
 
+		var (
+			c2   = NewC2Options(cmd.Options...)
+			host string
+		)
+
+		if vms := GetVMInfo(NS(c2.ns), VMName(c2.vm), Cached()); len(vms) > 0 {
+			host = vms[0].Host
+		}
+
+		release, err := c2Slots.acquire(ctx, host)
+		if err != nil {
+			return
+		}
+
+		var once sync.Once
+
+		// Free up the host's slot before waiting to retry the command.
+		done := func() { once.Do(release) }
+		defer done()
+
 		opts := append(cmd.Options, C2Context(ctx), C2Wait())
 
 		id, err := ExecC2Command(opts...)
@@ -110,6 +134,8 @@ func ScheduleC2ParallelCommand(ctx context.Context, cmd *C2ParallelCommand) {
 				var retry C2RetryError
 
 				if errors.As(err, &retry) {
+					done()
+
 					if err := util.SleepContext(ctx, retry.Delay); err != nil {
 						return
 					}
@@ -134,6 +160,8 @@ func ScheduleC2ParallelCommand(ctx context.Context, cmd *C2ParallelCommand) {
 				var retry C2RetryError
 
 				if errors.As(err, &retry) {
+					done()
+
 					if err := util.SleepContext(ctx, retry.Delay); err != nil {
 						return
 					}
@@ -158,6 +186,8 @@ func ScheduleC2ParallelCommand(ctx context.Context, cmd *C2ParallelCommand) {
 				var retry C2RetryError
 
 				if errors.As(err, &retry) {
+					done()
+
 					if err := util.SleepContext(ctx, retry.Delay); err != nil {
 						return
 					}
@@ -0,0 +1,87 @@
+package mm
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// VM_INFO_TTL is how long VM info collected from minimega can be returned from
+// the VM info cache when the `Cached` option is used. Collecting VM info for
+// large experiments requires several minimega queries per VM, so callers that
+// poll VM info frequently (ie. the UI VM table) are served from the cache
+// instead. The cache for a namespace is invalidated whenever VMs in it are
+// changed via this package.
+var VM_INFO_TTL = 5 * time.Second
+
+var vmInfoCache = struct {
+	sync.Mutex
+
+	vms map[string]VMs
+	ts  map[string]time.Time
+}{
+	vms: make(map[string]VMs),
+	ts:  make(map[string]time.Time),
+}
+
+// InvalidateVMInfo removes all cached VM info for the given namespace.
+func InvalidateVMInfo(ns string) {
+	vmInfoCache.Lock()
+	defer vmInfoCache.Unlock()
+
+	prefix := ns + "/"
+
+	for key := range vmInfoCache.vms {
+		if strings.HasPrefix(key, prefix) {
+			delete(vmInfoCache.vms, key)
+			delete(vmInfoCache.ts, key)
+		}
+	}
+}
+
+func cachedVMInfo(ns, vm string) (VMs, bool) {
+	vmInfoCache.Lock()
+	defer vmInfoCache.Unlock()
+
+	key := ns + "/" + vm
+
+	vms, ok := vmInfoCache.vms[key]
+	if !ok || time.Since(vmInfoCache.ts[key]) >= VM_INFO_TTL {
+		return nil, false
+	}
+
+	return vms.clone(), true
+}
+
+func cacheVMInfo(ns, vm string, vms VMs) {
+	vmInfoCache.Lock()
+	defer vmInfoCache.Unlock()
+
+	key := ns + "/" + vm
+
+	vmInfoCache.vms[key] = vms.clone()
+	vmInfoCache.ts[key] = time.Now()
+}
+
+// clone returns a copy of the VMs that doesn't share any slices or maps with
+// the original, since callers often modify the VMs returned to them.
+func (this VMs) clone() VMs {
+	if this == nil {
+		return nil
+	}
+
+	vms := make(VMs, len(this))
+
+	for i, vm := range this {
+		vm.IPv4 = append([]string(nil), vm.IPv4...)
+		vm.Networks = append([]string(nil), vm.Networks...)
+		vm.Taps = append([]string(nil), vm.Taps...)
+		vm.Captures = append([]Capture(nil), vm.Captures...)
+		vm.Tags = append([]string(nil), vm.Tags...)
+		vm.GuestIPs = append([]GuestIP(nil), vm.GuestIPs...)
+
+		vms[i] = vm
+	}
+
+	return vms
+}
@@ -0,0 +1,34 @@
+package mm
+
+import "testing"
+
+func TestVMInfoCache(t *testing.T) {
+	cacheVMInfo("test", "", VMs{{Name: "foo", IPv4: []string{"10.0.0.1"}}})
+
+	vms, ok := cachedVMInfo("test", "")
+	if !ok || len(vms) != 1 {
+		t.Log("expected cached VM info")
+		t.FailNow()
+	}
+
+	// Modifying returned VMs shouldn't modify the cache.
+	vms[0].IPv4[0] = "n/a"
+
+	vms, _ = cachedVMInfo("test", "")
+	if vms[0].IPv4[0] != "10.0.0.1" {
+		t.Logf("expected cached IP to be unchanged, got %s", vms[0].IPv4[0])
+		t.FailNow()
+	}
+
+	if _, ok := cachedVMInfo("test", "foo"); ok {
+		t.Log("expected no cached VM info for single VM")
+		t.FailNow()
+	}
+
+	InvalidateVMInfo("test")
+
+	if _, ok := cachedVMInfo("test", ""); ok {
+		t.Log("expected cached VM info to be invalidated")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,171 @@
+package mm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CAPABILITIES_TTL is how long host capabilities are cached for before being
+// collected from the host again. Capabilities rarely change, and collecting
+// them requires running commands on each cluster host.
+var CAPABILITIES_TTL = 10 * time.Minute
+
+// HostCapabilities are the hardware capabilities of a cluster host relevant to
+// scheduling and launching VMs. They are also represented as capability labels
+// (see Labels) so VMs can require specific capabilities.
+type HostCapabilities struct {
+	Arch          string   `json:"arch"`
+	KVM           bool     `json:"kvm"`
+	CPUVendor     string   `json:"cpuVendor"`
+	CPUFlags      []string `json:"cpuFlags,omitempty"`
+	HugepagesFree int      `json:"hugepagesFree"`
+	HugepageSize  int      `json:"hugepageSize"`
+	SRIOVNICs     []string `json:"sriovNICs,omitempty"`
+}
+
+// Labels returns the capability labels for the host. The following labels are
+// supported:
+//
+//	-- `kvm`: KVM acceleration is available
+//	-- `arch:<arch>`: the host architecture (ie. `arch:x86_64`)
+//	-- `vendor:<vendor>`: the CPU vendor (ie. `vendor:intel` or `vendor:amd`)
+//	-- `cpu:<flag>`: the CPU supports the given flag (ie. `cpu:avx2`)
+//	-- `hugepages`: free hugepages are available
+//	-- `sriov`: at least one SR-IOV capable NIC is available
+func (this HostCapabilities) Labels() []string {
+	var labels []string
+
+	if this.KVM {
+		labels = append(labels, "kvm")
+	}
+
+	if this.Arch != "" {
+		labels = append(labels, "arch:"+this.Arch)
+	}
+
+	if this.CPUVendor != "" {
+		labels = append(labels, "vendor:"+this.CPUVendor)
+	}
+
+	for _, flag := range this.CPUFlags {
+		labels = append(labels, "cpu:"+flag)
+	}
+
+	if this.HugepagesFree > 0 {
+		labels = append(labels, "hugepages")
+	}
+
+	if len(this.SRIOVNICs) > 0 {
+		labels = append(labels, "sriov")
+	}
+
+	return labels
+}
+
+// Missing returns the given required capability labels the host doesn't have.
+func (this HostCapabilities) Missing(required ...string) []string {
+	have := make(map[string]struct{})
+
+	for _, label := range this.Labels() {
+		have[label] = struct{}{}
+	}
+
+	var missing []string
+
+	for _, label := range required {
+		if _, ok := have[strings.ToLower(label)]; !ok {
+			missing = append(missing, label)
+		}
+	}
+
+	return missing
+}
+
+// capabilitiesScript prints one `key=value` line per capability. It's run via
+// `bash -c` on each cluster host.
+const capabilitiesScript = `echo arch=$(uname -m); ` +
+	`test -c /dev/kvm && echo kvm=true || echo kvm=false; ` +
+	`echo vendor=$(awk -F': ' '/^vendor_id/{print $2; exit}' /proc/cpuinfo); ` +
+	`echo flags=$(awk -F': ' '/^flags/{print $2; exit}' /proc/cpuinfo); ` +
+	`echo hugepages=$(awk '/^HugePages_Free/{print $2}' /proc/meminfo); ` +
+	`echo hugepagesize=$(awk '/^Hugepagesize/{print $2}' /proc/meminfo); ` +
+	`echo sriov=$(for f in /sys/class/net/*/device/sriov_totalvfs; do test -e $f && test $(cat $f) -gt 0 && basename $(dirname $(dirname $f)); done)`
+
+var capabilities = struct {
+	sync.Mutex
+
+	hosts map[string]*HostCapabilities
+	ts    map[string]time.Time
+}{
+	hosts: make(map[string]*HostCapabilities),
+	ts:    make(map[string]time.Time),
+}
+
+// getCapabilities returns the (possibly cached) capabilities of the given
+// host, or nil if they couldn't be collected.
+func (this Minimega) getCapabilities(host string) *HostCapabilities {
+	capabilities.Lock()
+	defer capabilities.Unlock()
+
+	if caps, ok := capabilities.hosts[host]; ok && time.Since(capabilities.ts[host]) < CAPABILITIES_TTL {
+		return caps
+	}
+
+	resp, err := this.MeshShellResponse(host, fmt.Sprintf(`bash -c "%s"`, capabilitiesScript))
+	if err != nil || resp == "" {
+		return nil
+	}
+
+	caps := ParseHostCapabilities(resp)
+
+	capabilities.hosts[host] = caps
+	capabilities.ts[host] = time.Now()
+
+	return caps
+}
+
+// ParseHostCapabilities parses the `key=value` output of the host capabilities
+// script.
+func ParseHostCapabilities(out string) *HostCapabilities {
+	caps := new(HostCapabilities)
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "arch":
+			caps.Arch = value
+		case "kvm":
+			caps.KVM, _ = strconv.ParseBool(value)
+		case "vendor":
+			switch value {
+			case "GenuineIntel":
+				caps.CPUVendor = "intel"
+			case "AuthenticAMD":
+				caps.CPUVendor = "amd"
+			default:
+				caps.CPUVendor = strings.ToLower(value)
+			}
+		case "flags":
+			caps.CPUFlags = strings.Fields(value)
+			sort.Strings(caps.CPUFlags)
+		case "hugepages":
+			caps.HugepagesFree, _ = strconv.Atoi(value)
+		case "hugepagesize":
+			caps.HugepageSize, _ = strconv.Atoi(value)
+		case "sriov":
+			caps.SRIOVNICs = strings.Fields(value)
+		}
+	}
+
+	return caps
+}
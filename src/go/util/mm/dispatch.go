@@ -0,0 +1,359 @@
+package mm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/util"
+)
+
+// C2_DISPATCH_RATE is how many C2 commands dispatched via `DispatchC2` (or
+// `ScheduleC2ParallelCommand`) are started per second across all cluster
+// hosts. Zero (or negative) means there's no limit.
+var C2_DISPATCH_RATE = 20.0
+
+// C2_DISPATCH_HOST_LIMIT is how many C2 commands dispatched via `DispatchC2`
+// (or `ScheduleC2ParallelCommand`) can be in flight at once for the VMs on a
+// single cluster host, since each host's cc server pushes commands and files to
+// its VMs over their serial or virtio channels. Commands hold their slot until
+// their VM responds. Zero (or negative) means there's no limit. Changes only
+// apply to hosts not yet dispatched to.
+var C2_DISPATCH_HOST_LIMIT = 8
+
+// C2TaskState is the state of a single C2 command in a dispatch batch.
+type C2TaskState string
+
+const (
+	C2TASK_QUEUED   C2TaskState = "queued"
+	C2TASK_RUNNING  C2TaskState = "running"
+	C2TASK_DONE     C2TaskState = "done"
+	C2TASK_FAILED   C2TaskState = "failed"
+	C2TASK_CANCELED C2TaskState = "canceled"
+)
+
+// C2Task is a single C2 command (ie. `C2Command` or `C2SendFile`) to dispatch
+// to a VM. The namespace, VM, and context options are set by the dispatcher.
+type C2Task struct {
+	VM      string
+	Options []C2Option
+}
+
+// C2TaskResult tracks the dispatch of a C2Task. Response is only collected if
+// the `C2DispatchResponses` option is used.
+type C2TaskResult struct {
+	VM       string      `json:"vm"`
+	Host     string      `json:"host"`
+	ID       string      `json:"id,omitempty"`
+	State    C2TaskState `json:"state"`
+	Response string      `json:"response,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Started  time.Time   `json:"started"`
+	Finished time.Time   `json:"finished"`
+}
+
+// C2Progress summarizes the state of the tasks in a dispatch batch.
+type C2Progress struct {
+	Total    int `json:"total"`
+	Queued   int `json:"queued"`
+	Running  int `json:"running"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+	Canceled int `json:"canceled"`
+}
+
+// Complete returns true if none of the tasks are still queued or running.
+func (this C2Progress) Complete() bool {
+	return this.Queued == 0 && this.Running == 0
+}
+
+// C2Batch tracks the completion of a group of C2 tasks dispatched together.
+type C2Batch struct {
+	sync.Mutex
+
+	results []C2TaskResult
+	done    chan struct{}
+}
+
+// Progress returns the current state of the batch's tasks.
+func (this *C2Batch) Progress() C2Progress {
+	this.Lock()
+	defer this.Unlock()
+
+	p := C2Progress{Total: len(this.results)}
+
+	for _, r := range this.results {
+		switch r.State {
+		case C2TASK_QUEUED:
+			p.Queued++
+		case C2TASK_RUNNING:
+			p.Running++
+		case C2TASK_DONE:
+			p.Done++
+		case C2TASK_FAILED:
+			p.Failed++
+		case C2TASK_CANCELED:
+			p.Canceled++
+		}
+	}
+
+	return p
+}
+
+// Results returns a copy of the current results of the batch's tasks, in the
+// order the tasks were dispatched.
+func (this *C2Batch) Results() []C2TaskResult {
+	this.Lock()
+	defer this.Unlock()
+
+	results := make([]C2TaskResult, len(this.results))
+	copy(results, this.results)
+
+	return results
+}
+
+// Done returns a channel that's closed once every task in the batch is
+// complete.
+func (this *C2Batch) Done() <-chan struct{} {
+	return this.done
+}
+
+// Wait blocks until every task in the batch is complete or the given context
+// is canceled, returning the batch's results.
+func (this *C2Batch) Wait(ctx context.Context) ([]C2TaskResult, error) {
+	select {
+	case <-this.done:
+		return this.Results(), nil
+	case <-ctx.Done():
+		return this.Results(), ctx.Err()
+	}
+}
+
+func (this *C2Batch) update(i int, f func(*C2TaskResult)) {
+	this.Lock()
+	defer this.Unlock()
+
+	f(&this.results[i])
+}
+
+// C2DispatchOption is a function that configures options for dispatching C2
+// tasks. It is used in `mm.DispatchC2`.
+type C2DispatchOption func(*c2DispatchOptions)
+
+type c2DispatchOptions struct {
+	responses bool
+	progress  func(C2Progress)
+}
+
+// C2DispatchResponses collects each task's response once its command completes.
+func C2DispatchResponses() C2DispatchOption {
+	return func(o *c2DispatchOptions) {
+		o.responses = true
+	}
+}
+
+// C2DispatchProgress sets a function called with the batch's progress each
+// time a task completes.
+func C2DispatchProgress(f func(C2Progress)) C2DispatchOption {
+	return func(o *c2DispatchOptions) {
+		o.progress = f
+	}
+}
+
+// DispatchC2 queues the given C2 tasks for the VMs in the given namespace,
+// returning a batch that tracks their completion. Tasks are started no faster
+// than C2_DISPATCH_RATE, and no more than C2_DISPATCH_HOST_LIMIT tasks (from
+// this or any other batch) run at once for the VMs on each cluster host. Each
+// task waits for its VM to respond before freeing up its host's slot. Tasks
+// still queued when the given context is canceled are marked canceled.
+func DispatchC2(ctx context.Context, ns string, tasks []C2Task, opts ...C2DispatchOption) *C2Batch {
+	var o c2DispatchOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	hosts := make(map[string]string)
+
+	for _, vm := range GetVMInfo(NS(ns), Cached()) {
+		hosts[vm.Name] = vm.Host
+	}
+
+	exec := func(ctx context.Context, task C2Task) (string, string, error) {
+		opts := append([]C2Option{}, task.Options...)
+		opts = append(opts, C2NS(ns), C2VM(task.VM), C2Context(ctx), C2Wait())
+
+		id, err := ExecC2Command(opts...)
+		if err != nil {
+			return "", "", err
+		}
+
+		if !o.responses {
+			return id, "", nil
+		}
+
+		resp, err := GetC2Response(C2NS(ns), C2CommandID(id))
+		if err != nil {
+			return id, "", fmt.Errorf("getting response for C2 command: %w", err)
+		}
+
+		return id, resp, nil
+	}
+
+	return dispatch(ctx, tasks, hosts, exec, o)
+}
+
+func dispatch(ctx context.Context, tasks []C2Task, hosts map[string]string, exec func(context.Context, C2Task) (string, string, error), o c2DispatchOptions) *C2Batch {
+	batch := &C2Batch{
+		results: make([]C2TaskResult, len(tasks)),
+		done:    make(chan struct{}),
+	}
+
+	for i, task := range tasks {
+		batch.results[i] = C2TaskResult{VM: task.VM, Host: hosts[task.VM], State: C2TASK_QUEUED}
+	}
+
+	var wg sync.WaitGroup
+
+	finish := func(i int, id, resp string, err error) {
+		batch.update(i, func(r *C2TaskResult) {
+			r.ID = id
+			r.Response = resp
+			r.Finished = time.Now()
+
+			switch {
+			case err == nil:
+				r.State = C2TASK_DONE
+			case r.State == C2TASK_QUEUED:
+				r.State = C2TASK_CANCELED
+				r.Error = err.Error()
+			default:
+				r.State = C2TASK_FAILED
+				r.Error = err.Error()
+			}
+		})
+
+		if o.progress != nil {
+			o.progress(batch.Progress())
+		}
+	}
+
+	for i, task := range tasks {
+		host, ok := hosts[task.VM]
+		if !ok {
+			batch.update(i, func(r *C2TaskResult) { r.State = C2TASK_RUNNING })
+			finish(i, "", "", fmt.Errorf("VM %s not found", task.VM))
+
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(i int, task C2Task) {
+			defer wg.Done()
+
+			release, err := c2Slots.acquire(ctx, host)
+			if err != nil {
+				finish(i, "", "", err)
+				return
+			}
+
+			defer release()
+
+			batch.update(i, func(r *C2TaskResult) {
+				r.State = C2TASK_RUNNING
+				r.Started = time.Now()
+			})
+
+			id, resp, err := exec(ctx, task)
+			finish(i, id, resp, err)
+		}(i, task)
+	}
+
+	go func() {
+		wg.Wait()
+		close(batch.done)
+	}()
+
+	return batch
+}
+
+// c2Slots limits the rate C2 commands are started at and how many are in
+// flight at once for each cluster host.
+var c2Slots = &c2Limiter{hosts: make(map[string]chan struct{})}
+
+type c2Limiter struct {
+	sync.Mutex
+
+	hosts map[string]chan struct{}
+	next  time.Time
+}
+
+// acquire blocks until a C2 command can be started for a VM on the given host,
+// returning a function that frees up the command's slot once it's complete.
+func (this *c2Limiter) acquire(ctx context.Context, host string) (func(), error) {
+	release := func() {}
+
+	if slots := this.slots(host); slots != nil {
+		select {
+		case slots <- struct{}{}:
+			release = func() { <-slots }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err := util.SleepContext(ctx, this.wait()); err != nil {
+		release()
+		return nil, err
+	}
+
+	// A slot (or the end of the wait) and cancelation can be ready at the same
+	// time, in which case select picks one at random, so make sure the command
+	// isn't started after the context has already been canceled.
+	if err := ctx.Err(); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (this *c2Limiter) slots(host string) chan struct{} {
+	this.Lock()
+	defer this.Unlock()
+
+	slots, ok := this.hosts[host]
+	if !ok {
+		if C2_DISPATCH_HOST_LIMIT > 0 {
+			slots = make(chan struct{}, C2_DISPATCH_HOST_LIMIT)
+		}
+
+		this.hosts[host] = slots
+	}
+
+	return slots
+}
+
+// wait reserves the next start time allowed by C2_DISPATCH_RATE, returning how
+// long to wait until then.
+func (this *c2Limiter) wait() time.Duration {
+	if C2_DISPATCH_RATE <= 0 {
+		return 0
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	now := time.Now()
+
+	if this.next.Before(now) {
+		this.next = now
+	}
+
+	wait := this.next.Sub(now)
+	this.next = this.next.Add(time.Duration(float64(time.Second) / C2_DISPATCH_RATE))
+
+	return wait
+}
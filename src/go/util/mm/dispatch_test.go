@@ -0,0 +1,146 @@
+package mm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatch(t *testing.T) {
+	rate, limit := C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT
+	defer func() { C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT = rate, limit }()
+
+	C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT = 0, 2
+
+	var (
+		hosts    = make(map[string]string)
+		tasks    []C2Task
+		mu       sync.Mutex
+		inFlight = make(map[string]int)
+		peak     = make(map[string]int)
+	)
+
+	for i := 0; i < 10; i++ {
+		vm := fmt.Sprintf("vm-%d", i)
+
+		hosts[vm] = fmt.Sprintf("dispatch-test-host-%d", i%2)
+		tasks = append(tasks, C2Task{VM: vm})
+	}
+
+	tasks = append(tasks, C2Task{VM: "missing"})
+
+	exec := func(ctx context.Context, task C2Task) (string, string, error) {
+		host := hosts[task.VM]
+
+		mu.Lock()
+		inFlight[host]++
+
+		if inFlight[host] > peak[host] {
+			peak[host] = inFlight[host]
+		}
+
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight[host]--
+		mu.Unlock()
+
+		if task.VM == "vm-3" {
+			return "3", "", fmt.Errorf("command failed")
+		}
+
+		return task.VM, "ok", nil
+	}
+
+	var updates int
+
+	progress := func(C2Progress) {
+		mu.Lock()
+		updates++
+		mu.Unlock()
+	}
+
+	batch := dispatch(context.Background(), tasks, hosts, exec, c2DispatchOptions{progress: progress})
+
+	results, err := batch.Wait(context.Background())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for host, n := range peak {
+		if n > 2 {
+			t.Logf("expected at most 2 commands in flight for %s, got %d", host, n)
+			t.FailNow()
+		}
+	}
+
+	p := batch.Progress()
+
+	if !p.Complete() || p.Total != 11 || p.Done != 9 || p.Failed != 2 {
+		t.Logf("unexpected progress: %+v", p)
+		t.FailNow()
+	}
+
+	if updates != 11 {
+		t.Logf("expected 11 progress updates, got %d", updates)
+		t.FailNow()
+	}
+
+	if results[0].ID != "vm-0" || results[0].State != C2TASK_DONE || results[0].Host != "dispatch-test-host-0" {
+		t.Logf("unexpected result: %+v", results[0])
+		t.FailNow()
+	}
+
+	if results[3].State != C2TASK_FAILED || results[3].Error != "command failed" {
+		t.Logf("unexpected result for failed command: %+v", results[3])
+		t.FailNow()
+	}
+
+	if results[10].State != C2TASK_FAILED {
+		t.Logf("unexpected result for missing VM: %+v", results[10])
+		t.FailNow()
+	}
+}
+
+func TestDispatchCanceled(t *testing.T) {
+	rate, limit := C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT
+	defer func() { C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT = rate, limit }()
+
+	C2_DISPATCH_RATE, C2_DISPATCH_HOST_LIMIT = 0, 1
+
+	var (
+		hosts = map[string]string{"vm-0": "dispatch-cancel-host", "vm-1": "dispatch-cancel-host"}
+		tasks = []C2Task{{VM: "vm-0"}, {VM: "vm-1"}}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		started     = make(chan struct{}, 2)
+	)
+
+	exec := func(ctx context.Context, task C2Task) (string, string, error) {
+		started <- struct{}{}
+
+		<-ctx.Done()
+		return "", "", ctx.Err()
+	}
+
+	batch := dispatch(ctx, tasks, hosts, exec, c2DispatchOptions{})
+
+	// Only one command can be in flight for the host, so the other is still
+	// queued when the dispatch is canceled.
+	<-started
+	cancel()
+
+	<-batch.Done()
+
+	p := batch.Progress()
+
+	if p.Failed != 1 || p.Canceled != 1 {
+		t.Logf("expected one failed and one canceled command, got %+v", p)
+		t.FailNow()
+	}
+}
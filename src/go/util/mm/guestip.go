@@ -0,0 +1,485 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+)
+
+// GUEST_IP_TTL is how long a guest IP discovered via `DiscoverGuestIPs` is
+// kept for once it's no longer observed (ie. a transient ARP entry or a guest
+// whose cc agent stopped checking in) before it's dropped.
+var GUEST_IP_TTL = 5 * time.Minute
+
+// GUEST_IP_DHCP_LEASES are the DHCP lease files read from each cluster host
+// when discovering guest IPs. Globs are expanded by the host's shell.
+var GUEST_IP_DHCP_LEASES = []string{"/tmp/minimega/dnsmasq*/dnsmasq.leases", "/var/lib/misc/dnsmasq.leases"}
+
+// GuestIPSource is where a guest IP was discovered.
+type GuestIPSource string
+
+const (
+	GUEST_IP_CC   GuestIPSource = "cc"
+	GUEST_IP_ARP  GuestIPSource = "arp"
+	GUEST_IP_DHCP GuestIPSource = "dhcp"
+)
+
+// priority is used to pick between different addresses discovered for the same
+// interface at the same time. Addresses reported by a VM's cc agent are
+// preferred since they come from the guest itself, and ARP entries are
+// preferred over DHCP leases since leases outlive guests being reconfigured.
+func (this GuestIPSource) priority() int {
+	switch this {
+	case GUEST_IP_CC:
+		return 3
+	case GUEST_IP_ARP:
+		return 2
+	case GUEST_IP_DHCP:
+		return 1
+	}
+
+	return 0
+}
+
+// GuestIP is an IPv4 address discovered for a running VM. Interface is the
+// index of the VM interface the address was observed on, or -1 if it couldn't
+// be mapped to an interface (ie. a cc agent reporting addresses for a VM with
+// several unaddressed interfaces).
+type GuestIP struct {
+	Interface int           `json:"interface"`
+	MAC       string        `json:"mac,omitempty"`
+	Address   string        `json:"address"`
+	Source    GuestIPSource `json:"source"`
+	Seen      time.Time     `json:"seen"`
+}
+
+var guestIPs = struct {
+	sync.Mutex
+
+	ips map[string]map[string][]GuestIP
+}{
+	ips: make(map[string]map[string][]GuestIP),
+}
+
+// StartGuestIPDiscovery discovers the guest IPs of VMs in each namespace
+// returned by the given function at the given interval until the given context
+// is canceled. Discovered IPs for namespaces no longer returned are dropped.
+func StartGuestIPDiscovery(ctx context.Context, interval time.Duration, namespaces func() []string) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			active := make(map[string]struct{})
+
+			for _, ns := range namespaces() {
+				active[ns] = struct{}{}
+
+				if err := DiscoverGuestIPs(ns); err != nil {
+					plog.Error("discovering guest IPs", "ns", ns, "err", err)
+				}
+			}
+
+			guestIPs.Lock()
+
+			for ns := range guestIPs.ips {
+				if _, ok := active[ns]; !ok {
+					delete(guestIPs.ips, ns)
+				}
+			}
+
+			guestIPs.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// DiscoverGuestIPs collects the IPs of VMs in the given namespace from the
+// addresses their cc agents check in with, the DHCP leases on their cluster
+// hosts, and their cluster hosts' ARP tables. Discovered IPs are merged into the
+// VM info returned by `GetVMInfo`, taking precedence over the IPs minimega
+// reports.
+func DiscoverGuestIPs(ns string) error {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"uuid", "host", "name", "mac", "ip"}
+
+	var (
+		vms   []guestVM
+		hosts = make(map[string]struct{})
+	)
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		vm := guestVM{
+			name: row["name"],
+			uuid: row["uuid"],
+			ips:  splitList(row["ip"]),
+		}
+
+		for _, mac := range splitList(row["mac"]) {
+			vm.macs = append(vm.macs, strings.ToLower(mac))
+		}
+
+		vms = append(vms, vm)
+		hosts[row["host"]] = struct{}{}
+	}
+
+	if len(vms) == 0 {
+		return nil
+	}
+
+	cc := make(map[string][]string)
+
+	cmd = mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "cc client"
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		cc[row["uuid"]] = parseAddrList(row["ip"])
+	}
+
+	var (
+		leases = make(map[string]string)
+		neigh  = make(map[string]string)
+		errs   []string
+	)
+
+	for host := range hosts {
+		files := strings.Join(GUEST_IP_DHCP_LEASES, " ")
+
+		if resp, err := MeshShellResponse(host, fmt.Sprintf(`bash -c "cat %s 2> /dev/null"`, files)); err == nil {
+			for mac, ip := range parseDHCPLeases(resp) {
+				leases[mac] = ip
+			}
+		} else {
+			errs = append(errs, fmt.Sprintf("reading DHCP leases on %s: %v", host, err))
+		}
+
+		if resp, err := MeshShellResponse(host, "ip -4 neigh show"); err == nil {
+			for mac, ip := range parseNeighbors(resp) {
+				neigh[mac] = ip
+			}
+		} else {
+			errs = append(errs, fmt.Sprintf("reading ARP table on %s: %v", host, err))
+		}
+	}
+
+	discovered := resolveGuestIPs(vms, cc, leases, neigh, time.Now())
+
+	guestIPs.Lock()
+	guestIPs.ips[ns] = mergeGuestIPs(guestIPs.ips[ns], discovered, time.Now())
+	guestIPs.Unlock()
+
+	InvalidateVMInfo(ns)
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// applyGuestIPs sets the discovered IPs for each VM in the given namespace, and
+// replaces the IPs reported by minimega with the best address discovered for
+// each interface.
+func applyGuestIPs(ns string, vms VMs) {
+	guestIPs.Lock()
+	defer guestIPs.Unlock()
+
+	discovered := guestIPs.ips[ns]
+
+	for i, vm := range vms {
+		ips := discovered[vm.Name]
+		if len(ips) == 0 {
+			continue
+		}
+
+		vm.GuestIPs = append([]GuestIP(nil), ips...)
+
+		for len(vm.IPv4) < len(vm.Networks) {
+			vm.IPv4 = append(vm.IPv4, "")
+		}
+
+		for iface, ip := range bestGuestIPs(ips) {
+			if iface < len(vm.IPv4) {
+				vm.IPv4[iface] = ip.Address
+			}
+		}
+
+		vms[i] = vm
+	}
+}
+
+type guestVM struct {
+	name string
+	uuid string
+	macs []string
+	ips  []string
+}
+
+// resolveGuestIPs maps the addresses observed for VMs to their interfaces. DHCP
+// leases and ARP entries are mapped via interface MAC addresses. Addresses
+// reported by cc agents are mapped to the interface minimega reports the same
+// address for, the interface the address was otherwise observed on, the VM's
+// only interface, or the only interface left without an address.
+func resolveGuestIPs(vms []guestVM, cc map[string][]string, leases, neigh map[string]string, now time.Time) map[string][]GuestIP {
+	discovered := make(map[string][]GuestIP)
+
+	for _, vm := range vms {
+		var (
+			ips    []GuestIP
+			mapped = make(map[string]int)
+		)
+
+		for iface, mac := range vm.macs {
+			if ip, ok := leases[mac]; ok {
+				ips = append(ips, GuestIP{Interface: iface, MAC: mac, Address: ip, Source: GUEST_IP_DHCP, Seen: now})
+				mapped[ip] = iface
+			}
+
+			if ip, ok := neigh[mac]; ok {
+				ips = append(ips, GuestIP{Interface: iface, MAC: mac, Address: ip, Source: GUEST_IP_ARP, Seen: now})
+				mapped[ip] = iface
+			}
+		}
+
+		for iface, ip := range vm.ips {
+			if ip != "" {
+				if _, ok := mapped[ip]; !ok {
+					mapped[ip] = iface
+				}
+			}
+		}
+
+		var unmapped []string
+
+		for _, ip := range cc[vm.uuid] {
+			if iface, ok := mapped[ip]; ok {
+				ips = append(ips, ccGuestIP(vm, iface, ip, now))
+			} else {
+				unmapped = append(unmapped, ip)
+			}
+		}
+
+		if len(unmapped) > 0 {
+			addressed := make(map[int]bool)
+
+			for _, iface := range mapped {
+				addressed[iface] = true
+			}
+
+			var empty []int
+
+			for iface := range vm.macs {
+				if !addressed[iface] {
+					empty = append(empty, iface)
+				}
+			}
+
+			for _, ip := range unmapped {
+				iface := -1
+
+				if len(vm.macs) == 1 {
+					iface = 0
+				} else if len(empty) == 1 && len(unmapped) == 1 {
+					iface = empty[0]
+				}
+
+				ips = append(ips, ccGuestIP(vm, iface, ip, now))
+			}
+		}
+
+		if len(ips) > 0 {
+			discovered[vm.name] = ips
+		}
+	}
+
+	return discovered
+}
+
+func ccGuestIP(vm guestVM, iface int, ip string, now time.Time) GuestIP {
+	g := GuestIP{Interface: iface, Address: ip, Source: GUEST_IP_CC, Seen: now}
+
+	if iface >= 0 && iface < len(vm.macs) {
+		g.MAC = vm.macs[iface]
+	}
+
+	return g
+}
+
+// mergeGuestIPs combines newly discovered IPs with previously discovered ones,
+// keeping previous IPs that weren't observed again unless they haven't been
+// seen within GUEST_IP_TTL.
+func mergeGuestIPs(prev, discovered map[string][]GuestIP, now time.Time) map[string][]GuestIP {
+	merged := make(map[string][]GuestIP)
+
+	key := func(ip GuestIP) string {
+		return fmt.Sprintf("%d/%s/%s", ip.Interface, ip.Source, ip.Address)
+	}
+
+	for vm, ips := range discovered {
+		merged[vm] = append(merged[vm], ips...)
+	}
+
+	for vm, ips := range prev {
+		seen := make(map[string]struct{})
+
+		for _, ip := range merged[vm] {
+			seen[key(ip)] = struct{}{}
+		}
+
+		for _, ip := range ips {
+			if _, ok := seen[key(ip)]; ok {
+				continue
+			}
+
+			if now.Sub(ip.Seen) < GUEST_IP_TTL {
+				merged[vm] = append(merged[vm], ip)
+			}
+		}
+	}
+
+	for _, ips := range merged {
+		sort.SliceStable(ips, func(i, j int) bool {
+			if ips[i].Interface != ips[j].Interface {
+				return ips[i].Interface < ips[j].Interface
+			}
+
+			return ips[i].Seen.After(ips[j].Seen)
+		})
+	}
+
+	return merged
+}
+
+// bestGuestIPs returns the most recently seen address for each interface,
+// preferring addresses from higher priority sources when seen at the same time.
+func bestGuestIPs(ips []GuestIP) map[int]GuestIP {
+	best := make(map[int]GuestIP)
+
+	for _, ip := range ips {
+		if ip.Interface < 0 {
+			continue
+		}
+
+		cur, ok := best[ip.Interface]
+
+		switch {
+		case !ok, ip.Seen.After(cur.Seen):
+			best[ip.Interface] = ip
+		case ip.Seen.Equal(cur.Seen) && ip.Source.priority() > cur.Source.priority():
+			best[ip.Interface] = ip
+		}
+	}
+
+	return best
+}
+
+// parseDHCPLeases parses dnsmasq lease files, returning leased IPv4 addresses
+// keyed by MAC address. Each lease line looks like the following:
+//
+//	1718900000 52:54:00:12:34:56 10.0.0.5 hostname 01:52:54:00:12:34:56
+func parseDHCPLeases(data string) map[string]string {
+	leases := make(map[string]string)
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		if ip := parseIPv4(fields[2]); ip != "" {
+			leases[strings.ToLower(fields[1])] = ip
+		}
+	}
+
+	return leases
+}
+
+// parseNeighbors parses the output of `ip -4 neigh show`, returning IPv4
+// addresses keyed by MAC address. Entries that never resolved are skipped. Each
+// entry looks like the following:
+//
+//	10.0.0.5 dev mega_tap1 lladdr 52:54:00:12:34:56 REACHABLE
+func parseNeighbors(data string) map[string]string {
+	neigh := make(map[string]string)
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[len(fields)-1] {
+		case "FAILED", "INCOMPLETE":
+			continue
+		}
+
+		ip := parseIPv4(fields[0])
+		if ip == "" {
+			continue
+		}
+
+		for i, f := range fields[:len(fields)-1] {
+			if f == "lladdr" {
+				neigh[strings.ToLower(fields[i+1])] = ip
+				break
+			}
+		}
+	}
+
+	return neigh
+}
+
+// parseAddrList parses a list of addresses as reported by minimega (ie.
+// `[10.0.0.5/24 fe80::1/64]`), returning the usable IPv4 addresses.
+func parseAddrList(s string) []string {
+	var ips []string
+
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if ip := parseIPv4(strings.Trim(f, `"`)); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// parseIPv4 returns the given address (which may include a prefix length) if
+// it's an IPv4 address usable by other hosts, or an empty string otherwise.
+func parseIPv4(s string) string {
+	s, _, _ = strings.Cut(s, "/")
+
+	ip := net.ParseIP(s).To4()
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return ""
+	}
+
+	return ip.String()
+}
+
+func splitList(s string) []string {
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ", ")
+}
@@ -0,0 +1,105 @@
+package mm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseGuestIPSources(t *testing.T) {
+	leases := parseDHCPLeases(`
+1718900000 52:54:00:AA:00:01 10.0.0.5 vm-1 01:52:54:00:aa:00:01
+1718900000 52:54:00:aa:00:02 fe80::1 vm-2 *
+bogus
+`)
+
+	if len(leases) != 1 || leases["52:54:00:aa:00:01"] != "10.0.0.5" {
+		t.Logf("unexpected DHCP leases: %v", leases)
+		t.FailNow()
+	}
+
+	neigh := parseNeighbors(`
+10.0.0.6 dev mega_tap1 lladdr 52:54:00:aa:00:02 REACHABLE
+10.0.0.7 dev mega_tap1 lladdr 52:54:00:aa:00:03 STALE
+10.0.0.8 dev mega_tap1 FAILED
+10.0.0.9 dev mega_tap1 INCOMPLETE
+`)
+
+	if len(neigh) != 2 || neigh["52:54:00:aa:00:02"] != "10.0.0.6" || neigh["52:54:00:aa:00:03"] != "10.0.0.7" {
+		t.Logf("unexpected ARP entries: %v", neigh)
+		t.FailNow()
+	}
+
+	ips := parseAddrList(`[127.0.0.1/8 10.0.0.10/24 169.254.1.1/16 fe80::1/64 "192.168.1.5"]`)
+
+	if len(ips) != 2 || ips[0] != "10.0.0.10" || ips[1] != "192.168.1.5" {
+		t.Logf("unexpected cc addresses: %v", ips)
+		t.FailNow()
+	}
+}
+
+func TestResolveGuestIPs(t *testing.T) {
+	now := time.Now()
+
+	vms := []guestVM{
+		{name: "dhcp", uuid: "1", macs: []string{"00:00:00:00:01:00", "00:00:00:00:01:01"}},
+		{name: "reconfigured", uuid: "2", macs: []string{"00:00:00:00:02:00"}},
+		{name: "cc", uuid: "3", macs: []string{"00:00:00:00:03:00", "00:00:00:00:03:01"}, ips: []string{"10.0.0.30", ""}},
+		{name: "ambiguous", uuid: "4", macs: []string{"00:00:00:00:04:00", "00:00:00:00:04:01"}},
+	}
+
+	var (
+		cc = map[string][]string{
+			"2": {"10.0.0.21"},
+			"3": {"10.0.0.30", "10.0.1.30"},
+			"4": {"10.0.0.40", "10.0.1.40"},
+		}
+
+		leases = map[string]string{"00:00:00:00:01:01": "10.0.1.10", "00:00:00:00:02:00": "10.0.0.20"}
+		neigh  = map[string]string{"00:00:00:00:02:00": "10.0.0.21"}
+	)
+
+	discovered := resolveGuestIPs(vms, cc, leases, neigh, now)
+
+	best := func(vm string) map[int]GuestIP {
+		return bestGuestIPs(discovered[vm])
+	}
+
+	if ips := best("dhcp"); len(ips) != 1 || ips[1].Address != "10.0.1.10" || ips[1].Source != GUEST_IP_DHCP {
+		t.Logf("unexpected IPs for DHCP VM: %+v", ips)
+		t.FailNow()
+	}
+
+	// The ARP entry (and cc agent) for the new address wins over the stale lease.
+	if ips := best("reconfigured"); ips[0].Address != "10.0.0.21" || ips[0].Source != GUEST_IP_CC {
+		t.Logf("unexpected IPs for reconfigured VM: %+v", ips)
+		t.FailNow()
+	}
+
+	if ips := best("cc"); ips[0].Address != "10.0.0.30" || ips[1].Address != "10.0.1.30" {
+		t.Logf("unexpected IPs for cc VM: %+v", ips)
+		t.FailNow()
+	}
+
+	for _, ip := range discovered["ambiguous"] {
+		if ip.Interface != -1 {
+			t.Logf("expected ambiguous cc addresses to be unmapped, got %+v", ip)
+			t.FailNow()
+		}
+	}
+
+	// Addresses no longer observed are kept until GUEST_IP_TTL elapses.
+	later := now.Add(time.Minute)
+	merged := mergeGuestIPs(discovered, map[string][]GuestIP{"dhcp": {{Interface: 0, Address: "10.0.0.11", Source: GUEST_IP_ARP, Seen: later}}}, later)
+
+	if ips := bestGuestIPs(merged["dhcp"]); len(ips) != 2 || ips[0].Address != "10.0.0.11" || ips[1].Address != "10.0.1.10" {
+		t.Logf("unexpected merged IPs: %+v", ips)
+		t.FailNow()
+	}
+
+	merged = mergeGuestIPs(discovered, nil, now.Add(GUEST_IP_TTL))
+
+	if len(merged) != 0 {
+		t.Logf("expected expired IPs to be dropped, got %+v", merged)
+		t.FailNow()
+	}
+}
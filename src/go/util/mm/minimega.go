@@ -16,6 +16,7 @@ import (
 
 	"phenix/util/common"
 	"phenix/util/mm/mmcli"
+	"phenix/util/perror"
 	"phenix/util/plog"
 
 	"github.com/hashicorp/go-multierror"
@@ -51,6 +52,8 @@ func (Minimega) ReadScriptFromFile(filename string) error {
 }
 
 func (Minimega) ClearNamespace(ns string) error {
+	defer InvalidateVMInfo(ns)
+
 	cmd := mmcli.NewCommand()
 	cmd.Command = "clear namespace " + ns
 
@@ -62,6 +65,8 @@ func (Minimega) ClearNamespace(ns string) error {
 }
 
 func (Minimega) LaunchVMs(ns string, start ...string) error {
+	defer InvalidateVMInfo(ns)
+
 	cmd := mmcli.NewNamespacedCommand(ns)
 	cmd.Command = "vm launch"
 
@@ -76,11 +81,18 @@ func (Minimega) LaunchVMs(ns string, start ...string) error {
 			return fmt.Errorf("starting VMs: %w", err)
 		}
 	} else {
-		for _, name := range start {
-			cmd.Command = "vm start " + name
+		// Start VMs in a single round-trip to minimega since there could be
+		// thousands of them.
+		cmds := make([]*mmcli.Command, len(start))
+
+		for i, name := range start {
+			cmds[i] = mmcli.NewNamespacedCommand(ns)
+			cmds[i].Command = "vm start " + name
+		}
 
-			if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-				return fmt.Errorf("starting VM %s: %w", name, err)
+		for i, resp := range mmcli.RunBatch(cmds...) {
+			if err := mmcli.ErrorResponse(resp); err != nil {
+				return fmt.Errorf("starting VM %s: %w", start[i], err)
 			}
 		}
 	}
@@ -134,6 +146,19 @@ func (Minimega) GetLaunchProgress(ns string, expected int) (float64, error) {
 func (this Minimega) GetVMInfo(opts ...Option) VMs {
 	o := NewOptions(opts...)
 
+	if o.cached {
+		if vms, ok := cachedVMInfo(o.ns, o.vm); ok {
+			return vms
+		}
+	}
+
+	vms := this.getVMInfo(o)
+	cacheVMInfo(o.ns, o.vm, vms)
+
+	return vms
+}
+
+func (this Minimega) getVMInfo(o options) VMs {
 	// don't rely on `cc_active` column in `vm info` table
 	activeC2 := getActiveC2(o.ns)
 
@@ -241,6 +266,8 @@ func (this Minimega) GetVMInfo(opts ...Option) VMs {
 		vms = append(vms, vm)
 	}
 
+	applyGuestIPs(o.ns, vms)
+
 	return vms
 }
 
@@ -304,6 +331,8 @@ func (Minimega) GetVNCEndpoint(opts ...Option) (string, error) {
 func (Minimega) StartVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("vm start %s", o.vm)
 
@@ -317,6 +346,8 @@ func (Minimega) StartVM(opts ...Option) error {
 func (Minimega) StopVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("vm stop %s", o.vm)
 
@@ -330,6 +361,8 @@ func (Minimega) StopVM(opts ...Option) error {
 func (Minimega) RedeployVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 
 	// Get VM info before killing VM below.
@@ -442,6 +475,8 @@ func (Minimega) RedeployVM(opts ...Option) error {
 func (Minimega) KillVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("vm kill %s", o.vm)
 
@@ -489,6 +524,8 @@ func (Minimega) GetVMState(opts ...Option) (string, error) {
 func (Minimega) ConnectVMInterface(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("vm net connect %s %d %s", o.vm, o.connectIface, o.connectVLAN)
 
@@ -502,6 +539,8 @@ func (Minimega) ConnectVMInterface(opts ...Option) error {
 func (Minimega) DisconnectVMInterface(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("vm net disconnect %s %d", o.vm, o.connectIface)
 
@@ -604,6 +643,8 @@ func (Minimega) CloseTunnel(opts ...Option) error {
 func (Minimega) StartVMCapture(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	defer InvalidateVMInfo(o.ns)
+
 	captures := GetVMCaptures(opts...)
 
 	for _, capture := range captures {
@@ -646,6 +687,8 @@ func (Minimega) StartVMCapture(opts ...Option) error {
 }
 
 func (Minimega) StopVMCapture(opts ...Option) error {
+	defer InvalidateVMInfo(NewOptions(opts...).ns)
+
 	captures := GetVMCaptures(opts...)
 
 	if len(captures) == 0 {
@@ -758,6 +801,9 @@ func (this Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
 		host.DiskUsage.Phenix = this.getDiskUsage(host.Name, common.PhenixBase)
 		host.DiskUsage.Minimega = this.getDiskUsage(host.Name, common.MinimegaBase)
 
+		// Add capabilities
+		host.Capabilities = this.getCapabilities(host.Name)
+
 		cluster = append(cluster, host)
 	}
 
@@ -771,6 +817,9 @@ func (this Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
 	head.DiskUsage.Phenix = this.getDiskUsage(head.Name, common.PhenixBase)
 	head.DiskUsage.Minimega = this.getDiskUsage(head.Name, common.MinimegaBase)
 
+	// Add capabilities
+	head.Capabilities = this.getCapabilities(head.Name)
+
 	cluster = append(cluster, head)
 
 	return cluster, nil
@@ -792,6 +841,9 @@ func (this Minimega) GetNamespaceHosts(ns string) (Hosts, error) {
 		host.DiskUsage.Phenix = this.getDiskUsage(host.Name, common.PhenixBase)
 		host.DiskUsage.Minimega = this.getDiskUsage(host.Name, common.MinimegaBase)
 
+		// Add capabilities
+		host.Capabilities = this.getCapabilities(host.Name)
+
 		hosts = append(hosts, host)
 	}
 
@@ -970,6 +1022,10 @@ func (this Minimega) ExecC2Command(opts ...C2Option) (string, error) {
 	if o.command != "" {
 		cmd := fmt.Sprintf("cc exec %s", o.command)
 
+		if o.background {
+			cmd = fmt.Sprintf("cc background %s", o.command)
+		}
+
 		id, err := exec(o.ns, o.vm, cmd)
 		if err != nil {
 			return "", fmt.Errorf("calling '%s' for vm %s: %w", cmd, o.vm, err)
@@ -1338,7 +1394,7 @@ func inject(disk string, part int, injects ...string) error {
 	cmd.Command = fmt.Sprintf("disk inject %s:%d files %s", disk, part, files)
 
 	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("injecting files into disk %s: %w", disk, err)
+		return perror.Errorf(perror.CodeInjection, "disk/"+disk, "injecting files into disk %s: %w", disk, err)
 	}
 
 	return nil
@@ -5,11 +5,6 @@ package mmcli
 import (
 	"errors"
 	"fmt"
-	"strings"
-	"sync"
-	"time"
-
-	"phenix/util/common"
 
 	"github.com/activeshadow/libminimega/minicli"
 	"github.com/activeshadow/libminimega/miniclient"
@@ -18,11 +13,6 @@ import (
 
 var ErrTimeout = fmt.Errorf("timeout running command")
 
-var (
-	mu sync.Mutex
-	mm *miniclient.Conn
-)
-
 // noop returns a closed channel
 func noop() chan *miniclient.Response {
 	out := make(chan *miniclient.Response)
@@ -34,7 +24,15 @@ func noop() chan *miniclient.Response {
 func wrapErr(err error) chan *miniclient.Response {
 	out := make(chan *miniclient.Response, 1)
 
-	out <- &miniclient.Response{
+	out <- errResponse(err)
+
+	close(out)
+
+	return out
+}
+
+func errResponse(err error) *miniclient.Response {
+	return &miniclient.Response{
 		Resp: minicli.Responses{
 			&minicli.Response{
 				Error: err.Error(),
@@ -42,10 +40,6 @@ func wrapErr(err error) chan *miniclient.Response {
 		},
 		More: false,
 	}
-
-	close(out)
-
-	return out
 }
 
 // ErrorResponse is used when only concerned with errors returned from a call to
@@ -142,55 +136,115 @@ func SingleDataResponse(responses chan *miniclient.Response) (interface{}, error
 	return data, err
 }
 
-// Run dials the minimega Unix socket and runs the given command, automatically
-// redialing if disconnected. Any errors encountered will be returned as part of
-// the response channel.
+// Run runs the given command on a connection from the pool of connections to
+// the minimega Unix socket, dialing a new connection if none are idle. If the
+// command has a timeout, it applies to waiting for a connection and to waiting
+// for each response. Any errors encountered will be returned as part of the
+// response channel.
 func Run(c *Command) chan *miniclient.Response {
-	mu.Lock()
-	defer mu.Unlock()
+	p := getPool()
 
-	var err error
+	conn, err := p.get(c.Timeout)
+	if err != nil {
+		return wrapErr(err)
+	}
 
-	if mm == nil {
-		if mm, err = miniclient.Dial(common.MinimegaBase); err != nil {
-			return wrapErr(fmt.Errorf("unable to dial: %w", err))
-		}
+	if err := conn.send(c.String()); err != nil {
+		p.put(conn, err)
+		return wrapErr(fmt.Errorf("minimega error: %w", err))
 	}
 
-	// check if there's already an error and try to redial
-	if err := mm.Error(); err != nil {
-		s := err.Error()
+	out := make(chan *miniclient.Response)
 
-		if strings.Contains(s, "broken pipe") || strings.Contains(s, "no such file or directory") {
-			if mm, err = miniclient.Dial(common.MinimegaBase); err != nil {
-				return wrapErr(fmt.Errorf("unable to redial: %w", err))
+	go func() {
+		defer close(out)
 
-			}
-		} else {
-			return wrapErr(fmt.Errorf("minimega error: %w", err))
+		err := conn.recv(c.Timeout, func(resp *miniclient.Response) { out <- resp })
+		p.put(conn, err)
+
+		if err != nil {
+			out <- errResponse(err)
 		}
+	}()
+
+	return out
+}
+
+// RunBatch runs the given commands in a single round-trip to minimega by
+// pipelining them on one connection from the pool. Minimega runs the commands
+// in order, and the response channel for each command is returned at the same
+// index as the command, already filled and closed. Each command's timeout (if
+// any) applies to waiting for each of its responses. If the connection fails,
+// the command being read and all the commands after it get the error as their
+// response.
+func RunBatch(cmds ...*Command) []chan *miniclient.Response {
+	results := make([]chan *miniclient.Response, len(cmds))
+
+	if len(cmds) == 0 {
+		return results
 	}
 
-	if c.Timeout == 0 {
-		return mm.Run(c.String())
+	p := getPool()
+
+	conn, err := p.get(cmds[0].Timeout)
+	if err != nil {
+		for i := range results {
+			results[i] = wrapErr(err)
+		}
+
+		return results
 	}
 
-	var (
-		resp chan *miniclient.Response
-		done = make(chan struct{})
-	)
+	requests := make([]string, len(cmds))
+
+	for i, c := range cmds {
+		requests[i] = c.String()
+	}
+
+	// Send the requests while reading the responses so minimega doesn't block
+	// writing responses nobody is reading yet.
+	sent := make(chan error, 1)
 
 	go func() {
-		resp = mm.Run(c.String())
-		close(done)
+		err := conn.send(requests...)
+		if err != nil {
+			// Unblock the reader since the rest of the responses won't be coming.
+			conn.Close()
+		}
+
+		sent <- err
 	}()
 
-	select {
-	case <-done:
-		return resp
-	case <-time.After(c.Timeout):
-		// Reset mm since the miniclient has a lock that is likely still activated.
-		mm = nil
-		return wrapErr(ErrTimeout)
+	for i, c := range cmds {
+		if err != nil {
+			results[i] = wrapErr(err)
+			continue
+		}
+
+		var resps []*miniclient.Response
+
+		err = conn.recv(c.Timeout, func(resp *miniclient.Response) { resps = append(resps, resp) })
+		if err != nil {
+			// Unblock the sender (if still sending) since the connection won't be
+			// reused.
+			conn.Close()
+			resps = append(resps, errResponse(err))
+		}
+
+		results[i] = make(chan *miniclient.Response, len(resps))
+
+		for _, resp := range resps {
+			results[i] <- resp
+		}
+
+		close(results[i])
+	}
+
+	if sendErr := <-sent; err == nil {
+		err = sendErr
 	}
+
+	p.put(conn, err)
+
+	return results
 }
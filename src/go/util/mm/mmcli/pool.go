@@ -0,0 +1,158 @@
+package mmcli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"phenix/util/common"
+
+	"github.com/activeshadow/libminimega/miniclient"
+)
+
+// POOL_SIZE is how many connections to the local minimega instance can be open
+// at once, and thus how many commands can be run concurrently. Commands run
+// when all the connections are busy wait for one to become idle. Changes only
+// apply before the first command is run.
+var POOL_SIZE = 4
+
+var (
+	poolOnce sync.Once
+	connPool *pool
+)
+
+// conn is a single connection to the local minimega instance. Unlike
+// miniclient.Conn, requests can be pipelined on it, which is what allows
+// batches of commands to be run in a single round-trip.
+type conn struct {
+	net.Conn
+
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+func dial() (*conn, error) {
+	c, err := net.Dial("unix", path.Join(common.MinimegaBase, "minimega"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: c, enc: json.NewEncoder(c), dec: json.NewDecoder(c)}, nil
+}
+
+// send writes the given command requests to minimega.
+func (this *conn) send(cmds ...string) error {
+	for _, cmd := range cmds {
+		if err := this.enc.Encode(miniclient.Request{Command: cmd}); err != nil {
+			return fmt.Errorf("encoding command: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recv reads the responses to a single command from minimega, passing each one
+// to the given function. Minimega processes requests on a connection in order,
+// so the responses to pipelined commands are read in the order the commands
+// were sent. The given timeout applies to waiting for each response, with zero
+// meaning there's no timeout.
+func (this *conn) recv(timeout time.Duration, fn func(*miniclient.Response)) error {
+	for {
+		var (
+			resp     miniclient.Response
+			deadline time.Time
+		)
+
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+
+		this.SetReadDeadline(deadline)
+
+		if err := this.dec.Decode(&resp); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return ErrTimeout
+			}
+
+			return fmt.Errorf("minimega error: %w", err)
+		}
+
+		fn(&resp)
+
+		if !resp.More {
+			return nil
+		}
+	}
+}
+
+// pool limits the number of connections open to minimega at once, reusing idle
+// connections when possible. Connections that encounter an error (including
+// timeouts, since the rest of the responses may still be coming) are closed
+// rather than reused.
+type pool struct {
+	slots chan struct{}
+	idle  chan *conn
+}
+
+func getPool() *pool {
+	poolOnce.Do(func() {
+		size := POOL_SIZE
+
+		if size < 1 {
+			size = 1
+		}
+
+		connPool = &pool{
+			slots: make(chan struct{}, size),
+			idle:  make(chan *conn, size),
+		}
+	})
+
+	return connPool
+}
+
+// get returns an idle connection from the pool, dialing a new one if none are
+// idle. If the pool is full, it waits for a connection to be released or the
+// given timeout to expire (zero meaning there's no timeout).
+func (this *pool) get(timeout time.Duration) (*conn, error) {
+	if timeout > 0 {
+		select {
+		case this.slots <- struct{}{}:
+		case <-time.After(timeout):
+			return nil, ErrTimeout
+		}
+	} else {
+		this.slots <- struct{}{}
+	}
+
+	select {
+	case c := <-this.idle:
+		return c, nil
+	default:
+	}
+
+	c, err := dial()
+	if err != nil {
+		<-this.slots
+		return nil, fmt.Errorf("unable to dial: %w", err)
+	}
+
+	return c, nil
+}
+
+// put releases the given connection back to the pool, closing it if it
+// encountered an error.
+func (this *pool) put(c *conn, err error) {
+	if err != nil {
+		c.Close()
+	} else {
+		this.idle <- c
+	}
+
+	<-this.slots
+}
@@ -0,0 +1,98 @@
+package mmcli
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/util/common"
+
+	"github.com/activeshadow/libminimega/minicli"
+	"github.com/activeshadow/libminimega/miniclient"
+)
+
+// serve fakes the minimega command socket, echoing each command back as its
+// response (split over two responses to test correlation of multi-response
+// commands).
+func serve(t *testing.T) {
+	common.MinimegaBase = t.TempDir()
+
+	l, err := net.Listen("unix", filepath.Join(common.MinimegaBase, "minimega"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer c.Close()
+
+				var (
+					dec = json.NewDecoder(c)
+					enc = json.NewEncoder(c)
+				)
+
+				for {
+					var req miniclient.Request
+
+					if err := dec.Decode(&req); err != nil {
+						return
+					}
+
+					enc.Encode(miniclient.Response{Resp: minicli.Responses{{Response: req.Command}}, More: true})
+					enc.Encode(miniclient.Response{Resp: minicli.Responses{{Error: "done"}}})
+				}
+			}()
+		}
+	}()
+}
+
+func TestRunBatch(t *testing.T) {
+	serve(t)
+
+	var cmds []*Command
+
+	for _, vm := range []string{"foo", "bar", "baz"} {
+		cmd := NewNamespacedCommand("test")
+		cmd.Command = "vm start " + vm
+
+		cmds = append(cmds, cmd)
+	}
+
+	results := RunBatch(cmds...)
+
+	if len(results) != len(cmds) {
+		t.Logf("expected %d results, got %d", len(cmds), len(results))
+		t.FailNow()
+	}
+
+	for i, result := range results {
+		resp, err := SingleResponse(result)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if resp != cmds[i].String() {
+			t.Logf("expected response for %q, got %q", cmds[i].String(), resp)
+			t.FailNow()
+		}
+	}
+
+	// Connections used for batches are released back to the pool.
+	resp, _ := SingleResponse(Run(cmds[0]))
+	if !strings.HasSuffix(resp, "vm start foo") {
+		t.Logf("expected response for vm start foo, got %q", resp)
+		t.FailNow()
+	}
+}
@@ -32,6 +32,8 @@ type options struct {
 	srcPort int
 	dstPort int
 	dstHost string
+
+	cached bool
 }
 
 func NewOptions(opts ...Option) options {
@@ -56,6 +58,14 @@ func VMName(v string) Option {
 	}
 }
 
+// Cached allows VM info to be returned from the VM info cache if it was
+// collected within the last VM_INFO_TTL.
+func Cached() Option {
+	return func(o *options) {
+		o.cached = true
+	}
+}
+
 func CPU(c int) Option {
 	return func(o *options) {
 		o.cpu = c
@@ -170,8 +180,9 @@ type c2Options struct {
 
 	mount *bool
 
-	timeout time.Duration
-	wait    bool
+	timeout    time.Duration
+	wait       bool
+	background bool
 
 	skipActiveClientCheck bool
 
@@ -260,6 +271,14 @@ func C2Wait() C2Option {
 	}
 }
 
+// C2Background causes the C2 command to be executed in the background on the
+// VM (via `cc background`) instead of waiting for it to complete.
+func C2Background() C2Option {
+	return func(o *c2Options) {
+		o.background = true
+	}
+}
+
 func C2SkipActiveClientCheck(s bool) C2Option {
 	return func(o *c2Options) {
 		o.skipActiveClientCheck = s
@@ -138,6 +138,9 @@ type Host struct {
 	Uptime      float64   `json:"uptime"`
 	Schedulable bool      `json:"schedulable"`
 	Headnode    bool      `json:"headnode"`
+
+	// Capabilities will be nil if the host's capabilities couldn't be collected.
+	Capabilities *HostCapabilities `json:"capabilities,omitempty"`
 }
 
 type DiskUsage struct {
@@ -177,6 +180,36 @@ func (this VMs) SortByUptime(asc bool) {
 	})
 }
 
+func (this VMs) SortByState(asc bool) {
+	sort.SliceStable(this, func(i, j int) bool {
+		if asc {
+			return this[i].State < this[j].State
+		}
+
+		return this[i].State > this[j].State
+	})
+}
+
+func (this VMs) SortByCPUs(asc bool) {
+	sort.SliceStable(this, func(i, j int) bool {
+		if asc {
+			return this[i].CPUs < this[j].CPUs
+		}
+
+		return this[i].CPUs > this[j].CPUs
+	})
+}
+
+func (this VMs) SortByRAM(asc bool) {
+	sort.SliceStable(this, func(i, j int) bool {
+		if asc {
+			return this[i].RAM < this[j].RAM
+		}
+
+		return this[i].RAM > this[j].RAM
+	})
+}
+
 func (this VMs) SortBy(col string, asc bool) {
 	switch col {
 	case "name":
@@ -185,6 +218,12 @@ func (this VMs) SortBy(col string, asc bool) {
 		this.SortByHost(asc)
 	case "uptime":
 		this.SortByUptime(asc)
+	case "state":
+		this.SortByState(asc)
+	case "cpus":
+		this.SortByCPUs(asc)
+	case "ram":
+		this.SortByRAM(asc)
 	}
 }
 
@@ -230,6 +269,8 @@ type VM struct {
 	CdRom           string    `json:"cdRom"`
 	Tags            []string  `json:"tags"`
 	Snapshot        bool      `json:"snapshot"`
+	GuestIPs        []GuestIP `json:"guestIPs,omitempty"`
+	Persistent      bool      `json:"persistent,omitempty"`
 
 	// Used internally to track network <--> IP relationship, since
 	// network ordering from minimega may not be the same as network
@@ -266,6 +307,9 @@ func (this VM) Copy() VM {
 	vm.Tags = make([]string, len(this.Tags))
 	copy(vm.Tags, this.Tags)
 
+	vm.GuestIPs = make([]GuestIP, len(this.GuestIPs))
+	copy(vm.GuestIPs, this.GuestIPs)
+
 	return vm
 }
 
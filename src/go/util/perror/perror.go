@@ -0,0 +1,132 @@
+// Package perror provides structured phenix errors carrying a machine-readable
+// code and the failing resource, so automation can branch on the category of a
+// failure instead of matching on error strings.
+package perror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code is a machine-readable failure category.
+type Code string
+
+const (
+	// Validation errors are raised when configs, specs, or app settings fail
+	// validation.
+	CodeValidation Code = "validation"
+
+	// Scheduling errors are raised when VMs can't be scheduled on cluster hosts.
+	CodeScheduling Code = "scheduling"
+
+	// Injection errors are raised when files can't be generated for, or
+	// injected into, VM disks.
+	CodeInjection Code = "injection"
+
+	// Minimega errors are raised when minimega fails to execute a command.
+	CodeMinimega Code = "minimega"
+
+	// User app errors are raised when a user app fails.
+	CodeUserApp Code = "user-app"
+
+	// Dependency errors are raised when external resources an experiment depends
+	// on aren't available.
+	CodeDependency Code = "dependency"
+
+	// Conflict errors are raised when an experiment can't start because another
+	// member of one of its concurrency groups is running.
+	CodeConflict Code = "conflict"
+)
+
+// Sentinel errors for each code, for use with `errors.Is`.
+var (
+	ErrValidation = &Error{Code: CodeValidation}
+	ErrScheduling = &Error{Code: CodeScheduling}
+	ErrInjection  = &Error{Code: CodeInjection}
+	ErrMinimega   = &Error{Code: CodeMinimega}
+	ErrUserApp    = &Error{Code: CodeUserApp}
+	ErrDependency = &Error{Code: CodeDependency}
+	ErrConflict   = &Error{Code: CodeConflict}
+)
+
+// Error is a phenix error with a code and the resource that failed, formatted
+// as `<kind>/<name>` (ie. `experiment/foo`, `vm/foo/bar`, `app/soh`).
+type Error struct {
+	Code     Code
+	Resource string
+
+	err error
+}
+
+// New returns a new error with the given code and resource wrapping the given
+// error.
+func New(code Code, resource string, err error) *Error {
+	return &Error{Code: code, Resource: resource, err: err}
+}
+
+// Wrap returns the given error wrapped in a new error with the given code and
+// resource, unless it already carries a code, in which case it's returned as
+// is since the existing code is more specific. Nil errors are returned as is.
+func Wrap(code Code, resource string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+
+	if errors.As(err, &e) {
+		return err
+	}
+
+	return New(code, resource, err)
+}
+
+// Errorf is a convenience function for wrapping a formatted error in a new error
+// with the given code and resource.
+func Errorf(code Code, resource, format string, a ...any) *Error {
+	return New(code, resource, fmt.Errorf(format, a...))
+}
+
+// As returns the first error in the given error's chain with a code, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+
+	if errors.As(err, &e) {
+		return e, true
+	}
+
+	return nil, false
+}
+
+// CodeOf returns the code of the first error in the given error's chain with a
+// code, or an empty code if there isn't one.
+func CodeOf(err error) Code {
+	if e, ok := As(err); ok {
+		return e.Code
+	}
+
+	return ""
+}
+
+func (this Error) Error() string {
+	if this.err == nil {
+		return string(this.Code) + " error"
+	}
+
+	return this.err.Error()
+}
+
+func (this Error) Unwrap() error {
+	return this.err
+}
+
+// Is matches errors with the same code, so `errors.Is(err, ErrValidation)`
+// works anywhere in an error chain.
+func (this Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return t.Code == this.Code && (t.Resource == "" || t.Resource == this.Resource)
+}
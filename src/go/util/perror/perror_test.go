@@ -0,0 +1,41 @@
+package perror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	err := fmt.Errorf("applying apps: %w", Wrap(CodeUserApp, "app/foo", errors.New("exit status 1")))
+
+	if !errors.Is(err, ErrUserApp) {
+		t.Log("expected user app error")
+		t.FailNow()
+	}
+
+	if errors.Is(err, ErrValidation) {
+		t.Log("expected user app error to not match validation errors")
+		t.FailNow()
+	}
+
+	if !errors.Is(err, &Error{Code: CodeUserApp, Resource: "app/foo"}) || errors.Is(err, &Error{Code: CodeUserApp, Resource: "app/bar"}) {
+		t.Log("expected user app error to only match its own resource")
+		t.FailNow()
+	}
+
+	// Wrapping an already classified error keeps the more specific code.
+	inner := New(CodeScheduling, "experiment/foo", errors.New("no hosts"))
+	err = Wrap(CodeUserApp, "app/foo", fmt.Errorf("running scheduler: %w", inner))
+
+	e, ok := As(err)
+	if !ok || e.Code != CodeScheduling || e.Resource != "experiment/foo" {
+		t.Logf("expected scheduling error for experiment/foo, got %v", e)
+		t.FailNow()
+	}
+
+	if CodeOf(errors.New("foo")) != "" || Wrap(CodeMinimega, "experiment/foo", nil) != nil {
+		t.Log("expected untyped and nil errors to not be classified")
+		t.FailNow()
+	}
+}
@@ -9,8 +9,23 @@ import (
 	"strings"
 	"time"
 
+	"phenix/api/experiment"
+	"phenix/api/health"
+	"phenix/api/inventory"
+	"phenix/api/matrix"
+	"phenix/api/mirror"
+	"phenix/api/renumber"
+	"phenix/api/retention"
+	"phenix/api/smoke"
+	"phenix/api/usage"
+	"phenix/api/vlan"
+	"phenix/app"
 	"phenix/store"
 	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/audit"
+	"phenix/util/daemon"
+	"phenix/util/journal"
 	"phenix/util/mm"
 
 	"github.com/olekukonko/tablewriter"
@@ -110,6 +125,7 @@ func buildSingleVMTable(table *tablewriter.Table, vm mm.VM) {
 
 	var (
 		ifaces   []string
+		guestIPs []string
 		uptime   string
 		metadata []byte
 	)
@@ -118,6 +134,16 @@ func buildSingleVMTable(table *tablewriter.Table, vm mm.VM) {
 		ifaces = append(ifaces, fmt.Sprintf("ID: %d, IP: %s, VLAN: %s", idx, vm.IPv4[idx], nw))
 	}
 
+	for _, ip := range vm.GuestIPs {
+		iface := "unknown"
+
+		if ip.Interface >= 0 {
+			iface = strconv.Itoa(ip.Interface)
+		}
+
+		guestIPs = append(guestIPs, fmt.Sprintf("ID: %s, IP: %s, Source: %s, Seen: %s", iface, ip.Address, ip.Source, ip.Seen.Format(time.RFC3339)))
+	}
+
 	if vm.Running {
 		uptime = (time.Duration(vm.Uptime) * time.Second).String()
 	}
@@ -131,6 +157,11 @@ func buildSingleVMTable(table *tablewriter.Table, vm mm.VM) {
 	table.Append([]string{"Running", strconv.FormatBool(vm.Running)})
 	table.Append([]string{"Disk", vm.Disk})
 	table.Append([]string{"Interfaces", strings.Join(ifaces, "\n")})
+
+	if len(guestIPs) > 0 {
+		table.Append([]string{"Guest IPs", strings.Join(guestIPs, "\n")})
+	}
+
 	table.Append([]string{"Uptime", uptime})
 	table.Append([]string{"VCPUs", strconv.Itoa(vm.CPUs)})
 	table.Append([]string{"Memory", strconv.Itoa(vm.RAM)})
@@ -211,6 +242,47 @@ func PrintTableOfVLANAliases(writer io.Writer, info map[string]map[string]int) {
 	table.Render()
 }
 
+// PrintTableOfVLANStats writes the given VLAN stats to the given writer as an
+// ASCII table, with a row for each VLAN followed by a row for each of its taps.
+// Rates are left blank for taps that haven't been sampled before.
+func PrintTableOfVLANStats(writer io.Writer, stats []vlan.VLANStats) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"VLAN", "Tap", "VM", "Host", "RX Bytes", "TX Bytes", "RX Drops", "TX Drops", "RX B/s", "TX B/s"})
+
+	rates := func(r *vlan.Rates) []string {
+		if r == nil {
+			return []string{"", ""}
+		}
+
+		return []string{fmt.Sprintf("%.0f", r.RxBytes), fmt.Sprintf("%.0f", r.TxBytes)}
+	}
+
+	counters := func(c vlan.Counters) []string {
+		return []string{
+			strconv.FormatUint(c.RxBytes, 10), strconv.FormatUint(c.TxBytes, 10),
+			strconv.FormatUint(c.RxDropped, 10), strconv.FormatUint(c.TxDropped, 10),
+		}
+	}
+
+	for _, vs := range stats {
+		row := []string{fmt.Sprintf("%s (%d)", vs.Alias, vs.ID), "", "", ""}
+		row = append(row, counters(vs.Counters)...)
+		row = append(row, rates(&vs.Rates)...)
+
+		table.Append(row)
+
+		for _, ts := range vs.Taps {
+			row := []string{"", ts.Tap, fmt.Sprintf("%s[%d]", ts.VM, ts.Interface), ts.Host}
+			row = append(row, counters(ts.Counters)...)
+			row = append(row, rates(ts.Rates)...)
+
+			table.Append(row)
+		}
+	}
+
+	table.Render()
+}
+
 func PrintTableOfVLANRanges(writer io.Writer, info map[string][2]int) {
 	table := tablewriter.NewWriter(writer)
 	table.SetHeader([]string{"Experiment", "VLAN Range"})
@@ -242,3 +314,520 @@ func PrintTableOfSubnetCaptures(writer io.Writer, captures []mm.Capture) {
 
 	table.Render()
 }
+
+// PrintTableOfInventories writes the given VM inventories to the given writer
+// as an ASCII table. The table headers are set to Host, OS, Collected,
+// Packages, Processes, and Services.
+func PrintTableOfInventories(writer io.Writer, hosts map[string]inventory.Host) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Host", "OS", "Collected", "Packages", "Processes", "Services"})
+
+	var names []string
+
+	for name := range hosts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		inv := hosts[name]
+
+		table.Append([]string{
+			name,
+			inv.OSType,
+			inv.Collected,
+			strconv.Itoa(len(inv.Packages)),
+			strconv.Itoa(len(inv.Processes)),
+			strconv.Itoa(len(inv.Services)),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfInventoryMatches writes the given inventory query matches to the
+// given writer as an ASCII table. The table headers are set to Host, Package,
+// and Version.
+func PrintTableOfInventoryMatches(writer io.Writer, matches []inventory.Match) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Host", "Package", "Version"})
+
+	for _, m := range matches {
+		table.Append([]string{m.Hostname, m.Package, m.Version})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfUsage writes the given usage report to the given writer as an
+// ASCII table. The table headers are set to Name (User or Namespace),
+// Experiments, Hours, vCPU-Hours, Memory GB-Hours, and Disk GB-Hours.
+func PrintTableOfUsage(writer io.Writer, report *usage.Report) {
+	name := "User"
+
+	if report.GroupBy == usage.GROUP_BY_NAMESPACE {
+		name = "Namespace"
+	}
+
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{name, "Experiments", "Hours", "vCPU-Hours", "Memory GB-Hours", "Disk GB-Hours"})
+
+	for _, e := range report.Entries {
+		table.Append([]string{
+			e.Name,
+			strings.Join(e.Experiments, "\n"),
+			fmt.Sprintf("%.2f", e.Hours),
+			fmt.Sprintf("%.2f", e.VCPUHours),
+			fmt.Sprintf("%.2f", e.MemoryGBHours),
+			fmt.Sprintf("%.2f", e.DiskGBHours),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfApps writes the given installed user apps to the given writer as
+// an ASCII table.
+func PrintTableOfApps(writer io.Writer, apps ...types.App) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Name", "Version", "Pinnable Versions", "Description", "Source", "Installed"})
+
+	for _, a := range apps {
+		var versions []string
+
+		for v := range a.Spec.Versions {
+			versions = append(versions, v)
+		}
+
+		sort.Strings(versions)
+
+		table.Append([]string{a.Metadata.Name, a.Spec.Version, strings.Join(versions, ", "), a.Spec.Description, a.Spec.Source, a.Metadata.Updated})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfAppRuns writes the last run of each app for each experiment
+// lifecycle stage to the given writer as an ASCII table, sorted by app name and
+// start time.
+func PrintTableOfAppRuns(writer io.Writer, runs map[string]map[string]ifaces.AppRun) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"App", "Stage", "Result", "Started", "Ended", "Error"})
+	table.SetAutoWrapText(false)
+
+	var apps []string
+
+	for app := range runs {
+		apps = append(apps, app)
+	}
+
+	sort.Strings(apps)
+
+	for _, app := range apps {
+		var stages []string
+
+		for stage := range runs[app] {
+			stages = append(stages, stage)
+		}
+
+		sort.Slice(stages, func(i, j int) bool {
+			return runs[app][stages[i]].Start() < runs[app][stages[j]].Start()
+		})
+
+		for _, stage := range stages {
+			run := runs[app][stage]
+
+			// Failed attempts that were retried are listed before the run's result.
+			for i, attempt := range run.Attempts() {
+				table.Append([]string{app, fmt.Sprintf("%s (attempt %d)", stage, i+1), "error", "", attempt.Failed(), attempt.Error()})
+			}
+
+			table.Append([]string{app, stage, run.Result(), run.Start(), run.End(), run.Error()})
+		}
+	}
+
+	table.Render()
+}
+
+// PrintTableOfAppDiffs writes the given app diffs to the given writer as an
+// ASCII table, with a row for each change an app would make. Apps that
+// wouldn't change anything are listed with no changes.
+func PrintTableOfAppDiffs(writer io.Writer, diffs []app.AppDiff) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"App", "Stage", "Change", "Path", "Old", "New"})
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+
+	value := func(v any) string {
+		if v == nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%v", v)
+	}
+
+	for _, diff := range diffs {
+		var (
+			name  = diff.App
+			stage = string(diff.Stage)
+		)
+
+		if diff.Error != "" {
+			table.Append([]string{name, stage, "error", "", "", diff.Error})
+		} else if diff.Empty() {
+			table.Append([]string{name, stage, "none", "", "", ""})
+			continue
+		}
+
+		for _, node := range diff.NodesAdded {
+			table.Append([]string{name, stage, "node added", node, "", ""})
+		}
+
+		for _, node := range diff.NodesRemoved {
+			table.Append([]string{name, stage, "node removed", node, "", ""})
+		}
+
+		for _, inject := range diff.InjectionsAdded {
+			table.Append([]string{name, stage, "injection added", "nodes[" + inject.Node + "].injections", "", inject.Src + " -> " + inject.Dst})
+		}
+
+		for _, inject := range diff.InjectionsRemoved {
+			table.Append([]string{name, stage, "injection removed", "nodes[" + inject.Node + "].injections", inject.Src + " -> " + inject.Dst, ""})
+		}
+
+		for _, change := range diff.Changes {
+			kind := "changed"
+
+			if change.Old == nil {
+				kind = "added"
+			} else if change.New == nil {
+				kind = "removed"
+			}
+
+			table.Append([]string{name, stage, kind, change.Path, value(change.Old), value(change.New)})
+		}
+	}
+
+	table.Render()
+}
+
+// PrintTableOfArtifacts writes the given experiment artifacts to the given
+// writer as an ASCII table, with a row for each artifact.
+func PrintTableOfArtifacts(writer io.Writer, artifacts []experiment.Artifact) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"App", "Name", "Path", "MIME Type", "Size", "Created", "Description"})
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+
+	for _, a := range artifacts {
+		size := "missing"
+
+		if a.Exists {
+			size = strconv.FormatInt(a.Size, 10)
+		}
+
+		table.Append([]string{a.App, a.Name, a.Path, a.MIMEType, size, a.Created, a.Description})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfFirewallRules writes the given firewall rules to the given
+// writer as an ASCII table, with a row for each rule.
+func PrintTableOfFirewallRules(writer io.Writer, rules []app.FirewallNodeRules) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Host", "Backend", "Injected To", "Rule"})
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1, 2})
+
+	for _, node := range rules {
+		for _, rule := range node.Rules {
+			table.Append([]string{node.Host, node.Backend, node.Dst, rule})
+		}
+	}
+
+	table.Render()
+}
+
+// PrintTableOfVMHealth writes the given experiment health to the given writer
+// as an ASCII table, with a row for each VM check. Unless all is true, only
+// checks that aren't ok are included.
+func PrintTableOfVMHealth(writer io.Writer, report *health.Experiment, all bool) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"VM", "Health", "Source", "Check", "Severity", "Message"})
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0, 1})
+
+	for _, vm := range report.VMs {
+		var rows int
+
+		for _, check := range vm.Checks {
+			if !all && check.Severity == health.SEVERITY_OK {
+				continue
+			}
+
+			source := string(check.Source)
+
+			if check.App != "" {
+				source = fmt.Sprintf("%s (%s)", source, check.App)
+			}
+
+			table.Append([]string{vm.Name, string(vm.Severity), source, check.Name, string(check.Severity), check.Message})
+			rows++
+		}
+
+		if rows == 0 {
+			table.Append([]string{vm.Name, string(vm.Severity), "", "", "", ""})
+		}
+	}
+
+	table.Render()
+}
+
+// PrintTableOfSmokeResults writes the given smoke test results to the given
+// writer as an ASCII table, with a row for each failed SoH check or user check.
+func PrintTableOfSmokeResults(writer io.Writer, results []smoke.Result) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Test", "Start", "Duration", "Result", "Failures"})
+	table.SetAutoWrapText(false)
+
+	for _, res := range results {
+		var failures []string
+
+		if res.Error != "" {
+			failures = append(failures, res.Error)
+		}
+
+		failures = append(failures, res.SoH...)
+
+		for _, c := range res.Checks {
+			if !c.Passed {
+				failures = append(failures, fmt.Sprintf("check %s (%s): %s", c.Name, c.VM, c.Error))
+			}
+		}
+
+		result := "PASS"
+		if !res.Passed {
+			result = "FAIL"
+		}
+
+		table.Append([]string{
+			res.Test,
+			res.Start.Local().Format(time.RFC3339),
+			res.End.Sub(res.Start).Round(time.Second).String(),
+			result,
+			strings.Join(failures, "\n"),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfMatrixResults writes the given scenario test results to the given
+// writer as an ASCII table, with a row for each scenario and topology tested.
+func PrintTableOfMatrixResults(writer io.Writer, results []matrix.Result) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Scenario", "Topology", "Apps", "Result", "Failures"})
+	table.SetAutoWrapText(false)
+
+	for _, res := range results {
+		apps := make(map[string]struct{})
+
+		for _, d := range res.Apps {
+			apps[d.App] = struct{}{}
+		}
+
+		result := "PASS"
+		if !res.Passed {
+			result = "FAIL"
+		}
+
+		table.Append([]string{
+			res.Scenario,
+			res.Topology,
+			strconv.Itoa(len(apps)),
+			result,
+			strings.Join(res.Failures, "\n"),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfRetentionActions writes the files deleted (or that would be
+// deleted for a dry run) by the given retention report to the given writer as
+// an ASCII table.
+func PrintTableOfRetentionActions(writer io.Writer, report retention.Report) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Experiment", "Rule", "Path", "Size", "Date", "Deleted"})
+	table.SetAutoWrapText(false)
+
+	for _, a := range report.Actions {
+		table.Append([]string{
+			a.Experiment,
+			string(a.Rule),
+			a.Path,
+			retention.FormatSize(a.Size),
+			a.Date,
+			strconv.FormatBool(a.Deleted),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfPersistentDisks writes the given persistent disks to the given
+// writer as an ASCII table. Disks that haven't been created yet are listed as
+// pending.
+func PrintTableOfPersistentDisks(writer io.Writer, disks ...experiment.PersistentDisk) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Node", "Disk", "Status", "Host"})
+	table.SetAutoWrapText(false)
+
+	for _, d := range disks {
+		status := "created"
+
+		if d.Host == "" {
+			status = "pending"
+		}
+
+		table.Append([]string{d.Node, d.Disk, status, d.Host})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfPreloadTransfers writes the given experiment image preload
+// transfers to the given writer as an ASCII table.
+func PrintTableOfPreloadTransfers(writer io.Writer, transfers ...experiment.PreloadTransfer) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Host", "Image", "Status"})
+	table.SetAutoWrapText(false)
+
+	for _, t := range transfers {
+		status := "copied"
+
+		if t.Error != "" {
+			status = t.Error
+		}
+
+		table.Append([]string{t.Host, t.Image, status})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfMirrorRepos writes the given package mirror repositories to the
+// given writer as an ASCII table.
+func PrintTableOfMirrorRepos(writer io.Writer, repos ...mirror.Repo) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Type", "Path", "Files", "Size"})
+	table.SetAutoWrapText(false)
+
+	for _, r := range repos {
+		table.Append([]string{r.Type, r.Path, strconv.Itoa(r.Files), retention.FormatSize(r.Size)})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfDaemons writes the given app daemons to the given writer as an
+// ASCII table.
+func PrintTableOfDaemons(writer io.Writer, daemons ...daemon.Daemon) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Experiment", "Name", "Command", "Restart", "State", "PID", "Restarts", "Last Exit"})
+	table.SetAutoWrapText(false)
+
+	for _, d := range daemons {
+		var pid string
+
+		if d.Status.PID > 0 {
+			pid = strconv.Itoa(d.Status.PID)
+		}
+
+		table.Append([]string{
+			d.Experiment,
+			d.Name,
+			strings.Join(append([]string{d.Command}, d.Args...), " "),
+			string(d.Restart),
+			d.Status.State,
+			pid,
+			strconv.Itoa(d.Status.Restarts),
+			d.Status.LastExit,
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfAuditEvents writes the given audit events to the given writer as
+// an ASCII table, with each event's details listed below its action.
+func PrintTableOfAuditEvents(writer io.Writer, events []audit.Event) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Time", "User", "Action", "Resource"})
+	table.SetAutoWrapText(false)
+
+	for _, e := range events {
+		var details []string
+
+		for k, v := range e.Details {
+			details = append(details, fmt.Sprintf("%s: %v", k, v))
+		}
+
+		sort.Strings(details)
+
+		action := e.Action
+
+		if len(details) > 0 {
+			action += "\n" + strings.Join(details, "\n")
+		}
+
+		table.Append([]string{e.Time.Local().Format(time.RFC3339), e.User, action, e.Resource})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfJournalEntries writes the given experiment timeline entries to
+// the given writer as an ASCII table.
+func PrintTableOfJournalEntries(writer io.Writer, entries []journal.Entry) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Time", "Category", "User", "Message"})
+	table.SetAutoWrapText(false)
+
+	for _, e := range entries {
+		var details []string
+
+		for k, v := range e.Details {
+			details = append(details, fmt.Sprintf("%s: %s", k, v))
+		}
+
+		sort.Strings(details)
+
+		msg := e.Message
+
+		if len(details) > 0 {
+			msg += "\n" + strings.Join(details, "\n")
+		}
+
+		table.Append([]string{e.Time.Local().Format(time.RFC3339), string(e.Category), e.User, msg})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfRenumberChanges writes the given renumbering changes to the given
+// writer in a tabular format.
+func PrintTableOfRenumberChanges(writer io.Writer, changes []renumber.Change) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Config", "Path", "Old", "New"})
+	table.SetAutoWrapText(false)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+
+	for _, c := range changes {
+		table.Append([]string{c.Config, c.Path, c.Old, c.New})
+	}
+
+	table.Render()
+}
@@ -97,19 +97,65 @@ func (shell) ExecCommand(ctx context.Context, opts ...Option) ([]byte, []byte, e
 	// canceled below in order to gracefully terminate the child process. Using
 	// `exec.CommandContext` forcefully kills the child process when the context
 	// is canceled.
-	cmd := exec.Command(o.cmd, o.args...)
+	var (
+		cmd *exec.Cmd
+		sc  *SandboxedCmd
+	)
+
+	if o.sandbox != nil {
+		var err error
+
+		if sc, err = o.sandbox.Command(o.cmd, o.args...); err != nil {
+			return nil, nil, fmt.Errorf("sandboxing command: %w", err)
+		}
+
+		defer sc.Close()
+
+		cmd = sc.Cmd
+	} else {
+		cmd = exec.Command(o.cmd, o.args...)
+	}
 
 	cmd.Stdin = stdIn
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
+
+	var stdout, stderr io.Reader
+
+	stdout, _ = cmd.StdoutPipe()
+	stderr, _ = cmd.StderrPipe()
+
+	if o.stdoutTee != nil {
+		stdout = io.TeeReader(stdout, o.stdoutTee)
+	}
+
+	if o.stderrTee != nil {
+		stderr = io.TeeReader(stderr, o.stderrTee)
+	}
 
 	cmd.Env = os.Environ()
 	cmd.Env = append(cmd.Env, o.env...)
 
+	parent := ctx
+
+	if o.sandbox != nil && o.sandbox.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, o.sandbox.Timeout)
+		defer cancel()
+	}
+
 	if err := cmd.Start(); err != nil {
 		return nil, nil, fmt.Errorf("starting command: %w", err)
 	}
 
+	// The whole process group of sandboxed commands is signaled.
+	signal := func(sig syscall.Signal) {
+		if sc != nil {
+			sc.Signal(sig)
+		} else {
+			cmd.Process.Signal(sig)
+		}
+	}
+
 	var (
 		done = make(chan struct{})
 		errs error
@@ -121,13 +167,13 @@ func (shell) ExecCommand(ctx context.Context, opts ...Option) ([]byte, []byte, e
 		case <-done:
 			return
 		case <-ctx.Done():
-			cmd.Process.Signal(syscall.SIGTERM)
+			signal(syscall.SIGTERM)
 
 			select {
 			case <-done:
 				return
 			case <-time.After(10 * time.Second):
-				cmd.Process.Kill()
+				signal(syscall.SIGKILL)
 			}
 		}
 	}()
@@ -189,6 +235,13 @@ func (shell) ExecCommand(ctx context.Context, opts ...Option) ([]byte, []byte, e
 	wg.Wait()
 
 	if err := cmd.Wait(); err != nil {
+		switch {
+		case o.sandbox != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil:
+			err = fmt.Errorf("%w after %v: %w", ErrSandboxTimeout, o.sandbox.Timeout, err)
+		case sc != nil && sc.OOMKilled():
+			err = fmt.Errorf("%w: %w", ErrSandboxMemory, err)
+		}
+
 		errs = multierror.Append(errs, fmt.Errorf("waiting for command to complete: %w", err))
 	}
 
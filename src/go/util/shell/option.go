@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bufio"
+	"io"
 	"os"
 )
 
@@ -16,7 +17,12 @@ type options struct {
 	stdout chan []byte
 	stderr chan []byte
 
+	stdoutTee io.Writer
+	stderrTee io.Writer
+
 	splitter bufio.SplitFunc
+
+	sandbox *Sandbox
 }
 
 func newOptions(opts ...Option) options {
@@ -79,3 +85,27 @@ func SplitBytes() Option {
 		o.splitter = bufio.ScanBytes
 	}
 }
+
+// TeeStdout writes everything the command writes to STDOUT to the given writer
+// as it's written, in addition to it being returned.
+func TeeStdout(w io.Writer) Option {
+	return func(o *options) {
+		o.stdoutTee = w
+	}
+}
+
+// TeeStderr writes everything the command writes to STDERR to the given writer
+// as it's written, in addition to it being returned.
+func TeeStderr(w io.Writer) Option {
+	return func(o *options) {
+		o.stderrTee = w
+	}
+}
+
+// WithSandbox runs the command in the given sandbox, limiting how long it can
+// run for and the resources it can use.
+func WithSandbox(s Sandbox) Option {
+	return func(o *options) {
+		o.sandbox = &s
+	}
+}
@@ -0,0 +1,286 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"phenix/util/plog"
+)
+
+var (
+	ErrSandboxTimeout = errors.New("sandbox timeout exceeded")
+	ErrSandboxMemory  = errors.New("sandbox memory limit exceeded")
+)
+
+// CgroupRoot is the cgroup (v2) each sandboxed command gets its own child
+// cgroup under. It's created if it doesn't exist.
+var CgroupRoot = "/sys/fs/cgroup/phenix"
+
+var sandboxID uint64
+
+// Sandbox limits what a command executed via ExecCommand can do so a runaway
+// command (ie. a custom user app) can't take down the host. Zero values mean no
+// limit.
+type Sandbox struct {
+	// Timeout is how long the command is allowed to run for (wall-clock) before
+	// it's terminated, regardless of the context it's executed with.
+	Timeout time.Duration
+
+	// MemoryMax is the maximum memory (in bytes) the command and its children
+	// can use before they're OOM-killed.
+	MemoryMax int64
+
+	// CPUMax is the maximum number of CPUs (ie. 1.5) the command and its
+	// children can use at once before they're throttled.
+	CPUMax float64
+
+	// PidsMax is the maximum number of processes the command and its children
+	// can have at once.
+	PidsMax int64
+
+	// NoFile is the maximum number of files the command can have open at once.
+	NoFile uint64
+
+	// FileSize is the maximum size (in bytes) of files the command can write.
+	FileSize uint64
+
+	// WorkDir is the working directory the command is run in. It's created
+	// (only accessible by the phenix user) if it doesn't exist.
+	WorkDir string
+}
+
+func (this Sandbox) cgroupLimited() bool {
+	return this.MemoryMax > 0 || this.CPUMax > 0 || this.PidsMax > 0
+}
+
+// SandboxedCmd is a command prepared to run in a sandbox. It's used by
+// ExecCommand, and directly for commands phenix interacts with while they run
+// (ie. custom user apps using the gRPC protocol).
+type SandboxedCmd struct {
+	*exec.Cmd
+
+	cg  *cgroup
+	dir *os.File
+}
+
+// Command returns the given command prepared to run in the sandbox, with the
+// sandbox's resource limits, in its working directory, and in its own process
+// group so any children it starts are terminated along with it. Commands with
+// cgroup limits are started directly in their own cgroup. The sandbox timeout
+// isn't applied to the command; it's up to the caller to do so. Close must be
+// called once the command exits.
+func (this Sandbox) Command(name string, args ...string) (*SandboxedCmd, error) {
+	wrapped, args, err := this.wrap(name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &SandboxedCmd{Cmd: exec.Command(wrapped, args...)}
+
+	if this.WorkDir != "" {
+		if err := os.MkdirAll(this.WorkDir, 0700); err != nil {
+			return nil, fmt.Errorf("creating sandbox working directory: %w", err)
+		}
+
+		sc.Dir = this.WorkDir
+	}
+
+	sc.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if this.cgroupLimited() {
+		// Limits requested by the sandbox that can't be enforced (ie. phenix isn't
+		// running as root) are an error rather than the command being run without
+		// them.
+		if sc.cg, err = newCgroup(this, name); err != nil {
+			return nil, fmt.Errorf("creating sandbox cgroup: %w", err)
+		}
+
+		if sc.dir, err = sc.cg.open(); err != nil {
+			sc.Close()
+			return nil, err
+		}
+
+		// The command is started directly in the cgroup (requires Linux 5.7+) so
+		// its limits apply before it executes anything.
+		sc.SysProcAttr.UseCgroupFD = true
+		sc.SysProcAttr.CgroupFD = int(sc.dir.Fd())
+	}
+
+	return sc, nil
+}
+
+// Signal sends the given signal to the command's whole process group.
+func (this SandboxedCmd) Signal(sig syscall.Signal) error {
+	return syscall.Kill(-this.Process.Pid, sig)
+}
+
+// OOMKilled returns true if the command (or any of its children) was killed for
+// exceeding the sandbox's memory limit.
+func (this SandboxedCmd) OOMKilled() bool {
+	return this.cg != nil && this.cg.oomKilled()
+}
+
+// Close removes the command's cgroup, if any, killing any processes left in it.
+func (this SandboxedCmd) Close() {
+	if this.dir != nil {
+		this.dir.Close()
+	}
+
+	if this.cg != nil {
+		this.cg.remove()
+	}
+}
+
+// cgroup is the cgroup a sandboxed command is run in.
+type cgroup struct {
+	path string
+}
+
+// newCgroup creates a cgroup with the limits of the given sandbox for the given
+// command.
+func newCgroup(sb Sandbox, cmd string) (*cgroup, error) {
+	parent := filepath.Dir(CgroupRoot)
+
+	if _, err := os.Stat(filepath.Join(parent, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 hierarchy not mounted at %s: %w", parent, err)
+	}
+
+	// Processes can only be added to leaf cgroups, and controllers have to be
+	// enabled in the parent of a cgroup for its limits to be set.
+	if err := os.MkdirAll(CgroupRoot, 0755); err != nil {
+		return nil, fmt.Errorf("creating cgroup root %s: %w", CgroupRoot, err)
+	}
+
+	for _, dir := range []string{parent, CgroupRoot} {
+		control := filepath.Join(dir, "cgroup.subtree_control")
+
+		if err := os.WriteFile(control, []byte("+memory +cpu +pids"), 0644); err != nil {
+			return nil, fmt.Errorf("enabling cgroup controllers in %s: %w", dir, err)
+		}
+	}
+
+	name := fmt.Sprintf("%s-%d-%d", filepath.Base(cmd), os.Getpid(), atomic.AddUint64(&sandboxID, 1))
+	path := filepath.Join(CgroupRoot, name)
+
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, fmt.Errorf("creating cgroup %s: %w", path, err)
+	}
+
+	cg := &cgroup{path: path}
+
+	limits := make(map[string]string)
+
+	if sb.MemoryMax > 0 {
+		limits["memory.max"] = strconv.FormatInt(sb.MemoryMax, 10)
+		limits["memory.swap.max"] = "0"
+	}
+
+	if sb.CPUMax > 0 {
+		// Quota of CPU time (in microseconds) per 100ms period.
+		limits["cpu.max"] = fmt.Sprintf("%d 100000", int64(sb.CPUMax*100000))
+	}
+
+	if sb.PidsMax > 0 {
+		limits["pids.max"] = strconv.FormatInt(sb.PidsMax, 10)
+	}
+
+	for file, limit := range limits {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(limit), 0644); err != nil {
+			// Swap accounting isn't always enabled in the kernel.
+			if file == "memory.swap.max" && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			cg.remove()
+			return nil, fmt.Errorf("setting cgroup %s to %s: %w", file, limit, err)
+		}
+	}
+
+	return cg, nil
+}
+
+// open opens the cgroup's directory so commands can be started directly in it.
+func (this cgroup) open() (*os.File, error) {
+	dir, err := os.Open(this.path)
+	if err != nil {
+		return nil, fmt.Errorf("opening cgroup %s: %w", this.path, err)
+	}
+
+	return dir, nil
+}
+
+// oomKilled returns true if any processes in the cgroup were killed for
+// exceeding its memory limit.
+func (this cgroup) oomKilled() bool {
+	events, err := os.ReadFile(filepath.Join(this.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(events), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "oom_kill" {
+			return fields[1] != "0"
+		}
+	}
+
+	return false
+}
+
+// remove kills any processes left in the cgroup (ie. children the command
+// orphaned) and removes it.
+func (this cgroup) remove() {
+	// Not supported by older kernels, in which case removing the cgroup fails
+	// if processes are left in it.
+	os.WriteFile(filepath.Join(this.path, "cgroup.kill"), []byte("1"), 0644)
+
+	var err error
+
+	// Killed processes aren't removed from the cgroup immediately.
+	for i := 0; i < 10; i++ {
+		if err = os.Remove(this.path); err == nil {
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	plog.Warn("unable to remove sandbox cgroup", "cgroup", this.path, "err", err)
+}
+
+// wrap returns the command and arguments to execute for the given command so
+// it's run with the resource limits of the sandbox. The limits are set by
+// prlimit, which sets them on itself before executing the command, so they
+// apply before the command runs. An error is returned if the sandbox has
+// resource limits and prlimit isn't available.
+func (this Sandbox) wrap(cmd string, args []string) (string, []string, error) {
+	var limits []string
+
+	if this.NoFile > 0 {
+		limits = append(limits, fmt.Sprintf("--nofile=%d:%d", this.NoFile, this.NoFile))
+	}
+
+	if this.FileSize > 0 {
+		limits = append(limits, fmt.Sprintf("--fsize=%d:%d", this.FileSize, this.FileSize))
+	}
+
+	if len(limits) == 0 {
+		return cmd, args, nil
+	}
+
+	prlimit, err := exec.LookPath("prlimit")
+	if err != nil {
+		return "", nil, fmt.Errorf("resource limits require prlimit: %w", err)
+	}
+
+	limits = append(limits, "--", cmd)
+
+	return prlimit, append(limits, args...), nil
+}
@@ -0,0 +1,93 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSandboxTimeout(t *testing.T) {
+	start := time.Now()
+
+	_, _, err := ExecCommand(
+		context.Background(),
+		Command("sleep"), Args("30"),
+		WithSandbox(Sandbox{Timeout: 100 * time.Millisecond}),
+	)
+
+	// Errors are wrapped in a multierror, which doesn't support errors.Is.
+	if err == nil || !strings.Contains(err.Error(), ErrSandboxTimeout.Error()) {
+		t.Logf("expected sandbox timeout error, got %v", err)
+		t.FailNow()
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Logf("expected command to be terminated after timeout, ran for %v", elapsed)
+		t.FailNow()
+	}
+}
+
+func TestSandboxWorkDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app")
+
+	stdout, _, err := ExecCommand(
+		context.Background(),
+		Command("pwd"),
+		WithSandbox(Sandbox{WorkDir: dir}),
+	)
+
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if got := strings.TrimSpace(string(stdout)); got != dir {
+		t.Logf("expected command to run in %s, ran in %s", dir, got)
+		t.FailNow()
+	}
+}
+
+func TestSandboxRlimits(t *testing.T) {
+	if _, err := exec.LookPath("prlimit"); err != nil {
+		t.Skip("prlimit not available")
+	}
+
+	stdout, _, err := ExecCommand(
+		context.Background(),
+		Command("sh"), Args("-c", "ulimit -n"),
+		WithSandbox(Sandbox{NoFile: 64}),
+	)
+
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// The limit has to be in effect from the start of the command, not applied
+	// to it once it's already running.
+	if got := strings.TrimSpace(string(stdout)); got != "64" {
+		t.Logf("expected open files limit of 64, got %s", got)
+		t.FailNow()
+	}
+}
+
+func TestSandboxCgroupUnavailable(t *testing.T) {
+	defer func(root string) { CgroupRoot = root }(CgroupRoot)
+
+	// Not a cgroup v2 hierarchy, so cgroup limits can't be enforced.
+	CgroupRoot = filepath.Join(t.TempDir(), "phenix")
+
+	_, _, err := ExecCommand(
+		context.Background(),
+		Command("true"),
+		WithSandbox(Sandbox{MemoryMax: 1 << 30}),
+	)
+
+	if err == nil || !strings.Contains(err.Error(), "sandbox cgroup") {
+		t.Logf("expected sandbox cgroup error, got %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,139 @@
+// Package subsystem tracks which optional phenix subsystems were compiled into
+// the phenix binary and which of those have been disabled at runtime.
+//
+// Optional subsystems can be left out of the binary entirely using the
+// following build tags, which lets minimal (ie. embedded or air-gapped)
+// deployments avoid shipping code they don't use:
+//
+//	noui       web UI and API server (`phenix ui`)
+//	noscorch   scorch app, `phenix scorch`, and the scorch web API
+//	noimage    image builder (`phenix image`)
+//	nosoh      state of health app and the state of health web API
+//
+// For example, `go build -tags noui,noscorch`. Each subsystem registers itself
+// from the code left out by its build tag. Subsystems compiled into the binary
+// can still be disabled at runtime via the `subsystems.disabled` setting.
+package subsystem
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	UI     = "ui"
+	SCORCH = "scorch"
+	IMAGE  = "image"
+	SOH    = "soh"
+)
+
+// All is the list of optional subsystems.
+var All = []string{UI, SCORCH, IMAGE, SOH}
+
+var (
+	ErrNotCompiled = errors.New("subsystem not compiled into phenix")
+	ErrDisabled    = errors.New("subsystem disabled")
+	ErrUnknown     = errors.New("unknown subsystem")
+)
+
+var (
+	compiled = make(map[string]bool)
+	disabled = make(map[string]bool)
+
+	mu sync.RWMutex
+)
+
+// Register marks the given subsystem as compiled into the phenix binary. It's
+// meant to be called from the init function of code excluded by the
+// subsystem's build tag.
+func Register(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	compiled[name] = true
+}
+
+// Disable disables the given subsystems at runtime, replacing any subsystems
+// previously disabled. Subsystems that aren't compiled into the binary can be
+// disabled too, but unknown subsystems result in an error.
+func Disable(names ...string) error {
+	updated := make(map[string]bool)
+
+	for _, name := range names {
+		if !known(name) {
+			return fmt.Errorf("%w: %s (options: %v)", ErrUnknown, name, All)
+		}
+
+		updated[name] = true
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	disabled = updated
+
+	return nil
+}
+
+// Check returns nil if the given subsystem is compiled into the binary and
+// hasn't been disabled. Otherwise, the error returned wraps either
+// ErrNotCompiled or ErrDisabled. Names that aren't optional subsystems are
+// always available.
+func Check(name string) error {
+	if !known(name) {
+		return nil
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if !compiled[name] {
+		return fmt.Errorf("%w: %s", ErrNotCompiled, name)
+	}
+
+	if disabled[name] {
+		return fmt.Errorf("%w: %s", ErrDisabled, name)
+	}
+
+	return nil
+}
+
+// Enabled returns true if the given subsystem is available for use.
+func Enabled(name string) bool {
+	return Check(name) == nil
+}
+
+// Status describes whether an optional subsystem is available.
+type Status struct {
+	Name     string `json:"name"`
+	Compiled bool   `json:"compiled"`
+	Disabled bool   `json:"disabled"`
+}
+
+// List returns the status of each optional subsystem, sorted by name.
+func List() []Status {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	status := make([]Status, len(All))
+
+	for i, name := range All {
+		status[i] = Status{Name: name, Compiled: compiled[name], Disabled: disabled[name]}
+	}
+
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+
+	return status
+}
+
+func known(name string) bool {
+	for _, n := range All {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package subsystem
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	Register(UI)
+
+	if err := Check(UI); err != nil {
+		t.Logf("expected %s to be enabled, got %v", UI, err)
+		t.FailNow()
+	}
+
+	if err := Check(SCORCH); !errors.Is(err, ErrNotCompiled) {
+		t.Logf("expected ErrNotCompiled for %s, got %v", SCORCH, err)
+		t.FailNow()
+	}
+
+	if err := Check("ntp"); err != nil {
+		t.Logf("expected non-subsystem to be enabled, got %v", err)
+		t.FailNow()
+	}
+
+	if err := Disable(UI); err != nil {
+		t.Logf("unexpected error disabling %s: %v", UI, err)
+		t.FailNow()
+	}
+
+	if err := Check(UI); !errors.Is(err, ErrDisabled) {
+		t.Logf("expected ErrDisabled for %s, got %v", UI, err)
+		t.FailNow()
+	}
+
+	if err := Disable("bogus"); !errors.Is(err, ErrUnknown) {
+		t.Logf("expected ErrUnknown, got %v", err)
+		t.FailNow()
+	}
+
+	if Enabled(UI) {
+		t.Log("expected failed Disable to leave disabled subsystems unchanged")
+		t.FailNow()
+	}
+}
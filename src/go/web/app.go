@@ -0,0 +1,198 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"phenix/api/userapp"
+	"phenix/app"
+	"phenix/types"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+	"github.com/hpcloud/tail"
+	"golang.org/x/net/websocket"
+)
+
+// GET /applications/installed
+func GetInstalledApplications(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetInstalledApplications")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("applications", "list") {
+		err := weberror.NewWebError(nil, "listing applications not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	apps, err := userapp.List()
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get installed applications from store")
+	}
+
+	allowed := []types.App{}
+
+	for _, app := range apps {
+		if role.Allowed("applications", "list", app.Metadata.Name) {
+			allowed = append(allowed, app)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("applications", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process installed applications")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /experiments/{name}/apps/{app}/log
+func GetExperimentAppLog(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentAppLog")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+		a    = vars["app"]
+	)
+
+	if !role.Allowed("experiments/files", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment %s files not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if !validLogName(name) || !validLogName(a) {
+		return weberror.NewWebError(nil, "invalid experiment or app name").SetStatus(http.StatusBadRequest)
+	}
+
+	body, err := os.ReadFile(app.AppLogPath(name, a))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return weberror.NewWebError(err, "no log for app %s in experiment %s", a, name).SetStatus(http.StatusNotFound)
+		}
+
+		return weberror.NewWebError(err, "unable to read log for app %s in experiment %s", a, name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /experiments/{name}/apps/{app}/log/ws
+func StreamExperimentAppLog(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "StreamExperimentAppLog")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+		a    = vars["app"]
+	)
+
+	if !role.Allowed("experiments/files", "get", name) {
+		plog.Warn("streaming experiment app log not allowed", "user", ctx.Value("user").(string), "exp", name, "app", a)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if !validLogName(name) || !validLogName(a) {
+		http.Error(w, "invalid experiment or app name", http.StatusBadRequest)
+		return
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		// The log file isn't created until the app is first run, so keep trying to
+		// open it until the client goes away.
+		t, err := tail.TailFile(app.AppLogPath(name, a), tail.Config{Follow: true, ReOpen: true, MustExist: false, Poll: true, Logger: tail.DiscardingLogger})
+		if err != nil {
+			plog.Error("tailing experiment app log", "exp", name, "app", a, "err", err)
+			return
+		}
+
+		defer t.Cleanup()
+		defer t.Stop()
+
+		// Detect the client going away, since nothing is ever read from it.
+		closed := make(chan struct{})
+
+		go func() {
+			io.Copy(io.Discard, ws)
+			close(closed)
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case line, ok := <-t.Lines:
+				if !ok {
+					return
+				}
+
+				if _, err := ws.Write([]byte(line.Text + "\n")); err != nil {
+					return
+				}
+			}
+		}
+	}).ServeHTTP(w, r)
+}
+
+// validLogName returns true if the given experiment or app name can't be used to
+// read log files outside the experiment's files directory.
+func validLogName(name string) bool {
+	return name != "" && name == filepath.Base(name) && !strings.HasPrefix(name, ".")
+}
+
+// GET /applications/details
+func GetApplicationDetails(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetApplicationDetails")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("applications", "list") {
+		err := weberror.NewWebError(nil, "listing applications not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	allowed := []app.Info{}
+
+	for _, info := range app.Describe() {
+		if role.Allowed("applications", "list", info.Name) {
+			allowed = append(allowed, info)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("applications", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process application details")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
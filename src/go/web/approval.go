@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/util/plog"
+	"phenix/web/approval"
+	"phenix/web/broker"
+	bt "phenix/web/broker/brokertypes"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /approvals
+func GetApprovals(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetApprovals")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	// Users only see approvals for operations they're allowed to perform, since
+	// those are the only ones they can approve.
+	allowed := []approval.Approval{}
+
+	for _, a := range approval.List() {
+		if role.Allowed(a.Resource, a.Verb, a.Name) {
+			allowed = append(allowed, a)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("approvals", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process approvals")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /approvals/{id}/approve
+func ApproveApproval(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ApproveApproval")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		id   = mux.Vars(r)["id"]
+	)
+
+	a, err := approval.Get(id)
+	if err != nil {
+		return approvalError(err, id)
+	}
+
+	// Approvers must be authorized to perform the operation themselves.
+	if !role.Allowed(a.Resource, a.Verb, a.Name) {
+		err := weberror.NewWebError(nil, "approving %s of %s not allowed for %s", a.Operation, a.Name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	a, err = approval.Approve(id, user)
+	if err != nil {
+		return approvalError(err, id)
+	}
+
+	body, _ := json.Marshal(a)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy(a.Resource, a.Verb, a.Name),
+		bt.NewResource("approval", a.ID, "approve"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /approvals/{id}
+func RejectApproval(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RejectApproval")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		id   = mux.Vars(r)["id"]
+	)
+
+	a, err := approval.Get(id)
+	if err != nil {
+		return approvalError(err, id)
+	}
+
+	// Requesters can cancel their own approvals.
+	if a.RequestedBy != user && !role.Allowed(a.Resource, a.Verb, a.Name) {
+		err := weberror.NewWebError(nil, "rejecting %s of %s not allowed for %s", a.Operation, a.Name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := approval.Reject(id, user); err != nil {
+		return approvalError(err, id)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy(a.Resource, a.Verb, a.Name),
+		bt.NewResource("approval", a.ID, "delete"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func approvalError(err error, id string) error {
+	switch {
+	case errors.Is(err, approval.ErrNotFound), errors.Is(err, approval.ErrExpired):
+		err := weberror.NewWebError(err, "approval %s does not exist or has expired", id)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, approval.ErrSelfApprove):
+		err := weberror.NewWebError(err, "approval %s must be approved by a different user", id)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	return weberror.NewWebError(err, "unable to process approval %s", id).SetStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,359 @@
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/util/audit"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	bt "phenix/web/broker/brokertypes"
+	"phenix/web/rbac"
+
+	"github.com/gorilla/mux"
+)
+
+// Header is the HTTP header a requester provides the ID of an approved
+// approval in when repeating a request that required approval.
+const Header = "X-Phenix-Approval"
+
+// Operations are the destructive operations that can require approval.
+var Operations = []string{"experiment-delete", "experiment-stop", "vm-kill", "config-delete"}
+
+var (
+	ErrNotFound    = errors.New("approval not found")
+	ErrExpired     = errors.New("approval expired")
+	ErrNotApproved = errors.New("approval not approved yet")
+	ErrSelfApprove = errors.New("approval must be approved by a different user")
+	ErrMismatch    = errors.New("approval does not match request")
+)
+
+// Approval is a request by one user to perform a destructive operation that
+// must be approved by a second user authorized to perform the same operation
+// before it expires. Once approved, the requester repeats the original request
+// with the approval ID in the `X-Phenix-Approval` header to perform the
+// operation.
+type Approval struct {
+	ID          string     `json:"id"`
+	Operation   string     `json:"operation"`
+	Method      string     `json:"method"`
+	Path        string     `json:"path"`
+	Resource    string     `json:"resource"`
+	Verb        string     `json:"verb"`
+	Name        string     `json:"name"`
+	RequestedBy string     `json:"requestedBy"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	Expires     time.Time  `json:"expires"`
+	ApprovedBy  string     `json:"approvedBy,omitempty"`
+	ApprovedAt  *time.Time `json:"approvedAt,omitempty"`
+}
+
+func (this Approval) expired() bool {
+	return time.Now().After(this.Expires)
+}
+
+var approvals = struct {
+	sync.Mutex
+
+	window     time.Duration
+	operations map[string]bool
+	pending    map[string]*Approval
+}{
+	operations: make(map[string]bool),
+	pending:    make(map[string]*Approval),
+}
+
+// Enable requires approval for the given operations (all operations if none
+// are given). Approvals must be approved and used within the given window.
+func Enable(window time.Duration, ops ...string) error {
+	if len(ops) == 0 {
+		ops = Operations
+	}
+
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	for _, op := range ops {
+		if !known(op) {
+			return fmt.Errorf("unknown operation %s (expected one of %s)", op, strings.Join(Operations, ", "))
+		}
+
+		approvals.operations[op] = true
+	}
+
+	approvals.window = window
+
+	return nil
+}
+
+// Required returns true if the given operation requires approval.
+func Required(op string) bool {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	return approvals.operations[op]
+}
+
+// Require wraps the given handler so the given operation requires approval.
+// The RBAC resource, verb, and resource name (as returned by the given name
+// function for each request) the operation requires are used to determine who
+// can approve it. The name must match the name the handler checks RBAC with,
+// otherwise requests could bypass approval.
+func Require(op, resource, verb string, name func(*http.Request) string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || !Required(op) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			ctx  = r.Context()
+			role = ctx.Value("role").(rbac.Role)
+			user = ctx.Value("user").(string)
+			rn   = name(r)
+		)
+
+		// Let the handler deny requests not allowed for the requester.
+		if !role.Allowed(resource, verb, rn) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if id := r.Header.Get(Header); id != "" {
+			a, err := use(id, op, rn, user)
+			if err != nil {
+				plog.Warn("using approval", "id", id, "user", user, "err", err)
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+
+			record(user, "approval/use", a)
+
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		a := request(op, resource, verb, rn, user, r)
+
+		record(user, "approval/request", a)
+
+		body, _ := json.Marshal(a)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy(resource, verb, rn),
+			bt.NewResource("approval", a.ID, "create"),
+			body,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(body)
+	})
+}
+
+// PathVars returns a resource name function for `Require` that joins the
+// given request path variables with slashes (ie. `PathVars("exp", "name")`
+// returns `<exp>/<name>`).
+func PathVars(keys ...string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		var (
+			vars   = mux.Vars(r)
+			values = make([]string, len(keys))
+		)
+
+		for i, k := range keys {
+			values[i] = vars[k]
+		}
+
+		return strings.Join(values, "/")
+	}
+}
+
+// List returns the pending approvals that haven't expired yet.
+func List() []Approval {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	prune()
+
+	var list []Approval
+
+	for _, a := range approvals.pending {
+		list = append(list, *a)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].RequestedAt.Before(list[j].RequestedAt)
+	})
+
+	return list
+}
+
+// Get returns the pending approval with the given ID.
+func Get(id string) (Approval, error) {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	prune()
+
+	a, ok := approvals.pending[id]
+	if !ok {
+		return Approval{}, ErrNotFound
+	}
+
+	return *a, nil
+}
+
+// Approve approves the pending approval with the given ID on behalf of the
+// given user, who must not be the user that requested it. The caller is
+// responsible for checking the user is authorized to perform the operation.
+func Approve(id, user string) (Approval, error) {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	a, ok := approvals.pending[id]
+	if !ok {
+		return Approval{}, ErrNotFound
+	}
+
+	if a.expired() {
+		delete(approvals.pending, id)
+		return Approval{}, ErrExpired
+	}
+
+	if a.RequestedBy == user {
+		return Approval{}, ErrSelfApprove
+	}
+
+	now := time.Now().UTC()
+
+	a.ApprovedBy = user
+	a.ApprovedAt = &now
+
+	record(user, "approval/approve", *a)
+
+	return *a, nil
+}
+
+// Reject removes the pending approval with the given ID on behalf of the given
+// user. The caller is responsible for checking the user is authorized to
+// reject it.
+func Reject(id, user string) (Approval, error) {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	a, ok := approvals.pending[id]
+	if !ok {
+		return Approval{}, ErrNotFound
+	}
+
+	delete(approvals.pending, id)
+
+	record(user, "approval/reject", *a)
+
+	return *a, nil
+}
+
+func request(op, resource, verb, name, user string, r *http.Request) Approval {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	prune()
+
+	now := time.Now().UTC()
+
+	a := &Approval{
+		ID:          newID(),
+		Operation:   op,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Resource:    resource,
+		Verb:        verb,
+		Name:        name,
+		RequestedBy: user,
+		RequestedAt: now,
+		Expires:     now.Add(approvals.window),
+	}
+
+	approvals.pending[a.ID] = a
+
+	return *a
+}
+
+// use removes the approval with the given ID if it has been approved for the
+// given operation requested by the given user.
+func use(id, op, name, user string) (Approval, error) {
+	approvals.Lock()
+	defer approvals.Unlock()
+
+	a, ok := approvals.pending[id]
+	if !ok {
+		return Approval{}, ErrNotFound
+	}
+
+	if a.expired() {
+		delete(approvals.pending, id)
+		return Approval{}, ErrExpired
+	}
+
+	if a.Operation != op || a.Name != name || a.RequestedBy != user {
+		return Approval{}, ErrMismatch
+	}
+
+	if a.ApprovedBy == "" {
+		return Approval{}, ErrNotApproved
+	}
+
+	delete(approvals.pending, id)
+
+	return *a, nil
+}
+
+// prune removes expired approvals. The caller must hold the approvals lock.
+func prune() {
+	for id, a := range approvals.pending {
+		if a.expired() {
+			delete(approvals.pending, id)
+			record("", "approval/expire", *a)
+		}
+	}
+}
+
+func record(user, action string, a Approval) {
+	audit.Record(audit.Event{
+		User:     user,
+		Action:   action,
+		Resource: a.Resource + "/" + a.Name,
+		Details: map[string]any{
+			"approval":    a.ID,
+			"operation":   a.Operation,
+			"requestedBy": a.RequestedBy,
+			"approvedBy":  a.ApprovedBy,
+		},
+	})
+}
+
+func known(op string) bool {
+	for _, o := range Operations {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
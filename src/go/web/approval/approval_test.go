@@ -0,0 +1,129 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "phenix/types/version/v1"
+	"phenix/util/common"
+	"phenix/web/rbac"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRequire(t *testing.T) {
+	common.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+
+	if err := Enable(time.Minute, "experiment-delete"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	role := rbac.Role{
+		Spec: &v1.RoleSpec{
+			Policies: []*v1.PolicySpec{
+				{Resources: []string{"experiments"}, ResourceNames: []string{"*"}, Verbs: []string{"delete"}},
+			},
+		},
+	}
+
+	var deleted int
+
+	router := mux.NewRouter()
+	router.Handle("/experiments/{name}", Require("experiment-delete", "experiments", "delete", PathVars("name"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deleted++
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	do := func(user, id string) *httptest.ResponseRecorder {
+		ctx := context.WithValue(context.Background(), "role", role)
+		ctx = context.WithValue(ctx, "user", user)
+
+		req := httptest.NewRequest(http.MethodDelete, "/experiments/foo", nil).WithContext(ctx)
+
+		if id != "" {
+			req.Header.Set(Header, id)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	rec := do("alice", "")
+	if rec.Code != http.StatusAccepted || deleted != 0 {
+		t.Logf("expected approval to be required, got status %d", rec.Code)
+		t.FailNow()
+	}
+
+	var a Approval
+
+	if err := json.Unmarshal(rec.Body.Bytes(), &a); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if a.Name != "foo" || a.RequestedBy != "alice" {
+		t.Logf("unexpected approval %+v", a)
+		t.FailNow()
+	}
+
+	if rec := do("alice", a.ID); rec.Code != http.StatusForbidden || deleted != 0 {
+		t.Logf("expected unapproved approval to be rejected, got status %d", rec.Code)
+		t.FailNow()
+	}
+
+	if _, err := Approve(a.ID, "alice"); !errors.Is(err, ErrSelfApprove) {
+		t.Logf("expected ErrSelfApprove, got %v", err)
+		t.FailNow()
+	}
+
+	if _, err := Approve(a.ID, "bob"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if rec := do("bob", a.ID); rec.Code != http.StatusForbidden || deleted != 0 {
+		t.Logf("expected approval used by another user to be rejected, got status %d", rec.Code)
+		t.FailNow()
+	}
+
+	if rec := do("alice", a.ID); rec.Code != http.StatusNoContent || deleted != 1 {
+		t.Logf("expected approved request to succeed, got status %d", rec.Code)
+		t.FailNow()
+	}
+
+	// Approvals can only be used once.
+	if rec := do("alice", a.ID); rec.Code != http.StatusForbidden || deleted != 1 {
+		t.Logf("expected used approval to be rejected, got status %d", rec.Code)
+		t.FailNow()
+	}
+}
+
+func TestExpired(t *testing.T) {
+	common.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+
+	if err := Enable(-time.Second, "vm-kill"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	a := request("vm-kill", "vms", "delete", "foo/bar", "alice", httptest.NewRequest(http.MethodDelete, "/experiments/foo/vms/bar", nil))
+
+	if _, err := Approve(a.ID, "bob"); !errors.Is(err, ErrExpired) {
+		t.Logf("expected ErrExpired, got %v", err)
+		t.FailNow()
+	}
+
+	if err := Enable(time.Minute, "foo"); err == nil {
+		t.Log("expected error for unknown operation")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"phenix/util/audit"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+)
+
+// GET /audit?user=<user>&action=<prefix>&resource=<prefix>&experiment=<name>&since=<RFC3339>&until=<RFC3339>&limit=<count>
+func GetAuditEvents(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetAuditEvents")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("audit", "list") {
+		err := weberror.NewWebError(nil, "listing audit events not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	filter := audit.Filter{
+		User:       query.Get("user"),
+		Action:     query.Get("action"),
+		Resource:   query.Get("resource"),
+		Experiment: query.Get("experiment"),
+	}
+
+	var err error
+
+	for key, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(key); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return weberror.NewWebError(err, "invalid %s time provided (expected RFC3339)", key).SetStatus(http.StatusBadRequest)
+			}
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return weberror.NewWebError(err, "invalid limit provided").SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	events, err := audit.Query(filter)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get audit events")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if events == nil {
+		events = []audit.Event{}
+	}
+
+	body, err := json.Marshal(util.WithRoot("events", events))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process audit events")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
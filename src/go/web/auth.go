@@ -55,8 +55,8 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "proxy user mismatch", http.StatusUnauthorized)
 			return
 		}
-	} else if o.proxyAuthHeader != "" {
-		if user := r.Header.Get(o.proxyAuthHeader); user != req.Username {
+	} else if options().proxyAuthHeader != "" {
+		if user := r.Header.Get(options().proxyAuthHeader); user != req.Username {
 			http.Error(w, "proxy user mismatch", http.StatusUnauthorized)
 			return
 		}
@@ -72,11 +72,11 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	if token == nil { // not using proxy JWT
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 			"sub": u.Username(),
-			"exp": time.Now().Add(o.jwtLifetime).Unix(),
+			"exp": time.Now().Add(options().jwtLifetime).Unix(),
 		})
 
 		// Sign and get the complete encoded token as a string using the secret
-		raw, err = token.SignedString([]byte(o.jwtKey))
+		raw, err = token.SignedString([]byte(options().jwtKey))
 		if err != nil {
 			http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 			return
@@ -142,7 +142,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	} else {
 		switch r.Method {
 		case "GET":
-			if o.proxyAuthHeader == "" {
+			if options().proxyAuthHeader == "" {
 				var ok bool
 
 				user, pass, ok = r.BasicAuth()
@@ -163,7 +163,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 					}
 				}
 			} else {
-				user = r.Header.Get(o.proxyAuthHeader)
+				user = r.Header.Get(options().proxyAuthHeader)
 
 				if user == "" {
 					http.Error(w, "proxy authentication failed", http.StatusUnauthorized)
@@ -173,7 +173,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 				proxied = true
 			}
 		case "POST":
-			if o.proxyAuthHeader != "" {
+			if options().proxyAuthHeader != "" {
 				http.Error(w, "proxy auth enabled -- must login via GET request", http.StatusBadRequest)
 				return
 			}
@@ -223,11 +223,11 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	if token == nil {
 		token = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 			"sub": u.Username(),
-			"exp": time.Now().Add(o.jwtLifetime).Unix(),
+			"exp": time.Now().Add(options().jwtLifetime).Unix(),
 		})
 
 		// Sign and get the complete encoded token as a string using the secret
-		signed, err = token.SignedString([]byte(o.jwtKey))
+		signed, err = token.SignedString([]byte(options().jwtKey))
 		if err != nil {
 			http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 			return
@@ -337,7 +337,7 @@ func CreateUserToken(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Sign and get the complete encoded token as a string using the secret
-	signed, err := token.SignedString([]byte(o.jwtKey))
+	signed, err := token.SignedString([]byte(options().jwtKey))
 	if err != nil {
 		http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 		return
@@ -0,0 +1,188 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	v1 "phenix/types/version/v1"
+)
+
+type canIRequest struct {
+	rbac.Check
+
+	// User or token to check for. The requesting user is checked if neither is
+	// provided.
+	User  string `json:"user,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+type canIResponse struct {
+	User    string `json:"user"`
+	Role    string `json:"role"`
+	Allowed bool   `json:"allowed"`
+}
+
+type policyTestRequest struct {
+	// Role is the proposed role to test. If not provided, the existing role named
+	// by RoleName is tested.
+	Role     *v1.RoleSpec `json:"role,omitempty"`
+	RoleName string       `json:"roleName,omitempty"`
+
+	Expectations []rbac.Expectation `json:"expectations"`
+}
+
+type policyTestResponse struct {
+	Passed  bool                     `json:"passed"`
+	Results []rbac.ExpectationResult `json:"results"`
+}
+
+// GET /authz/can-i
+// POST /authz/can-i
+func CanI(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CanI")
+
+	var (
+		ctx  = r.Context()
+		user = ctx.Value("user").(string)
+		role = ctx.Value("role").(rbac.Role)
+		req  canIRequest
+	)
+
+	if r.Method == "POST" {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return weberror.NewWebError(err, "unable to parse request").SetStatus(http.StatusInternalServerError)
+		}
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			return weberror.NewWebError(err, "invalid can-i request provided").SetStatus(http.StatusBadRequest)
+		}
+	} else {
+		// Tokens aren't accepted as query parameters so they don't end up in logs.
+		query := r.URL.Query()
+
+		req.Resource = query.Get("resource")
+		req.Verb = query.Get("verb")
+		req.Name = query.Get("name")
+		req.User = query.Get("user")
+	}
+
+	if req.Resource == "" || req.Verb == "" {
+		return weberror.NewWebError(nil, "resource and verb must be provided").SetStatus(http.StatusBadRequest)
+	}
+
+	resp := canIResponse{User: user}
+
+	// Checked before getting the user so users that can't get other users can't
+	// use this to find out which users exist.
+	if req.User != "" && req.User != user && !role.Allowed("users", "get", req.User) {
+		err := weberror.NewWebError(nil, "checking permissions of user %s not allowed for %s", req.User, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if req.User != "" || req.Token != "" {
+		var (
+			target *rbac.User
+			err    error
+		)
+
+		if req.Token != "" {
+			target, err = rbac.UserFromToken(req.Token)
+		} else {
+			target, err = rbac.GetUser(req.User)
+		}
+
+		if err != nil {
+			return weberror.NewWebError(err, "unable to get user to check").SetStatus(http.StatusNotFound)
+		}
+
+		if req.User != "" && target.Username() != req.User {
+			return weberror.NewWebError(nil, "token does not belong to user %s", req.User).SetStatus(http.StatusBadRequest)
+		}
+
+		if target.Username() != user && !role.Allowed("users", "get", target.Username()) {
+			err := weberror.NewWebError(nil, "checking permissions of user %s not allowed for %s", target.Username(), user)
+			return err.SetStatus(http.StatusForbidden)
+		}
+
+		if role, err = target.Role(); err != nil {
+			return weberror.NewWebError(err, "unable to get role for user %s", target.Username()).SetStatus(http.StatusInternalServerError)
+		}
+
+		// Views only scope VM access, so a failure to load them is treated the
+		// same as the auth middleware does (VM access is denied).
+		role, _ = role.WithViews()
+
+		resp.User = target.Username()
+	}
+
+	if role.Spec != nil {
+		resp.Role = role.Spec.Name
+	}
+
+	resp.Allowed = role.CanI(req.Check)
+
+	body, _ := json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /authz/policy-test
+func TestPolicy(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "TestPolicy")
+
+	var (
+		ctx  = r.Context()
+		user = ctx.Value("user").(string)
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("roles", "list") {
+		err := weberror.NewWebError(nil, "testing role policies not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to parse request").SetStatus(http.StatusInternalServerError)
+	}
+
+	var req policyTestRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid policy test request provided").SetStatus(http.StatusBadRequest)
+	}
+
+	var proposed *rbac.Role
+
+	switch {
+	case req.Role != nil:
+		proposed = rbac.NewRole(req.Role)
+	case req.RoleName != "":
+		proposed, err = rbac.RoleFromConfig(req.RoleName)
+		if err != nil {
+			return weberror.NewWebError(err, "unable to get role %s", req.RoleName).SetStatus(http.StatusNotFound)
+		}
+	default:
+		return weberror.NewWebError(nil, "role or role name must be provided").SetStatus(http.StatusBadRequest)
+	}
+
+	var resp policyTestResponse
+
+	resp.Results, resp.Passed = proposed.Test(req.Expectations...)
+
+	body, _ = json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"phenix/api/vm"
+	"phenix/util/audit"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	bt "phenix/web/broker/brokertypes"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+type broadcastRequest struct {
+	VMs     []string          `json:"vms"`
+	Labels  map[string]string `json:"labels"`
+	Keys    string            `json:"keys"`
+	Command string            `json:"command"`
+	File    string            `json:"file"`
+}
+
+// POST /experiments/{exp}/vms/broadcast
+func BroadcastVMs(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "BroadcastVMs")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		exp  = mux.Vars(r)["exp"]
+	)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request").SetStatus(http.StatusBadRequest)
+	}
+
+	var req broadcastRequest
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "unable to parse request").SetStatus(http.StatusBadRequest)
+	}
+
+	opts := []vm.BroadcastOption{
+		vm.BroadcastToVMs(req.VMs...),
+		vm.BroadcastKeys(req.Keys),
+		vm.BroadcastCommand(req.Command),
+		vm.BroadcastFile(req.File),
+	}
+
+	for k, v := range req.Labels {
+		opts = append(opts, vm.BroadcastToLabel(k, v))
+	}
+
+	group, err := vm.BroadcastGroup(exp, opts...)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to select VM group").SetStatus(http.StatusBadRequest)
+	}
+
+	// The whole group is rejected if any VM in it is off limits, rather than
+	// silently broadcasting to a subset of it.
+	var denied []string
+
+	for _, name := range group {
+		if !role.Allowed("vms/broadcast", "create", fmt.Sprintf("%s/%s", exp, name)) {
+			denied = append(denied, name)
+		}
+	}
+
+	if len(denied) > 0 {
+		err := weberror.NewWebError(nil, "broadcasting to VMs %s not allowed for %s", strings.Join(denied, ", "), user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	details := map[string]any{"vms": group}
+
+	if req.Command != "" || req.File != "" {
+		details["command"] = req.Command
+		details["file"] = req.File
+	} else {
+		details["keys"] = req.Keys
+	}
+
+	audit.Record(audit.Event{
+		User:     user,
+		Action:   "vms/broadcast",
+		Resource: "experiments/" + exp,
+		Details:  details,
+	})
+
+	// Keys are typed before the response is sent, so typing stops if the client
+	// goes away. Commands are only queued before the response is sent, so
+	// they're dispatched with a context that outlives the request.
+	bctx := ctx
+
+	if req.Command != "" || req.File != "" {
+		bctx = context.Background()
+	}
+
+	// Restrict the broadcast to the group RBAC was checked against.
+	results, batch, err := vm.Broadcast(bctx, exp, vm.BroadcastToVMs(group...), vm.BroadcastKeys(req.Keys), vm.BroadcastCommand(req.Command), vm.BroadcastFile(req.File))
+	if err != nil {
+		return weberror.NewWebError(err, "unable to broadcast to VM group").SetStatus(http.StatusBadRequest)
+	}
+
+	if batch == nil {
+		body, err = json.Marshal(util.WithRoot("results", results))
+	} else {
+		body, err = json.Marshal(util.WithRoot("results", batch.Results()))
+	}
+
+	if err != nil {
+		return weberror.NewWebError(err, "unable to process broadcast results").SetStatus(http.StatusInternalServerError)
+	}
+
+	if batch == nil {
+		publishBroadcastResults(exp, group, nil)
+	} else {
+		// Clients are notified of each VM's command result (including its cc
+		// command ID) once every command in the batch completes.
+		go func() {
+			<-batch.Done()
+			publishBroadcastResults(exp, group, batch.Results())
+		}()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// publishBroadcastResults notifies clients of a broadcast to each VM in the
+// given group, including the VM's command result if commands were broadcast.
+func publishBroadcastResults(exp string, group []string, results []mm.C2TaskResult) {
+	for i, name := range group {
+		var (
+			fullName = exp + "/" + name
+			body     json.RawMessage
+		)
+
+		if i < len(results) {
+			body, _ = json.Marshal(results[i])
+		}
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/broadcast", "create", fullName),
+			bt.NewResource("experiment/vm", fullName, "broadcast"),
+			body,
+		)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"phenix/api/vm"
 	"phenix/app"
+	"phenix/util/journal"
 	"phenix/util/pubsub"
 	"phenix/web/util"
 
@@ -21,11 +22,15 @@ var (
 	broadcast  = make(chan bt.Publish, 1024)
 	register   = make(chan *Client, 1024)
 	unregister = make(chan *Client, 1024)
+
+	// per-topic sequence numbers for broadcast publications
+	sequences = make(map[string]uint64)
 )
 
 func Start() {
 	triggerSub := pubsub.Subscribe("trigger-app")
 	delayedSub := pubsub.Subscribe("delayed-start")
+	journalSub := pubsub.Subscribe("journal")
 
 	for {
 		select {
@@ -46,18 +51,32 @@ func Start() {
 				resource.Name = trigger.Resource
 			}
 
-			if trigger.State == "error" {
+			if (trigger.State == "error" || trigger.State == "retrying") && trigger.Error != nil {
 				var (
 					humanized *putil.HumanizedError
-					result    []byte
+					result    = map[string]any{"error": trigger.Error.Error()}
 				)
 
 				if errors.As(trigger.Error, &humanized) {
-					result, _ = json.Marshal(map[string]any{"error": humanized.Humanized()})
-				} else {
-					result, _ = json.Marshal(map[string]any{"error": trigger.Error.Error()})
+					result["error"] = humanized.Humanized()
 				}
 
+				if trigger.Stage != "" {
+					result["stage"] = trigger.Stage
+				}
+
+				body, _ := json.Marshal(result)
+
+				broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: body}
+			} else if trigger.State == "progress" && trigger.Progress != nil {
+				result, _ := json.Marshal(trigger.Progress)
+
+				broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: result}
+			} else if trigger.Stage != "" {
+				// Events emitted while applying apps for an experiment stage include
+				// the stage so clients can tell which stage each event is for.
+				result, _ := json.Marshal(map[string]any{"stage": trigger.Stage})
+
 				broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: result}
 			} else {
 				broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: nil}
@@ -84,6 +103,18 @@ func Start() {
 			policy := bt.NewRequestPolicy("vms/start", "update", strings.Join(names, "_"))
 			resource := bt.NewResource("experiment/vm", delayed, "start")
 
+			broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: body}
+		case pub := <-journalSub:
+			entry := pub.(journal.Entry)
+
+			body, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+
+			policy := bt.NewRequestPolicy("experiments/journal", "get", entry.Experiment)
+			resource := bt.NewResource("experiment/journal", entry.Experiment, string(entry.Category))
+
 			broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: body}
 		case cli := <-register:
 			clients[cli] = true
@@ -93,6 +124,13 @@ func Start() {
 				delete(clients, cli)
 			}
 		case pub := <-broadcast:
+			if pub.Resource != nil {
+				key := topicKey(pub.Resource)
+
+				sequences[key]++
+				pub.Seq = sequences[key]
+			}
+
 			for cli := range clients {
 				var (
 					policy = pub.RequestPolicy
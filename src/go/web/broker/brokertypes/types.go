@@ -28,6 +28,37 @@ type Publish struct {
 	RequestPolicy *RequestPolicy  `json:"-"`
 	Resource      *Resource       `json:"resource"`
 	Result        json.RawMessage `json:"result"`
+
+	// Seq is the sequence number of the publication for its topic (resource
+	// type and name). It's only set for broadcast publications.
+	Seq uint64 `json:"seq,omitempty"`
+
+	// Delta is true when Result is a JSON merge patch (RFC 7386) to be applied
+	// to the client's copy of the publication with sequence number Base for the
+	// same topic.
+	Delta bool   `json:"delta,omitempty"`
+	Base  uint64 `json:"base,omitempty"`
+
+	// Snapshot forces the publication to be sent with its full result, even to
+	// clients that have enabled delta publications. It's set for publications
+	// resent in response to a resync request.
+	Snapshot bool `json:"-"`
+}
+
+// Subscription is the payload of a `broker/subscribe` request, used by clients
+// to limit and shape the broadcast publications they receive.
+type Subscription struct {
+	// Experiments limits experiment related publications to the given
+	// experiments. All experiments are included if empty.
+	Experiments []string `json:"experiments"`
+
+	// Fields limits the results of publications to the given top-level fields.
+	// All fields are included if empty.
+	Fields []string `json:"fields"`
+
+	// Deltas enables delta publications for topics the client has already
+	// received a full publication for.
+	Deltas bool `json:"deltas"`
 }
 
 type Request struct {
@@ -67,6 +98,51 @@ Response:
 	}
 }
 
+Subscribe (all fields optional):
+
+{
+	"resource": {
+		"type": "broker",
+		"action": "subscribe"
+	},
+	"request": {
+		"experiments": ["<exp name>"],
+		"fields": ["status", "vms"],
+		"deltas": true
+	}
+}
+
+Delta Publications:
+
+{
+	"resource": {
+		"type": "experiment",
+		"name": "<exp name>",
+		"action": "update"
+	},
+	"seq": 43,
+	"delta": true,
+	"base": 42,
+	"result": {
+		"status": "started"
+	}
+}
+
+Resync (when the client's copy of a topic isn't at a delta's base sequence
+number, the client requests the topic's full state to be sent again; all
+topics are sent again if the request is omitted):
+
+{
+	"resource": {
+		"type": "broker",
+		"action": "resync"
+	},
+	"request": {
+		"type": "experiment",
+		"name": "<exp name>"
+	}
+}
+
 Screenshot Updates:
 
 {
@@ -12,7 +12,6 @@ import (
 
 	"phenix/api/experiment"
 	"phenix/api/vm"
-	"phenix/util/cache"
 	"phenix/util/mm"
 	"phenix/util/plog"
 	"phenix/web/proto"
@@ -62,6 +61,10 @@ type Client struct {
 	// the WebSocket connection.
 	vms  []vmScope
 	vmMu sync.RWMutex
+
+	// Track the state of each topic published to this client so delta
+	// publications can be sent if the client has subscribed to them.
+	topics topics
 }
 
 func NewClient(role rbac.Role, conn *websocket.Conn) *Client {
@@ -141,6 +144,41 @@ func (this *Client) read() {
 			}
 
 			switch req.Resource.Type {
+			case "broker":
+				switch req.Resource.Action {
+				case "subscribe":
+					var sub bt.Subscription
+
+					if len(req.Payload) > 0 {
+						if err := json.Unmarshal(req.Payload, &sub); err != nil {
+							plog.Error("cannot unmarshal subscription payload", "err", err)
+							continue
+						}
+					}
+
+					this.topics.subscribe(sub)
+				case "resync":
+					var resource *bt.Resource
+
+					if len(req.Payload) > 0 {
+						resource = new(bt.Resource)
+
+						if err := json.Unmarshal(req.Payload, resource); err != nil {
+							plog.Error("cannot unmarshal resync payload", "err", err)
+							continue
+						}
+					}
+
+					// Topics are only tracked for publications that passed RBAC
+					// checks when they were broadcast, so no need to check again here.
+					for _, pub := range this.topics.resync(resource) {
+						this.publish <- pub
+					}
+				default:
+					plog.Error("unexpected WebSocket request resource action for broker resource type", "action", req.Resource.Action)
+				}
+
+				continue
 			case "experiment/vms":
 			case "experiment/topology":
 				// TODO: check RBAC permissions?
@@ -167,23 +205,13 @@ func (this *Client) read() {
 						continue
 					}
 
-					cacheKey := fmt.Sprintf("experiment|%s|search", req.Resource.Name)
-
-					val, ok := cache.Get(cacheKey)
-					if !ok {
-						// warm the cache (again?)
-						if _, err := vm.Topology(req.Resource.Name, nil); err != nil {
-							plog.Error("getting experiment topology", "exp", req.Resource.Name, "err", err)
-							continue
-						}
-
-						val, _ = cache.Get(cacheKey)
+					search, err := vm.GetTopologySearch(req.Resource.Name)
+					if err != nil {
+						plog.Error("getting experiment topology search", "exp", req.Resource.Name, "err", err)
+						continue
 					}
 
-					var (
-						search = val.(vm.TopologySearch)
-						nodes  []int
-					)
+					var nodes []int
 
 					switch strings.ToLower(term) {
 					case "hostname":
@@ -274,7 +302,7 @@ func (this *Client) read() {
 				continue
 			}
 
-			vms, err := vm.List(expName)
+			vms, err := vm.List(expName, vm.ListWithCachedInfo())
 			if err != nil {
 				plog.Error("getting list of VMs for experiment", "exp", expName, "err", err)
 				continue
@@ -313,15 +341,6 @@ func (this *Client) read() {
 				}
 
 				if this.role.Allowed("vms", "list", fmt.Sprintf("%s/%s", expName, vm.Name)) {
-					if vm.Running {
-						screenshot, err := util.GetScreenshot(expName, vm.Name, "200")
-						if err != nil {
-							plog.Error("getting screenshot for WebSocket client", "err", err)
-						} else {
-							vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-						}
-					}
-
 					allowed = append(allowed, vm)
 				}
 			}
@@ -333,7 +352,7 @@ func (this *Client) read() {
 				size = int(payload["page_size"].(float64))
 			)
 
-			payload = map[string]interface{}{"total": len(allowed)}
+			total := len(allowed)
 
 			if sort != "" {
 				allowed.SortBy(sort, asc)
@@ -343,6 +362,21 @@ func (this *Client) read() {
 				allowed = allowed.Paginate(page, size)
 			}
 
+			// Only get screenshots for the VMs actually being returned, since
+			// getting them for every VM in large experiments is expensive.
+			for i, vm := range allowed {
+				if !vm.Running {
+					continue
+				}
+
+				screenshot, err := util.GetScreenshot(expName, vm.Name, "200")
+				if err != nil {
+					plog.Error("getting screenshot for WebSocket client", "err", err)
+				} else {
+					allowed[i].Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+				}
+			}
+
 			this.vmMu.Lock()
 
 			this.vms = nil
@@ -353,7 +387,7 @@ func (this *Client) read() {
 
 			this.vmMu.Unlock()
 
-			resp := &proto.VMList{Total: uint32(len(allowed))}
+			resp := &proto.VMList{Total: uint32(total)}
 
 			resp.Vms = make([]*proto.VM, len(allowed))
 			for i, v := range allowed {
@@ -402,7 +436,23 @@ func (this *Client) write() {
 	}
 }
 
+// prepare shapes broadcast publications for this client based on its
+// subscription. It returns false if the message should not be sent.
+func (this *Client) prepare(msg interface{}) (interface{}, bool) {
+	pub, ok := msg.(bt.Publish)
+	if !ok || pub.Seq == 0 {
+		return msg, true
+	}
+
+	return this.topics.prepare(pub)
+}
+
 func (this *Client) publisher(msg interface{}) error {
+	msg, ok := this.prepare(msg)
+	if !ok {
+		return nil
+	}
+
 	this.connMu.Lock()
 	defer this.connMu.Unlock()
 
@@ -428,18 +478,21 @@ func (this *Client) publisher(msg interface{}) error {
 	}
 
 	for i := 0; i < len(this.publish); i++ {
-		if _, err := w.Write(newline); err != nil {
-			return fmt.Errorf("writing newline to client connection: %w", err)
+		msg, ok := this.prepare(<-this.publish)
+		if !ok {
+			continue
 		}
 
-		msg := <-this.publish
-
 		b, err := json.Marshal(msg)
 		if err != nil {
 			plog.Error("marshaling message to be published", "err", err)
 			continue
 		}
 
+		if _, err := w.Write(newline); err != nil {
+			return fmt.Errorf("writing newline to client connection: %w", err)
+		}
+
 		if _, err := w.Write(b); err != nil {
 			return fmt.Errorf("writing message to client connection: %w", err)
 		}
@@ -0,0 +1,183 @@
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"golang.org/x/exp/slices"
+)
+
+// topic tracks the last full state of a topic published to a client, which
+// delta publications to the client are computed against.
+type topic struct {
+	resource *bt.Resource
+	seq      uint64
+	state    map[string]any
+}
+
+// topics tracks the state of each topic published to a single client, along
+// with the client's subscription settings.
+type topics struct {
+	sync.Mutex
+
+	sub    bt.Subscription
+	topics map[string]*topic
+}
+
+func topicKey(r *bt.Resource) string {
+	return r.Type + "|" + r.Name
+}
+
+func (this *topics) subscribe(sub bt.Subscription) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.sub = sub
+
+	// Previously sent state may not match the new field set.
+	this.topics = nil
+}
+
+// prepare shapes the given broadcast publication for the client based on its
+// subscription. It returns false if the publication should not be sent to the
+// client at all.
+func (this *topics) prepare(pub bt.Publish) (bt.Publish, bool) {
+	this.Lock()
+	defer this.Unlock()
+
+	if pub.Resource == nil {
+		return pub, true
+	}
+
+	if !this.subscribed(pub.Resource) {
+		return pub, false
+	}
+
+	key := topicKey(pub.Resource)
+
+	if pub.Resource.Action == "delete" {
+		delete(this.topics, key)
+		return pub, true
+	}
+
+	var state map[string]any
+
+	// Only JSON object results can be filtered or sent as deltas.
+	if len(pub.Result) == 0 || json.Unmarshal(pub.Result, &state) != nil || state == nil {
+		return pub, true
+	}
+
+	if len(this.sub.Fields) > 0 {
+		for k := range state {
+			if !slices.Contains(this.sub.Fields, k) {
+				delete(state, k)
+			}
+		}
+
+		pub.Result, _ = json.Marshal(state)
+	}
+
+	if !this.sub.Deltas {
+		return pub, true
+	}
+
+	if this.topics == nil {
+		this.topics = make(map[string]*topic)
+	}
+
+	prev, ok := this.topics[key]
+	this.topics[key] = &topic{resource: pub.Resource, seq: pub.Seq, state: state}
+
+	// Resyncs are sent as full snapshots so the client can replace its copy of
+	// the topic, which may be at any sequence number.
+	if !ok || pub.Snapshot {
+		return pub, true
+	}
+
+	patch, err := json.Marshal(mergePatch(prev.state, state))
+	if err != nil || len(patch) >= len(pub.Result) {
+		// Not worth sending a delta.
+		return pub, true
+	}
+
+	pub.Delta = true
+	pub.Base = prev.seq
+	pub.Result = patch
+
+	return pub, true
+}
+
+// resync returns full publications of the current state of the topic for the
+// given resource, or of all topics if the given resource is nil.
+func (this *topics) resync(r *bt.Resource) []bt.Publish {
+	this.Lock()
+	defer this.Unlock()
+
+	var pubs []bt.Publish
+
+	for key, t := range this.topics {
+		if r != nil && key != topicKey(r) {
+			continue
+		}
+
+		result, err := json.Marshal(t.state)
+		if err != nil {
+			continue
+		}
+
+		pubs = append(pubs, bt.Publish{Resource: t.resource, Result: result, Seq: t.seq, Snapshot: true})
+	}
+
+	return pubs
+}
+
+func (this *topics) subscribed(r *bt.Resource) bool {
+	if len(this.sub.Experiments) == 0 {
+		return true
+	}
+
+	// Only experiment related publications are filtered by experiment.
+	if !strings.HasPrefix(r.Type, "experiment") && !strings.HasPrefix(r.Type, "apps/") {
+		return true
+	}
+
+	// Names of VM related resources are in the form `<exp>/<vm>`.
+	exp, _, _ := strings.Cut(r.Name, "/")
+
+	return slices.Contains(this.sub.Experiments, exp)
+}
+
+// mergePatch computes a JSON merge patch (RFC 7386) that transforms prev into
+// next. Per RFC 7386, arrays are replaced as a whole when changed.
+func mergePatch(prev, next map[string]any) map[string]any {
+	patch := make(map[string]any)
+
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			patch[k] = nil
+		}
+	}
+
+	for k, v := range next {
+		old, ok := prev[k]
+		if ok && reflect.DeepEqual(old, v) {
+			continue
+		}
+
+		oldMap, oldOK := old.(map[string]any)
+		newMap, newOK := v.(map[string]any)
+
+		if ok && oldOK && newOK {
+			patch[k] = mergePatch(oldMap, newMap)
+			continue
+		}
+
+		patch[k] = v
+	}
+
+	return patch
+}
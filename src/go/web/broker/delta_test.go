@@ -0,0 +1,106 @@
+package broker
+
+import (
+	"encoding/json"
+	"testing"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+func TestTopicsPrepare(t *testing.T) {
+	var topics topics
+
+	topics.subscribe(bt.Subscription{Experiments: []string{"foo"}, Fields: []string{"status", "vms"}, Deltas: true})
+
+	pub := func(name string, seq uint64, result string) bt.Publish {
+		return bt.Publish{Resource: bt.NewResource("experiment", name, "update"), Seq: seq, Result: json.RawMessage(result)}
+	}
+
+	if _, ok := topics.prepare(pub("bar", 1, `{"status": "started"}`)); ok {
+		t.Fatal("expected publication for unsubscribed experiment to be dropped")
+	}
+
+	full, ok := topics.prepare(pub("foo", 1, `{"status": "stopped", "vms": [{"name": "vm1", "state": "SHUTDOWN"}], "topology": "big"}`))
+	if !ok || full.Delta {
+		t.Fatalf("expected full publication, got %+v", full)
+	}
+
+	if string(full.Result) != `{"status":"stopped","vms":[{"name":"vm1","state":"SHUTDOWN"}]}` {
+		t.Fatalf("expected result to be limited to subscribed fields, got %s", full.Result)
+	}
+
+	delta, ok := topics.prepare(pub("foo", 2, `{"status": "started", "vms": [{"name": "vm1", "state": "SHUTDOWN"}]}`))
+	if !ok || !delta.Delta || delta.Base != 1 {
+		t.Fatalf("expected delta publication against seq 1, got %+v", delta)
+	}
+
+	if string(delta.Result) != `{"status":"started"}` {
+		t.Fatalf("unexpected delta result %s", delta.Result)
+	}
+
+	resync := topics.resync(bt.NewResource("experiment", "foo", ""))
+	if len(resync) != 1 || resync[0].Seq != 2 || resync[0].Delta {
+		t.Fatalf("unexpected resync publications %+v", resync)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	var prev, next map[string]any
+
+	json.Unmarshal([]byte(`{"a": 1, "b": {"c": 2, "d": 3}, "e": [1, 2]}`), &prev)
+	json.Unmarshal([]byte(`{"a": 1, "b": {"c": 2, "d": 4}, "e": [1, 2, 3], "f": true}`), &next)
+
+	patch, _ := json.Marshal(mergePatch(prev, next))
+
+	if string(patch) != `{"b":{"d":4},"e":[1,2,3],"f":true}` {
+		t.Fatalf("unexpected merge patch %s", patch)
+	}
+
+	patch, _ = json.Marshal(mergePatch(next, prev))
+
+	if string(patch) != `{"b":{"d":3},"e":[1,2],"f":null}` {
+		t.Fatalf("unexpected merge patch %s", patch)
+	}
+}
+
+func TestClientPrepareResync(t *testing.T) {
+	client := new(Client)
+	client.topics.subscribe(bt.Subscription{Deltas: true})
+
+	pub := func(seq uint64, result string) bt.Publish {
+		return bt.Publish{Resource: bt.NewResource("experiment", "foo", "update"), Seq: seq, Result: json.RawMessage(result)}
+	}
+
+	client.prepare(pub(1, `{"status": "stopped", "vms": [{"name": "vm1"}]}`))
+
+	if msg, _ := client.prepare(pub(2, `{"status": "started", "vms": [{"name": "vm1"}]}`)); !msg.(bt.Publish).Delta {
+		t.Fatalf("expected delta publication, got %+v", msg)
+	}
+
+	resync := client.topics.resync(nil)
+	if len(resync) != 1 {
+		t.Fatalf("expected a single resync publication, got %+v", resync)
+	}
+
+	// Resync publications are queued to the client like any other publication,
+	// so they must make it through prepare as full snapshots.
+	msg, ok := client.prepare(resync[0])
+	if !ok {
+		t.Fatal("expected resync publication to be sent")
+	}
+
+	full := msg.(bt.Publish)
+
+	if full.Delta || full.Base != 0 || full.Seq != 2 {
+		t.Fatalf("expected full resync publication at seq 2, got %+v", full)
+	}
+
+	if string(full.Result) != `{"status":"started","vms":[{"name":"vm1"}]}` {
+		t.Fatalf("unexpected resync result %s", full.Result)
+	}
+
+	// Deltas continue against the resynced state.
+	if msg, _ := client.prepare(pub(3, `{"status": "stopped", "vms": [{"name": "vm1"}]}`)); !msg.(bt.Publish).Delta || msg.(bt.Publish).Base != 2 {
+		t.Fatalf("expected delta publication against seq 2, got %+v", msg)
+	}
+}
@@ -43,9 +43,11 @@ type builder struct {
 func GetBuilder(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetBuilder")
 
-	if o.unbundled {
+	opts := options()
+
+	if opts.unbundled {
 		tmpl := template.Must(template.New("builder.html").ParseFiles("web/public/builder.html"))
-		tmpl.Execute(w, o.basePath)
+		tmpl.Execute(w, opts.basePath)
 	} else {
 		bfs := util.NewBinaryFileSystem(
 			&assetfs.AssetFS{
@@ -55,7 +57,7 @@ func GetBuilder(w http.ResponseWriter, r *http.Request) {
 			},
 		)
 
-		bfs.ServeTemplate(w, "builder.html", o.basePath)
+		bfs.ServeTemplate(w, "builder.html", opts.basePath)
 	}
 }
 
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/anonymize"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/bundle
+func GetExperimentBundle(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentBundle")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		name  = mux.Vars(r)["name"]
+		query = r.URL.Query()
+		opts  = []experiment.BundleOption{experiment.BundleWithFiles(query.Get("files") != "false")}
+	)
+
+	if !role.Allowed("experiments/files", "get", name) {
+		err := weberror.NewWebError(nil, "exporting bundle for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if query.Get("anonymize") == "true" {
+		anon, err := experimentAnonymizer(name)
+		if err != nil {
+			return weberror.NewWebError(err, "unable to anonymize experiment %s", name)
+		}
+
+		opts = append(opts, experiment.BundleWithAnonymizer(anon))
+	}
+
+	var buf bytes.Buffer
+
+	skipped, err := experiment.Bundle(name, &buf, opts...)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to export bundle for experiment %s", name)
+	}
+
+	for _, s := range skipped {
+		plog.Warn("file left out of experiment bundle", "exp", name, "file", s)
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tgz", name))
+	http.ServeContent(w, r, "", time.Now(), bytes.NewReader(buf.Bytes()))
+
+	return nil
+}
+
+// experimentAnonymizer returns an anonymizer for the given experiment using the
+// server's anonymization key.
+func experimentAnonymizer(name string) (*anonymize.Anonymizer, error) {
+	key, err := anonymize.LoadKey(experiment.AnonymizeKeyPath())
+	if err != nil {
+		return nil, err
+	}
+
+	return experiment.Anonymizer(name, key)
+}
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"phenix/api/callback"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	bt "phenix/web/broker/brokertypes"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// newCallbackRouter returns the router for the guest callback API, which is
+// served on its own endpoint so guests don't get access to the rest of the API.
+func newCallbackRouter() *mux.Router {
+	var (
+		router = mux.NewRouter().StrictSlash(true)
+		api    = router.PathPrefix("/api/v1/callback").Subrouter()
+	)
+
+	api.Handle("/ready", weberror.ErrorHandler(CallbackReady)).Methods("POST")
+	api.Handle("/metadata", weberror.ErrorHandler(GetCallbackMetadata)).Methods("GET")
+	api.Handle("/guestinfo", weberror.ErrorHandler(GetCallbackGuestInfo)).Methods("GET")
+	api.Handle("/secrets/{name}", weberror.ErrorHandler(GetCallbackSecret)).Methods("GET")
+
+	api.Use(callbackAuth)
+
+	return router
+}
+
+// callbackAuth authenticates guests using the callback token minted for their
+// VM, provided as a bearer token in the Authorization header.
+func callbackAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		exp, vm, err := callback.Lookup(token)
+		if err != nil {
+			if !errors.Is(err, callback.ErrInvalidToken) {
+				plog.Error("looking up callback token", "err", err)
+			}
+
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "callback-exp", exp)
+		ctx = context.WithValue(ctx, "callback-vm", vm)
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// POST /callback/ready
+func CallbackReady(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CallbackReady")
+
+	var (
+		ctx = r.Context()
+		exp = ctx.Value("callback-exp").(string)
+		vm  = ctx.Value("callback-vm").(string)
+		req struct {
+			Message string `json:"message"`
+		}
+	)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return weberror.NewWebError(err, "unable to parse request body")
+		}
+	}
+
+	if err := callback.Ready(exp, vm, req.Message); err != nil {
+		err := weberror.NewWebError(err, "unable to mark VM %s in experiment %s as ready", vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info("VM reported ready via callback", "exp", exp, "vm", vm)
+
+	body, _ = json.Marshal(map[string]string{"vm": vm, "message": req.Message})
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms", "get", exp+"/"+vm),
+		bt.NewResource("experiment/vm", exp+"/"+vm, "ready"),
+		body,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// GET /callback/metadata
+func GetCallbackMetadata(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetCallbackMetadata")
+
+	var (
+		ctx = r.Context()
+		exp = ctx.Value("callback-exp").(string)
+		vm  = ctx.Value("callback-vm").(string)
+	)
+
+	node, err := callback.Node(exp, vm)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get metadata for VM %s in experiment %s", vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(map[string]any{"experiment": exp, "node": node})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process metadata for VM %s in experiment %s", vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /callback/guestinfo
+func GetCallbackGuestInfo(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetCallbackGuestInfo")
+
+	var (
+		ctx = r.Context()
+		exp = ctx.Value("callback-exp").(string)
+		vm  = ctx.Value("callback-vm").(string)
+	)
+
+	info, err := callback.GuestInfo(exp, vm)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get guest info for VM %s in experiment %s", vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(info)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process guest info for VM %s in experiment %s", vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /callback/secrets/{name}
+func GetCallbackSecret(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetCallbackSecret")
+
+	var (
+		ctx  = r.Context()
+		exp  = ctx.Value("callback-exp").(string)
+		vm   = ctx.Value("callback-vm").(string)
+		name = mux.Vars(r)["name"]
+	)
+
+	secret, err := callback.Secret(exp, vm, name)
+	if err != nil {
+		if errors.Is(err, callback.ErrSecretNotFound) {
+			err := weberror.NewWebError(err, "secret %s not found for VM %s", name, vm)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to get secret %s for VM %s in experiment %s", name, vm, exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info("secret fetched via callback", "exp", exp, "vm", vm, "secret", name)
+
+	// Served as plain text so in-guest scripts can use it as is.
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(secret))
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"phenix/api/experiment"
+	"phenix/api/timeline"
 	"phenix/api/vm"
 	"phenix/app"
 	"phenix/types"
@@ -30,7 +31,43 @@ var (
 	waiters   = make(map[string]*sync.WaitGroup)
 )
 
-func startExperiment(name string) ([]byte, error) {
+// resumeTimelines restarts the timelines of experiments that were already
+// running when the server started (ie. after the server was restarted), so
+// their remaining events still get executed. Events already executed or
+// skipped aren't executed again.
+func resumeTimelines() {
+	exps, err := experiment.List()
+	if err != nil {
+		plog.Error("getting list of experiments to resume timelines for", "err", err)
+		return
+	}
+
+	for _, exp := range exps {
+		if !exp.Running() || !timeline.Configured(&exp) {
+			continue
+		}
+
+		name := exp.Metadata.Name
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+
+		if err := timeline.Start(ctx, &wg, &exp); err != nil {
+			cancel() // avoid leakage
+
+			plog.Error("resuming experiment timeline", "exp", name, "err", err)
+			continue
+		}
+
+		cancelers[name] = append(cancelers[name], cancel)
+		waiters[name] = &wg
+
+		plog.Info("resumed experiment timeline", "exp", name)
+	}
+}
+
+func startExperiment(name string, opts ...experiment.StartOption) ([]byte, error) {
 	if err := cache.LockExperimentForStarting(name); err != nil {
 		err := weberror.NewWebError(err, "unable to lock experiment %s for starting", name)
 		return nil, err.SetStatus(http.StatusConflict)
@@ -60,7 +97,9 @@ func startExperiment(name string) ([]byte, error) {
 
 		ch := make(chan error)
 
-		if err := experiment.Start(ctx, experiment.StartWithName(name), experiment.StartWithErrorChannel(ch)); err != nil {
+		opts = append(opts, experiment.StartWithName(name), experiment.StartWithErrorChannel(ch))
+
+		if err := experiment.Start(ctx, opts...); err != nil {
 			cancel() // avoid leakage
 			delete(cancelers, name)
 
@@ -128,6 +167,11 @@ func startExperiment(name string) ([]byte, error) {
 				)
 
 				err := weberror.NewWebError(s.err, "unable to start experiment %s", name)
+
+				if errors.Is(s.err, experiment.ErrConcurrencyConflict) {
+					return nil, err.SetStatus(http.StatusConflict)
+				}
+
 				return nil, err.SetStatus(http.StatusBadRequest)
 			}
 
@@ -146,6 +190,14 @@ func startExperiment(name string) ([]byte, error) {
 				fmt.Printf("Error scheduling experiment apps to run periodically: %v\n", err)
 			}
 
+			if err := timeline.Start(ctx, &wg, s.exp); err != nil {
+				plog.Error("starting experiment timeline", "exp", name, "err", err)
+			}
+
+			if err := experiment.LaunchDeferred(ctx, &wg, s.exp); err != nil {
+				plog.Error("launching deferred VMs", "exp", name, "err", err)
+			}
+
 			vms, err := vm.List(name)
 			if err != nil {
 				// TODO
@@ -195,7 +247,7 @@ func startExperiment(name string) ([]byte, error) {
 	}
 }
 
-func stopExperiment(name string) ([]byte, error) {
+func stopExperiment(name string, opts ...experiment.StopOption) ([]byte, error) {
 	if err := cache.LockExperimentForStopping(name); err != nil {
 		err := weberror.NewWebError(err, "unable to lock experiment %s for stopping", name)
 		return nil, err.SetStatus(http.StatusConflict)
@@ -222,7 +274,7 @@ func stopExperiment(name string) ([]byte, error) {
 	delete(cancelers, name)
 	delete(waiters, name)
 
-	if err := experiment.Stop(name); err != nil {
+	if err := experiment.Stop(name, opts...); err != nil {
 		broker.Broadcast(
 			bt.NewRequestPolicy("experiments/stop", "update", name),
 			bt.NewResource("experiment", name, "errorStopping"),
@@ -79,6 +79,70 @@ func GetConfigs(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// GET /configs/search
+func SearchConfigs(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SearchConfigs")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		query = config.ParseSearchQuery(r.URL.Query().Get("q"))
+	)
+
+	if !role.Allowed("configs", "list") {
+		err := weberror.NewWebError(nil, "searching configs not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	// Individual query parameters take precedence over fields in the query
+	// string.
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query.Kind = kind
+	}
+
+	if ip := r.URL.Query().Get("ip"); ip != "" {
+		query.IP = ip
+	}
+
+	if app := r.URL.Query().Get("app"); app != "" {
+		query.App = app
+	}
+
+	if key := r.URL.Query().Get("appKey"); key != "" {
+		query.AppKey = key
+	}
+
+	configs, err := config.Search(query)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to search configs")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	allowed := []store.Config{}
+
+	for _, cfg := range configs {
+		if !role.Allowed("configs", "list", cfg.FullName()) {
+			continue
+		}
+
+		cfg.Spec = nil
+		cfg.Status = nil
+
+		allowed = append(allowed, cfg)
+	}
+
+	body, err := json.Marshal(util.WithRoot("configs", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process configs")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
 // POST /configs/download
 func DownloadConfigs(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "DownloadConfigs")
@@ -512,6 +576,13 @@ func UpdateConfig(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// configFullName returns the full name of the config in the request path, as
+// used for RBAC checks.
+func configFullName(r *http.Request) string {
+	vars := mux.Vars(r)
+	return store.ConfigFullName(vars["kind"], vars["name"])
+}
+
 // DELETE /configs/{kind}/{name}
 func DeleteConfig(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "DeleteConfig")
@@ -0,0 +1,129 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/diagnostics
+func GetExperimentDiagnostics(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentDiagnostics")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+	)
+
+	if !role.Allowed("vms/diagnostics", "list") {
+		err := weberror.NewWebError(nil, "listing VM diagnostics for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	diags, err := vm.ListDiagnostics(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM diagnostics for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	allowed := []vm.Diagnostics{}
+
+	for _, diag := range diags {
+		if role.Allowed("vms/diagnostics", "list", fmt.Sprintf("%s/%s", exp, diag.VM)) {
+			allowed = append(allowed, diag)
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{"diagnostics": allowed})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM diagnostics for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /experiments/{exp}/vms/{name}/diagnostics
+func GetVMDiagnostics(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetVMDiagnostics")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/diagnostics", "get", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "getting diagnostics for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	_, path, err := vm.DiagnosticsBundle(exp, name)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err := weberror.NewWebError(err, "no diagnostics collected for VM %s", name)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to get diagnostics for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%s-diagnostics.tar.gz", exp, name))
+	http.ServeFile(w, r, path)
+
+	return nil
+}
+
+// POST /experiments/{exp}/vms/{name}/diagnostics
+func CollectVMDiagnostics(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CollectVMDiagnostics")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+		user = ctx.Value("user").(string)
+	)
+
+	if !role.Allowed("vms/diagnostics", "create", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "collecting diagnostics for VM %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	diag, err := vm.CollectDiagnostics(exp, name, "requested by "+user)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to collect diagnostics for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(diag)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process diagnostics for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
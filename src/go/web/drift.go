@@ -0,0 +1,105 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/vms/{name}/drift
+func GetVMDrift(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetVMDrift")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/drift", "get", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "checking drift for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	drift, err := vm.CheckDrift(ctx, exp, name)
+	if err != nil {
+		if errors.Is(err, vm.ErrNoGoldenState) {
+			err := weberror.NewWebError(err, "no golden state recorded for the image used by VM %s", name)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to check drift for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(map[string]any{"drift": drift, "drifted": drift.Drifted()})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process drift for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{exp}/vms/{name}/drift/golden
+func RecordVMGoldenState(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RecordVMGoldenState")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/drift", "create", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "recording golden state from VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	var req struct {
+		Paths []string `json:"paths"`
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return weberror.NewWebError(err, "unable to parse request body")
+		}
+	}
+
+	state, err := vm.RecordGoldenState(ctx, exp, name, req.Paths)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to record golden state from VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err = json.Marshal(state)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process golden state for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
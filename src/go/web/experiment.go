@@ -2,12 +2,10 @@ package web
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 
 	"phenix/api/vm"
-	"phenix/util/cache"
 	"phenix/util/mm"
 	"phenix/util/plog"
 	"phenix/web/rbac"
@@ -80,22 +78,13 @@ func SearchExperimentTopology(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cacheKey := fmt.Sprintf("experiment|%s|search", name)
-
-	val, ok := cache.Get(cacheKey)
-	if !ok {
-		if _, err := vm.Topology(name, nil); err != nil {
-			http.Error(w, "error getting experiment topology", http.StatusBadRequest)
-			return
-		}
-
-		val, _ = cache.Get(cacheKey)
+	search, err := vm.GetTopologySearch(name)
+	if err != nil {
+		http.Error(w, "error getting experiment topology", http.StatusBadRequest)
+		return
 	}
 
-	var (
-		search = val.(vm.TopologySearch)
-		nodes  []int
-	)
+	var nodes []int
 
 	// TODO: how to handle multiple terms? AND or OR?
 
@@ -11,7 +11,7 @@ import (
 func GetFeatures(w http.ResponseWriter, r *http.Request) {
 	features := make([]string, 0)
 
-	for f := range o.features {
+	for f := range options().features {
 		features = append(features, f)
 	}
 
@@ -136,7 +136,7 @@ func GetExperiments(w http.ResponseWriter, r *http.Request) {
 }
 
 // POST /experiments
-func CreateExperiment(w http.ResponseWriter, r *http.Request) {
+func CreateExperiment(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "CreateExperiment")
 
 	var (
@@ -145,29 +145,25 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if !role.Allowed("experiments", "create") {
-		plog.Warn("creating experiments not allowed", "user", ctx.Value("user").(string))
-		http.Error(w, "forbidden", http.StatusForbidden)
-		return
+		err := weberror.NewWebError(nil, "creating experiments not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		plog.Error("reading request body", "err", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+		err := weberror.NewWebError(err, "unable to read request body")
+		return err.SetStatus(http.StatusInternalServerError)
 	}
 
 	var req proto.CreateExperimentRequest
 	if err := unmarshaler.Unmarshal(body, &req); err != nil {
-		plog.Error("unmashaling request body", "err", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		return
+		err := weberror.NewWebError(err, "unable to parse request body")
+		return err.SetStatus(http.StatusInternalServerError)
 	}
 
 	if err := cache.LockExperimentForCreation(req.Name); err != nil {
-		plog.Error("locking experiment", "exp", req.Name, "action", "creation", "err", err)
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
+		err := weberror.NewWebError(err, "unable to lock experiment %s for creation", req.Name)
+		return err.SetStatus(http.StatusConflict)
 	}
 
 	defer cache.UnlockExperiment(req.Name)
@@ -188,6 +184,8 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 		experiment.CreateWithDeployMode(deployMode),
 		experiment.CreateWithDefaultBridge(req.DefaultBridge),
 		experiment.CreateWithGREMesh(req.UseGreMesh),
+		experiment.CreateWithOwner(ctx.Value("user").(string)),
+		experiment.CreateWithEnvironment(req.Environment),
 	}
 
 	if req.WorkflowBranch != "" {
@@ -196,9 +194,10 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := experiment.Create(ctx, opts...); err != nil {
-		plog.Error("creating experiment", "exp", req.Name, "err", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		// The error code and failing resource of typed errors are included in the
+		// response so clients can branch on the failure category.
+		err := weberror.NewWebError(err, "unable to create experiment %s", req.Name)
+		return err.SetStatus(http.StatusBadRequest)
 	}
 
 	if warns := notes.Warnings(ctx, true); warns != nil {
@@ -209,23 +208,20 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 
 	exp, err := experiment.Get(req.Name)
 	if err != nil {
-		plog.Error("getting experiment", "exp", req.Name, "err", err)
-		http.Error(w, "", http.StatusInternalServerError)
-		return
+		err := weberror.NewWebError(err, "unable to get experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
 	}
 
 	vms, err := vm.List(req.Name)
 	if err != nil {
-		plog.Error("listing experiment VMs", "exp", req.Name, "err", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		err := weberror.NewWebError(err, "unable to list VMs for experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
 	}
 
 	body, err = marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
 	if err != nil {
-		plog.Error("marshaling experiment", "err", req.Name, "err", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		err := weberror.NewWebError(err, "unable to process experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
 	}
 
 	broker.Broadcast(
@@ -235,6 +231,8 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 	)
 
 	w.WriteHeader(http.StatusNoContent)
+
+	return nil
 }
 
 // PUT /experiments/{name}
@@ -326,7 +324,7 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 		return weberror.NewWebError(err, "unable to get experiment %s from store", name)
 	}
 
-	vms, err := vm.List(name)
+	vms, err := vm.List(name, vm.ListWithCachedInfo())
 	if err != nil {
 		// TODO
 	}
@@ -360,15 +358,6 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 		}
 
 		if role.Allowed("vms", "list", fmt.Sprintf("%s/%s", name, vm.Name)) {
-			if vm.Running && size != "" {
-				screenshot, err := util.GetScreenshot(name, vm.Name, size)
-				if err != nil {
-					plog.Error("getting screenshot", "err", err)
-				} else {
-					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-				}
-			}
-
 			allowed = append(allowed, vm)
 		}
 	}
@@ -386,6 +375,23 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 		allowed = allowed.Paginate(n, s)
 	}
 
+	// Only get screenshots for the VMs actually being returned, since getting
+	// them for every VM in large experiments is expensive.
+	if size != "" {
+		for i, vm := range allowed {
+			if !vm.Running {
+				continue
+			}
+
+			screenshot, err := util.GetScreenshot(name, vm.Name, size)
+			if err != nil {
+				plog.Error("getting screenshot", "err", err)
+			} else {
+				allowed[i].Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+			}
+		}
+	}
+
 	experiment := util.ExperimentToProtobuf(*exp, status, allowed)
 	experiment.VmCount = uint32(totalBeforePaging)
 	body, err := marshaler.Marshal(experiment)
@@ -439,7 +445,7 @@ func DeleteExperiment(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// POST /experiments/{name}/start
+// POST /experiments/{name}/start[?profile=lite]
 func StartExperiment(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "StartExperiment")
 
@@ -455,7 +461,54 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := startExperiment(name)
+	profile := r.URL.Query().Get("profile")
+
+	if _, err := experiment.GetStartProfile(profile); err != nil {
+		return weberror.NewWebError(err, "invalid start profile %s", profile).SetStatus(http.StatusBadRequest)
+	}
+
+	var (
+		bestEffort = r.URL.Query().Get("bestEffort") == "true"
+		queue      = r.URL.Query().Get("queue") == "true"
+		opts       = []experiment.StartOption{experiment.StartWithProfile(profile), experiment.StartWithBestEffort(bestEffort), experiment.StartWithQueue(queue)}
+	)
+
+	// Optional limits on how long apps can run for each start stage.
+	for param, opt := range map[string]func(time.Duration) experiment.StartOption{
+		"stageTimeout": experiment.StartWithStageTimeout,
+		"appTimeout":   experiment.StartWithAppTimeout,
+	} {
+		if val := r.URL.Query().Get(param); val != "" {
+			timeout, err := time.ParseDuration(val)
+			if err != nil {
+				return weberror.NewWebError(err, "invalid %s %s", param, val).SetStatus(http.StatusBadRequest)
+			}
+
+			opts = append(opts, opt(timeout))
+		}
+	}
+
+	opts = append(opts, experiment.StartWithUser(ctx.Value("user").(string)))
+
+	// Optional retries for apps that fail in each start stage.
+	if val := r.URL.Query().Get("appRetries"); val != "" {
+		retries, err := strconv.Atoi(val)
+		if err != nil || retries < 0 {
+			return weberror.NewWebError(err, "invalid appRetries %s", val).SetStatus(http.StatusBadRequest)
+		}
+
+		backoff := app.DEFAULT_RETRY_BACKOFF
+
+		if val := r.URL.Query().Get("appRetryBackoff"); val != "" {
+			if backoff, err = time.ParseDuration(val); err != nil {
+				return weberror.NewWebError(err, "invalid appRetryBackoff %s", val).SetStatus(http.StatusBadRequest)
+			}
+		}
+
+		opts = append(opts, experiment.StartWithAppRetries(retries, backoff))
+	}
+
+	body, err := startExperiment(name, opts...)
 	if err != nil {
 		return err
 	}
@@ -480,7 +533,7 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := stopExperiment(name)
+	body, err := stopExperiment(name, experiment.StopWithUser(ctx.Value("user").(string)))
 	if err != nil {
 		return err
 	}
@@ -496,6 +549,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 	var (
 		ctx  = r.Context()
 		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
 		vars = mux.Vars(r)
 		name = vars["name"]
 
@@ -504,7 +558,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 	)
 
 	if !role.Allowed("experiments/trigger", "create", name) {
-		plog.Warn("triggering experiment apps not allowed", "user", ctx.Value("user").(string), "exp", name)
+		plog.Warn("triggering experiment apps not allowed", "user", user, "exp", name)
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -530,6 +584,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithCancel(context.Background())
 			ctx = app.SetContextTriggerUI(ctx)
 			ctx = app.SetContextMetadata(ctx, md)
+			ctx = app.SetContextUser(ctx, user)
 			cancelers[k] = append(cancelers[k], cancel)
 
 			if err := experiment.TriggerRunning(ctx, name, a); err != nil {
@@ -828,16 +883,67 @@ func GetExperimentFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if query.Get("anonymize") == "true" {
+		anon, err := experimentAnonymizer(name)
+		if err != nil {
+			plog.Error("creating anonymizer for experiment", "exp", name, "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if contents, err = anon.File(path, contents); err != nil {
+			plog.Error("anonymizing file for experiment", "exp", name, "file", path, "err", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	if r.Header.Get("Accept") == "text/plain" {
 		w.Header().Set("Content-Type", "text/plain")
 		w.Write(contents)
 		return
 	}
 
+	// Use the MIME type registered for app artifacts instead of sniffing it.
+	if mimeType := experiment.ArtifactMIMEType(name, path); mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+
 	w.Header().Set("Content-Disposition", "attachment; filename="+file)
 	http.ServeContent(w, r, "", time.Now(), bytes.NewReader(contents))
 }
 
+// GET /experiments/{name}/artifacts
+func GetExperimentArtifacts(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentArtifacts")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/files", "list", name) {
+		err := weberror.NewWebError(nil, "listing artifacts for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	artifacts, err := experiment.Artifacts(name)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get artifacts for experiment %s", name)
+	}
+
+	body, err := json.Marshal(util.WithRoot("artifacts", artifacts))
+	if err != nil {
+		return weberror.NewWebError(err, "unable to process artifacts for experiment %s", name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
 // GET /experiments/{name}/apps
 func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "GetExperimentApps")
@@ -876,21 +982,51 @@ func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// GET /experiments/{name}/apps/runs
+func GetExperimentAppRuns(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentAppRuns")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/apps", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment app runs for %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get experiment %s from store", name)
+	}
+
+	body, _ := json.Marshal(exp.Status.AppRuns())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
 // GET /experiments/{exp}/vms
 func GetVMs(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetVMs")
 
 	var (
-		ctx     = r.Context()
-		role    = ctx.Value("role").(rbac.Role)
-		vars    = mux.Vars(r)
-		expName = vars["exp"]
-		query   = r.URL.Query()
-		size    = query.Get("screenshot")
-		sortCol = query.Get("sortCol")
-		sortDir = query.Get("sortDir")
-		pageNum = query.Get("pageNum")
-		perPage = query.Get("perPage")
+		ctx          = r.Context()
+		role         = ctx.Value("role").(rbac.Role)
+		vars         = mux.Vars(r)
+		expName      = vars["exp"]
+		query        = r.URL.Query()
+		size         = query.Get("screenshot")
+		sortCol      = query.Get("sortCol")
+		sortDir      = query.Get("sortDir")
+		pageNum      = query.Get("pageNum")
+		perPage      = query.Get("perPage")
+		fields       = query.Get("fields")
+		clientFilter = query.Get("filter")
 	)
 
 	if !role.Allowed("vms", "list") {
@@ -904,7 +1040,7 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vms, err := vm.List(expName)
+	vms, err := vm.List(expName, vm.ListWithCachedInfo())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -912,17 +1048,18 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 
 	allowed := mm.VMs{}
 
+	// Build a Boolean expression tree and determine
+	// the fields that should be searched
+	filterTree := mm.BuildTree(clientFilter)
+
 	for _, vm := range vms {
-		if role.Allowed("vms", "list", fmt.Sprintf("%s/%s", expName, vm.Name)) {
-			if vm.Running && size != "" {
-				screenshot, err := util.GetScreenshot(expName, vm.Name, size)
-				if err != nil {
-					plog.Error("getting screenshot", "err", err)
-				} else {
-					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-				}
-			}
+		// If the filter supplied could not be
+		// parsed, do not add the VM
+		if len(clientFilter) > 0 && (filterTree == nil || !filterTree.Evaluate(&vm)) {
+			continue
+		}
 
+		if role.Allowed("vms", "list", fmt.Sprintf("%s/%s", expName, vm.Name)) {
 			allowed = append(allowed, vm)
 		}
 	}
@@ -931,6 +1068,8 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		allowed.SortBy(sortCol, sortDir == "asc")
 	}
 
+	total := len(allowed)
+
 	if pageNum != "" && perPage != "" {
 		n, _ := strconv.Atoi(pageNum)
 		s, _ := strconv.Atoi(perPage)
@@ -938,14 +1077,38 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		allowed = allowed.Paginate(n, s)
 	}
 
-	resp := &proto.VMList{Total: uint32(len(allowed))}
+	// Only get screenshots for the VMs actually being returned, since getting
+	// them for every VM in large experiments is expensive.
+	if size != "" {
+		for i, vm := range allowed {
+			if !vm.Running {
+				continue
+			}
+
+			screenshot, err := util.GetScreenshot(expName, vm.Name, size)
+			if err != nil {
+				plog.Error("getting screenshot", "err", err)
+			} else {
+				allowed[i].Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+			}
+		}
+	}
+
+	resp := &proto.VMList{Total: uint32(total)}
 
 	resp.Vms = make([]*proto.VM, len(allowed))
 	for i, v := range allowed {
 		resp.Vms[i] = util.VMToProtobuf(expName, v, exp.Spec.Topology())
 	}
 
-	body, err := marshaler.Marshal(resp)
+	var body []byte
+
+	if fields == "" {
+		body, err = marshaler.Marshal(resp)
+	} else {
+		body, err = selectVMFields(resp, strings.Split(fields, ","))
+	}
+
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -954,6 +1117,30 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// selectVMFields marshals the given VM list with only the given VM fields
+// included for each VM, using the JSON names of the VM fields (ie.
+// `name,state,ipv4`).
+func selectVMFields(list *proto.VMList, fields []string) ([]byte, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	vms := make([]map[string]json.RawMessage, len(list.Vms))
+
+	for i, v := range list.Vms {
+		body, err := marshaler.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if vms[i], err = util.SelectFields(body, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(map[string]any{"vms": vms, "total": list.Total})
+}
+
 // GET /experiments/{exp}/vms/{name}
 func GetVM(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetVM")
@@ -1054,7 +1241,7 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 	case *proto.UpdateVMRequest_Host:
 		opts = append(opts, vm.UpdateWithHost(req.GetHost()))
 	}
-	
+
 	switch req.SnapshotOption.(type) {
 	case *proto.UpdateVMRequest_Snapshot:
 		opts = append(opts, vm.UpdateWithSnapshot(req.GetSnapshot()))
@@ -2771,7 +2958,7 @@ func GetError(w http.ResponseWriter, r *http.Request) error {
 func CreateConsole(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "CreateConsole")
 
-	if !o.minimegaConsole {
+	if !options().minimegaConsole {
 		plog.Error("request made for minimega console, but console not enabled")
 		http.Error(w, "'minimega-console' CLI arg not enabled", http.StatusMethodNotAllowed)
 		return
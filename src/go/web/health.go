@@ -0,0 +1,58 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"phenix/api/health"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/health
+func GetExperimentHealth(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentHealth")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["name"]
+	)
+
+	if !role.Allowed("vms/health", "list") {
+		err := weberror.NewWebError(nil, "listing VM health for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	report, err := health.Get(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM health for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	allowed := report.VMs[:0]
+
+	for _, vm := range report.VMs {
+		if role.Allowed("vms/health", "list", fmt.Sprintf("%s/%s", exp, vm.Name)) {
+			allowed = append(allowed, vm)
+		}
+	}
+
+	report.VMs = allowed
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM health for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
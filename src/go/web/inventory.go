@@ -0,0 +1,129 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/inventory"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/inventory[?vm=<vm name>][&package=<package name>[&version=<version constraint>]]
+func GetExperimentInventory(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentInventory")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		name  = vars["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("experiments/inventory", "get", name) {
+		err := weberror.NewWebError(nil, "getting inventory for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var result any
+
+	if pkg := query.Get("package"); pkg != "" {
+		matches, err := inventory.Query(name, pkg, query.Get("version"))
+		if err != nil {
+			return inventoryError(err, name)
+		}
+
+		result = util.WithRoot("matches", matches)
+	} else {
+		hosts, err := inventory.Get(name, query["vm"]...)
+		if err != nil {
+			return inventoryError(err, name)
+		}
+
+		result = util.WithRoot("hosts", hosts)
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process inventory for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/inventory
+func CollectExperimentInventory(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CollectExperimentInventory")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/inventory", "create", name) {
+		err := weberror.NewWebError(nil, "collecting inventory for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		VMs []string `json:"vms"`
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			return weberror.NewWebError(err, "invalid inventory request provided")
+		}
+	}
+
+	// We don't want to use the HTTP request's context here since collection can
+	// take a while and shouldn't be canceled if the client goes away.
+	hosts, err := inventory.Collect(context.Background(), name, inventory.CollectFromHosts(req.VMs...))
+	if err != nil {
+		return inventoryError(err, name)
+	}
+
+	body, err = json.Marshal(util.WithRoot("hosts", hosts))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process inventory for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+func inventoryError(err error, name string) error {
+	switch {
+	case errors.Is(err, inventory.ErrNoInventory):
+		err := weberror.NewWebError(err, "no inventory collected for experiment %s", name)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, experiment.ErrExperimentNotRunning):
+		err := weberror.NewWebError(err, "experiment %s is not running", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	return weberror.NewWebError(err, "unable to process inventory for experiment %s", name).SetStatus(http.StatusInternalServerError)
+}
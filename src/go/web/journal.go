@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"phenix/util/journal"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/journal?category=app,soh&since=<RFC3339>&until=<RFC3339>
+func GetExperimentJournal(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentJournal")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		name  = mux.Vars(r)["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("experiments/journal", "get", name) {
+		err := weberror.NewWebError(nil, "getting timeline for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var (
+		filter journal.Filter
+		err    error
+	)
+
+	if filter.Categories, err = journal.ParseCategories(query.Get("category")); err != nil {
+		return weberror.NewWebError(err, "invalid timeline category provided").SetStatus(http.StatusBadRequest)
+	}
+
+	for key, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := query.Get(key); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return weberror.NewWebError(err, "invalid %s time provided (expected RFC3339)", key).SetStatus(http.StatusBadRequest)
+			}
+		}
+	}
+
+	entries, err := journal.Timeline(name, filter)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get timeline for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(util.WithRoot("entries", entries))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process timeline for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/journal
+func AddExperimentAnnotation(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "AddExperimentAnnotation")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/journal", "create", name) {
+		err := weberror.NewWebError(nil, "annotating timeline for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Message string    `json:"message"`
+		Time    time.Time `json:"time"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid annotation provided").SetStatus(http.StatusBadRequest)
+	}
+
+	entry, err := journal.Annotate(name, user, req.Message, req.Time)
+	if err != nil {
+		if errors.Is(err, journal.ErrEmptyAnnotation) {
+			return weberror.NewWebError(err, "annotation message is required").SetStatus(http.StatusBadRequest)
+		}
+
+		err := weberror.NewWebError(err, "unable to annotate timeline for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, _ = json.Marshal(entry)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
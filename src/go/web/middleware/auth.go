@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"phenix/util/plog"
 	"phenix/web/rbac"
+	"phenix/web/share"
 	jwtutil "phenix/web/util/jwt"
 	"strings"
 
@@ -28,6 +29,52 @@ func fromPhenixAuthTokenHeader(r *http.Request) (string, error) {
 	return authHeaderParts[1], nil
 }
 
+func fromShareToken(r *http.Request) string {
+	if token := r.Header.Get(share.Header); token != "" {
+		return token
+	}
+
+	if token := r.URL.Query().Get("share"); token != "" {
+		return token
+	}
+
+	// Browser links (ie. for VNC) include tokens in the `token` parameter, which
+	// is otherwise used for JWTs.
+	if token := r.URL.Query().Get("token"); strings.HasPrefix(token, share.TokenPrefix) {
+		return token
+	}
+
+	return ""
+}
+
+// ShareAuth authenticates requests that include a share link token using the
+// role granted by the share link, bypassing user authentication. Requests
+// without a share link token are passed to the given handler as is.
+func ShareAuth(next, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := fromShareToken(r)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		link, err := share.Authenticate(token, r.RemoteAddr)
+		if err != nil {
+			plog.Error("rejecting share link request", "path", r.URL.Path, "err", err)
+			http.Error(w, "invalid share link", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := r.Context()
+
+		ctx = context.WithValue(ctx, "user", link.User())
+		ctx = context.WithValue(ctx, "role", link.Role())
+		ctx = context.WithValue(ctx, "jwt", token)
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func NoAuth(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		role, _ := rbac.RoleFromConfig("global-admin")
@@ -187,12 +234,13 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 		return func(h http.Handler) http.Handler { return NoAuth(h) }
 	} else if jwtKey == "proxy-jwt" {
 		plog.Info("using JWTs from proxy")
-		return func(h http.Handler) http.Handler { return validTokenMiddleware(userMiddleware(h)) }
+		return func(h http.Handler) http.Handler { return ShareAuth(validTokenMiddleware(userMiddleware(h)), h) }
 	} else if strings.HasPrefix(jwtKey, "dev|") {
 		plog.Debug("development JWT key provided -- enabling dev auth")
 		return func(h http.Handler) http.Handler { return devAuthMiddleware(h) }
 	}
 
 	// First validate the token itself, then ensure the user in the token is valid.
-	return func(h http.Handler) http.Handler { return tokenMiddleware.Handler(userMiddleware(h)) }
+	// Requests using share links skip both.
+	return func(h http.Handler) http.Handler { return ShareAuth(tokenMiddleware.Handler(userMiddleware(h)), h) }
 }
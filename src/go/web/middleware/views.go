@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"phenix/util/plog"
+	"phenix/web/rbac"
+)
+
+// Views limits the role set by the auth middleware to the VMs in the views
+// bound to it, so it must be used after the auth middleware.
+func Views(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, ok := r.Context().Value("role").(rbac.Role)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		role, err := role.WithViews()
+		if err != nil {
+			plog.Error("getting views for role", "err", err)
+		}
+
+		ctx := context.WithValue(r.Context(), "role", role)
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
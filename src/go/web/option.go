@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"phenix/api/retention"
+	"phenix/api/smoke"
+	"phenix/api/webhook"
 	"phenix/util/common"
 	"phenix/util/plog"
 	"phenix/web/rbac"
@@ -37,6 +40,30 @@ type serverOptions struct {
 	features map[string]bool
 
 	unixSocketGid int
+
+	statusWebhook []webhook.Option
+
+	approvalWindow     time.Duration
+	approvalOperations []string
+
+	smokeTests []smoke.Test
+
+	retention *retention.Policies
+
+	callbackEndpoint string
+
+	guestIPDiscovery time.Duration
+
+	scheduledStarts time.Duration
+	imagePreload    time.Duration
+
+	metrics bool
+
+	logLevel         string
+	defaultApps      []string
+	defaultScheduler string
+
+	reloader func() ([]ServerOption, error)
 }
 
 func newServerOptions(opts ...ServerOption) serverOptions {
@@ -129,6 +156,73 @@ func ServeMinimegaLogs(m string) ServerOption {
 	}
 }
 
+func ServeWithStatusWebhook(opts ...webhook.Option) ServerOption {
+	return func(o *serverOptions) {
+		o.statusWebhook = opts
+	}
+}
+
+// ServeWithApprovals requires a second user to approve the given destructive
+// operations (all operations if none are given) within the given window.
+func ServeWithApprovals(window time.Duration, ops ...string) ServerOption {
+	return func(o *serverOptions) {
+		o.approvalWindow = window
+		o.approvalOperations = ops
+	}
+}
+
+// ServeWithSmokeTests runs the given smoke tests nightly at their configured
+// time of day while the server is running.
+func ServeWithSmokeTests(t []smoke.Test) ServerOption {
+	return func(o *serverOptions) {
+		o.smokeTests = t
+	}
+}
+
+// ServeWithRetention applies the given experiment data retention policies
+// periodically while the server is running.
+func ServeWithRetention(p retention.Policies) ServerOption {
+	return func(o *serverOptions) {
+		o.retention = &p
+	}
+}
+
+// ServeWithCallbackEndpoint serves the guest callback API on the given
+// endpoint, which should be reachable from guests via the management network.
+func ServeWithCallbackEndpoint(e string) ServerOption {
+	return func(o *serverOptions) {
+		o.callbackEndpoint = e
+	}
+}
+
+// ServeWithGuestIPDiscovery discovers the guest IPs of VMs in running
+// experiments at the given interval while the server is running.
+func ServeWithGuestIPDiscovery(i time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.guestIPDiscovery = i
+	}
+}
+
+// ServeWithScheduledStarts starts experiments with a scheduled start when it's
+// due, checking at the given interval while the server is running. If preload
+// is greater than zero, experiment images are preloaded onto cluster hosts that
+// long before each scheduled start.
+func ServeWithScheduledStarts(interval, preload time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.scheduledStarts = interval
+		o.imagePreload = preload
+	}
+}
+
+// ServeWithMetrics serves app and experiment lifecycle metrics in the
+// Prometheus text format at /metrics. The endpoint isn't authenticated, so
+// Prometheus can scrape it without a phēnix token.
+func ServeWithMetrics(m bool) ServerOption {
+	return func(o *serverOptions) {
+		o.metrics = m
+	}
+}
+
 func ServeUnbundled() ServerOption {
 	return func(o *serverOptions) {
 		o.unbundled = true
@@ -181,6 +275,37 @@ func ServeWithUnixSocketGid(g int) ServerOption {
 	}
 }
 
+// ServeWithLogLevel sets the level phenix logs messages at.
+func ServeWithLogLevel(l string) ServerOption {
+	return func(o *serverOptions) {
+		o.logLevel = l
+	}
+}
+
+// ServeWithDefaultApps sets the apps applied to every experiment (all the
+// built-in default apps if none are given).
+func ServeWithDefaultApps(a []string) ServerOption {
+	return func(o *serverOptions) {
+		o.defaultApps = a
+	}
+}
+
+// ServeWithDefaultScheduler sets the scheduler used when scheduling an
+// experiment without specifying a scheduler.
+func ServeWithDefaultScheduler(s string) ServerOption {
+	return func(o *serverOptions) {
+		o.defaultScheduler = s
+	}
+}
+
+// ServeWithReloader sets the function used to get updated server options when
+// the server configuration is reloaded (see Reload).
+func ServeWithReloader(f func() ([]ServerOption, error)) ServerOption {
+	return func(o *serverOptions) {
+		o.reloader = f
+	}
+}
+
 // GET /options
 func GetOptions(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "GetOptions")
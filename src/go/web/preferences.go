@@ -0,0 +1,182 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	v1 "phenix/types/version/v1"
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /users/{username}/preferences
+func GetUserPreferences(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetUserPreferences")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		username = mux.Vars(r)["username"]
+	)
+
+	if !role.Allowed("users", "get", username) {
+		err := weberror.NewWebError(nil, "getting preferences for user %s not allowed for %s", username, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	user, err := rbac.GetUser(username)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get user %s", username)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	body, _ := json.Marshal(user.Preferences())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /users/{username}/preferences
+func UpdateUserPreferences(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateUserPreferences")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		username = mux.Vars(r)["username"]
+	)
+
+	if !role.Allowed("users", "patch", username) {
+		err := weberror.NewWebError(nil, "updating preferences for user %s not allowed for %s", username, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	var prefs v1.UserPreferences
+
+	if err := json.Unmarshal(body, &prefs); err != nil {
+		return weberror.NewWebError(err, "unable to parse request body")
+	}
+
+	user, err := rbac.GetUser(username)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get user %s", username)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	if err := user.UpdatePreferences(prefs); err != nil {
+		return weberror.NewWebError(err, "unable to update preferences for user %s", username)
+	}
+
+	return writePreferences(w, user)
+}
+
+// PUT /users/{username}/preferences/views/{table}/{view}
+func SaveUserView(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SaveUserView")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		username = vars["username"]
+	)
+
+	if !role.Allowed("users", "patch", username) {
+		err := weberror.NewWebError(nil, "saving views for user %s not allowed for %s", username, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	var view v1.SavedView
+
+	if err := json.Unmarshal(body, &view); err != nil {
+		return weberror.NewWebError(err, "unable to parse request body")
+	}
+
+	// The table and name in the path always win.
+	view.Table = vars["table"]
+	view.Name = vars["view"]
+
+	user, err := rbac.GetUser(username)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get user %s", username)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	if err := user.SaveView(view); err != nil {
+		return weberror.NewWebError(err, "unable to save view %s for user %s", view.Name, username)
+	}
+
+	return writePreferences(w, user)
+}
+
+// DELETE /users/{username}/preferences/views/{table}/{view}
+func DeleteUserView(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteUserView")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		username = vars["username"]
+	)
+
+	if !role.Allowed("users", "patch", username) {
+		err := weberror.NewWebError(nil, "deleting views for user %s not allowed for %s", username, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	user, err := rbac.GetUser(username)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get user %s", username)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	if err := user.DeleteView(vars["table"], vars["view"]); err != nil {
+		if errors.Is(err, rbac.ErrViewNotFound) {
+			err := weberror.NewWebError(err, "view %s not found for user %s", vars["view"], username)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to delete view %s for user %s", vars["view"], username)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	return writePreferences(w, user)
+}
+
+// writePreferences writes the user's preferences to the response and notifies
+// the user's other sessions they changed.
+func writePreferences(w http.ResponseWriter, user *rbac.User) error {
+	body, _ := json.Marshal(user.Preferences())
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("users", "get", user.Username()),
+		bt.NewResource("user/preferences", user.Username(), "update"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
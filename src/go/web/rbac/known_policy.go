@@ -11,6 +11,7 @@ type Permission struct {
 
 var Permissions = []Permission{
 	{"applications", "list"},
+	{"audit", "list"},
 	{"configs", "create"},
 	{"configs", "delete"},
 	{"configs", "get"},
@@ -28,24 +29,32 @@ var Permissions = []Permission{
 	{"experiments/captures", "list"},
 	{"experiments/files", "get"},
 	{"experiments/files", "list"},
+	{"experiments/journal", "create"},
+	{"experiments/journal", "get"},
 	{"experiments/netflow", "create"},
 	{"experiments/netflow", "delete"},
 	{"experiments/netflow", "get"},
 	{"experiments/schedule", "create"},
 	{"experiments/schedule", "get"},
+	{"experiments/shares", "create"},
+	{"experiments/shares", "delete"},
+	{"experiments/shares", "list"},
 	{"experiments/start", "update"},
 	{"experiments/stop", "update"},
 	{"experiments/topology", "get"},
 	{"experiments/trigger", "create"},
 	{"experiments/trigger", "delete"},
+	{"experiments/vlans", "get"},
 	{"history", "get"},
 	{"hosts", "list"},
 	{"miniconsole", "get"},
 	{"miniconsole", "post"},
 	{"options", "list"},
+	{"options", "update"},
 	{"roles", "list"},
 	{"scenarios", "list"},
 	{"schemas", "get"},
+	{"smoke-tests", "list"},
 	{"topologies", "list"},
 	{"users", "create"},
 	{"users", "delete"},
@@ -53,20 +62,29 @@ var Permissions = []Permission{
 	{"users", "list"},
 	{"users", "patch"},
 	{"users/roles", "patch"},
+	{"views", "get"},
+	{"views", "list"},
 	{"vms", "delete"},
 	{"vms", "get"},
 	{"vms", "list"},
 	{"vms", "patch"},
+	{"vms/broadcast", "create"},
 	{"vms/captures", "create"},
 	{"vms/captures", "delete"},
 	{"vms/captures", "list"},
 	{"vms/cdrom", "delete"},
 	{"vms/cdrom", "update"},
 	{"vms/commit", "create"},
+	{"vms/diagnostics", "create"},
+	{"vms/diagnostics", "get"},
+	{"vms/diagnostics", "list"},
+	{"vms/drift", "create"},
+	{"vms/drift", "get"},
 	{"vms/forwards", "create"},
 	{"vms/forwards", "delete"},
 	{"vms/forwards", "get"},
 	{"vms/forwards", "list"},
+	{"vms/health", "list"},
 	{"vms/memorySnapshot", "create"},
 	{"vms/mount", "delete"},
 	{"vms/mount", "get"},
@@ -79,6 +97,7 @@ var Permissions = []Permission{
 	{"vms/screenshot", "get"},
 	{"vms/shutdown", "update"},
 	{"vms/snapshots", "create"},
+	{"vms/snapshots", "delete"},
 	{"vms/snapshots", "list"},
 	{"vms/snapshots", "update"},
 	{"vms/start", "update"},
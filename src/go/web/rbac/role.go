@@ -25,6 +25,10 @@ type Role struct {
 
 	config         *store.Config
 	mappedPolicies map[string][]Policy
+
+	// set via `WithViews`
+	views    []View
+	viewsErr error
 }
 
 func GetRoles() ([]*Role, error) {
@@ -148,7 +152,7 @@ func (this Role) Allowed(resource, verb string, names ...string) bool {
 			}
 
 			for _, n := range names {
-				if policy.resourceNameAllowed(n) {
+				if policy.resourceNameAllowed(n) && this.inView(resource, n) {
 					return true
 				}
 			}
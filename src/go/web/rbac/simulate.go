@@ -0,0 +1,150 @@
+package rbac
+
+import (
+	"fmt"
+
+	"phenix/api/config"
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+	jwtutil "phenix/web/util/jwt"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Check is a verb on a resource (and optionally a resource name) to check
+// against a role.
+type Check struct {
+	Resource string `json:"resource" yaml:"resource"`
+	Verb     string `json:"verb" yaml:"verb"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+func (this Check) String() string {
+	if this.Name == "" {
+		return fmt.Sprintf("%s %s", this.Verb, this.Resource)
+	}
+
+	return fmt.Sprintf("%s %s/%s", this.Verb, this.Resource, this.Name)
+}
+
+// Expectation is a check expected to be allowed (or not) by a role.
+type Expectation struct {
+	Check   `yaml:",inline"`
+	Allowed bool `json:"allowed" yaml:"allowed"`
+}
+
+// ExpectationResult is the result of testing an expectation against a role.
+type ExpectationResult struct {
+	Expectation `yaml:",inline"`
+	Actual      bool `json:"actual" yaml:"actual"`
+	Passed      bool `json:"passed" yaml:"passed"`
+}
+
+// CanI returns true if the role allows the given check.
+func (this Role) CanI(check Check) bool {
+	if check.Name == "" {
+		return this.Allowed(check.Resource, check.Verb)
+	}
+
+	return this.Allowed(check.Resource, check.Verb, check.Name)
+}
+
+// Test checks each of the given expectations against the role, returning the
+// result of each and whether all of them passed. It's meant to be used to
+// validate a proposed role before it's applied.
+func (this Role) Test(expectations ...Expectation) ([]ExpectationResult, bool) {
+	var (
+		results = make([]ExpectationResult, len(expectations))
+		passed  = true
+	)
+
+	for i, e := range expectations {
+		actual := this.CanI(e.Check)
+
+		results[i] = ExpectationResult{Expectation: e, Actual: actual, Passed: actual == e.Allowed}
+
+		if !results[i].Passed {
+			passed = false
+		}
+	}
+
+	return results, passed
+}
+
+// NewRole returns a role for the given role spec that isn't (yet) persisted to
+// the store, ie. to test a proposed role.
+func NewRole(spec *v1.RoleSpec) *Role {
+	return &Role{Spec: spec}
+}
+
+// RoleFromFile returns the role defined in the role config at the given path
+// without persisting it to the store.
+func RoleFromFile(path string) (*Role, error) {
+	c, err := store.NewConfigFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading role config: %w", err)
+	}
+
+	if c.Kind != "Role" {
+		return nil, fmt.Errorf("config kind %s is not Role", c.Kind)
+	}
+
+	var spec v1.RoleSpec
+
+	if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("decoding role: %w", err)
+	}
+
+	return &Role{Spec: &spec, config: c}, nil
+}
+
+// Apply persists the role, creating its config in the store if it doesn't
+// exist yet. It's only supported for roles read via RoleFromFile.
+func (this Role) Apply() error {
+	if this.config == nil {
+		return fmt.Errorf("role has no config to apply")
+	}
+
+	existing, _ := store.NewConfig("role/" + this.config.Metadata.Name)
+
+	if err := store.Get(existing); err != nil {
+		if _, err := config.Create(config.CreateFromConfig(this.config), config.CreateWithValidation()); err != nil {
+			return fmt.Errorf("creating role: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := config.Update(existing.FullName(), this.config); err != nil {
+		return fmt.Errorf("updating role: %w", err)
+	}
+
+	return nil
+}
+
+// UserFromToken returns the user the given API token belongs to. An error is
+// returned if the token was never issued to the user or has since been
+// deleted.
+func UserFromToken(token string) (*User, error) {
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("parsing token: %w", err)
+	}
+
+	uname, err := jwtutil.UsernameFromClaims(parsed.Claims.(jwt.MapClaims))
+	if err != nil {
+		return nil, fmt.Errorf("getting user from token: %w", err)
+	}
+
+	user, err := GetUser(uname)
+	if err != nil {
+		return nil, fmt.Errorf("getting user %s: %w", uname, err)
+	}
+
+	if err := user.ValidateToken(token); err != nil {
+		return nil, fmt.Errorf("validating token for user %s: %w", uname, err)
+	}
+
+	return user, nil
+}
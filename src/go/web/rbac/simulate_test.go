@@ -0,0 +1,37 @@
+package rbac
+
+import (
+	"testing"
+)
+
+func TestRoleTest(t *testing.T) {
+	setup()
+
+	expectations := []Expectation{
+		{Check: Check{Resource: "experiments", Verb: "get"}, Allowed: true},
+		{Check: Check{Resource: "experiments", Verb: "delete", Name: "exp1"}, Allowed: true},
+		{Check: Check{Resource: "experiments", Verb: "delete", Name: "exp2"}, Allowed: false},
+		{Check: Check{Resource: "things", Verb: "get", Name: "thing1"}, Allowed: false},
+	}
+
+	results, passed := role.Test(expectations...)
+
+	if !passed {
+		t.Logf("expected all expectations to pass, got %+v", results)
+		t.FailNow()
+	}
+
+	expectations = append(expectations, Expectation{Check: Check{Resource: "vms", Verb: "create"}, Allowed: true})
+
+	results, passed = role.Test(expectations...)
+
+	if passed {
+		t.Log("expected failed expectation to fail the test")
+		t.FailNow()
+	}
+
+	if last := results[len(results)-1]; last.Passed || last.Actual {
+		t.Logf("expected create vms to be denied and fail, got %+v", last)
+		t.FailNow()
+	}
+}
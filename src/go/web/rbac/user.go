@@ -43,7 +43,11 @@ spec:
 			- get
 */
 
-var ErrPasswordInvalid = fmt.Errorf("password invalid")
+var (
+	ErrPasswordInvalid     = fmt.Errorf("password invalid")
+	ErrInvalidOutputFormat = fmt.Errorf("invalid output format")
+	ErrViewNotFound        = fmt.Errorf("saved view not found")
+)
 
 type User struct {
 	Spec *v1.UserSpec
@@ -251,6 +255,89 @@ func (this User) ValidatePassword(p string) error {
 	return nil
 }
 
+// Preferences returns the user's preferences, which are empty if the user
+// hasn't set any.
+func (this User) Preferences() v1.UserPreferences {
+	if this.Spec.Preferences == nil {
+		return v1.UserPreferences{}
+	}
+
+	return *this.Spec.Preferences
+}
+
+// UpdatePreferences replaces the user's preferences.
+func (this User) UpdatePreferences(prefs v1.UserPreferences) error {
+	switch prefs.OutputFormat {
+	case "", "yaml", "json":
+	default:
+		return fmt.Errorf("%w: %s (expected 'yaml' or 'json')", ErrInvalidOutputFormat, prefs.OutputFormat)
+	}
+
+	seen := make(map[string]struct{})
+
+	for _, v := range prefs.Views {
+		if v.Name == "" || v.Table == "" {
+			return fmt.Errorf("saved views must have a name and table")
+		}
+
+		if _, ok := seen[v.Table+"/"+v.Name]; ok {
+			return fmt.Errorf("duplicate saved view %s for table %s", v.Name, v.Table)
+		}
+
+		seen[v.Table+"/"+v.Name] = struct{}{}
+	}
+
+	this.Spec.Preferences = &prefs
+	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
+
+	if err := this.Save(); err != nil {
+		return fmt.Errorf("updating user preferences: %w", err)
+	}
+
+	return nil
+}
+
+// SaveView adds the given saved view to the user's preferences, replacing any
+// existing saved view with the same name for the same table.
+func (this User) SaveView(view v1.SavedView) error {
+	prefs := this.Preferences()
+
+	var views []v1.SavedView
+
+	for _, v := range prefs.Views {
+		if v.Table != view.Table || v.Name != view.Name {
+			views = append(views, v)
+		}
+	}
+
+	prefs.Views = append(views, view)
+
+	return this.UpdatePreferences(prefs)
+}
+
+// DeleteView removes the named saved view for the given table from the user's
+// preferences.
+func (this User) DeleteView(table, name string) error {
+	var (
+		prefs = this.Preferences()
+		views []v1.SavedView
+	)
+
+	for _, v := range prefs.Views {
+		if v.Table != table || v.Name != name {
+			views = append(views, v)
+		}
+	}
+
+	if len(views) == len(prefs.Views) {
+		return ErrViewNotFound
+	}
+
+	prefs.Views = views
+
+	return this.UpdatePreferences(prefs)
+}
+
 func (this User) Save() error {
 	if err := store.Update(this.config); err != nil {
 		return fmt.Errorf("updating user in store: %w", err)
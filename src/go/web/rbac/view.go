@@ -0,0 +1,284 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+/*
+version: v1
+kind: View
+metadata:
+	name: blue-team
+spec:
+	experiments:
+	- exercise-*
+	team: blue
+	labels:
+		zone: dmz
+	roles:
+	- blue-team
+*/
+
+// VIEW_CACHE_TTL is how long views, and the node labels of experiments they
+// scope, are cached for when checking VM permissions, since VM permissions are
+// checked for every VM when listing an experiment's VMs.
+var VIEW_CACHE_TTL = 5 * time.Second
+
+var ErrViewNotExist = errors.New("view does not exist")
+
+type View struct {
+	Metadata store.ConfigMetadata `json:"metadata"`
+	Spec     *v1.ViewSpec         `json:"spec"`
+
+	// role names (as opposed to role config names) the view is bound to
+	roles map[string]bool
+}
+
+// GetViews returns all the views in the store.
+func GetViews() ([]View, error) {
+	configs, err := config.List("view")
+	if err != nil {
+		return nil, fmt.Errorf("getting view configs: %w", err)
+	}
+
+	roles, err := config.List("role")
+	if err != nil {
+		return nil, fmt.Errorf("getting role configs: %w", err)
+	}
+
+	// Views can be bound to either role names (ie. "Blue Team") or the names of
+	// role configs (ie. "blue-team"), but only the role name is available when
+	// checking a user's permissions.
+	names := make(map[string]string)
+
+	for _, r := range roles {
+		if name, ok := r.Spec["roleName"].(string); ok {
+			names[r.Metadata.Name] = name
+		}
+	}
+
+	views := make([]View, len(configs))
+
+	for i, c := range configs {
+		var spec v1.ViewSpec
+
+		if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("decoding view %s: %w", c.Metadata.Name, err)
+		}
+
+		view := View{Metadata: c.Metadata, Spec: &spec, roles: make(map[string]bool)}
+
+		for _, r := range spec.Roles {
+			view.roles[r] = true
+
+			if name, ok := names[r]; ok {
+				view.roles[name] = true
+			}
+		}
+
+		views[i] = view
+	}
+
+	return views, nil
+}
+
+// GetView returns the view with the given name.
+func GetView(name string) (*View, error) {
+	views, err := GetViews()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range views {
+		if v.Metadata.Name == name {
+			return &v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrViewNotExist, name)
+}
+
+// Bound returns true if the view is bound to the given role.
+func (this View) Bound(role Role) bool {
+	if role.Spec == nil {
+		return false
+	}
+
+	return this.roles[role.Spec.Name]
+}
+
+// VMs returns the names of the VMs in the given experiment that are part of the
+// view. No VMs are returned if the experiment isn't scoped by the view.
+func (this View) VMs(exp string) ([]string, error) {
+	if !this.Spec.Includes(exp) {
+		return nil, nil
+	}
+
+	nodes, err := nodeLabels(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	var vms []string
+
+	for vm, labels := range nodes {
+		if this.Spec.Selects(labels) {
+			vms = append(vms, vm)
+		}
+	}
+
+	return vms, nil
+}
+
+// WithViews returns a copy of the role that limits access to the VMs in
+// experiments scoped by the views bound to the role. If the views can't be
+// loaded, access to all VMs is denied rather than risk exposing VMs outside
+// the role's views (the error is also returned).
+func (this Role) WithViews() (Role, error) {
+	views, err := cachedViews()
+	if err != nil {
+		this.viewsErr = err
+		return this, err
+	}
+
+	this.views = nil
+
+	for _, v := range views {
+		if v.Bound(this) {
+			this.views = append(this.views, v)
+		}
+	}
+
+	return this, nil
+}
+
+// Views returns the views bound to the role, if it was created via `WithViews`.
+func (this Role) Views() []View {
+	return this.views
+}
+
+// inView returns true if the resource with the given name isn't a VM resource,
+// or if the VM is allowed by the views bound to the role.
+func (this Role) inView(resource, name string) bool {
+	if resource != "vms" && !strings.HasPrefix(resource, "vms/") {
+		return true
+	}
+
+	if this.viewsErr != nil {
+		return false
+	}
+
+	if len(this.views) == 0 {
+		return true
+	}
+
+	return this.viewAllowed(this.views, name)
+}
+
+// viewAllowed returns true if the VM with the given name (in the form of
+// `<experiment>/<vm>`) is part of a view bound to the role, or if none of the
+// views bound to the role scope the VM's experiment. VM names containing glob
+// patterns are only allowed if their experiment isn't scoped.
+func (this Role) viewAllowed(views []View, name string) bool {
+	exp, vm, ok := strings.Cut(name, "/")
+	if !ok {
+		return true
+	}
+
+	var scoped bool
+
+	for _, v := range views {
+		if !v.Spec.Includes(exp) {
+			continue
+		}
+
+		scoped = true
+
+		nodes, err := nodeLabels(exp)
+		if err != nil {
+			return false
+		}
+
+		if labels, ok := nodes[vm]; ok && v.Spec.Selects(labels) {
+			return true
+		}
+	}
+
+	return !scoped
+}
+
+var viewCache = struct {
+	sync.Mutex
+
+	views   []View
+	viewsTS time.Time
+
+	nodes   map[string]map[string]map[string]string
+	nodesTS map[string]time.Time
+}{
+	nodes:   make(map[string]map[string]map[string]string),
+	nodesTS: make(map[string]time.Time),
+}
+
+func cachedViews() ([]View, error) {
+	viewCache.Lock()
+	defer viewCache.Unlock()
+
+	if time.Since(viewCache.viewsTS) < VIEW_CACHE_TTL {
+		return viewCache.views, nil
+	}
+
+	views, err := GetViews()
+	if err != nil {
+		return nil, err
+	}
+
+	viewCache.views = views
+	viewCache.viewsTS = time.Now()
+
+	return views, nil
+}
+
+// nodeLabels returns the labels of each node in the given experiment's
+// topology, keyed by hostname.
+func nodeLabels(exp string) (map[string]map[string]string, error) {
+	viewCache.Lock()
+	defer viewCache.Unlock()
+
+	if time.Since(viewCache.nodesTS[exp]) < VIEW_CACHE_TTL {
+		return viewCache.nodes[exp], nil
+	}
+
+	c, _ := store.NewConfig("experiment/" + exp)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", exp, err)
+	}
+
+	e, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment %s: %w", exp, err)
+	}
+
+	nodes := make(map[string]map[string]string)
+
+	for _, node := range e.Spec.Topology().Nodes() {
+		nodes[node.General().Hostname()] = node.Labels()
+	}
+
+	viewCache.nodes[exp] = nodes
+	viewCache.nodesTS[exp] = time.Now()
+
+	return nodes, nil
+}
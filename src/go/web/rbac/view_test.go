@@ -0,0 +1,59 @@
+package rbac
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestViewAllowed(t *testing.T) {
+	var spec v1.RoleSpec
+	json.Unmarshal([]byte(`{"roleName": "Blue Team", "policies": [{"resources": ["vms", "vms/*"], "resourceNames": ["*", "*/*"], "verbs": ["*"]}]}`), &spec)
+
+	viewCache.Lock()
+	viewCache.nodes["exercise"] = map[string]map[string]string{
+		"blue-web": {"team": "blue", "zone": "dmz"},
+		"blue-db":  {"team": "blue"},
+		"red-web":  {"team": "red", "zone": "dmz"},
+	}
+	viewCache.nodesTS["exercise"] = time.Now().Add(time.Hour)
+	viewCache.Unlock()
+
+	view := View{
+		Spec:  &v1.ViewSpec{Experiments: []string{"exer*"}, Team: "blue"},
+		roles: map[string]bool{"Blue Team": true},
+	}
+
+	role := Role{Spec: &spec}
+
+	if !view.Bound(role) {
+		t.Log("expected view to be bound to role")
+		t.FailNow()
+	}
+
+	role.views = []View{view}
+
+	cases := map[string]bool{
+		"exercise/blue-web": true,
+		"exercise/blue-db":  true,
+		"exercise/red-web":  false,
+		"exercise/*":        false,
+		"other/red-web":     true,
+	}
+
+	for name, expected := range cases {
+		if allowed := role.Allowed("vms/start", "update", name); allowed != expected {
+			t.Logf("expected %s to be allowed: %v, got %v", name, expected, allowed)
+			t.FailNow()
+		}
+	}
+
+	view.Spec.Labels = map[string]string{"zone": "dmz"}
+
+	if role.Allowed("vms", "list", "exercise/blue-db") {
+		t.Log("expected VM without view labels to not be allowed")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"phenix/api/readme"
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/readme[?render=true]
+func GetExperimentReadme(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentReadme")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/readme", "get", name) {
+		err := weberror.NewWebError(nil, "getting README for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var (
+		md  string
+		err error
+	)
+
+	if render, _ := strconv.ParseBool(r.URL.Query().Get("render")); render {
+		md, err = readme.Render(name, readme.RenderWithBaseURL(readmeBaseURL(r)))
+	} else {
+		md, err = readme.Get(name)
+	}
+
+	if err != nil {
+		return readmeError(err, name)
+	}
+
+	body, err := json.Marshal(util.WithRoot("markdown", md))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process README for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /experiments/{name}/readme
+func UpdateExperimentReadme(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateExperimentReadme")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/readme", "update", name) {
+		err := weberror.NewWebError(nil, "updating README for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Markdown string `json:"markdown"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid README request provided")
+	}
+
+	if err := readme.Set(name, req.Markdown); err != nil {
+		return readmeError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// readmeBaseURL returns the base URL of the phenix UI as seen by the client
+// making the request so console links in rendered READMEs work when handed out.
+func readmeBaseURL(r *http.Request) string {
+	scheme := "http"
+
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	return scheme + "://" + r.Host + strings.TrimSuffix(options().basePath, "/")
+}
+
+func readmeError(err error, name string) error {
+	switch {
+	case errors.Is(err, readme.ErrNoReadme):
+		err := weberror.NewWebError(err, "no README for experiment %s", name)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, store.ErrNotExist):
+		err := weberror.NewWebError(err, "experiment %s does not exist", name)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	return weberror.NewWebError(err, "unable to process README for experiment %s", name).SetStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,268 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"phenix/api/webhook"
+	"phenix/app"
+	"phenix/scheduler"
+	"phenix/util/audit"
+	"phenix/util/plog"
+	"phenix/web/middleware"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/exp/slices"
+)
+
+var ErrReloadNotSupported = errors.New("server configuration reload not supported")
+
+var (
+	// Guards server options that can change when the server configuration is
+	// reloaded. Handlers should use options() to access them.
+	optionsMu sync.RWMutex
+
+	// Serializes reloads of the server configuration.
+	reloadMu sync.Mutex
+
+	authMiddleware struct {
+		sync.RWMutex
+		mw mux.MiddlewareFunc
+	}
+
+	statusWebhookCancel context.CancelFunc
+)
+
+// options returns a copy of the current server options.
+func options() serverOptions {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+
+	return o
+}
+
+// Reload gets updated server options from the reloader provided when the
+// server was started and applies the settings that can be changed without
+// restarting the server: log level, authentication settings (JWT signing key
+// and lifetime, proxy auth header, and users), the status webhook, default
+// apps, and the default scheduler. It returns the names of the settings that
+// changed, and the names of changed settings that only take effect once the
+// server is restarted. The reload is recorded in the audit log as done by the
+// given user.
+func Reload(user string) ([]string, []string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	current := options()
+
+	if current.reloader == nil {
+		return nil, nil, ErrReloadNotSupported
+	}
+
+	opts, err := current.reloader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting updated server options: %w", err)
+	}
+
+	updated := newServerOptions(opts...)
+	updated.reloader = current.reloader
+
+	if err := applySettings(updated); err != nil {
+		return nil, nil, err
+	}
+
+	var changed, restart []string
+
+	check := func(name string, a, b any, list *[]string) {
+		if !reflect.DeepEqual(a, b) {
+			*list = append(*list, name)
+		}
+	}
+
+	check("log-level", current.logLevel, updated.logLevel, &changed)
+	check("default-apps", current.defaultApps, updated.defaultApps, &changed)
+	check("default-scheduler", current.defaultScheduler, updated.defaultScheduler, &changed)
+	check("jwt-signing-key", current.jwtKey, updated.jwtKey, &changed)
+	check("jwt-lifetime", current.jwtLifetime, updated.jwtLifetime, &changed)
+	check("proxy-auth-header", current.proxyAuthHeader, updated.proxyAuthHeader, &changed)
+	check("users", current.users, updated.users, &changed)
+
+	if !webhook.Equal(current.statusWebhook, updated.statusWebhook) {
+		changed = append(changed, "status-webhook")
+	}
+
+	check("listen-endpoint", current.endpoint, updated.endpoint, &restart)
+	check("base-path", current.basePath, updated.basePath, &restart)
+	check("tls", [2]string{current.tlsKeyPath, current.tlsCrtPath}, [2]string{updated.tlsKeyPath, updated.tlsCrtPath}, &restart)
+	check("features", current.features, updated.features, &restart)
+	check("minimega-console", current.minimegaConsole, updated.minimegaConsole, &restart)
+	check("logs.minimega-path", current.minimegaLogs, updated.minimegaLogs, &restart)
+	check("approvals", [2]any{current.approvalWindow, current.approvalOperations}, [2]any{updated.approvalWindow, updated.approvalOperations}, &restart)
+	check("callback-endpoint", current.callbackEndpoint, updated.callbackEndpoint, &restart)
+	check("smoke-tests", current.smokeTests, updated.smokeTests, &restart)
+	check("retention-policies", current.retention, updated.retention, &restart)
+
+	// Keep settings that require a restart as they are so the running server
+	// stays consistent with its current options.
+	next := current
+
+	next.logLevel = updated.logLevel
+	next.defaultApps = updated.defaultApps
+	next.defaultScheduler = updated.defaultScheduler
+	next.jwtKey = updated.jwtKey
+	next.jwtLifetime = updated.jwtLifetime
+	next.proxyAuthHeader = updated.proxyAuthHeader
+	next.users = updated.users
+	next.statusWebhook = updated.statusWebhook
+
+	optionsMu.Lock()
+	o = next
+	optionsMu.Unlock()
+
+	setAuthMiddleware(next.jwtKey, next.proxyAuthHeader)
+
+	if slices.Contains(changed, "users") {
+		if err := ConfigureUsers(next.users); err != nil {
+			plog.Error("configuring users after reload", "err", err)
+		}
+	}
+
+	if slices.Contains(changed, "status-webhook") {
+		startStatusWebhook(next.statusWebhook)
+	}
+
+	if len(restart) > 0 {
+		plog.Warn("changed server settings require a restart to take effect", "settings", restart)
+	}
+
+	plog.Info("reloaded server configuration", "user", user, "changed", changed)
+
+	audit.Record(audit.Event{
+		User:     user,
+		Action:   "options/reload",
+		Resource: "options",
+		Details:  map[string]any{"changed": changed, "restartRequired": restart},
+	})
+
+	return changed, restart, nil
+}
+
+// applySettings applies the settings in the given server options that are
+// managed outside of the web package.
+func applySettings(opts serverOptions) error {
+	if err := app.SetDefaultApps(opts.defaultApps...); err != nil {
+		return fmt.Errorf("setting default apps: %w", err)
+	}
+
+	if opts.logLevel != "" {
+		plog.SetLevelText(opts.logLevel)
+	}
+
+	scheduler.SetDefault(opts.defaultScheduler)
+
+	return nil
+}
+
+func setAuthMiddleware(jwtKey, proxyAuthHeader string) {
+	authMiddleware.Lock()
+	defer authMiddleware.Unlock()
+
+	authMiddleware.mw = middleware.Auth(jwtKey, proxyAuthHeader)
+}
+
+func currentAuthMiddleware() mux.MiddlewareFunc {
+	authMiddleware.RLock()
+	defer authMiddleware.RUnlock()
+
+	return authMiddleware.mw
+}
+
+// startStatusWebhook (re)starts the status webhook with the given options,
+// stopping the webhook already running, if any.
+func startStatusWebhook(opts []webhook.Option) {
+	if statusWebhookCancel != nil {
+		statusWebhookCancel()
+		statusWebhookCancel = nil
+	}
+
+	if opts == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statusWebhookCancel = cancel
+
+	go webhook.Start(ctx, opts...)
+}
+
+// handleReloadSignal reloads the server configuration each time the process
+// receives SIGHUP.
+func handleReloadSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+
+	go func() {
+		for range sig {
+			plog.Info("received SIGHUP, reloading server configuration")
+
+			if _, _, err := Reload("SIGHUP"); err != nil {
+				plog.Error("reloading server configuration", "err", err)
+			}
+		}
+	}()
+}
+
+// POST /options/reload
+func ReloadOptions(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ReloadOptions")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+	)
+
+	if !role.Allowed("options", "update") {
+		err := weberror.NewWebError(nil, "reloading options not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	changed, restart, err := Reload(user)
+	if err != nil {
+		if errors.Is(err, ErrReloadNotSupported) {
+			return weberror.NewWebError(err, "reloading options not supported by this server").SetStatus(http.StatusNotImplemented)
+		}
+
+		err := weberror.NewWebError(err, "unable to reload options")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if changed == nil {
+		changed = []string{}
+	}
+
+	if restart == nil {
+		restart = []string{}
+	}
+
+	body, err := json.Marshal(map[string]any{"changed": changed, "restartRequired": restart})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process reloaded options")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
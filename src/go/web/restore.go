@@ -0,0 +1,298 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	bt "phenix/web/broker/brokertypes"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/vms/{name}/restore-points
+func GetVMRestorePoints(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetVMRestorePoints")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/snapshots", "list", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "listing restore points for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	points, err := vm.RestorePoints(exp, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get restore points for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	policy, err := vm.GetRestorePolicy(exp, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get restore point policy for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if points == nil {
+		points = []*vm.RestorePoint{}
+	}
+
+	body, err := json.Marshal(map[string]any{"restorePoints": points, "policy": policy})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process restore points for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{exp}/vms/{name}/restore-points
+func CreateVMRestorePoint(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateVMRestorePoint")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		exp      = vars["exp"]
+		name     = vars["name"]
+		fullName = exp + "/" + name
+	)
+
+	if !role.Allowed("vms/snapshots", "create", fullName) {
+		err := weberror.NewWebError(nil, "creating restore points for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "unable to parse request body")
+	}
+
+	if err := cache.LockVMForSnapshotting(exp, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s for snapshotting", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(exp, name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+		bt.NewResource("experiment/vm/snapshot", fullName, "creating"),
+		nil,
+	)
+
+	cb := func(s string) {
+		progress, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return
+		}
+
+		marshalled, _ := json.Marshal(map[string]any{"percent": progress / 100})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+			bt.NewResource("experiment/vm/snapshot", fullName, "progress"),
+			marshalled,
+		)
+	}
+
+	if err := vm.CreateRestorePoint(exp, name, req.Name, cb); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+			bt.NewResource("experiment/vm/snapshot", fullName, "errorCreating"),
+			nil,
+		)
+
+		if errors.Is(err, vm.ErrRestorePointExists) {
+			err := weberror.NewWebError(err, "restore point %s already exists for VM %s", req.Name, name)
+			return err.SetStatus(http.StatusConflict)
+		}
+
+		err := weberror.NewWebError(err, "unable to create restore point %s for VM %s", req.Name, name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+		bt.NewResource("experiment/vm/snapshot", fullName, "create"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// POST /experiments/{exp}/vms/{name}/restore-points/{point}/restore
+func RestoreVMToPoint(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RestoreVMToPoint")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		exp      = vars["exp"]
+		name     = vars["name"]
+		point    = vars["point"]
+		fullName = exp + "/" + name
+	)
+
+	if !role.Allowed("vms/snapshots", "update", fullName) {
+		err := weberror.NewWebError(nil, "restoring VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockVMForRestoring(exp, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s for restoring", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(exp, name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+		bt.NewResource("experiment/vm/snapshot", fullName, "restoring"),
+		nil,
+	)
+
+	if err := vm.RestoreToPoint(exp, name, point); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+			bt.NewResource("experiment/vm/snapshot", fullName, "errorRestoring"),
+			nil,
+		)
+
+		err := weberror.NewWebError(err, "unable to restore VM %s to %s", name, point)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/snapshots", "create", fullName),
+		bt.NewResource("experiment/vm/snapshot", fullName, "restore"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// DELETE /experiments/{exp}/vms/{name}/restore-points/{point}
+func DeleteVMRestorePoint(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteVMRestorePoint")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		exp      = vars["exp"]
+		name     = vars["name"]
+		point    = vars["point"]
+		fullName = exp + "/" + name
+	)
+
+	if !role.Allowed("vms/snapshots", "delete", fullName) {
+		err := weberror.NewWebError(nil, "deleting restore points for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := vm.DeleteRestorePoint(exp, name, point); err != nil {
+		if errors.Is(err, vm.ErrRestorePointNotFound) {
+			err := weberror.NewWebError(err, "restore point %s not found for VM %s", point, name)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to delete restore point %s for VM %s", point, name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/snapshots", "list", fullName),
+		bt.NewResource("experiment/vm/snapshot", fullName, "delete"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// PUT /experiments/{exp}/vms/{name}/restore-points/policy
+func UpdateVMRestorePolicy(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateVMRestorePolicy")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/snapshots", "delete", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "updating restore point policy for VM %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to read request body")
+	}
+
+	var req struct {
+		MaxPoints int    `json:"maxPoints"`
+		MaxAge    string `json:"maxAge"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "unable to parse request body")
+	}
+
+	policy := vm.RestorePolicy{MaxPoints: req.MaxPoints}
+
+	if req.MaxAge != "" {
+		if policy.MaxAge, err = time.ParseDuration(req.MaxAge); err != nil {
+			return weberror.NewWebError(err, "invalid max age %s", req.MaxAge)
+		}
+	}
+
+	if err := vm.SetRestorePolicy(exp, name, policy); err != nil {
+		err := weberror.NewWebError(err, "unable to update restore point policy for VM %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, _ = json.Marshal(util.WithRoot("policy", policy))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+type scheduledStart struct {
+	StartAt *time.Time          `json:"startAt,omitempty"`
+	Preload *experiment.Preload `json:"preload,omitempty"`
+}
+
+// GET /experiments/{name}/start-at
+func GetExperimentScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return scheduledStartError(err, name)
+	}
+
+	var resp scheduledStart
+
+	if at, ok := experiment.ScheduledStart(*exp); ok {
+		resp.StartAt = &at
+	}
+
+	if p, ok := experiment.PreloadStatus(name); ok {
+		resp.Preload = &p
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process scheduled start for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /experiments/{name}/start-at
+func ScheduleExperimentStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ScheduleExperimentStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/start", "update", name) {
+		err := weberror.NewWebError(nil, "scheduling start of experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req scheduledStart
+
+	if err := json.Unmarshal(body, &req); err != nil || req.StartAt == nil {
+		return weberror.NewWebError(err, "invalid scheduled start request provided").SetStatus(http.StatusBadRequest)
+	}
+
+	if err := experiment.ScheduleStart(name, *req.StartAt, user); err != nil {
+		return scheduledStartError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// DELETE /experiments/{name}/start-at
+func CancelExperimentScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CancelExperimentScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/start", "update", name) {
+		err := weberror.NewWebError(nil, "canceling scheduled start of experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := experiment.ScheduleStart(name, time.Time{}, user); err != nil {
+		return scheduledStartError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// POST /experiments/{name}/preload
+func PreloadExperimentImages(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "PreloadExperimentImages")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/start", "update", name) {
+		err := weberror.NewWebError(nil, "preloading images for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		return scheduledStartError(err, name)
+	}
+
+	// Preloading can take a while, so it's done in the background and its
+	// progress is available via GET /experiments/{name}/start-at.
+	go func() {
+		// We don't want to use the HTTP request's context here.
+		if _, err := experiment.PreloadImages(context.Background(), name); err != nil {
+			plog.Error("preloading experiment images", "exp", name, "err", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+// startScheduledExperiment starts the given experiment per its scheduled start.
+func startScheduledExperiment(name string) error {
+	_, err := startExperiment(name, experiment.StartWithUser("scheduled-start"))
+	return err
+}
+
+func scheduledStartError(err error, name string) error {
+	if errors.Is(err, store.ErrNotExist) {
+		err := weberror.NewWebError(err, "experiment %s does not exist", name)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	return weberror.NewWebError(err, "unable to schedule start of experiment %s", name).SetStatus(http.StatusBadRequest)
+}
@@ -0,0 +1,28 @@
+//go:build !noscorch
+
+package web
+
+import (
+	"phenix/util/subsystem"
+	"phenix/web/scorch"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+func init() {
+	optionalSubsystems[subsystem.SCORCH] = optionalSubsystem{routes: scorchRoutes, start: scorch.Start}
+}
+
+func scorchRoutes(api *mux.Router) {
+	api.Handle("/experiments/{name}/scorch/components/{run}/{loop}/{stage}/{cmp}", weberror.ErrorHandler(scorch.GetComponentOutput)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/scorch/components/{run}/{loop}/{stage}/{cmp}/ws", scorch.StreamComponentOutput).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/scorch/pipelines", weberror.ErrorHandler(scorch.GetPipelines)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/scorch/pipelines/{run}/{loop}", weberror.ErrorHandler(scorch.GetPipeline)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/scorch/pipelines/{run}", weberror.ErrorHandler(scorch.StartPipeline)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/scorch/pipelines/{run}", weberror.ErrorHandler(scorch.CancelPipeline)).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/scorch/terminals", scorch.GetTerminals).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}", scorch.ConnectTerminal).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/exit/{id}", scorch.ExitTerminal).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/ws/{id}", scorch.StreamTerminal).Methods("GET", "OPTIONS")
+}
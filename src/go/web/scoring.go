@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/scoring"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/scores
+func GetExperimentScores(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentScores")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/scores", "get", name) {
+		err := weberror.NewWebError(nil, "getting scores for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	board, err := scoring.Get(name)
+	if err != nil {
+		if errors.Is(err, scoring.ErrScoringNotConfigured) {
+			err := weberror.NewWebError(err, "scoring not configured for experiment %s", name)
+			return err.SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to get scores for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(board)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process scores for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/scores/flags
+func SubmitExperimentFlag(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SubmitExperimentFlag")
+
+	var (
+		ctx  = r.Context()
+		user = ctx.Value("user").(string)
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/flags", "create", name) {
+		err := weberror.NewWebError(nil, "submitting flags for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Team string `json:"team"`
+		Flag string `json:"flag"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid flag submission provided")
+	}
+
+	// The team the flag is credited to is always derived from the caller's team
+	// membership -- a team in the request is only used to double check it.
+	capture, err := scoring.SubmitFlag(name, user, req.Team, req.Flag)
+	if err != nil {
+		switch {
+		case errors.Is(err, experiment.ErrExperimentNotRunning):
+			err := weberror.NewWebError(err, "experiment %s is not running", name)
+			return err.SetStatus(http.StatusConflict)
+		case errors.Is(err, scoring.ErrScoringNotConfigured):
+			err := weberror.NewWebError(err, "scoring not configured for experiment %s", name)
+			return err.SetStatus(http.StatusNotFound)
+		case errors.Is(err, scoring.ErrNotTeamMember):
+			err := weberror.NewWebError(err, "user %s is not a member of the team submitting the flag", user)
+			return err.SetStatus(http.StatusForbidden)
+		case errors.Is(err, scoring.ErrUnknownTeam), errors.Is(err, scoring.ErrInvalidFlag):
+			err := weberror.NewWebError(err, "invalid team or flag submitted")
+			return err.SetStatus(http.StatusBadRequest)
+		case errors.Is(err, scoring.ErrFlagAlreadyCaptured):
+			err := weberror.NewWebError(err, "flag already captured by team")
+			return err.SetStatus(http.StatusConflict)
+		}
+
+		err := weberror.NewWebError(err, "unable to submit flag for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err = json.Marshal(capture)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process flag capture")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -2,18 +2,26 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 
+	"phenix/api/experiment"
+	"phenix/api/retention"
+	"phenix/api/smoke"
+	"phenix/app"
 	"phenix/util/common"
+	"phenix/util/metrics"
+	"phenix/util/mm"
 	"phenix/util/plog"
+	"phenix/util/subsystem"
+	"phenix/web/approval"
 	"phenix/web/broker"
 	"phenix/web/forward"
 	"phenix/web/middleware"
 	"phenix/web/rbac"
-	"phenix/web/scorch"
 	"phenix/web/util"
 	"phenix/web/weberror"
 
@@ -29,6 +37,17 @@ type route struct {
 
 var o serverOptions
 
+// optionalSubsystem holds the API routes of, and any processors to start for,
+// an optional subsystem. Subsystems add themselves to optionalSubsystems from
+// code excluded by their build tag so their packages aren't linked into
+// phenix when they're left out.
+type optionalSubsystem struct {
+	routes func(*mux.Router)
+	start  func(basePath string)
+}
+
+var optionalSubsystems = make(map[string]optionalSubsystem)
+
 func ConfigureUsers(users []string) error {
 	setUserRole := func(user *rbac.User, rname string, resources ...string) {
 		if role, err := rbac.RoleFromConfig(rname); err == nil {
@@ -76,16 +95,34 @@ func ConfigureUsers(users []string) error {
 }
 
 func Start(opts ...ServerOption) error {
+	optionsMu.Lock()
 	o = newServerOptions(opts...)
+	optionsMu.Unlock()
+
+	// The server options can be replaced by a reload once the server is up, so
+	// only use this copy of them from here on.
+	current := options()
 
-	ConfigureUsers(o.users)
+	ConfigureUsers(current.users)
+
+	if err := applySettings(current); err != nil {
+		return err
+	}
+
+	if current.approvalWindow > 0 {
+		if err := approval.Enable(current.approvalWindow, current.approvalOperations...); err != nil {
+			return fmt.Errorf("enabling approvals: %w", err)
+		}
+
+		plog.Info("approval required for destructive operations", "window", current.approvalWindow)
+	}
 
 	var (
 		router = mux.NewRouter().StrictSlash(true)
 		assets http.FileSystem
 	)
 
-	if o.unbundled {
+	if current.unbundled {
 		assets = http.Dir("web/public")
 		plog.Info("serving unbundled assets")
 	} else {
@@ -96,13 +133,19 @@ func Start(opts ...ServerOption) error {
 		}
 	}
 
-	if o.featured("tunneler-download") {
+	if current.featured("tunneler-download") {
 		plog.Info("Serving phēnix tunneler downloads")
 		router.HandleFunc("/downloads/tunneler/{name}", forward.GetTunneler).Methods("GET")
 	}
 
 	router.HandleFunc("/features", GetFeatures).Methods("GET")
 	router.HandleFunc("/version", GetVersion).Methods("GET")
+
+	if current.metrics {
+		plog.Info("serving Prometheus metrics", "path", "/metrics")
+		router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
+
 	router.HandleFunc("/builder", GetBuilder).Methods("GET")
 	router.HandleFunc("/builder/save", SaveBuilderTopology).Methods("POST")
 
@@ -144,26 +187,35 @@ func Start(opts ...ServerOption) error {
 	api.Handle("/builder/topologies/{name}", weberror.ErrorHandler(GetBuilderTopology)).Methods("GET", "OPTIONS")
 	api.Handle("/configs", weberror.ErrorHandler(GetConfigs)).Methods("GET", "OPTIONS")
 	api.Handle("/configs", weberror.ErrorHandler(CreateConfig)).Methods("POST", "OPTIONS")
+	api.Handle("/configs/search", weberror.ErrorHandler(SearchConfigs)).Methods("GET", "OPTIONS")
 	api.Handle("/configs/{kind}/{name}", weberror.ErrorHandler(GetConfig)).Methods("GET", "OPTIONS")
 	api.Handle("/configs/{kind}/{name}", weberror.ErrorHandler(UpdateConfig)).Methods("PUT", "OPTIONS")
-	api.Handle("/configs/{kind}/{name}", weberror.ErrorHandler(DeleteConfig)).Methods("DELETE", "OPTIONS")
+	api.Handle("/configs/{kind}/{name}", approval.Require("config-delete", "configs", "delete", configFullName, weberror.ErrorHandler(DeleteConfig))).Methods("DELETE", "OPTIONS")
 	api.Handle("/configs/download", weberror.ErrorHandler(DownloadConfigs)).Methods("POST", "OPTIONS")
 	api.Handle("/schemas/{version}", weberror.ErrorHandler(GetSchemaSpec)).Methods("GET", "OPTIONS")
 	api.Handle("/schemas/{kind}/{version}", weberror.ErrorHandler(GetSchema)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments", GetExperiments).Methods("GET", "OPTIONS")
-	api.HandleFunc("/experiments", CreateExperiment).Methods("POST", "OPTIONS")
+	api.Handle("/experiments", weberror.ErrorHandler(CreateExperiment)).Methods("POST", "OPTIONS")
 	api.Handle("/experiments/builder", weberror.ErrorHandler(CreateExperimentFromBuilder)).Methods("POST", "OPTIONS")
 	api.Handle("/experiments/builder", weberror.ErrorHandler(UpdateExperimentFromBuilder)).Methods("PUT", "OPTIONS")
 	api.Handle("/experiments/{name}", weberror.ErrorHandler(GetExperiment)).Methods("GET", "OPTIONS")
 	api.Handle("/experiments/{name}", weberror.ErrorHandler(UpdateExperiment)).Methods("PATCH", "OPTIONS")
-	api.HandleFunc("/experiments/{name}", DeleteExperiment).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{name}", approval.Require("experiment-delete", "experiments", "delete", approval.PathVars("name"), http.HandlerFunc(DeleteExperiment))).Methods("DELETE", "OPTIONS")
 	api.Handle("/experiments/{name}/apps", weberror.ErrorHandler(GetExperimentApps)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/apps/runs", weberror.ErrorHandler(GetExperimentAppRuns)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/apps/{app}/log", weberror.ErrorHandler(GetExperimentAppLog)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/apps/{app}/log/ws", StreamExperimentAppLog).Methods("GET", "OPTIONS")
 	api.Handle("/experiments/{name}/start", weberror.ErrorHandler(StartExperiment)).Methods("POST", "OPTIONS")
-	api.Handle("/experiments/{name}/stop", weberror.ErrorHandler(StopExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/start-at", weberror.ErrorHandler(GetExperimentScheduledStart)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/start-at", weberror.ErrorHandler(ScheduleExperimentStart)).Methods("PUT", "OPTIONS")
+	api.Handle("/experiments/{name}/start-at", weberror.ErrorHandler(CancelExperimentScheduledStart)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{name}/preload", weberror.ErrorHandler(PreloadExperimentImages)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/stop", approval.Require("experiment-stop", "experiments/stop", "update", approval.PathVars("name"), weberror.ErrorHandler(StopExperiment))).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", GetNetflow).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", StartNetflow).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", StopNetflow).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow/ws", GetNetflowWebSocket).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/vlans/stats", weberror.ErrorHandler(GetExperimentVLANStats)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/topology", GetExperimentTopology).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/topology/search", SearchExperimentTopology).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/trigger", TriggerExperimentApps).Methods("POST", "OPTIONS")
@@ -173,24 +225,48 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{name}/captures", GetExperimentCaptures).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/captureSubnet", StartCaptureSubnet).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/stopCaptureSubnet", StopCaptureSubnet).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/bundle", weberror.ErrorHandler(GetExperimentBundle)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/files", GetExperimentFiles).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/files/{filename}", GetExperimentFile).Methods("GET", "OPTIONS")
-	api.Handle("/experiments/{name}/scorch/components/{run}/{loop}/{stage}/{cmp}", weberror.ErrorHandler(scorch.GetComponentOutput)).Methods("GET", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/scorch/components/{run}/{loop}/{stage}/{cmp}/ws", scorch.StreamComponentOutput).Methods("GET", "OPTIONS")
-	api.Handle("/experiments/{name}/scorch/pipelines", weberror.ErrorHandler(scorch.GetPipelines)).Methods("GET", "OPTIONS")
-	api.Handle("/experiments/{name}/scorch/pipelines/{run}/{loop}", weberror.ErrorHandler(scorch.GetPipeline)).Methods("GET", "OPTIONS")
-	api.Handle("/experiments/{name}/scorch/pipelines/{run}", weberror.ErrorHandler(scorch.StartPipeline)).Methods("POST", "OPTIONS")
-	api.Handle("/experiments/{name}/scorch/pipelines/{run}", weberror.ErrorHandler(scorch.CancelPipeline)).Methods("DELETE", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/scorch/terminals", scorch.GetTerminals).Methods("GET", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}", scorch.ConnectTerminal).Methods("GET", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/exit/{id}", scorch.ExitTerminal).Methods("POST", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/ws/{id}", scorch.StreamTerminal).Methods("GET", "OPTIONS")
-	api.HandleFunc("/experiments/{name}/soh", GetExperimentSoH).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/artifacts", weberror.ErrorHandler(GetExperimentArtifacts)).Methods("GET", "OPTIONS")
+
+	// Optional subsystem routes are only available if the subsystem is.
+	for _, name := range subsystem.All {
+		if sub, ok := optionalSubsystems[name]; ok && sub.routes != nil && subsystem.Enabled(name) {
+			sub.routes(api)
+		}
+	}
+
+	api.Handle("/experiments/{name}/health", weberror.ErrorHandler(GetExperimentHealth)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/diagnostics", weberror.ErrorHandler(GetExperimentDiagnostics)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/scores", weberror.ErrorHandler(GetExperimentScores)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/scores/flags", weberror.ErrorHandler(SubmitExperimentFlag)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/inventory", weberror.ErrorHandler(GetExperimentInventory)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/inventory", weberror.ErrorHandler(CollectExperimentInventory)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/readme", weberror.ErrorHandler(GetExperimentReadme)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/readme", weberror.ErrorHandler(UpdateExperimentReadme)).Methods("PUT", "OPTIONS")
+	api.Handle("/approvals", weberror.ErrorHandler(GetApprovals)).Methods("GET", "OPTIONS")
+	api.Handle("/approvals/{id}", weberror.ErrorHandler(RejectApproval)).Methods("DELETE", "OPTIONS")
+	api.Handle("/approvals/{id}/approve", weberror.ErrorHandler(ApproveApproval)).Methods("POST", "OPTIONS")
+	api.Handle("/smoke-tests/history", weberror.ErrorHandler(GetSmokeTestHistory)).Methods("GET", "OPTIONS")
+	api.Handle("/audit", weberror.ErrorHandler(GetAuditEvents)).Methods("GET", "OPTIONS")
+	api.Handle("/views", weberror.ErrorHandler(GetViews)).Methods("GET", "OPTIONS")
+	api.Handle("/views/{name}", weberror.ErrorHandler(GetView)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/journal", weberror.ErrorHandler(GetExperimentJournal)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/journal", weberror.ErrorHandler(AddExperimentAnnotation)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/shares", weberror.ErrorHandler(GetExperimentShares)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/shares", weberror.ErrorHandler(CreateExperimentShare)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/shares/{id}", weberror.ErrorHandler(RevokeExperimentShare)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{name}/timeline", weberror.ErrorHandler(GetExperimentTimeline)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/timeline/pause", weberror.ErrorHandler(PauseExperimentTimeline)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/timeline/resume", weberror.ErrorHandler(ResumeExperimentTimeline)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/timeline/events/{event}/skip", weberror.ErrorHandler(SkipExperimentTimelineEvent)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms", GetVMs).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms", UpdateVMs).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/broadcast", weberror.ErrorHandler(BroadcastVMs)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", GetVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", UpdateVM).Methods("PATCH", "OPTIONS")
-	api.HandleFunc("/experiments/{exp}/vms/{name}", DeleteVM).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}", approval.Require("vm-kill", "vms", "delete", approval.PathVars("exp", "name"), http.HandlerFunc(DeleteVM))).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/reset", ResetVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/restart", RestartVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/start", StartVM).Methods("POST", "OPTIONS")
@@ -208,6 +284,15 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", GetVMSnapshots).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", SnapshotVM).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots/{snapshot}", RestoreVM).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/diagnostics", weberror.ErrorHandler(GetVMDiagnostics)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/diagnostics", weberror.ErrorHandler(CollectVMDiagnostics)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/drift", weberror.ErrorHandler(GetVMDrift)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/drift/golden", weberror.ErrorHandler(RecordVMGoldenState)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/restore-points", weberror.ErrorHandler(GetVMRestorePoints)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/restore-points", weberror.ErrorHandler(CreateVMRestorePoint)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/restore-points/policy", weberror.ErrorHandler(UpdateVMRestorePolicy)).Methods("PUT", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/restore-points/{point}", weberror.ErrorHandler(DeleteVMRestorePoint)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/restore-points/{point}/restore", weberror.ErrorHandler(RestoreVMToPoint)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/commit", CommitVM).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/memorySnapshot", CreateVMMemorySnapshot).Methods("POST", "OPTIONS")
 
@@ -216,7 +301,7 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{exp}/vms/{name}/forwards", forward.DeletePortForward).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/forwards/{host}/{port}/ws", forward.GetPortForwardWebSocket).Methods("GET", "OPTIONS")
 
-	if o.featured("vm-mount") {
+	if current.featured("vm-mount") {
 		api.HandleFunc("/experiments/{exp}/vms/{name}/mount", MountVM).Methods("POST", "OPTIONS")
 		api.HandleFunc("/experiments/{exp}/vms/{name}/unmount", UnmountVM).Methods("DELETE", "OPTIONS")
 		api.HandleFunc("/experiments/{exp}/vms/{name}/files", GetMountFiles).Methods("GET", "OPTIONS").Queries("path", "{path}")
@@ -226,6 +311,8 @@ func Start(opts ...ServerOption) error {
 
 	api.HandleFunc("/vms", GetAllVMs).Methods("GET", "OPTIONS")
 	api.HandleFunc("/applications", GetApplications).Methods("GET", "OPTIONS")
+	api.Handle("/applications/installed", weberror.ErrorHandler(GetInstalledApplications)).Methods("GET", "OPTIONS")
+	api.Handle("/applications/details", weberror.ErrorHandler(GetApplicationDetails)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/topologies", GetTopologies).Methods("GET", "OPTIONS")
 	api.HandleFunc("/topologies/{topo}/scenarios", GetScenarios).Methods("GET", "OPTIONS")
 	api.HandleFunc("/disks", GetDisks).Methods("GET", "OPTIONS")
@@ -236,11 +323,21 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/users/{username}", UpdateUser).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/users/{username}", DeleteUser).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/users/{username}/tokens", CreateUserToken).Methods("POST", "OPTIONS")
+	api.Handle("/users/{username}/preferences", weberror.ErrorHandler(GetUserPreferences)).Methods("GET", "OPTIONS")
+	api.Handle("/users/{username}/preferences", weberror.ErrorHandler(UpdateUserPreferences)).Methods("PUT", "OPTIONS")
+	api.Handle("/users/{username}/preferences/views/{table}/{view}", weberror.ErrorHandler(SaveUserView)).Methods("PUT", "OPTIONS")
+	api.Handle("/users/{username}/preferences/views/{table}/{view}", weberror.ErrorHandler(DeleteUserView)).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/roles", GetRoles).Methods("GET", "OPTIONS")
+	api.Handle("/authz/can-i", weberror.ErrorHandler(CanI)).Methods("GET", "POST", "OPTIONS")
+	api.Handle("/authz/policy-test", weberror.ErrorHandler(TestPolicy)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/signup", Signup).Methods("POST", "OPTIONS")
 	api.HandleFunc("/login", Login).Methods("GET", "POST", "OPTIONS")
 	api.HandleFunc("/logout", Logout).Methods("GET", "OPTIONS")
 	api.Handle("/history", weberror.ErrorHandler(GetHistory)).Methods("POST", "OPTIONS")
+	api.Handle("/usage", weberror.ErrorHandler(GetUsageReport)).Methods("GET", "OPTIONS")
+	api.Handle("/templates", weberror.ErrorHandler(GetTemplates)).Methods("GET", "OPTIONS")
+	api.Handle("/templates/{name}", weberror.ErrorHandler(GetTemplate)).Methods("GET", "OPTIONS")
+	api.Handle("/templates/{name}/experiments", weberror.ErrorHandler(CreateExperimentFromTemplate)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/ws", broker.ServeWS).Methods("GET")
 	api.HandleFunc("/console", CreateConsole).Methods("POST", "OPTIONS")
 	api.HandleFunc("/console/{pid}/ws", WsConsole).Methods("GET", "OPTIONS")
@@ -257,18 +354,19 @@ func Start(opts ...ServerOption) error {
 
 	optionRoutes := []route{
 		{"/options", weberror.ErrorHandler(GetOptions), []string{"GET"}},
+		{"/options/reload", weberror.ErrorHandler(ReloadOptions), []string{"POST"}},
 	}
 
 	addRoutesToRouter(api, workflowRoutes...)
 	addRoutesToRouter(api, errorRoutes...)
 	addRoutesToRouter(api, optionRoutes...)
 
-	if o.allowCORS {
+	if current.allowCORS {
 		plog.Info("CORS is enabled on HTTP API endpoints")
 		api.Use(middleware.AllowCORS)
 	}
 
-	switch o.logMiddleware {
+	switch current.logMiddleware {
 	case "full":
 		plog.Info("full HTTP logging is enabled")
 		api.Use(middleware.LogFull)
@@ -277,22 +375,91 @@ func Start(opts ...ServerOption) error {
 		api.Use(middleware.LogRequests)
 	}
 
-	api.Use(middleware.Auth(o.jwtKey, o.proxyAuthHeader))
+	setAuthMiddleware(current.jwtKey, current.proxyAuthHeader)
+
+	// The auth middleware is looked up for each request so changes to auth
+	// settings take effect when the server configuration is reloaded.
+	api.Use(func(h http.Handler) http.Handler {
+		return currentAuthMiddleware()(h)
+	})
+
+	api.Use(middleware.Views)
 
 	plog.Info("starting websockets broker")
 
 	go broker.Start()
 
-	plog.Info("starting scorch processors")
+	plog.Info("watching app registry", "dir", app.APP_REGISTRY_DIR)
+
+	go app.WatchRegistry(context.Background())
+
+	for _, name := range subsystem.All {
+		if sub, ok := optionalSubsystems[name]; ok && sub.start != nil && subsystem.Enabled(name) {
+			plog.Info("starting subsystem processors", "subsystem", name)
 
-	go scorch.Start(o.basePath)
+			go sub.start(current.basePath)
+		}
+	}
 
 	plog.Info("starting log publisher")
 
-	go PublishMinimegaLogs(context.Background(), o.minimegaLogs)
+	go PublishMinimegaLogs(context.Background(), current.minimegaLogs)
+
+	if current.statusWebhook != nil {
+		startStatusWebhook(current.statusWebhook)
+	}
+
+	if current.reloader != nil {
+		handleReloadSignal()
+	}
+
+	if current.smokeTests != nil {
+		plog.Info("starting smoke test scheduler", "tests", len(current.smokeTests))
 
-	plog.Info("using base path", "path", o.basePath)
-	plog.Info("using JWT lifetime", "lifetime", o.jwtLifetime)
+		smoke.Start(context.Background(), current.smokeTests)
+	}
+
+	if current.retention != nil {
+		plog.Info("starting retention janitor", "dryRun", current.retention.DryRun)
+
+		retention.Start(context.Background(), *current.retention)
+	}
+
+	if current.guestIPDiscovery > 0 {
+		plog.Info("starting guest IP discovery", "interval", current.guestIPDiscovery)
+
+		mm.StartGuestIPDiscovery(context.Background(), current.guestIPDiscovery, runningExperiments)
+	}
+
+	resumeTimelines()
+
+	if current.scheduledStarts > 0 {
+		plog.Info("starting scheduled experiment starter", "interval", current.scheduledStarts, "preload", current.imagePreload)
+
+		experiment.StartScheduled(context.Background(), current.scheduledStarts, current.imagePreload, startScheduledExperiment)
+	}
+
+	if current.callbackEndpoint != "" {
+		plog.Info("starting guest callback server", "endpoint", current.callbackEndpoint)
+
+		go func() {
+			var (
+				router = newCallbackRouter()
+				err    error
+			)
+
+			if current.tlsEnabled() {
+				err = http.ListenAndServeTLS(current.callbackEndpoint, current.tlsCrtPath, current.tlsKeyPath, router)
+			} else {
+				err = http.ListenAndServe(current.callbackEndpoint, router)
+			}
+
+			plog.Error("serving guest callback API", "err", err)
+		}()
+	}
+
+	plog.Info("using base path", "path", current.basePath)
+	plog.Info("using JWT lifetime", "lifetime", current.jwtLifetime)
 
 	if common.UnixSocket != "" {
 		var (
@@ -316,9 +483,9 @@ func Start(opts ...ServerOption) error {
 			return err
 		}
 
-		if o.unixSocketGid != -1 {
-			plog.Info("setting Unix socket group permissions", "gid", o.unixSocketGid)
-			if err = os.Chown(common.UnixSocket, -1, o.unixSocketGid); err != nil {
+		if current.unixSocketGid != -1 {
+			plog.Info("setting Unix socket group permissions", "gid", current.unixSocketGid)
+			if err = os.Chown(common.UnixSocket, -1, current.unixSocketGid); err != nil {
 				return err
 			}
 			if err := os.Chmod(common.UnixSocket, 0775); err != nil {
@@ -333,13 +500,32 @@ func Start(opts ...ServerOption) error {
 		}()
 	}
 
-	if o.tlsEnabled() {
-		plog.Info("starting HTTPS server", "endpoint", o.endpoint)
-		return http.ListenAndServeTLS(o.endpoint, o.tlsCrtPath, o.tlsKeyPath, router)
+	if current.tlsEnabled() {
+		plog.Info("starting HTTPS server", "endpoint", current.endpoint)
+		return http.ListenAndServeTLS(current.endpoint, current.tlsCrtPath, current.tlsKeyPath, router)
 	} else {
-		plog.Info("Starting HTTP server", "endpoint", o.endpoint)
-		return http.ListenAndServe(o.endpoint, router)
+		plog.Info("Starting HTTP server", "endpoint", current.endpoint)
+		return http.ListenAndServe(current.endpoint, router)
+	}
+}
+
+// runningExperiments returns the names of running experiments, which are also
+// the minimega namespaces of their VMs.
+func runningExperiments() []string {
+	exps, err := experiment.List()
+	if err != nil {
+		plog.Error("getting list of experiments", "err", err)
 	}
+
+	var running []string
+
+	for _, exp := range exps {
+		if exp.Running() {
+			running = append(running, exp.Spec.ExperimentName())
+		}
+	}
+
+	return running
 }
 
 func addRoutesToRouter(router *mux.Router, routes ...route) {
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/share"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+type shareLink struct {
+	share.Link
+
+	State string `json:"state"`
+	Token string `json:"token,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// GET /experiments/{name}/shares
+func GetExperimentShares(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentShares")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/shares", "list", name) {
+		err := weberror.NewWebError(nil, "listing share links for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	links, err := share.List(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get share links for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	resp := make([]shareLink, len(links))
+
+	for i, link := range links {
+		resp[i] = shareLink{Link: link, State: link.State()}
+	}
+
+	body, err := json.Marshal(util.WithRoot("shares", resp))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process share links for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/shares
+func CreateExperimentShare(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateExperimentShare")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/shares", "create", name) {
+		err := weberror.NewWebError(nil, "creating share links for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Scope       share.Scope `json:"scope"`
+		VM          string      `json:"vm"`
+		Description string      `json:"description"`
+		Expires     string      `json:"expires"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid share link provided").SetStatus(http.StatusBadRequest)
+	}
+
+	var ttl time.Duration
+
+	if req.Expires != "" {
+		if ttl, err = time.ParseDuration(req.Expires); err != nil {
+			return weberror.NewWebError(err, "invalid share link expiration %s", req.Expires).SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		return weberror.NewWebError(err, "unable to find experiment %s", name).SetStatus(http.StatusNotFound)
+	}
+
+	// Users can't share more access than they have themselves.
+	switch req.Scope {
+	case share.ScopeDashboard:
+		if !role.Allowed("experiments", "get", name) || !role.Allowed("vms", "list", name+"/*") {
+			err := weberror.NewWebError(nil, "sharing dashboard for experiment %s not allowed for %s", name, user)
+			return err.SetStatus(http.StatusForbidden)
+		}
+	case share.ScopeConsole:
+		if !role.Allowed("vms/vnc", "get", name+"/"+req.VM) {
+			err := weberror.NewWebError(nil, "sharing console for VM %s in experiment %s not allowed for %s", req.VM, name, user)
+			return err.SetStatus(http.StatusForbidden)
+		}
+
+		if _, err := vm.Get(name, req.VM); err != nil {
+			return weberror.NewWebError(err, "unable to find VM %s in experiment %s", req.VM, name).SetStatus(http.StatusNotFound)
+		}
+	}
+
+	link, token, err := share.Create(name, req.Scope, req.VM, req.Description, user, ttl)
+	if err != nil {
+		if errors.Is(err, share.ErrInvalidScope) {
+			return weberror.NewWebError(err, "invalid share link scope %s", req.Scope).SetStatus(http.StatusBadRequest)
+		}
+
+		err := weberror.NewWebError(err, "unable to create share link for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	resp := shareLink{Link: *link, State: link.State(), Token: token}
+
+	// Console links can be opened directly in a browser. Dashboard links are
+	// used by providing the token to the API (ie. in the share link header).
+	if link.Scope == share.ScopeConsole {
+		resp.URL = fmt.Sprintf("%sapi/v1/experiments/%s/vms/%s/vnc?share=%s", options().basePath, name, link.VM, token)
+	}
+
+	body, _ = json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /experiments/{name}/shares/{id}
+func RevokeExperimentShare(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RevokeExperimentShare")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+		id   = vars["id"]
+	)
+
+	if !role.Allowed("experiments/shares", "delete", name) {
+		err := weberror.NewWebError(nil, "revoking share links for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	link, err := share.Get(id)
+	if err != nil || link.Experiment != name {
+		return weberror.NewWebError(err, "unable to find share link %s for experiment %s", id, name).SetStatus(http.StatusNotFound)
+	}
+
+	if err := share.Revoke(id, user); err != nil {
+		err := weberror.NewWebError(err, "unable to revoke share link %s", id)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,452 @@
+// Package share manages expiring share links that grant limited access to an
+// experiment (ie. a view-only dashboard or a single VM console) to someone
+// without a phenix account, such as an external evaluator. Each link carries
+// its own RBAC role scoped to the experiment (and VM) it was created for.
+// Links are persisted to disk so they survive restarts of the UI server, and
+// creating, revoking, and using links is recorded in the audit log.
+package share
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/util/audit"
+	"phenix/util/common"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+
+	v1 "phenix/types/version/v1"
+)
+
+// Header is the HTTP header a share link token can be provided in. Tokens can
+// also be provided in the `share` URL parameter, or the `token` URL parameter
+// already used for JWTs in browser links.
+const Header = "X-Phenix-Share-Token"
+
+// TokenPrefix prefixes all share link tokens so they can be told apart from
+// JWTs.
+const TokenPrefix = "phenix-share-"
+
+type Scope string
+
+const (
+	// ScopeDashboard grants view-only access to an experiment and its VMs.
+	ScopeDashboard Scope = "dashboard"
+
+	// ScopeConsole grants access to the VNC console of a single VM.
+	ScopeConsole Scope = "console"
+)
+
+var (
+	ErrNotFound     = errors.New("share link not found")
+	ErrExpired      = errors.New("share link expired")
+	ErrRevoked      = errors.New("share link revoked")
+	ErrInvalidScope = errors.New("invalid share link scope")
+)
+
+var (
+	// DefaultTTL is how long share links are valid for if not specified.
+	DefaultTTL = 24 * time.Hour
+
+	// MaxTTL is the longest share links can be valid for.
+	MaxTTL = 30 * 24 * time.Hour
+
+	// UseAuditInterval limits how often use of a share link is recorded in the
+	// audit log, since a single page view makes many API requests.
+	UseAuditInterval = 10 * time.Minute
+)
+
+// Link is a share link for an experiment. The link's token is only returned
+// when the link is created; only a hash of it is kept.
+type Link struct {
+	ID          string     `json:"id"`
+	Experiment  string     `json:"experiment"`
+	Scope       Scope      `json:"scope"`
+	VM          string     `json:"vm,omitempty"`
+	Description string     `json:"description,omitempty"`
+	CreatedBy   string     `json:"createdBy"`
+	Created     time.Time  `json:"created"`
+	Expires     time.Time  `json:"expires"`
+	RevokedBy   string     `json:"revokedBy,omitempty"`
+	Revoked     *time.Time `json:"revoked,omitempty"`
+	Uses        int        `json:"uses"`
+	LastUsed    *time.Time `json:"lastUsed,omitempty"`
+}
+
+// State returns the state of the link: active, expired, or revoked.
+func (this Link) State() string {
+	switch {
+	case this.Revoked != nil:
+		return "revoked"
+	case time.Now().After(this.Expires):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// User returns the user name requests made with the link are attributed to.
+func (this Link) User() string {
+	return "share/" + this.ID
+}
+
+// Resource returns the audit log resource for the link.
+func (this Link) Resource() string {
+	return "experiments/" + this.Experiment + "/shares/" + this.ID
+}
+
+// Role returns the RBAC role granted by the link.
+func (this Link) Role() rbac.Role {
+	spec := &v1.RoleSpec{Name: "Share Link"}
+
+	switch this.Scope {
+	case ScopeDashboard:
+		spec.Policies = []*v1.PolicySpec{
+			{
+				Resources:     []string{"experiments", "experiments/journal"},
+				ResourceNames: []string{this.Experiment},
+				Verbs:         []string{"get", "list"},
+			},
+			{
+				Resources:     []string{"vms", "vms/screenshot"},
+				ResourceNames: []string{this.Experiment + "/*"},
+				Verbs:         []string{"get", "list"},
+			},
+		}
+	case ScopeConsole:
+		spec.Policies = []*v1.PolicySpec{
+			{
+				Resources:     []string{"experiments"},
+				ResourceNames: []string{this.Experiment},
+				Verbs:         []string{"get"},
+			},
+			{
+				Resources:     []string{"vms", "vms/screenshot", "vms/vnc"},
+				ResourceNames: []string{this.Experiment + "/" + this.VM},
+				Verbs:         []string{"get"},
+			},
+		}
+	}
+
+	return rbac.Role{Spec: spec}
+}
+
+// record is a link as persisted, including the hash of its token.
+type record struct {
+	Link
+
+	Hash string `json:"hash"`
+
+	lastAudited time.Time
+}
+
+var links = struct {
+	sync.Mutex
+
+	loaded  bool
+	records map[string]*record
+}{
+	records: make(map[string]*record),
+}
+
+// Create creates a new share link for the given experiment with the given
+// scope, valid for the given duration (DefaultTTL if zero), returning the link
+// and its token. A VM must be given for console links.
+func Create(exp string, scope Scope, vm, desc, user string, ttl time.Duration) (*Link, string, error) {
+	switch scope {
+	case ScopeDashboard:
+		vm = ""
+	case ScopeConsole:
+		if vm == "" {
+			return nil, "", fmt.Errorf("%w: a VM is required for console links", ErrInvalidScope)
+		}
+	default:
+		return nil, "", fmt.Errorf("%w: %s (expected dashboard or console)", ErrInvalidScope, scope)
+	}
+
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	if ttl < 0 || ttl > MaxTTL {
+		return nil, "", fmt.Errorf("share link lifetime must be between 0 and %v", MaxTTL)
+	}
+
+	var (
+		id    = make([]byte, 8)
+		token = make([]byte, 32)
+	)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("generating share link ID: %w", err)
+	}
+
+	if _, err := rand.Read(token); err != nil {
+		return nil, "", fmt.Errorf("generating share link token: %w", err)
+	}
+
+	var (
+		raw = TokenPrefix + hex.EncodeToString(token)
+		now = time.Now().UTC()
+	)
+
+	r := &record{
+		Link: Link{
+			ID:          hex.EncodeToString(id),
+			Experiment:  exp,
+			Scope:       scope,
+			VM:          vm,
+			Description: desc,
+			CreatedBy:   user,
+			Created:     now,
+			Expires:     now.Add(ttl),
+		},
+		Hash: hash(raw),
+	}
+
+	links.Lock()
+	defer links.Unlock()
+
+	if err := load(); err != nil {
+		return nil, "", err
+	}
+
+	links.records[r.ID] = r
+
+	if err := save(); err != nil {
+		delete(links.records, r.ID)
+		return nil, "", err
+	}
+
+	audit.Record(audit.Event{
+		User:     user,
+		Action:   "share/create",
+		Resource: r.Resource(),
+		Details: map[string]any{
+			"scope":   string(scope),
+			"vm":      vm,
+			"expires": r.Expires,
+		},
+	})
+
+	link := r.Link
+
+	return &link, raw, nil
+}
+
+// List returns the share links for the given experiment, or for all
+// experiments if no experiment is given, newest first. Expired and revoked
+// links are included so their use can be reviewed.
+func List(exp string) ([]Link, error) {
+	links.Lock()
+	defer links.Unlock()
+
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	list := []Link{}
+
+	for _, r := range links.records {
+		if exp == "" || r.Experiment == exp {
+			list = append(list, r.Link)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+
+	return list, nil
+}
+
+// Get returns the share link with the given ID.
+func Get(id string) (*Link, error) {
+	links.Lock()
+	defer links.Unlock()
+
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	r, ok := links.records[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	link := r.Link
+
+	return &link, nil
+}
+
+// Revoke revokes the share link with the given ID so it can no longer be used.
+func Revoke(id, user string) error {
+	links.Lock()
+	defer links.Unlock()
+
+	if err := load(); err != nil {
+		return err
+	}
+
+	r, ok := links.records[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	if r.Revoked != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+
+	r.Revoked = &now
+	r.RevokedBy = user
+
+	if err := save(); err != nil {
+		return err
+	}
+
+	audit.Record(audit.Event{User: user, Action: "share/revoke", Resource: r.Resource()})
+
+	return nil
+}
+
+// Authenticate returns the share link for the given token, if the link is
+// still active, and records its use. The given remote address is included in
+// the audit log.
+func Authenticate(token, remote string) (*Link, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, ErrNotFound
+	}
+
+	links.Lock()
+	defer links.Unlock()
+
+	if err := load(); err != nil {
+		return nil, err
+	}
+
+	h := hash(token)
+
+	var r *record
+
+	for _, rec := range links.records {
+		if rec.Hash == h {
+			r = rec
+			break
+		}
+	}
+
+	if r == nil {
+		return nil, ErrNotFound
+	}
+
+	switch r.State() {
+	case "revoked":
+		return nil, fmt.Errorf("%w: %s", ErrRevoked, r.ID)
+	case "expired":
+		return nil, fmt.Errorf("%w: %s", ErrExpired, r.ID)
+	}
+
+	now := time.Now().UTC()
+
+	r.Uses++
+	r.LastUsed = &now
+
+	if now.Sub(r.lastAudited) >= UseAuditInterval {
+		r.lastAudited = now
+
+		audit.Record(audit.Event{
+			User:     r.User(),
+			Action:   "share/use",
+			Resource: r.Resource(),
+			Details:  map[string]any{"remote": remote, "uses": r.Uses},
+		})
+
+		// Use counts are only persisted when audited to avoid writing the links
+		// file on every request.
+		if err := save(); err != nil {
+			plog.Error("saving share links", "err", err)
+		}
+	}
+
+	link := r.Link
+
+	return &link, nil
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// load reads persisted links, if not done already. The links lock must be held.
+func load() error {
+	if links.loaded {
+		return nil
+	}
+
+	body, err := os.ReadFile(path())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			links.loaded = true
+			return nil
+		}
+
+		return fmt.Errorf("reading share links: %w", err)
+	}
+
+	var records []*record
+
+	if err := json.Unmarshal(body, &records); err != nil {
+		return fmt.Errorf("parsing share links: %w", err)
+	}
+
+	for _, r := range records {
+		links.records[r.ID] = r
+	}
+
+	links.loaded = true
+
+	return nil
+}
+
+// save persists links. The links lock must be held.
+func save() error {
+	records := make([]*record, 0, len(links.records))
+
+	for _, r := range links.records {
+		records = append(records, r)
+	}
+
+	body, _ := json.Marshal(records)
+
+	if err := os.MkdirAll(filepath.Dir(path()), 0755); err != nil {
+		return fmt.Errorf("creating share links directory: %w", err)
+	}
+
+	// Write to a temporary file first so a partial write doesn't lose links.
+	tmp := path() + ".tmp"
+
+	if err := os.WriteFile(tmp, body, 0600); err != nil {
+		return fmt.Errorf("writing share links: %w", err)
+	}
+
+	if err := os.Rename(tmp, path()); err != nil {
+		return fmt.Errorf("writing share links: %w", err)
+	}
+
+	return nil
+}
+
+func path() string {
+	return filepath.Join(common.PhenixBase, "share-links.json")
+}
@@ -0,0 +1,106 @@
+package share
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"phenix/util/common"
+)
+
+func TestShareLinks(t *testing.T) {
+	common.PhenixBase = t.TempDir()
+	common.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+
+	if _, _, err := Create("foo", ScopeConsole, "", "", "admin", 0); !errors.Is(err, ErrInvalidScope) {
+		t.Logf("expected invalid scope error for console link without VM, got %v", err)
+		t.FailNow()
+	}
+
+	link, token, err := Create("foo", ScopeConsole, "bar", "evaluator", "admin", time.Hour)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := Authenticate("bogus", ""); !errors.Is(err, ErrNotFound) {
+		t.Logf("expected not found error for bogus token, got %v", err)
+		t.FailNow()
+	}
+
+	used, err := Authenticate(token, "127.0.0.1")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if used.ID != link.ID || used.Uses != 1 {
+		t.Logf("expected link %s with 1 use, got link %s with %d uses", link.ID, used.ID, used.Uses)
+		t.FailNow()
+	}
+
+	role := used.Role()
+
+	if !role.Allowed("vms/vnc", "get", "foo/bar") {
+		t.Log("expected console link to allow VNC for foo/bar")
+		t.FailNow()
+	}
+
+	if role.Allowed("vms/vnc", "get", "foo/baz") || role.Allowed("vms", "patch", "foo/bar") {
+		t.Log("expected console link to only allow viewing foo/bar")
+		t.FailNow()
+	}
+
+	// Links should be reloaded from disk.
+	links.records = make(map[string]*record)
+	links.loaded = false
+
+	if err := Revoke(link.ID, "admin"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := Authenticate(token, ""); !errors.Is(err, ErrRevoked) {
+		t.Logf("expected revoked error, got %v", err)
+		t.FailNow()
+	}
+
+	list, err := List("foo")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(list) != 1 || list[0].State() != "revoked" || list[0].RevokedBy != "admin" {
+		t.Logf("expected a single link revoked by admin, got %+v", list)
+		t.FailNow()
+	}
+}
+
+func TestShareLinkExpired(t *testing.T) {
+	common.PhenixBase = t.TempDir()
+	common.AuditFile = filepath.Join(t.TempDir(), "audit.log")
+
+	link, token, err := Create("foo", ScopeDashboard, "", "", "admin", time.Hour)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	role := link.Role()
+
+	if !role.Allowed("vms", "list", "foo/bar") || role.Allowed("vms", "list", "other/bar") {
+		t.Log("expected dashboard link to only allow listing VMs in foo")
+		t.FailNow()
+	}
+
+	links.Lock()
+	links.records[link.ID].Expires = time.Now().Add(-time.Minute)
+	links.Unlock()
+
+	if _, err := Authenticate(token, ""); !errors.Is(err, ErrExpired) {
+		t.Logf("expected expired error, got %v", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/smoke"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+)
+
+// GET /smoke-tests/history
+func GetSmokeTestHistory(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetSmokeTestHistory")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		test = r.URL.Query().Get("test")
+	)
+
+	if !role.Allowed("smoke-tests", "list") {
+		err := weberror.NewWebError(nil, "listing smoke test history not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	results, err := smoke.History(test)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get smoke test history")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if results == nil {
+		results = []smoke.Result{}
+	}
+
+	body, err := json.Marshal(util.WithRoot("results", results))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process smoke test history")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
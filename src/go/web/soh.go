@@ -1,3 +1,5 @@
+//go:build !nosoh
+
 package web
 
 import (
@@ -6,12 +8,21 @@ import (
 
 	"phenix/api/soh"
 	"phenix/util/plog"
+	"phenix/util/subsystem"
 	"phenix/web/rbac"
 
 	"github.com/gorilla/mux"
 )
 
-// GET /experiments/{exp}/soh[?statusFilter=<status filter>]
+func init() {
+	optionalSubsystems[subsystem.SOH] = optionalSubsystem{routes: sohRoutes}
+}
+
+func sohRoutes(api *mux.Router) {
+	api.HandleFunc("/experiments/{name}/soh", GetExperimentSoH).Methods("GET", "OPTIONS")
+}
+
+// GET /experiments/{exp}/soh[?statusFilter=<status filter>][&anonymize=true]
 func GetExperimentSoH(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetExperimentSoH")
 
@@ -48,5 +59,15 @@ func GetExperimentSoH(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if query.Get("anonymize") == "true" {
+		anon, err := experimentAnonymizer(exp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		marshalled = anon.Text(marshalled)
+	}
+
 	w.Write(marshalled)
 }
@@ -0,0 +1,207 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/template"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/notes"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /templates
+func GetTemplates(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetTemplates")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("templates", "list") {
+		err := weberror.NewWebError(nil, "listing templates not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	templates, err := template.List()
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get templates from store")
+	}
+
+	allowed := []types.Template{}
+
+	for _, tmpl := range templates {
+		if role.Allowed("templates", "list", tmpl.Metadata.Name) {
+			allowed = append(allowed, tmpl)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("templates", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process templates")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /templates/{name}
+func GetTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("templates", "get", name) {
+		err := weberror.NewWebError(nil, "getting template %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	tmpl, err := template.Get(name)
+	if err != nil {
+		return templateError(err, name)
+	}
+
+	body, err := json.Marshal(tmpl)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process template %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /templates/{name}/experiments
+func CreateExperimentFromTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateExperimentFromTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("templates/experiments", "create", name) {
+		err := weberror.NewWebError(nil, "creating experiments from template %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse request")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Name     string `json:"name"`
+		Topology string `json:"topology"`
+		Scenario string `json:"scenario"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		return weberror.NewWebError(err, "invalid template request provided")
+	}
+
+	if req.Name == "" {
+		return weberror.NewWebError(nil, "no experiment name provided")
+	}
+
+	// Users shouldn't be able to create experiments they won't be able to see
+	// once created.
+	if !role.Allowed("experiments", "get", req.Name) {
+		err := weberror.NewWebError(nil, "creating experiment %s not allowed for %s", req.Name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockExperimentForCreation(req.Name); err != nil {
+		err := weberror.NewWebError(err, "unable to create experiment %s", req.Name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(req.Name)
+
+	opts := []template.InstantiateOption{
+		template.InstantiateWithName(req.Name),
+		template.InstantiateWithTopology(req.Topology),
+		template.InstantiateWithScenario(req.Scenario),
+		template.InstantiateWithOwner(user),
+	}
+
+	if err := template.Instantiate(ctx, name, opts...); err != nil {
+		return templateError(err, name)
+	}
+
+	if warns := notes.Warnings(ctx, true); warns != nil {
+		for _, warn := range warns {
+			plog.Warn("creating experiment from template", "template", name, "warnings", warn)
+		}
+	}
+
+	exp, err := experiment.Get(req.Name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	vms, err := vm.List(req.Name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to list VMs for experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err = marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "get", req.Name),
+		bt.NewResource("experiment", req.Name, "create"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+func templateError(err error, name string) error {
+	switch {
+	case errors.Is(err, template.ErrTemplateNotFound):
+		err := weberror.NewWebError(err, "template %s not found", name)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, template.ErrParameterNotAllowed), errors.Is(err, template.ErrLimitExceeded):
+		err := weberror.NewWebError(err, "experiment request not allowed by template %s", name).WithMetadata("reason", err.Error(), true)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	return weberror.NewWebError(err, "unable to create experiment from template %s", name).SetStatus(http.StatusInternalServerError)
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/timeline"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/timeline
+func GetExperimentTimeline(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentTimeline")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/timeline", "get", name) {
+		err := weberror.NewWebError(nil, "getting timeline for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	status, err := timeline.Get(name)
+	if err != nil {
+		return timelineError(err, name)
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process timeline for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/timeline/pause
+func PauseExperimentTimeline(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "PauseExperimentTimeline")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/timeline", "update", name) {
+		err := weberror.NewWebError(nil, "pausing timeline for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := timeline.Pause(name); err != nil {
+		return timelineError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// POST /experiments/{name}/timeline/resume
+func ResumeExperimentTimeline(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ResumeExperimentTimeline")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/timeline", "update", name) {
+		err := weberror.NewWebError(nil, "resuming timeline for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := timeline.Resume(name); err != nil {
+		return timelineError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// POST /experiments/{name}/timeline/events/{event}/skip
+func SkipExperimentTimelineEvent(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SkipExperimentTimelineEvent")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		name  = vars["name"]
+		event = vars["event"]
+	)
+
+	if !role.Allowed("experiments/timeline", "update", name) {
+		err := weberror.NewWebError(nil, "skipping timeline events for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := timeline.Skip(name, event); err != nil {
+		return timelineError(err, name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func timelineError(err error, name string) error {
+	switch {
+	case errors.Is(err, timeline.ErrTimelineNotConfigured):
+		err := weberror.NewWebError(err, "timeline not configured for experiment %s", name)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, timeline.ErrEventNotFound):
+		err := weberror.NewWebError(err, "timeline event not found for experiment %s", name)
+		return err.SetStatus(http.StatusNotFound)
+	case errors.Is(err, timeline.ErrTimelineNotActive):
+		err := weberror.NewWebError(err, "timeline not active for experiment %s", name)
+		return err.SetStatus(http.StatusConflict)
+	case errors.Is(err, timeline.ErrEventNotPending):
+		err := weberror.NewWebError(err, "timeline event already executed or skipped for experiment %s", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	return weberror.NewWebError(err, "unable to process timeline for experiment %s", name).SetStatus(http.StatusInternalServerError)
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"phenix/api/usage"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+)
+
+// GET /usage[?month=<YYYY-MM>|window=<duration>][&group_by=<user|namespace>][&user=<user>][&namespace=<namespace>]
+func GetUsageReport(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetUsageReport")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("usage", "list") {
+		err := weberror.NewWebError(nil, "getting usage reports not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	opts := []usage.ReportOption{
+		usage.ReportGroupBy(usage.GroupBy(query.Get("group_by"))),
+		usage.ReportForUser(query.Get("user")),
+		usage.ReportForNamespace(query.Get("namespace")),
+	}
+
+	if month := query.Get("month"); month != "" {
+		t, err := time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid month '%s' provided (expected YYYY-MM)", month)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, usage.ReportForMonth(t))
+	} else if window := query.Get("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid window '%s' provided", window)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, usage.ReportForWindow(d))
+	}
+
+	report, err := usage.Generate(opts...)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to generate usage report")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process usage report")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -1,5 +1,27 @@
 package util
 
+import "encoding/json"
+
 func WithRoot(key string, obj any) map[string]any {
 	return map[string]any{key: obj}
 }
+
+// SelectFields returns the given JSON object with only the given top-level
+// fields included. Unknown fields are ignored.
+func SelectFields(body []byte, fields []string) (map[string]json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage)
+
+	for _, field := range fields {
+		if value, ok := obj[field]; ok {
+			selected[field] = value
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+type viewExperiment struct {
+	Name string   `json:"name"`
+	VMs  []string `json:"vms"`
+}
+
+// GET /views
+func GetViews(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetViews")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	views, err := rbac.GetViews()
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get views")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Users can always list the views bound to their role.
+	allowed := []rbac.View{}
+
+	for _, v := range views {
+		if v.Bound(role) || role.Allowed("views", "list", v.Metadata.Name) {
+			allowed = append(allowed, v)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("views", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process views")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /views/{name}
+func GetView(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetView")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	view, err := rbac.GetView(name)
+	if err != nil {
+		if errors.Is(err, rbac.ErrViewNotExist) {
+			return weberror.NewWebError(err, "view %s not found", name).SetStatus(http.StatusNotFound)
+		}
+
+		err := weberror.NewWebError(err, "unable to get view %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if !view.Bound(role) && !role.Allowed("views", "get", name) {
+		err := weberror.NewWebError(nil, "getting view %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	exps, err := experiment.List()
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiments for view %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	scoped := []viewExperiment{}
+
+	for _, exp := range exps {
+		if !view.Spec.Includes(exp.Metadata.Name) || !role.Allowed("experiments", "get", exp.Metadata.Name) {
+			continue
+		}
+
+		vms, err := view.VMs(exp.Metadata.Name)
+		if err != nil {
+			plog.Error("getting VMs for view", "view", name, "exp", exp.Metadata.Name, "err", err)
+			continue
+		}
+
+		sort.Strings(vms)
+
+		scoped = append(scoped, viewExperiment{Name: exp.Metadata.Name, VMs: vms})
+	}
+
+	body, err := json.Marshal(map[string]any{"view": view, "experiments": scoped})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process view %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/vlan"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/vlans/stats
+func GetExperimentVLANStats(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentVLANStats")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		exp  = mux.Vars(r)["exp"]
+	)
+
+	if !role.Allowed("experiments/vlans", "get", exp) {
+		err := weberror.NewWebError(nil, "getting VLAN stats for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	stats, err := vlan.Stats(vlan.Experiment(exp))
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get VLAN stats for experiment %s", exp)
+	}
+
+	body, err := json.Marshal(util.WithRoot("vlans", stats))
+	if err != nil {
+		return weberror.NewWebError(err, "unable to process VLAN stats for experiment %s", exp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -68,7 +68,7 @@ func GetVNC(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Pragma", "no-cache")                                   // HTTP 1.0.
 	w.Header().Set("Expires", "0")                                         // Proxies.
 
-	if o.unbundled {
+	if options().unbundled {
 		tmpl := template.Must(template.New("vnc.html").ParseFiles("web/public/vnc.html"))
 		tmpl.Execute(w, config)
 	} else {
@@ -89,11 +89,18 @@ func GetVNCWebSocket(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetVNCWebSocket")
 
 	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
 		vars = mux.Vars(r)
 		exp  = vars["exp"]
 		name = vars["name"]
 	)
 
+	if !role.Allowed("vms/vnc", "get", exp+"/"+name) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
 	endpoint, err := mm.GetVNCEndpoint(mm.NS(exp), mm.VMName(name))
 	if err != nil {
 		plog.Error("getting VNC endpoint", "err", err)
@@ -126,7 +133,7 @@ type vncConfig struct {
 
 func newVNCBannerConfig(token, exp, vm string) *vncConfig {
 	return &vncConfig{
-		BasePath: o.basePath,
+		BasePath: options().basePath,
 		Token:    token,
 		ExpName:  exp,
 		VMName:   vm,
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"phenix/store"
+	"phenix/util/perror"
 	"phenix/util/plog"
 )
 
@@ -16,6 +17,11 @@ type WebError struct {
 	Status int    `json:"-"`
 	URL    string `json:"url"`
 
+	// Machine-readable failure category and failing resource, set when the cause
+	// is (or wraps) a `perror.Error`.
+	Code     perror.Code `json:"code,omitempty"`
+	Resource string      `json:"resource,omitempty"`
+
 	UserMetadata map[string]string `json:"metadata,omitempty"`
 }
 
@@ -33,6 +39,11 @@ func NewWebError(cause error, format string, args ...interface{}) *WebError {
 		URL:    "/api/v1/errors/" + event.ID,
 	}
 
+	if e, ok := perror.As(cause); ok {
+		err.Code = e.Code
+		err.Resource = e.Resource
+	}
+
 	return err
 }
 